@@ -274,6 +274,7 @@ func (acsSession *session) startACSSession(client wsclient.ClientServer) error {
 		client,
 		acsSession.state,
 		acsSession.stateManager,
+		cfg.MaxTrackedENIs,
 	)
 	eniAttachHandler.start()
 	defer eniAttachHandler.stop()
@@ -39,6 +39,10 @@ type attachENIHandler struct {
 	containerInstance *string
 	acsClient         wsclient.ClientServer
 	state             dockerstate.TaskEngineState
+	// maxENIs is the maximum number of eni attachments tracked in the state.
+	// Attachments beyond it are refused. The number of attachments is not
+	// limited if it is not positive
+	maxENIs int
 }
 
 // newAttachENIHandler returns an instance of the attachENIHandler struct
@@ -47,7 +51,8 @@ func newAttachENIHandler(ctx context.Context,
 	containerInstanceArn string,
 	acsClient wsclient.ClientServer,
 	taskEngineState dockerstate.TaskEngineState,
-	saver statemanager.Saver) attachENIHandler {
+	saver statemanager.Saver,
+	maxENIs int) attachENIHandler {
 
 	// Create a cancelable context from the parent context
 	derivedContext, cancel := context.WithCancel(ctx)
@@ -60,6 +65,7 @@ func newAttachENIHandler(ctx context.Context,
 		acsClient:         acsClient,
 		state:             taskEngineState,
 		saver:             saver,
+		maxENIs:           maxENIs,
 	}
 }
 
@@ -121,6 +127,12 @@ func (handler *attachENIHandler) handleSingleMessage(message *ecsacs.AttachTaskN
 		eniAckTimeoutHandler := ackTimeoutHandler{mac: mac, state: handler.state}
 		return eniAttachment.StartTimer(eniAckTimeoutHandler.handle)
 	}
+	if dockerstate.ENILimitReached(handler.state, mac, handler.maxENIs) {
+		// The task engine stops the task the eni is for with the same reason
+		return errors.Errorf(
+			"attach eni message handler: refusing attachment of eni %s for task %s, the maximum number of tracked enis (%d) is reached",
+			mac, aws.StringValue(message.TaskArn), handler.maxENIs)
+	}
 	if err := handler.addENIAttachmentToState(message, receivedAt); err != nil {
 		return errors.Wrapf(err, "attach eni message handler: unable to add eni attachment to engine state")
 	}
@@ -140,6 +152,7 @@ func (handler *attachENIHandler) addENIAttachmentToState(message *ecsacs.AttachT
 		AttachmentARN:    attachmentARN,
 		AttachStatusSent: false,
 		MACAddress:       mac,
+		ReceivedAt:       receivedAt,
 		// Stop tracking the eni attachment after timeout
 		ExpiresAt: receivedAt.Add(time.Duration(aws.Int64Value(message.WaitTimeoutMs)) * time.Millisecond),
 	}
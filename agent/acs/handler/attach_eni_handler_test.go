@@ -216,7 +216,7 @@ func TestENIAckSingleMessage(t *testing.T) {
 
 	ctx := context.TODO()
 	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
-	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager)
+	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager, 0)
 
 	var ackSent sync.WaitGroup
 	ackSent.Add(1)
@@ -270,7 +270,7 @@ func TestENIAckSingleMessageDuplicateENIAttachmentMessageStartsTimer(t *testing.
 
 	ctx := context.TODO()
 	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
-	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, mockState, manager)
+	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, mockState, manager, 0)
 
 	// Set expiresAt to a value in the past
 	expiresAt := time.Unix(time.Now().Unix()-1, 0)
@@ -321,7 +321,7 @@ func TestENIAckHappyPath(t *testing.T) {
 	manager := mock_statemanager.NewMockStateManager(ctrl)
 
 	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
-	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager)
+	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager, 0)
 
 	var ackSent sync.WaitGroup
 	ackSent.Add(1)
@@ -367,7 +367,7 @@ func TestENIAckTimeout(t *testing.T) {
 	manager := mock_statemanager.NewMockStateManager(ctrl)
 
 	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
-	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager)
+	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager, 0)
 	mockNetInterface1 := ecsacs.ElasticNetworkInterface{
 		Ec2Id:         aws.String("1"),
 		MacAddress:    aws.String(randomMAC),
@@ -404,7 +404,7 @@ func TestENIAckWithinTimeout(t *testing.T) {
 	manager := mock_statemanager.NewMockStateManager(ctrl)
 
 	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
-	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager)
+	eniAttachHandler := newAttachENIHandler(ctx, clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager, 0)
 	mockNetInterface1 := ecsacs.ElasticNetworkInterface{
 		Ec2Id:         aws.String("1"),
 		MacAddress:    aws.String(randomMAC),
@@ -431,3 +431,43 @@ func TestENIAckWithinTimeout(t *testing.T) {
 
 	assert.Len(t, taskEngineState.(*dockerstate.DockerTaskEngineState).AllENIAttachments(), 1)
 }
+
+// TestENIAttachmentBeyondMaxENIs tests that the attachment of an eni beyond the
+// maximum number of tracked enis is acked but refused
+func TestENIAttachmentBeyondMaxENIs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	taskEngineState := dockerstate.NewTaskEngineState()
+	taskEngineState.AddENIAttachment(&api.ENIAttachment{MACAddress: "existing"})
+	manager := mock_statemanager.NewMockStateManager(ctrl)
+
+	mockWSClient := mock_wsclient.NewMockClientServer(ctrl)
+	eniAttachHandler := newAttachENIHandler(context.TODO(), clusterName, containerInstanceArn, mockWSClient, taskEngineState, manager, 1)
+
+	var ackSent sync.WaitGroup
+	ackSent.Add(1)
+	mockWSClient.EXPECT().MakeRequest(gomock.Any()).Do(func(ackRequest *ecsacs.AckRequest) {
+		ackSent.Done()
+	})
+
+	message := &ecsacs.AttachTaskNetworkInterfacesMessage{
+		MessageId:            aws.String(eniMessageId),
+		ClusterArn:           aws.String(clusterName),
+		ContainerInstanceArn: aws.String(containerInstanceArn),
+		ElasticNetworkInterfaces: []*ecsacs.ElasticNetworkInterface{{
+			Ec2Id:         aws.String("1"),
+			MacAddress:    aws.String(randomMAC),
+			AttachmentArn: aws.String("attachmentarn"),
+		}},
+		TaskArn:       aws.String(taskArn),
+		WaitTimeoutMs: aws.Int64(waitTimeoutMillis),
+	}
+
+	err := eniAttachHandler.handleSingleMessage(message)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), randomMAC)
+	_, ok := taskEngineState.ENIByMac(randomMAC)
+	assert.False(t, ok, "the refused attachment should not be tracked")
+	ackSent.Wait()
+}
@@ -114,6 +114,8 @@ type Container struct {
 
 	Command []*string `locationName:"command" type:"list"`
 
+	CommandManifest *string `locationName:"commandManifest" type:"string"`
+
 	Cpu *int64 `locationName:"cpu" type:"integer"`
 
 	DockerConfig *DockerConfig `locationName:"dockerConfig" type:"structure"`
@@ -124,6 +126,8 @@ type Container struct {
 
 	Essential *bool `locationName:"essential" type:"boolean"`
 
+	HealthCheck *HealthCheck `locationName:"healthCheck" type:"structure"`
+
 	Image *string `locationName:"image" type:"string"`
 
 	Links []*string `locationName:"links" type:"list"`
@@ -138,8 +142,12 @@ type Container struct {
 
 	PortMappings []*PortMapping `locationName:"portMappings" type:"list"`
 
+	ReadinessProbe *ReadinessProbe `locationName:"readinessProbe" type:"structure"`
+
 	RegistryAuthentication *RegistryAuthenticationData `locationName:"registryAuthentication" type:"structure"`
 
+	TransitionDependencySet *TransitionDependencySet `locationName:"transitionDependencySet" type:"structure"`
+
 	VolumesFrom []*VolumeFrom `locationName:"volumesFrom" type:"list"`
 }
 
@@ -153,6 +161,30 @@ func (s Container) GoString() string {
 	return s.String()
 }
 
+type ContainerDependency struct {
+	_ struct{} `type:"structure"`
+
+	ContainerName *string `locationName:"containerName" type:"string"`
+
+	DependentStatus *string `locationName:"dependentStatus" type:"string"`
+
+	SatisfiedHealth *string `locationName:"satisfiedHealth" type:"string"`
+
+	SatisfiedReady *bool `locationName:"satisfiedReady" type:"boolean"`
+
+	SatisfiedStatus *string `locationName:"satisfiedStatus" type:"string"`
+}
+
+// String returns the string representation
+func (s ContainerDependency) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ContainerDependency) GoString() string {
+	return s.String()
+}
+
 type DockerConfig struct {
 	_ struct{} `type:"structure"`
 
@@ -247,6 +279,28 @@ func (s ErrorOutput) GoString() string {
 	return s.String()
 }
 
+type HealthCheck struct {
+	_ struct{} `type:"structure"`
+
+	Command []*string `locationName:"command" type:"list"`
+
+	Interval *int64 `locationName:"interval" type:"integer"`
+
+	Retries *int64 `locationName:"retries" type:"integer"`
+
+	Timeout *int64 `locationName:"timeout" type:"integer"`
+}
+
+// String returns the string representation
+func (s HealthCheck) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s HealthCheck) GoString() string {
+	return s.String()
+}
+
 type HeartbeatMessage struct {
 	_ struct{} `type:"structure"`
 
@@ -575,6 +629,32 @@ func (s PortMapping) GoString() string {
 	return s.String()
 }
 
+type ReadinessProbe struct {
+	_ struct{} `type:"structure"`
+
+	Interval *int64 `locationName:"interval" type:"integer"`
+
+	Path *string `locationName:"path" type:"string"`
+
+	Port *int64 `locationName:"port" type:"integer"`
+
+	Retries *int64 `locationName:"retries" type:"integer"`
+
+	Timeout *int64 `locationName:"timeout" type:"integer"`
+
+	Type *string `locationName:"type" type:"string"`
+}
+
+// String returns the string representation
+func (s ReadinessProbe) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s ReadinessProbe) GoString() string {
+	return s.String()
+}
+
 type RegistryAuthenticationData struct {
 	_ struct{} `type:"structure"`
 
@@ -631,6 +711,24 @@ func (s StageUpdateMessage) GoString() string {
 	return s.String()
 }
 
+type Tag struct {
+	_ struct{} `type:"structure"`
+
+	Key *string `locationName:"key" type:"string"`
+
+	Value *string `locationName:"value" type:"string"`
+}
+
+// String returns the string representation
+func (s Tag) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s Tag) GoString() string {
+	return s.String()
+}
+
 type Task struct {
 	_ struct{} `type:"structure"`
 
@@ -638,16 +736,28 @@ type Task struct {
 
 	Containers []*Container `locationName:"containers" type:"list"`
 
+	Cpu *float64 `locationName:"cpu" type:"double"`
+
 	DesiredStatus *string `locationName:"desiredStatus" type:"string"`
 
 	ElasticNetworkInterfaces []*ElasticNetworkInterface `locationName:"elasticNetworkInterfaces" type:"list"`
 
 	Family *string `locationName:"family" type:"string"`
 
+	IpcMode *string `locationName:"ipcMode" type:"string"`
+
+	Memory *int64 `locationName:"memory" type:"integer"`
+
 	Overrides *string `locationName:"overrides" type:"string"`
 
+	PidMode *string `locationName:"pidMode" type:"string"`
+
+	Priority *int64 `locationName:"priority" type:"integer"`
+
 	RoleCredentials *IAMRoleCredentials `locationName:"roleCredentials" type:"structure"`
 
+	Tags []*Tag `locationName:"tags" type:"list"`
+
 	TaskDefinitionAccountId *string `locationName:"taskDefinitionAccountId" type:"string"`
 
 	Version *string `locationName:"version" type:"string"`
@@ -665,6 +775,22 @@ func (s Task) GoString() string {
 	return s.String()
 }
 
+type TransitionDependencySet struct {
+	_ struct{} `type:"structure"`
+
+	ContainerDependencies []*ContainerDependency `locationName:"containerDependencies" type:"list"`
+}
+
+// String returns the string representation
+func (s TransitionDependencySet) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s TransitionDependencySet) GoString() string {
+	return s.String()
+}
+
 type UpdateFailureOutput struct {
 	_ struct{} `type:"structure"`
 }
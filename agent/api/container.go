@@ -17,12 +17,19 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
 )
 
 const (
 	// DockerContainerMinimumMemoryInBytes is the minimum amount of
 	// memory to be allocated to a docker container
 	DockerContainerMinimumMemoryInBytes = 4 * 1024 * 1024 // 4MB
+	// MemoryUnitMiB is the default unit of the memory of containers
+	MemoryUnitMiB = "MiB"
+	// MemoryUnitGiB sets the memory of containers in gibibytes
+	MemoryUnitGiB = "GiB"
 	// defaultContainerSteadyStateStatus defines the container status at
 	// which the container is assumed to be in steady state. It is set
 	// to 'ContainerRunning' unless overridden
@@ -53,13 +60,54 @@ type Container struct {
 	Links                  []string
 	VolumesFrom            []VolumeFrom  `json:"volumesFrom"`
 	MountPoints            []MountPoint  `json:"mountPoints"`
+	Mounts                 []Mount       `json:"mounts"`
 	Ports                  []PortBinding `json:"portMappings"`
+	ExposedPorts           []ExposedPort `json:"exposedPorts"`
 	Essential              bool
 	EntryPoint             *[]string
 	Environment            map[string]string           `json:"environment"`
 	Overrides              ContainerOverrides          `json:"overrides"`
 	DockerConfig           DockerConfig                `json:"dockerConfig"`
 	RegistryAuthentication *RegistryAuthenticationData `json:"registryAuthentication"`
+	// Platform is the platform, in the 'os/arch[/variant]' format, that the
+	// image of the container is expected to be built for. It selects the
+	// variant of a multi-arch image that is pulled
+	Platform string `json:"platform"`
+	// DisableHealthcheck disables the healthcheck defined by the image of the
+	// container, if any
+	DisableHealthcheck bool `json:"disableHealthcheck"`
+	// HealthCheck is the docker health check of the container, overriding
+	// the one defined by its image, if any
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+	// ReadinessProbe is the check the Agent runs against the container once
+	// it is running to determine when it is ready
+	ReadinessProbe *ReadinessProbe `json:"readinessProbe,omitempty"`
+	// AttachStdout and AttachStderr attach the stdout and stderr of the
+	// container when it is created, for logging setups that capture the
+	// output of the container through the attached streams
+	AttachStdout bool `json:"attachStdout"`
+	AttachStderr bool `json:"attachStderr"`
+	// RestartPolicy is the docker restart policy of the container, used by
+	// single container tasks to have docker restart the container when it
+	// fails instead of stopping the task
+	RestartPolicy *RestartPolicy `json:"restartPolicy,omitempty"`
+	// LogSecretOptions are log driver options whose values are secrets
+	// stored in SSM Parameter Store or Secrets Manager. They are resolved
+	// with the task role when the container is created and their values are
+	// never recorded
+	LogSecretOptions []LogSecretOption `json:"logSecretOptions,omitempty"`
+	// MemoryUnit is the unit of Memory, either MiB or GiB. Memory is in MiB
+	// if it's not set. Setting it requires a memory limit for the container
+	MemoryUnit string `json:"memoryUnit,omitempty"`
+	// StopTimeout is how long the container is given to exit once it is
+	// asked to stop, before it is killed. It is read from the StopTimeout of
+	// the docker config of the container. The stop timeout of the Agent is
+	// used when it's 0
+	StopTimeout time.Duration `json:"stopTimeout,omitempty"`
+	// CommandManifest is the path, relative to the command manifest directory
+	// of the Agent, of a file with the entry point and command of the
+	// container. It is read when the container is created
+	CommandManifest string `json:"commandManifest,omitempty"`
 
 	// lock is used for fields that are accessed and updated concurrently
 	lock sync.RWMutex
@@ -126,12 +174,59 @@ type Container struct {
 	knownExitCode     *int
 	KnownPortBindings []PortBinding
 
+	// exitHistory is a bounded list of the most recent exits of the container
+	exitHistory []ContainerExit
+	// lastRestart is the most recent restart of the container within its task
+	lastRestart *ContainerRestart
+	// restartCount is the number of times the container was restarted
+	restartCount int
+	// imagePullSkipReason is the reason the image of the container was not
+	// pulled, if the pull was skipped
+	imagePullSkipReason string
+	// startAttempts is the number of attempts made to start the container,
+	// including the one that started it
+	startAttempts int
+	// stopAttempts is the number of attempts made to gracefully stop the
+	// container
+	stopAttempts int
+	// killedOnStop is set when the container did not exit on the stop signal
+	// and had to be killed
+	killedOnStop bool
+	// startedAt is the time docker reports the container was started at
+	startedAt time.Time
+	// ipv4Address is the address docker assigned to the container on the
+	// network it is attached to, if any
+	ipv4Address string
+	// pullLockWaitStart is the time the agent started waiting on the image
+	// pull lock to pull the image of the container. It is the zero time
+	// when the agent is not waiting on the lock
+	pullLockWaitStart time.Time
+	// healthStatus is the health of the container as last reported by its
+	// docker health check
+	healthStatus ContainerHealthStatus
+	// readinessStatus is the readiness of the container as last determined
+	// by its readiness probe
+	readinessStatus ContainerReadinessStatus
+	// effectiveConfig and effectiveHostConfig are the redacted docker configs
+	// the container was created with
+	effectiveConfig     *docker.Config
+	effectiveHostConfig *docker.HostConfig
+
 	// SteadyStateStatusUnsafe specifies the steady state status for the container
 	// If uninitialized, it's assumed to be set to 'ContainerRunning'. Even though
 	// it's not only supposed to be set when the container is being created, it's
 	// exposed outside of the package so that it's marshalled/unmarshalled in the
 	// the JSON body while saving the state
 	SteadyStateStatusUnsafe *ContainerStatus `json:"SteadyStateStatus,omitempty"`
+
+	// ImagePullSourceUnsafe is where the image of the container came from
+	// when it was pulled, and ImagePullSourceReportedUnsafe whether it has
+	// been reported. They are exposed outside of the package so that each
+	// pull is reported once across restarts.
+	// NOTE: Do not access them directly. Instead, use `SetImagePullSource`,
+	// `GetImagePullSource` and `TakeImagePullSource`
+	ImagePullSourceUnsafe         ImagePullSource `json:"ImagePullSource,omitempty"`
+	ImagePullSourceReportedUnsafe bool            `json:"ImagePullSourceReported,omitempty"`
 }
 
 // DockerContainer is a mapping between containers-as-docker-knows-them and
@@ -246,6 +341,119 @@ func (c *Container) GetKnownExitCode() *int {
 	return c.knownExitCode
 }
 
+// SetImagePullSkipReason records why the image of the container was not pulled
+func (c *Container) SetImagePullSkipReason(reason string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.imagePullSkipReason = reason
+}
+
+// GetImagePullSkipReason returns why the image of the container was not
+// pulled. It returns an empty string if the image was pulled
+func (c *Container) GetImagePullSkipReason() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.imagePullSkipReason
+}
+
+// SetStartAttempts sets the number of attempts made to start the container
+func (c *Container) SetStartAttempts(attempts int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.startAttempts = attempts
+}
+
+// GetStartAttempts returns the number of attempts made to start the
+// container. It returns 0 if no attempt was made
+func (c *Container) GetStartAttempts() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.startAttempts
+}
+
+// IncrementStopAttempts records an attempt to gracefully stop the container
+// and returns the number of attempts made so far
+func (c *Container) IncrementStopAttempts() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.stopAttempts++
+	return c.stopAttempts
+}
+
+// GetStopAttempts returns the number of attempts made to gracefully stop the
+// container. It returns 0 if no attempt was made
+func (c *Container) GetStopAttempts() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.stopAttempts
+}
+
+// SetKilledOnStop records that the container did not exit on the stop signal
+// and had to be killed
+func (c *Container) SetKilledOnStop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.killedOnStop = true
+}
+
+// GetKilledOnStop returns true if the container did not exit on the stop
+// signal and had to be killed
+func (c *Container) GetKilledOnStop() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.killedOnStop
+}
+
+// SetStartedAt sets the time docker reports the container was started at
+func (c *Container) SetStartedAt(startedAt time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.startedAt = startedAt
+}
+
+// GetStartedAt returns the time docker reports the container was started at.
+// It returns the zero time if the container was not started
+func (c *Container) GetStartedAt() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.startedAt
+}
+
+// SetIPv4Address sets the address docker assigned to the container on the
+// network it is attached to
+func (c *Container) SetIPv4Address(address string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ipv4Address = address
+}
+
+// GetIPv4Address returns the address docker assigned to the container on the
+// network it is attached to. It returns an empty string if the container has
+// no address of its own, e.g. in the host or awsvpc network modes
+func (c *Container) GetIPv4Address() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.ipv4Address
+}
+
+// SetPullLockWaitStart sets the time the agent started waiting on the image
+// pull lock to pull the image of the container. The zero time indicates that
+// the agent is no longer waiting on the lock
+func (c *Container) SetPullLockWaitStart(waitStart time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.pullLockWaitStart = waitStart
+}
+
+// GetPullLockWaitStart returns the time the agent started waiting on the image
+// pull lock to pull the image of the container. It returns the zero time if
+// the agent is not waiting on the lock
+func (c *Container) GetPullLockWaitStart() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.pullLockWaitStart
+}
+
 // String returns a human readable string representation of this object
 func (c *Container) String() string {
 	ret := fmt.Sprintf("%s(%s) (%s->%s)", c.Name, c.Image,
@@ -302,6 +510,19 @@ func (c *Container) GetNextKnownStateProgression() ContainerStatus {
 	return c.GetKnownStatus() + 1
 }
 
+// GetMemoryInBytes returns the memory limit of the container in bytes, in
+// the unit of the container. It returns an error if the unit is unknown
+func (c *Container) GetMemoryInBytes() (int64, error) {
+	switch c.MemoryUnit {
+	case "", MemoryUnitMiB:
+		return int64(c.Memory) * 1024 * 1024, nil
+	case MemoryUnitGiB:
+		return int64(c.Memory) * 1024 * 1024 * 1024, nil
+	}
+	return 0, fmt.Errorf("unknown memory unit %s of container %s, expected %s or %s",
+		c.MemoryUnit, c.Name, MemoryUnitMiB, MemoryUnitGiB)
+}
+
 // IsInternal returns true if the container type is either `ContainerEmptyHostVolume`
 // or `ContainerCNIPause`. It returns false otherwise
 func (c *Container) IsInternal() bool {
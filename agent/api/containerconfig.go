@@ -0,0 +1,84 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// redactedValue replaces the values of environment variables and log driver
+// options in the effective docker config of a container, as they may hold
+// secrets
+const redactedValue = "[redacted]"
+
+// LogSecretOption is a log driver option whose value is resolved from a secret
+type LogSecretOption struct {
+	// Name is the name of the log driver option
+	Name string `json:"name"`
+	// ValueFrom is the ARN of the Secrets Manager secret, or the name or ARN
+	// of the SSM parameter, holding the value of the option
+	ValueFrom string `json:"valueFrom"`
+}
+
+// SetEffectiveConfig records the docker config and host config computed for
+// the container when it was created. Environment variable values and log
+// driver options are redacted before being recorded
+func (c *Container) SetEffectiveConfig(config *docker.Config, hostConfig *docker.HostConfig) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.effectiveConfig = redactDockerConfig(config)
+	c.effectiveHostConfig = redactDockerHostConfig(hostConfig)
+}
+
+// GetEffectiveConfig returns the redacted docker config and host config the
+// container was created with. It returns false if the container wasn't created
+// by this instance of the agent
+func (c *Container) GetEffectiveConfig() (*docker.Config, *docker.HostConfig, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.effectiveConfig == nil {
+		return nil, nil, false
+	}
+	return c.effectiveConfig, c.effectiveHostConfig, true
+}
+
+func redactDockerConfig(config *docker.Config) *docker.Config {
+	if config == nil {
+		return nil
+	}
+	redacted := *config
+	redacted.Env = make([]string, len(config.Env))
+	for i, env := range config.Env {
+		redacted.Env[i] = strings.SplitN(env, "=", 2)[0] + "=" + redactedValue
+	}
+	return &redacted
+}
+
+func redactDockerHostConfig(hostConfig *docker.HostConfig) *docker.HostConfig {
+	if hostConfig == nil {
+		return nil
+	}
+	redacted := *hostConfig
+	if len(hostConfig.LogConfig.Config) != 0 {
+		redacted.LogConfig.Config = make(map[string]string, len(hostConfig.LogConfig.Config))
+		for key := range hostConfig.LogConfig.Config {
+			redacted.LogConfig.Config[key] = redactedValue
+		}
+	}
+	return &redacted
+}
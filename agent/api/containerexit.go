@@ -0,0 +1,86 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import "time"
+
+// ContainerExitHistorySize is the maximum number of exits retained in the
+// exit history of a container. Older exits are discarded first.
+const ContainerExitHistorySize = 10
+
+// ContainerExit records a single exit of a container
+type ContainerExit struct {
+	// ExitCode is the exit code of the container, if known
+	ExitCode *int
+	// OOMKilled is set if the container was killed for exceeding its
+	// memory limit
+	OOMKilled bool
+	// DetectedByPoll is set if the exit was found by polling docker for the
+	// state of the container rather than reported by a docker event
+	DetectedByPoll bool
+	// Time is the time at which the exit was observed by the agent
+	Time time.Time
+}
+
+// exitCodeReasons describes exit codes with a well known meaning. Exit codes
+// above 128 are those of processes terminated by signal (exit code - 128)
+var exitCodeReasons = map[int]string{
+	125: "Docker failed to run the container",
+	126: "Container command could not be invoked",
+	127: "Container command not found",
+	130: "Container interrupted (SIGINT)",
+	134: "Container aborted (SIGABRT)",
+	137: "Container killed (SIGKILL)",
+	139: "Container crashed with a segmentation fault (SIGSEGV)",
+	143: "Container terminated (SIGTERM)",
+}
+
+// Reason returns a description of why the container exited, derived from its
+// exit code. It returns an empty string if the exit code is unknown or has no
+// well known meaning
+func (exit ContainerExit) Reason() string {
+	if exit.OOMKilled {
+		return "Container killed for exceeding its memory limit (OOM)"
+	}
+	if exit.ExitCode == nil {
+		return ""
+	}
+	return exitCodeReasons[*exit.ExitCode]
+}
+
+// RecordExit appends an exit to the exit history of the container, discarding
+// the oldest exit if the history is full
+func (c *Container) RecordExit(exit ContainerExit) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.exitHistory) >= ContainerExitHistorySize {
+		c.exitHistory = c.exitHistory[len(c.exitHistory)-ContainerExitHistorySize+1:]
+	}
+	c.exitHistory = append(c.exitHistory, exit)
+}
+
+// GetExitHistory returns a copy of the exit history of the container, ordered
+// from the oldest to the most recent exit
+func (c *Container) GetExitHistory() []ContainerExit {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if len(c.exitHistory) == 0 {
+		return nil
+	}
+	history := make([]ContainerExit, len(c.exitHistory))
+	copy(history, c.exitHistory)
+	return history
+}
@@ -0,0 +1,80 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordExit(t *testing.T) {
+	container := &Container{}
+	assert.Empty(t, container.GetExitHistory())
+
+	exitCode := 1
+	container.RecordExit(ContainerExit{ExitCode: &exitCode})
+	oomExitCode := 137
+	container.RecordExit(ContainerExit{ExitCode: &oomExitCode, OOMKilled: true})
+
+	history := container.GetExitHistory()
+	assert.Len(t, history, 2)
+	assert.Equal(t, 1, *history[0].ExitCode)
+	assert.False(t, history[0].OOMKilled)
+	assert.Equal(t, 137, *history[1].ExitCode)
+	assert.True(t, history[1].OOMKilled)
+}
+
+func TestRecordExitIsBounded(t *testing.T) {
+	container := &Container{}
+	for i := 0; i < ContainerExitHistorySize+5; i++ {
+		exitCode := i
+		container.RecordExit(ContainerExit{ExitCode: &exitCode})
+	}
+
+	history := container.GetExitHistory()
+	assert.Len(t, history, ContainerExitHistorySize)
+	// The oldest exits should have been discarded
+	assert.Equal(t, 5, *history[0].ExitCode)
+	assert.Equal(t, ContainerExitHistorySize+4, *history[ContainerExitHistorySize-1].ExitCode)
+}
+
+func TestGetExitHistoryReturnsCopy(t *testing.T) {
+	container := &Container{}
+	container.RecordExit(ContainerExit{OOMKilled: true})
+
+	history := container.GetExitHistory()
+	history[0].OOMKilled = false
+	assert.True(t, container.GetExitHistory()[0].OOMKilled)
+}
+
+func TestContainerExitReason(t *testing.T) {
+	exitCode := func(code int) *int { return &code }
+	testCases := []struct {
+		exit   ContainerExit
+		reason string
+	}{
+		{ContainerExit{ExitCode: exitCode(137)}, "Container killed (SIGKILL)"},
+		{ContainerExit{ExitCode: exitCode(143)}, "Container terminated (SIGTERM)"},
+		{ContainerExit{ExitCode: exitCode(139)}, "Container crashed with a segmentation fault (SIGSEGV)"},
+		{ContainerExit{ExitCode: exitCode(127)}, "Container command not found"},
+		{ContainerExit{ExitCode: exitCode(137), OOMKilled: true}, "Container killed for exceeding its memory limit (OOM)"},
+		{ContainerExit{ExitCode: exitCode(0)}, ""},
+		{ContainerExit{ExitCode: exitCode(1)}, ""},
+		{ContainerExit{}, ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.reason, tc.exit.Reason())
+	}
+}
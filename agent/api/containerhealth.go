@@ -0,0 +1,84 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+)
+
+// ContainerHealthStatus is the health of a container, as reported by its
+// docker health check
+type ContainerHealthStatus string
+
+const (
+	// ContainerHealthUnknown is the health of containers without a health
+	// check, or whose health check did not report yet
+	ContainerHealthUnknown ContainerHealthStatus = ""
+	// ContainerHealthy is the health of containers passing their health check
+	ContainerHealthy ContainerHealthStatus = "HEALTHY"
+	// ContainerUnhealthy is the health of containers failing their health
+	// check
+	ContainerUnhealthy ContainerHealthStatus = "UNHEALTHY"
+)
+
+// HealthCheck is the health check of a container in the task definition
+type HealthCheck struct {
+	// Command is the command docker runs to check the health of the
+	// container, starting with either CMD or CMD-SHELL
+	Command []string `json:"command"`
+	// Interval is the time between checks, in seconds
+	Interval int `json:"interval,omitempty"`
+	// Timeout is the time a check is given to complete, in seconds
+	Timeout int `json:"timeout,omitempty"`
+	// Retries is the number of consecutive failed checks after which the
+	// container is unhealthy
+	Retries int `json:"retries,omitempty"`
+}
+
+// dockerHealthConfig returns the docker health config of the health check.
+// Unset durations and retries use the defaults of docker
+func (healthCheck *HealthCheck) dockerHealthConfig() (*docker.HealthConfig, error) {
+	if len(healthCheck.Command) < 2 {
+		return nil, errors.New("health check command requires a type and a command")
+	}
+	if healthCheck.Command[0] != "CMD" && healthCheck.Command[0] != "CMD-SHELL" {
+		return nil, errors.Errorf("unsupported health check command type %s", healthCheck.Command[0])
+	}
+	if healthCheck.Interval < 0 || healthCheck.Timeout < 0 || healthCheck.Retries < 0 {
+		return nil, errors.New("health check interval, timeout and retries cannot be negative")
+	}
+	return &docker.HealthConfig{
+		Test:     healthCheck.Command,
+		Interval: time.Duration(healthCheck.Interval) * time.Second,
+		Timeout:  time.Duration(healthCheck.Timeout) * time.Second,
+		Retries:  healthCheck.Retries,
+	}, nil
+}
+
+// SetHealthStatus sets the health of the container
+func (c *Container) SetHealthStatus(health ContainerHealthStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.healthStatus = health
+}
+
+// GetHealthStatus returns the health of the container
+func (c *Container) GetHealthStatus() ContainerHealthStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.healthStatus
+}
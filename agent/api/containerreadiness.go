@@ -0,0 +1,110 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package api
+
+import "time"
+
+// ContainerReadinessStatus is the readiness of a container, as determined by
+// the readiness probe the Agent runs against it
+type ContainerReadinessStatus string
+
+const (
+	// ContainerReadinessUnknown is the readiness of containers without a
+	// readiness probe, or whose readiness probe did not succeed yet
+	ContainerReadinessUnknown ContainerReadinessStatus = ""
+	// ContainerReady is the readiness of containers whose readiness probe
+	// succeeded
+	ContainerReady ContainerReadinessStatus = "READY"
+	// ContainerNotReady is the readiness of containers whose readiness probe
+	// failed more times than it is retried. Such containers never become
+	// ready
+	ContainerNotReady ContainerReadinessStatus = "NOT_READY"
+)
+
+// ReadinessProbeType is the kind of check a readiness probe makes
+type ReadinessProbeType string
+
+const (
+	// ReadinessProbeTCP probes succeed when a connection to the port of the
+	// container can be established
+	ReadinessProbeTCP ReadinessProbeType = "tcp"
+	// ReadinessProbeHTTP probes succeed when a GET request to the path on the
+	// port of the container returns a 2xx or 3xx status
+	ReadinessProbeHTTP ReadinessProbeType = "http"
+)
+
+const (
+	defaultReadinessProbeInterval = 5 * time.Second
+	defaultReadinessProbeTimeout  = 2 * time.Second
+	defaultReadinessProbeRetries  = 3
+)
+
+// ReadinessProbe is a check the Agent runs against a running container to
+// determine when it is ready, so that containers depending on its readiness
+// are only started then
+type ReadinessProbe struct {
+	// Type is the kind of check the probe makes
+	Type ReadinessProbeType `json:"type"`
+	// Port is the port of the container that is probed
+	Port uint16 `json:"port"`
+	// Path is the path requested by http probes
+	Path string `json:"path,omitempty"`
+	// Interval is the time between checks, in seconds. It is 5 seconds if
+	// unset
+	Interval int `json:"interval,omitempty"`
+	// Timeout is the time a check is given to succeed, in seconds. It is 2
+	// seconds if unset
+	Timeout int `json:"timeout,omitempty"`
+	// Retries is the number of failed checks after which the container is
+	// not ready. It is 3 if unset
+	Retries int `json:"retries,omitempty"`
+}
+
+// IntervalDuration returns the time between the checks of the probe
+func (probe *ReadinessProbe) IntervalDuration() time.Duration {
+	if probe.Interval <= 0 {
+		return defaultReadinessProbeInterval
+	}
+	return time.Duration(probe.Interval) * time.Second
+}
+
+// TimeoutDuration returns the time a check of the probe is given to succeed
+func (probe *ReadinessProbe) TimeoutDuration() time.Duration {
+	if probe.Timeout <= 0 {
+		return defaultReadinessProbeTimeout
+	}
+	return time.Duration(probe.Timeout) * time.Second
+}
+
+// RetryCount returns the number of failed checks after which the container
+// is not ready
+func (probe *ReadinessProbe) RetryCount() int {
+	if probe.Retries <= 0 {
+		return defaultReadinessProbeRetries
+	}
+	return probe.Retries
+}
+
+// SetReadinessStatus sets the readiness of the container
+func (c *Container) SetReadinessStatus(readiness ContainerReadinessStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.readinessStatus = readiness
+}
+
+// GetReadinessStatus returns the readiness of the container
+func (c *Container) GetReadinessStatus() ContainerReadinessStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.readinessStatus
+}
@@ -0,0 +1,172 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+)
+
+// ContainerRestartReason is the cause of a restart of a container within its
+// task
+type ContainerRestartReason string
+
+const (
+	// ContainerRestartReasonExitCode is used for restarts caused by the
+	// container exiting
+	ContainerRestartReasonExitCode ContainerRestartReason = "ExitCode"
+	// ContainerRestartReasonOOM is used for restarts caused by the container
+	// being killed for exceeding its memory limit
+	ContainerRestartReasonOOM ContainerRestartReason = "OutOfMemory"
+	// ContainerRestartReasonHealthCheck is used for restarts caused by the
+	// container failing its health check
+	ContainerRestartReasonHealthCheck ContainerRestartReason = "HealthCheckFailed"
+)
+
+// RestartPolicyOnFailure is the docker restart policy which restarts the
+// container when it exits with a non-zero exit code. It is the only restart
+// policy supported for containers managed by the agent
+const RestartPolicyOnFailure = "on-failure"
+
+// RestartPolicy is the docker restart policy of a container
+type RestartPolicy struct {
+	// Name is the name of the restart policy
+	Name string `json:"name"`
+	// MaximumRetryCount is the maximum number of restarts of the container,
+	// with zero meaning that the container is restarted indefinitely
+	MaximumRetryCount int `json:"maximumRetryCount"`
+}
+
+// ContainerRestart records a single restart of a container
+type ContainerRestart struct {
+	// Reason is the cause of the restart
+	Reason ContainerRestartReason
+	// ExitCode is the exit code of the container, if the restart was caused
+	// by an exit with a known exit code
+	ExitCode *int
+	// Time is the time at which the container was restarted
+	Time time.Time
+}
+
+// NewContainerExitRestart returns the restart caused by an exit of the
+// container. Exits caused by the container running out of memory take
+// precedence over the exit code
+func NewContainerExitRestart(exit ContainerExit, t time.Time) ContainerRestart {
+	reason := ContainerRestartReasonExitCode
+	if exit.OOMKilled {
+		reason = ContainerRestartReasonOOM
+	}
+	return ContainerRestart{
+		Reason:   reason,
+		ExitCode: exit.ExitCode,
+		Time:     t,
+	}
+}
+
+// NewContainerHealthCheckRestart returns the restart caused by the container
+// failing its health check
+func NewContainerHealthCheckRestart(t time.Time) ContainerRestart {
+	return ContainerRestart{
+		Reason: ContainerRestartReasonHealthCheck,
+		Time:   t,
+	}
+}
+
+// RecordRestart records a restart of the container
+func (c *Container) RecordRestart(restart ContainerRestart) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.restartCount++
+	c.lastRestart = &restart
+}
+
+// GetLastRestart returns the most recent restart of the container. It returns
+// false if the container was never restarted
+func (c *Container) GetLastRestart() (ContainerRestart, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.lastRestart == nil {
+		return ContainerRestart{}, false
+	}
+	return *c.lastRestart, true
+}
+
+// GetRestartCount returns the number of times the container was restarted
+func (c *Container) GetRestartCount() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.restartCount
+}
+
+// ResetForRecreate resets the known state of a container whose docker
+// container was removed so that it is created again from scratch. The sent
+// status is kept so that states already reported are not reported again
+func (c *Container) ResetForRecreate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.KnownStatusUnsafe = ContainerStatusNone
+	c.AppliedStatus = ContainerStatusNone
+	c.ApplyingError = nil
+	c.knownExitCode = nil
+	c.KnownPortBindings = nil
+}
+
+// DockerRestartsOnExit returns true if docker is expected to restart the
+// container, according to its restart policy, after an exit with the given
+// exit code. Exits of containers that are being stopped by the agent are never
+// followed by a restart
+func (c *Container) DockerRestartsOnExit(exitCode *int) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.RestartPolicy == nil || c.RestartPolicy.Name != RestartPolicyOnFailure {
+		return false
+	}
+	if c.DesiredStatusUnsafe.Terminal() || exitCode == nil || *exitCode == 0 {
+		return false
+	}
+	return c.RestartPolicy.MaximumRetryCount == 0 || c.restartCount < c.RestartPolicy.MaximumRetryCount
+}
+
+// dockerRestartPolicy returns the docker restart policy of the container.
+// Restart policies are only supported for tasks with a single container, as
+// docker restarting a container behind the back of the agent would otherwise
+// break the ordering of the containers within the task
+func (task *Task) dockerRestartPolicy(container *Container) (docker.RestartPolicy, error) {
+	policy := container.RestartPolicy
+	if policy == nil {
+		return docker.RestartPolicy{}, nil
+	}
+	if policy.Name != RestartPolicyOnFailure {
+		return docker.RestartPolicy{}, errors.Errorf("unsupported restart policy %q for container %s",
+			policy.Name, container.Name)
+	}
+	if policy.MaximumRetryCount < 0 {
+		return docker.RestartPolicy{}, errors.Errorf("invalid maximum retry count %d for container %s",
+			policy.MaximumRetryCount, container.Name)
+	}
+	for _, other := range task.Containers {
+		if other.Name != container.Name && !other.IsInternal() {
+			return docker.RestartPolicy{}, errors.Errorf("restart policy of container %s is only supported for single container tasks",
+				container.Name)
+		}
+	}
+	return docker.RestartOnFailure(policy.MaximumRetryCount), nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordRestartExitCode(t *testing.T) {
+	container := &Container{}
+	_, ok := container.GetLastRestart()
+	assert.False(t, ok)
+
+	exitCode := 1
+	restartTime := time.Now()
+	container.RecordRestart(NewContainerExitRestart(ContainerExit{ExitCode: &exitCode}, restartTime))
+
+	restart, ok := container.GetLastRestart()
+	require.True(t, ok)
+	assert.Equal(t, ContainerRestartReasonExitCode, restart.Reason)
+	assert.Equal(t, exitCode, *restart.ExitCode)
+	assert.Equal(t, restartTime, restart.Time)
+	assert.Equal(t, 1, container.GetRestartCount())
+}
+
+func TestRecordRestartOOM(t *testing.T) {
+	container := &Container{}
+	exitCode := 1
+	container.RecordRestart(NewContainerExitRestart(ContainerExit{ExitCode: &exitCode}, time.Now()))
+	oomExitCode := 137
+	container.RecordRestart(NewContainerExitRestart(ContainerExit{ExitCode: &oomExitCode, OOMKilled: true}, time.Now()))
+
+	restart, ok := container.GetLastRestart()
+	require.True(t, ok)
+	assert.Equal(t, ContainerRestartReasonOOM, restart.Reason, "the OOM kill should take precedence over the exit code")
+	assert.Equal(t, oomExitCode, *restart.ExitCode)
+	assert.Equal(t, 2, container.GetRestartCount())
+}
+
+func TestRecordRestartHealthCheck(t *testing.T) {
+	container := &Container{}
+	container.RecordRestart(NewContainerHealthCheckRestart(time.Now()))
+
+	restart, ok := container.GetLastRestart()
+	require.True(t, ok)
+	assert.Equal(t, ContainerRestartReasonHealthCheck, restart.Reason)
+	assert.Nil(t, restart.ExitCode)
+}
+
+func TestDockerHostConfigRestartPolicy(t *testing.T) {
+	container := &Container{
+		Name:          "c1",
+		RestartPolicy: &RestartPolicy{Name: RestartPolicyOnFailure, MaximumRetryCount: 3},
+	}
+	testTask := &Task{Containers: []*Container{container}}
+
+	hostConfig, err := testTask.DockerHostConfig(container, dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "on-failure", hostConfig.RestartPolicy.Name)
+	assert.Equal(t, 3, hostConfig.RestartPolicy.MaximumRetryCount)
+
+	testTask.Containers = append(testTask.Containers, &Container{Name: "c2"})
+	_, err = testTask.DockerHostConfig(container, dockerMap(testTask))
+	assert.NotNil(t, err, "restart policies are only supported for single container tasks")
+
+	testTask.Containers = testTask.Containers[:1]
+	container.RestartPolicy.Name = "always"
+	_, err = testTask.DockerHostConfig(container, dockerMap(testTask))
+	assert.NotNil(t, err, "only the on-failure restart policy is supported")
+}
+
+func TestDockerRestartsOnExit(t *testing.T) {
+	container := &Container{
+		DesiredStatusUnsafe: ContainerRunning,
+		RestartPolicy:       &RestartPolicy{Name: RestartPolicyOnFailure, MaximumRetryCount: 1},
+	}
+	exitCode := 1
+	successExitCode := 0
+
+	assert.True(t, container.DockerRestartsOnExit(&exitCode))
+	assert.False(t, container.DockerRestartsOnExit(&successExitCode), "successful exits are not restarted")
+	assert.False(t, container.DockerRestartsOnExit(nil))
+
+	container.RecordRestart(NewContainerExitRestart(ContainerExit{ExitCode: &exitCode}, time.Now()))
+	assert.False(t, container.DockerRestartsOnExit(&exitCode), "maximum retry count has been reached")
+
+	container.RestartPolicy.MaximumRetryCount = 0
+	assert.True(t, container.DockerRestartsOnExit(&exitCode))
+
+	container.SetDesiredStatus(ContainerStopped)
+	assert.False(t, container.DockerRestartsOnExit(&exitCode), "containers stopped by the agent are not restarted")
+}
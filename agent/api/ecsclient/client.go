@@ -175,7 +175,7 @@ func (client *APIECSClient) registerContainerInstance(clusterRef string, contain
 	// Micro-optimization, the pointer to this is used multiple times below
 	integerStr := "INTEGER"
 
-	cpu, mem := getCpuAndMemory()
+	cpu, mem := GetCPUAndMemory()
 	remainingMem := mem - int64(client.config.ReservedMemory)
 	if remainingMem < 0 {
 		return "", fmt.Errorf(
@@ -254,7 +254,9 @@ func validateRegisteredAttributes(expectedAttributes, actualAttributes []*ecs.At
 	return err
 }
 
-func getCpuAndMemory() (int64, int64) {
+// GetCPUAndMemory returns the CPU of the instance in CPU units and its memory
+// in MiB, before the memory reserved for the host is taken out
+func GetCPUAndMemory() (int64, int64) {
 	memInfo, err := system.ReadMemInfo()
 	mem := int64(0)
 	if err == nil {
@@ -263,7 +265,7 @@ func getCpuAndMemory() (int64, int64) {
 		seelog.Errorf("Unable getting memory info: %v", err)
 	}
 
-	cpu := runtime.NumCPU() * 1024
+	cpu := runtime.NumCPU() * api.CPUUnitsPerVCPU
 
 	return int64(cpu), mem
 }
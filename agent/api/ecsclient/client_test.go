@@ -402,7 +402,7 @@ func TestRegisterContainerInstanceWithNegativeResource(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
-	_, mem := getCpuAndMemory()
+	_, mem := GetCPUAndMemory()
 	mockEC2Metadata := mock_ec2.NewMockEC2MetadataClient(mockCtrl)
 	client := NewECSClient(credentials.AnonymousCredentials,
 		&config.Config{Cluster: configuredCluster,
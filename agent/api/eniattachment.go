@@ -14,6 +14,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -35,10 +36,16 @@ type ENIAttachment struct {
 	MACAddress string `json:"macAddress"`
 	// Status is the status of the eni: none/attached/detached
 	Status ENIAttachmentStatus `json:"status"`
+	// ReceivedAt is the timestamp at which the ENI Attachment was received
+	// from ECS
+	ReceivedAt time.Time `json:"receivedAt"`
 	// ExpiresAt is the timestamp past which the ENI Attachment is considered
 	// unsuccessful. The SubmitTaskStateChange API, with the attachment information
 	// should be invoked before this timestamp.
 	ExpiresAt time.Time `json:"expiresAt"`
+	// AttachedAt is the timestamp at which the device of the ENI was
+	// confirmed on the instance by the ENI watcher
+	AttachedAt time.Time `json:"attachedAt"`
 	// ackTimer is used to register the expirtation timeout callback for unsuccessful
 	// ENI attachments
 	ackTimer ttime.Timer
@@ -82,6 +89,39 @@ func (eni *ENIAttachment) SetSentStatus() {
 	eni.AttachStatusSent = true
 }
 
+// HasExpired returns true if the ENI attachment has an expiration time and it
+// has passed
+func (eni *ENIAttachment) HasExpired() bool {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	return !eni.ExpiresAt.IsZero() && time.Now().After(eni.ExpiresAt)
+}
+
+// SetAttachedAt records the time at which the device of the ENI was confirmed
+// on the instance. Only the first confirmation is recorded
+func (eni *ENIAttachment) SetAttachedAt(attachedAt time.Time) {
+	eni.guard.Lock()
+	defer eni.guard.Unlock()
+
+	if eni.AttachedAt.IsZero() {
+		eni.AttachedAt = attachedAt
+	}
+}
+
+// GetReconciliationLag returns the time between the ENI attachment being
+// received and the device of the ENI being confirmed on the instance. It
+// returns false if the device has not been confirmed yet
+func (eni *ENIAttachment) GetReconciliationLag() (time.Duration, bool) {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	if eni.AttachedAt.IsZero() || eni.ReceivedAt.IsZero() {
+		return 0, false
+	}
+	return eni.AttachedAt.Sub(eni.ReceivedAt), true
+}
+
 // StopAckTimer stops the ack timer set on the ENI attachment
 func (eni *ENIAttachment) StopAckTimer() {
 	eni.guard.Lock()
@@ -90,6 +130,21 @@ func (eni *ENIAttachment) StopAckTimer() {
 	eni.ackTimer.Stop()
 }
 
+// eniAttachmentJSON has the fields of ENIAttachment without its methods, so
+// that it can be marshaled with the default encoding
+type eniAttachmentJSON ENIAttachment
+
+// MarshalJSON marshals the ENI attachment while holding its guard. The
+// attachment is saved with the state of the task engine, which can happen
+// while the attached status is being marked as sent; reading it under the
+// guard ensures that restored attachments are not acknowledged twice
+func (eni *ENIAttachment) MarshalJSON() ([]byte, error) {
+	eni.guard.RLock()
+	defer eni.guard.RUnlock()
+
+	return json.Marshal((*eniAttachmentJSON)(eni))
+}
+
 // String returns a string representation of the ENI Attachment
 func (eni *ENIAttachment) String() string {
 	eni.guard.RLock()
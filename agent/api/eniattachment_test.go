@@ -56,6 +56,18 @@ func TestMarshalUnmarshal(t *testing.T) {
 	assert.Equal(t, expectedExpiresAtUTC, unmarshalledExpiresAtUTC)
 }
 
+func TestMarshalAttachStatusSent(t *testing.T) {
+	attachment := &ENIAttachment{MACAddress: mac}
+	attachment.SetSentStatus()
+
+	bytes, err := json.Marshal(attachment)
+	assert.NoError(t, err)
+	var unmarshalledAttachment ENIAttachment
+	assert.NoError(t, json.Unmarshal(bytes, &unmarshalledAttachment))
+	assert.True(t, unmarshalledAttachment.IsSent())
+	assert.Equal(t, mac, unmarshalledAttachment.MACAddress)
+}
+
 func TestStartTimerErrorWhenExpiresAtIsInThePast(t *testing.T) {
 	expiresAt := time.Now().Unix() - 1
 	attachment := &ENIAttachment{
@@ -68,3 +80,22 @@ func TestStartTimerErrorWhenExpiresAtIsInThePast(t *testing.T) {
 	}
 	assert.Error(t, attachment.StartTimer(func() {}))
 }
+
+func TestENIAttachmentReconciliationLag(t *testing.T) {
+	receivedAt := time.Now()
+	attachment := &ENIAttachment{ReceivedAt: receivedAt}
+	_, ok := attachment.GetReconciliationLag()
+	assert.False(t, ok, "lag should not be known before the device is confirmed")
+
+	attachment.SetAttachedAt(receivedAt.Add(2 * time.Second))
+	attachment.SetAttachedAt(receivedAt.Add(5 * time.Second))
+	lag, ok := attachment.GetReconciliationLag()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, lag, "only the first confirmation should be recorded")
+}
+
+func TestENIAttachmentHasExpired(t *testing.T) {
+	assert.False(t, (&ENIAttachment{}).HasExpired(), "attachments without expiration should not expire")
+	assert.False(t, (&ENIAttachment{ExpiresAt: time.Now().Add(time.Minute)}).HasExpired())
+	assert.True(t, (&ENIAttachment{ExpiresAt: time.Now().Add(-time.Minute)}).HasExpired())
+}
@@ -33,6 +33,26 @@ func IsInstanceTypeChangedError(err error) bool {
 	return false
 }
 
+// IsInstanceNotFoundError returns true if the error of a call to the backend
+// is because the container instance the call refers to is not registered,
+// e.g. because it was deregistered out of band
+func IsInstanceNotFoundError(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	if awsErr.Code() != "ClientException" && awsErr.Code() != "InvalidParameterException" {
+		return false
+	}
+	message := strings.ToLower(awsErr.Message())
+	if !strings.Contains(message, "container instance") {
+		return false
+	}
+	return strings.Contains(message, "not found") ||
+		strings.Contains(message, "inactive") ||
+		strings.Contains(message, "deregistered")
+}
+
 type badVolumeError struct {
 	msg string
 }
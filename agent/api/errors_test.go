@@ -0,0 +1,34 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInstanceNotFoundError(t *testing.T) {
+	assert.True(t, IsInstanceNotFoundError(
+		awserr.New("ClientException", "The referenced container instance was not found.", nil)))
+	assert.True(t, IsInstanceNotFoundError(
+		awserr.New("InvalidParameterException", "Container instance is inactive", nil)))
+	assert.False(t, IsInstanceNotFoundError(
+		awserr.New("ClientException", "Task not found", nil)))
+	assert.False(t, IsInstanceNotFoundError(
+		awserr.New("ServerException", "Container instance not found", nil)))
+	assert.False(t, IsInstanceNotFoundError(errors.New("container instance not found")))
+}
@@ -0,0 +1,73 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package api
+
+// ImagePullSource classifies where the image of a container came from when
+// the agent pulled it
+type ImagePullSource string
+
+const (
+	// ImagePullSourceCacheHit indicates that the image was already present
+	// on the instance, either because the pull was skipped or because docker
+	// found every layer cached
+	ImagePullSourceCacheHit ImagePullSource = "CacheHit"
+	// ImagePullSourcePartial indicates that some layers of the image were
+	// already present and the rest were pulled from the registry
+	ImagePullSourcePartial ImagePullSource = "Partial"
+	// ImagePullSourceRegistry indicates that every layer of the image was
+	// pulled from the registry
+	ImagePullSourceRegistry ImagePullSource = "Registry"
+)
+
+// ImagePullSourceFromLayers classifies a pull from the number of layers
+// docker reported as already present and the number it pulled
+func ImagePullSourceFromLayers(cachedLayers, pulledLayers int) ImagePullSource {
+	switch {
+	case pulledLayers == 0:
+		return ImagePullSourceCacheHit
+	case cachedLayers > 0:
+		return ImagePullSourcePartial
+	default:
+		return ImagePullSourceRegistry
+	}
+}
+
+// SetImagePullSource records where the image of the container came from
+// when it was pulled, which is reported once
+func (c *Container) SetImagePullSource(source ImagePullSource) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ImagePullSourceUnsafe = source
+	c.ImagePullSourceReportedUnsafe = false
+}
+
+// GetImagePullSource returns where the image of the container came from. It
+// returns an empty source if the image has not been pulled
+func (c *Container) GetImagePullSource() ImagePullSource {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.ImagePullSourceUnsafe
+}
+
+// TakeImagePullSource returns where the image of the container came from the
+// first time it is called after the image is pulled, so that each pull is
+// reported once. It returns an empty source otherwise
+func (c *Container) TakeImagePullSource() ImagePullSource {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.ImagePullSourceReportedUnsafe {
+		return ""
+	}
+	c.ImagePullSourceReportedUnsafe = c.ImagePullSourceUnsafe != ""
+	return c.ImagePullSourceUnsafe
+}
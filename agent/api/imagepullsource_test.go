@@ -0,0 +1,57 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeImagePullSourceOnce(t *testing.T) {
+	container := &Container{}
+	assert.Equal(t, ImagePullSource(""), container.TakeImagePullSource(), "no image has been pulled")
+
+	container.SetImagePullSource(ImagePullSourceRegistry)
+	assert.Equal(t, ImagePullSourceRegistry, container.TakeImagePullSource())
+	assert.Equal(t, ImagePullSource(""), container.TakeImagePullSource(), "the pull should only be taken once")
+	assert.Equal(t, ImagePullSourceRegistry, container.GetImagePullSource())
+
+	container.SetImagePullSource(ImagePullSourceCacheHit)
+	assert.Equal(t, ImagePullSourceCacheHit, container.TakeImagePullSource(), "a new pull should be taken")
+}
+
+func TestImagePullSourcePersisted(t *testing.T) {
+	container := &Container{}
+	container.SetImagePullSource(ImagePullSourcePartial)
+	container.TakeImagePullSource()
+	pending := &Container{}
+	pending.SetImagePullSource(ImagePullSourceRegistry)
+
+	for _, tc := range []struct {
+		container *Container
+		take      ImagePullSource
+	}{
+		{container, ""},
+		{pending, ImagePullSourceRegistry},
+	} {
+		data, err := json.Marshal(tc.container)
+		require.NoError(t, err)
+		var restored Container
+		require.NoError(t, json.Unmarshal(data, &restored))
+		assert.Equal(t, tc.container.GetImagePullSource(), restored.GetImagePullSource())
+		assert.Equal(t, tc.take, restored.TakeImagePullSource())
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MountType is the type of a container mount
+type MountType string
+
+const (
+	// MountTypeBind mounts a path of the host into the container
+	MountTypeBind MountType = "bind"
+	// MountTypeVolume mounts a named Docker volume into the container
+	MountTypeVolume MountType = "volume"
+	// MountTypeTmpfs mounts a tmpfs filesystem into the container
+	MountTypeTmpfs MountType = "tmpfs"
+)
+
+// bindPropagations are the valid propagation modes of bind mounts
+var bindPropagations = map[string]struct{}{
+	"private":  {},
+	"rprivate": {},
+	"shared":   {},
+	"rshared":  {},
+	"slave":    {},
+	"rslave":   {},
+}
+
+// Mount is a mount of a container, of any of the supported mount types. Only
+// the options of the type of the mount may be set
+type Mount struct {
+	Type MountType `json:"type"`
+	// Source is the path on the host for bind mounts and the name of the
+	// volume for volume mounts. It must be empty for tmpfs mounts
+	Source string `json:"source"`
+	// Target is the path in the container
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"readOnly"`
+
+	BindOptions   *BindOptions   `json:"bindOptions"`
+	VolumeOptions *VolumeOptions `json:"volumeOptions"`
+	TmpfsOptions  *TmpfsOptions  `json:"tmpfsOptions"`
+}
+
+// BindOptions are the options of bind mounts
+type BindOptions struct {
+	// Propagation is the mount propagation mode, such as 'rshared'
+	Propagation string `json:"propagation"`
+}
+
+// VolumeOptions are the options of volume mounts
+type VolumeOptions struct {
+	// NoCopy disables copying the data at the target in the image into an
+	// empty volume
+	NoCopy bool `json:"noCopy"`
+}
+
+// TmpfsOptions are the options of tmpfs mounts
+type TmpfsOptions struct {
+	// SizeBytes is the size of the tmpfs filesystem. It is unlimited if unset
+	SizeBytes int64 `json:"sizeBytes"`
+	// Mode is the file mode of the tmpfs filesystem, such as 01777
+	Mode uint32 `json:"mode"`
+}
+
+// validate returns an error if the mount is missing fields required by its
+// type or sets options of another type
+func (mount *Mount) validate() error {
+	if mount.Target == "" {
+		return errors.Errorf("%s mount has no target", mount.Type)
+	}
+	switch mount.Type {
+	case MountTypeBind, MountTypeVolume:
+		if mount.Source == "" {
+			return errors.Errorf("%s mount of %s has no source", mount.Type, mount.Target)
+		}
+	case MountTypeTmpfs:
+		if mount.Source != "" {
+			return errors.Errorf("tmpfs mount of %s can't have a source", mount.Target)
+		}
+	default:
+		return errors.Errorf("mount of %s has unsupported type: %s", mount.Target, mount.Type)
+	}
+	if mount.BindOptions != nil && mount.Type != MountTypeBind {
+		return errors.Errorf("%s mount of %s can't have bind options", mount.Type, mount.Target)
+	}
+	if mount.VolumeOptions != nil && mount.Type != MountTypeVolume {
+		return errors.Errorf("%s mount of %s can't have volume options", mount.Type, mount.Target)
+	}
+	if mount.TmpfsOptions != nil && mount.Type != MountTypeTmpfs {
+		return errors.Errorf("%s mount of %s can't have tmpfs options", mount.Type, mount.Target)
+	}
+	if mount.BindOptions != nil && mount.BindOptions.Propagation != "" {
+		if _, ok := bindPropagations[mount.BindOptions.Propagation]; !ok {
+			return errors.Errorf("bind mount of %s has invalid propagation: %s", mount.Target, mount.BindOptions.Propagation)
+		}
+	}
+	if mount.TmpfsOptions != nil && mount.TmpfsOptions.SizeBytes < 0 {
+		return errors.Errorf("tmpfs mount of %s has negative size: %d", mount.Target, mount.TmpfsOptions.SizeBytes)
+	}
+	return nil
+}
+
+// dockerBind returns the bind of a bind or volume mount, in the
+// 'source:target[:options]' format of the Docker host config
+func (mount *Mount) dockerBind() string {
+	var options []string
+	if mount.ReadOnly {
+		options = append(options, "ro")
+	}
+	if mount.BindOptions != nil && mount.BindOptions.Propagation != "" {
+		options = append(options, mount.BindOptions.Propagation)
+	}
+	if mount.VolumeOptions != nil && mount.VolumeOptions.NoCopy {
+		options = append(options, "nocopy")
+	}
+	bind := mount.Source + ":" + mount.Target
+	if len(options) > 0 {
+		bind += ":" + strings.Join(options, ",")
+	}
+	return bind
+}
+
+// dockerTmpfsOptions returns the options of a tmpfs mount, in the format of
+// the Docker host config
+func (mount *Mount) dockerTmpfsOptions() string {
+	var options []string
+	if mount.ReadOnly {
+		options = append(options, "ro")
+	}
+	if mount.TmpfsOptions != nil {
+		if mount.TmpfsOptions.SizeBytes > 0 {
+			options = append(options, "size="+strconv.FormatInt(mount.TmpfsOptions.SizeBytes, 10))
+		}
+		if mount.TmpfsOptions.Mode != 0 {
+			options = append(options, "mode="+strconv.FormatUint(uint64(mount.TmpfsOptions.Mode), 8))
+		}
+	}
+	return strings.Join(options, ",")
+}
+
+// dockerMounts translates the mounts of the container into the binds and the
+// tmpfs mounts of the Docker host config. It returns an error if a mount is
+// invalid or if several mounts share a target
+func dockerMounts(container *Container) ([]string, map[string]string, error) {
+	var binds []string
+	var tmpfs map[string]string
+	targets := make(map[string]struct{}, len(container.Mounts))
+	for i := range container.Mounts {
+		mount := &container.Mounts[i]
+		if err := mount.validate(); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid mount for container %s", container.Name)
+		}
+		if _, ok := targets[mount.Target]; ok {
+			return nil, nil, errors.Errorf("invalid mount for container %s: several mounts target %s", container.Name, mount.Target)
+		}
+		targets[mount.Target] = struct{}{}
+
+		if mount.Type == MountTypeTmpfs {
+			if tmpfs == nil {
+				tmpfs = make(map[string]string)
+			}
+			tmpfs[mount.Target] = mount.dockerTmpfsOptions()
+			continue
+		}
+		binds = append(binds, mount.dockerBind())
+	}
+	return binds, tmpfs, nil
+}
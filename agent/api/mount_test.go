@@ -0,0 +1,123 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mountTask(mounts ...Mount) *Task {
+	return &Task{
+		Arn: "arn",
+		Containers: []*Container{
+			{
+				Name:   "c1",
+				Mounts: mounts,
+			},
+		},
+	}
+}
+
+func TestDockerHostConfigBindMount(t *testing.T) {
+	task := mountTask(
+		Mount{Type: MountTypeBind, Source: "/host/data", Target: "/data"},
+		Mount{
+			Type:        MountTypeBind,
+			Source:      "/host/config",
+			Target:      "/config",
+			ReadOnly:    true,
+			BindOptions: &BindOptions{Propagation: "rslave"},
+		},
+	)
+
+	hostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"/host/data:/data", "/host/config:/config:ro,rslave"}, hostConfig.Binds)
+	assert.Empty(t, hostConfig.Tmpfs)
+}
+
+func TestDockerHostConfigVolumeMount(t *testing.T) {
+	task := mountTask(
+		Mount{Type: MountTypeVolume, Source: "cache", Target: "/cache"},
+		Mount{
+			Type:          MountTypeVolume,
+			Source:        "seed",
+			Target:        "/seed",
+			VolumeOptions: &VolumeOptions{NoCopy: true},
+		},
+	)
+
+	hostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"cache:/cache", "seed:/seed:nocopy"}, hostConfig.Binds)
+}
+
+func TestDockerHostConfigTmpfsMount(t *testing.T) {
+	task := mountTask(
+		Mount{Type: MountTypeTmpfs, Target: "/tmp"},
+		Mount{
+			Type:         MountTypeTmpfs,
+			Target:       "/run",
+			ReadOnly:     true,
+			TmpfsOptions: &TmpfsOptions{SizeBytes: 65536, Mode: 01777},
+		},
+	)
+
+	hostConfig, err := task.DockerHostConfig(task.Containers[0], dockerMap(task))
+	require.Nil(t, err)
+	assert.Empty(t, hostConfig.Binds)
+	assert.Equal(t, map[string]string{
+		"/tmp": "",
+		"/run": "ro,size=65536,mode=1777",
+	}, hostConfig.Tmpfs)
+}
+
+func TestDockerHostConfigInvalidMounts(t *testing.T) {
+	testCases := []struct {
+		name  string
+		mount Mount
+	}{
+		{"no target", Mount{Type: MountTypeBind, Source: "/host"}},
+		{"bind without source", Mount{Type: MountTypeBind, Target: "/data"}},
+		{"volume without source", Mount{Type: MountTypeVolume, Target: "/data"}},
+		{"tmpfs with source", Mount{Type: MountTypeTmpfs, Source: "/host", Target: "/data"}},
+		{"unknown type", Mount{Type: "npipe", Source: "/host", Target: "/data"}},
+		{"bind options on volume", Mount{Type: MountTypeVolume, Source: "v", Target: "/data", BindOptions: &BindOptions{}}},
+		{"volume options on tmpfs", Mount{Type: MountTypeTmpfs, Target: "/data", VolumeOptions: &VolumeOptions{}}},
+		{"tmpfs options on bind", Mount{Type: MountTypeBind, Source: "/host", Target: "/data", TmpfsOptions: &TmpfsOptions{}}},
+		{"invalid propagation", Mount{Type: MountTypeBind, Source: "/host", Target: "/data", BindOptions: &BindOptions{Propagation: "bogus"}}},
+		{"negative tmpfs size", Mount{Type: MountTypeTmpfs, Target: "/data", TmpfsOptions: &TmpfsOptions{SizeBytes: -1}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := mountTask(tc.mount)
+			_, err := task.DockerHostConfig(task.Containers[0], dockerMap(task))
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestDockerHostConfigDuplicateMountTargets(t *testing.T) {
+	task := mountTask(
+		Mount{Type: MountTypeBind, Source: "/host/data", Target: "/data"},
+		Mount{Type: MountTypeTmpfs, Target: "/data"},
+	)
+
+	_, err := task.DockerHostConfig(task.Containers[0], dockerMap(task))
+	assert.NotNil(t, err)
+}
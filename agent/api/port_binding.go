@@ -36,6 +36,15 @@ type PortBinding struct {
 	Protocol TransportProtocol
 }
 
+// ExposedPort represents a port that a container exposes without publishing it
+// on the host
+type ExposedPort struct {
+	// ContainerPort is the port inside the container
+	ContainerPort uint16 `json:"containerPort"`
+	// Protocol is the protocol of the port
+	Protocol TransportProtocol `json:"protocol"`
+}
+
 // PortBindingFromDockerPortBinding constructs a PortBinding slice from a docker
 // NetworkSettings.Ports map.
 func PortBindingFromDockerPortBinding(dockerPortBindings map[docker.Port][]docker.PortBinding) ([]PortBinding, NamedError) {
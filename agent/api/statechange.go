@@ -37,6 +37,12 @@ type ContainerStateChange struct {
 	// PortBindings are the details of the host ports picked for the specified
 	// container ports
 	PortBindings []PortBinding
+	// StartAttempts is the number of attempts made to start the container.
+	// It is greater than 1 if starting the container had to be retried
+	StartAttempts int
+	// Health is the health of the container as reported by its docker
+	// health check, if any
+	Health ContainerHealthStatus
 
 	// Container is a pointer to the container involved in the state change that gives the event handler a hook into
 	// storing what status was sent.  This is used to ensure the same event is handled only once.
@@ -74,6 +80,12 @@ func (c *ContainerStateChange) String() string {
 	if len(c.PortBindings) != 0 {
 		res += fmt.Sprintf(", Ports %v", c.PortBindings)
 	}
+	if c.StartAttempts > 1 {
+		res += fmt.Sprintf(", Start attempts %d", c.StartAttempts)
+	}
+	if c.Health != ContainerHealthUnknown {
+		res += ", Health " + string(c.Health)
+	}
 	if c.Container != nil {
 		res += ", Known Sent: " + c.Container.GetSentStatus().String()
 	}
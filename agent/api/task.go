@@ -38,22 +38,46 @@ const (
 	// PauseContainerName is the internal name for the pause container
 	PauseContainerName = "~internal~ecs~pause"
 
+	// TaskBlockedOnPauseImage is the blocking reason of tasks whose pause
+	// container cannot be created until the pause image is pulled
+	TaskBlockedOnPauseImage = "waiting for pause image"
+
 	emptyHostVolumeName = "~internal~ecs-emptyvolume-source"
 
 	// awsSDKCredentialsRelativeURIPathEnvironmentVariableName defines the name of the environment
 	// variable containers' config, which will be used by the AWS SDK to fetch
 	// credentials.
 	awsSDKCredentialsRelativeURIPathEnvironmentVariableName = "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"
+	// awsSDKCredentialsFullURIEnvironmentVariableName defines the name of the
+	// environment variable holding the full URI of the credentials endpoint,
+	// used for containers that can't reach the credentials endpoint through
+	// its default address
+	awsSDKCredentialsFullURIEnvironmentVariableName = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	// networkModeHost specifies the string used to define the `host` docker
+	// networking mode
+	networkModeHost = "host"
 	// networkModeNone specifies the string used to define the `none` docker networking mode
 	networkModeNone = "none"
+	// networkModeAWSVPC is the network mode reported for the containers of
+	// tasks with an ENI
+	networkModeAWSVPC = "awsvpc"
 	// networkModeContainerPrefix specifies the prefix string used for setting the
 	// container's network mode to be mapped to that of another existing container
 	networkModeContainerPrefix = "container:"
+	// logDriverNone specifies the log driver that discards the output of
+	// containers
+	logDriverNone = "none"
 )
 
 // TaskOverrides are the overrides applied to a task
 type TaskOverrides struct{}
 
+// TaskTag is a resource tag of a task
+type TaskTag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
 // Task is the internal representation of a task in the ECS agent
 type Task struct {
 	// Arn is the unique identifer for the task
@@ -68,6 +92,30 @@ type Task struct {
 	Containers []*Container
 	// Volumes are the volumes for the task
 	Volumes []TaskVolume `json:"volumes"`
+	// Priority determines the order in which the containers of pending tasks
+	// are created when container creation is constrained. Tasks with a higher
+	// priority go first
+	Priority int
+	// CPU is the number of vCPUs the containers of the task can use in
+	// total. It is enforced by the parent cgroup of the task when task
+	// limits are enabled
+	CPU float64 `json:"cpu,omitempty"`
+	// Memory is the memory, in MiB, the containers of the task can use in
+	// total. It is enforced by the parent cgroup of the task when task
+	// limits are enabled
+	Memory int64 `json:"memory,omitempty"`
+	// PIDMode is the PID namespace of the containers of the task. With
+	// PIDModeTask, the containers share one PID namespace; with PIDModeHost,
+	// they use the PID namespace of the host
+	PIDMode string `json:"pidMode,omitempty"`
+	// IPCMode is the IPC namespace of the containers of the task. With
+	// IPCModeTask, the containers share one IPC namespace; with IPCModeHost,
+	// they use the IPC namespace of the host; with IPCModeNone, each of them
+	// has a private IPC namespace
+	IPCMode string `json:"ipcMode,omitempty"`
+	// Tags are the resource tags of the task, which are attached to the
+	// metrics of its containers
+	Tags []TaskTag `json:"tags"`
 
 	// DesiredStatusUnsafe represents the state where the task should go. Generally,
 	// the desired status is informed by the ECS backend as a result of either
@@ -115,20 +163,78 @@ type Task struct {
 	credentialsIDLock sync.RWMutex
 
 	// ENI is the elastic network interface specified by this task
-	ENI     *ENI
-	eniLock sync.RWMutex
+	ENI *ENI
+	// pauseContainerPID is the pid of the pause container whose network
+	// namespace was set up for the ENI of the task. It is guarded by the
+	// eniLock
+	pauseContainerPID string
+	eniLock           sync.RWMutex
+
+	// launchTimes records when the task reached each of its launch
+	// milestones, for reporting launch latency. launchLatencyTaken is set
+	// once the launch latency has been taken for reporting
+	launchTimes        map[TaskLaunchMilestone]time.Time
+	launchLatencyTaken bool
+	launchTimesLock    sync.RWMutex
+
+	// stopStartedAt is when the engine started stopping the containers of
+	// the task, for spending the stop budget of the task
+	stopStartedAt     time.Time
+	stopStartedAtLock sync.Mutex
+
+	// stopRequestedAt is when the desired status of the task became stopped,
+	// for reporting how long the task took to stop. It is guarded by the
+	// desiredStatusLock
+	stopRequestedAt time.Time
+	// stoppedByContainer is the name of the essential container whose exit
+	// caused the task to stop, if any. It is guarded by the desiredStatusLock
+	stoppedByContainer string
+
+	// blockedReason describes what keeps the containers of the task from
+	// progressing, if anything
+	blockedReason     string
+	blockedReasonLock sync.RWMutex
+
+	// timeline is a bounded list of the significant events in the
+	// lifecycle of the task, for post-mortem debugging
+	timeline     []TaskEvent
+	timelineLock sync.RWMutex
 }
 
 // PostUnmarshalTask is run after a task has been unmarshalled, but before it has been
 // run. It is possible it will be subsequently called after that and should be
 // able to handle such an occurrence appropriately (e.g. behave idempotently).
-func (task *Task) PostUnmarshalTask(cfg *config.Config, credentialsManager credentials.Manager) {
+// It returns an error if the task can't be run as defined
+func (task *Task) PostUnmarshalTask(cfg *config.Config, credentialsManager credentials.Manager) error {
 	// TODO, add rudimentary plugin support and call any plugins that want to
 	// hook into this
 	task.adjustForPlatform()
 	task.initializeEmptyVolumes()
-	task.initializeCredentialsEndpoint(credentialsManager)
+	task.initializeCredentialsEndpoint(cfg, credentialsManager)
 	task.addNetworkResourceProvisioningDependency(cfg)
+	task.addNamespaceSourceDependency()
+	task.initializeStopTimeouts()
+	return task.validateNamespaceModes()
+}
+
+// initializeStopTimeouts sets the stop timeouts of the containers that ask
+// for one, in seconds, with the StopTimeout of their docker config
+func (task *Task) initializeStopTimeouts() {
+	for _, container := range task.Containers {
+		if container.DockerConfig.Config == nil {
+			continue
+		}
+		var config struct {
+			StopTimeout *int
+		}
+		if err := json.Unmarshal([]byte(*container.DockerConfig.Config), &config); err != nil {
+			// The docker config is rejected when the container is created
+			continue
+		}
+		if config.StopTimeout != nil && *config.StopTimeout > 0 {
+			container.StopTimeout = time.Duration(*config.StopTimeout) * time.Second
+		}
+	}
 }
 
 func (task *Task) initializeEmptyVolumes() {
@@ -182,7 +288,9 @@ func (task *Task) initializeEmptyVolumes() {
 }
 
 // initializeCredentialsEndpoint sets the credentials endpoint for all containers in a task if needed.
-func (task *Task) initializeCredentialsEndpoint(credentialsManager credentials.Manager) {
+// Containers in the host network mode are given the full URI of the credentials
+// endpoint if a host network credentials endpoint is configured.
+func (task *Task) initializeCredentialsEndpoint(cfg *config.Config, credentialsManager credentials.Manager) {
 	id := task.GetCredentialsID()
 	if id == "" {
 		// No credentials set for the task. Do not inject the endpoint environment variable.
@@ -206,6 +314,10 @@ func (task *Task) initializeCredentialsEndpoint(credentialsManager credentials.M
 		if container.Environment == nil {
 			container.Environment = make(map[string]string)
 		}
+		if cfg != nil && cfg.HostNetworkCredentialsEndpoint != "" && task.ContainerNetworkMode(container) == networkModeHost {
+			container.Environment[awsSDKCredentialsFullURIEnvironmentVariableName] = cfg.HostNetworkCredentialsEndpoint + credentialsEndpointRelativeURI
+			continue
+		}
 		container.Environment[awsSDKCredentialsRelativeURIPathEnvironmentVariableName] = credentialsEndpointRelativeURI
 	}
 
@@ -416,8 +528,10 @@ func (task *Task) dockerConfig(container *Container) (*docker.Config, *DockerCli
 		dockerEnv = append(dockerEnv, envKey+"="+envVal)
 	}
 
-	// Convert MB to B
-	dockerMem := int64(container.Memory * 1024 * 1024)
+	dockerMem, err := container.GetMemoryInBytes()
+	if err != nil {
+		return nil, &DockerClientConfigError{err.Error()}
+	}
 	if dockerMem != 0 && dockerMem < DockerContainerMinimumMemoryInBytes {
 		dockerMem = DockerContainerMinimumMemoryInBytes
 	}
@@ -444,13 +558,58 @@ func (task *Task) dockerConfig(container *Container) (*docker.Config, *DockerCli
 			return nil, &DockerClientConfigError{"Unable decode given docker config: " + err.Error()}
 		}
 	}
+	if config.Memory < 0 {
+		return nil, &DockerClientConfigError{fmt.Sprintf("invalid memory of container %s: %d bytes", container.Name, config.Memory)}
+	}
+	if container.MemoryUnit != "" && config.Memory == 0 {
+		return nil, &DockerClientConfigError{fmt.Sprintf("memory unit %s of container %s requires a memory limit", container.MemoryUnit, container.Name)}
+	}
 	if config.Labels == nil {
 		config.Labels = make(map[string]string)
 	}
+	if container.DisableHealthcheck && container.HealthCheck != nil {
+		return nil, &DockerClientConfigError{fmt.Sprintf("container %s cannot both disable and define a health check", container.Name)}
+	}
+	if container.DisableHealthcheck {
+		// A test of NONE disables the healthcheck inherited from the image
+		config.Healthcheck = &docker.HealthConfig{Test: []string{"NONE"}}
+	}
+	if container.HealthCheck != nil {
+		healthConfig, err := container.HealthCheck.dockerHealthConfig()
+		if err != nil {
+			return nil, &DockerClientConfigError{fmt.Sprintf("invalid health check of container %s: %v", container.Name, err)}
+		}
+		config.Healthcheck = healthConfig
+	}
+	if container.AttachStdout || container.AttachStderr {
+		if err := validateAttachLogDriver(container); err != nil {
+			return nil, &DockerClientConfigError{err.Error()}
+		}
+		config.AttachStdout = container.AttachStdout
+		config.AttachStderr = container.AttachStderr
+	}
 
 	return config, nil
 }
 
+// validateAttachLogDriver returns an error if the log driver of the container
+// discards the output that attaching the stdout or stderr would capture
+func validateAttachLogDriver(container *Container) error {
+	if container.DockerConfig.HostConfig == nil {
+		return nil
+	}
+	hostConfig := &docker.HostConfig{}
+	err := json.Unmarshal([]byte(*container.DockerConfig.HostConfig), hostConfig)
+	if err != nil {
+		return errors.Errorf("unable to decode given host config: %v", err)
+	}
+	if hostConfig.LogConfig.Type == logDriverNone {
+		return errors.Errorf("unable to attach stdout or stderr of container %s with the %s log driver",
+			container.Name, logDriverNone)
+	}
+	return nil
+}
+
 // dockerCPUShares converts containerCPU shares if needed as per the logic stated below:
 // Docker silently converts 0 to 1024 CPU shares, which is probably not what we
 // want.  Instead, we convert 0 to 2 to be closer to expected behavior. The
@@ -472,6 +631,11 @@ func (task *Task) dockerExposedPorts(container *Container) map[docker.Port]struc
 		dockerPort := docker.Port(strconv.Itoa(int(portBinding.ContainerPort)) + "/" + portBinding.Protocol.String())
 		dockerExposedPorts[dockerPort] = struct{}{}
 	}
+	// Exposed ports have no host bindings, so they only go into the config
+	for _, exposedPort := range container.ExposedPorts {
+		dockerPort := docker.Port(strconv.Itoa(int(exposedPort.ContainerPort)) + "/" + exposedPort.Protocol.String())
+		dockerExposedPorts[dockerPort] = struct{}{}
+	}
 	return dockerExposedPorts
 }
 
@@ -520,11 +684,24 @@ func (task *Task) dockerHostConfig(container *Container, dockerContainerMap map[
 		return nil, &HostConfigError{err.Error()}
 	}
 
+	mountBinds, tmpfs, err := dockerMounts(container)
+	if err != nil {
+		return nil, &HostConfigError{err.Error()}
+	}
+	binds = append(binds, mountBinds...)
+
+	restartPolicy, err := task.dockerRestartPolicy(container)
+	if err != nil {
+		return nil, &HostConfigError{err.Error()}
+	}
+
 	hostConfig := &docker.HostConfig{
-		Links:        dockerLinkArr,
-		Binds:        binds,
-		PortBindings: dockerPortMap,
-		VolumesFrom:  volumesFrom,
+		Links:         dockerLinkArr,
+		Binds:         binds,
+		PortBindings:  dockerPortMap,
+		VolumesFrom:   volumesFrom,
+		Tmpfs:         tmpfs,
+		RestartPolicy: restartPolicy,
 	}
 
 	if container.DockerConfig.HostConfig != nil {
@@ -538,10 +715,24 @@ func (task *Task) dockerHostConfig(container *Container, dockerContainerMap map[
 
 	// Determine if network mode should be overridden and override it if needed
 	ok, networkMode := task.shouldOverrideNetworkMode(container, dockerContainerMap)
-	if !ok {
-		return hostConfig, nil
+	if ok {
+		hostConfig.NetworkMode = networkMode
+	}
+
+	pidMode, err := task.dockerPIDMode(container, dockerContainerMap)
+	if err != nil {
+		return nil, &HostConfigError{err.Error()}
+	}
+	if pidMode != "" {
+		hostConfig.PidMode = pidMode
+	}
+	ipcMode, err := task.dockerIPCMode(container, dockerContainerMap)
+	if err != nil {
+		return nil, &HostConfigError{err.Error()}
+	}
+	if ipcMode != "" {
+		hostConfig.IpcMode = ipcMode
 	}
-	hostConfig.NetworkMode = networkMode
 
 	return hostConfig, nil
 }
@@ -728,7 +919,7 @@ func (task *Task) updateTaskDesiredStatus() {
 		if cont.Essential && (cont.KnownTerminal() || cont.DesiredTerminal()) {
 			seelog.Debugf("Updating task desired status to stopped because of container: [%s]; task: [%s]",
 				cont.Name, task.String())
-			task.SetDesiredStatus(TaskStopped)
+			task.setDesiredStatusStoppedBy(cont.Name)
 		}
 	}
 }
@@ -783,6 +974,41 @@ func (task *Task) GetKnownStatusTime() time.Time {
 	return task.KnownStatusTimeUnsafe
 }
 
+// RemainingStopBudget returns how much of the stop budget of the task is left
+// at the given time. The budget starts being spent at the first call, when the
+// engine starts stopping the containers of the task
+func (task *Task) RemainingStopBudget(budget time.Duration, now time.Time) time.Duration {
+	task.stopStartedAtLock.Lock()
+	defer task.stopStartedAtLock.Unlock()
+
+	if task.stopStartedAt.IsZero() {
+		task.stopStartedAt = now
+	}
+	remaining := budget - now.Sub(task.stopStartedAt)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// SetBlockedReason sets what keeps the containers of the task from
+// progressing. The empty string indicates that the task is not blocked
+func (task *Task) SetBlockedReason(reason string) {
+	task.blockedReasonLock.Lock()
+	defer task.blockedReasonLock.Unlock()
+
+	task.blockedReason = reason
+}
+
+// GetBlockedReason returns what keeps the containers of the task from
+// progressing, or the empty string if the task is not blocked
+func (task *Task) GetBlockedReason() string {
+	task.blockedReasonLock.RLock()
+	defer task.blockedReasonLock.RUnlock()
+
+	return task.blockedReason
+}
+
 // SetCredentialsID sets the credentials ID for the task
 func (task *Task) SetCredentialsID(id string) {
 	task.credentialsIDLock.Lock()
@@ -812,9 +1038,36 @@ func (task *Task) SetDesiredStatus(status TaskStatus) {
 	task.desiredStatusLock.Lock()
 	defer task.desiredStatusLock.Unlock()
 
+	if status.Terminal() && !task.DesiredStatusUnsafe.Terminal() {
+		task.stopRequestedAt = ttime.Now()
+	}
 	task.DesiredStatusUnsafe = status
 }
 
+// setDesiredStatusStoppedBy sets the desired status of the task to stopped
+// because the essential container with the given name stopped. The container
+// is only recorded if the task was not already stopping
+func (task *Task) setDesiredStatusStoppedBy(containerName string) {
+	task.desiredStatusLock.Lock()
+	defer task.desiredStatusLock.Unlock()
+
+	if !task.DesiredStatusUnsafe.Terminal() {
+		task.stopRequestedAt = ttime.Now()
+		task.stoppedByContainer = containerName
+	}
+	task.DesiredStatusUnsafe = TaskStopped
+}
+
+// GetStoppedByContainer returns the name of the essential container whose
+// exit caused the task to stop, or an empty string if the task was not
+// stopped by the exit of one of its containers
+func (task *Task) GetStoppedByContainer() string {
+	task.desiredStatusLock.RLock()
+	defer task.desiredStatusLock.RUnlock()
+
+	return task.stoppedByContainer
+}
+
 // GetSentStatus safely returns the SentStatus of the task
 func (task *Task) GetSentStatus() TaskStatus {
 	task.sentStatusLock.RLock()
@@ -847,6 +1100,44 @@ func (task *Task) GetTaskENI() *ENI {
 	return task.ENI
 }
 
+// SetPauseContainerPID sets the pid of the pause container whose network
+// namespace was set up for the ENI of the task
+func (task *Task) SetPauseContainerPID(pid string) {
+	task.eniLock.Lock()
+	defer task.eniLock.Unlock()
+
+	task.pauseContainerPID = pid
+}
+
+// GetPauseContainerPID returns the pid of the pause container whose network
+// namespace was set up for the ENI of the task, if any
+func (task *Task) GetPauseContainerPID() string {
+	task.eniLock.RLock()
+	defer task.eniLock.RUnlock()
+
+	return task.pauseContainerPID
+}
+
+// ContainerNetworkMode returns the network mode of a container of the task.
+// Containers of tasks with an ENI are reported in the awsvpc network mode,
+// rather than in the network mode of the pause container they're attached to
+func (task *Task) ContainerNetworkMode(container *Container) string {
+	if task.GetTaskENI() != nil {
+		return networkModeAWSVPC
+	}
+	if _, hostConfig, ok := container.GetEffectiveConfig(); ok && hostConfig != nil && hostConfig.NetworkMode != "" {
+		return hostConfig.NetworkMode
+	}
+	if container.DockerConfig.HostConfig != nil {
+		hostConfig := &docker.HostConfig{}
+		err := json.Unmarshal([]byte(*container.DockerConfig.HostConfig), hostConfig)
+		if err == nil && hostConfig.NetworkMode != "" {
+			return hostConfig.NetworkMode
+		}
+	}
+	return defaultNetworkMode
+}
+
 // String returns a human readable string representation of this object
 func (t *Task) String() string {
 	res := fmt.Sprintf("%s:%s %s, TaskStatus: (%s->%s)",
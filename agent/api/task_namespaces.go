@@ -0,0 +1,194 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"fmt"
+)
+
+const (
+	// PIDModeTask shares one PID namespace across the containers of a task
+	PIDModeTask = "task"
+	// PIDModeHost runs the containers of a task in the PID namespace of the
+	// host
+	PIDModeHost = "host"
+	// pidModeHostDocker is the docker PID mode of containers using the PID
+	// namespace of the host
+	pidModeHostDocker = "host"
+	// IPCModeTask shares one IPC namespace across the containers of a task
+	IPCModeTask = "task"
+	// IPCModeHost runs the containers of a task in the IPC namespace of the
+	// host
+	IPCModeHost = "host"
+	// IPCModeNone gives each container of a task its own private IPC
+	// namespace, without shared memory mounted
+	IPCModeNone = "none"
+	// ipcModeHostDocker is the docker IPC mode of containers using the IPC
+	// namespace of the host
+	ipcModeHostDocker = "host"
+	// ipcModeNoneDocker is the docker IPC mode of containers with a private
+	// IPC namespace and no shared memory
+	ipcModeNoneDocker = "none"
+	// ipcModeShareableDocker is the docker IPC mode of containers whose
+	// private IPC namespace other containers can join
+	ipcModeShareableDocker = "shareable"
+	// namespaceModeContainerPrefix is the prefix of the docker namespace
+	// modes that join the namespace of another container
+	namespaceModeContainerPrefix = "container:"
+)
+
+// namespaceSourceContainer returns the container whose namespaces are shared
+// with the other containers of the task. That's the pause container of tasks
+// that have one, and the first container of the task otherwise
+func (task *Task) namespaceSourceContainer() *Container {
+	var source *Container
+	for _, container := range task.Containers {
+		if container.Type == ContainerCNIPause {
+			return container
+		}
+		if source == nil && !container.IsInternal() {
+			source = container
+		}
+	}
+	return source
+}
+
+// sharesTaskNamespaces returns true if the containers of the task share some
+// of their namespaces with the namespace source container
+func (task *Task) sharesTaskNamespaces() bool {
+	return task.PIDMode == PIDModeTask || task.IPCMode == IPCModeTask
+}
+
+// validateNamespaceModes returns an error if the PID or IPC mode of the task
+// is unknown, or can't be used with the network mode of the task
+func (task *Task) validateNamespaceModes() error {
+	switch task.PIDMode {
+	case "", PIDModeTask, PIDModeHost:
+	default:
+		return fmt.Errorf("invalid pid mode %q", task.PIDMode)
+	}
+	switch task.IPCMode {
+	case "", IPCModeTask, IPCModeNone:
+	case IPCModeHost:
+		// The containers of awsvpc tasks are isolated from the host, which
+		// the IPC namespace of the host would defeat
+		if task.isNetworkModeVPC() {
+			return fmt.Errorf("ipc mode %q is not supported with the awsvpc network mode", task.IPCMode)
+		}
+	default:
+		return fmt.Errorf("invalid ipc mode %q", task.IPCMode)
+	}
+	return nil
+}
+
+// addNamespaceSourceDependency makes the containers of the task that share
+// namespaces with the namespace source container wait for it to be running
+// before they are created, as docker can only join the namespaces of a
+// running container. The pause container is already running before the other
+// containers of the task are pulled, so it needs no extra dependency
+func (task *Task) addNamespaceSourceDependency() {
+	if !task.sharesTaskNamespaces() {
+		return
+	}
+	source := task.namespaceSourceContainer()
+	if source == nil || source.Type == ContainerCNIPause {
+		return
+	}
+	for _, container := range task.Containers {
+		if container.IsInternal() || container.Name == source.Name {
+			continue
+		}
+		dependency := ContainerDependency{
+			ContainerName:   source.Name,
+			SatisfiedStatus: ContainerRunning,
+			DependentStatus: ContainerCreated,
+		}
+		if !hasContainerDependency(container, dependency) {
+			container.TransitionDependencySet.ContainerDependencies = append(container.TransitionDependencySet.ContainerDependencies, dependency)
+		}
+	}
+}
+
+// hasContainerDependency returns true if the container already has the
+// dependency, as tasks can be post-unmarshalled more than once
+func hasContainerDependency(container *Container, dependency ContainerDependency) bool {
+	for _, existing := range container.TransitionDependencySet.ContainerDependencies {
+		if existing == dependency {
+			return true
+		}
+	}
+	return false
+}
+
+// dockerPIDMode returns the docker PID mode of the container, for the PID
+// mode of its task
+func (task *Task) dockerPIDMode(container *Container, dockerContainerMap map[string]*DockerContainer) (string, error) {
+	switch task.PIDMode {
+	case "":
+		return "", nil
+	case PIDModeHost:
+		if container.IsInternal() {
+			return "", nil
+		}
+		return pidModeHostDocker, nil
+	case PIDModeTask:
+		return task.namespaceSourceMode(container, dockerContainerMap)
+	default:
+		return "", fmt.Errorf("invalid pid mode %q", task.PIDMode)
+	}
+}
+
+// dockerIPCMode returns the docker IPC mode of the container, for the IPC
+// mode of its task
+func (task *Task) dockerIPCMode(container *Container, dockerContainerMap map[string]*DockerContainer) (string, error) {
+	switch task.IPCMode {
+	case "":
+		return "", nil
+	case IPCModeHost:
+		if container.IsInternal() {
+			return "", nil
+		}
+		return ipcModeHostDocker, nil
+	case IPCModeNone:
+		if container.IsInternal() {
+			return "", nil
+		}
+		return ipcModeNoneDocker, nil
+	case IPCModeTask:
+		// Docker only lets other containers join the IPC namespace of
+		// containers that make it shareable
+		if source := task.namespaceSourceContainer(); source != nil && source.Name == container.Name {
+			return ipcModeShareableDocker, nil
+		}
+		return task.namespaceSourceMode(container, dockerContainerMap)
+	default:
+		return "", fmt.Errorf("invalid ipc mode %q", task.IPCMode)
+	}
+}
+
+// namespaceSourceMode returns the docker namespace mode that makes the
+// container join the namespace of the namespace source container of the
+// task. The source container itself, and the other internal containers, keep
+// their own namespace
+func (task *Task) namespaceSourceMode(container *Container, dockerContainerMap map[string]*DockerContainer) (string, error) {
+	source := task.namespaceSourceContainer()
+	if source == nil || source.Name == container.Name || container.IsInternal() {
+		return "", nil
+	}
+	dockerContainer, ok := dockerContainerMap[source.Name]
+	if !ok || dockerContainer == nil {
+		return "", fmt.Errorf("container %s, whose namespaces are shared with the task, is not created", source.Name)
+	}
+	return namespaceModeContainerPrefix + dockerContainer.DockerID, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerHostConfigPIDModeHost(t *testing.T) {
+	testTask := &Task{
+		PIDMode: PIDModeHost,
+		Containers: []*Container{
+			{Name: "c1"},
+			{Name: emptyHostVolumeName, Type: ContainerEmptyHostVolume},
+		},
+	}
+
+	config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "host", config.PidMode)
+
+	config, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Empty(t, config.PidMode, "internal containers keep their own pid namespace")
+}
+
+func TestDockerHostConfigPIDModeTask(t *testing.T) {
+	testTask := &Task{
+		PIDMode: PIDModeTask,
+		Containers: []*Container{
+			{Name: "c1"},
+			{Name: "c2"},
+		},
+	}
+
+	config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Empty(t, config.PidMode, "the first container is the source of the namespace")
+
+	config, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "container:"+dockerIDPrefix+"c1", config.PidMode)
+
+	_, err = testTask.DockerHostConfig(testTask.Containers[1], map[string]*DockerContainer{})
+	assert.NotNil(t, err, "the source container must be created first")
+}
+
+func TestDockerHostConfigPIDModeTaskPauseContainer(t *testing.T) {
+	testTask := &Task{
+		PIDMode: PIDModeTask,
+		ENI:     &ENI{ID: "eniID"},
+		Containers: []*Container{
+			{Name: "c1"},
+			{Name: PauseContainerName, Type: ContainerCNIPause},
+		},
+	}
+
+	config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "container:"+dockerIDPrefix+PauseContainerName, config.PidMode)
+
+	config, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Empty(t, config.PidMode)
+}
+
+func TestDockerHostConfigInvalidPIDMode(t *testing.T) {
+	testTask := &Task{
+		PIDMode:    "invalid",
+		Containers: []*Container{{Name: "c1"}},
+	}
+
+	_, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	assert.NotNil(t, err)
+}
+
+func TestPostUnmarshalTaskNamespaceSourceDependency(t *testing.T) {
+	testTask := &Task{
+		PIDMode: PIDModeTask,
+		Containers: []*Container{
+			{Name: "c1"},
+			{Name: "c2"},
+		},
+	}
+
+	cfg := &config.Config{}
+	require.NoError(t, testTask.PostUnmarshalTask(cfg, nil))
+	// Tasks can be post-unmarshalled more than once
+	require.NoError(t, testTask.PostUnmarshalTask(cfg, nil))
+
+	assert.Empty(t, testTask.Containers[0].TransitionDependencySet.ContainerDependencies)
+	assert.Equal(t, []ContainerDependency{{
+		ContainerName:   "c1",
+		SatisfiedStatus: ContainerRunning,
+		DependentStatus: ContainerCreated,
+	}}, testTask.Containers[1].TransitionDependencySet.ContainerDependencies)
+}
+
+func TestDockerHostConfigIPCMode(t *testing.T) {
+	testCases := []struct {
+		ipcMode           string
+		expectedSourceIPC string
+		expectedIPC       string
+	}{
+		{ipcMode: "", expectedSourceIPC: "", expectedIPC: ""},
+		{ipcMode: IPCModeHost, expectedSourceIPC: "host", expectedIPC: "host"},
+		{ipcMode: IPCModeNone, expectedSourceIPC: "none", expectedIPC: "none"},
+		{ipcMode: IPCModeTask, expectedSourceIPC: "shareable", expectedIPC: "container:" + dockerIDPrefix + "c1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ipcMode, func(t *testing.T) {
+			testTask := &Task{
+				IPCMode: tc.ipcMode,
+				Containers: []*Container{
+					{Name: "c1"},
+					{Name: "c2"},
+				},
+			}
+
+			config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedSourceIPC, config.IpcMode)
+
+			config, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask))
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedIPC, config.IpcMode)
+		})
+	}
+}
+
+func TestDockerHostConfigIPCModeTaskPauseContainer(t *testing.T) {
+	testTask := &Task{
+		IPCMode: IPCModeTask,
+		ENI:     &ENI{ID: "eniID"},
+		Containers: []*Container{
+			{Name: "c1"},
+			{Name: PauseContainerName, Type: ContainerCNIPause},
+		},
+	}
+
+	config, err := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "container:"+dockerIDPrefix+PauseContainerName, config.IpcMode)
+
+	config, err = testTask.DockerHostConfig(testTask.Containers[1], dockerMap(testTask))
+	require.Nil(t, err)
+	assert.Equal(t, "shareable", config.IpcMode)
+}
+
+func TestPostUnmarshalTaskValidatesNamespaceModes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		pidMode     string
+		ipcMode     string
+		eni         *ENI
+		expectError bool
+	}{
+		{name: "no modes"},
+		{name: "task ipc with awsvpc", ipcMode: IPCModeTask, eni: &ENI{ID: "eniID"}},
+		{name: "none ipc with awsvpc", ipcMode: IPCModeNone, eni: &ENI{ID: "eniID"}},
+		{name: "host ipc", ipcMode: IPCModeHost},
+		{name: "host ipc with awsvpc", ipcMode: IPCModeHost, eni: &ENI{ID: "eniID"}, expectError: true},
+		{name: "invalid ipc mode", ipcMode: "shareable", expectError: true},
+		{name: "invalid pid mode", pidMode: "private", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testTask := &Task{
+				PIDMode:    tc.pidMode,
+				IPCMode:    tc.ipcMode,
+				ENI:        tc.eni,
+				Containers: []*Container{{Name: "c1"}},
+			}
+			err := testTask.PostUnmarshalTask(&config.Config{}, nil)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -20,12 +20,14 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
+	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const dockerIDPrefix = "dockerid-"
@@ -81,6 +83,180 @@ func TestDockerConfigPortBinding(t *testing.T) {
 	}
 }
 
+func TestDockerConfigExposedPorts(t *testing.T) {
+	testTask := &Task{
+		Containers: []*Container{
+			{
+				Name:         "c1",
+				Ports:        []PortBinding{{10, 10, "", TransportProtocolTCP}},
+				ExposedPorts: []ExposedPort{{8080, TransportProtocolTCP}, {53, TransportProtocolUDP}},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	assert.Nil(t, err)
+	assert.Len(t, config.ExposedPorts, 3)
+	assert.Contains(t, config.ExposedPorts, docker.Port("10/tcp"))
+	assert.Contains(t, config.ExposedPorts, docker.Port("8080/tcp"))
+	assert.Contains(t, config.ExposedPorts, docker.Port("53/udp"))
+
+	hostConfig, hcErr := testTask.DockerHostConfig(testTask.Containers[0], dockerMap(testTask))
+	assert.Nil(t, hcErr)
+	assert.Len(t, hostConfig.PortBindings, 1, "exposed ports should not be bound on the host")
+	assert.NotContains(t, hostConfig.PortBindings, docker.Port("8080/tcp"))
+	assert.NotContains(t, hostConfig.PortBindings, docker.Port("53/udp"))
+}
+
+func TestDockerConfigAttachStdio(t *testing.T) {
+	testTask := &Task{
+		Containers: []*Container{
+			{
+				Name:         "c1",
+				AttachStdout: true,
+				AttachStderr: true,
+			},
+			{
+				Name:         "c2",
+				AttachStderr: true,
+				DockerConfig: DockerConfig{
+					HostConfig: strptr(`{"LogConfig":{"Type":"json-file"}}`),
+				},
+			},
+			{
+				Name: "c3",
+			},
+			{
+				Name:         "c4",
+				AttachStdout: true,
+				DockerConfig: DockerConfig{
+					HostConfig: strptr(`{"LogConfig":{"Type":"none"}}`),
+				},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	assert.Nil(t, err)
+	assert.True(t, config.AttachStdout)
+	assert.True(t, config.AttachStderr)
+
+	config, err = testTask.DockerConfig(testTask.Containers[1])
+	assert.Nil(t, err)
+	assert.False(t, config.AttachStdout)
+	assert.True(t, config.AttachStderr)
+
+	config, err = testTask.DockerConfig(testTask.Containers[2])
+	assert.Nil(t, err)
+	assert.False(t, config.AttachStdout, "stdout should not be attached by default")
+	assert.False(t, config.AttachStderr, "stderr should not be attached by default")
+
+	_, err = testTask.DockerConfig(testTask.Containers[3])
+	assert.NotNil(t, err, "attaching with the none log driver should be rejected")
+}
+
+func TestDockerConfigDisableHealthcheck(t *testing.T) {
+	testTask := &Task{
+		Containers: []*Container{
+			{
+				Name:               "c1",
+				DisableHealthcheck: true,
+			},
+			{
+				Name: "c2",
+			},
+			{
+				Name:               "c3",
+				DisableHealthcheck: true,
+				DockerConfig: DockerConfig{
+					Config: strptr(`{"Healthcheck":{"Test":["CMD-SHELL","exit 0"]}}`),
+				},
+			},
+		},
+	}
+
+	config, err := testTask.DockerConfig(testTask.Containers[0])
+	assert.Nil(t, err)
+	require.NotNil(t, config.Healthcheck)
+	assert.Equal(t, []string{"NONE"}, config.Healthcheck.Test)
+
+	config, err = testTask.DockerConfig(testTask.Containers[1])
+	assert.Nil(t, err)
+	assert.Nil(t, config.Healthcheck, "healthcheck of the image should be kept by default")
+
+	config, err = testTask.DockerConfig(testTask.Containers[2])
+	assert.Nil(t, err)
+	require.NotNil(t, config.Healthcheck)
+	assert.Equal(t, []string{"NONE"}, config.Healthcheck.Test, "disabling should override the docker config")
+}
+
+func TestDockerConfigHealthCheck(t *testing.T) {
+	container := &Container{
+		Name: "c1",
+		HealthCheck: &HealthCheck{
+			Command:  []string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"},
+			Interval: 30,
+			Timeout:  5,
+			Retries:  3,
+		},
+	}
+	testTask := &Task{Containers: []*Container{container}}
+
+	config, err := testTask.DockerConfig(container)
+	require.Nil(t, err)
+	assert.Equal(t, &docker.HealthConfig{
+		Test:     []string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"},
+		Interval: 30 * time.Second,
+		Timeout:  5 * time.Second,
+		Retries:  3,
+	}, config.Healthcheck)
+}
+
+func TestDockerConfigInvalidHealthCheck(t *testing.T) {
+	testCases := []struct {
+		name      string
+		container *Container
+	}{
+		{
+			name:      "no command",
+			container: &Container{Name: "c1", HealthCheck: &HealthCheck{Command: []string{"CMD"}}},
+		},
+		{
+			name:      "unsupported command type",
+			container: &Container{Name: "c1", HealthCheck: &HealthCheck{Command: []string{"NONE", "exit 0"}}},
+		},
+		{
+			name:      "negative interval",
+			container: &Container{Name: "c1", HealthCheck: &HealthCheck{Command: []string{"CMD", "true"}, Interval: -1}},
+		},
+		{
+			name: "disabled and defined",
+			container: &Container{
+				Name:               "c1",
+				DisableHealthcheck: true,
+				HealthCheck:        &HealthCheck{Command: []string{"CMD", "true"}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testTask := &Task{Containers: []*Container{tc.container}}
+			_, err := testTask.DockerConfig(tc.container)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestRemainingStopBudget(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	budget := 30 * time.Second
+	stopStarted := time.Now()
+
+	assert.Equal(t, budget, task.RemainingStopBudget(budget, stopStarted))
+	assert.Equal(t, 10*time.Second, task.RemainingStopBudget(budget, stopStarted.Add(20*time.Second)))
+	assert.Zero(t, task.RemainingStopBudget(budget, stopStarted.Add(time.Minute)), "spent budget should not be negative")
+}
+
 func TestDockerConfigCPUShareZero(t *testing.T) {
 	testTask := &Task{
 		Containers: []*Container{
@@ -141,6 +317,82 @@ func TestDockerConfigCPUShareUnchanged(t *testing.T) {
 	}
 }
 
+func TestDockerConfigMemory(t *testing.T) {
+	testCases := []struct {
+		name           string
+		container      *Container
+		expectedMemory int64
+	}{
+		{
+			name:           "no limit",
+			container:      &Container{Name: "c1"},
+			expectedMemory: 0,
+		},
+		{
+			name:           "MiB by default",
+			container:      &Container{Name: "c1", Memory: 512},
+			expectedMemory: 512 * 1024 * 1024,
+		},
+		{
+			name:           "MiB",
+			container:      &Container{Name: "c1", Memory: 256, MemoryUnit: MemoryUnitMiB},
+			expectedMemory: 256 * 1024 * 1024,
+		},
+		{
+			name:           "GiB",
+			container:      &Container{Name: "c1", Memory: 2, MemoryUnit: MemoryUnitGiB},
+			expectedMemory: 2 * 1024 * 1024 * 1024,
+		},
+		{
+			name:           "below minimum",
+			container:      &Container{Name: "c1", Memory: 1},
+			expectedMemory: DockerContainerMinimumMemoryInBytes,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testTask := &Task{Containers: []*Container{tc.container}}
+			config, err := testTask.DockerConfig(tc.container)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedMemory, config.Memory)
+		})
+	}
+}
+
+func TestDockerConfigInvalidMemory(t *testing.T) {
+	testCases := []struct {
+		name      string
+		container *Container
+	}{
+		{
+			name:      "unknown unit",
+			container: &Container{Name: "c1", Memory: 512, MemoryUnit: "MB"},
+		},
+		{
+			name:      "zero with unit",
+			container: &Container{Name: "c1", Memory: 0, MemoryUnit: MemoryUnitMiB},
+		},
+		{
+			name: "negative in docker config",
+			container: &Container{
+				Name:         "c1",
+				Memory:       512,
+				DockerConfig: DockerConfig{Config: strptr(`{"Memory":-1}`)},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testTask := &Task{Containers: []*Container{tc.container}}
+			_, err := testTask.DockerConfig(tc.container)
+			require.NotNil(t, err)
+			assert.Equal(t, "DockerClientConfigError", err.ErrorName())
+		})
+	}
+}
+
 func TestDockerHostConfigPortBinding(t *testing.T) {
 	testTask := &Task{
 		Containers: []*Container{
@@ -494,7 +746,7 @@ func TestGetCredentialsEndpointWhenCredentialsAreSet(t *testing.T) {
 		IAMRoleCredentials: credentials.IAMRoleCredentials{CredentialsID: "credsid"},
 	}
 	credentialsManager.EXPECT().GetTaskCredentials(credentialsIDInTask).Return(taskCredentials, true)
-	task.initializeCredentialsEndpoint(credentialsManager)
+	task.initializeCredentialsEndpoint(&config.Config{}, credentialsManager)
 
 	// Test if all containers in the task have the environment variable for
 	// credentials endpoint set correctly.
@@ -507,6 +759,55 @@ func TestGetCredentialsEndpointWhenCredentialsAreSet(t *testing.T) {
 	}
 }
 
+func TestGetCredentialsEndpointByNetworkMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+
+	taskCredentials := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: credentials.IAMRoleCredentials{CredentialsID: "credsid"},
+	}
+	credentialsManager.EXPECT().GetTaskCredentials("credsid").Return(taskCredentials, true).Times(2)
+	cfg := &config.Config{HostNetworkCredentialsEndpoint: "http://127.0.0.1:51679"}
+
+	hostContainer := &Container{
+		Name: "host",
+		DockerConfig: DockerConfig{
+			HostConfig: strptr(`{"NetworkMode":"host"}`),
+		},
+	}
+	bridgeContainer := &Container{
+		Name: "bridge",
+		DockerConfig: DockerConfig{
+			HostConfig: strptr(`{"NetworkMode":"bridge"}`),
+		},
+	}
+	task := &Task{
+		Containers:    []*Container{hostContainer, bridgeContainer},
+		credentialsID: "credsid",
+	}
+	task.initializeCredentialsEndpoint(cfg, credentialsManager)
+
+	assert.Equal(t, "http://127.0.0.1:51679/v2/credentials/credsid",
+		hostContainer.Environment[awsSDKCredentialsFullURIEnvironmentVariableName])
+	assert.NotContains(t, hostContainer.Environment, awsSDKCredentialsRelativeURIPathEnvironmentVariableName)
+	assert.Equal(t, "/v2/credentials/credsid",
+		bridgeContainer.Environment[awsSDKCredentialsRelativeURIPathEnvironmentVariableName])
+	assert.NotContains(t, bridgeContainer.Environment, awsSDKCredentialsFullURIEnvironmentVariableName)
+
+	awsvpcContainer := &Container{Name: "awsvpc"}
+	awsvpcTask := &Task{
+		Containers:    []*Container{awsvpcContainer},
+		credentialsID: "credsid",
+		ENI:           &ENI{ID: "eni-12345"},
+	}
+	awsvpcTask.initializeCredentialsEndpoint(cfg, credentialsManager)
+
+	assert.Equal(t, "/v2/credentials/credsid",
+		awsvpcContainer.Environment[awsSDKCredentialsRelativeURIPathEnvironmentVariableName])
+	assert.NotContains(t, awsvpcContainer.Environment, awsSDKCredentialsFullURIEnvironmentVariableName)
+}
+
 func TestGetCredentialsEndpointWhenCredentialsAreNotSet(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -524,7 +825,7 @@ func TestGetCredentialsEndpointWhenCredentialsAreNotSet(t *testing.T) {
 			}},
 	}
 
-	task.initializeCredentialsEndpoint(credentialsManager)
+	task.initializeCredentialsEndpoint(&config.Config{}, credentialsManager)
 
 	for _, container := range task.Containers {
 		env := container.Environment
@@ -598,6 +899,40 @@ func TestPostUnmarshalTaskWithEmptyVolumes(t *testing.T) {
 	assert.Equal(t, []string{expectedEmptyVolumeContainerCmd}, emptyContainer.Command, "Should have expected command")
 }
 
+func TestPostUnmarshalTaskStopTimeout(t *testing.T) {
+	taskFromACS := ecsacs.Task{
+		Arn:           strptr("myArn"),
+		DesiredStatus: strptr("RUNNING"),
+		Family:        strptr("myFamily"),
+		Version:       strptr("1"),
+		Containers: []*ecsacs.Container{
+			{
+				Name: strptr("database"),
+				DockerConfig: &ecsacs.DockerConfig{
+					Config: strptr(`{"StopTimeout":120}`),
+				},
+			},
+			{
+				Name: strptr("default"),
+			},
+			{
+				Name: strptr("invalid"),
+				DockerConfig: &ecsacs.DockerConfig{
+					Config: strptr(`{"StopTimeout":-1}`),
+				},
+			},
+		},
+	}
+	seqNum := int64(42)
+	task, err := TaskFromACS(&taskFromACS, &ecsacs.PayloadMessage{SeqNum: &seqNum})
+	require.NoError(t, err, "Should be able to handle acs task")
+	task.PostUnmarshalTask(nil, nil)
+
+	assert.Equal(t, 2*time.Minute, task.Containers[0].StopTimeout)
+	assert.Zero(t, task.Containers[1].StopTimeout)
+	assert.Zero(t, task.Containers[2].StopTimeout)
+}
+
 func TestTaskFromACS(t *testing.T) {
 	testTime := ttime.Now().Truncate(1 * time.Second).Format(time.RFC3339)
 
@@ -607,6 +942,9 @@ func TestTaskFromACS(t *testing.T) {
 	boolptr := func(b bool) *bool {
 		return &b
 	}
+	floatptr := func(f float64) *float64 {
+		return &f
+	}
 	// Testing type conversions, bleh. At least the type conversion itself
 	// doesn't look this messy.
 	taskFromAcs := ecsacs.Task{
@@ -616,15 +954,22 @@ func TestTaskFromACS(t *testing.T) {
 		Version:       strptr("1"),
 		Containers: []*ecsacs.Container{
 			{
-				Name:        strptr("myName"),
-				Cpu:         intptr(10),
-				Command:     []*string{strptr("command"), strptr("command2")},
-				EntryPoint:  []*string{strptr("sh"), strptr("-c")},
-				Environment: map[string]*string{"key": strptr("value")},
-				Essential:   boolptr(true),
-				Image:       strptr("image:tag"),
-				Links:       []*string{strptr("link1"), strptr("link2")},
-				Memory:      intptr(100),
+				Name:            strptr("myName"),
+				Cpu:             intptr(10),
+				Command:         []*string{strptr("command"), strptr("command2")},
+				CommandManifest: strptr("web/command.json"),
+				EntryPoint:      []*string{strptr("sh"), strptr("-c")},
+				Environment:     map[string]*string{"key": strptr("value")},
+				Essential:       boolptr(true),
+				HealthCheck: &ecsacs.HealthCheck{
+					Command:  []*string{strptr("CMD-SHELL"), strptr("exit 0")},
+					Interval: intptr(10),
+					Retries:  intptr(3),
+					Timeout:  intptr(5),
+				},
+				Image:  strptr("image:tag"),
+				Links:  []*string{strptr("link1"), strptr("link2")},
+				Memory: intptr(100),
 				MountPoints: []*ecsacs.MountPoint{
 					{
 						ContainerPath: strptr("/container/path"),
@@ -646,6 +991,25 @@ func TestTaskFromACS(t *testing.T) {
 						SourceContainer: strptr("volumeLink"),
 					},
 				},
+				ReadinessProbe: &ecsacs.ReadinessProbe{
+					Type:     strptr("http"),
+					Port:     intptr(8080),
+					Path:     strptr("/ready"),
+					Interval: intptr(1),
+					Timeout:  intptr(2),
+					Retries:  intptr(3),
+				},
+				TransitionDependencySet: &ecsacs.TransitionDependencySet{
+					ContainerDependencies: []*ecsacs.ContainerDependency{
+						{
+							ContainerName:   strptr("db"),
+							SatisfiedStatus: strptr("RUNNING"),
+							SatisfiedHealth: strptr("HEALTHY"),
+							SatisfiedReady:  boolptr(true),
+							DependentStatus: strptr("CREATED"),
+						},
+					},
+				},
 				DockerConfig: &ecsacs.DockerConfig{
 					Config:     strptr("config json"),
 					HostConfig: strptr("hostconfig json"),
@@ -669,6 +1033,14 @@ func TestTaskFromACS(t *testing.T) {
 			SecretAccessKey: strptr("OhhSecret"),
 			SessionToken:    strptr("sessionToken"),
 		},
+		Tags: []*ecsacs.Tag{
+			{Key: strptr("team"), Value: strptr("web")},
+		},
+		Cpu:      floatptr(0.5),
+		Memory:   intptr(512),
+		PidMode:  strptr("task"),
+		IpcMode:  strptr("none"),
+		Priority: intptr(10),
 	}
 	expectedTask := &Task{
 		Arn:                 "myArn",
@@ -709,11 +1081,37 @@ func TestTaskFromACS(t *testing.T) {
 						SourceContainer: "volumeLink",
 					},
 				},
+				ReadinessProbe: &ReadinessProbe{
+					Type:     ReadinessProbeHTTP,
+					Port:     8080,
+					Path:     "/ready",
+					Interval: 1,
+					Timeout:  2,
+					Retries:  3,
+				},
+				TransitionDependencySet: TransitionDependencySet{
+					ContainerDependencies: []ContainerDependency{
+						{
+							ContainerName:   "db",
+							SatisfiedStatus: ContainerRunning,
+							SatisfiedHealth: ContainerHealthy,
+							SatisfiedReady:  true,
+							DependentStatus: ContainerCreated,
+						},
+					},
+				},
 				DockerConfig: DockerConfig{
 					Config:     strptr("config json"),
 					HostConfig: strptr("hostconfig json"),
 					Version:    strptr("version string"),
 				},
+				CommandManifest: "web/command.json",
+				HealthCheck: &HealthCheck{
+					Command:  []string{"CMD-SHELL", "exit 0"},
+					Interval: 10,
+					Retries:  3,
+					Timeout:  5,
+				},
 			},
 		},
 		Volumes: []TaskVolume{
@@ -724,6 +1122,14 @@ func TestTaskFromACS(t *testing.T) {
 				},
 			},
 		},
+		Tags: []TaskTag{
+			{Key: "team", Value: "web"},
+		},
+		CPU:                 0.5,
+		Memory:              512,
+		PIDMode:             PIDModeTask,
+		IPCMode:             IPCModeNone,
+		Priority:            10,
 		StartSequenceNumber: 42,
 	}
 
@@ -744,6 +1150,18 @@ func TestTaskFromACS(t *testing.T) {
 	if !reflect.DeepEqual(task.StopSequenceNumber, expectedTask.StopSequenceNumber) {
 		t.Fatal("StopSequenceNumber should be equal")
 	}
+	if !reflect.DeepEqual(task.Tags, expectedTask.Tags) {
+		t.Fatal("Tags should be equal")
+	}
+	if task.CPU != expectedTask.CPU || task.Memory != expectedTask.Memory {
+		t.Fatal("CPU and Memory should be equal")
+	}
+	if task.PIDMode != expectedTask.PIDMode || task.IPCMode != expectedTask.IPCMode {
+		t.Fatal("PIDMode and IPCMode should be equal")
+	}
+	if task.Priority != expectedTask.Priority {
+		t.Fatal("Priority should be equal")
+	}
 }
 
 func TestTaskUpdateKnownStatusHappyPath(t *testing.T) {
@@ -770,6 +1188,31 @@ func TestTaskUpdateKnownStatusHappyPath(t *testing.T) {
 
 // TestTaskUpdateKnownStatusNotChangeToRunningWithEssentialContainerStopped tests when there is one essential
 // container is stopped while the other containers are running, the task status shouldn't be changed to running
+// TestUpdateDesiredStatusRecordsStoppedByContainer tests that the essential
+// container whose exit stops the task is recorded, but not when the task was
+// already stopping
+func TestUpdateDesiredStatusRecordsStoppedByContainer(t *testing.T) {
+	task := &Task{
+		DesiredStatusUnsafe: TaskRunning,
+		Containers: []*Container{
+			{Name: "essential", Essential: true, KnownStatusUnsafe: ContainerStopped},
+			{Name: "sidecar", KnownStatusUnsafe: ContainerStopped},
+		},
+	}
+	task.UpdateDesiredStatus()
+	assert.Equal(t, TaskStopped, task.GetDesiredStatus())
+	assert.Equal(t, "essential", task.GetStoppedByContainer())
+
+	task = &Task{
+		DesiredStatusUnsafe: TaskStopped,
+		Containers: []*Container{
+			{Name: "essential", Essential: true, KnownStatusUnsafe: ContainerStopped},
+		},
+	}
+	task.UpdateDesiredStatus()
+	assert.Empty(t, task.GetStoppedByContainer())
+}
+
 func TestTaskUpdateKnownStatusNotChangeToRunningWithEssentialContainerStopped(t *testing.T) {
 	testTask := &Task{
 		KnownStatusUnsafe: TaskCreated,
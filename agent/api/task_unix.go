@@ -22,6 +22,10 @@ import (
 const (
 	portBindingHostIP = "0.0.0.0"
 
+	// defaultNetworkMode is the network mode of containers that don't
+	// specify one
+	defaultNetworkMode = "bridge"
+
 	//memorySwappinessDefault is the expected default value for this platform. This is used in task_windows.go
 	//and is maintained here for unix default. Also used for testing
 	memorySwappinessDefault = 0
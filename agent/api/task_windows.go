@@ -25,6 +25,10 @@ import (
 const (
 	portBindingHostIP = ""
 
+	// defaultNetworkMode is the network mode of containers that don't
+	// specify one
+	defaultNetworkMode = "nat"
+
 	//memorySwappinessDefault is the expected default value for this platform
 	memorySwappinessDefault = -1
 )
@@ -0,0 +1,134 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import "time"
+
+// TaskLaunchMilestone is a point in the launch of a task that bounds one of
+// the launch phases
+type TaskLaunchMilestone int
+
+const (
+	// TaskLaunchReceived is reached when the task is added to the engine
+	TaskLaunchReceived TaskLaunchMilestone = iota
+	// TaskLaunchStarted is reached when the engine starts transitioning the
+	// containers of the task
+	TaskLaunchStarted
+	// TaskLaunchPulled is reached when the last container image of the task
+	// has been pulled
+	TaskLaunchPulled
+	// TaskLaunchCreated is reached when the last container of the task has
+	// been created
+	TaskLaunchCreated
+	// TaskLaunchRunning is reached when the task is running
+	TaskLaunchRunning
+)
+
+// TaskLaunchLatency is the time a task spent in each phase of its launch.
+// The phases are consecutive, so they add up to the total launch time
+type TaskLaunchLatency struct {
+	// QueueWait is the time from the task being received until the engine
+	// started working on it
+	QueueWait time.Duration
+	// Pull is the time spent pulling container images
+	Pull time.Duration
+	// Create is the time spent creating containers after the last pull
+	Create time.Duration
+	// Start is the time spent starting containers after the last create
+	Start time.Duration
+}
+
+// Total returns the time from the task being received until it was running
+func (latency TaskLaunchLatency) Total() time.Duration {
+	return latency.QueueWait + latency.Pull + latency.Create + latency.Start
+}
+
+// RecordLaunchMilestone records the time at which the task reached a launch
+// milestone. The pulled and created milestones track the last container to
+// reach them; milestones reached after the task is running are ignored
+func (task *Task) RecordLaunchMilestone(milestone TaskLaunchMilestone, t time.Time) {
+	task.launchTimesLock.Lock()
+	defer task.launchTimesLock.Unlock()
+
+	if task.launchTimes == nil {
+		task.launchTimes = make(map[TaskLaunchMilestone]time.Time)
+	}
+	if _, ok := task.launchTimes[TaskLaunchRunning]; ok {
+		return
+	}
+	if _, ok := task.launchTimes[milestone]; ok {
+		if milestone != TaskLaunchPulled && milestone != TaskLaunchCreated {
+			return
+		}
+	}
+	task.launchTimes[milestone] = t
+}
+
+// GetLaunchLatency returns the launch phase durations of the task. It returns
+// false if the launch of the task was not observed from start to finish, as is
+// the case for tasks restored from a checkpoint. A phase that no container
+// went through, such as pulling for a task whose images are all cached, has
+// a duration of zero
+func (task *Task) GetLaunchLatency() (TaskLaunchLatency, bool) {
+	task.launchTimesLock.RLock()
+	defer task.launchTimesLock.RUnlock()
+
+	return task.getLaunchLatencyUnsafe()
+}
+
+// TakeLaunchLatency returns the launch phase durations of the task, like
+// GetLaunchLatency, the first time they are known. It returns false on every
+// later call, so that the launch latency of a task is reported once
+func (task *Task) TakeLaunchLatency() (TaskLaunchLatency, bool) {
+	task.launchTimesLock.Lock()
+	defer task.launchTimesLock.Unlock()
+
+	if task.launchLatencyTaken {
+		return TaskLaunchLatency{}, false
+	}
+	latency, ok := task.getLaunchLatencyUnsafe()
+	task.launchLatencyTaken = ok
+	return latency, ok
+}
+
+// getLaunchLatencyUnsafe returns the launch phase durations of the task. It
+// must be called with the launchTimesLock held
+func (task *Task) getLaunchLatencyUnsafe() (TaskLaunchLatency, bool) {
+	received, ok := task.launchTimes[TaskLaunchReceived]
+	if !ok {
+		return TaskLaunchLatency{}, false
+	}
+	if _, ok := task.launchTimes[TaskLaunchRunning]; !ok {
+		return TaskLaunchLatency{}, false
+	}
+
+	var phases [TaskLaunchRunning]time.Duration
+	previous := received
+	for milestone := TaskLaunchStarted; milestone <= TaskLaunchRunning; milestone++ {
+		t, ok := task.launchTimes[milestone]
+		if !ok || t.Before(previous) {
+			// Collapse the phase into the previous one
+			t = previous
+		}
+		phases[milestone-1] = t.Sub(previous)
+		previous = t
+	}
+
+	return TaskLaunchLatency{
+		QueueWait: phases[TaskLaunchStarted-1],
+		Pull:      phases[TaskLaunchPulled-1],
+		Create:    phases[TaskLaunchCreated-1],
+		Start:     phases[TaskLaunchRunning-1],
+	}, true
+}
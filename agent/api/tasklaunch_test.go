@@ -0,0 +1,102 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLaunchLatencySimpleTask(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	received := time.Now()
+	task.RecordLaunchMilestone(TaskLaunchReceived, received)
+	task.RecordLaunchMilestone(TaskLaunchStarted, received.Add(1*time.Second))
+	// Two containers, the last one to be pulled and created counts
+	task.RecordLaunchMilestone(TaskLaunchPulled, received.Add(3*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchPulled, received.Add(5*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchCreated, received.Add(6*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchCreated, received.Add(8*time.Second))
+	running := received.Add(11 * time.Second)
+	task.RecordLaunchMilestone(TaskLaunchRunning, running)
+
+	latency, ok := task.GetLaunchLatency()
+	assert.True(t, ok)
+	assert.Equal(t, 1*time.Second, latency.QueueWait)
+	assert.Equal(t, 4*time.Second, latency.Pull)
+	assert.Equal(t, 3*time.Second, latency.Create)
+	assert.Equal(t, 3*time.Second, latency.Start)
+	// The phases add up to the time from the task being received until it
+	// was running
+	assert.Equal(t, running.Sub(received), latency.QueueWait+latency.Pull+latency.Create+latency.Start)
+	assert.Equal(t, running.Sub(received), latency.Total())
+}
+
+func TestTakeLaunchLatencyOnce(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	received := time.Now()
+	task.RecordLaunchMilestone(TaskLaunchReceived, received)
+	_, ok := task.TakeLaunchLatency()
+	assert.False(t, ok, "tasks that are not yet running have no launch latency")
+
+	task.RecordLaunchMilestone(TaskLaunchRunning, received.Add(2*time.Second))
+	latency, ok := task.TakeLaunchLatency()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, latency.Total())
+
+	_, ok = task.TakeLaunchLatency()
+	assert.False(t, ok, "the launch latency should only be taken once")
+	_, ok = task.GetLaunchLatency()
+	assert.True(t, ok, "the launch latency should still be known")
+}
+
+func TestGetLaunchLatencyWithoutPull(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	received := time.Now()
+	task.RecordLaunchMilestone(TaskLaunchReceived, received)
+	task.RecordLaunchMilestone(TaskLaunchStarted, received.Add(1*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchCreated, received.Add(2*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchRunning, received.Add(4*time.Second))
+
+	latency, ok := task.GetLaunchLatency()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), latency.Pull)
+	assert.Equal(t, 1*time.Second, latency.Create)
+	assert.Equal(t, 4*time.Second, latency.Total())
+}
+
+func TestGetLaunchLatencyIgnoresMilestonesAfterRunning(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	received := time.Now()
+	task.RecordLaunchMilestone(TaskLaunchReceived, received)
+	task.RecordLaunchMilestone(TaskLaunchRunning, received.Add(2*time.Second))
+	task.RecordLaunchMilestone(TaskLaunchCreated, received.Add(10*time.Second))
+
+	latency, ok := task.GetLaunchLatency()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, latency.Start)
+	assert.Equal(t, 2*time.Second, latency.Total())
+}
+
+func TestGetLaunchLatencyIncompleteLaunch(t *testing.T) {
+	task := &Task{Arn: "arn"}
+	_, ok := task.GetLaunchLatency()
+	assert.False(t, ok, "restored tasks have no launch latency")
+
+	task.RecordLaunchMilestone(TaskLaunchReceived, time.Now())
+	_, ok = task.GetLaunchLatency()
+	assert.False(t, ok, "tasks that are not yet running have no launch latency")
+}
@@ -0,0 +1,50 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+// CPUUnitsPerVCPU is the number of CPU units of one vCPU
+const CPUUnitsPerVCPU = 1024
+
+// ReservedCPU returns the CPU the task reserves on the instance in CPU units.
+// That is the task level CPU if it is set, and the total of the CPU of its
+// containers otherwise
+func (task *Task) ReservedCPU() int64 {
+	if task.CPU > 0 {
+		return int64(task.CPU * CPUUnitsPerVCPU)
+	}
+	var cpu int64
+	for _, container := range task.Containers {
+		cpu += int64(container.CPU)
+	}
+	return cpu
+}
+
+// ReservedMemory returns the memory the task reserves on the instance in MiB.
+// That is the task level memory if it is set, and the total of the memory of
+// its containers otherwise. Containers with an unknown memory unit are not
+// counted
+func (task *Task) ReservedMemory() int64 {
+	if task.Memory > 0 {
+		return task.Memory
+	}
+	var memory int64
+	for _, container := range task.Containers {
+		bytes, err := container.GetMemoryInBytes()
+		if err != nil {
+			continue
+		}
+		memory += bytes / 1024 / 1024
+	}
+	return memory
+}
@@ -0,0 +1,44 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskReservedCPU(t *testing.T) {
+	task := &Task{
+		Containers: []*Container{{Name: "c1", CPU: 256}, {Name: "c2", CPU: 512}},
+	}
+	assert.Equal(t, int64(768), task.ReservedCPU())
+
+	task.CPU = 2.5
+	assert.Equal(t, int64(2560), task.ReservedCPU())
+}
+
+func TestTaskReservedMemory(t *testing.T) {
+	task := &Task{
+		Containers: []*Container{
+			{Name: "c1", Memory: 256},
+			{Name: "c2", Memory: 1, MemoryUnit: MemoryUnitGiB},
+			{Name: "c3", Memory: 1, MemoryUnit: "TiB"},
+		},
+	}
+	assert.Equal(t, int64(1280), task.ReservedMemory())
+
+	task.Memory = 4096
+	assert.Equal(t, int64(4096), task.ReservedMemory())
+}
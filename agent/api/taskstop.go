@@ -0,0 +1,36 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import "time"
+
+// GetStopLatency returns the time the task took to stop, from its desired
+// status becoming stopped until its known status became stopped. It returns
+// false if the task hasn't stopped yet or if its stop was not observed from
+// start to finish, as is the case for tasks restored from a checkpoint
+func (task *Task) GetStopLatency() (time.Duration, bool) {
+	task.desiredStatusLock.RLock()
+	stopRequestedAt := task.stopRequestedAt
+	task.desiredStatusLock.RUnlock()
+
+	if stopRequestedAt.IsZero() || task.GetKnownStatus() != TaskStopped {
+		return 0, false
+	}
+	stoppedAt := task.GetKnownStatusTime()
+	if stoppedAt.Before(stopRequestedAt) {
+		// The task stopped on its own before being asked to
+		return 0, true
+	}
+	return stoppedAt.Sub(stopRequestedAt), true
+}
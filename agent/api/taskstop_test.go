@@ -0,0 +1,58 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStopLatency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	ttime.SetTime(mockTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	stopRequested := time.Now()
+	gomock.InOrder(
+		mockTime.EXPECT().Now().Return(stopRequested),
+		mockTime.EXPECT().Now().Return(stopRequested.Add(7*time.Second)),
+	)
+
+	task := &Task{Arn: "arn", DesiredStatusUnsafe: TaskRunning, KnownStatusUnsafe: TaskRunning}
+	task.SetDesiredStatus(TaskStopped)
+	// Redundant transitions don't reset the time the stop was requested at
+	task.SetDesiredStatus(TaskStopped)
+	_, ok := task.GetStopLatency()
+	assert.False(t, ok, "task has not stopped yet")
+
+	task.SetKnownStatus(TaskStopped)
+	latency, ok := task.GetStopLatency()
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, latency)
+}
+
+func TestGetStopLatencyUnknownForRestoredTask(t *testing.T) {
+	task := &Task{Arn: "arn", DesiredStatusUnsafe: TaskStopped}
+	task.SetKnownStatus(TaskStopped)
+	_, ok := task.GetStopLatency()
+	assert.False(t, ok, "stop of restored tasks was not observed")
+}
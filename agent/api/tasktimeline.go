@@ -0,0 +1,87 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// TaskTimelineSize is the maximum number of events retained in the timeline
+// of a task. Older events are discarded first.
+const TaskTimelineSize = 100
+
+// TaskEventType is a significant event in the lifecycle of a task
+type TaskEventType string
+
+const (
+	// TaskEventAdded is recorded when the task is added to the engine
+	TaskEventAdded TaskEventType = "Added"
+	// TaskEventPulled is recorded when the image of a container is pulled
+	TaskEventPulled TaskEventType = "Pulled"
+	// TaskEventCreated is recorded when a container is created
+	TaskEventCreated TaskEventType = "Created"
+	// TaskEventStarted is recorded when a container is started
+	TaskEventStarted TaskEventType = "Started"
+	// TaskEventSteadyState is recorded when the task is reported running
+	TaskEventSteadyState TaskEventType = "SteadyState"
+	// TaskEventStopped is recorded when the task is reported stopped
+	TaskEventStopped TaskEventType = "Stopped"
+	// TaskEventCleanedUp is recorded when the containers of the task are
+	// removed, before the task is removed from the engine
+	TaskEventCleanedUp TaskEventType = "CleanedUp"
+)
+
+// TaskEvent is an event in the timeline of a task
+type TaskEvent struct {
+	Type TaskEventType
+	// ContainerName is the name of the container the event is about, for
+	// events of a single container
+	ContainerName string
+	Time          time.Time
+}
+
+// String returns a compact description of the event, for logging
+func (event TaskEvent) String() string {
+	if event.ContainerName == "" {
+		return fmt.Sprintf("%s@%s", event.Type, event.Time.Format(time.RFC3339Nano))
+	}
+	return fmt.Sprintf("%s(%s)@%s", event.Type, event.ContainerName, event.Time.Format(time.RFC3339Nano))
+}
+
+// RecordEvent appends an event to the timeline of the task, discarding the
+// oldest event if the timeline is full
+func (task *Task) RecordEvent(event TaskEvent) {
+	task.timelineLock.Lock()
+	defer task.timelineLock.Unlock()
+
+	if len(task.timeline) >= TaskTimelineSize {
+		task.timeline = task.timeline[len(task.timeline)-TaskTimelineSize+1:]
+	}
+	task.timeline = append(task.timeline, event)
+}
+
+// GetTimeline returns a copy of the timeline of the task, ordered from the
+// oldest to the most recent event
+func (task *Task) GetTimeline() []TaskEvent {
+	task.timelineLock.RLock()
+	defer task.timelineLock.RUnlock()
+
+	if len(task.timeline) == 0 {
+		return nil
+	}
+	timeline := make([]TaskEvent, len(task.timeline))
+	copy(timeline, task.timeline)
+	return timeline
+}
@@ -0,0 +1,46 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordEventIsBounded(t *testing.T) {
+	task := &Task{}
+	assert.Empty(t, task.GetTimeline())
+
+	start := time.Now()
+	for i := 0; i < TaskTimelineSize+5; i++ {
+		task.RecordEvent(TaskEvent{Type: TaskEventStarted, Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	timeline := task.GetTimeline()
+	assert.Len(t, timeline, TaskTimelineSize)
+	// The oldest events should have been discarded
+	assert.Equal(t, start.Add(5*time.Second), timeline[0].Time)
+	assert.Equal(t, start.Add(time.Duration(TaskTimelineSize+4)*time.Second), timeline[TaskTimelineSize-1].Time)
+}
+
+func TestGetTimelineReturnsCopy(t *testing.T) {
+	task := &Task{}
+	task.RecordEvent(TaskEvent{Type: TaskEventAdded})
+
+	timeline := task.GetTimeline()
+	timeline[0].Type = TaskEventStopped
+	assert.Equal(t, TaskEventAdded, task.GetTimeline()[0].Type)
+}
@@ -43,8 +43,8 @@ func TestContainerEqual(t *testing.T) {
 		{Container{Memory: 1}, Container{Memory: 1}, true},
 		{Container{Links: []string{"1", "2"}}, Container{Links: []string{"1", "2"}}, true},
 		{Container{Links: []string{"1", "2"}}, Container{Links: []string{"2", "1"}}, true},
-		{Container{VolumesFrom: []VolumeFrom{{"1", false}, {"2", true}}}, Container{VolumesFrom: []VolumeFrom{{"1", false}, {"2", true}}}, true},
-		{Container{VolumesFrom: []VolumeFrom{{"1", false}, {"2", true}}}, Container{VolumesFrom: []VolumeFrom{{"2", true}, {"1", false}}}, true},
+		{Container{VolumesFrom: []VolumeFrom{{"1", false, false}, {"2", true, false}}}, Container{VolumesFrom: []VolumeFrom{{"1", false, false}, {"2", true, false}}}, true},
+		{Container{VolumesFrom: []VolumeFrom{{"1", false, false}, {"2", true, false}}}, Container{VolumesFrom: []VolumeFrom{{"2", true, false}, {"1", false, false}}}, true},
 		{Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolTCP}}}, Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolTCP}}}, true},
 		{Container{Essential: true}, Container{Essential: true}, true},
 		{Container{EntryPoint: nil}, Container{EntryPoint: nil}, true},
@@ -64,7 +64,7 @@ func TestContainerEqual(t *testing.T) {
 		{Container{CPU: 1}, Container{CPU: 2e2}, false},
 		{Container{Memory: 1}, Container{Memory: 2e2}, false},
 		{Container{Links: []string{"1", "2"}}, Container{Links: []string{"1", "二"}}, false},
-		{Container{VolumesFrom: []VolumeFrom{{"1", false}, {"2", true}}}, Container{VolumesFrom: []VolumeFrom{{"1", false}, {"二", false}}}, false},
+		{Container{VolumesFrom: []VolumeFrom{{"1", false, false}, {"2", true, false}}}, Container{VolumesFrom: []VolumeFrom{{"1", false, false}, {"二", false, false}}}, false},
 		{Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolTCP}}}, Container{Ports: []PortBinding{{1, 2, "二", TransportProtocolTCP}}}, false},
 		{Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolTCP}}}, Container{Ports: []PortBinding{{1, 22, "1", TransportProtocolTCP}}}, false},
 		{Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolTCP}}}, Container{Ports: []PortBinding{{1, 2, "1", TransportProtocolUDP}}}, false},
@@ -15,6 +15,14 @@ type ContainerDependency struct {
 	ContainerName string `json:"ContainerName"`
 	// SatisfiedStatus defines the status that satisfies the dependency
 	SatisfiedStatus ContainerStatus `json:"SatisfiedStatus"`
+	// SatisfiedHealth defines the health the container on which a transition
+	// depends must report, in addition to reaching SatisfiedStatus, to
+	// satisfy the dependency. It is ignored when empty
+	SatisfiedHealth ContainerHealthStatus `json:"SatisfiedHealth,omitempty"`
+	// SatisfiedReady requires the container on which a transition depends
+	// to be ready, as determined by its readiness probe, in addition to
+	// reaching SatisfiedStatus, to satisfy the dependency
+	SatisfiedReady bool `json:"SatisfiedReady,omitempty"`
 	// DependentStatus defines the status that cannot be reached until the
 	// resource satisfies the dependency
 	DependentStatus ContainerStatus `json:"DependentStatus"`
@@ -52,4 +52,8 @@ func (e *EmptyHostVolume) SourcePath() string {
 type VolumeFrom struct {
 	SourceContainer string `json:"sourceContainer"`
 	ReadOnly        bool   `json:"readOnly"`
+	// WaitForCompletion marks the source container as one that populates the
+	// volumes. The container is only started once the source container has
+	// exited successfully
+	WaitForCompletion bool `json:"waitForCompletion"`
 }
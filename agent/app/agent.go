@@ -16,6 +16,11 @@ package app
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -56,6 +61,13 @@ const (
 	instanceIDMismatchErrorFormat              = "Data mismatch; saved InstanceID '%s' does not match current InstanceID '%s'. Overwriting old datafile"
 	instanceTypeMismatchErrorFormat            = "The current instance type does not match the registered instance type. Please revert the instance type change, or alternatively launch a new instance: %v"
 
+	// generatedInstanceIDFile is the file, in the data directory, that holds
+	// the instance ID generated by the "generated" instance ID fallback policy
+	generatedInstanceIDFile = "generated-instance-id"
+	// generatedInstanceIDPrefix tells generated instance IDs apart from EC2
+	// instance IDs
+	generatedInstanceIDPrefix = "generated-"
+
 	vpcIDAttributeName    = "ecs.vpc-id"
 	subnetIDAttributeName = "ecs.subnet-id"
 )
@@ -92,6 +104,11 @@ type ecsAgent struct {
 	vpc                   string
 	subnet                string
 	mac                   string
+	// unavailableCapabilities is the list of capabilities that were requested
+	// in the config, but could not be advertised at registration
+	unavailableCapabilities []handlers.UnavailableCapability
+	// startTime is the time the agent was created
+	startTime time.Time
 }
 
 // newAgent returns a new ecsAgent object
@@ -119,7 +136,7 @@ func newAgent(
 	}
 	seelog.Debugf("Loaded config: %s", cfg.String())
 
-	dockerClient, err := engine.NewDockerGoClient(dockerclient.NewFactory(cfg.DockerEndpoint), cfg)
+	dockerClient, err := engine.NewDockerGoClient(dockerclient.NewFactoryWithPoolSize(cfg.DockerEndpoint, cfg.DockerClientPoolSize), cfg)
 	if err != nil {
 		// This is also non terminal in the current config
 		seelog.Criticalf("Error creating Docker client: %v", err)
@@ -142,7 +159,8 @@ func newAgent(
 			PluginsPath:            cfg.CNIPluginsPath,
 			MinSupportedCNIVersion: config.DefaultMinSupportedCNIVersion,
 		}),
-		os: oswrapper.New(),
+		os:        oswrapper.New(),
+		startTime: time.Now(),
 	}, nil
 }
 
@@ -182,7 +200,7 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 	}
 
 	// Initialize the state manager
-	stateManager, err := agent.newStateManager(taskEngine,
+	stateManager, err := agent.newStateManager(taskEngine, credentialsManager,
 		&agent.cfg.Cluster, &agent.containerInstanceARN, &currentEC2InstanceID)
 	if err != nil {
 		seelog.Criticalf("Error creating state manager: %v", err)
@@ -204,6 +222,8 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 			// to not update the config to disable the TaskENIEnabled flag and
 			// move on
 			seelog.Warnf("Unable to detect VPC ID for the Instance, disabling Task ENI capability: %v", err)
+			agent.markCapabilityUnavailable(attributePrefix+taskENIAttributeSuffix,
+				"instance is not launched in a VPC")
 			agent.cfg.TaskENIEnabled = false
 		default:
 			// Encountered an error initializing dependencies for dealing with
@@ -235,12 +255,60 @@ func (agent *ecsAgent) doStart(containerChangeEventStream *eventstream.EventStre
 		deregisterContainerInstanceEventStreamName, agent.ctx)
 	deregisterInstanceEventStream.StartListening()
 	taskHandler := eventhandler.NewTaskHandler(stateManager)
+	taskHandler.SetBatchWindow(agent.cfg.StateChangeBatchWindow)
+	acsCtx, cancelACS := context.WithCancel(agent.ctx)
+	defer cancelACS()
+	reregister := make(chan struct{}, 1)
+	if agent.cfg.InstanceNotFoundPolicy == config.InstanceNotFoundPolicyReregister {
+		taskHandler.SetInstanceNotFoundHandler(func() error {
+			// The sessions with the backend were started with the arn of the
+			// deregistered container instance, so the acs session is stopped
+			// for the new container instance to be registered before exiting
+			select {
+			case reregister <- struct{}{}:
+			default:
+			}
+			cancelACS()
+			return nil
+		})
+	}
 	agent.startAsyncRoutines(containerChangeEventStream, credentialsManager, imageManager,
 		taskEngine, stateManager, deregisterInstanceEventStream, client, taskHandler)
 
 	// Start the acs session, which should block doStart
-	return agent.startACSSession(credentialsManager, taskEngine, stateManager,
+	exitCode := agent.startACSSession(acsCtx, credentialsManager, taskEngine, stateManager,
 		deregisterInstanceEventStream, client, state, taskHandler)
+	select {
+	case <-reregister:
+		return agent.reregisterAndExit(taskEngine, stateManager, client, vpcSubnetAttributes)
+	default:
+		return exitCode
+	}
+}
+
+// reregisterAndExit registers a new container instance in place of the one
+// that is no longer registered and returns the exit code for the agent to be
+// restarted as the new container instance. The task engine is disabled first,
+// so that the state is not saved while the arn of the container instance
+// changes, and the new arn is saved before exiting
+func (agent *ecsAgent) reregisterAndExit(taskEngine engine.TaskEngine,
+	stateManager statemanager.StateManager,
+	client api.ECSClient,
+	additionalAttributes []*ecs.Attribute) int {
+
+	taskEngine.Disable()
+	err := agent.registerNewContainerInstance(stateManager, client, additionalAttributes)
+	if err != nil {
+		seelog.Criticalf("Unable to register a new container instance: %v", err)
+		return exitcodes.ExitError
+	}
+	err = stateManager.ForceSave()
+	if err != nil {
+		seelog.Criticalf("Unable to save the arn of the new container instance: %v", err)
+		return exitcodes.ExitError
+	}
+	seelog.Critical("Exiting to run as the new container instance")
+	return exitcodes.ExitError
 }
 
 // newTaskEngine creates a new docker task engine object. It tries to load the
@@ -265,8 +333,8 @@ func (agent *ecsAgent) newTaskEngine(containerChangeEventStream *eventstream.Eve
 
 	// previousState is used to verify that our current runtime configuration is
 	// compatible with our past configuration as reflected by our state-file
-	previousState, err := agent.newStateManager(previousTaskEngine, &previousCluster,
-		&previousContainerInstanceArn, &previousEC2InstanceID)
+	previousState, err := agent.newStateManager(previousTaskEngine, credentialsManager,
+		&previousCluster, &previousContainerInstanceArn, &previousEC2InstanceID)
 	if err != nil {
 		seelog.Criticalf("Error creating state manager: %v", err)
 		return nil, "", err
@@ -274,7 +342,12 @@ func (agent *ecsAgent) newTaskEngine(containerChangeEventStream *eventstream.Eve
 
 	err = previousState.Load()
 	if err != nil {
-		seelog.Criticalf("Error loading previously saved state: %v", err)
+		if loadErr, ok := err.(*statemanager.LoadError); ok {
+			seelog.Criticalf("Error loading previously saved state, reason: %s, field: %s, version: %d: %v",
+				loadErr.Reason, loadErr.Field, loadErr.Version, loadErr.Err)
+		} else {
+			seelog.Criticalf("Error loading previously saved state: %v", err)
+		}
 		return nil, "", err
 	}
 
@@ -324,22 +397,69 @@ func (agent *ecsAgent) setClusterInConfig(previousCluster string) error {
 	return nil
 }
 
-// getEC2InstanceID gets the EC2 instance ID from the metadata service
+// getEC2InstanceID gets the EC2 instance ID from the metadata service. If the
+// instance identity document is unavailable, the instance ID is determined by
+// the instance ID fallback policy
 func (agent *ecsAgent) getEC2InstanceID() string {
 	instanceIdentityDoc, err := agent.ec2MetadataClient.InstanceIdentityDocument()
 	if err != nil {
 		seelog.Criticalf(
 			"Unable to access EC2 Metadata service to determine EC2 ID: %v", err)
-		return ""
+		return agent.getFallbackInstanceID()
 	}
 	return instanceIdentityDoc.InstanceID
 }
 
+// getFallbackInstanceID returns the instance ID to use when the instance
+// identity document is unavailable, according to the instance ID fallback
+// policy
+func (agent *ecsAgent) getFallbackInstanceID() string {
+	switch agent.cfg.InstanceIDFallbackPolicy {
+	case config.InstanceIDFallbackPolicyConfigured:
+		seelog.Infof("Using the configured fallback instance ID: %s", agent.cfg.FallbackInstanceID)
+		return agent.cfg.FallbackInstanceID
+	case config.InstanceIDFallbackPolicyGenerated:
+		instanceID, err := loadOrGenerateInstanceID(agent.cfg.DataDir)
+		if err != nil {
+			seelog.Criticalf("Unable to load or generate the fallback instance ID: %v", err)
+			return ""
+		}
+		seelog.Infof("Using the generated fallback instance ID: %s", instanceID)
+		return instanceID
+	}
+	return ""
+}
+
+// loadOrGenerateInstanceID returns the instance ID saved in the data
+// directory, generating and saving one first if there is none, so that the
+// same ID is used across restarts of the agent
+func loadOrGenerateInstanceID(dataDir string) (string, error) {
+	idFile := filepath.Join(dataDir, generatedInstanceIDFile)
+	data, err := ioutil.ReadFile(idFile)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	instanceID := generatedInstanceIDPrefix + utils.RandHex()
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(idFile, []byte(instanceID), 0644); err != nil {
+		return "", err
+	}
+	return instanceID, nil
+}
+
 // newStateManager creates a new state manager object for the task engine.
 // Rest of the parameters are pointers and it's expected that all of these
-// will be backfilled when state manager's Load() method is invoked
+// will be backfilled when state manager's Load() method is invoked. The
+// credentials of tasks are only saved if checkpointing them is enabled
 func (agent *ecsAgent) newStateManager(
 	taskEngine engine.TaskEngine,
+	credentialsManager credentials.Manager,
 	cluster *string,
 	containerInstanceArn *string,
 	savedInstanceID *string) (statemanager.StateManager, error) {
@@ -348,7 +468,7 @@ func (agent *ecsAgent) newStateManager(
 		return statemanager.NewNoopStateManager(), nil
 	}
 
-	return agent.stateManagerFactory.NewStateManager(agent.cfg,
+	options := []statemanager.Option{
 		statemanager.AddSaveable("TaskEngine", taskEngine),
 		// This is for making testing easier as we can mock this
 		agent.saveableOptionFactory.AddSaveable("ContainerInstanceArn",
@@ -356,7 +476,12 @@ func (agent *ecsAgent) newStateManager(
 		agent.saveableOptionFactory.AddSaveable("Cluster", cluster),
 		// This is for making testing easier as we can mock this
 		agent.saveableOptionFactory.AddSaveable("EC2InstanceID", savedInstanceID),
-	)
+	}
+	if agent.cfg.TaskCredentialsCheckpointEnabled {
+		options = append(options,
+			agent.saveableOptionFactory.AddSaveable("TaskCredentials", credentialsManager))
+	}
+	return agent.stateManagerFactory.NewStateManager(agent.cfg, options...)
 }
 
 // constructVPCSubnetAttributes returns vpc and subnet IDs of the instance as
@@ -431,6 +556,25 @@ func (agent *ecsAgent) reregisterContainerInstance(client api.ECSClient, capabil
 	return transientError{err}
 }
 
+// registerNewContainerInstance registers a new container instance for the ECS
+// Agent when the one it runs as is no longer registered, e.g. because it was
+// deregistered out of band
+func (agent *ecsAgent) registerNewContainerInstance(
+	stateManager statemanager.StateManager,
+	client api.ECSClient,
+	additionalAttributes []*ecs.Attribute) error {
+
+	previousContainerInstanceARN := agent.containerInstanceARN
+	seelog.Warnf("Container instance '%s' is no longer registered, registering a new one", previousContainerInstanceARN)
+	agent.containerInstanceARN = ""
+	err := agent.registerContainerInstance(stateManager, client, additionalAttributes)
+	if err != nil {
+		agent.containerInstanceARN = previousContainerInstanceARN
+		return err
+	}
+	return nil
+}
+
 // startAsyncRoutines starts all of the background methods
 func (agent *ecsAgent) startAsyncRoutines(
 	containerChangeEventStream *eventstream.EventStream,
@@ -447,18 +591,28 @@ func (agent *ecsAgent) startAsyncRoutines(
 		go imageManager.StartImageCleanupProcess(agent.ctx)
 	}
 
-	go sighandlers.StartTerminationHandler(stateManager, taskEngine)
+	var drain func() int
+	if agent.cfg.DrainOnTerminationEnabled {
+		drain = func() int {
+			ctx, cancel := context.WithTimeout(agent.ctx, agent.cfg.DrainTimeout)
+			defer cancel()
+			return agent.drain(ctx, taskEngine)
+		}
+	}
+	go sighandlers.StartTerminationHandler(stateManager, taskEngine, drain)
+	sighandlers.StartPauseHandler(taskEngine)
 
 	// Agent introspection api
-	go handlers.ServeHttp(&agent.containerInstanceARN, taskEngine, agent.cfg)
+	go handlers.ServeHttp(&agent.containerInstanceARN, taskEngine, stateManager, taskHandler, agent.startTime, agent.cfg, agent.unavailableCapabilities)
 
 	// Start serving the endpoint to fetch IAM Role credentials
-	go credentialshandler.ServeHTTP(credentialsManager, agent.containerInstanceARN, agent.cfg)
+	go credentialshandler.ServeHTTP(credentialsManager, agent.containerInstanceARN, agent.cfg, taskEngine)
 
 	// Start sending events to the backend
 	go eventhandler.HandleEngineEvents(taskEngine, client, taskHandler)
 
 	telemetrySessionParams := tcshandler.TelemetrySessionParams{
+		Ctx:                           agent.ctx,
 		CredentialProvider:            agent.credentialProvider,
 		Cfg:                           agent.cfg,
 		ContainerInstanceArn:          agent.containerInstanceARN,
@@ -476,6 +630,7 @@ func (agent *ecsAgent) startAsyncRoutines(
 // startACSSession starts a session with ECS's Agent Communication service. This
 // is a blocking call and only returns when the handler returns
 func (agent *ecsAgent) startACSSession(
+	ctx context.Context,
 	credentialsManager credentials.Manager,
 	taskEngine engine.TaskEngine,
 	stateManager statemanager.StateManager,
@@ -485,7 +640,7 @@ func (agent *ecsAgent) startACSSession(
 	taskHandler *eventhandler.TaskHandler) int {
 
 	acsSession := acshandler.NewSession(
-		agent.ctx,
+		ctx,
 		agent.cfg,
 		deregisterInstanceEventStream,
 		agent.containerInstanceARN,
@@ -14,9 +14,13 @@
 package app
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
+	"github.com/aws/amazon-ecs-agent/agent/handlers"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/cihub/seelog"
@@ -29,6 +33,7 @@ const (
 	attributePrefix                             = "ecs.capability."
 	taskENIAttributeSuffix                      = "task-eni"
 	taskENIBlockInstanceMetadataAttributeSuffix = "task-eni-block-instance-metadata"
+	taskENISlotsAttributeSuffix                 = "task-eni-slots"
 	cniPluginVersionSuffix                      = "cni-plugin-version"
 )
 
@@ -52,6 +57,7 @@ const (
 //    com.amazonaws.ecs.capability.task-iam-role-network-host
 //    ecs.capability.task-eni
 //    ecs.capability.task-eni-block-instance-metadata
+//    ecs.capability.task-eni-slots
 func (agent *ecsAgent) capabilities() []*ecs.Attribute {
 	var capabilities []*ecs.Attribute
 
@@ -78,6 +84,9 @@ func (agent *ecsAgent) capabilities() []*ecs.Attribute {
 		requiredVersion := dockerclient.LoggingDriverMinimumVersion[loggingDriver]
 		if _, ok := knownVersions[requiredVersion]; ok {
 			capabilities = appendNameOnlyAttribute(capabilities, capabilityPrefix+"logging-driver."+string(loggingDriver))
+		} else {
+			agent.markCapabilityUnavailable(capabilityPrefix+"logging-driver."+string(loggingDriver),
+				fmt.Sprintf("requires Docker API version %s", requiredVersion))
 		}
 	}
 
@@ -99,7 +108,8 @@ func (agent *ecsAgent) capabilities() []*ecs.Attribute {
 		if _, ok := supportedVersions[dockerclient.Version_1_19]; ok {
 			capabilities = appendNameOnlyAttribute(capabilities, capabilityPrefix+capabilityTaskIAMRole)
 		} else {
-			seelog.Warn("Task IAM Role not enabled due to unsuppported Docker version")
+			agent.markCapabilityUnavailable(capabilityPrefix+capabilityTaskIAMRole,
+				fmt.Sprintf("requires Docker API version %s", dockerclient.Version_1_19))
 		}
 	}
 
@@ -108,21 +118,25 @@ func (agent *ecsAgent) capabilities() []*ecs.Attribute {
 		if _, ok := supportedVersions[dockerclient.Version_1_19]; ok {
 			capabilities = appendNameOnlyAttribute(capabilities, capabilityPrefix+capabilityTaskIAMRoleNetHost)
 		} else {
-			seelog.Warn("Task IAM Role for Host Network not enabled due to unsuppported Docker version")
+			agent.markCapabilityUnavailable(capabilityPrefix+capabilityTaskIAMRoleNetHost,
+				fmt.Sprintf("requires Docker API version %s", dockerclient.Version_1_19))
 		}
 	}
 
 	if agent.cfg.TaskENIEnabled {
 		// The assumption here is that all of the dependecies for supporting the
 		// Task ENI in the Agent have already been validated prior to the invocation of
-		// the `agent.capabilities()` call
-		capabilities = append(capabilities, &ecs.Attribute{
-			Name: aws.String(attributePrefix + taskENIAttributeSuffix),
-		})
+		// the `agent.capabilities()` call. The CNI plugins still need to be present
+		// for the version to be queried; if they aren't, 'awsvpc' can't be supported
 		taskENIVersionAttribute, err := agent.getTaskENIPluginVersionAttribute()
 		if err != nil {
+			agent.markCapabilityUnavailable(attributePrefix+taskENIAttributeSuffix,
+				fmt.Sprintf("unable to query the version of the '%s' CNI plugin: %v", ecscni.ECSENIPluginName, err))
 			return capabilities
 		}
+		capabilities = append(capabilities, &ecs.Attribute{
+			Name: aws.String(attributePrefix + taskENIAttributeSuffix),
+		})
 		capabilities = append(capabilities, taskENIVersionAttribute)
 		// We only care about AWSVPCBlockInstanceMetdata if Task ENI is enabled
 		if agent.cfg.AWSVPCBlockInstanceMetdata {
@@ -132,6 +146,13 @@ func (agent *ecsAgent) capabilities() []*ecs.Attribute {
 				Name: aws.String(attributePrefix + taskENIBlockInstanceMetadataAttributeSuffix),
 			})
 		}
+		// The number of ENI slots is only advertised if it is known
+		if agent.cfg.TaskENISlots > 0 {
+			capabilities = append(capabilities, &ecs.Attribute{
+				Name:  aws.String(attributePrefix + taskENISlotsAttributeSuffix),
+				Value: aws.String(strconv.Itoa(agent.cfg.TaskENISlots)),
+			})
+		}
 
 	}
 
@@ -159,6 +180,16 @@ func (agent *ecsAgent) getTaskENIPluginVersionAttribute() (*ecs.Attribute, error
 	}, nil
 }
 
+// markCapabilityUnavailable records a capability that was requested in the
+// config, but cannot be advertised, along with the reason for it
+func (agent *ecsAgent) markCapabilityUnavailable(name string, reason string) {
+	seelog.Warnf("Capability %s is not available: %s", name, reason)
+	agent.unavailableCapabilities = append(agent.unavailableCapabilities, handlers.UnavailableCapability{
+		Name:   name,
+		Reason: reason,
+	})
+}
+
 func appendNameOnlyAttribute(attributes []*ecs.Attribute, name string) []*ecs.Attribute {
 	return append(attributes, &ecs.Attribute{Name: aws.String(name)})
 }
@@ -14,6 +14,7 @@
 package app
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/ecs_client/model/ecs"
@@ -30,6 +31,7 @@ import (
 	aws_credentials "github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCapabilities(t *testing.T) {
@@ -208,6 +210,8 @@ func TestCapabilitiesTaskIAMRoleForUnSupportedDockerVersion(t *testing.T) {
 
 	_, ok := capMap["com.amazonaws.ecs.capability.task-iam-role"]
 	assert.False(t, ok, "task-iam-role capability set for unsupported docker version")
+	assert.Len(t, agent.unavailableCapabilities, 1)
+	assert.Equal(t, "com.amazonaws.ecs.capability.task-iam-role", agent.unavailableCapabilities[0].Name)
 }
 
 func TestCapabilitiesTaskIAMRoleNetworkHostForSupportedDockerVersion(t *testing.T) {
@@ -339,3 +343,89 @@ func TestAWSVPCBlockInstanceMetadataWhenTaskENIIsDisabled(t *testing.T) {
 		}
 	}
 }
+
+func TestCapabilitiesTaskENIWithoutCNIPlugins(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := engine.NewMockDockerClient(ctrl)
+	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
+	conf := &config.Config{
+		TaskENIEnabled:             true,
+		AWSVPCBlockInstanceMetdata: true,
+	}
+
+	gomock.InOrder(
+		client.EXPECT().SupportedVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		client.EXPECT().KnownVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		cniClient.EXPECT().Version(ecscni.ECSENIPluginName).Return("", errors.New("plugin not found")),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:          ctx,
+		cfg:          conf,
+		dockerClient: client,
+		cniClient:    cniClient,
+	}
+	capabilities := agent.capabilities()
+
+	for _, capability := range capabilities {
+		name := aws.StringValue(capability.Name)
+		assert.NotEqual(t, attributePrefix+taskENIAttributeSuffix, name)
+		assert.NotEqual(t, attributePrefix+taskENIBlockInstanceMetadataAttributeSuffix, name)
+	}
+	assert.Len(t, agent.unavailableCapabilities, 1)
+	unavailable := agent.unavailableCapabilities[0]
+	assert.Equal(t, attributePrefix+taskENIAttributeSuffix, unavailable.Name)
+	assert.Contains(t, unavailable.Reason, ecscni.ECSENIPluginName)
+	assert.Contains(t, unavailable.Reason, "plugin not found")
+}
+
+func TestCapabilitiesTaskENISlots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := engine.NewMockDockerClient(ctrl)
+	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
+	conf := &config.Config{
+		TaskENIEnabled: true,
+		TaskENISlots:   3,
+	}
+
+	gomock.InOrder(
+		client.EXPECT().SupportedVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		client.EXPECT().KnownVersions().Return([]dockerclient.DockerVersion{
+			dockerclient.Version_1_17,
+		}),
+		cniClient.EXPECT().Version(ecscni.ECSENIPluginName).Return("v1", nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:          ctx,
+		cfg:          conf,
+		dockerClient: client,
+		cniClient:    cniClient,
+	}
+	capabilities := agent.capabilities()
+
+	var slots *ecs.Attribute
+	for _, capability := range capabilities {
+		if aws.StringValue(capability.Name) == attributePrefix+taskENISlotsAttributeSuffix {
+			slots = capability
+		}
+	}
+	require.NotNil(t, slots, "ENI slots attribute not found")
+	assert.Equal(t, "3", aws.StringValue(slots.Value))
+}
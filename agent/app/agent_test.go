@@ -16,6 +16,9 @@ package app
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -40,6 +43,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -475,6 +479,47 @@ func TestNewTaskEngineRestoreFromCheckpoint(t *testing.T) {
 	assert.Equal(t, expectedInstanceID, instanceID)
 }
 
+func TestNewTaskEngineRestoreFromCheckpointWithTaskCredentials(t *testing.T) {
+	ctrl, credentialsManager, state, imageManager, _,
+		dockerClient, stateManagerFactory, saveableOptionFactory := setup(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	cfg := config.DefaultConfig()
+	cfg.Checkpoint = true
+	cfg.TaskCredentialsCheckpointEnabled = true
+	iid := ec2metadata.EC2InstanceIdentityDocument{
+		InstanceID: "inst-1",
+		Region:     "us-west-2",
+	}
+	gomock.InOrder(
+		saveableOptionFactory.EXPECT().AddSaveable("ContainerInstanceArn", gomock.Any()).Return(nil),
+		saveableOptionFactory.EXPECT().AddSaveable("Cluster", gomock.Any()).Return(nil),
+		saveableOptionFactory.EXPECT().AddSaveable("EC2InstanceID", gomock.Any()).Return(nil),
+		saveableOptionFactory.EXPECT().AddSaveable("TaskCredentials", credentialsManager).Return(nil),
+		stateManagerFactory.EXPECT().NewStateManager(gomock.Any(),
+			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+		).Return(statemanager.NewNoopStateManager(), nil),
+		ec2MetadataClient.EXPECT().InstanceIdentityDocument().Return(iid, nil),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                   ctx,
+		cfg:                   &cfg,
+		dockerClient:          dockerClient,
+		stateManagerFactory:   stateManagerFactory,
+		ec2MetadataClient:     ec2MetadataClient,
+		saveableOptionFactory: saveableOptionFactory,
+	}
+
+	_, _, err := agent.newTaskEngine(eventstream.NewEventStream("events", ctx),
+		credentialsManager, state, imageManager)
+	assert.NoError(t, err)
+}
+
 func TestSetClusterInConfigMismatch(t *testing.T) {
 	clusterNamesInConfig := []string{"", "foo"}
 	for _, clusterNameInConfig := range clusterNamesInConfig {
@@ -501,12 +546,47 @@ func TestGetEC2InstanceIDIIDError(t *testing.T) {
 	defer ctrl.Finish()
 
 	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
-	agent := &ecsAgent{ec2MetadataClient: ec2MetadataClient}
+	cfg := config.DefaultConfig()
+	agent := &ecsAgent{ec2MetadataClient: ec2MetadataClient, cfg: &cfg}
 
 	ec2MetadataClient.EXPECT().InstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{}, errors.New("error"))
 	assert.Equal(t, "", agent.getEC2InstanceID())
 }
 
+func TestGetEC2InstanceIDIIDErrorFallbackToConfiguredID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	cfg := config.DefaultConfig()
+	cfg.InstanceIDFallbackPolicy = config.InstanceIDFallbackPolicyConfigured
+	cfg.FallbackInstanceID = "on-prem-1"
+	agent := &ecsAgent{ec2MetadataClient: ec2MetadataClient, cfg: &cfg}
+
+	ec2MetadataClient.EXPECT().InstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{}, errors.New("error"))
+	assert.Equal(t, "on-prem-1", agent.getEC2InstanceID())
+}
+
+func TestGetEC2InstanceIDIIDErrorFallbackToGeneratedID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dataDir, err := ioutil.TempDir("", "ecs_generated_instance_id_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	cfg := config.DefaultConfig()
+	cfg.InstanceIDFallbackPolicy = config.InstanceIDFallbackPolicyGenerated
+	cfg.DataDir = dataDir
+	agent := &ecsAgent{ec2MetadataClient: ec2MetadataClient, cfg: &cfg}
+
+	ec2MetadataClient.EXPECT().InstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{}, errors.New("error")).Times(2)
+	instanceID := agent.getEC2InstanceID()
+	assert.True(t, strings.HasPrefix(instanceID, generatedInstanceIDPrefix), "unexpected generated instance ID: %s", instanceID)
+	assert.Equal(t, instanceID, agent.getEC2InstanceID(), "the generated instance ID should be stable")
+}
+
 func TestReregisterContainerInstanceHappyPath(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -574,6 +654,117 @@ func TestReregisterContainerInstanceInstanceTypeChanged(t *testing.T) {
 	assert.False(t, isTranisent(err))
 }
 
+func TestRegisterNewContainerInstance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDockerClient := engine.NewMockDockerClient(ctrl)
+	stateManager := mock_statemanager.NewMockStateManager(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+	mockCredentialsProvider := app_mocks.NewMockProvider(ctrl)
+
+	newContainerInstanceARN := "newContainerInstanceARN"
+	gomock.InOrder(
+		mockCredentialsProvider.EXPECT().Retrieve().Return(aws_credentials.Value{}, nil),
+		mockDockerClient.EXPECT().SupportedVersions().Return(nil),
+		mockDockerClient.EXPECT().KnownVersions().Return(nil),
+		// The deregistered container instance isn't registered again
+		client.EXPECT().RegisterContainerInstance("", gomock.Any()).Return(newContainerInstanceARN, nil),
+		stateManager.EXPECT().Save(),
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.Cluster = clusterName
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                ctx,
+		cfg:                &cfg,
+		dockerClient:       mockDockerClient,
+		credentialProvider: aws_credentials.NewCredentials(mockCredentialsProvider),
+	}
+	agent.containerInstanceARN = containerInstanceARN
+
+	err := agent.registerNewContainerInstance(stateManager, client, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, newContainerInstanceARN, agent.containerInstanceARN)
+}
+
+func TestReregisterAndExit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDockerClient := engine.NewMockDockerClient(ctrl)
+	taskEngine := engine.NewMockTaskEngine(ctrl)
+	stateManager := mock_statemanager.NewMockStateManager(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+	mockCredentialsProvider := app_mocks.NewMockProvider(ctrl)
+
+	newContainerInstanceARN := "newContainerInstanceARN"
+	gomock.InOrder(
+		// The engine is disabled before the arn changes
+		taskEngine.EXPECT().Disable(),
+		mockCredentialsProvider.EXPECT().Retrieve().Return(aws_credentials.Value{}, nil),
+		mockDockerClient.EXPECT().SupportedVersions().Return(nil),
+		mockDockerClient.EXPECT().KnownVersions().Return(nil),
+		client.EXPECT().RegisterContainerInstance("", gomock.Any()).Return(newContainerInstanceARN, nil),
+		stateManager.EXPECT().Save(),
+		// The new arn is saved regardless of the rate limit of Save
+		stateManager.EXPECT().ForceSave().Return(nil),
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.Cluster = clusterName
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                ctx,
+		cfg:                &cfg,
+		dockerClient:       mockDockerClient,
+		credentialProvider: aws_credentials.NewCredentials(mockCredentialsProvider),
+	}
+	agent.containerInstanceARN = containerInstanceARN
+
+	exitCode := agent.reregisterAndExit(taskEngine, stateManager, client, nil)
+	assert.Equal(t, exitcodes.ExitError, exitCode)
+	assert.Equal(t, newContainerInstanceARN, agent.containerInstanceARN)
+}
+
+func TestRegisterNewContainerInstanceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDockerClient := engine.NewMockDockerClient(ctrl)
+	stateManager := mock_statemanager.NewMockStateManager(ctrl)
+	client := mock_api.NewMockECSClient(ctrl)
+	mockCredentialsProvider := app_mocks.NewMockProvider(ctrl)
+
+	gomock.InOrder(
+		mockCredentialsProvider.EXPECT().Retrieve().Return(aws_credentials.Value{}, nil),
+		mockDockerClient.EXPECT().SupportedVersions().Return(nil),
+		mockDockerClient.EXPECT().KnownVersions().Return(nil),
+		client.EXPECT().RegisterContainerInstance("", gomock.Any()).Return("", errors.New("error")),
+	)
+
+	cfg := config.DefaultConfig()
+	cfg.Cluster = clusterName
+	ctx, cancel := context.WithCancel(context.TODO())
+	// Cancel the context to cancel async routines
+	defer cancel()
+	agent := &ecsAgent{
+		ctx:                ctx,
+		cfg:                &cfg,
+		dockerClient:       mockDockerClient,
+		credentialProvider: aws_credentials.NewCredentials(mockCredentialsProvider),
+	}
+	agent.containerInstanceARN = containerInstanceARN
+
+	err := agent.registerNewContainerInstance(stateManager, client, nil)
+	assert.Error(t, err)
+	assert.Equal(t, containerInstanceARN, agent.containerInstanceARN)
+}
+
 func TestReregisterContainerInstanceAttributeError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -160,7 +160,8 @@ func (agent *ecsAgent) startUdevWatcher(state dockerstate.TaskEngineState, state
 		return errors.Wrapf(err, "unable to create udev monitor")
 	}
 	// Create Watcher
-	eniWatcher := watcher.New(agent.ctx, agent.mac, udevMonitor, state, stateChangeEvents)
+	eniWatcher := watcher.New(agent.ctx, agent.mac, udevMonitor, state, stateChangeEvents,
+		agent.cfg.ENIPendingEventTimeout, agent.cfg.MaxTrackedENIs, agent.cfg.ENIReconciliationInterval)
 	if err := eniWatcher.Init(); err != nil {
 		return errors.Wrapf(err, "unable to initialize eni watcher")
 	}
@@ -145,6 +145,7 @@ func TestDoStartTaskENIHappyPath(t *testing.T) {
 	client.EXPECT().DiscoverTelemetryEndpoint(gomock.Any()).Return(
 		"tele-endpoint", nil).AnyTimes()
 
+	state.EXPECT().AllENIAttachments().Return(nil).AnyTimes()
 	gomock.InOrder(
 		mockOS.EXPECT().Getpid().Return(10),
 		mockMetadata.EXPECT().PrimaryENIMAC().Return(mac, nil),
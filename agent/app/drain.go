@@ -0,0 +1,69 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/cihub/seelog"
+	"golang.org/x/net/context"
+)
+
+// drainPollInterval is how often the tasks of the engine are checked while
+// waiting for them to stop
+var drainPollInterval = time.Second
+
+// drain stops the task engine from accepting new tasks, stops all of the tasks
+// it manages and waits for them to stop, or for the context to be done. It
+// returns the code the Agent exits with: ExitSuccess once all of the tasks are
+// stopped, and ExitTerminal if some were still running when the context was
+// done, as the Agent must not be restarted in either case
+func (agent *ecsAgent) drain(ctx context.Context, taskEngine engine.TaskEngine) int {
+	taskEngine.Drain()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		running, err := runningTasks(taskEngine)
+		if err != nil {
+			seelog.Errorf("Unable to list tasks while draining the instance: %v", err)
+		} else if running == 0 {
+			seelog.Info("All tasks are stopped, the instance is drained")
+			return exitcodes.ExitSuccess
+		}
+		select {
+		case <-ctx.Done():
+			seelog.Warnf("Timed out draining the instance with %d tasks still running", running)
+			return exitcodes.ExitTerminal
+		case <-ticker.C:
+		}
+	}
+}
+
+// runningTasks returns the number of tasks of the engine that are not stopped
+func runningTasks(taskEngine engine.TaskEngine) (int, error) {
+	tasks, err := taskEngine.ListTasks()
+	if err != nil {
+		return 0, err
+	}
+	running := 0
+	for _, task := range tasks {
+		if !task.GetKnownStatus().Terminal() {
+			running++
+		}
+	}
+	return running, nil
+}
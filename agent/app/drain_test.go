@@ -0,0 +1,86 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/aws/amazon-ecs-agent/agent/sighandlers/exitcodes"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func drainTestTasks() []*api.Task {
+	var tasks []*api.Task
+	for _, arn := range []string{"task1", "task2"} {
+		task := &api.Task{Arn: arn}
+		task.SetDesiredStatus(api.TaskRunning)
+		task.SetKnownStatus(api.TaskRunning)
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+func TestDrainWaitsForTasksToStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := engine.NewMockTaskEngine(ctrl)
+
+	defer func(interval time.Duration) { drainPollInterval = interval }(drainPollInterval)
+	drainPollInterval = time.Millisecond
+
+	tasks := drainTestTasks()
+	gomock.InOrder(
+		taskEngine.EXPECT().Drain().Do(func() {
+			for _, task := range tasks {
+				task.SetDesiredStatus(api.TaskStopped)
+			}
+		}),
+		taskEngine.EXPECT().ListTasks().Return(tasks, nil),
+		taskEngine.EXPECT().ListTasks().Do(func() {
+			for _, task := range tasks {
+				task.SetKnownStatus(api.TaskStopped)
+			}
+		}).Return(tasks, nil),
+	)
+
+	agent := &ecsAgent{}
+	exitCode := agent.drain(context.Background(), taskEngine)
+	assert.Equal(t, exitcodes.ExitSuccess, exitCode)
+	for _, task := range tasks {
+		assert.Equal(t, api.TaskStopped, task.GetDesiredStatus(), "Expected %s to be stopped", task.Arn)
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	taskEngine := engine.NewMockTaskEngine(ctrl)
+
+	defer func(interval time.Duration) { drainPollInterval = interval }(drainPollInterval)
+	drainPollInterval = time.Millisecond
+
+	taskEngine.EXPECT().Drain()
+	taskEngine.EXPECT().ListTasks().Return(drainTestTasks(), nil).MinTimes(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	agent := &ecsAgent{}
+	exitCode := agent.drain(ctx, taskEngine)
+	assert.Equal(t, exitcodes.ExitTerminal, exitCode)
+}
@@ -0,0 +1,128 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package awslogs creates the CloudWatch Logs log groups of containers that
+// use the awslogs log driver
+package awslogs
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	roundtripTimeout = 5 * time.Second
+	// logGroupTTL is how long a log group is known to exist after it was
+	// created or found to exist. It is created again after that, in case it
+	// was deleted in the meantime
+	logGroupTTL = 1 * time.Hour
+)
+
+// LogGroupCreator creates CloudWatch Logs log groups
+type LogGroupCreator interface {
+	// CreateLogGroup creates the log group in the given region. It succeeds
+	// if the log group already exists
+	CreateLogGroup(group, region string) error
+}
+
+// CloudWatchLogsSDK is an interface that specifies the subset of the AWS Go
+// SDK's CloudWatch Logs client that the Agent uses. This interface is meant to
+// allow injecting a mock for testing.
+type CloudWatchLogsSDK interface {
+	CreateLogGroup(*cloudwatchlogs.CreateLogGroupInput) (*cloudwatchlogs.CreateLogGroupOutput, error)
+}
+
+type logGroupCreator struct {
+	httpClient *http.Client
+
+	newCloudWatchLogsClient func(cfg *aws.Config) CloudWatchLogsSDK
+	time                    ttime.Time
+
+	lock sync.Mutex // guards groups
+	// groups holds the log groups that were created, or are being created
+	groups map[logGroupKey]*logGroup
+}
+
+type logGroupKey struct {
+	region string
+	group  string
+}
+
+// logGroup serializes the creation of a single log group, so that CloudWatch
+// Logs is called once for it while other log groups are created concurrently
+type logGroup struct {
+	lock sync.Mutex // guards existsSince
+	// existsSince is the time the log group was created or found to exist.
+	// It is zero if the log group was not created yet
+	existsSince time.Time
+}
+
+// NewLogGroupCreator returns a LogGroupCreator that calls CloudWatch Logs
+// with the credentials of the Agent, which are the ones the awslogs log driver
+// of Docker uses
+func NewLogGroupCreator(acceptInsecureCert bool) LogGroupCreator {
+	return &logGroupCreator{
+		httpClient: httpclient.New(roundtripTimeout, acceptInsecureCert),
+		newCloudWatchLogsClient: func(cfg *aws.Config) CloudWatchLogsSDK {
+			return cloudwatchlogs.New(session.New(cfg))
+		},
+		time:   &ttime.DefaultTime{},
+		groups: make(map[logGroupKey]*logGroup),
+	}
+}
+
+// CreateLogGroup creates the log group in the given region, unless it was
+// created or found to exist within the last hour
+func (creator *logGroupCreator) CreateLogGroup(group, region string) error {
+	entry := creator.getLogGroup(region, group)
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if !entry.existsSince.IsZero() && creator.time.Now().Sub(entry.existsSince) < logGroupTTL {
+		return nil
+	}
+
+	cfg := aws.NewConfig().WithRegion(region).WithHTTPClient(creator.httpClient)
+	_, err := creator.newCloudWatchLogsClient(cfg).CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(group),
+	})
+	if err != nil {
+		awsErr, ok := err.(awserr.Error)
+		if !ok || awsErr.Code() != cloudwatchlogs.ErrCodeResourceAlreadyExistsException {
+			return fmt.Errorf("awslogs: unable to create log group %s in region %s: %v", group, region, err)
+		}
+	}
+	entry.existsSince = creator.time.Now()
+	return nil
+}
+
+// getLogGroup returns the log group in the given region, adding it if it is
+// not known yet
+func (creator *logGroupCreator) getLogGroup(region, group string) *logGroup {
+	creator.lock.Lock()
+	defer creator.lock.Unlock()
+	key := logGroupKey{region: region, group: group}
+	entry, ok := creator.groups[key]
+	if !ok {
+		entry = &logGroup{}
+		creator.groups[key] = entry
+	}
+	return entry
+}
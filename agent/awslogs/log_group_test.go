@@ -0,0 +1,130 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package awslogs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/awslogs/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogGroupCreator(ctrl *gomock.Controller) (*logGroupCreator, *mock_awslogs.MockCloudWatchLogsSDK) {
+	client := mock_awslogs.NewMockCloudWatchLogsSDK(ctrl)
+	return &logGroupCreator{
+		newCloudWatchLogsClient: func(cfg *aws.Config) CloudWatchLogsSDK {
+			return client
+		},
+		time:   &ttime.DefaultTime{},
+		groups: make(map[logGroupKey]*logGroup),
+	}, client
+}
+
+func TestCreateLogGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	creator, client := newTestLogGroupCreator(ctrl)
+
+	client.EXPECT().CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String("myGroup"),
+	}).Return(&cloudwatchlogs.CreateLogGroupOutput{}, nil)
+
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+	// The log group is only created once
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+}
+
+func TestCreateLogGroupAlreadyExists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	creator, client := newTestLogGroupCreator(ctrl)
+
+	client.EXPECT().CreateLogGroup(gomock.Any()).Return(nil,
+		awserr.New(cloudwatchlogs.ErrCodeResourceAlreadyExistsException, "The specified log group already exists", nil))
+
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+}
+
+func TestCreateLogGroupAccessDenied(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	creator, client := newTestLogGroupCreator(ctrl)
+
+	accessDenied := awserr.New("AccessDeniedException", "not authorized to perform: logs:CreateLogGroup", nil)
+	client.EXPECT().CreateLogGroup(gomock.Any()).Return(nil, accessDenied).Times(2)
+
+	err := creator.CreateLogGroup("myGroup", "us-west-2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDeniedException")
+	// Log groups that failed to be created are tried again
+	assert.Error(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+}
+
+func TestCreateLogGroupAfterTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	creator, client := newTestLogGroupCreator(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	creator.time = mockTime
+
+	now := time.Now()
+	gomock.InOrder(
+		mockTime.EXPECT().Now().Return(now),
+		mockTime.EXPECT().Now().Return(now.Add(logGroupTTL-time.Second)),
+		mockTime.EXPECT().Now().Return(now.Add(logGroupTTL)),
+		mockTime.EXPECT().Now().Return(now.Add(logGroupTTL)),
+	)
+	client.EXPECT().CreateLogGroup(gomock.Any()).Return(&cloudwatchlogs.CreateLogGroupOutput{}, nil).Times(2)
+
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+	// The log group is created again once it is no longer known to exist
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+}
+
+func TestCreateLogGroupDoesNotBlockOtherGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	creator, client := newTestLogGroupCreator(ctrl)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	client.EXPECT().CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String("slowGroup"),
+	}).Do(func(input *cloudwatchlogs.CreateLogGroupInput) {
+		close(started)
+		<-release
+	}).Return(&cloudwatchlogs.CreateLogGroupOutput{}, nil)
+	client.EXPECT().CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String("myGroup"),
+	}).Return(&cloudwatchlogs.CreateLogGroupOutput{}, nil)
+
+	slowGroupCreated := make(chan error)
+	go func() {
+		slowGroupCreated <- creator.CreateLogGroup("slowGroup", "us-west-2")
+	}()
+	<-started
+	// Other log groups are created while CloudWatch Logs is still called for
+	// the slow one
+	assert.NoError(t, creator.CreateLogGroup("myGroup", "us-west-2"))
+	close(release)
+	assert.NoError(t, <-slowGroupCreated)
+}
@@ -0,0 +1,45 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Package cgroup manages the parent cgroups that enforce the CPU and memory
+// limits of tasks
+package cgroup
+
+const (
+	// DefaultCPUPeriod is the CFS period, in microseconds, that CPU quotas
+	// are relative to
+	DefaultCPUPeriod = 100000
+)
+
+// Spec is the path and limits of a cgroup
+type Spec struct {
+	// Path is the path of the cgroup, relative to the root of each cgroup
+	// subsystem, e.g. /ecs/<task id>
+	Path string
+	// CPUPeriod and CPUQuota are the CFS period and quota of the cgroup, in
+	// microseconds. The CPU is not limited if the quota is 0
+	CPUPeriod int64
+	CPUQuota  int64
+	// MemoryLimit is the memory limit of the cgroup in bytes. The memory is
+	// not limited if it is 0
+	MemoryLimit int64
+}
+
+// Control creates and removes cgroups
+type Control interface {
+	// Create creates the cgroup with the limits of the spec. It succeeds if
+	// the cgroup already exists, in which case its limits are updated
+	Create(spec Spec) error
+	// Remove removes the cgroup at the path. It succeeds if the cgroup does
+	// not exist
+	Remove(path string) error
+}
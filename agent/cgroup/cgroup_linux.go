@@ -0,0 +1,99 @@
+// +build linux
+
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultMountPoint is where the cgroup v1 subsystems are mounted
+	defaultMountPoint = "/sys/fs/cgroup"
+
+	cpuSubsystem    = "cpu"
+	memorySubsystem = "memory"
+
+	cpuPeriodFile   = "cpu.cfs_period_us"
+	cpuQuotaFile    = "cpu.cfs_quota_us"
+	memoryLimitFile = "memory.limit_in_bytes"
+)
+
+// control manages cgroups through the cgroup filesystem
+type control struct {
+	// mountPoint is the directory the cgroup subsystems are mounted in
+	mountPoint string
+}
+
+// New returns a Control that manages cgroups through the cgroup filesystem
+func New() (Control, error) {
+	return &control{mountPoint: defaultMountPoint}, nil
+}
+
+// Create creates the cgroup in the cpu and memory subsystems and writes its
+// limits
+func (c *control) Create(spec Spec) error {
+	cpuDir := filepath.Join(c.mountPoint, cpuSubsystem, spec.Path)
+	if err := os.MkdirAll(cpuDir, 0755); err != nil {
+		return errors.Wrapf(err, "cgroup: unable to create cpu cgroup %s", spec.Path)
+	}
+	if spec.CPUQuota > 0 {
+		period := spec.CPUPeriod
+		if period <= 0 {
+			period = DefaultCPUPeriod
+		}
+		if err := writeValue(cpuDir, cpuPeriodFile, period); err != nil {
+			return err
+		}
+		if err := writeValue(cpuDir, cpuQuotaFile, spec.CPUQuota); err != nil {
+			return err
+		}
+	}
+
+	memoryDir := filepath.Join(c.mountPoint, memorySubsystem, spec.Path)
+	if err := os.MkdirAll(memoryDir, 0755); err != nil {
+		return errors.Wrapf(err, "cgroup: unable to create memory cgroup %s", spec.Path)
+	}
+	if spec.MemoryLimit > 0 {
+		if err := writeValue(memoryDir, memoryLimitFile, spec.MemoryLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove removes the cgroup from the cpu and memory subsystems. Cgroups are
+// removed with rmdir, which fails while they still have tasks
+func (c *control) Remove(path string) error {
+	for _, subsystem := range []string{cpuSubsystem, memorySubsystem} {
+		err := os.Remove(filepath.Join(c.mountPoint, subsystem, path))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "cgroup: unable to remove %s cgroup %s", subsystem, path)
+		}
+	}
+	return nil
+}
+
+func writeValue(dir, file string, value int64) error {
+	err := ioutil.WriteFile(filepath.Join(dir, file), []byte(strconv.FormatInt(value, 10)), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "cgroup: unable to write %s", file)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// +build linux
+
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readValue(t *testing.T, path string) string {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	mountPoint, err := ioutil.TempDir("", "ecs_cgroup_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(mountPoint)
+	c := &control{mountPoint: mountPoint}
+
+	err = c.Create(Spec{Path: "/ecs/task1", CPUQuota: 50000, MemoryLimit: 512 * 1024 * 1024})
+	require.NoError(t, err)
+	assert.Equal(t, "100000", readValue(t, filepath.Join(mountPoint, "cpu", "ecs", "task1", "cpu.cfs_period_us")))
+	assert.Equal(t, "50000", readValue(t, filepath.Join(mountPoint, "cpu", "ecs", "task1", "cpu.cfs_quota_us")))
+	assert.Equal(t, "536870912", readValue(t, filepath.Join(mountPoint, "memory", "ecs", "task1", "memory.limit_in_bytes")))
+
+	// The limit files are created by the kernel in a real cgroup filesystem,
+	// so they are removed for rmdir to succeed
+	os.Remove(filepath.Join(mountPoint, "cpu", "ecs", "task1", "cpu.cfs_period_us"))
+	os.Remove(filepath.Join(mountPoint, "cpu", "ecs", "task1", "cpu.cfs_quota_us"))
+	os.Remove(filepath.Join(mountPoint, "memory", "ecs", "task1", "memory.limit_in_bytes"))
+	require.NoError(t, c.Remove("/ecs/task1"))
+	_, err = os.Stat(filepath.Join(mountPoint, "cpu", "ecs", "task1"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(mountPoint, "memory", "ecs", "task1"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Removing a cgroup that does not exist succeeds
+	assert.NoError(t, c.Remove("/ecs/task1"))
+}
+
+func TestCreateWithoutLimits(t *testing.T) {
+	mountPoint, err := ioutil.TempDir("", "ecs_cgroup_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(mountPoint)
+	c := &control{mountPoint: mountPoint}
+
+	require.NoError(t, c.Create(Spec{Path: "/ecs/task1"}))
+	// Creating a cgroup that exists succeeds
+	require.NoError(t, c.Create(Spec{Path: "/ecs/task1"}))
+	_, err = os.Stat(filepath.Join(mountPoint, "cpu", "ecs", "task1", "cpu.cfs_quota_us"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(mountPoint, "memory", "ecs", "task1", "memory.limit_in_bytes"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"reflect"
 	"strconv"
@@ -58,10 +59,43 @@ const (
 	// DefaultDockerStopTimeout specifies the value for container stop timeout duration
 	DefaultDockerStopTimeout = 30 * time.Second
 
+	// DefaultMaxContainerStopTimeout specifies the default for the longest
+	// stop timeout a container can ask for in its task definition
+	DefaultMaxContainerStopTimeout = 10 * time.Minute
+
+	// DefaultDrainTimeout specifies the default for the longest time the
+	// Agent waits for tasks to stop when draining the instance on termination
+	DefaultDrainTimeout = time.Minute
+
 	// DefaultImageCleanupTimeInterval specifies the default value for image cleanup duration. It is used to
 	// remove the images pulled by agent.
 	DefaultImageCleanupTimeInterval = 30 * time.Minute
 
+	// DefaultFilesystemMetricsInterval specifies the default interval
+	// at which the writable layer size of containers is sampled
+	DefaultFilesystemMetricsInterval = 5 * time.Minute
+
+	// DefaultSteadyStateVerifyInterval specifies the default interval at
+	// which the containers of tasks in steady state are checked with Docker
+	DefaultSteadyStateVerifyInterval = 10 * time.Minute
+
+	// DefaultENIPendingEventTimeout specifies the default duration for which
+	// udev events for ENIs whose attachment is not yet known are held
+	DefaultENIPendingEventTimeout = 1 * time.Minute
+
+	// DefaultENIReconciliationInterval specifies the default interval at
+	// which the ENIs on the instance are reconciled with their attachments
+	DefaultENIReconciliationInterval = 30 * time.Second
+
+	// DefaultSteadyStatePollLatencyThreshold specifies the default Docker
+	// response latency above which the adaptive steady state poll backs off
+	DefaultSteadyStatePollLatencyThreshold = 2 * time.Second
+
+	// MaxStateChangeBatchWindow specifies the longest window over which
+	// state changes are coalesced before they are submitted, which bounds
+	// the latency batching adds to their submission
+	MaxStateChangeBatchWindow = 10 * time.Second
+
 	// DefaultNumImagesToDeletePerCycle specifies the default number of images to delete when agent performs
 	// image cleanup.
 	DefaultNumImagesToDeletePerCycle = 5
@@ -81,6 +115,21 @@ const (
 	// image cleanup.
 	minimumImageCleanupInterval = 10 * time.Minute
 
+	// minimumFilesystemMetricsInterval specifies the minimum interval
+	// at which the writable layer size of containers is sampled, as computing
+	// it is expensive for the Docker daemon
+	minimumFilesystemMetricsInterval = 1 * time.Minute
+
+	// minimumSteadyStateVerifyInterval specifies the minimum interval at
+	// which the containers of tasks in steady state are checked with Docker,
+	// as every check describes every container of every such task
+	minimumSteadyStateVerifyInterval = 5 * time.Second
+
+	// minimumENIReconciliationInterval specifies the minimum interval at
+	// which the ENIs on the instance are reconciled, as every reconciliation
+	// lists the network interfaces of the instance
+	minimumENIReconciliationInterval = 5 * time.Second
+
 	// minimumNumImagesToDeletePerCycle specifies the minimum number of images that to be deleted when
 	// performing image cleanup.
 	minimumNumImagesToDeletePerCycle = 1
@@ -93,6 +142,117 @@ const (
 
 	// pauseContainerTarball is the path to the pause container tarball
 	pauseContainerTarballPath = "/images/amazon-ecs-pause.tar"
+
+	// MissingVolumePolicyCreate specifies that missing host volume source
+	// paths should be created before the container is created
+	MissingVolumePolicyCreate = "create"
+
+	// MissingVolumePolicyFail specifies that containers referencing missing
+	// host volume source paths should fail to be created
+	MissingVolumePolicyFail = "fail"
+
+	// MissingNetworkPolicyCreate specifies that missing user-defined docker
+	// networks should be created before the container is created
+	MissingNetworkPolicyCreate = "create"
+
+	// MissingNetworkPolicyFail specifies that containers referencing missing
+	// user-defined docker networks should fail to be created
+	MissingNetworkPolicyFail = "fail"
+
+	// PlatformMismatchPolicyWarn specifies that a warning is logged when the
+	// platform requested for a container does not match the host
+	PlatformMismatchPolicyWarn = "warn"
+
+	// PlatformMismatchPolicyFail specifies that containers whose requested
+	// platform does not match the host fail to be created
+	PlatformMismatchPolicyFail = "fail"
+
+	// WorkingDirValidationPolicyWarn specifies that a warning is logged when
+	// the working directory of a container is relative or unlikely to exist in
+	// its image
+	WorkingDirValidationPolicyWarn = "warn"
+
+	// WorkingDirValidationPolicyFail specifies that containers whose working
+	// directory is relative or unlikely to exist in their image fail to be
+	// created
+	WorkingDirValidationPolicyFail = "fail"
+
+	// ReservedLabelConflictPolicyDrop specifies that labels of containers
+	// that use the reserved com.amazonaws.ecs. prefix are dropped with a
+	// warning
+	ReservedLabelConflictPolicyDrop = "drop"
+
+	// ReservedLabelConflictPolicyFail specifies that containers with labels
+	// that use the reserved com.amazonaws.ecs. prefix fail to be created
+	ReservedLabelConflictPolicyFail = "fail"
+
+	// InstanceIDFallbackPolicyConfigured specifies that the configured
+	// fallback instance ID is used when the instance identity document is
+	// unavailable
+	InstanceIDFallbackPolicyConfigured = "configured"
+
+	// InstanceIDFallbackPolicyGenerated specifies that an ID generated once
+	// and saved in the data directory is used when the instance identity
+	// document is unavailable
+	InstanceIDFallbackPolicyGenerated = "generated"
+
+	// UnknownContainerEventPolicyIgnore specifies that Docker events for
+	// containers the Agent does not track are ignored
+	UnknownContainerEventPolicyIgnore = "ignore"
+
+	// UnknownContainerEventPolicyAdopt specifies that containers the Agent
+	// does not track are adopted into their task when Docker reports an event
+	// for them, if their labels show that the Agent created them for a task
+	// it tracks
+	UnknownContainerEventPolicyAdopt = "adopt"
+
+	// InstanceNotFoundPolicyRetry specifies that state changes that fail to
+	// be submitted because the container instance is no longer registered
+	// are retried
+	InstanceNotFoundPolicyRetry = "retry"
+
+	// InstanceNotFoundPolicyReregister specifies that the Agent registers a
+	// new container instance when a state change fails to be submitted
+	// because the container instance is no longer registered, and restarts
+	// to use it
+	InstanceNotFoundPolicyReregister = "reregister"
+
+	// MissingEssentialContainerPolicyStop specifies that restored tasks whose
+	// essential container is missing from Docker are stopped
+	MissingEssentialContainerPolicyStop = "stop"
+
+	// MissingEssentialContainerPolicyRestart specifies that restored tasks
+	// whose essential container is missing from Docker are started again
+	// from scratch
+	MissingEssentialContainerPolicyRestart = "restart"
+
+	// PausedContainerPolicyUnpause specifies that containers of restored
+	// tasks that Docker reports as paused are unpaused
+	PausedContainerPolicyUnpause = "unpause"
+
+	// PausedContainerPolicyStop specifies that restored tasks with a
+	// container that Docker reports as paused are stopped
+	PausedContainerPolicyStop = "stop"
+
+	// ImagePullBehaviorDefault specifies that the images of containers are
+	// always pulled
+	ImagePullBehaviorDefault = "default"
+
+	// ImagePullBehaviorOnce specifies that the images of containers are only
+	// pulled if the Agent has not pulled them before
+	ImagePullBehaviorOnce = "once"
+
+	// ImagePullBehaviorPreferCached specifies that the images of containers
+	// are only pulled if they are not already present on the instance
+	ImagePullBehaviorPreferCached = "prefer-cached"
+
+	// DefaultMissingVolumeDirMode specifies the default permissions of
+	// directories created for missing host volumes
+	DefaultMissingVolumeDirMode os.FileMode = 0755
+
+	// redactedValue replaces the values of sensitive fields in redacted
+	// configs
+	redactedValue = "[redacted]"
 )
 
 var (
@@ -199,6 +359,30 @@ func (cfg *Config) trimWhitespace() {
 	}
 }
 
+// Redacted returns a copy of the config in which the values of the fields
+// tagged as sensitive are masked, so that it can be shared
+func (cfg *Config) Redacted() *Config {
+	redacted := *cfg
+	cfgElem := reflect.ValueOf(&redacted).Elem()
+	cfgStructField := cfgElem.Type()
+
+	for i := 0; i < cfgElem.NumField(); i++ {
+		cfgField := cfgElem.Field(i)
+		if cfgStructField.Field(i).Tag.Get("sensitive") != "true" || utils.ZeroOrNil(cfgField.Interface()) {
+			continue
+		}
+		switch cfgField.Interface().(type) {
+		case string:
+			cfgField.SetString(redactedValue)
+		case *SensitiveRawMessage:
+			cfgField.Set(reflect.ValueOf(NewSensitiveRawMessage(json.RawMessage(`"` + redactedValue + `"`))))
+		default:
+			cfgField.Set(reflect.Zero(cfgField.Type()))
+		}
+	}
+	return &redacted
+}
+
 func fileConfig() (Config, error) {
 	fileName := utils.DefaultIfBlank(os.Getenv("ECS_AGENT_CONFIG_FILE_PATH"), defaultConfigFileName)
 	cfg := Config{}
@@ -242,6 +426,17 @@ func environmentConfig() (Config, error) {
 	dockerEndpoint := os.Getenv("DOCKER_HOST")
 	engineAuthType := os.Getenv("ECS_ENGINE_AUTH_TYPE")
 	engineAuthData := os.Getenv("ECS_ENGINE_AUTH_DATA")
+	if engineAuthDataFile := os.Getenv("ECS_ENGINE_AUTH_DATA_FILE"); engineAuthData == "" && engineAuthDataFile != "" {
+		// Auth data can be read from a file, such as a mounted secret, to
+		// keep credentials out of the environment of the Agent
+		data, err := ioutil.ReadFile(engineAuthDataFile)
+		if err != nil {
+			wrappedErr := fmt.Errorf("Unable to read ECS_ENGINE_AUTH_DATA_FILE: %v", err)
+			seelog.Error(wrappedErr)
+			errs = append(errs, wrappedErr)
+		}
+		engineAuthData = string(data)
+	}
 
 	var checkpoint bool
 	dataDir := os.Getenv("ECS_DATADIR")
@@ -283,9 +478,16 @@ func environmentConfig() (Config, error) {
 	updatesEnabled := utils.ParseBool(os.Getenv("ECS_UPDATES_ENABLED"), false)
 
 	disableMetrics := utils.ParseBool(os.Getenv("ECS_DISABLE_METRICS"), false)
+	telemetryReconnectDisabled := utils.ParseBool(os.Getenv("ECS_DISABLE_TELEMETRY_RECONNECT"), false)
+	filesystemMetricsEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_CONTAINER_FILESYSTEM_METRICS"), false)
+	filesystemMetricsInterval := parseEnvVariableDuration("ECS_CONTAINER_FILESYSTEM_METRICS_INTERVAL")
+	steadyStateVerifyInterval := parseEnvVariableDuration("ECS_STEADY_STATE_VERIFY_INTERVAL")
 
 	reservedMemory := parseEnvVariableUint16("ECS_RESERVED_MEMORY")
 
+	taskStopTimeout := parseEnvVariableDuration("ECS_TASK_STOP_TIMEOUT")
+	maxContainerStopTimeout := parseEnvVariableDuration("ECS_MAX_CONTAINER_STOP_TIMEOUT")
+
 	var dockerStopTimeout time.Duration
 	parsedStopTimeout := parseEnvVariableDuration("ECS_CONTAINER_STOP_TIMEOUT")
 	if parsedStopTimeout >= minimumDockerStopTimeout {
@@ -312,8 +514,27 @@ func environmentConfig() (Config, error) {
 	seLinuxCapable := utils.ParseBool(os.Getenv("ECS_SELINUX_CAPABLE"), false)
 	appArmorCapable := utils.ParseBool(os.Getenv("ECS_APPARMOR_CAPABLE"), false)
 	taskENIEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_ENI"), false)
+	eniPendingEventTimeout := parseEnvVariableDuration("ECS_ENI_PENDING_EVENT_TIMEOUT")
+	eniReconciliationInterval := parseEnvVariableDuration("ECS_ENI_RECONCILIATION_INTERVAL")
+	steadyStatePollMinInterval := parseEnvVariableDuration("ECS_STEADY_STATE_POLL_MIN_INTERVAL")
+	steadyStatePollMaxInterval := parseEnvVariableDuration("ECS_STEADY_STATE_POLL_MAX_INTERVAL")
+	steadyStatePollLatencyThreshold := parseEnvVariableDuration("ECS_STEADY_STATE_POLL_LATENCY_THRESHOLD")
+	stateChangeBatchWindow := parseEnvVariableDuration("ECS_STATE_CHANGE_BATCH_WINDOW")
+	unknownTaskStopEventsEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_UNKNOWN_TASK_STOP_EVENTS"), false)
+	containerExitReasonsEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_CONTAINER_EXIT_REASONS"), false)
+	startupEventReconcileEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_STARTUP_EVENT_RECONCILE"), false)
+	taskMetadataFileEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_METADATA_FILE"), false)
+	awslogsGroupCreationEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_AWSLOGS_GROUP_CREATION"), false)
+	taskCPUMemLimitEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_CPU_MEM_LIMIT"), false)
+	commandManifestDir := os.Getenv("ECS_COMMAND_MANIFEST_DIR")
+	dataDirOnHost := os.Getenv("ECS_HOST_DATA_DIR")
+	taskCredentialsCheckpointEnabled := utils.ParseBool(os.Getenv("ECS_CHECKPOINT_TASK_CREDENTIALS"), false)
+	eniCapacityFailFastEnabled := utils.ParseBool(os.Getenv("ECS_ENI_CAPACITY_FAIL_FAST"), false)
+	drainOnTerminationEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_DRAIN_ON_TERMINATION"), false)
+	drainTimeout := parseEnvVariableDuration("ECS_DRAIN_TIMEOUT")
 	taskIAMRoleEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_IAM_ROLE"), false)
 	taskIAMRoleEnabledForNetworkHost := utils.ParseBool(os.Getenv("ECS_ENABLE_TASK_IAM_ROLE_NETWORK_HOST"), false)
+	hostNetworkCredentialsEndpoint := os.Getenv("ECS_HOST_NETWORK_CREDENTIALS_ENDPOINT")
 
 	credentialsAuditLogFile := os.Getenv("ECS_AUDIT_LOGFILE")
 	credentialsAuditLogDisabled := utils.ParseBool(os.Getenv("ECS_AUDIT_LOGFILE_DISABLED"), false)
@@ -354,6 +575,81 @@ func environmentConfig() (Config, error) {
 		}
 	}
 
+	missingVolumePolicy := os.Getenv("ECS_MISSING_VOLUME_POLICY")
+	hostRootDir := os.Getenv("ECS_HOST_ROOT_DIR")
+	missingNetworkPolicy := os.Getenv("ECS_MISSING_NETWORK_POLICY")
+	platformMismatchPolicy := os.Getenv("ECS_PLATFORM_MISMATCH_POLICY")
+	workingDirValidationPolicy := os.Getenv("ECS_WORKING_DIR_VALIDATION_POLICY")
+	reservedLabelConflictPolicy := os.Getenv("ECS_RESERVED_LABEL_CONFLICT_POLICY")
+	unknownContainerEventPolicy := os.Getenv("ECS_UNKNOWN_CONTAINER_EVENT_POLICY")
+	instanceNotFoundPolicy := os.Getenv("ECS_INSTANCE_NOT_FOUND_POLICY")
+	instanceIDFallbackPolicy := os.Getenv("ECS_INSTANCE_ID_FALLBACK_POLICY")
+	fallbackInstanceID := os.Getenv("ECS_FALLBACK_INSTANCE_ID")
+	missingEssentialContainerPolicy := os.Getenv("ECS_MISSING_ESSENTIAL_CONTAINER_POLICY")
+	pausedContainerPolicy := os.Getenv("ECS_PAUSED_CONTAINER_POLICY")
+	imagePullBehavior := os.Getenv("ECS_IMAGE_PULL_BEHAVIOR")
+	imagePullDiskFullCleanupEnabled := utils.ParseBool(os.Getenv("ECS_ENABLE_IMAGE_PULL_DISK_FULL_CLEANUP"), false)
+	hostPortConflictCheckDisabled := utils.ParseBool(os.Getenv("ECS_DISABLE_HOST_PORT_CONFLICT_CHECK"), false)
+	taskCPUCapacityCheckDisabled := utils.ParseBool(os.Getenv("ECS_DISABLE_TASK_CPU_CAPACITY_CHECK"), false)
+	var missingVolumeDirMode os.FileMode
+	missingVolumeDirModeEnv := os.Getenv("ECS_MISSING_VOLUME_DIR_MODE")
+	if missingVolumeDirModeEnv != "" {
+		mode, err := strconv.ParseUint(missingVolumeDirModeEnv, 8, 32)
+		if err != nil {
+			seelog.Warnf("Invalid format for \"ECS_MISSING_VOLUME_DIR_MODE\", expected an octal file mode like 0755. err %v", err)
+		} else {
+			missingVolumeDirMode = os.FileMode(mode)
+		}
+	}
+
+	dockerClientPoolSizeEnvVal := os.Getenv("ECS_DOCKER_CLIENT_POOL_SIZE")
+	dockerClientPoolSize, err := strconv.Atoi(dockerClientPoolSizeEnvVal)
+	if dockerClientPoolSizeEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_DOCKER_CLIENT_POOL_SIZE\", expected an integer. err %v", err)
+	}
+
+	containerCreateConcurrencyEnvVal := os.Getenv("ECS_CONTAINER_CREATE_CONCURRENCY")
+	containerCreateConcurrency, err := strconv.Atoi(containerCreateConcurrencyEnvVal)
+	if containerCreateConcurrencyEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_CONTAINER_CREATE_CONCURRENCY\", expected an integer. err %v", err)
+	}
+
+	eniSetupConcurrencyEnvVal := os.Getenv("ECS_ENI_SETUP_CONCURRENCY")
+	eniSetupConcurrency, err := strconv.Atoi(eniSetupConcurrencyEnvVal)
+	if eniSetupConcurrencyEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_ENI_SETUP_CONCURRENCY\", expected an integer. err %v", err)
+	}
+
+	imagePullConcurrencyEnvVal := os.Getenv("ECS_IMAGE_PULL_CONCURRENCY")
+	imagePullConcurrency, err := strconv.Atoi(imagePullConcurrencyEnvVal)
+	if imagePullConcurrencyEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_IMAGE_PULL_CONCURRENCY\", expected an integer. err %v", err)
+	}
+
+	maxTrackedENIsEnvVal := os.Getenv("ECS_MAX_TRACKED_ENIS")
+	maxTrackedENIs, err := strconv.Atoi(maxTrackedENIsEnvVal)
+	if maxTrackedENIsEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_MAX_TRACKED_ENIS\", expected an integer. err %v", err)
+	}
+
+	taskENISlotsEnvVal := os.Getenv("ECS_TASK_ENI_SLOTS")
+	taskENISlots, err := strconv.Atoi(taskENISlotsEnvVal)
+	if taskENISlotsEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_TASK_ENI_SLOTS\", expected an integer. err %v", err)
+	}
+
+	telemetryBufferSizeEnvVal := os.Getenv("ECS_TELEMETRY_BUFFER_SIZE")
+	telemetryBufferSize, err := strconv.Atoi(telemetryBufferSizeEnvVal)
+	if telemetryBufferSizeEnvVal != "" && err != nil {
+		seelog.Warnf("Invalid format for \"ECS_TELEMETRY_BUFFER_SIZE\", expected an integer. err %v", err)
+	}
+
+	defaultDNSServers := parseEnvVariableStringSlice("ECS_DEFAULT_DNS_SERVERS")
+	defaultDNSSearch := parseEnvVariableStringSlice("ECS_DEFAULT_DNS_SEARCH")
+	instanceTagLabels := parseEnvVariableStringSlice("ECS_INSTANCE_TAG_LABELS")
+	prefetchImages := parseEnvVariableStringSlice("ECS_PREFETCH_IMAGES")
+	pauseImagePrefetchEnabled := utils.ParseBool(os.Getenv("ECS_PREFETCH_PAUSE_CONTAINER_IMAGE"), false)
+
 	if len(errs) > 0 {
 		err = utils.NewMultiError(errs...)
 	} else {
@@ -373,6 +669,11 @@ func environmentConfig() (Config, error) {
 		UpdatesEnabled:                   updatesEnabled,
 		UpdateDownloadDir:                updateDownloadDir,
 		DisableMetrics:                   disableMetrics,
+		TelemetryReconnectDisabled:       telemetryReconnectDisabled,
+		TelemetryBufferSize:              telemetryBufferSize,
+		FilesystemMetricsEnabled:         filesystemMetricsEnabled,
+		FilesystemMetricsInterval:        filesystemMetricsInterval,
+		SteadyStateVerifyInterval:        steadyStateVerifyInterval,
 		ReservedMemory:                   reservedMemory,
 		AvailableLoggingDrivers:          availableLoggingDrivers,
 		PrivilegedDisabled:               privilegedDisabled,
@@ -380,11 +681,22 @@ func environmentConfig() (Config, error) {
 		AppArmorCapable:                  appArmorCapable,
 		TaskCleanupWaitDuration:          taskCleanupWaitDuration,
 		TaskENIEnabled:                   taskENIEnabled,
+		ENIPendingEventTimeout:           eniPendingEventTimeout,
+		ENIReconciliationInterval:        eniReconciliationInterval,
+		MaxTrackedENIs:                   maxTrackedENIs,
+		TaskENISlots:                     taskENISlots,
+		SteadyStatePollMinInterval:       steadyStatePollMinInterval,
+		SteadyStatePollMaxInterval:       steadyStatePollMaxInterval,
+		SteadyStatePollLatencyThreshold:  steadyStatePollLatencyThreshold,
+		StateChangeBatchWindow:           stateChangeBatchWindow,
 		TaskIAMRoleEnabled:               taskIAMRoleEnabled,
 		DockerStopTimeout:                dockerStopTimeout,
+		TaskStopTimeout:                  taskStopTimeout,
+		MaxContainerStopTimeout:          maxContainerStopTimeout,
 		CredentialsAuditLogFile:          credentialsAuditLogFile,
 		CredentialsAuditLogDisabled:      credentialsAuditLogDisabled,
 		TaskIAMRoleEnabledForNetworkHost: taskIAMRoleEnabledForNetworkHost,
+		HostNetworkCredentialsEndpoint:   hostNetworkCredentialsEndpoint,
 		ImageCleanupDisabled:             imageCleanupDisabled,
 		MinimumImageDeletionAge:          minimumImageDeletionAge,
 		ImageCleanupInterval:             imageCleanupInterval,
@@ -393,6 +705,44 @@ func environmentConfig() (Config, error) {
 		CNIPluginsPath:                   cniPluginsPath,
 		AWSVPCBlockInstanceMetdata:       awsVPCBlockInstanceMetadata,
 		AWSVPCAdditionalLocalRoutes:      additionalLocalRoutes,
+		DefaultDNSServers:                defaultDNSServers,
+		DefaultDNSSearch:                 defaultDNSSearch,
+		MissingVolumePolicy:              missingVolumePolicy,
+		MissingVolumeDirMode:             missingVolumeDirMode,
+		HostRootDir:                      hostRootDir,
+		MissingNetworkPolicy:             missingNetworkPolicy,
+		DockerClientPoolSize:             dockerClientPoolSize,
+		ContainerCreateConcurrency:       containerCreateConcurrency,
+		ENISetupConcurrency:              eniSetupConcurrency,
+		ImagePullConcurrency:             imagePullConcurrency,
+		PlatformMismatchPolicy:           platformMismatchPolicy,
+		WorkingDirValidationPolicy:       workingDirValidationPolicy,
+		ReservedLabelConflictPolicy:      reservedLabelConflictPolicy,
+		UnknownContainerEventPolicy:      unknownContainerEventPolicy,
+		InstanceNotFoundPolicy:           instanceNotFoundPolicy,
+		MissingEssentialContainerPolicy:  missingEssentialContainerPolicy,
+		PausedContainerPolicy:            pausedContainerPolicy,
+		ImagePullBehavior:                imagePullBehavior,
+		ImagePullDiskFullCleanupEnabled:  imagePullDiskFullCleanupEnabled,
+		HostPortConflictCheckDisabled:    hostPortConflictCheckDisabled,
+		TaskCPUCapacityCheckDisabled:     taskCPUCapacityCheckDisabled,
+		UnknownTaskStopEventsEnabled:     unknownTaskStopEventsEnabled,
+		ContainerExitReasonsEnabled:      containerExitReasonsEnabled,
+		InstanceTagLabels:                instanceTagLabels,
+		PrefetchImages:                   prefetchImages,
+		PauseImagePrefetchEnabled:        pauseImagePrefetchEnabled,
+		StartupEventReconcileEnabled:     startupEventReconcileEnabled,
+		TaskMetadataFileEnabled:          taskMetadataFileEnabled,
+		AWSLogsGroupCreationEnabled:      awslogsGroupCreationEnabled,
+		TaskCPUMemLimitEnabled:           taskCPUMemLimitEnabled,
+		CommandManifestDir:               commandManifestDir,
+		DataDirOnHost:                    dataDirOnHost,
+		TaskCredentialsCheckpointEnabled: taskCredentialsCheckpointEnabled,
+		ENICapacityFailFastEnabled:       eniCapacityFailFastEnabled,
+		DrainOnTerminationEnabled:        drainOnTerminationEnabled,
+		DrainTimeout:                     drainTimeout,
+		InstanceIDFallbackPolicy:         instanceIDFallbackPolicy,
+		FallbackInstanceID:               fallbackInstanceID,
 	}, err
 }
 
@@ -410,6 +760,18 @@ func parseEnvVariableUint16(envVar string) uint16 {
 	return var16
 }
 
+func parseEnvVariableStringSlice(envVar string) []string {
+	envVal := os.Getenv(envVar)
+	var slice []string
+	if envVal != "" {
+		err := json.Unmarshal([]byte(envVal), &slice)
+		if err != nil {
+			seelog.Warnf("Invalid format for \""+envVar+"\" environment variable; expected a JSON array like [\"a\",\"b\"]. err %v", err)
+		}
+	}
+	return slice
+}
+
 func parseEnvVariableDuration(envVar string) time.Duration {
 	var duration time.Duration
 	envVal := os.Getenv(envVar)
@@ -434,6 +796,23 @@ func ec2MetadataConfig(ec2client ec2.EC2MetadataClient) Config {
 	return Config{AWSRegion: iid.Region}
 }
 
+// taskENISlotsFromInstanceType returns the number of ENIs that tasks can use on
+// the instance, derived from its instance type. It returns 0 if the instance
+// type or its limit is not known
+func taskENISlotsFromInstanceType(ec2client ec2.EC2MetadataClient) int {
+	iid, err := ec2client.InstanceIdentityDocument()
+	if err != nil {
+		seelog.Errorf("Unable to get the instance type from EC2 Metadata, tasks will not fail fast without ENI capacity unless ECS_TASK_ENI_SLOTS is set: %v", err)
+		return 0
+	}
+	slots, ok := ec2.TaskENISlots(iid.InstanceType)
+	if !ok {
+		seelog.Errorf("Unknown ENI limit for instance type %s, tasks will not fail fast without ENI capacity unless ECS_TASK_ENI_SLOTS is set", iid.InstanceType)
+		return 0
+	}
+	return slots
+}
+
 // NewConfig returns a config struct created by merging environment variables,
 // a config file, and EC2 Metadata info.
 // The 'config' struct it returns can be used, even if an error is returned. An
@@ -477,6 +856,11 @@ func NewConfig(ec2client ec2.EC2MetadataClient) (config *Config, err error) {
 		config.Merge(ec2MetadataConfig(ec2client))
 	}
 
+	if config.ENICapacityFailFastEnabled && config.TaskENISlots == 0 {
+		// Derive it from metadata only if it is used and not configured
+		config.TaskENISlots = taskENISlotsFromInstanceType(ec2client)
+	}
+
 	return config, err
 }
 
@@ -519,6 +903,184 @@ func (cfg *Config) validateAndOverrideBounds() error {
 		cfg.NumImagesToDeletePerCycle = DefaultNumImagesToDeletePerCycle
 	}
 
+	if cfg.MissingVolumePolicy != "" &&
+		cfg.MissingVolumePolicy != MissingVolumePolicyCreate &&
+		cfg.MissingVolumePolicy != MissingVolumePolicyFail {
+		seelog.Warnf("Invalid value for missing volume policy, will be ignored. Parsed value: %s, valid values: %s, %s.", cfg.MissingVolumePolicy, MissingVolumePolicyCreate, MissingVolumePolicyFail)
+		cfg.MissingVolumePolicy = ""
+	}
+
+	if cfg.MissingNetworkPolicy != "" &&
+		cfg.MissingNetworkPolicy != MissingNetworkPolicyCreate &&
+		cfg.MissingNetworkPolicy != MissingNetworkPolicyFail {
+		seelog.Warnf("Invalid value for missing network policy, will be ignored. Parsed value: %s, valid values: %s, %s.", cfg.MissingNetworkPolicy, MissingNetworkPolicyCreate, MissingNetworkPolicyFail)
+		cfg.MissingNetworkPolicy = ""
+	}
+
+	if cfg.PlatformMismatchPolicy != PlatformMismatchPolicyWarn &&
+		cfg.PlatformMismatchPolicy != PlatformMismatchPolicyFail {
+		seelog.Warnf("Invalid value for platform mismatch policy, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.", PlatformMismatchPolicyWarn, cfg.PlatformMismatchPolicy, PlatformMismatchPolicyWarn, PlatformMismatchPolicyFail)
+		cfg.PlatformMismatchPolicy = PlatformMismatchPolicyWarn
+	}
+
+	if cfg.WorkingDirValidationPolicy != "" &&
+		cfg.WorkingDirValidationPolicy != WorkingDirValidationPolicyWarn &&
+		cfg.WorkingDirValidationPolicy != WorkingDirValidationPolicyFail {
+		seelog.Warnf("Invalid value for working directory validation policy, will be ignored. Parsed value: %s, valid values: %s, %s.", cfg.WorkingDirValidationPolicy, WorkingDirValidationPolicyWarn, WorkingDirValidationPolicyFail)
+		cfg.WorkingDirValidationPolicy = ""
+	}
+
+	if cfg.ReservedLabelConflictPolicy != "" &&
+		cfg.ReservedLabelConflictPolicy != ReservedLabelConflictPolicyDrop &&
+		cfg.ReservedLabelConflictPolicy != ReservedLabelConflictPolicyFail {
+		seelog.Warnf("Invalid value for reserved label conflict policy, will be ignored. Parsed value: %s, valid values: %s, %s.", cfg.ReservedLabelConflictPolicy, ReservedLabelConflictPolicyDrop, ReservedLabelConflictPolicyFail)
+		cfg.ReservedLabelConflictPolicy = ""
+	}
+
+	if cfg.InstanceIDFallbackPolicy != "" &&
+		cfg.InstanceIDFallbackPolicy != InstanceIDFallbackPolicyConfigured &&
+		cfg.InstanceIDFallbackPolicy != InstanceIDFallbackPolicyGenerated {
+		seelog.Warnf("Invalid value for instance ID fallback policy, will be ignored. Parsed value: %s, valid values: %s, %s.", cfg.InstanceIDFallbackPolicy, InstanceIDFallbackPolicyConfigured, InstanceIDFallbackPolicyGenerated)
+		cfg.InstanceIDFallbackPolicy = ""
+	}
+	if cfg.InstanceIDFallbackPolicy == InstanceIDFallbackPolicyConfigured && cfg.FallbackInstanceID == "" {
+		seelog.Warnf("Instance ID fallback policy %s requires a fallback instance ID, will be ignored.", InstanceIDFallbackPolicyConfigured)
+		cfg.InstanceIDFallbackPolicy = ""
+	}
+
+	if cfg.UnknownContainerEventPolicy != UnknownContainerEventPolicyIgnore &&
+		cfg.UnknownContainerEventPolicy != UnknownContainerEventPolicyAdopt {
+		seelog.Warnf("Invalid value for unknown container event policy, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.", UnknownContainerEventPolicyIgnore, cfg.UnknownContainerEventPolicy, UnknownContainerEventPolicyIgnore, UnknownContainerEventPolicyAdopt)
+		cfg.UnknownContainerEventPolicy = UnknownContainerEventPolicyIgnore
+	}
+
+	if cfg.InstanceNotFoundPolicy != InstanceNotFoundPolicyRetry &&
+		cfg.InstanceNotFoundPolicy != InstanceNotFoundPolicyReregister {
+		seelog.Warnf("Invalid value for instance not found policy, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.", InstanceNotFoundPolicyRetry, cfg.InstanceNotFoundPolicy, InstanceNotFoundPolicyRetry, InstanceNotFoundPolicyReregister)
+		cfg.InstanceNotFoundPolicy = InstanceNotFoundPolicyRetry
+	}
+
+	if cfg.MissingEssentialContainerPolicy != MissingEssentialContainerPolicyStop &&
+		cfg.MissingEssentialContainerPolicy != MissingEssentialContainerPolicyRestart {
+		seelog.Warnf("Invalid value for missing essential container policy, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.", MissingEssentialContainerPolicyStop, cfg.MissingEssentialContainerPolicy, MissingEssentialContainerPolicyStop, MissingEssentialContainerPolicyRestart)
+		cfg.MissingEssentialContainerPolicy = MissingEssentialContainerPolicyStop
+	}
+
+	if cfg.PausedContainerPolicy != PausedContainerPolicyUnpause &&
+		cfg.PausedContainerPolicy != PausedContainerPolicyStop {
+		seelog.Warnf("Invalid value for paused container policy, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s.", PausedContainerPolicyUnpause, cfg.PausedContainerPolicy, PausedContainerPolicyUnpause, PausedContainerPolicyStop)
+		cfg.PausedContainerPolicy = PausedContainerPolicyUnpause
+	}
+
+	if cfg.ImagePullBehavior != ImagePullBehaviorDefault &&
+		cfg.ImagePullBehavior != ImagePullBehaviorOnce &&
+		cfg.ImagePullBehavior != ImagePullBehaviorPreferCached {
+		seelog.Warnf("Invalid value for image pull behavior, will be overridden with the default value: %s. Parsed value: %s, valid values: %s, %s, %s.", ImagePullBehaviorDefault, cfg.ImagePullBehavior, ImagePullBehaviorDefault, ImagePullBehaviorOnce, ImagePullBehaviorPreferCached)
+		cfg.ImagePullBehavior = ImagePullBehaviorDefault
+	}
+
+	if cfg.HostNetworkCredentialsEndpoint != "" {
+		endpoint, err := url.Parse(cfg.HostNetworkCredentialsEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			seelog.Warnf("Invalid value for host network credentials endpoint, will be ignored. Parsed value: %s, expected an http or https URL.", cfg.HostNetworkCredentialsEndpoint)
+			cfg.HostNetworkCredentialsEndpoint = ""
+		} else {
+			cfg.HostNetworkCredentialsEndpoint = strings.TrimSuffix(cfg.HostNetworkCredentialsEndpoint, "/")
+		}
+	}
+
+	if cfg.DockerClientPoolSize < 0 {
+		seelog.Warnf("Invalid value for docker client pool size, will be ignored. Parsed value: %d, minimum value: 0.", cfg.DockerClientPoolSize)
+		cfg.DockerClientPoolSize = 0
+	}
+
+	if cfg.ContainerCreateConcurrency < 0 {
+		seelog.Warnf("Invalid value for container create concurrency, will be ignored. Parsed value: %d, minimum value: 0.", cfg.ContainerCreateConcurrency)
+		cfg.ContainerCreateConcurrency = 0
+	}
+
+	if cfg.ENISetupConcurrency < 0 {
+		seelog.Warnf("Invalid value for ENI setup concurrency, will be ignored. Parsed value: %d, minimum value: 0.", cfg.ENISetupConcurrency)
+		cfg.ENISetupConcurrency = 0
+	}
+
+	if cfg.ImagePullConcurrency < 0 {
+		seelog.Warnf("Invalid value for image pull concurrency, will be ignored. Parsed value: %d, minimum value: 0.", cfg.ImagePullConcurrency)
+		cfg.ImagePullConcurrency = 0
+	}
+
+	if cfg.MaxTrackedENIs < 0 {
+		seelog.Warnf("Invalid value for maximum number of tracked ENIs, will be ignored. Parsed value: %d, minimum value: 0.", cfg.MaxTrackedENIs)
+		cfg.MaxTrackedENIs = 0
+	}
+
+	if cfg.TaskENISlots < 0 {
+		seelog.Warnf("Invalid value for number of task ENI slots, will be ignored. Parsed value: %d, minimum value: 0.", cfg.TaskENISlots)
+		cfg.TaskENISlots = 0
+	}
+
+	if cfg.ENIReconciliationInterval < minimumENIReconciliationInterval {
+		seelog.Warnf("Invalid value for ENI reconciliation interval, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultENIReconciliationInterval.String(), cfg.ENIReconciliationInterval, minimumENIReconciliationInterval)
+		cfg.ENIReconciliationInterval = DefaultENIReconciliationInterval
+	}
+
+	if cfg.FilesystemMetricsInterval < minimumFilesystemMetricsInterval {
+		seelog.Warnf("Invalid value for container filesystem metrics interval, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultFilesystemMetricsInterval.String(), cfg.FilesystemMetricsInterval, minimumFilesystemMetricsInterval)
+		cfg.FilesystemMetricsInterval = DefaultFilesystemMetricsInterval
+	}
+
+	if cfg.SteadyStateVerifyInterval < minimumSteadyStateVerifyInterval {
+		seelog.Warnf("Invalid value for steady state verify interval, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultSteadyStateVerifyInterval.String(), cfg.SteadyStateVerifyInterval, minimumSteadyStateVerifyInterval)
+		cfg.SteadyStateVerifyInterval = DefaultSteadyStateVerifyInterval
+	}
+
+	if cfg.SteadyStatePollMinInterval == 0 {
+		cfg.SteadyStatePollMinInterval = cfg.SteadyStateVerifyInterval
+	} else if cfg.SteadyStatePollMinInterval < minimumSteadyStateVerifyInterval || cfg.SteadyStatePollMinInterval > cfg.SteadyStateVerifyInterval {
+		seelog.Warnf("Invalid value for minimum steady state poll interval, will be overridden with the steady state verify interval: %v. Parsed value: %v, minimum value: %v.", cfg.SteadyStateVerifyInterval, cfg.SteadyStatePollMinInterval, minimumSteadyStateVerifyInterval)
+		cfg.SteadyStatePollMinInterval = cfg.SteadyStateVerifyInterval
+	}
+
+	if cfg.SteadyStatePollMaxInterval != 0 && cfg.SteadyStatePollMaxInterval < cfg.SteadyStateVerifyInterval {
+		seelog.Warnf("Invalid value for maximum steady state poll interval, the interval will not be adapted. Parsed value: %v, minimum value: %v.", cfg.SteadyStatePollMaxInterval, cfg.SteadyStateVerifyInterval)
+		cfg.SteadyStatePollMaxInterval = 0
+	}
+
+	if cfg.SteadyStatePollLatencyThreshold == 0 {
+		cfg.SteadyStatePollLatencyThreshold = DefaultSteadyStatePollLatencyThreshold
+	} else if cfg.SteadyStatePollLatencyThreshold < 0 {
+		seelog.Warnf("Invalid value for steady state poll latency threshold, will be overridden with the default value: %s. Parsed value: %v.", DefaultSteadyStatePollLatencyThreshold.String(), cfg.SteadyStatePollLatencyThreshold)
+		cfg.SteadyStatePollLatencyThreshold = DefaultSteadyStatePollLatencyThreshold
+	}
+
+	if cfg.StateChangeBatchWindow < 0 {
+		seelog.Warnf("Invalid value for state change batch window, will be ignored. Parsed value: %v, minimum value: 0.", cfg.StateChangeBatchWindow)
+		cfg.StateChangeBatchWindow = 0
+	} else if cfg.StateChangeBatchWindow > MaxStateChangeBatchWindow {
+		seelog.Warnf("Invalid value for state change batch window, will be overridden with the maximum value: %s. Parsed value: %v.", MaxStateChangeBatchWindow.String(), cfg.StateChangeBatchWindow)
+		cfg.StateChangeBatchWindow = MaxStateChangeBatchWindow
+	}
+
+	if cfg.TaskStopTimeout < 0 {
+		seelog.Warnf("Invalid value for task stop timeout, will be ignored. Parsed value: %v, minimum value: 0.", cfg.TaskStopTimeout)
+		cfg.TaskStopTimeout = 0
+	}
+
+	if cfg.MaxContainerStopTimeout < minimumDockerStopTimeout {
+		seelog.Warnf("Invalid value for max container stop timeout, will be overridden with the default value: %s. Parsed value: %v, minimum value: %v.", DefaultMaxContainerStopTimeout.String(), cfg.MaxContainerStopTimeout, minimumDockerStopTimeout)
+		cfg.MaxContainerStopTimeout = DefaultMaxContainerStopTimeout
+	}
+
+	if cfg.DrainTimeout <= 0 {
+		seelog.Warnf("Invalid value for drain timeout, will be overridden with the default value: %s. Parsed value: %v.", DefaultDrainTimeout.String(), cfg.DrainTimeout)
+		cfg.DrainTimeout = DefaultDrainTimeout
+	}
+
+	if cfg.TelemetryBufferSize < 0 {
+		seelog.Warnf("Invalid value for telemetry buffer size, will be ignored. Parsed value: %d, minimum value: 0.", cfg.TelemetryBufferSize)
+		cfg.TelemetryBufferSize = 0
+	}
+
 	cfg.platformOverrides()
 
 	return nil
@@ -16,6 +16,7 @@ package config
 import (
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"testing"
@@ -28,6 +29,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMerge(t *testing.T) {
@@ -60,6 +62,60 @@ func TestBrokenEC2Metadata(t *testing.T) {
 	}
 }
 
+func TestTaskENISlotsDerivedFromInstanceType(t *testing.T) {
+	os.Clearenv()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEc2Metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	mockEc2Metadata.EXPECT().InstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{
+		InstanceType: "m4.xlarge",
+	}, nil)
+	os.Setenv("AWS_DEFAULT_REGION", "us-west-2")
+	defer os.Unsetenv("AWS_DEFAULT_REGION")
+	os.Setenv("ECS_ENI_CAPACITY_FAIL_FAST", "true")
+	defer os.Unsetenv("ECS_ENI_CAPACITY_FAIL_FAST")
+
+	cfg, err := NewConfig(mockEc2Metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, cfg.TaskENISlots)
+}
+
+func TestTaskENISlotsUnknownInstanceType(t *testing.T) {
+	os.Clearenv()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockEc2Metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	mockEc2Metadata.EXPECT().InstanceIdentityDocument().Return(ec2metadata.EC2InstanceIdentityDocument{
+		InstanceType: "unknown.type",
+	}, nil)
+	os.Setenv("AWS_DEFAULT_REGION", "us-west-2")
+	defer os.Unsetenv("AWS_DEFAULT_REGION")
+	os.Setenv("ECS_ENI_CAPACITY_FAIL_FAST", "true")
+	defer os.Unsetenv("ECS_ENI_CAPACITY_FAIL_FAST")
+
+	cfg, err := NewConfig(mockEc2Metadata)
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.TaskENISlots)
+}
+
+func TestTaskENISlotsConfiguredOverridesInstanceType(t *testing.T) {
+	os.Clearenv()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// The instance type is not queried when the number of slots is configured
+	mockEc2Metadata := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	os.Setenv("AWS_DEFAULT_REGION", "us-west-2")
+	defer os.Unsetenv("AWS_DEFAULT_REGION")
+	os.Setenv("ECS_ENI_CAPACITY_FAIL_FAST", "true")
+	defer os.Unsetenv("ECS_ENI_CAPACITY_FAIL_FAST")
+	os.Setenv("ECS_TASK_ENI_SLOTS", "7")
+	defer os.Unsetenv("ECS_TASK_ENI_SLOTS")
+
+	cfg, err := NewConfig(mockEc2Metadata)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, cfg.TaskENISlots)
+}
+
 func TestBrokenEC2MetadataEndpoint(t *testing.T) {
 	os.Clearenv()
 	ctrl := gomock.NewController(t)
@@ -105,6 +161,8 @@ func TestEnvironmentConfig(t *testing.T) {
 	defer os.Unsetenv("ECS_ENABLE_TASK_IAM_ROLE")
 	os.Setenv("ECS_ENABLE_TASK_IAM_ROLE_NETWORK_HOST", "true")
 	defer os.Unsetenv("ECS_ENABLE_TASK_IAM_ROLE_NETWORK_HOST")
+	os.Setenv("ECS_HOST_NETWORK_CREDENTIALS_ENDPOINT", "http://127.0.0.1:51679")
+	defer os.Unsetenv("ECS_HOST_NETWORK_CREDENTIALS_ENDPOINT")
 	os.Setenv("ECS_DISABLE_IMAGE_CLEANUP", "true")
 	defer os.Unsetenv("ECS_DISABLE_IMAGE_CLEANUP")
 	os.Setenv("ECS_IMAGE_CLEANUP_INTERVAL", "2h")
@@ -117,6 +175,112 @@ func TestEnvironmentConfig(t *testing.T) {
 	defer os.Unsetenv("ECS_INSTANCE_ATTRIBUTES")
 	os.Setenv("ECS_ENABLE_TASK_ENI", "true")
 	defer os.Unsetenv("ECS_ENABLE_TASK_ENI")
+	os.Setenv("ECS_DEFAULT_DNS_SERVERS", `["169.254.169.253","10.0.0.2"]`)
+	defer os.Unsetenv("ECS_DEFAULT_DNS_SERVERS")
+	os.Setenv("ECS_DEFAULT_DNS_SEARCH", `["example.com"]`)
+	defer os.Unsetenv("ECS_DEFAULT_DNS_SEARCH")
+	os.Setenv("ECS_MISSING_VOLUME_POLICY", "create")
+	defer os.Unsetenv("ECS_MISSING_VOLUME_POLICY")
+	os.Setenv("ECS_MISSING_VOLUME_DIR_MODE", "0700")
+	defer os.Unsetenv("ECS_MISSING_VOLUME_DIR_MODE")
+	os.Setenv("ECS_HOST_ROOT_DIR", "/host")
+	defer os.Unsetenv("ECS_HOST_ROOT_DIR")
+	os.Setenv("ECS_MISSING_NETWORK_POLICY", "fail")
+	defer os.Unsetenv("ECS_MISSING_NETWORK_POLICY")
+	os.Setenv("ECS_DOCKER_CLIENT_POOL_SIZE", "16")
+	defer os.Unsetenv("ECS_DOCKER_CLIENT_POOL_SIZE")
+	os.Setenv("ECS_CONTAINER_CREATE_CONCURRENCY", "4")
+	defer os.Unsetenv("ECS_CONTAINER_CREATE_CONCURRENCY")
+	os.Setenv("ECS_ENI_SETUP_CONCURRENCY", "3")
+	defer os.Unsetenv("ECS_ENI_SETUP_CONCURRENCY")
+	os.Setenv("ECS_IMAGE_PULL_CONCURRENCY", "2")
+	defer os.Unsetenv("ECS_IMAGE_PULL_CONCURRENCY")
+	os.Setenv("ECS_ENABLE_DRAIN_ON_TERMINATION", "true")
+	defer os.Unsetenv("ECS_ENABLE_DRAIN_ON_TERMINATION")
+	os.Setenv("ECS_DRAIN_TIMEOUT", "90s")
+	defer os.Unsetenv("ECS_DRAIN_TIMEOUT")
+	os.Setenv("ECS_MAX_TRACKED_ENIS", "64")
+	defer os.Unsetenv("ECS_MAX_TRACKED_ENIS")
+	os.Setenv("ECS_PLATFORM_MISMATCH_POLICY", "fail")
+	defer os.Unsetenv("ECS_PLATFORM_MISMATCH_POLICY")
+	os.Setenv("ECS_WORKING_DIR_VALIDATION_POLICY", "warn")
+	defer os.Unsetenv("ECS_WORKING_DIR_VALIDATION_POLICY")
+	os.Setenv("ECS_RESERVED_LABEL_CONFLICT_POLICY", "fail")
+	defer os.Unsetenv("ECS_RESERVED_LABEL_CONFLICT_POLICY")
+	os.Setenv("ECS_UNKNOWN_CONTAINER_EVENT_POLICY", "adopt")
+	defer os.Unsetenv("ECS_UNKNOWN_CONTAINER_EVENT_POLICY")
+	os.Setenv("ECS_INSTANCE_NOT_FOUND_POLICY", "reregister")
+	defer os.Unsetenv("ECS_INSTANCE_NOT_FOUND_POLICY")
+	os.Setenv("ECS_MISSING_ESSENTIAL_CONTAINER_POLICY", "restart")
+	defer os.Unsetenv("ECS_MISSING_ESSENTIAL_CONTAINER_POLICY")
+	os.Setenv("ECS_PAUSED_CONTAINER_POLICY", "stop")
+	defer os.Unsetenv("ECS_PAUSED_CONTAINER_POLICY")
+	os.Setenv("ECS_IMAGE_PULL_BEHAVIOR", "prefer-cached")
+	defer os.Unsetenv("ECS_IMAGE_PULL_BEHAVIOR")
+	os.Setenv("ECS_ENABLE_IMAGE_PULL_DISK_FULL_CLEANUP", "true")
+	defer os.Unsetenv("ECS_ENABLE_IMAGE_PULL_DISK_FULL_CLEANUP")
+	os.Setenv("ECS_DISABLE_HOST_PORT_CONFLICT_CHECK", "true")
+	defer os.Unsetenv("ECS_DISABLE_HOST_PORT_CONFLICT_CHECK")
+	os.Setenv("ECS_DISABLE_TASK_CPU_CAPACITY_CHECK", "true")
+	defer os.Unsetenv("ECS_DISABLE_TASK_CPU_CAPACITY_CHECK")
+	os.Setenv("ECS_ENABLE_UNKNOWN_TASK_STOP_EVENTS", "true")
+	defer os.Unsetenv("ECS_ENABLE_UNKNOWN_TASK_STOP_EVENTS")
+	os.Setenv("ECS_ENABLE_CONTAINER_EXIT_REASONS", "true")
+	defer os.Unsetenv("ECS_ENABLE_CONTAINER_EXIT_REASONS")
+	os.Setenv("ECS_ENABLE_STARTUP_EVENT_RECONCILE", "true")
+	defer os.Unsetenv("ECS_ENABLE_STARTUP_EVENT_RECONCILE")
+	os.Setenv("ECS_ENABLE_TASK_METADATA_FILE", "true")
+	defer os.Unsetenv("ECS_ENABLE_TASK_METADATA_FILE")
+	os.Setenv("ECS_ENABLE_AWSLOGS_GROUP_CREATION", "true")
+	defer os.Unsetenv("ECS_ENABLE_AWSLOGS_GROUP_CREATION")
+	os.Setenv("ECS_ENABLE_TASK_CPU_MEM_LIMIT", "true")
+	defer os.Unsetenv("ECS_ENABLE_TASK_CPU_MEM_LIMIT")
+	os.Setenv("ECS_HOST_DATA_DIR", "/var/lib/ecs-test")
+	defer os.Unsetenv("ECS_HOST_DATA_DIR")
+	os.Setenv("ECS_COMMAND_MANIFEST_DIR", "/etc/ecs/manifests")
+	defer os.Unsetenv("ECS_COMMAND_MANIFEST_DIR")
+	os.Setenv("ECS_CHECKPOINT_TASK_CREDENTIALS", "true")
+	defer os.Unsetenv("ECS_CHECKPOINT_TASK_CREDENTIALS")
+	os.Setenv("ECS_ENI_CAPACITY_FAIL_FAST", "true")
+	defer os.Unsetenv("ECS_ENI_CAPACITY_FAIL_FAST")
+	os.Setenv("ECS_TASK_ENI_SLOTS", "5")
+	defer os.Unsetenv("ECS_TASK_ENI_SLOTS")
+	os.Setenv("ECS_INSTANCE_ID_FALLBACK_POLICY", "configured")
+	defer os.Unsetenv("ECS_INSTANCE_ID_FALLBACK_POLICY")
+	os.Setenv("ECS_FALLBACK_INSTANCE_ID", "on-prem-1")
+	defer os.Unsetenv("ECS_FALLBACK_INSTANCE_ID")
+	os.Setenv("ECS_INSTANCE_TAG_LABELS", "[\"CostCenter\",\"Team\"]")
+	defer os.Unsetenv("ECS_INSTANCE_TAG_LABELS")
+	os.Setenv("ECS_PREFETCH_IMAGES", "[\"busybox:latest\",\"amazon/amazon-ecs-sample\"]")
+	defer os.Unsetenv("ECS_PREFETCH_IMAGES")
+	os.Setenv("ECS_PREFETCH_PAUSE_CONTAINER_IMAGE", "true")
+	defer os.Unsetenv("ECS_PREFETCH_PAUSE_CONTAINER_IMAGE")
+	os.Setenv("ECS_DISABLE_TELEMETRY_RECONNECT", "true")
+	defer os.Unsetenv("ECS_DISABLE_TELEMETRY_RECONNECT")
+	os.Setenv("ECS_TELEMETRY_BUFFER_SIZE", "30")
+	defer os.Unsetenv("ECS_TELEMETRY_BUFFER_SIZE")
+	os.Setenv("ECS_ENABLE_CONTAINER_FILESYSTEM_METRICS", "true")
+	defer os.Unsetenv("ECS_ENABLE_CONTAINER_FILESYSTEM_METRICS")
+	os.Setenv("ECS_CONTAINER_FILESYSTEM_METRICS_INTERVAL", "10m")
+	defer os.Unsetenv("ECS_CONTAINER_FILESYSTEM_METRICS_INTERVAL")
+	os.Setenv("ECS_STEADY_STATE_VERIFY_INTERVAL", "30s")
+	defer os.Unsetenv("ECS_STEADY_STATE_VERIFY_INTERVAL")
+	os.Setenv("ECS_TASK_STOP_TIMEOUT", "45s")
+	defer os.Unsetenv("ECS_TASK_STOP_TIMEOUT")
+	os.Setenv("ECS_MAX_CONTAINER_STOP_TIMEOUT", "5m")
+	defer os.Unsetenv("ECS_MAX_CONTAINER_STOP_TIMEOUT")
+	os.Setenv("ECS_ENI_PENDING_EVENT_TIMEOUT", "5s")
+	defer os.Unsetenv("ECS_ENI_PENDING_EVENT_TIMEOUT")
+	os.Setenv("ECS_ENI_RECONCILIATION_INTERVAL", "1m")
+	defer os.Unsetenv("ECS_ENI_RECONCILIATION_INTERVAL")
+	os.Setenv("ECS_STEADY_STATE_POLL_MIN_INTERVAL", "10s")
+	defer os.Unsetenv("ECS_STEADY_STATE_POLL_MIN_INTERVAL")
+	os.Setenv("ECS_STEADY_STATE_POLL_MAX_INTERVAL", "20m")
+	defer os.Unsetenv("ECS_STEADY_STATE_POLL_MAX_INTERVAL")
+	os.Setenv("ECS_STEADY_STATE_POLL_LATENCY_THRESHOLD", "5s")
+	defer os.Unsetenv("ECS_STEADY_STATE_POLL_LATENCY_THRESHOLD")
+	os.Setenv("ECS_STATE_CHANGE_BATCH_WINDOW", "500ms")
+	defer os.Unsetenv("ECS_STATE_CHANGE_BATCH_WINDOW")
 	additionalLocalRoutesJSON := `["1.2.3.4/22","5.6.7.8/32"]`
 	os.Setenv("ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES", additionalLocalRoutesJSON)
 	defer os.Unsetenv("ECS_AWSVPC_ADDITIONAL_LOCAL_ROUTES")
@@ -136,6 +300,7 @@ func TestEnvironmentConfig(t *testing.T) {
 	assert.True(t, conf.AppArmorCapable, "Wrong value for AppArmorCapable")
 	assert.True(t, conf.TaskIAMRoleEnabled, "Wrong value for TaskIAMRoleEnabled")
 	assert.True(t, conf.TaskIAMRoleEnabledForNetworkHost, "Wrong value for TaskIAMRoleEnabledForNetworkHost")
+	assert.Equal(t, "http://127.0.0.1:51679", conf.HostNetworkCredentialsEndpoint)
 	assert.True(t, conf.ImageCleanupDisabled, "Wrong value for ImageCleanupDisabled")
 	assert.True(t, conf.TaskENIEnabled, "Wrong value for TaskNetwork")
 	assert.Equal(t, 30*time.Minute, conf.MinimumImageDeletionAge)
@@ -143,11 +308,94 @@ func TestEnvironmentConfig(t *testing.T) {
 	assert.Equal(t, 2, conf.NumImagesToDeletePerCycle)
 	assert.Equal(t, "testing", conf.InstanceAttributes["my_attribute"])
 	assert.Equal(t, 90*time.Second, conf.TaskCleanupWaitDuration)
+	assert.Equal(t, []string{"169.254.169.253", "10.0.0.2"}, conf.DefaultDNSServers)
+	assert.Equal(t, []string{"example.com"}, conf.DefaultDNSSearch)
+	assert.Equal(t, MissingVolumePolicyCreate, conf.MissingVolumePolicy)
+	assert.Equal(t, os.FileMode(0700), conf.MissingVolumeDirMode)
+	assert.Equal(t, "/host", conf.HostRootDir, "Wrong value for HostRootDir")
+	assert.Equal(t, MissingNetworkPolicyFail, conf.MissingNetworkPolicy)
+	assert.Equal(t, 16, conf.DockerClientPoolSize)
+	assert.Equal(t, 4, conf.ContainerCreateConcurrency)
+	assert.Equal(t, 3, conf.ENISetupConcurrency)
+	assert.Equal(t, 2, conf.ImagePullConcurrency)
+	assert.Equal(t, 64, conf.MaxTrackedENIs)
+	assert.Equal(t, PlatformMismatchPolicyFail, conf.PlatformMismatchPolicy)
+	assert.Equal(t, WorkingDirValidationPolicyWarn, conf.WorkingDirValidationPolicy)
+	assert.Equal(t, ReservedLabelConflictPolicyFail, conf.ReservedLabelConflictPolicy)
+	assert.Equal(t, UnknownContainerEventPolicyAdopt, conf.UnknownContainerEventPolicy)
+	assert.Equal(t, InstanceNotFoundPolicyReregister, conf.InstanceNotFoundPolicy)
+	assert.Equal(t, MissingEssentialContainerPolicyRestart, conf.MissingEssentialContainerPolicy)
+	assert.Equal(t, PausedContainerPolicyStop, conf.PausedContainerPolicy)
+	assert.Equal(t, ImagePullBehaviorPreferCached, conf.ImagePullBehavior)
+	assert.True(t, conf.ImagePullDiskFullCleanupEnabled, "Wrong value for ImagePullDiskFullCleanupEnabled")
+	assert.True(t, conf.HostPortConflictCheckDisabled, "Wrong value for HostPortConflictCheckDisabled")
+	assert.True(t, conf.TaskCPUCapacityCheckDisabled, "Wrong value for TaskCPUCapacityCheckDisabled")
+	assert.True(t, conf.UnknownTaskStopEventsEnabled, "Wrong value for UnknownTaskStopEventsEnabled")
+	assert.True(t, conf.ContainerExitReasonsEnabled, "Wrong value for ContainerExitReasonsEnabled")
+	assert.True(t, conf.StartupEventReconcileEnabled, "Wrong value for StartupEventReconcileEnabled")
+	assert.True(t, conf.TaskMetadataFileEnabled, "Wrong value for TaskMetadataFileEnabled")
+	assert.True(t, conf.AWSLogsGroupCreationEnabled, "Wrong value for AWSLogsGroupCreationEnabled")
+	assert.True(t, conf.TaskCPUMemLimitEnabled, "Wrong value for TaskCPUMemLimitEnabled")
+	assert.Equal(t, "/var/lib/ecs-test", conf.DataDirOnHost, "Wrong value for DataDirOnHost")
+	assert.Equal(t, "/etc/ecs/manifests", conf.CommandManifestDir, "Wrong value for CommandManifestDir")
+	assert.True(t, conf.TaskCredentialsCheckpointEnabled, "Wrong value for TaskCredentialsCheckpointEnabled")
+	assert.True(t, conf.ENICapacityFailFastEnabled, "Wrong value for ENICapacityFailFastEnabled")
+	assert.Equal(t, 5, conf.TaskENISlots, "Wrong value for TaskENISlots")
+	assert.True(t, conf.DrainOnTerminationEnabled, "Wrong value for DrainOnTerminationEnabled")
+	assert.Equal(t, 90*time.Second, conf.DrainTimeout)
+	assert.Equal(t, InstanceIDFallbackPolicyConfigured, conf.InstanceIDFallbackPolicy)
+	assert.Equal(t, "on-prem-1", conf.FallbackInstanceID)
+	assert.Equal(t, []string{"CostCenter", "Team"}, conf.InstanceTagLabels)
+	assert.Equal(t, []string{"busybox:latest", "amazon/amazon-ecs-sample"}, conf.PrefetchImages)
+	assert.True(t, conf.PauseImagePrefetchEnabled, "Wrong value for PauseImagePrefetchEnabled")
+	assert.True(t, conf.TelemetryReconnectDisabled, "Wrong value for TelemetryReconnectDisabled")
+	assert.Equal(t, 30, conf.TelemetryBufferSize)
+	assert.True(t, conf.FilesystemMetricsEnabled, "Wrong value for FilesystemMetricsEnabled")
+	assert.Equal(t, 10*time.Minute, conf.FilesystemMetricsInterval)
+	assert.Equal(t, 30*time.Second, conf.SteadyStateVerifyInterval)
+	assert.Equal(t, 5*time.Second, conf.ENIPendingEventTimeout)
+	assert.Equal(t, time.Minute, conf.ENIReconciliationInterval)
+	assert.Equal(t, 10*time.Second, conf.SteadyStatePollMinInterval)
+	assert.Equal(t, 20*time.Minute, conf.SteadyStatePollMaxInterval)
+	assert.Equal(t, 5*time.Second, conf.SteadyStatePollLatencyThreshold)
+	assert.Equal(t, 500*time.Millisecond, conf.StateChangeBatchWindow)
+	assert.Equal(t, 45*time.Second, conf.TaskStopTimeout)
+	assert.Equal(t, 5*time.Minute, conf.MaxContainerStopTimeout)
 	serializedAdditionalLocalRoutesJSON, err := json.Marshal(conf.AWSVPCAdditionalLocalRoutes)
 	assert.NoError(t, err, "should marshal additional local routes")
 	assert.Equal(t, additionalLocalRoutesJSON, string(serializedAdditionalLocalRoutesJSON))
 }
 
+func TestEngineAuthDataFromFile(t *testing.T) {
+	authDataFile, err := ioutil.TempFile("", "ecs_engine_auth_data")
+	require.NoError(t, err)
+	defer os.Remove(authDataFile.Name())
+	_, err = authDataFile.WriteString(`{"registry.tld":{"username":"user","password":"swordfish"}}`)
+	require.NoError(t, err)
+	authDataFile.Close()
+
+	os.Setenv("ECS_ENGINE_AUTH_DATA_FILE", authDataFile.Name())
+	defer os.Unsetenv("ECS_ENGINE_AUTH_DATA_FILE")
+
+	conf, err := environmentConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"registry.tld":{"username":"user","password":"swordfish"}}`, string(conf.EngineAuthData.Contents()))
+
+	os.Setenv("ECS_ENGINE_AUTH_DATA", `{"registry.tld":{"username":"env","password":"dragon"}}`)
+	defer os.Unsetenv("ECS_ENGINE_AUTH_DATA")
+	conf, err = environmentConfig()
+	assert.NoError(t, err)
+	assert.Contains(t, string(conf.EngineAuthData.Contents()), "dragon", "ECS_ENGINE_AUTH_DATA should take precedence")
+}
+
+func TestEngineAuthDataFileMissing(t *testing.T) {
+	os.Setenv("ECS_ENGINE_AUTH_DATA_FILE", "/does/not/exist")
+	defer os.Unsetenv("ECS_ENGINE_AUTH_DATA_FILE")
+
+	_, err := environmentConfig()
+	assert.Error(t, err)
+}
+
 func TestTrimWhitespace(t *testing.T) {
 	os.Setenv("AWS_DEFAULT_REGION", "foo-bar-1")
 	defer os.Unsetenv("AWS_DEFAULT_REGION")
@@ -246,6 +494,273 @@ func TestInvalidDockerStopTimeout(t *testing.T) {
 	}
 }
 
+func TestInvalidMissingVolumePolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.MissingVolumePolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.MissingVolumePolicy)
+}
+
+func TestInvalidMissingNetworkPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.MissingNetworkPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.MissingNetworkPolicy)
+}
+
+func TestInvalidPlatformMismatchPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.PlatformMismatchPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, PlatformMismatchPolicyWarn, conf.PlatformMismatchPolicy)
+}
+
+func TestInvalidWorkingDirValidationPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.WorkingDirValidationPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.WorkingDirValidationPolicy)
+}
+
+func TestInvalidInstanceIDFallbackPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.InstanceIDFallbackPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.InstanceIDFallbackPolicy)
+}
+
+func TestConfiguredInstanceIDFallbackPolicyWithoutID(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.InstanceIDFallbackPolicy = InstanceIDFallbackPolicyConfigured
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.InstanceIDFallbackPolicy)
+}
+
+func TestInvalidReservedLabelConflictPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.ReservedLabelConflictPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.ReservedLabelConflictPolicy)
+}
+
+func TestInvalidUnknownContainerEventPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.UnknownContainerEventPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, UnknownContainerEventPolicyIgnore, conf.UnknownContainerEventPolicy)
+}
+
+func TestInvalidInstanceNotFoundPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.InstanceNotFoundPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, InstanceNotFoundPolicyRetry, conf.InstanceNotFoundPolicy)
+}
+
+func TestInvalidMissingEssentialContainerPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.MissingEssentialContainerPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, MissingEssentialContainerPolicyStop, conf.MissingEssentialContainerPolicy)
+}
+
+func TestInvalidPausedContainerPolicy(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.PausedContainerPolicy = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, PausedContainerPolicyUnpause, conf.PausedContainerPolicy)
+}
+
+func TestInvalidImagePullBehavior(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.ImagePullBehavior = "invalid"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, ImagePullBehaviorDefault, conf.ImagePullBehavior)
+}
+
+func TestInvalidHostNetworkCredentialsEndpoint(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.HostNetworkCredentialsEndpoint = "127.0.0.1:51679"
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Empty(t, conf.HostNetworkCredentialsEndpoint, "endpoints without a scheme should be ignored")
+}
+
+func TestInvalidDockerClientPoolSize(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.DockerClientPoolSize = -1
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Zero(t, conf.DockerClientPoolSize)
+}
+
+func TestInvalidMaxTrackedENIs(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.MaxTrackedENIs = -1
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Zero(t, conf.MaxTrackedENIs)
+}
+
+func TestInvalidSteadyStatePollBounds(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.SteadyStateVerifyInterval = 10 * time.Minute
+	conf.SteadyStatePollMinInterval = time.Second
+	conf.SteadyStatePollMaxInterval = time.Minute
+	conf.SteadyStatePollLatencyThreshold = -time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, conf.SteadyStatePollMinInterval)
+	assert.Zero(t, conf.SteadyStatePollMaxInterval)
+	assert.Equal(t, DefaultSteadyStatePollLatencyThreshold, conf.SteadyStatePollLatencyThreshold)
+
+	conf.SteadyStatePollMinInterval = time.Hour
+	err = conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, conf.SteadyStatePollMinInterval, "the minimum should not exceed the verify interval")
+}
+
+func TestDefaultSteadyStatePollMinInterval(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.SteadyStateVerifyInterval = time.Minute
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, conf.SteadyStatePollMinInterval)
+}
+
+func TestInvalidStateChangeBatchWindow(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.StateChangeBatchWindow = -time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Zero(t, conf.StateChangeBatchWindow)
+
+	conf.StateChangeBatchWindow = time.Hour
+	err = conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, MaxStateChangeBatchWindow, conf.StateChangeBatchWindow)
+}
+
+func TestInvalidFilesystemMetricsInterval(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.FilesystemMetricsInterval = 10 * time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFilesystemMetricsInterval, conf.FilesystemMetricsInterval)
+}
+
+func TestInvalidSteadyStateVerifyInterval(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.SteadyStateVerifyInterval = time.Second
+	conf.SteadyStatePollMaxInterval = 2 * time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSteadyStateVerifyInterval, conf.SteadyStateVerifyInterval)
+	assert.Zero(t, conf.SteadyStatePollMaxInterval, "the maximum should not be below the minimum interval")
+}
+
+func TestInvalidENIReconciliationInterval(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.ENIReconciliationInterval = time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultENIReconciliationInterval, conf.ENIReconciliationInterval)
+}
+
+func TestInvalidTaskStopTimeout(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.TaskStopTimeout = -1 * time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Zero(t, conf.TaskStopTimeout)
+}
+
+func TestInvalidMaxContainerStopTimeout(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.MaxContainerStopTimeout = 0
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxContainerStopTimeout, conf.MaxContainerStopTimeout)
+}
+
+func TestInvalidDrainTimeout(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.DrainTimeout = -1 * time.Second
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultDrainTimeout, conf.DrainTimeout)
+}
+
+func TestInvalidTelemetryBufferSize(t *testing.T) {
+	conf := DefaultConfig()
+	conf.AWSRegion = "us-west-2"
+	conf.TelemetryBufferSize = -1
+
+	err := conf.validateAndOverrideBounds()
+	assert.NoError(t, err)
+	assert.Zero(t, conf.TelemetryBufferSize)
+}
+
 func TestInvalidFormatParseEnvVariableUint16(t *testing.T) {
 	os.Setenv("FOO", "foo")
 	var16 := parseEnvVariableUint16("FOO")
@@ -262,6 +777,12 @@ func TestValidFormatParseEnvVariableUint16(t *testing.T) {
 	}
 }
 
+func TestInvalidFormatParseEnvVariableStringSlice(t *testing.T) {
+	os.Setenv("FOO", "foo")
+	defer os.Unsetenv("FOO")
+	assert.Empty(t, parseEnvVariableStringSlice("FOO"))
+}
+
 func TestInvalidFormatParseEnvVariableDuration(t *testing.T) {
 	os.Setenv("FOO", "foo")
 	duration := parseEnvVariableDuration("FOO")
@@ -443,3 +964,16 @@ func TestInvalidAWSVPCAdditionalLocalRoutes(t *testing.T) {
 	_, err := environmentConfig()
 	assert.Error(t, err)
 }
+
+func TestRedactedMasksSensitiveFields(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Cluster = "myCluster"
+	cfg.EngineAuthType = "dockercfg"
+	cfg.EngineAuthData = NewSensitiveRawMessage([]byte(`{"https://index.docker.io/v1/":{"auth":"secret"}}`))
+
+	redacted := cfg.Redacted()
+	assert.Equal(t, "myCluster", redacted.Cluster)
+	assert.Equal(t, "dockercfg", redacted.EngineAuthType)
+	assert.Equal(t, `"[redacted]"`, string(redacted.EngineAuthData.Contents()))
+	assert.Contains(t, string(cfg.EngineAuthData.Contents()), "secret", "the config itself should not be changed")
+}
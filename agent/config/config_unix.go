@@ -1,4 +1,6 @@
+//go:build !windows
 // +build !windows
+
 // Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License"). You may
@@ -28,26 +30,40 @@ const (
 // DefaultConfig returns the default configuration for Linux
 func DefaultConfig() Config {
 	return Config{
-		DockerEndpoint:              "unix:///var/run/docker.sock",
-		ReservedPorts:               []uint16{SSHPort, DockerReservedPort, DockerReservedSSLPort, AgentIntrospectionPort, AgentCredentialsPort},
-		ReservedPortsUDP:            []uint16{},
-		DataDir:                     "/data/",
-		DisableMetrics:              false,
-		ReservedMemory:              0,
-		AvailableLoggingDrivers:     []dockerclient.LoggingDriver{dockerclient.JSONFileDriver},
-		TaskCleanupWaitDuration:     DefaultTaskCleanupWaitDuration,
-		DockerStopTimeout:           DefaultDockerStopTimeout,
-		CredentialsAuditLogFile:     defaultCredentialsAuditLogFile,
-		CredentialsAuditLogDisabled: false,
-		ImageCleanupDisabled:        false,
-		MinimumImageDeletionAge:     DefaultImageDeletionAge,
-		ImageCleanupInterval:        DefaultImageCleanupTimeInterval,
-		NumImagesToDeletePerCycle:   DefaultNumImagesToDeletePerCycle,
-		CNIPluginsPath:              defaultCNIPluginsPath,
-		PauseContainerTarballPath:   pauseContainerTarballPath,
-		PauseContainerImageName:     DefaultPauseContainerImageName,
-		PauseContainerTag:           DefaultPauseContainerTag,
-		AWSVPCBlockInstanceMetdata:  false,
+		DockerEndpoint:                  "unix:///var/run/docker.sock",
+		ReservedPorts:                   []uint16{SSHPort, DockerReservedPort, DockerReservedSSLPort, AgentIntrospectionPort, AgentCredentialsPort},
+		ReservedPortsUDP:                []uint16{},
+		DataDir:                         "/data/",
+		DataDirOnHost:                   "/var/lib/ecs",
+		DisableMetrics:                  false,
+		ReservedMemory:                  0,
+		AvailableLoggingDrivers:         []dockerclient.LoggingDriver{dockerclient.JSONFileDriver},
+		TaskCleanupWaitDuration:         DefaultTaskCleanupWaitDuration,
+		DockerStopTimeout:               DefaultDockerStopTimeout,
+		MaxContainerStopTimeout:         DefaultMaxContainerStopTimeout,
+		DrainTimeout:                    DefaultDrainTimeout,
+		CredentialsAuditLogFile:         defaultCredentialsAuditLogFile,
+		CredentialsAuditLogDisabled:     false,
+		ImageCleanupDisabled:            false,
+		MinimumImageDeletionAge:         DefaultImageDeletionAge,
+		ImageCleanupInterval:            DefaultImageCleanupTimeInterval,
+		NumImagesToDeletePerCycle:       DefaultNumImagesToDeletePerCycle,
+		CNIPluginsPath:                  defaultCNIPluginsPath,
+		PauseContainerTarballPath:       pauseContainerTarballPath,
+		PauseContainerImageName:         DefaultPauseContainerImageName,
+		PauseContainerTag:               DefaultPauseContainerTag,
+		AWSVPCBlockInstanceMetdata:      false,
+		MissingVolumeDirMode:            DefaultMissingVolumeDirMode,
+		PlatformMismatchPolicy:          PlatformMismatchPolicyWarn,
+		UnknownContainerEventPolicy:     UnknownContainerEventPolicyIgnore,
+		InstanceNotFoundPolicy:          InstanceNotFoundPolicyRetry,
+		MissingEssentialContainerPolicy: MissingEssentialContainerPolicyStop,
+		PausedContainerPolicy:           PausedContainerPolicyUnpause,
+		ImagePullBehavior:               ImagePullBehaviorDefault,
+		FilesystemMetricsInterval:       DefaultFilesystemMetricsInterval,
+		SteadyStateVerifyInterval:       DefaultSteadyStateVerifyInterval,
+		ENIPendingEventTimeout:          DefaultENIPendingEventTimeout,
+		ENIReconciliationInterval:       DefaultENIReconciliationInterval,
 	}
 }
 
@@ -63,6 +63,8 @@ func TestConfigDefault(t *testing.T) {
 	assert.Equal(t, []dockerclient.LoggingDriver{dockerclient.JSONFileDriver}, cfg.AvailableLoggingDrivers, "Default logging drivers set incorrectly")
 	assert.Equal(t, 3*time.Hour, cfg.TaskCleanupWaitDuration, "Default task cleanup wait duration set incorrectly")
 	assert.False(t, cfg.TaskENIEnabled, "TaskENIEnabled set incorrectly")
+	assert.Equal(t, DefaultENIPendingEventTimeout, cfg.ENIPendingEventTimeout, "Default ENIPendingEventTimeout set incorrectly")
+	assert.Equal(t, DefaultENIReconciliationInterval, cfg.ENIReconciliationInterval, "Default ENIReconciliationInterval set incorrectly")
 	assert.False(t, cfg.TaskIAMRoleEnabled, "TaskIAMRoleEnabled set incorrectly")
 	assert.False(t, cfg.TaskIAMRoleEnabledForNetworkHost, "TaskIAMRoleEnabledForNetworkHost set incorrectly")
 	assert.False(t, cfg.CredentialsAuditLogDisabled, "CredentialsAuditLogDisabled set incorrectly")
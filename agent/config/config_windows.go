@@ -1,4 +1,6 @@
+//go:build windows
 // +build windows
+
 // Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License"). You may
@@ -60,18 +62,31 @@ func DefaultConfig() Config {
 		},
 		ReservedPortsUDP: []uint16{},
 		DataDir:          filepath.Join(ecsRoot, "data"),
+		DataDirOnHost:    ecsRoot,
 		// DisableMetrics is set to true on Windows as docker stats does not work
-		DisableMetrics:              true,
-		ReservedMemory:              0,
-		AvailableLoggingDrivers:     []dockerclient.LoggingDriver{dockerclient.JSONFileDriver},
-		TaskCleanupWaitDuration:     DefaultTaskCleanupWaitDuration,
-		DockerStopTimeout:           DefaultDockerStopTimeout,
-		CredentialsAuditLogFile:     filepath.Join(ecsRoot, defaultCredentialsAuditLogFile),
-		CredentialsAuditLogDisabled: false,
-		ImageCleanupDisabled:        false,
-		MinimumImageDeletionAge:     DefaultImageDeletionAge,
-		ImageCleanupInterval:        DefaultImageCleanupTimeInterval,
-		NumImagesToDeletePerCycle:   DefaultNumImagesToDeletePerCycle,
+		DisableMetrics:                  true,
+		ReservedMemory:                  0,
+		AvailableLoggingDrivers:         []dockerclient.LoggingDriver{dockerclient.JSONFileDriver},
+		TaskCleanupWaitDuration:         DefaultTaskCleanupWaitDuration,
+		DockerStopTimeout:               DefaultDockerStopTimeout,
+		MaxContainerStopTimeout:         DefaultMaxContainerStopTimeout,
+		DrainTimeout:                    DefaultDrainTimeout,
+		CredentialsAuditLogFile:         filepath.Join(ecsRoot, defaultCredentialsAuditLogFile),
+		CredentialsAuditLogDisabled:     false,
+		ImageCleanupDisabled:            false,
+		MinimumImageDeletionAge:         DefaultImageDeletionAge,
+		ImageCleanupInterval:            DefaultImageCleanupTimeInterval,
+		NumImagesToDeletePerCycle:       DefaultNumImagesToDeletePerCycle,
+		MissingVolumeDirMode:            DefaultMissingVolumeDirMode,
+		PlatformMismatchPolicy:          PlatformMismatchPolicyWarn,
+		UnknownContainerEventPolicy:     UnknownContainerEventPolicyIgnore,
+		InstanceNotFoundPolicy:          InstanceNotFoundPolicyRetry,
+		MissingEssentialContainerPolicy: MissingEssentialContainerPolicyStop,
+		PausedContainerPolicy:           PausedContainerPolicyUnpause,
+		ImagePullBehavior:               ImagePullBehaviorDefault,
+		FilesystemMetricsInterval:       DefaultFilesystemMetricsInterval,
+		SteadyStateVerifyInterval:       DefaultSteadyStateVerifyInterval,
+		ENIReconciliationInterval:       DefaultENIReconciliationInterval,
 	}
 }
 
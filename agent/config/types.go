@@ -15,6 +15,7 @@ package config
 
 import (
 	"encoding/json"
+	"os"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
@@ -63,8 +64,10 @@ type Config struct {
 	// Supported types, right now, can be found in the dockerauth package: https://godoc.org/github.com/aws/amazon-ecs-agent/agent/engine/dockerauth
 	EngineAuthType string `trim:"true"`
 	// EngineAuthData contains authentication data. Please see the documentation
-	// for EngineAuthType for more information.
-	EngineAuthData *SensitiveRawMessage
+	// for EngineAuthType for more information. In the environment, it can be
+	// set with ECS_ENGINE_AUTH_DATA or read from the file named by
+	// ECS_ENGINE_AUTH_DATA_FILE.
+	EngineAuthData *SensitiveRawMessage `sensitive:"true"`
 
 	// UpdatesEnabled specifies whether updates should be applied to this agent.
 	// Default true
@@ -78,6 +81,25 @@ type Config struct {
 	// sent to the ECS telemetry endpoint
 	DisableMetrics bool
 
+	// TelemetryReconnectDisabled configures whether the telemetry session is
+	// left closed, rather than re-established, when it is lost
+	TelemetryReconnectDisabled bool
+
+	// TelemetryBufferSize specifies the number of metrics messages that are
+	// held while the telemetry session is down, to be published once it is
+	// back. The oldest messages are dropped first. If unset, metrics gathered
+	// while the session is down are only published in aggregate.
+	TelemetryBufferSize int
+
+	// FilesystemMetricsEnabled configures whether the writable layer size of
+	// containers is reported to the ECS telemetry endpoint
+	FilesystemMetricsEnabled bool
+
+	// FilesystemMetricsInterval specifies how often the writable layer size
+	// of containers is sampled. It is expensive to compute, so it is sampled
+	// far less often than CPU and memory usage
+	FilesystemMetricsInterval time.Duration
+
 	// ReservedMemory specifies the amount of memory (in MB) to reserve for things
 	// other than containers managed by ECS
 	ReservedMemory uint16
@@ -86,6 +108,17 @@ type Config struct {
 	// containers managed by ECS
 	DockerStopTimeout time.Duration
 
+	// TaskStopTimeout specifies the total amount of time the containers of a
+	// task are given to exit once the task starts stopping, after which any
+	// remaining containers are killed. Each container is given at most
+	// DockerStopTimeout. It is disabled when 0
+	TaskStopTimeout time.Duration
+
+	// MaxContainerStopTimeout specifies the longest stop timeout a container
+	// can ask for in its task definition, to be given instead of
+	// DockerStopTimeout
+	MaxContainerStopTimeout time.Duration
+
 	// AvailableLoggingDrivers specifies the logging drivers available for use
 	// with Docker.  If not set, it defaults to ["json-file"].
 	AvailableLoggingDrivers []dockerclient.LoggingDriver
@@ -120,10 +153,102 @@ type Config struct {
 	// tasks with IAM Roles when networkMode is set to 'host'
 	TaskIAMRoleEnabledForNetworkHost bool
 
+	// HostNetworkCredentialsEndpoint is the endpoint of the credentials server,
+	// such as "http://127.0.0.1:51679", that containers in the 'host' network
+	// mode are given with the AWS_CONTAINER_CREDENTIALS_FULL_URI environment
+	// variable. When unset, they are given the relative URI of their
+	// credentials, like containers in other network modes
+	HostNetworkCredentialsEndpoint string
+
 	// TaskENIEnabled specifies if the Agent is capable of launching task within
 	// defined EC2 networks
 	TaskENIEnabled bool
 
+	// ENIPendingEventTimeout specifies how long udev events for ENIs whose
+	// attachment has not been received yet are held and re-evaluated before
+	// being dropped. A negative value drops them right away
+	ENIPendingEventTimeout time.Duration
+
+	// ENIReconciliationInterval specifies how often the ENIs on the instance
+	// are listed to send the attachments of those whose udev events were
+	// missed, e.g. because the Agent was not running when they were attached
+	ENIReconciliationInterval time.Duration
+
+	// MaxTrackedENIs specifies the maximum number of ENI attachments the
+	// Agent tracks. Attachments of ENIs beyond it are refused when they are
+	// received, and the tasks they are for are stopped with a reason. If
+	// unset, the number of tracked ENIs is not limited
+	MaxTrackedENIs int
+
+	// ENICapacityFailFastEnabled specifies whether tasks using the awsvpc
+	// network mode are stopped right away, with a "no ENI capacity" reason,
+	// when all of the ENI slots of the instance are used by other tasks. The
+	// number of slots is TaskENISlots. Otherwise such tasks wait for an
+	// attachment that cannot happen
+	ENICapacityFailFastEnabled bool
+
+	// TaskENISlots is the number of ENIs that tasks can use on the instance,
+	// which is advertised as the task-eni-slots attribute. If unset and
+	// ENICapacityFailFastEnabled is set, it defaults to the number of ENIs
+	// that the instance type supports, less the primary ENI. If that is not
+	// known either, the number of slots is not known
+	TaskENISlots int
+
+	// DrainOnTerminationEnabled specifies whether the Agent drains the
+	// instance when it is asked to terminate: new tasks are stopped as soon
+	// as they are received and all of the running tasks are stopped, before
+	// the Agent exits. Otherwise tasks are left running across restarts
+	DrainOnTerminationEnabled bool
+
+	// DrainTimeout specifies the longest time the Agent waits for tasks to
+	// stop when draining the instance, after which it exits with tasks still
+	// running
+	DrainTimeout time.Duration
+
+	// InstanceIDFallbackPolicy specifies the instance ID the Agent uses when
+	// the instance identity document is unavailable, e.g. outside of EC2 or
+	// when the instance metadata service is disabled. It can be set to
+	// "configured" to use FallbackInstanceID, or "generated" to use an ID
+	// that is generated once and saved in the data directory. If unset, the
+	// instance ID is left empty
+	InstanceIDFallbackPolicy string
+
+	// FallbackInstanceID is the instance ID used with the "configured"
+	// instance ID fallback policy
+	FallbackInstanceID string
+
+	// SteadyStateVerifyInterval specifies the interval at which the states of
+	// the containers of tasks in steady state are checked with Docker. It is
+	// the interval the adapted interval starts from
+	SteadyStateVerifyInterval time.Duration
+
+	// SteadyStatePollMinInterval specifies the shortest interval at which the
+	// states of the containers of tasks in steady state are checked with
+	// Docker when the interval is adapted. It defaults to
+	// SteadyStateVerifyInterval, in which case the interval never gets shorter
+	// than the configured one
+	SteadyStatePollMinInterval time.Duration
+
+	// SteadyStatePollMaxInterval specifies the longest interval at which the
+	// states of the containers of tasks in steady state are checked with
+	// Docker. When it is set, the interval starts at SteadyStateVerifyInterval
+	// and backs off when Docker responds slower than
+	// SteadyStatePollLatencyThreshold and speeds up when it responds faster.
+	// The interval is fixed if it is unset
+	SteadyStatePollMaxInterval time.Duration
+
+	// SteadyStatePollLatencyThreshold specifies the Docker response latency
+	// above which the steady state poll interval backs off
+	SteadyStatePollLatencyThreshold time.Duration
+
+	// StateChangeBatchWindow specifies the window over which the container
+	// state changes of a task are coalesced with its task state change before
+	// it is submitted, so that rapid changes are submitted together. Task
+	// state changes are never coalesced with one another. It is capped at
+	// MaxStateChangeBatchWindow.
+	// State changes are submitted right away if it is unset
+	StateChangeBatchWindow time.Duration
+
 	// ImageCleanupDisabled specifies whether the Agent will periodically perform
 	// automated image cleanup
 	ImageCleanupDisabled bool
@@ -180,6 +305,203 @@ type Config struct {
 	// entries that will be added in the task's network namespace via the
 	// instance bridge interface rather than via the ENI.
 	AWSVPCAdditionalLocalRoutes []cnitypes.IPNet
+
+	// DefaultDNSServers specifies the DNS servers to be used by containers of
+	// bridge mode tasks that do not specify their own DNS servers
+	DefaultDNSServers []string
+
+	// DefaultDNSSearch specifies the DNS search domains to be used by
+	// containers of bridge mode tasks that do not specify their own DNS
+	// search domains
+	DefaultDNSSearch []string
+
+	// MissingVolumePolicy specifies how the Agent handles host volumes whose
+	// source path does not exist when a container is created. It can be set
+	// to "create" to create the directory or "fail" to fail the container
+	// creation. If unset, the path is handed to Docker as-is.
+	MissingVolumePolicy string
+
+	// MissingVolumeDirMode specifies the permissions of directories created
+	// for missing host volumes when MissingVolumePolicy is "create"
+	MissingVolumeDirMode os.FileMode
+
+	// HostRootDir is the path the root filesystem of the host is mounted at
+	// in the Agent container. MissingVolumePolicy is applied to the source
+	// paths of host volumes under it. It is empty if the Agent isn't run in
+	// a container
+	HostRootDir string
+
+	// MissingNetworkPolicy specifies how the Agent handles user-defined docker
+	// networks referenced by the network mode of a container that do not
+	// exist when the container is created. It can be set to "create" to
+	// create the network or "fail" to fail the container creation. If unset,
+	// the network mode is handed to Docker as-is.
+	MissingNetworkPolicy string
+
+	// DockerClientPoolSize specifies the number of idle connections to the
+	// Docker daemon that are kept open for reuse by the Docker client. If
+	// unset, a new connection is opened for every request. It applies to
+	// Docker endpoints reached over TCP or a unix socket.
+	DockerClientPoolSize int
+
+	// ContainerCreateConcurrency specifies the maximum number of containers
+	// that are created at the same time. Pending creates are served by task
+	// priority. If unset, creates are not limited.
+	ContainerCreateConcurrency int
+
+	// ENISetupConcurrency specifies the maximum number of task network
+	// namespaces that are set up with the CNI plugins at the same time.
+	// Pending setups are served by task priority. If unset, setups are not
+	// limited.
+	ENISetupConcurrency int
+
+	// ImagePullConcurrency specifies the maximum number of images that are
+	// pulled at the same time when concurrent pulls are enabled. Pending
+	// pulls are served by task priority. Prefetched images, including the
+	// pause image, count against the limit. A value of 1 pulls images one at
+	// a time. If unset, pulls are not limited.
+	ImagePullConcurrency int
+
+	// PlatformMismatchPolicy specifies how the Agent handles containers that
+	// request a platform that does not match the host. It can be set to
+	// "warn" to log a warning or "fail" to fail the container before its
+	// image is pulled. It defaults to "warn"
+	PlatformMismatchPolicy string
+
+	// WorkingDirValidationPolicy specifies whether the working directories of
+	// containers are checked against their images before the containers are
+	// created. It can be set to "warn" to log a warning or "fail" to fail the
+	// container creation when the working directory is relative or unlikely
+	// to exist in the image. Working directories are not checked if it is
+	// unset
+	WorkingDirValidationPolicy string
+
+	// ReservedLabelConflictPolicy specifies how the Agent handles labels of
+	// containers that use the com.amazonaws.ecs. prefix reserved for the
+	// labels the Agent sets. It can be set to "drop" to drop such labels with
+	// a warning or "fail" to fail the container creation. If unset, the
+	// labels are kept and the ones set by the Agent override them
+	ReservedLabelConflictPolicy string
+
+	// UnknownContainerEventPolicy specifies how the Agent handles Docker
+	// events for containers it does not track. It can be set to "ignore" to
+	// ignore the events or "adopt" to add containers that carry the labels of
+	// a task the Agent tracks to that task. It defaults to "ignore"
+	UnknownContainerEventPolicy string
+
+	// InstanceNotFoundPolicy specifies how the Agent handles state changes
+	// that fail to be submitted because the container instance is no longer
+	// registered, e.g. because it was deregistered out of band. It can be
+	// set to "retry" to keep retrying them, or "reregister" to register a new
+	// container instance and restart the Agent to use it
+	InstanceNotFoundPolicy string
+
+	// MissingEssentialContainerPolicy specifies how the Agent handles
+	// restored tasks whose essential container was removed from Docker while
+	// the Agent was down. It can be set to "stop" to stop the task with a
+	// reason naming the missing container or "restart" to remove the other
+	// containers of the task and start the whole task again. It defaults to
+	// "stop"
+	MissingEssentialContainerPolicy string
+
+	// PausedContainerPolicy specifies how the Agent handles containers of
+	// restored tasks that Docker reports as paused. It can be set to
+	// "unpause" to unpause the container and carry on with the task or
+	// "stop" to stop the task with a reason naming the paused container. It
+	// defaults to "unpause"
+	PausedContainerPolicy string
+
+	// ImagePullBehavior specifies when the images of containers are pulled.
+	// It can be set to "default" to always pull images, "once" to only pull
+	// images the Agent has not pulled before or "prefer-cached" to only pull
+	// images that are not present on the instance. It defaults to "default"
+	ImagePullBehavior string
+
+	// ImagePullDiskFullCleanupEnabled specifies whether the Agent removes
+	// unused images and retries the pull once when the pull of an image fails
+	// because the disk is full. Containers whose image still can't be pulled
+	// for lack of space are stopped
+	ImagePullDiskFullCleanupEnabled bool
+
+	// HostPortConflictCheckDisabled specifies whether the Agent skips
+	// checking that the static host ports requested by a task are not
+	// allocated to another task. Tasks with conflicting host ports are
+	// stopped with a RESOURCE_CONFLICT reason unless the check is disabled
+	HostPortConflictCheckDisabled bool
+
+	// TaskCPUCapacityCheckDisabled specifies whether the Agent skips checking
+	// that the CPU requested by a task does not exceed the CPU of the
+	// instance advertised at registration. Tasks requesting more CPU are
+	// stopped with a RESOURCE_CONFLICT reason unless the check is disabled
+	TaskCPUCapacityCheckDisabled bool
+
+	// UnknownTaskStopEventsEnabled specifies whether the Agent emits a STOPPED
+	// state change for stop requests targeting tasks it does not know about,
+	// such as tasks that have already been cleaned up. Such requests are
+	// always treated as already satisfied.
+	UnknownTaskStopEventsEnabled bool
+
+	// ContainerExitReasonsEnabled specifies whether the Agent reports a
+	// description of well known exit codes, such as 137 for SIGKILL, as the
+	// reason of container state changes that have no other reason. Stops
+	// detected by the steady state poll rather than a docker event are also
+	// called out in the reason
+	ContainerExitReasonsEnabled bool
+
+	// InstanceTagLabels specifies the keys of the instance tags that are added
+	// as labels to the containers the Agent creates. Tags are read from the
+	// instance metadata, which needs to allow access to instance tags
+	InstanceTagLabels []string
+
+	// StartupEventReconcileEnabled specifies whether the Agent drains the
+	// backlog of Docker events that piles up while it starts and reconciles
+	// each affected task once with the current state of its containers,
+	// instead of applying every event as a live transition
+	StartupEventReconcileEnabled bool
+
+	// PrefetchImages specifies images that the Agent pulls when it starts,
+	// before it accepts tasks. Images that fail to be pulled are skipped
+	PrefetchImages []string
+
+	// PauseImagePrefetchEnabled specifies whether the Agent pulls
+	// the pause container image when it starts, if the image is not loaded
+	// from the tarball. Pause containers of tasks wait for the pull to
+	// complete before they are created
+	PauseImagePrefetchEnabled bool
+
+	// TaskMetadataFileEnabled specifies whether the Agent writes a json file
+	// with the metadata of the task into a directory mounted into each of
+	// its containers, and keeps it up to date as the task changes
+	TaskMetadataFileEnabled bool
+
+	// AWSLogsGroupCreationEnabled specifies whether the Agent creates the
+	// CloudWatch Logs log group of containers that use the awslogs log driver
+	// before they are created, so that they don't fail to start because the
+	// log group doesn't exist
+	AWSLogsGroupCreationEnabled bool
+
+	// TaskCPUMemLimitEnabled specifies whether the Agent enforces the CPU and
+	// memory limits of tasks by creating a parent cgroup for each task that
+	// has them, which its containers are created in. It is only supported on
+	// Linux
+	TaskCPUMemLimitEnabled bool
+
+	// CommandManifestDir specifies the directory of the command manifests
+	// that containers can source their entry point and command from. The
+	// manifests are written by an external process. Containers cannot use
+	// command manifests when it is not set
+	CommandManifestDir string
+
+	// TaskCredentialsCheckpointEnabled specifies whether the credentials of
+	// task roles are saved with the rest of the checkpointed state, so that
+	// the credentials endpoint keeps serving them to running tasks after the
+	// Agent restarts, e.g. for an in-place upgrade
+	TaskCredentialsCheckpointEnabled bool
+
+	// DataDirOnHost is the path of the directory on the host that is mounted
+	// into the Agent container and contains DataDir. It is used to find the
+	// host path of files the Agent writes for containers
+	DataDirOnHost string
 }
 
 // SensitiveRawMessage is a struct to store some data that should not be logged
@@ -201,11 +523,11 @@ func NewSensitiveRawMessage(data json.RawMessage) *SensitiveRawMessage {
 }
 
 func (data SensitiveRawMessage) String() string {
-	return "[redacted]"
+	return redactedValue
 }
 
 func (data SensitiveRawMessage) GoString() string {
-	return "[redacted]"
+	return redactedValue
 }
 
 func (data SensitiveRawMessage) Contents() json.RawMessage {
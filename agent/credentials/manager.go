@@ -14,6 +14,7 @@
 package credentials
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -142,3 +143,62 @@ func (manager *credentialsManager) RemoveCredentials(id string) {
 
 	delete(manager.idToTaskCredentials, id)
 }
+
+// savedTaskCredentials is the saved form of TaskIAMRoleCredentials, which
+// includes the credentials that are never part of the json representation
+// served by the credentials endpoint
+type savedTaskCredentials struct {
+	ARN             string
+	RoleArn         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string
+}
+
+// MarshalJSON marshals the credentials of all tasks, keyed by credentials id,
+// so that they can be saved with the state of the agent and restored when
+// it restarts while tasks keep running
+func (manager *credentialsManager) MarshalJSON() ([]byte, error) {
+	manager.taskCredentialsLock.RLock()
+	defer manager.taskCredentialsLock.RUnlock()
+
+	saved := make(map[string]savedTaskCredentials, len(manager.idToTaskCredentials))
+	for id, taskCredentials := range manager.idToTaskCredentials {
+		saved[id] = savedTaskCredentials{
+			ARN:             taskCredentials.ARN,
+			RoleArn:         taskCredentials.IAMRoleCredentials.RoleArn,
+			AccessKeyID:     taskCredentials.IAMRoleCredentials.AccessKeyID,
+			SecretAccessKey: taskCredentials.IAMRoleCredentials.SecretAccessKey,
+			SessionToken:    taskCredentials.IAMRoleCredentials.SessionToken,
+			Expiration:      taskCredentials.IAMRoleCredentials.Expiration,
+		}
+	}
+	return json.Marshal(saved)
+}
+
+// UnmarshalJSON restores the credentials of tasks saved with MarshalJSON.
+// Restored credentials are added to the credentials already in the manager
+func (manager *credentialsManager) UnmarshalJSON(data []byte) error {
+	var saved map[string]savedTaskCredentials
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	manager.taskCredentialsLock.Lock()
+	defer manager.taskCredentialsLock.Unlock()
+	for id, taskCredentials := range saved {
+		manager.idToTaskCredentials[id] = &TaskIAMRoleCredentials{
+			ARN: taskCredentials.ARN,
+			IAMRoleCredentials: IAMRoleCredentials{
+				CredentialsID:   id,
+				RoleArn:         taskCredentials.RoleArn,
+				AccessKeyID:     taskCredentials.AccessKeyID,
+				SecretAccessKey: taskCredentials.SecretAccessKey,
+				SessionToken:    taskCredentials.SessionToken,
+				Expiration:      taskCredentials.Expiration,
+			},
+		}
+	}
+	return nil
+}
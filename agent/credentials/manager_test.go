@@ -14,12 +14,14 @@
 package credentials
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/acs/model/ecsacs"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestIAMRoleCredentialsFromACS tests if credentials sent from ACS can be
@@ -165,3 +167,30 @@ func TestRemoveExistingCredentials(t *testing.T) {
 		t.Error("Expected GetTaskCredentials to return false for removed credentials")
 	}
 }
+
+// TestTaskCredentialsSaveRestore tests if the credentials of tasks survive
+// being saved and restored with the state of the agent
+func TestTaskCredentialsSaveRestore(t *testing.T) {
+	taskCredentials := TaskIAMRoleCredentials{
+		ARN: "t1",
+		IAMRoleCredentials: IAMRoleCredentials{
+			CredentialsID:   "cid1",
+			RoleArn:         "r1",
+			AccessKeyID:     "akid1",
+			SecretAccessKey: "skid1",
+			SessionToken:    "stkn1",
+			Expiration:      "ts",
+		},
+	}
+	manager := NewManager()
+	require.NoError(t, manager.SetTaskCredentials(taskCredentials))
+
+	data, err := json.Marshal(manager)
+	require.NoError(t, err)
+
+	restoredManager := NewManager()
+	require.NoError(t, json.Unmarshal(data, restoredManager))
+	restoredCredentials, ok := restoredManager.GetTaskCredentials("cid1")
+	require.True(t, ok, "credentials should be restored")
+	assert.Equal(t, taskCredentials, restoredCredentials)
+}
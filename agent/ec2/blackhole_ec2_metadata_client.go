@@ -45,6 +45,10 @@ func (blackholeMetadataClient) SubnetID(mac string) (string, error) {
 	return "", errors.New("blackholed")
 }
 
+func (blackholeMetadataClient) InstanceTags() (map[string]string, error) {
+	return nil, errors.New("blackholed")
+}
+
 func (blackholeMetadataClient) GetMetadata(path string) (string, error) {
 	return "", errors.New("blackholed")
 }
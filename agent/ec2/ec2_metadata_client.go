@@ -32,6 +32,7 @@ const (
 	MacResource                               = "mac"
 	VPCIDResourceFormat                       = "network/interfaces/macs/%s/vpc-id"
 	SubnetIDResourceFormat                    = "network/interfaces/macs/%s/subnet-id"
+	InstanceTagsResource                      = "tags/instance"
 )
 
 const (
@@ -64,6 +65,7 @@ type EC2MetadataClient interface {
 	VPCID(mac string) (string, error)
 	SubnetID(mac string) (string, error)
 	PrimaryENIMAC() (string, error)
+	InstanceTags() (map[string]string, error)
 }
 
 type ec2MetadataClientImpl struct {
@@ -138,3 +140,25 @@ func (c *ec2MetadataClientImpl) VPCID(mac string) (string, error) {
 func (c *ec2MetadataClientImpl) SubnetID(mac string) (string, error) {
 	return c.client.GetMetadata(fmt.Sprintf(SubnetIDResourceFormat, mac))
 }
+
+// InstanceTags returns the tags of the instance, keyed by tag name. Access to
+// the tags has to be allowed in the metadata options of the instance
+func (c *ec2MetadataClientImpl) InstanceTags() (map[string]string, error) {
+	keys, err := c.client.GetMetadata(InstanceTagsResource)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, key := range strings.Split(strings.TrimSpace(keys), "\n") {
+		if key == "" {
+			continue
+		}
+		value, err := c.client.GetMetadata(InstanceTagsResource + "/" + key)
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
@@ -177,3 +177,32 @@ func TestSubnetID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, subnetID, subnetIDResponse)
 }
+
+func TestInstanceTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := mock_ec2.NewMockHttpClient(ctrl)
+	testClient := ec2.NewEC2MetadataClient(mockGetter)
+
+	gomock.InOrder(
+		mockGetter.EXPECT().GetMetadata(ec2.InstanceTagsResource).Return("CostCenter\nName\n", nil),
+		mockGetter.EXPECT().GetMetadata(ec2.InstanceTagsResource+"/CostCenter").Return("1234", nil),
+		mockGetter.EXPECT().GetMetadata(ec2.InstanceTagsResource+"/Name").Return("web", nil),
+	)
+	tags, err := testClient.InstanceTags()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"CostCenter": "1234", "Name": "web"}, tags)
+}
+
+func TestInstanceTagsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := mock_ec2.NewMockHttpClient(ctrl)
+	testClient := ec2.NewEC2MetadataClient(mockGetter)
+
+	mockGetter.EXPECT().GetMetadata(ec2.InstanceTagsResource).Return("", errors.New("tags not allowed"))
+	_, err := testClient.InstanceTags()
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package ec2
+
+// maxENIsByInstanceType is the maximum number of network interfaces that can
+// be attached to an instance, keyed by instance type
+// http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/using-eni.html#AvailableIpPerENI
+var maxENIsByInstanceType = map[string]int{
+	"c4.large":    3,
+	"c4.xlarge":   4,
+	"c4.2xlarge":  4,
+	"c4.4xlarge":  8,
+	"c4.8xlarge":  8,
+	"c5.large":    3,
+	"c5.xlarge":   4,
+	"c5.2xlarge":  4,
+	"c5.4xlarge":  8,
+	"c5.9xlarge":  8,
+	"c5.18xlarge": 15,
+	"m4.large":    2,
+	"m4.xlarge":   4,
+	"m4.2xlarge":  4,
+	"m4.4xlarge":  8,
+	"m4.10xlarge": 8,
+	"m4.16xlarge": 8,
+	"m5.large":    3,
+	"m5.xlarge":   4,
+	"m5.2xlarge":  4,
+	"m5.4xlarge":  8,
+	"m5.12xlarge": 8,
+	"m5.24xlarge": 15,
+	"r4.large":    3,
+	"r4.xlarge":   4,
+	"r4.2xlarge":  4,
+	"r4.4xlarge":  8,
+	"r4.8xlarge":  8,
+	"r4.16xlarge": 15,
+	"t2.nano":     2,
+	"t2.micro":    2,
+	"t2.small":    3,
+	"t2.medium":   3,
+	"t2.large":    3,
+	"t2.xlarge":   3,
+	"t2.2xlarge":  3,
+}
+
+// TaskENISlots returns the number of ENIs that tasks can use on an instance of
+// the given type, which excludes the primary ENI of the instance. It returns
+// false if the limit of the instance type is not known
+func TaskENISlots(instanceType string) (int, bool) {
+	maxENIs, ok := maxENIsByInstanceType[instanceType]
+	if !ok {
+		return 0, false
+	}
+	return maxENIs - 1, true
+}
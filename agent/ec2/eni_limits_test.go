@@ -0,0 +1,29 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package ec2_test
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskENISlots(t *testing.T) {
+	slots, ok := ec2.TaskENISlots("c4.8xlarge")
+	assert.True(t, ok)
+	assert.Equal(t, 7, slots, "the primary ENI should not be a task ENI slot")
+
+	_, ok = ec2.TaskENISlots("unknown.type")
+	assert.False(t, ok)
+}
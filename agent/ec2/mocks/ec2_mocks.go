@@ -87,6 +87,17 @@ func (_mr *_MockEC2MetadataClientRecorder) InstanceIdentityDocument() *gomock.Ca
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "InstanceIdentityDocument")
 }
 
+func (_m *MockEC2MetadataClient) InstanceTags() (map[string]string, error) {
+	ret := _m.ctrl.Call(_m, "InstanceTags")
+	ret0, _ := ret[0].(map[string]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockEC2MetadataClientRecorder) InstanceTags() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "InstanceTags")
+}
+
 func (_m *MockEC2MetadataClient) PrimaryENIMAC() (string, error) {
 	ret := _m.ctrl.Call(_m, "PrimaryENIMAC")
 	ret0, _ := ret[0].(string)
@@ -15,6 +15,7 @@ package ecr
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/async"
@@ -25,6 +26,8 @@ import (
 )
 
 const (
+	// MinimumJitterDuration and MaximumJitterDuration bound how long before
+	// their expiration cached tokens are refreshed
 	MinimumJitterDuration = 30 * time.Minute
 	MaximumJitterDuration = 1 * time.Hour
 )
@@ -45,6 +48,10 @@ type ECRSDK interface {
 type ecrClient struct {
 	sdkClient  ECRSDK
 	tokenCache async.Cache
+	// fetchLock serializes the lookups of tokens, so that concurrent pulls
+	// from the same registry wait for the token fetched by the first one
+	// instead of each calling ECR
+	fetchLock sync.Mutex
 }
 
 func NewECRClient(sdkClient ECRSDK, tokenCache async.Cache) ECRClient {
@@ -55,6 +62,9 @@ func NewECRClient(sdkClient ECRSDK, tokenCache async.Cache) ECRClient {
 }
 
 func (client *ecrClient) GetAuthorizationToken(registryId string) (*ecrapi.AuthorizationData, error) {
+	client.fetchLock.Lock()
+	defer client.fetchLock.Unlock()
+
 	cachedToken, found := client.tokenCache.Get(registryId)
 	if found {
 		cachedAuthData := cachedToken.(*ecrapi.AuthorizationData)
@@ -97,5 +107,5 @@ func (client *ecrClient) IsTokenValid(authData *ecrapi.AuthorizationData) bool {
 }
 
 func (client *ecrClient) expirationJitter() time.Duration {
-	return utils.AddJitter(MinimumJitterDuration, MaximumJitterDuration)
+	return utils.AddJitter(MinimumJitterDuration, MaximumJitterDuration-MinimumJitterDuration)
 }
@@ -59,14 +59,11 @@ func NewECRFactory(acceptInsecureCert bool) ECRFactory {
 // GetClient returns the correct region- and endpoint-aware client
 func (factory *ecrFactory) GetClient(region, endpointOverride string) ECRClient {
 	key := cacheKey{region: region, endpointOverride: endpointOverride}
-	client, ok := factory.clients[key]
-	if ok {
-		return client
-	}
-
+	// Clients are shared by concurrent pulls, so that they share the cache of
+	// tokens. The map is only ever accessed with the lock held
 	factory.clientsLock.Lock()
 	defer factory.clientsLock.Unlock()
-	client, ok = factory.clients[key]
+	client, ok := factory.clients[key]
 	if ok {
 		return client
 	}
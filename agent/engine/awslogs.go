@@ -0,0 +1,57 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/cihub/seelog"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	awslogsDriver = "awslogs"
+	// awslogsGroupOption is the log driver option with the log group
+	awslogsGroupOption = "awslogs-group"
+	// awslogsRegionOption is the log driver option with the region of the
+	// log group. The region of the instance is used if it is not set
+	awslogsRegionOption = "awslogs-region"
+	// awslogsCreateGroupOption is the log driver option that makes Docker
+	// create the log group itself
+	awslogsCreateGroupOption = "awslogs-create-group"
+)
+
+// createAWSLogsGroup creates the log group of the container if it uses the
+// awslogs log driver, so that the container doesn't fail to start because the
+// log group doesn't exist
+func (engine *DockerTaskEngine) createAWSLogsGroup(container *api.Container, hostConfig *docker.HostConfig) error {
+	if engine.logGroupCreator == nil || hostConfig.LogConfig.Type != awslogsDriver {
+		return nil
+	}
+	group := hostConfig.LogConfig.Config[awslogsGroupOption]
+	if group == "" || utils.ParseBool(hostConfig.LogConfig.Config[awslogsCreateGroupOption], false) {
+		return nil
+	}
+	region := hostConfig.LogConfig.Config[awslogsRegionOption]
+	if region == "" {
+		region = engine.cfg.AWSRegion
+	}
+
+	if err := engine.logGroupCreator.CreateLogGroup(group, region); err != nil {
+		return fmt.Errorf("unable to create log group %s of container %s: %v", group, container.Name, err)
+	}
+	seelog.Debugf("Log group %s of container %s exists in region %s", group, container.Name, region)
+	return nil
+}
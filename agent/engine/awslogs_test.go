@@ -0,0 +1,126 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/awslogs/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/aws-sdk-go/aws"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func awslogsTask(logConfig string) *api.Task {
+	return &api.Task{
+		Arn:     "myTaskArn",
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				DockerConfig: api.DockerConfig{
+					HostConfig: aws.String(`{"LogConfig":` + logConfig + `}`),
+				},
+			},
+		},
+	}
+}
+
+func TestCreateContainerCreatesAWSLogsGroup(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	logGroupCreator := mock_awslogs.NewMockLogGroupCreator(ctrl)
+	taskEngine.(*DockerTaskEngine).logGroupCreator = logGroupCreator
+
+	task := awslogsTask(`{"Type":"awslogs","Config":{"awslogs-group":"myGroup","awslogs-region":"eu-west-1"}}`)
+	gomock.InOrder(
+		// The log group creator succeeds if the log group already exists
+		logGroupCreator.EXPECT().CreateLogGroup("myGroup", "eu-west-1").Return(nil),
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+				assert.Equal(t, "awslogs", hostConfig.LogConfig.Type)
+			}),
+	)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	assert.NoError(t, metadata.Error)
+}
+
+func TestCreateContainerCreatesAWSLogsGroupInInstanceRegion(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	logGroupCreator := mock_awslogs.NewMockLogGroupCreator(ctrl)
+	taskEngine.(*DockerTaskEngine).logGroupCreator = logGroupCreator
+
+	task := awslogsTask(`{"Type":"awslogs","Config":{"awslogs-group":"myGroup"}}`)
+	logGroupCreator.EXPECT().CreateLogGroup("myGroup", "us-west-2").Return(nil)
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	assert.NoError(t, metadata.Error)
+}
+
+func TestCreateContainerAWSLogsGroupAccessDenied(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	logGroupCreator := mock_awslogs.NewMockLogGroupCreator(ctrl)
+	taskEngine.(*DockerTaskEngine).logGroupCreator = logGroupCreator
+
+	task := awslogsTask(`{"Type":"awslogs","Config":{"awslogs-group":"myGroup"}}`)
+	logGroupCreator.EXPECT().CreateLogGroup("myGroup", "us-west-2").Return(
+		errors.New("AccessDeniedException: not authorized to perform: logs:CreateLogGroup"))
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "AWSLogsGroupError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), "myGroup")
+	assert.Contains(t, metadata.Error.Error(), "AccessDeniedException")
+}
+
+func TestCreateContainerAWSLogsGroupNotCreated(t *testing.T) {
+	testCases := []struct {
+		name      string
+		logConfig string
+	}{
+		{"other log driver", `{"Type":"json-file","Config":{"awslogs-group":"myGroup"}}`},
+		{"no log group", `{"Type":"awslogs","Config":{"awslogs-region":"us-west-2"}}`},
+		{"created by docker", `{"Type":"awslogs","Config":{"awslogs-group":"myGroup","awslogs-create-group":"true"}}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+			defer ctrl.Finish()
+			taskEngine.(*DockerTaskEngine).logGroupCreator = mock_awslogs.NewMockLogGroupCreator(ctrl)
+
+			task := awslogsTask(tc.logConfig)
+			client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+
+			metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+			assert.NoError(t, metadata.Error)
+		})
+	}
+}
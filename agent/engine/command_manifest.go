@@ -0,0 +1,82 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/cihub/seelog"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+)
+
+// commandManifest is the content of the command manifest of a container
+type commandManifest struct {
+	EntryPoint *[]string `json:"entryPoint"`
+	Command    *[]string `json:"command"`
+}
+
+// applyCommandManifest reads the command manifest of the container, if it has
+// one, and sets the entry point and command it defines on the config the
+// container is created with. The manifest takes precedence over the task
+// definition, including its docker config, for each of the entry point and
+// command it defines; the ones it leaves out are kept from the task definition
+func (engine *DockerTaskEngine) applyCommandManifest(container *api.Container, config *docker.Config) engineError {
+	if container.CommandManifest == "" {
+		return nil
+	}
+	manifest, err := engine.readCommandManifest(container.CommandManifest)
+	if err != nil {
+		return CommandManifestError{container: container.Name, manifest: container.CommandManifest, err: err}
+	}
+	if manifest.EntryPoint != nil {
+		config.Entrypoint = *manifest.EntryPoint
+	}
+	if manifest.Command != nil {
+		config.Cmd = *manifest.Command
+	}
+	seelog.Infof("Applied command manifest %s to container %s", container.CommandManifest, container.Name)
+	return nil
+}
+
+// readCommandManifest reads a command manifest from the command manifest
+// directory. Manifests outside of the directory are rejected
+func (engine *DockerTaskEngine) readCommandManifest(manifestPath string) (*commandManifest, error) {
+	manifestDir := engine.cfg.CommandManifestDir
+	if manifestDir == "" {
+		return nil, errors.New("command manifests are not enabled")
+	}
+	manifestDir = filepath.Clean(manifestDir)
+	fullPath := filepath.Join(manifestDir, manifestPath)
+	if !strings.HasPrefix(fullPath, manifestDir+string(filepath.Separator)) {
+		return nil, errors.New("manifest is outside of the command manifest directory")
+	}
+
+	data, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read manifest")
+	}
+	manifest := &commandManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrap(err, "malformed manifest")
+	}
+	if manifest.EntryPoint == nil && manifest.Command == nil {
+		return nil, errors.New("manifest defines neither an entry point nor a command")
+	}
+	return manifest, nil
+}
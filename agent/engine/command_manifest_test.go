@@ -0,0 +1,137 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/aws-sdk-go/aws"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func commandManifestTask(container *api.Container) *api.Task {
+	return &api.Task{
+		Arn:        "myTaskArn",
+		Family:     "myFamily",
+		Version:    "1",
+		Containers: []*api.Container{container},
+	}
+}
+
+func TestCreateContainerCommandManifestPrecedence(t *testing.T) {
+	testCases := []struct {
+		name               string
+		manifest           string
+		dockerConfig       *string
+		expectedEntryPoint []string
+		expectedCommand    []string
+	}{
+		{
+			name:               "manifest overrides entry point and command",
+			manifest:           `{"entryPoint":["/bin/sh","-c"],"command":["./serve --v2"]}`,
+			expectedEntryPoint: []string{"/bin/sh", "-c"},
+			expectedCommand:    []string{"./serve --v2"},
+		},
+		{
+			name:               "entry point kept from the task definition",
+			manifest:           `{"command":["./serve --v2"]}`,
+			expectedEntryPoint: []string{"/entrypoint.sh"},
+			expectedCommand:    []string{"./serve --v2"},
+		},
+		{
+			name:               "manifest overrides the docker config",
+			manifest:           `{"command":["./serve --v2"]}`,
+			dockerConfig:       aws.String(`{"Cmd":["./serve --docker-config"]}`),
+			expectedEntryPoint: []string{"/entrypoint.sh"},
+			expectedCommand:    []string{"./serve --v2"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifestDir, err := ioutil.TempDir("", "ecs_command_manifest_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(manifestDir)
+			require.NoError(t, ioutil.WriteFile(filepath.Join(manifestDir, "app.json"), []byte(tc.manifest), 0644))
+
+			cfg := config.DefaultConfig()
+			cfg.CommandManifestDir = manifestDir
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+
+			entryPoint := []string{"/entrypoint.sh"}
+			container := &api.Container{
+				Name:            "c1",
+				EntryPoint:      &entryPoint,
+				Command:         []string{"./serve"},
+				CommandManifest: "app.json",
+				DockerConfig:    api.DockerConfig{Config: tc.dockerConfig},
+			}
+			task := commandManifestTask(container)
+
+			client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+					assert.Equal(t, tc.expectedEntryPoint, config.Entrypoint)
+					assert.Equal(t, tc.expectedCommand, config.Cmd)
+				})
+
+			metadata := taskEngine.(*DockerTaskEngine).createContainer(task, container)
+			assert.NoError(t, metadata.Error)
+		})
+	}
+}
+
+func TestCreateContainerCommandManifestErrors(t *testing.T) {
+	manifestDir, err := ioutil.TempDir("", "ecs_command_manifest_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(manifestDir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(manifestDir, "malformed.json"), []byte(`{"command":`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(manifestDir, "empty.json"), []byte(`{}`), 0644))
+
+	testCases := []struct {
+		name        string
+		manifestDir string
+		manifest    string
+	}{
+		{"not enabled", "", "app.json"},
+		{"missing", manifestDir, "missing.json"},
+		{"malformed", manifestDir, "malformed.json"},
+		{"empty", manifestDir, "empty.json"},
+		{"outside of the directory", manifestDir, "../app.json"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.CommandManifestDir = tc.manifestDir
+			ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+
+			container := &api.Container{Name: "c1", CommandManifest: tc.manifest}
+			task := commandManifestTask(container)
+
+			metadata := taskEngine.(*DockerTaskEngine).createContainer(task, container)
+			require.Error(t, metadata.Error)
+			assert.Equal(t, "CommandManifestError", metadata.Error.ErrorName())
+			assert.Contains(t, metadata.Error.Error(), tc.manifest)
+		})
+	}
+}
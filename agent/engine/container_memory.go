@@ -0,0 +1,46 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/cihub/seelog"
+	"github.com/docker/docker/pkg/system"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// readInstanceMemory returns the memory of the instance in bytes, or 0 if it
+// can't be read
+func readInstanceMemory() int64 {
+	memInfo, err := system.ReadMemInfo()
+	if err != nil {
+		seelog.Warnf("Unable to read the memory of the instance, memory limits of containers will not be checked against it: %v", err)
+		return 0
+	}
+	return memInfo.MemTotal
+}
+
+// validateContainerMemory returns an error if the memory limit the container
+// is created with exceeds the memory of the instance, as docker would either
+// reject it or never enforce it
+func (engine *DockerTaskEngine) validateContainerMemory(container *api.Container, config *docker.Config) engineError {
+	if engine.instanceMemory <= 0 || config.Memory <= engine.instanceMemory {
+		return nil
+	}
+	return MemoryLimitError{
+		container:      container.Name,
+		memory:         config.Memory,
+		instanceMemory: engine.instanceMemory,
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateContainerMemoryOverInstanceCapacity(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine.(*DockerTaskEngine).instanceMemory = 1024 * 1024 * 1024
+
+	task := &api.Task{
+		Arn: "myTaskArn",
+		Containers: []*api.Container{
+			{Name: "c1", Memory: 2, MemoryUnit: api.MemoryUnitGiB},
+		},
+	}
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "MemoryLimitError", metadata.Error.ErrorName())
+}
+
+func TestCreateContainerMemoryWithinInstanceCapacity(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine.(*DockerTaskEngine).instanceMemory = 1024 * 1024 * 1024
+
+	task := &api.Task{
+		Arn: "myTaskArn",
+		Containers: []*api.Container{
+			{Name: "c1", Memory: 1024, MemoryUnit: api.MemoryUnitMiB},
+		},
+	}
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(DockerContainerMetadata{DockerID: "dockerID"})
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	assert.NoError(t, metadata.Error)
+}
+
+func TestCreateContainerZeroMemoryWithUnit(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	task := &api.Task{
+		Arn: "myTaskArn",
+		Containers: []*api.Container{
+			{Name: "c1", MemoryUnit: api.MemoryUnitMiB},
+		},
+	}
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "DockerClientConfigError", metadata.Error.ErrorName())
+}
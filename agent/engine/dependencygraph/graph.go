@@ -89,17 +89,104 @@ func DependenciesAreResolved(target *api.Container, by []*api.Container) bool {
 	for _, cont := range by {
 		nameMap[cont.Name] = cont
 	}
-	neededVolumeContainers := make([]string, len(target.VolumesFrom))
-	for i, volume := range target.VolumesFrom {
-		neededVolumeContainers[i] = volume.SourceContainer
+	var neededVolumeContainers, populatingVolumeContainers []string
+	for _, volume := range target.VolumesFrom {
+		if volume.WaitForCompletion {
+			populatingVolumeContainers = append(populatingVolumeContainers, volume.SourceContainer)
+		} else {
+			neededVolumeContainers = append(neededVolumeContainers, volume.SourceContainer)
+		}
 	}
 
 	return verifyStatusResolvable(target, nameMap, neededVolumeContainers, volumeIsResolved) &&
+		verifyStatusResolvable(target, nameMap, populatingVolumeContainers, volumeIsPopulated) &&
 		verifyStatusResolvable(target, nameMap, linksToContainerNames(target.Links), linkIsResolved) &&
 		verifyStatusResolvable(target, nameMap, target.SteadyStateDependencies, onSteadyStateIsResolved) &&
 		verifyTransitionDependenciesResolved(target, nameMap)
 }
 
+// WaitingForVolumeCompletion returns true if the `target` container can not be
+// started yet because a container populating its volumes has not exited. The
+// target can be started once these containers have exited successfully.
+func WaitingForVolumeCompletion(target *api.Container, by []*api.Container) bool {
+	if target.GetDesiredStatus() != target.GetSteadyStateStatus() {
+		return false
+	}
+	nameMap := make(map[string]*api.Container)
+	for _, cont := range by {
+		nameMap[cont.Name] = cont
+	}
+	for _, volume := range target.VolumesFrom {
+		if !volume.WaitForCompletion {
+			continue
+		}
+		source, ok := nameMap[volume.SourceContainer]
+		if ok && source.GetKnownStatus() < api.ContainerStopped && !source.DesiredTerminal() {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitingForHealthyDependency returns true if the `target` container can not
+// be transitioned yet because a container it depends on has not reported the
+// health its transition requires. The target can be transitioned once the
+// health of that container changes, which is reported by a container event.
+func WaitingForHealthyDependency(target *api.Container, by []*api.Container) bool {
+	if target.GetDesiredStatus() >= api.ContainerStopped {
+		return false
+	}
+	nameMap := make(map[string]*api.Container)
+	for _, cont := range by {
+		nameMap[cont.Name] = cont
+	}
+	for _, dependency := range target.TransitionDependencySet.ContainerDependencies {
+		if dependency.SatisfiedHealth == "" {
+			continue
+		}
+		resource, ok := nameMap[dependency.ContainerName]
+		if !ok || resource.HealthCheck == nil || resource.DesiredTerminal() {
+			// The health of the resource will never change
+			continue
+		}
+		if resource.GetKnownStatus() == api.ContainerRunning &&
+			!resolvesContainerTransitionDependency(target, resource, dependency) {
+			return true
+		}
+	}
+	return false
+}
+
+// WaitingForReadyDependency returns true if the `target` container can not be
+// transitioned yet because a container it depends on has not been found to be
+// ready by its readiness probe. The target can be transitioned once the probe
+// of that container succeeds.
+func WaitingForReadyDependency(target *api.Container, by []*api.Container) bool {
+	if target.GetDesiredStatus() >= api.ContainerStopped {
+		return false
+	}
+	nameMap := make(map[string]*api.Container)
+	for _, cont := range by {
+		nameMap[cont.Name] = cont
+	}
+	for _, dependency := range target.TransitionDependencySet.ContainerDependencies {
+		if !dependency.SatisfiedReady {
+			continue
+		}
+		resource, ok := nameMap[dependency.ContainerName]
+		if !ok || resource.ReadinessProbe == nil || resource.DesiredTerminal() ||
+			resource.GetReadinessStatus() == api.ContainerNotReady {
+			// The readiness of the resource will never change
+			continue
+		}
+		if resource.GetKnownStatus() == api.ContainerRunning &&
+			!resolvesContainerTransitionDependency(target, resource, dependency) {
+			return true
+		}
+	}
+	return false
+}
+
 func linksToContainerNames(links []string) []string {
 	names := make([]string, 0, len(links))
 	for _, link := range links {
@@ -170,7 +257,13 @@ func resolvesContainerTransitionDependency(target *api.Container, resource *api.
 		return true
 	}
 	resourceKnown := resource.GetKnownStatus()
-	return resourceKnown >= dependency.SatisfiedStatus
+	if resourceKnown < dependency.SatisfiedStatus {
+		return false
+	}
+	if dependency.SatisfiedReady && resource.GetReadinessStatus() != api.ContainerReady {
+		return false
+	}
+	return dependency.SatisfiedHealth == "" || resource.GetHealthStatus() == dependency.SatisfiedHealth
 }
 
 func linkCanResolve(target *api.Container, link *api.Container) bool {
@@ -243,6 +336,18 @@ func volumeIsResolved(target *api.Container, volume *api.Container) bool {
 		knownStatus == api.ContainerStopped
 }
 
+// volumeIsPopulated defines a relationship where a target can be created once
+// the source volume container is resolved, but cannot be started until the
+// source volume container has exited successfully, populating the volumes.
+func volumeIsPopulated(target *api.Container, volume *api.Container) bool {
+	if target.GetKnownStatus() < api.ContainerCreated {
+		return volumeIsResolved(target, volume)
+	}
+
+	exitCode := volume.GetKnownExitCode()
+	return volume.GetKnownStatus() == api.ContainerStopped && exitCode != nil && *exitCode == 0
+}
+
 func onSteadyStateCanResolve(target *api.Container, run *api.Container) bool {
 	return target.GetDesiredStatus() >= api.ContainerCreated &&
 		run.GetDesiredStatus() >= run.GetSteadyStateStatus()
@@ -567,3 +567,135 @@ func TestTransitionDependenciesResolved(t *testing.T) {
 		})
 	}
 }
+
+func TestDependenciesAreResolvedWaitForHealthy(t *testing.T) {
+	db := steadyStateContainer("db", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	db.HealthCheck = &api.HealthCheck{Command: []string{"CMD", "true"}}
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.SetKnownStatus(api.ContainerPulled)
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedHealth: api.ContainerHealthy,
+		DependentStatus: api.ContainerCreated,
+	}}
+	containers := []*api.Container{db, app}
+
+	db.SetKnownStatus(api.ContainerCreated)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created before db runs")
+	assert.False(t, WaitingForHealthyDependency(app, containers), "db has to run before it reports health")
+
+	db.SetKnownStatus(api.ContainerRunning)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created before db is healthy")
+	assert.True(t, WaitingForHealthyDependency(app, containers))
+
+	db.SetHealthStatus(api.ContainerUnhealthy)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created while db is unhealthy")
+	assert.True(t, WaitingForHealthyDependency(app, containers))
+
+	db.SetHealthStatus(api.ContainerHealthy)
+	assert.True(t, DependenciesAreResolved(app, containers), "app should be created once db is healthy")
+	assert.False(t, WaitingForHealthyDependency(app, containers))
+}
+
+func TestWaitingForHealthyDependencyWithoutHealthCheck(t *testing.T) {
+	db := steadyStateContainer("db", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	db.SetKnownStatus(api.ContainerRunning)
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.SetKnownStatus(api.ContainerPulled)
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedHealth: api.ContainerHealthy,
+		DependentStatus: api.ContainerCreated,
+	}}
+	containers := []*api.Container{db, app}
+
+	assert.False(t, DependenciesAreResolved(app, containers))
+	assert.False(t, WaitingForHealthyDependency(app, containers), "db without a health check never becomes healthy")
+}
+
+func TestDependenciesAreResolvedWaitForReady(t *testing.T) {
+	db := steadyStateContainer("db", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	db.ReadinessProbe = &api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: 5432}
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.SetKnownStatus(api.ContainerPulled)
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedReady:  true,
+		DependentStatus: api.ContainerCreated,
+	}}
+	containers := []*api.Container{db, app}
+
+	db.SetKnownStatus(api.ContainerCreated)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created before db runs")
+	assert.False(t, WaitingForReadyDependency(app, containers), "db has to run before it is probed")
+
+	db.SetKnownStatus(api.ContainerRunning)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created before db is ready")
+	assert.True(t, WaitingForReadyDependency(app, containers))
+
+	db.SetReadinessStatus(api.ContainerReady)
+	assert.True(t, DependenciesAreResolved(app, containers), "app should be created once db is ready")
+	assert.False(t, WaitingForReadyDependency(app, containers))
+}
+
+func TestWaitingForReadyDependencyNotReady(t *testing.T) {
+	db := steadyStateContainer("db", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	db.SetKnownStatus(api.ContainerRunning)
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.SetKnownStatus(api.ContainerPulled)
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedReady:  true,
+		DependentStatus: api.ContainerCreated,
+	}}
+	containers := []*api.Container{db, app}
+
+	assert.False(t, DependenciesAreResolved(app, containers))
+	assert.False(t, WaitingForReadyDependency(app, containers), "db without a readiness probe never becomes ready")
+
+	db.ReadinessProbe = &api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: 5432}
+	db.SetReadinessStatus(api.ContainerNotReady)
+	assert.False(t, DependenciesAreResolved(app, containers))
+	assert.False(t, WaitingForReadyDependency(app, containers), "db whose probe failed never becomes ready")
+}
+
+func TestDependenciesAreResolvedWaitForCompletion(t *testing.T) {
+	populate := steadyStateContainer("populate", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.VolumesFrom = []api.VolumeFrom{{SourceContainer: "populate", WaitForCompletion: true}}
+	containers := []*api.Container{populate, app}
+
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't be created before the volume container")
+
+	populate.SetKnownStatus(api.ContainerCreated)
+	assert.True(t, DependenciesAreResolved(app, containers), "app should be created once the volume container is created")
+
+	app.SetKnownStatus(api.ContainerCreated)
+	populate.SetKnownStatus(api.ContainerRunning)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't start while the volume container runs")
+	assert.True(t, WaitingForVolumeCompletion(app, containers))
+
+	exitCode := 0
+	populate.SetKnownExitCode(&exitCode)
+	populate.SetKnownStatus(api.ContainerStopped)
+	assert.True(t, DependenciesAreResolved(app, containers), "app should start once the volume container exited successfully")
+	assert.False(t, WaitingForVolumeCompletion(app, containers))
+}
+
+func TestDependenciesAreResolvedWaitForCompletionFailed(t *testing.T) {
+	populate := steadyStateContainer("populate", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app := steadyStateContainer("app", []string{}, []string{}, api.ContainerRunning, api.ContainerRunning)
+	app.VolumesFrom = []api.VolumeFrom{{SourceContainer: "populate", WaitForCompletion: true}}
+	containers := []*api.Container{populate, app}
+
+	app.SetKnownStatus(api.ContainerCreated)
+	exitCode := 1
+	populate.SetKnownExitCode(&exitCode)
+	populate.SetKnownStatus(api.ContainerStopped)
+	assert.False(t, DependenciesAreResolved(app, containers), "app shouldn't start if the volume container failed")
+	assert.False(t, WaitingForVolumeCompletion(app, containers), "a failed volume container won't complete anymore")
+}
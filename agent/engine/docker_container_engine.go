@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -59,8 +60,15 @@ const (
 	startContainerTimeout   = 3 * time.Minute
 	stopContainerTimeout    = 30 * time.Second
 	removeContainerTimeout  = 5 * time.Minute
+	unpauseContainerTimeout = 30 * time.Second
 	inspectContainerTimeout = 30 * time.Second
 	removeImageTimeout      = 3 * time.Minute
+	inspectNetworkTimeout   = 30 * time.Second
+	createNetworkTimeout    = 1 * time.Minute
+	versionTimeout          = 30 * time.Second
+
+	// WritableLayerSizeTimeout is the timeout for the WritableLayerSize API.
+	WritableLayerSizeTimeout = 1 * time.Minute
 
 	// dockerPullBeginTimeout is the timeout from when a 'pull' is called to when
 	// we expect to see output on the pull progress stream. This is to work
@@ -70,6 +78,11 @@ const (
 	// pullStatusSuppressDelay controls the time where pull status progress bar
 	// output will be suppressed in debug mode
 	pullStatusSuppressDelay = 2 * time.Second
+	// pullStatusLayerExists and pullStatusLayerPulled end the pull status
+	// lines of layers that were already present and of layers that were
+	// pulled from the registry
+	pullStatusLayerExists = ": Already exists"
+	pullStatusLayerPulled = ": Pull complete"
 
 	// StatsInactivityTimeout controls the amount of time we hold open a
 	// connection to the Docker daemon waiting for stats data
@@ -100,7 +113,8 @@ type DockerClient interface {
 	ContainerEvents(ctx context.Context) (<-chan DockerContainerChangeEvent, error)
 
 	// PullImage pulls an image. authData should contain authentication data provided by the ECS backend.
-	PullImage(image string, authData *api.RegistryAuthenticationData) DockerContainerMetadata
+	// platform selects the variant of a multi-arch image to pull, the default variant is pulled if empty.
+	PullImage(image string, authData *api.RegistryAuthenticationData, platform string) DockerContainerMetadata
 
 	// ImportLocalEmptyVolumeImage imports a locally-generated empty-volume image for supported platforms.
 	ImportLocalEmptyVolumeImage() DockerContainerMetadata
@@ -117,6 +131,11 @@ type DockerClient interface {
 	// request.
 	StopContainer(string, time.Duration) DockerContainerMetadata
 
+	// StopContainerWithGracePeriod stops the container identified by the name provided, giving it the grace period
+	// provided, rather than the configured stop timeout, to exit before it is killed. A timeout value should be
+	// provided for the request.
+	StopContainerWithGracePeriod(string, time.Duration, time.Duration) DockerContainerMetadata
+
 	// DescribeContainer returns status information about the specified container.
 	DescribeContainer(string) (api.ContainerStatus, DockerContainerMetadata)
 
@@ -124,6 +143,10 @@ type DockerClient interface {
 	// A timeout value should be provided for the request.
 	RemoveContainer(string, time.Duration) error
 
+	// UnpauseContainer unpauses the container identified by the name provided. A timeout value should be provided
+	// for the request.
+	UnpauseContainer(string, time.Duration) error
+
 	// InspectContainer returns information about the specified container. A timeout value should be provided for the
 	// request.
 	InspectContainer(string, time.Duration) (*docker.Container, error)
@@ -132,6 +155,10 @@ type DockerClient interface {
 	// the request.
 	ListContainers(bool, time.Duration) ListContainersResponse
 
+	// WritableLayerSize returns the size, in bytes, of the writable layer of the specified container. Computing it is
+	// expensive for the Docker daemon. A timeout value should be provided for the request.
+	WritableLayerSize(string, time.Duration) (int64, error)
+
 	// Stats returns a channel of stat data for the specified container. A context should be provided so the request can
 	// be canceled.
 	Stats(string, context.Context) (<-chan *docker.Stats, error)
@@ -146,6 +173,14 @@ type DockerClient interface {
 	// value should be provided for the request.
 	RemoveImage(string, time.Duration) error
 	LoadImage(io.Reader, time.Duration) error
+
+	// InspectNetwork returns information about the specified docker network.
+	// A timeout value should be provided for the request.
+	InspectNetwork(string, time.Duration) (*docker.Network, error)
+
+	// CreateNetwork creates a docker network with the specified name and the
+	// default driver. A timeout value should be provided for the request.
+	CreateNetwork(string, time.Duration) error
 }
 
 // DockerGoClient wraps the underlying go-dockerclient library.
@@ -230,22 +265,25 @@ func (dg *dockerGoClient) time() ttime.Time {
 	return dg._time
 }
 
-func (dg *dockerGoClient) PullImage(image string, authData *api.RegistryAuthenticationData) DockerContainerMetadata {
+func (dg *dockerGoClient) PullImage(image string, authData *api.RegistryAuthenticationData, platform string) DockerContainerMetadata {
 	// TODO Switch to just using context.WithDeadline and get rid of this funky code
 	timeout := dg.time().After(pullImageTimeout)
 	ctx, cancel := context.WithCancel(context.TODO())
 
 	response := make(chan DockerContainerMetadata, 1)
 	go func() {
+		var source api.ImagePullSource
 		imagePullBackoff := utils.NewSimpleBackoff(minimumPullRetryDelay, maximumPullRetryDelay, pullRetryJitterMultiplier, pullRetryDelayMultiplier)
 		err := utils.RetryNWithBackoffCtx(ctx, imagePullBackoff, maximumPullRetries, func() error {
-			err := dg.pullImage(image, authData)
+			var err engineError
+			source, err = dg.pullImage(image, authData, platform)
 			if err != nil {
 				seelog.Warnf("Failed to pull image %s: %s", image, err.Error())
+				return err
 			}
-			return err
+			return nil
 		})
-		response <- DockerContainerMetadata{Error: wrapPullErrorAsEngineError(err)}
+		response <- DockerContainerMetadata{Error: wrapPullErrorAsEngineError(err), ImagePullSource: source}
 	}()
 	select {
 	case resp := <-response:
@@ -268,16 +306,18 @@ func wrapPullErrorAsEngineError(err error) engineError {
 	return retErr
 }
 
-func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenticationData) engineError {
+// pullImage pulls an image and classifies, from the status docker reports for
+// each layer, where the image came from
+func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenticationData, platform string) (api.ImagePullSource, engineError) {
 	log.Debug("Pulling image", "image", image)
 	client, err := dg.dockerClient()
 	if err != nil {
-		return CannotGetDockerClientError{version: dg.version, err: err}
+		return "", CannotGetDockerClientError{version: dg.version, err: err}
 	}
 
 	authConfig, err := dg.getAuthdata(image, authData)
 	if err != nil {
-		return wrapPullErrorAsEngineError(err)
+		return "", wrapPullErrorAsEngineError(err)
 	}
 
 	pullDebugOut, pullWriter := io.Pipe()
@@ -292,6 +332,7 @@ func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenti
 
 	opts := docker.PullImageOptions{
 		Repository:   repository,
+		Platform:     platform,
 		OutputStream: pullWriter,
 	}
 	timeout := dg.time().After(dockerPullBeginTimeout)
@@ -300,8 +341,21 @@ func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenti
 	pullBegan := make(chan bool, 1)
 	// pullBeganOnce ensures we only indicate it began once (since our channel will only be read 0 or 1 times)
 	pullBeganOnce := sync.Once{}
+	// cachedLayers and pulledLayers count the layers docker reported as
+	// already present and as pulled. They are only read once readDone is
+	// closed
+	var cachedLayers, pulledLayers int
+	readDone := make(chan struct{})
+	// pullSource waits for the last status lines of a finished pull to be
+	// read and classifies the pull from them
+	pullSource := func() api.ImagePullSource {
+		pullWriter.Close()
+		<-readDone
+		return api.ImagePullSourceFromLayers(cachedLayers, pulledLayers)
+	}
 
 	go func() {
+		defer close(readDone)
 		reader := bufio.NewReader(pullDebugOut)
 		var line string
 		var pullErr error
@@ -326,6 +380,13 @@ func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenti
 				// This can mean the daemon is 'hung' in pulling status for this image, but we can't be sure.
 				log.Error("Image 'pull' status marked as already being pulled", "image", image, "status", line)
 			}
+
+			status := strings.TrimSpace(line)
+			if strings.HasSuffix(status, pullStatusLayerExists) {
+				cachedLayers++
+			} else if strings.HasSuffix(status, pullStatusLayerPulled) {
+				pulledLayers++
+			}
 		}
 		if pullErr != nil && pullErr != io.EOF {
 			log.Warn("Error reading pull image status", "image", image, "err", pullErr)
@@ -342,20 +403,30 @@ func (dg *dockerGoClient) pullImage(image string, authData *api.RegistryAuthenti
 		break
 	case pullErr := <-pullFinished:
 		if pullErr != nil {
-			return CannotPullContainerError{pullErr}
+			return "", wrapPullError(pullErr, authConfig)
 		}
-		return nil
+		return pullSource(), nil
 	case <-timeout:
-		return &DockerTimeoutError{dockerPullBeginTimeout, "pullBegin"}
+		return "", &DockerTimeoutError{dockerPullBeginTimeout, "pullBegin"}
 	}
 	log.Debug("Pull began for image", "image", image)
 	defer log.Debug("Pull completed for image", "image", image)
 
 	err = <-pullFinished
 	if err != nil {
-		return CannotPullContainerError{err}
+		return "", wrapPullError(err, authConfig)
+	}
+	return pullSource(), nil
+}
+
+// wrapPullError wraps the error docker returned for the pull of an image. A
+// pull rejected for missing authentication, when no credentials were sent,
+// is reported as such rather than with the opaque error of the registry
+func wrapPullError(err error, authConfig docker.AuthConfiguration) engineError {
+	if authConfig == (docker.AuthConfiguration{}) && isAuthRequiredError(err) {
+		return CannotPullContainerAuthError{err}
 	}
-	return nil
+	return CannotPullContainerError{err}
 }
 
 // ImportLocalEmptyVolumeImage imports a locally-generated empty-volume image for supported platforms.
@@ -423,10 +494,25 @@ func (dg *dockerGoClient) InspectImage(image string) (*docker.Image, error) {
 }
 
 func (dg *dockerGoClient) getAuthdata(image string, authData *api.RegistryAuthenticationData) (docker.AuthConfiguration, error) {
-	if authData == nil || authData.Type != "ecr" {
-		return dg.auth.GetAuthconfig(image)
+	if authData != nil && authData.Type == "ecr" {
+		return dg.getECRAuthdata(image, authData.ECRAuthData)
 	}
-	provider := dockerauth.NewECRAuthProvider(authData.ECRAuthData, dg.ecrClientFactory)
+	authConfig, err := dg.auth.GetAuthconfig(image)
+	if err != nil || authConfig != (docker.AuthConfiguration{}) {
+		return authConfig, err
+	}
+	// Images in ECR registries without configured auth data are pulled with a
+	// token fetched with the credentials of the instance
+	if ecrAuthData, ok := dockerauth.ECRAuthDataForImage(image); ok {
+		return dg.getECRAuthdata(image, ecrAuthData)
+	}
+	return authConfig, nil
+}
+
+// getECRAuthdata returns the auth configuration to pull the image from ECR.
+// Tokens are cached by registry and region until they are about to expire
+func (dg *dockerGoClient) getECRAuthdata(image string, ecrAuthData *api.ECRAuthData) (docker.AuthConfiguration, error) {
+	provider := dockerauth.NewECRAuthProvider(ecrAuthData, dg.ecrClientFactory)
 	authConfig, err := provider.GetAuthconfig(image)
 	if err != nil {
 		return authConfig, CannotPullECRContainerError{err}
@@ -596,12 +682,16 @@ func (dg *dockerGoClient) inspectContainer(dockerID string, ctx context.Context)
 }
 
 func (dg *dockerGoClient) StopContainer(dockerID string, timeout time.Duration) DockerContainerMetadata {
-	timeout = timeout + dg.config.DockerStopTimeout
+	return dg.StopContainerWithGracePeriod(dockerID, dg.config.DockerStopTimeout, timeout)
+}
+
+func (dg *dockerGoClient) StopContainerWithGracePeriod(dockerID string, gracePeriod time.Duration, timeout time.Duration) DockerContainerMetadata {
+	timeout = timeout + gracePeriod
 
 	// Create a context that times out after the 'timeout' duration
 	// This is defined by the const 'stopContainerTimeout' and the
-	// 'DockerStopTimeout' in the config. Injecting the 'timeout'
-	// makes it easier to write tests.
+	// grace period, which defaults to the 'DockerStopTimeout' in the
+	// config. Injecting the 'timeout' makes it easier to write tests.
 	// Eventually, the context should be initialized from a parent root context
 	// instead of TODO.
 	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
@@ -610,7 +700,7 @@ func (dg *dockerGoClient) StopContainer(dockerID string, timeout time.Duration)
 	// Buffered channel so in the case of timeout it takes one write, never gets
 	// read, and can still be GC'd
 	response := make(chan DockerContainerMetadata, 1)
-	go func() { response <- dg.stopContainer(ctx, dockerID) }()
+	go func() { response <- dg.stopContainer(ctx, dockerID, gracePeriod) }()
 	select {
 	case resp := <-response:
 		return resp
@@ -625,13 +715,13 @@ func (dg *dockerGoClient) StopContainer(dockerID string, timeout time.Duration)
 	}
 }
 
-func (dg *dockerGoClient) stopContainer(ctx context.Context, dockerID string) DockerContainerMetadata {
+func (dg *dockerGoClient) stopContainer(ctx context.Context, dockerID string, gracePeriod time.Duration) DockerContainerMetadata {
 	client, err := dg.dockerClient()
 	if err != nil {
 		return DockerContainerMetadata{Error: CannotGetDockerClientError{version: dg.version, err: err}}
 	}
 
-	err = client.StopContainerWithContext(dockerID, uint(dg.config.DockerStopTimeout/time.Second), ctx)
+	err = client.StopContainerWithContext(dockerID, uint(gracePeriod/time.Second), ctx)
 	metadata := dg.containerMetadata(dockerID)
 	if err != nil {
 		log.Debug("Error stopping container", "err", err, "id", dockerID)
@@ -668,6 +758,27 @@ func (dg *dockerGoClient) RemoveContainer(dockerID string, timeout time.Duration
 	}
 }
 
+func (dg *dockerGoClient) UnpauseContainer(dockerID string, timeout time.Duration) error {
+	client, err := dg.dockerClient()
+	if err != nil {
+		return CannotUnpauseContainerError{err}
+	}
+
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan error, 1)
+	go func() { response <- client.UnpauseContainer(dockerID) }()
+	select {
+	case err := <-response:
+		if err != nil {
+			return CannotUnpauseContainerError{err}
+		}
+		return nil
+	case <-time.After(timeout):
+		return &DockerTimeoutError{timeout, "unpaused"}
+	}
+}
+
 func (dg *dockerGoClient) removeContainer(dockerID string, ctx context.Context) error {
 	client, err := dg.dockerClient()
 	if err != nil {
@@ -704,6 +815,19 @@ func metadataFromContainer(dockerContainer *docker.Container) DockerContainerMet
 		DockerID:     dockerContainer.ID,
 		PortBindings: bindings,
 		Volumes:      dockerContainer.Volumes,
+		StartedAt:    dockerContainer.State.StartedAt,
+		Paused:       dockerContainer.State.Paused,
+		HealthStatus: healthStatusFromState(dockerContainer.State.Health),
+	}
+	if dockerContainer.NetworkSettings != nil {
+		metadata.IPv4Address = dockerContainer.NetworkSettings.IPAddress
+		if metadata.IPv4Address == "" && len(dockerContainer.NetworkSettings.Networks) == 1 {
+			// Containers attached to a single user-defined network only
+			// report their address in the settings of that network
+			for _, network := range dockerContainer.NetworkSettings.Networks {
+				metadata.IPv4Address = network.IPAddress
+			}
+		}
 	}
 	// Workaround for https://github.com/docker/docker/issues/27601
 	// See https://github.com/docker/docker/blob/v1.12.2/daemon/inspect_unix.go#L38-L43
@@ -724,6 +848,11 @@ func metadataFromContainer(dockerContainer *docker.Container) DockerContainerMet
 	if dockerContainer.State.OOMKilled {
 		metadata.Error = OutOfMemoryError{}
 	}
+	if metadata.Error != nil {
+		// The reason takes the same form as the reasons derived from the
+		// applying errors of containers
+		metadata.Reason = api.NewNamedError(metadata.Error).Error()
+	}
 
 	return metadata
 }
@@ -829,6 +958,14 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 				if strings.HasPrefix(event.Status, "exec_create:") || strings.HasPrefix(event.Status, "exec_start:") {
 					continue
 				}
+				if health, ok := healthStatusFromEvent(event); ok {
+					changedContainers <- DockerContainerChangeEvent{
+						Status:                  api.ContainerRunning,
+						Health:                  health,
+						DockerContainerMetadata: DockerContainerMetadata{DockerID: containerID},
+					}
+					continue
+				}
 
 				// Because docker emits new events even when you use an old event api
 				// version, it's not that big a deal
@@ -836,6 +973,12 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 			}
 
 			metadata := dg.containerMetadata(containerID)
+			if status == api.ContainerStopped && metadata.ExitCode == nil {
+				// Containers with a restart policy may have already been
+				// restarted by docker when they're inspected, in which case
+				// the exit code is only known from the event itself
+				metadata.ExitCode = exitCodeFromEvent(event)
+			}
 
 			changedContainers <- DockerContainerChangeEvent{
 				Status:                  status,
@@ -847,6 +990,44 @@ func (dg *dockerGoClient) ContainerEvents(ctx context.Context) (<-chan DockerCon
 	return changedContainers, nil
 }
 
+// healthStatusFromEvent returns the health reported by a docker "health_status"
+// event, if the event is one
+func healthStatusFromEvent(event *docker.APIEvents) (api.ContainerHealthStatus, bool) {
+	switch event.Status {
+	case "health_status: healthy":
+		return api.ContainerHealthy, true
+	case "health_status: unhealthy":
+		return api.ContainerUnhealthy, true
+	}
+	return api.ContainerHealthUnknown, false
+}
+
+// healthStatusFromState returns the health docker reports for an inspected
+// container. Containers whose health check is still starting have no health
+func healthStatusFromState(health docker.Health) api.ContainerHealthStatus {
+	switch health.Status {
+	case "healthy":
+		return api.ContainerHealthy
+	case "unhealthy":
+		return api.ContainerUnhealthy
+	}
+	return api.ContainerHealthUnknown
+}
+
+// exitCodeFromEvent returns the exit code reported by a docker "die" event, if
+// any
+func exitCodeFromEvent(event *docker.APIEvents) *int {
+	exitCodeAttribute, ok := event.Actor.Attributes["exitCode"]
+	if !ok {
+		return nil
+	}
+	exitCode, err := strconv.Atoi(exitCodeAttribute)
+	if err != nil {
+		return nil
+	}
+	return &exitCode
+}
+
 // ListContainers returns a slice of container IDs.
 func (dg *dockerGoClient) ListContainers(all bool, timeout time.Duration) ListContainersResponse {
 	// Create a context that times out after the 'timeout' duration
@@ -899,6 +1080,59 @@ func (dg *dockerGoClient) listContainers(all bool, ctx context.Context) ListCont
 	return ListContainersResponse{DockerIDs: containerIDs, Error: nil}
 }
 
+// WritableLayerSize returns the size of the writable layer of a container.
+func (dg *dockerGoClient) WritableLayerSize(dockerID string, timeout time.Duration) (int64, error) {
+	type sizeResponse struct {
+		size int64
+		err  error
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+	defer cancel()
+
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan sizeResponse, 1)
+	go func() {
+		size, err := dg.writableLayerSize(dockerID, ctx)
+		response <- sizeResponse{size, err}
+	}()
+
+	select {
+	case resp := <-response:
+		return resp.size, resp.err
+	case <-ctx.Done():
+		err := ctx.Err()
+		if err == context.DeadlineExceeded {
+			return 0, &DockerTimeoutError{timeout, "sizing"}
+		}
+		return 0, &CannotInspectContainerError{err}
+	}
+}
+
+func (dg *dockerGoClient) writableLayerSize(dockerID string, ctx context.Context) (int64, error) {
+	client, err := dg.dockerClient()
+	if err != nil {
+		return 0, err
+	}
+
+	// Sizes are only reported by the list API
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Size:    true,
+		Filters: map[string][]string{"id": {dockerID}},
+		Context: ctx,
+	})
+	if err != nil {
+		return 0, &CannotInspectContainerError{err}
+	}
+	for _, container := range containers {
+		if container.ID == dockerID {
+			return container.SizeRw, nil
+		}
+	}
+	return 0, &CannotInspectContainerError{&docker.NoSuchContainer{ID: dockerID}}
+}
+
 func (dg *dockerGoClient) SupportedVersions() []dockerclient.DockerVersion {
 	return dg.clientFactory.FindSupportedAPIVersions()
 }
@@ -912,11 +1146,27 @@ func (dg *dockerGoClient) Version() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	info, err := client.Version()
-	if err != nil {
-		return "", err
+	type versionResponse struct {
+		info *docker.Env
+		err  error
+	}
+	// Buffered channel so in the case of timeout it takes one write, never gets
+	// read, and can still be GC'd
+	response := make(chan versionResponse, 1)
+	go func() {
+		info, err := client.Version()
+		response <- versionResponse{info, err}
+	}()
+
+	select {
+	case resp := <-response:
+		if resp.err != nil {
+			return "", resp.err
+		}
+		return resp.info.Get("Version"), nil
+	case <-dg.time().After(versionTimeout):
+		return "", &DockerTimeoutError{versionTimeout, "getting version"}
 	}
-	return info.Get("Version"), nil
 }
 
 // Stats returns a channel of *docker.Stats entries for the container.
@@ -996,3 +1246,65 @@ func (dg *dockerGoClient) loadImage(opts docker.LoadImageOptions) error {
 	}
 	return client.LoadImage(opts)
 }
+
+// InspectNetwork returns information about a docker network, with a specified
+// timeout
+func (dg *dockerGoClient) InspectNetwork(name string, timeout time.Duration) (*docker.Network, error) {
+	type networkResponse struct {
+		network *docker.Network
+		err     error
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	response := make(chan networkResponse, 1)
+	go func() {
+		network, err := dg.inspectNetwork(name)
+		response <- networkResponse{network, err}
+	}()
+	select {
+	case resp := <-response:
+		return resp.network, resp.err
+	case <-ctx.Done():
+		return nil, &DockerTimeoutError{timeout, "inspecting network"}
+	}
+}
+
+func (dg *dockerGoClient) inspectNetwork(name string) (*docker.Network, error) {
+	client, err := dg.dockerClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.NetworkInfo(name)
+}
+
+// CreateNetwork creates a docker network with the default driver, with a
+// specified timeout
+func (dg *dockerGoClient) CreateNetwork(name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	response := make(chan error, 1)
+	go func() {
+		response <- dg.createNetwork(docker.CreateNetworkOptions{
+			Name:           name,
+			CheckDuplicate: true,
+			Context:        ctx,
+		})
+	}()
+	select {
+	case resp := <-response:
+		return resp
+	case <-ctx.Done():
+		return &DockerTimeoutError{timeout, "creating network"}
+	}
+}
+
+func (dg *dockerGoClient) createNetwork(opts docker.CreateNetworkOptions) error {
+	client, err := dg.dockerClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.CreateNetwork(opts)
+	return err
+}
@@ -31,10 +31,13 @@ import (
 	"golang.org/x/net/context"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/async"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/aws/amazon-ecs-agent/agent/ecr"
 	"github.com/aws/amazon-ecs-agent/agent/ecr/mocks"
 	ecrapi "github.com/aws/amazon-ecs-agent/agent/ecr/model/ecr"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerauth"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface/mocks"
@@ -102,7 +105,7 @@ func TestPullImageOutputTimeout(t *testing.T) {
 		// Don't return, verify timeout happens
 	}).Times(maximumPullRetries) // expected number of retries
 
-	metadata := client.PullImage("image", nil)
+	metadata := client.PullImage("image", nil, "")
 	if metadata.Error == nil {
 		t.Error("Expected error for pull timeout")
 	}
@@ -136,7 +139,7 @@ func TestPullImageGlobalTimeout(t *testing.T) {
 		// Don't return, verify timeout happens
 	})
 
-	metadata := client.PullImage("image", nil)
+	metadata := client.PullImage("image", nil, "")
 	if metadata.Error == nil {
 		t.Error("Expected error for pull timeout")
 	}
@@ -147,7 +150,7 @@ func TestPullImageGlobalTimeout(t *testing.T) {
 	testTime.EXPECT().After(dockerPullBeginTimeout)
 	testTime.EXPECT().After(pullImageTimeout)
 	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image2:latest"}, gomock.Any())
-	_ = client.PullImage("image2", nil)
+	_ = client.PullImage("image2", nil, "")
 
 	// cleanup
 	wait.Done()
@@ -158,12 +161,104 @@ func TestPullImage(t *testing.T) {
 	defer done()
 
 	testTime.EXPECT().After(gomock.Any()).AnyTimes()
-	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:latest"}, gomock.Any()).Return(nil)
+	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:latest"}, docker.AuthConfiguration{}).Return(nil)
 
-	metadata := client.PullImage("image", nil)
+	metadata := client.PullImage("image", nil, "")
 	assert.NoError(t, metadata.Error, "Expected pull to succeed")
 }
 
+func TestPullImagePlatform(t *testing.T) {
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	mockDocker.EXPECT().PullImage(gomock.Any(), docker.AuthConfiguration{}).Do(func(opts docker.PullImageOptions, auth docker.AuthConfiguration) {
+		assert.Equal(t, "image:latest", opts.Repository)
+		assert.Equal(t, "linux/arm64", opts.Platform)
+	}).Return(nil)
+
+	metadata := client.PullImage("image", nil, "linux/arm64")
+	assert.NoError(t, metadata.Error, "Expected pull to succeed")
+}
+
+func TestPullImageSource(t *testing.T) {
+	testCases := []struct {
+		name           string
+		status         string
+		expectedSource api.ImagePullSource
+	}{
+		{
+			name: "cached image",
+			status: "latest: Pulling from library/image\n" +
+				"Digest: sha256:0123456789abcdef\n" +
+				"Status: Image is up to date for image:latest\n",
+			expectedSource: api.ImagePullSourceCacheHit,
+		},
+		{
+			name: "some layers cached",
+			status: "latest: Pulling from library/image\n" +
+				"a1b2c3d4: Already exists\n" +
+				"e5f6a7b8: Pulling fs layer\n" +
+				"e5f6a7b8: Downloading [=====>        ] 1MB/2MB\n" +
+				"e5f6a7b8: Pull complete\n" +
+				"Status: Downloaded newer image for image:latest\n",
+			expectedSource: api.ImagePullSourcePartial,
+		},
+		{
+			name: "fully pulled image",
+			status: "latest: Pulling from library/image\n" +
+				"a1b2c3d4: Pulling fs layer\n" +
+				"e5f6a7b8: Pulling fs layer\n" +
+				"a1b2c3d4: Pull complete\n" +
+				"e5f6a7b8: Pull complete\n" +
+				"Status: Downloaded newer image for image:latest\n",
+			expectedSource: api.ImagePullSourceRegistry,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDocker, client, testTime, done := dockerClientSetup(t)
+			defer done()
+
+			testTime.EXPECT().After(gomock.Any()).AnyTimes()
+			mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:latest"}, docker.AuthConfiguration{}).Do(
+				func(opts docker.PullImageOptions, auth docker.AuthConfiguration) {
+					io.WriteString(opts.OutputStream, tc.status)
+				}).Return(nil)
+
+			metadata := client.PullImage("image", nil, "")
+			assert.NoError(t, metadata.Error)
+			assert.Equal(t, tc.expectedSource, metadata.ImagePullSource)
+		})
+	}
+}
+
+func TestPullImageEngineAuthData(t *testing.T) {
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+	client.auth = dockerauth.NewDockerAuthProvider("docker", []byte(`{
+		"registry.tld":{"username":"user","password":"swordfish"},
+		"registry.tld/team":{"username":"team","password":"dragon"}
+	}`))
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	gomock.InOrder(
+		mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"registry.tld/team/private:latest"},
+			docker.AuthConfiguration{Username: "team", Password: "dragon"}).Return(nil),
+		mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"registry.tld/private:latest"},
+			docker.AuthConfiguration{Username: "user", Password: "swordfish"}).Return(nil),
+		// Images of registries without auth data are pulled anonymously
+		mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"public.tld/image:latest"},
+			docker.AuthConfiguration{}).Return(nil),
+	)
+
+	for _, image := range []string{"registry.tld/team/private", "registry.tld/private", "public.tld/image"} {
+		metadata := client.PullImage(image, nil, "")
+		assert.NoError(t, metadata.Error, "Expected pull of %s to succeed", image)
+	}
+}
+
 func TestPullImageTag(t *testing.T) {
 	mockDocker, client, testTime, done := dockerClientSetup(t)
 	defer done()
@@ -171,7 +266,7 @@ func TestPullImageTag(t *testing.T) {
 	testTime.EXPECT().After(gomock.Any()).AnyTimes()
 	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"image:mytag"}, gomock.Any()).Return(nil)
 
-	metadata := client.PullImage("image:mytag", nil)
+	metadata := client.PullImage("image:mytag", nil, "")
 	assert.NoError(t, metadata.Error, "Expected pull to succeed")
 }
 
@@ -185,10 +280,64 @@ func TestPullImageDigest(t *testing.T) {
 		gomock.Any(),
 	).Return(nil)
 
-	metadata := client.PullImage("image@sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb", nil)
+	metadata := client.PullImage("image@sha256:bc8813ea7b3603864987522f02a76101c17ad122e1c46d790efc0fca78ca7bfb", nil, "")
 	assert.NoError(t, metadata.Error, "Expected pull to succeed")
 }
 
+func TestPullImageAuthRequiredWithoutCredentials(t *testing.T) {
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+	// The pull is not retried, as it is bound to fail again
+	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{"registry.tld/private:latest"}, docker.AuthConfiguration{}).Return(
+		&docker.Error{Status: 401, Message: "unauthorized: authentication required"})
+
+	metadata := client.PullImage("registry.tld/private", nil, "")
+	require.Error(t, metadata.Error)
+	assert.IsType(t, CannotPullContainerAuthError{}, metadata.Error)
+	assert.Equal(t, "CannotPullContainerAuthError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), "authentication required but no credentials configured")
+}
+
+func TestWrapPullError(t *testing.T) {
+	credentials := docker.AuthConfiguration{Username: "user", Password: "swordfish"}
+	testCases := []struct {
+		name          string
+		err           error
+		authConfig    docker.AuthConfiguration
+		expectedError engineError
+	}{
+		{
+			name:          "401 without credentials",
+			err:           &docker.Error{Status: 401, Message: "unauthorized"},
+			expectedError: CannotPullContainerAuthError{&docker.Error{Status: 401, Message: "unauthorized"}},
+		},
+		{
+			name:          "login required without credentials",
+			err:           errors.New("pull access denied for private, repository does not exist or may require 'docker login'"),
+			expectedError: CannotPullContainerAuthError{errors.New("pull access denied for private, repository does not exist or may require 'docker login'")},
+		},
+		{
+			name:          "401 with credentials",
+			err:           &docker.Error{Status: 401, Message: "unauthorized"},
+			authConfig:    credentials,
+			expectedError: CannotPullContainerError{&docker.Error{Status: 401, Message: "unauthorized"}},
+		},
+		{
+			name:          "other error without credentials",
+			err:           errors.New("manifest for image:latest not found"),
+			expectedError: CannotPullContainerError{errors.New("manifest for image:latest not found")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedError, wrapPullError(tc.err, tc.authConfig))
+		})
+	}
+}
+
 func TestPullImageECRSuccess(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -239,10 +388,60 @@ func TestPullImageECRSuccess(t *testing.T) {
 		dockerAuthConfiguration,
 	).Return(nil)
 
-	metadata := client.PullImage(image, authData)
+	metadata := client.PullImage(image, authData, "")
 	assert.NoError(t, metadata.Error, "Expected pull to succeed")
 }
 
+// TestPullImageECRInstanceCredentials tests that images in ECR registries
+// are pulled with a token fetched with the credentials of the instance, which
+// is reused by later pulls until it is about to expire
+func TestPullImageECRInstanceCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+	ecrClientFactory := mock_ecr.NewMockECRFactory(ctrl)
+	client.ecrClientFactory = ecrClientFactory
+	ecrSDK := mock_ecr.NewMockECRSDK(ctrl)
+	ecrClient := ecr.NewECRClient(ecrSDK, async.NewLRUCache(10, time.Hour))
+
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
+
+	registry := "123456789012.dkr.ecr.us-west-2.amazonaws.com"
+	image := registry + "/myimage:tag"
+	dockerAuthConfiguration := docker.AuthConfiguration{
+		Username:      "AWS",
+		Password:      "password",
+		ServerAddress: "https://" + registry,
+	}
+	authorizationData := func(expiresIn time.Duration) *ecrapi.GetAuthorizationTokenOutput {
+		return &ecrapi.GetAuthorizationTokenOutput{
+			AuthorizationData: []*ecrapi.AuthorizationData{{
+				ProxyEndpoint:      aws.String("https://" + registry),
+				AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:password"))),
+				ExpiresAt:          aws.Time(time.Now().Add(expiresIn)),
+			}},
+		}
+	}
+
+	ecrClientFactory.EXPECT().GetClient("us-west-2", "").Return(ecrClient).Times(3)
+	gomock.InOrder(
+		// The token of the first pull expires within minutes, so it is
+		// refreshed by the second pull
+		ecrSDK.EXPECT().GetAuthorizationToken(&ecrapi.GetAuthorizationTokenInput{
+			RegistryIds: []*string{aws.String("123456789012")},
+		}).Return(authorizationData(time.Minute), nil),
+		// The refreshed token is reused by the third pull without calling ECR
+		ecrSDK.EXPECT().GetAuthorizationToken(gomock.Any()).Return(authorizationData(12*time.Hour), nil),
+	)
+	mockDocker.EXPECT().PullImage(&pullImageOptsMatcher{image}, dockerAuthConfiguration).Return(nil).Times(3)
+
+	for i := 0; i < 3; i++ {
+		metadata := client.PullImage(image, nil, "")
+		assert.NoError(t, metadata.Error, "Expected pull to succeed")
+	}
+}
+
 func TestPullImageECRAuthFail(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -278,7 +477,7 @@ func TestPullImageECRAuthFail(t *testing.T) {
 	// no retries for this error
 	ecrClient.EXPECT().GetAuthorizationToken(gomock.Any()).Return(nil, errors.New("test error"))
 
-	metadata := client.PullImage(image, authData)
+	metadata := client.PullImage(image, authData, "")
 	assert.Error(t, metadata.Error, "expected pull to fail")
 }
 
@@ -427,6 +626,23 @@ func TestStartContainer(t *testing.T) {
 	}
 }
 
+func TestStartContainerReportsDockerStartTime(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	startedAt := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	gomock.InOrder(
+		mockDocker.EXPECT().StartContainerWithContext("id", nil, gomock.Any()).Return(nil),
+		mockDocker.EXPECT().InspectContainerWithContext("id", gomock.Any()).Return(&docker.Container{
+			ID:    "id",
+			State: docker.State{Running: true, StartedAt: startedAt},
+		}, nil),
+	)
+	metadata := client.StartContainer("id", startContainerTimeout)
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, startedAt, metadata.StartedAt)
+}
+
 func TestStopContainerTimeout(t *testing.T) {
 	cfg := config.DefaultConfig()
 	cfg.DockerStopTimeout = xContainerShortTimeout
@@ -451,6 +667,19 @@ func TestStopContainerTimeout(t *testing.T) {
 	wait.Done()
 }
 
+func TestStopContainerWithGracePeriod(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	gomock.InOrder(
+		mockDocker.EXPECT().StopContainerWithContext("id", uint(5), gomock.Any()).Return(nil),
+		mockDocker.EXPECT().InspectContainerWithContext("id", gomock.Any()).Return(&docker.Container{ID: "id", State: docker.State{ExitCode: 137}}, nil),
+	)
+	metadata := client.StopContainerWithGracePeriod("id", 5*time.Second, stopContainerTimeout)
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, "id", metadata.DockerID)
+}
+
 func TestStopContainer(t *testing.T) {
 	mockDocker, client, _, done := dockerClientSetup(t)
 	defer done()
@@ -468,6 +697,33 @@ func TestStopContainer(t *testing.T) {
 	}
 }
 
+func TestDescribeContainerPaused(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDocker.EXPECT().InspectContainerWithContext("id", gomock.Any()).Return(&docker.Container{
+		ID:    "id",
+		State: docker.State{Running: true, Paused: true, StartedAt: time.Now()},
+	}, nil)
+	status, metadata := client.DescribeContainer("id")
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, api.ContainerRunning, status)
+	assert.True(t, metadata.Paused)
+}
+
+func TestUnpauseContainer(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDocker.EXPECT().UnpauseContainer("id").Return(nil)
+	assert.NoError(t, client.UnpauseContainer("id", unpauseContainerTimeout))
+
+	mockDocker.EXPECT().UnpauseContainer("id").Return(errors.New("test error"))
+	err := client.UnpauseContainer("id", unpauseContainerTimeout)
+	require.Error(t, err)
+	assert.Equal(t, "CannotUnpauseContainerError", err.(engineError).ErrorName())
+}
+
 func TestInspectContainerTimeout(t *testing.T) {
 	mockDocker, client, _, done := dockerClientSetup(t)
 	defer done()
@@ -646,10 +902,37 @@ func TestContainerEvents(t *testing.T) {
 	}
 }
 
-func TestDockerVersion(t *testing.T) {
+func TestContainerEventsHealthStatus(t *testing.T) {
 	mockDocker, client, _, done := dockerClientSetup(t)
 	defer done()
 
+	var events chan<- *docker.APIEvents
+	mockDocker.EXPECT().AddEventListener(gomock.Any()).Do(func(x interface{}) {
+		events = x.(chan<- *docker.APIEvents)
+	})
+
+	dockerEvents, err := client.ContainerEvents(context.TODO())
+	require.NoError(t, err)
+
+	go func() {
+		events <- &docker.APIEvents{Type: "container", ID: "containerId", Status: "health_status: healthy"}
+		events <- &docker.APIEvents{Type: "container", ID: "containerId", Status: "health_status: unhealthy"}
+	}()
+
+	event := <-dockerEvents
+	assert.Equal(t, "containerId", event.DockerID)
+	assert.Equal(t, api.ContainerRunning, event.Status)
+	assert.Equal(t, api.ContainerHealthy, event.Health)
+
+	event = <-dockerEvents
+	assert.Equal(t, api.ContainerUnhealthy, event.Health)
+}
+
+func TestDockerVersion(t *testing.T) {
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+
+	testTime.EXPECT().After(versionTimeout)
 	mockDocker.EXPECT().Version().Return(&docker.Env{"Version=1.6.0"}, nil)
 
 	str, err := client.Version()
@@ -661,6 +944,27 @@ func TestDockerVersion(t *testing.T) {
 	}
 }
 
+func TestDockerVersionTimeout(t *testing.T) {
+	mockDocker, client, testTime, done := dockerClientSetup(t)
+	defer done()
+
+	versionStarted := make(chan struct{})
+	versionDone := make(chan struct{})
+	defer close(versionDone)
+	timeout := make(chan time.Time, 1)
+	timeout <- time.Now()
+	testTime.EXPECT().After(versionTimeout).Return(timeout)
+	mockDocker.EXPECT().Version().Do(func() {
+		close(versionStarted)
+		<-versionDone
+	}).Return(nil, errors.New("daemon never responded"))
+
+	_, err := client.Version()
+	require.Error(t, err)
+	assert.Equal(t, "DockerTimeoutError", err.(api.NamedError).ErrorName())
+	<-versionStarted
+}
+
 func TestListContainers(t *testing.T) {
 	mockDocker, client, _, done := dockerClientSetup(t)
 	defer done()
@@ -682,6 +986,23 @@ func TestListContainers(t *testing.T) {
 	}
 }
 
+func TestWritableLayerSize(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDocker.EXPECT().ListContainers(gomock.Any()).Do(func(opts docker.ListContainersOptions) {
+		assert.True(t, opts.Size, "Expected sizes to be requested")
+		assert.Equal(t, []string{"id"}, opts.Filters["id"])
+	}).Return([]docker.APIContainers{{ID: "id", SizeRw: 42}}, nil)
+	size, err := client.WritableLayerSize("id", WritableLayerSizeTimeout)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), size)
+
+	mockDocker.EXPECT().ListContainers(gomock.Any()).Return(nil, nil)
+	_, err = client.WritableLayerSize("id", WritableLayerSizeTimeout)
+	assert.Error(t, err, "Expected an error for a missing container")
+}
+
 func TestListContainersTimeout(t *testing.T) {
 	mockDocker, client, _, done := dockerClientSetup(t)
 	defer done()
@@ -915,6 +1236,102 @@ func TestRemoveImage(t *testing.T) {
 	}
 }
 
+func TestInspectNetwork(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDocker.EXPECT().NetworkInfo("mynet").Return(&docker.Network{Name: "mynet"}, nil)
+	network, err := client.InspectNetwork("mynet", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "mynet", network.Name)
+
+	mockDocker.EXPECT().NetworkInfo("missing").Return(nil, &docker.NoSuchNetwork{ID: "missing"})
+	_, err = client.InspectNetwork("missing", time.Second)
+	assert.IsType(t, &docker.NoSuchNetwork{}, err)
+}
+
+func TestCreateNetwork(t *testing.T) {
+	mockDocker, client, _, done := dockerClientSetup(t)
+	defer done()
+
+	mockDocker.EXPECT().CreateNetwork(gomock.Any()).Do(func(opts docker.CreateNetworkOptions) {
+		assert.Equal(t, "mynet", opts.Name)
+		assert.True(t, opts.CheckDuplicate)
+	}).Return(&docker.Network{Name: "mynet"}, nil)
+	err := client.CreateNetwork("mynet", time.Second)
+	assert.NoError(t, err)
+}
+
+func TestCreateNetworkTimeout(t *testing.T) {
+	mockDocker, client, _, _ := dockerClientSetup(t)
+	wait := sync.WaitGroup{}
+	wait.Add(1)
+	mockDocker.EXPECT().CreateNetwork(gomock.Any()).Do(func(x interface{}) {
+		wait.Wait()
+	})
+	err := client.CreateNetwork("mynet", 2*time.Millisecond)
+	assert.Error(t, err, "Expected error for create network timeout")
+	wait.Done()
+}
+
+func TestMetadataFromContainerIPv4Address(t *testing.T) {
+	metadata := metadataFromContainer(&docker.Container{
+		ID:              "id",
+		NetworkSettings: &docker.NetworkSettings{IPAddress: "172.17.0.2"},
+	})
+	assert.Equal(t, "172.17.0.2", metadata.IPv4Address)
+
+	metadata = metadataFromContainer(&docker.Container{
+		ID: "id",
+		NetworkSettings: &docker.NetworkSettings{
+			Networks: map[string]docker.ContainerNetwork{"mynet": {IPAddress: "172.18.0.2"}},
+		},
+	})
+	assert.Equal(t, "172.18.0.2", metadata.IPv4Address, "address on the only user-defined network")
+
+	metadata = metadataFromContainer(&docker.Container{ID: "id"})
+	assert.Empty(t, metadata.IPv4Address)
+}
+
+func TestMetadataFromContainerHealthStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		expected api.ContainerHealthStatus
+	}{
+		{"healthy", api.ContainerHealthy},
+		{"unhealthy", api.ContainerUnhealthy},
+		{"starting", api.ContainerHealthUnknown},
+		{"", api.ContainerHealthUnknown},
+	} {
+		metadata := metadataFromContainer(&docker.Container{
+			ID:    "id",
+			State: docker.State{Running: true, Health: docker.Health{Status: tc.status}},
+		})
+		assert.Equal(t, tc.expected, metadata.HealthStatus, "health for status %q", tc.status)
+	}
+}
+
+func TestMetadataFromContainerReason(t *testing.T) {
+	metadata := metadataFromContainer(&docker.Container{
+		ID:    "id",
+		State: docker.State{OOMKilled: true, ExitCode: 137, FinishedAt: time.Now()},
+	})
+	assert.Equal(t, "OutOfMemoryError: Container killed due to memory usage", metadata.Reason)
+	assert.IsType(t, OutOfMemoryError{}, metadata.Error)
+
+	metadata = metadataFromContainer(&docker.Container{
+		ID:    "id",
+		State: docker.State{Error: "oci runtime error: executable file not found"},
+	})
+	assert.Equal(t, "DockerStateError: oci runtime error: executable file not found", metadata.Reason)
+
+	metadata = metadataFromContainer(&docker.Container{
+		ID:    "id",
+		State: docker.State{ExitCode: 0, FinishedAt: time.Now()},
+	})
+	assert.Empty(t, metadata.Reason)
+}
+
 func TestContainerMetadataWorkaroundIssue27601(t *testing.T) {
 	mockDocker, client, _, _ := dockerClientSetup(t)
 	mockDocker.EXPECT().InspectContainerWithContext("id", gomock.Any()).Return(&docker.Container{
@@ -15,6 +15,7 @@ package engine
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
@@ -40,6 +41,7 @@ type ImageManager interface {
 	AddAllImageStates(imageStates []*image.ImageState)
 	GetImageStateFromImageName(containerImageName string) *image.ImageState
 	StartImageCleanupProcess(ctx context.Context)
+	RemoveUnusedImages()
 	SetSaver(stateManager statemanager.Saver)
 }
 
@@ -56,6 +58,9 @@ type dockerImageManager struct {
 	minimumAgeBeforeDeletion         time.Duration
 	numImagesToDelete                int
 	imageCleanupTimeInterval         time.Duration
+	// pendingCreateImages holds the references of containers being created
+	// to their images, which are never candidates for deletion
+	pendingCreateImages *imageReferences
 }
 
 // ImageStatesForDeletion is used for implementing the sort interface
@@ -69,6 +74,7 @@ func NewImageManager(cfg *config.Config, client DockerClient, state dockerstate.
 		minimumAgeBeforeDeletion: cfg.MinimumImageDeletionAge,
 		numImagesToDelete:        cfg.NumImagesToDeletePerCycle,
 		imageCleanupTimeInterval: cfg.ImageCleanupInterval,
+		pendingCreateImages:      newImageReferences(),
 	}
 }
 
@@ -209,7 +215,7 @@ func (imageManager *dockerImageManager) removeImageState(imageStateToBeRemoved *
 	}
 }
 
-func (imageManager *dockerImageManager) getCandidateImagesForDeletion() []*image.ImageState {
+func (imageManager *dockerImageManager) getCandidateImagesForDeletion(minimumAgeBeforeDeletion time.Duration) []*image.ImageState {
 	if len(imageManager.imageStatesConsideredForDeletion) < 1 {
 		seelog.Debugf("Image Manager: Empty state!")
 		// no image states present in image manager
@@ -217,7 +223,11 @@ func (imageManager *dockerImageManager) getCandidateImagesForDeletion() []*image
 	}
 	var imagesForDeletion []*image.ImageState
 	for _, imageState := range imageManager.imageStatesConsideredForDeletion {
-		if imageManager.isImageOldEnough(imageState) && imageState.HasNoAssociatedContainers() {
+		if imageManager.pendingCreateImages.isReferenced(imageState.Image.Names) {
+			seelog.Infof("Image is referenced by a container being created, skipping deletion: [%s]", imageState.String())
+			continue
+		}
+		if isImageOldEnough(imageState, minimumAgeBeforeDeletion) && imageState.HasNoAssociatedContainers() {
 			seelog.Infof("Candidate image for deletion: [%s]", imageState.String())
 			imagesForDeletion = append(imagesForDeletion, imageState)
 		}
@@ -225,9 +235,9 @@ func (imageManager *dockerImageManager) getCandidateImagesForDeletion() []*image
 	return imagesForDeletion
 }
 
-func (imageManager *dockerImageManager) isImageOldEnough(imageState *image.ImageState) bool {
+func isImageOldEnough(imageState *image.ImageState, minimumAgeBeforeDeletion time.Duration) bool {
 	ageOfImage := time.Now().Sub(imageState.PulledAt)
-	return ageOfImage > imageManager.minimumAgeBeforeDeletion
+	return ageOfImage > minimumAgeBeforeDeletion
 }
 
 // Implementing sort interface based on last used times of the images
@@ -281,7 +291,20 @@ func (imageManager *dockerImageManager) performPeriodicImageCleanup(ctx context.
 	}
 }
 
+// RemoveUnusedImages removes all the unused images right away, outside of the
+// periodic image cleanup, to free up disk space. Unlike the periodic cleanup,
+// it removes images regardless of their age and of the number of images
+// deleted per cleanup cycle. It waits for the images being pulled to finish
+// pulling
+func (imageManager *dockerImageManager) RemoveUnusedImages() {
+	imageManager.removeImages(math.MaxInt32, 0)
+}
+
 func (imageManager *dockerImageManager) removeUnusedImages() {
+	imageManager.removeImages(imageManager.numImagesToDelete, imageManager.minimumAgeBeforeDeletion)
+}
+
+func (imageManager *dockerImageManager) removeImages(numImagesToDelete int, minimumAgeBeforeDeletion time.Duration) {
 	seelog.Debug("Attempting to obtain ImagePullDeleteLock for removing images")
 	ImagePullDeleteLock.Lock()
 	seelog.Debug("Obtained ImagePullDeleteLock for removing images")
@@ -296,8 +319,8 @@ func (imageManager *dockerImageManager) removeUnusedImages() {
 	for _, imageState := range imageManager.getAllImageStates() {
 		imageManager.imageStatesConsideredForDeletion[imageState.Image.ImageID] = imageState
 	}
-	for i := 0; i < imageManager.numImagesToDelete; i++ {
-		err := imageManager.removeLeastRecentlyUsedImage()
+	for i := 0; i < numImagesToDelete; i++ {
+		err := imageManager.removeLeastRecentlyUsedImage(minimumAgeBeforeDeletion)
 		if err != nil {
 			seelog.Infof("End of eligible images for deletion: %v; Still have %d image states being managed", err, len(imageManager.getAllImageStates()))
 			break
@@ -305,8 +328,8 @@ func (imageManager *dockerImageManager) removeUnusedImages() {
 	}
 }
 
-func (imageManager *dockerImageManager) removeLeastRecentlyUsedImage() error {
-	leastRecentlyUsedImage := imageManager.getUnusedImageForDeletion()
+func (imageManager *dockerImageManager) removeLeastRecentlyUsedImage(minimumAgeBeforeDeletion time.Duration) error {
+	leastRecentlyUsedImage := imageManager.getUnusedImageForDeletion(minimumAgeBeforeDeletion)
 	if leastRecentlyUsedImage == nil {
 		return fmt.Errorf("No more eligible images for deletion")
 	}
@@ -314,8 +337,8 @@ func (imageManager *dockerImageManager) removeLeastRecentlyUsedImage() error {
 	return nil
 }
 
-func (imageManager *dockerImageManager) getUnusedImageForDeletion() *image.ImageState {
-	candidateImageStatesForDeletion := imageManager.getCandidateImagesForDeletion()
+func (imageManager *dockerImageManager) getUnusedImageForDeletion(minimumAgeBeforeDeletion time.Duration) *image.ImageState {
+	candidateImageStatesForDeletion := imageManager.getCandidateImagesForDeletion(minimumAgeBeforeDeletion)
 	if len(candidateImageStatesForDeletion) < 1 {
 		seelog.Infof("No eligible images for deletion for this cleanup cycle")
 		return nil
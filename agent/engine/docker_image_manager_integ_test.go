@@ -296,15 +296,15 @@ func TestImageWithSameNameAndDifferentID(t *testing.T) {
 
 	// Pull the images needed for the test
 	if _, err = dockerClient.InspectImage(test3Image1Name); err == docker.ErrNoSuchImage {
-		metadata := dockerClient.PullImage(test3Image1Name, nil)
+		metadata := dockerClient.PullImage(test3Image1Name, nil, "")
 		assert.NoError(t, metadata.Error, "Failed to pull image %s", test3Image1Name)
 	}
 	if _, err = dockerClient.InspectImage(test3Image2Name); err == docker.ErrNoSuchImage {
-		metadata := dockerClient.PullImage(test3Image2Name, nil)
+		metadata := dockerClient.PullImage(test3Image2Name, nil, "")
 		assert.NoError(t, metadata.Error, "Failed to pull image %s", test3Image2Name)
 	}
 	if _, err = dockerClient.InspectImage(test3Image3Name); err == docker.ErrNoSuchImage {
-		metadata := dockerClient.PullImage(test3Image3Name, nil)
+		metadata := dockerClient.PullImage(test3Image3Name, nil, "")
 		assert.NoError(t, metadata.Error, "Failed to pull image %s", test3Image3Name)
 	}
 
@@ -442,7 +442,7 @@ func TestImageWithSameIDAndDifferentNames(t *testing.T) {
 
 	// Pull the images needed for the test
 	if _, err = dockerClient.InspectImage(test4Image1Name); err == docker.ErrNoSuchImage {
-		metadata := dockerClient.PullImage(test4Image1Name, nil)
+		metadata := dockerClient.PullImage(test4Image1Name, nil, "")
 		assert.NoError(t, metadata.Error, "Failed to pull image %s", test4Image1Name)
 	}
 
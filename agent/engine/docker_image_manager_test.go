@@ -433,7 +433,7 @@ func TestGetCandidateImagesForDeletionImageNoImageState(t *testing.T) {
 		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
 	}
 
-	imageStates := imageManager.getCandidateImagesForDeletion()
+	imageStates := imageManager.getCandidateImagesForDeletion(imageManager.minimumAgeBeforeDeletion)
 
 	if imageStates != nil {
 		t.Error("Expected no image state to be returned for deletion")
@@ -459,7 +459,7 @@ func TestGetCandidateImagesForDeletionImageJustPulled(t *testing.T) {
 		PulledAt: time.Now(),
 	}
 	imageManager.addImageState(sourceImageState)
-	imageStates := imageManager.getCandidateImagesForDeletion()
+	imageStates := imageManager.getCandidateImagesForDeletion(imageManager.minimumAgeBeforeDeletion)
 	if len(imageStates) > 0 {
 		t.Error("Expected no image state to be returned for deletion")
 	}
@@ -500,7 +500,7 @@ func TestGetCandidateImagesForDeletionImageHasContainerReference(t *testing.T) {
 	if err != nil {
 		t.Error("Error in adding container to an existing image state")
 	}
-	imageStates := imageManager.getCandidateImagesForDeletion()
+	imageStates := imageManager.getCandidateImagesForDeletion(imageManager.minimumAgeBeforeDeletion)
 	if len(imageStates) > 0 {
 		t.Error("Expected no image state to be returned for deletion")
 	}
@@ -555,7 +555,7 @@ func TestGetCandidateImagesForDeletionImageHasMoreContainerReferences(t *testing
 	if err != nil {
 		t.Error("Error removing container reference from image state")
 	}
-	imageStates := imageManager.getCandidateImagesForDeletion()
+	imageStates := imageManager.getCandidateImagesForDeletion(imageManager.minimumAgeBeforeDeletion)
 	if len(imageStates) > 0 {
 		t.Error("Expected no image state to be returned for deletion")
 	}
@@ -736,6 +736,80 @@ func TestImageCleanupHappyPath(t *testing.T) {
 	}
 }
 
+func TestImageCleanupSkipsImagePendingCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := NewMockDockerClient(ctrl)
+
+	imageManager := &dockerImageManager{
+		client: client,
+		state:  dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion: 1 * time.Millisecond,
+		numImagesToDelete:        config.DefaultNumImagesToDeletePerCycle,
+		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
+		pendingCreateImages:      newImageReferences(),
+	}
+	imageManager.SetSaver(statemanager.NewNoopStateManager())
+
+	sourceImage := &image.Image{
+		ImageID: "sha256:qwerty",
+		Names:   []string{"testContainerImage"},
+	}
+	imageState := &image.ImageState{
+		Image:      sourceImage,
+		PulledAt:   time.Now().AddDate(0, -2, 0),
+		LastUsedAt: time.Now().AddDate(0, -2, 0),
+	}
+	imageManager.addImageState(imageState)
+
+	// The image is referenced by a container being created and must not be
+	// removed, no RemoveImage call is expected
+	container := &api.Container{Name: "testContainer", Image: "testContainerImage"}
+	imageManager.pendingCreateImages.acquire(container)
+	imageManager.removeUnusedImages()
+	assert.Equal(t, 1, imageManager.GetImageStatesCount())
+
+	// Once the create is done, the image can be removed
+	imageManager.pendingCreateImages.release(container)
+	client.EXPECT().RemoveImage("testContainerImage", removeImageTimeout).Return(nil)
+	imageManager.removeUnusedImages()
+	assert.Equal(t, 0, imageManager.GetImageStatesCount())
+}
+
+func TestRemoveUnusedImagesIgnoresCleanupPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := NewMockDockerClient(ctrl)
+
+	imageManager := &dockerImageManager{
+		client: client,
+		state:  dockerstate.NewTaskEngineState(),
+		minimumAgeBeforeDeletion: config.DefaultImageDeletionAge,
+		numImagesToDelete:        1,
+		imageCleanupTimeInterval: config.DefaultImageCleanupTimeInterval,
+	}
+	imageManager.SetSaver(statemanager.NewNoopStateManager())
+
+	for _, name := range []string{"image1", "image2"} {
+		imageManager.addImageState(&image.ImageState{
+			Image:      &image.Image{ImageID: "sha256:" + name, Names: []string{name}},
+			PulledAt:   time.Now(),
+			LastUsedAt: time.Now(),
+		})
+	}
+
+	// The images were just pulled, the periodic cleanup must not remove them
+	imageManager.removeUnusedImages()
+	assert.Equal(t, 2, imageManager.GetImageStatesCount())
+
+	// Freeing up disk space removes all of them, regardless of the minimum
+	// age and of the number of images to delete per cycle
+	client.EXPECT().RemoveImage("image1", removeImageTimeout).Return(nil)
+	client.EXPECT().RemoveImage("image2", removeImageTimeout).Return(nil)
+	imageManager.RemoveUnusedImages()
+	assert.Equal(t, 0, imageManager.GetImageStatesCount())
+}
+
 func TestImageCleanupCannotRemoveImage(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -936,7 +1010,7 @@ func TestRemoveLeastRecentlyUsedImageNoImage(t *testing.T) {
 	client := NewMockDockerClient(ctrl)
 	imageManager := &dockerImageManager{client: client, state: dockerstate.NewTaskEngineState()}
 	imageManager.SetSaver(statemanager.NewNoopStateManager())
-	err := imageManager.removeLeastRecentlyUsedImage()
+	err := imageManager.removeLeastRecentlyUsedImage(imageManager.minimumAgeBeforeDeletion)
 	if err == nil {
 		t.Error("Expected Error for no LRU image to remove")
 	}
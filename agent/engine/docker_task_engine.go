@@ -15,25 +15,37 @@
 package engine
 
 import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/awslogs"
+	"github.com/aws/amazon-ecs-agent/agent/cgroup"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/emptyvolume"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
+	"github.com/aws/amazon-ecs-agent/agent/secrets"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	utilsync "github.com/aws/amazon-ecs-agent/agent/utils/sync"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/cihub/seelog"
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
@@ -44,6 +56,35 @@ const (
 	// DockerDefaultEndpoint is the default value for the Docker endpoint
 	DockerDefaultEndpoint = "unix:///var/run/docker.sock"
 	labelPrefix           = "com.amazonaws.ecs."
+
+	// eventBacklogQuietPeriod is how long the engine waits for another Docker
+	// event before it considers the backlog of events on startup drained
+	eventBacklogQuietPeriod = 100 * time.Millisecond
+	// eventBacklogMaxEvents is the maximum number of Docker events drained
+	// from the backlog on startup
+	eventBacklogMaxEvents = 1000
+	// bridgeNetworkMode is the docker network mode used when the task does
+	// not specify one
+	bridgeNetworkMode = "bridge"
+	// containerStopDetectedByPollReason is added to the reason of container
+	// stops that were found by the steady state poll of the task instead of
+	// being reported by a docker event
+	containerStopDetectedByPollReason = "Container stop detected by steady state poll, no docker event was received"
+	// killedExitCode is the exit code of containers killed with SIGKILL,
+	// which docker sends when a container does not exit within the grace
+	// period of a stop
+	killedExitCode = 128 + 9
+	// ipcModeTaskMinimumVersion is the docker API version of docker 17.06,
+	// the first version of docker that lets other containers join the IPC
+	// namespace of a container
+	ipcModeTaskMinimumVersion = dockerclient.Version_1_30
+
+	// retry settings for starting containers
+	startContainerMaxAttempts           = 3
+	startContainerRetryMinDelay         = 1 * time.Second
+	startContainerRetryMaxDelay         = 5 * time.Second
+	startContainerRetryDelayMultiplier  = 2
+	startContainerRetryJitterMultiplier = 0.2
 )
 
 // DockerTaskEngine is a state machine for managing a task and its containers
@@ -86,6 +127,15 @@ type DockerTaskEngine struct {
 	// all tasks, it must not aquire it for any significant duration
 	// The write mutex should be taken when adding and removing tasks from managedTasks.
 	processTasks sync.RWMutex
+	// draining is set once the engine starts draining, after which new tasks
+	// are stopped as soon as they are added. It is guarded by processTasks
+	draining bool
+	// paused is set while the engine is paused, during which new tasks are
+	// held in heldTasks, by arn, instead of being started. The tasks that
+	// were started before keep running and being verified. Both are guarded
+	// by processTasks
+	paused    bool
+	heldTasks map[string]*api.Task
 
 	enableConcurrentPull                bool
 	credentialsManager                  credentials.Manager
@@ -93,6 +143,53 @@ type DockerTaskEngine struct {
 	_timeOnce                           sync.Once
 	imageManager                        ImageManager
 	containerStatusToTransitionFunction map[api.ContainerStatus]transitionApplyFunc
+	// pendingCreateImages holds the references of containers that are pulled
+	// but not created yet to their images. It is owned by the image manager
+	pendingCreateImages *imageReferences
+
+	// createSemaphore limits the number of containers being created at the
+	// same time, handing out slots by task priority. It is nil if the number
+	// of concurrent creates is not limited
+	createSemaphore *prioritySemaphore
+	// cniSetupSemaphore limits the number of task network namespaces being
+	// set up at the same time. It is nil if the number of concurrent setups
+	// is not limited
+	cniSetupSemaphore *prioritySemaphore
+	// pullSemaphore limits the number of images being pulled at the same
+	// time when pulls are concurrent. It is nil if the number of concurrent
+	// pulls is not limited
+	pullSemaphore *prioritySemaphore
+	// instanceTagLabeler adds the configured instance tags as container
+	// labels. It is nil if no instance tags are configured
+	instanceTagLabeler *instanceTagLabeler
+	// unknownContainers tracks the containers not managed by the engine that
+	// are inspected for adoption. It is nil if unknown containers are not
+	// adopted
+	unknownContainers *unknownContainers
+	// steadyStatePoll adapts the interval at which tasks in steady state are
+	// checked to the latency of Docker. It is nil if the interval is fixed
+	steadyStatePoll *steadyStatePollInterval
+	// secretsResolver resolves the secret log driver options of containers
+	// with the credentials of their task role
+	secretsResolver secrets.Resolver
+	// logGroupCreator creates the log groups of containers that use the
+	// awslogs log driver. It is nil if log group creation is not enabled
+	logGroupCreator awslogs.LogGroupCreator
+	// cgroupControl creates the parent cgroups that enforce the limits of
+	// tasks. It is nil if task limits are not enabled
+	cgroupControl cgroup.Control
+	// taskMetadataFile writes the metadata of tasks into files mounted into
+	// their containers. It is nil if metadata files are not enabled
+	taskMetadataFile *taskMetadataFileWriter
+	// instanceMemory is the memory of the instance in bytes, which the
+	// memory limits of containers cannot exceed. It is 0 if unknown
+	instanceMemory int64
+	// instanceCPU is the CPU of the instance in CPU units, which the CPU
+	// requested by tasks cannot exceed
+	instanceCPU int64
+	// pauseImage tracks the pull of a custom pause image when the engine
+	// starts. It is nil if the pause image is not pulled by the engine
+	pauseImage *pauseImagePull
 }
 
 // NewDockerTaskEngine returns a created, but uninitialized, DockerTaskEngine.
@@ -107,6 +204,7 @@ func NewDockerTaskEngine(cfg *config.Config, client DockerClient, credentialsMan
 
 		state:         state,
 		managedTasks:  make(map[string]*managedTask),
+		heldTasks:     make(map[string]*api.Task),
 		taskStopGroup: utilsync.NewSequentialWaitGroup(),
 
 		stateChangeEvents: make(chan statechange.Event),
@@ -116,10 +214,55 @@ func NewDockerTaskEngine(cfg *config.Config, client DockerClient, credentialsMan
 
 		containerChangeEventStream: containerChangeEventStream,
 		imageManager:               imageManager,
+		pendingCreateImages:        pendingCreateImagesOf(imageManager),
+		secretsResolver:            secrets.NewResolver(cfg.AcceptInsecureCert),
 		cniClient: ecscni.NewClient(&ecscni.Config{
 			PluginsPath:            cfg.CNIPluginsPath,
 			MinSupportedCNIVersion: config.DefaultMinSupportedCNIVersion,
 		}),
+		instanceMemory: readInstanceMemory(),
+		instanceCPU:    instanceCPUUnits(),
+		pauseImage:     newPauseImagePull(cfg),
+	}
+
+	if cfg.AWSLogsGroupCreationEnabled {
+		dockerTaskEngine.logGroupCreator = awslogs.NewLogGroupCreator(cfg.AcceptInsecureCert)
+	}
+	if cfg.TaskCPUMemLimitEnabled {
+		control, err := cgroup.New()
+		if err != nil {
+			seelog.Warnf("Task CPU and memory limits will not be enforced: %v", err)
+		} else {
+			dockerTaskEngine.cgroupControl = control
+		}
+	}
+	if cfg.ContainerCreateConcurrency > 0 {
+		dockerTaskEngine.createSemaphore = newPrioritySemaphore(cfg.ContainerCreateConcurrency)
+	}
+	if cfg.ENISetupConcurrency > 0 {
+		dockerTaskEngine.cniSetupSemaphore = newPrioritySemaphore(cfg.ENISetupConcurrency)
+	}
+	if cfg.ImagePullConcurrency > 0 {
+		dockerTaskEngine.pullSemaphore = newPrioritySemaphore(cfg.ImagePullConcurrency)
+	}
+	if len(cfg.InstanceTagLabels) > 0 {
+		dockerTaskEngine.instanceTagLabeler = newInstanceTagLabeler(ec2.NewEC2MetadataClient(nil), cfg.InstanceTagLabels)
+	}
+	if cfg.UnknownContainerEventPolicy == config.UnknownContainerEventPolicyAdopt {
+		dockerTaskEngine.unknownContainers = newUnknownContainers()
+	}
+	if cfg.SteadyStatePollMaxInterval > 0 {
+		interval := dockerTaskEngine.fixedSteadyStateVerifyInterval()
+		minInterval := cfg.SteadyStatePollMinInterval
+		if minInterval <= 0 || minInterval > interval {
+			minInterval = interval
+		}
+		dockerTaskEngine.steadyStatePoll = newSteadyStatePollInterval(interval, minInterval,
+			cfg.SteadyStatePollMaxInterval, cfg.SteadyStatePollLatencyThreshold)
+	}
+
+	if cfg.TaskMetadataFileEnabled {
+		dockerTaskEngine.taskMetadataFile = newTaskMetadataFileWriter(cfg.DataDir, cfg.DataDirOnHost)
 	}
 
 	dockerTaskEngine.initializeContainerStatusToTransitionFunction()
@@ -174,6 +317,10 @@ func (engine *DockerTaskEngine) Init(ctx context.Context) error {
 	engine.synchronizeState()
 	// Now catch up and start processing new events per normal
 	go engine.handleDockerEvents(derivedCtx)
+	go engine.pullPauseImage()
+	// Prefetch images before the engine is considered initialized, which
+	// keeps the agent from accepting tasks until they are pulled
+	engine.prefetchImages()
 	engine.initialized = true
 	return nil
 }
@@ -226,6 +373,83 @@ func (engine *DockerTaskEngine) Disable() {
 	engine.processTasks.Lock()
 }
 
+// Pause stops the engine from starting new tasks, which are held until the
+// engine is resumed. The tasks that were started before keep being managed,
+// and their steady state keeps being verified
+func (engine *DockerTaskEngine) Pause() {
+	engine.processTasks.Lock()
+	defer engine.processTasks.Unlock()
+	if engine.paused {
+		return
+	}
+	seelog.Info("Pausing the task engine, new tasks are held until it is resumed")
+	engine.paused = true
+}
+
+// Resume starts the tasks that were held while the engine was paused, and the
+// tasks that are added from then on
+func (engine *DockerTaskEngine) Resume() {
+	engine.processTasks.Lock()
+	defer engine.processTasks.Unlock()
+	if !engine.paused {
+		return
+	}
+	seelog.Infof("Resuming the task engine, starting %d held tasks", len(engine.heldTasks))
+	engine.paused = false
+	engine.startHeldTasks()
+}
+
+// Paused returns true if the engine is paused
+func (engine *DockerTaskEngine) Paused() bool {
+	engine.processTasks.RLock()
+	defer engine.processTasks.RUnlock()
+	return engine.paused
+}
+
+// startHeldTasks starts the tasks that were held while the engine was paused.
+// It must be called with processTasks held
+func (engine *DockerTaskEngine) startHeldTasks() {
+	for arn, task := range engine.heldTasks {
+		engine.startTask(task)
+		delete(engine.heldTasks, arn)
+	}
+}
+
+// Drain stops the engine from accepting new tasks and moves the desired status
+// of all of the tasks it manages to STOPPED
+func (engine *DockerTaskEngine) Drain() {
+	// The tasks are stopped without holding processTasks, as their task
+	// managers may need it before they are able to receive
+	for _, managedTask := range engine.startDraining() {
+		seelog.Infof("Stopping task to drain the instance, task: %s", managedTask.Arn)
+		managedTask.acsMessages <- acsTransition{desiredStatus: api.TaskStopped}
+	}
+}
+
+// startDraining marks the engine as draining and returns the managed tasks
+// that are yet to be stopped
+func (engine *DockerTaskEngine) startDraining() []*managedTask {
+	engine.processTasks.Lock()
+	defer engine.processTasks.Unlock()
+	engine.draining = true
+	// Tasks held while the engine is paused are started to be stopped, so
+	// that they reach the cleanup path
+	for arn, task := range engine.heldTasks {
+		seelog.Infof("Stopping held task to drain the instance, task: %s", arn)
+		task.SetDesiredStatus(api.TaskStopped)
+		task.UpdateDesiredStatus()
+	}
+	engine.startHeldTasks()
+	var tasksToStop []*managedTask
+	for _, managedTask := range engine.managedTasks {
+		if managedTask.GetDesiredStatus().Terminal() {
+			continue
+		}
+		tasksToStop = append(tasksToStop, managedTask)
+	}
+	return tasksToStop
+}
+
 // synchronizeState explicitly goes through each docker container stored in
 // "state" and updates its KnownStatus appropriately, as well as queueing up
 // events to push upstream.
@@ -244,6 +468,7 @@ func (engine *DockerTaskEngine) synchronizeState() {
 			engine.startTask(task)
 			continue
 		}
+		var missingEssentialContainer *api.Container
 		for _, cont := range conts {
 			if cont.DockerID == "" {
 				log.Debug("Found container potentially created while we were down", "name", cont.DockerName)
@@ -267,20 +492,126 @@ func (engine *DockerTaskEngine) synchronizeState() {
 						cont.Container.ApplyingError = api.NewNamedError(&ContainerVanishedError{})
 						log.Warn("Could not describe previously known container; assuming dead", "err", metadata.Error, "id", cont.DockerID, "name", cont.DockerName)
 						engine.imageManager.RemoveContainerReferenceFromImageState(cont.Container)
+						if cont.Container.Essential {
+							missingEssentialContainer = cont.Container
+						}
 					}
 				} else {
 					engine.imageManager.RecordContainerReference(cont.Container)
+					// The health of the container isn't saved; docker has
+					// kept checking it while the agent was down
+					if metadata.HealthStatus != api.ContainerHealthUnknown {
+						cont.Container.SetHealthStatus(metadata.HealthStatus)
+					}
+					if metadata.IPv4Address != "" {
+						cont.Container.SetIPv4Address(metadata.IPv4Address)
+					}
+					if metadata.Paused {
+						engine.handlePausedContainer(task, cont)
+					}
 				}
 				if currentState > cont.Container.GetKnownStatus() {
 					cont.Container.SetKnownStatus(currentState)
 				}
 			}
 		}
+		if missingEssentialContainer != nil && engine.handleMissingEssentialContainer(task, missingEssentialContainer) {
+			// The task is started once its remaining containers are removed
+			go engine.restartRestoredTask(task)
+			continue
+		}
 		engine.startTask(task)
 	}
 	engine.saver.Save()
 }
 
+// handleMissingEssentialContainer applies the configured policy to a restored
+// task whose essential container was removed from Docker while the agent was
+// down. The task is either stopped with a reason naming the container or, if
+// it returns true, has to be restarted from scratch
+func (engine *DockerTaskEngine) handleMissingEssentialContainer(task *api.Task, container *api.Container) bool {
+	if engine.cfg.MissingEssentialContainerPolicy == config.MissingEssentialContainerPolicyRestart &&
+		!task.GetDesiredStatus().Terminal() {
+		seelog.Warnf("Essential container %s of task %s is missing after restart, restarting the task", container.Name, task.Arn)
+		return true
+	}
+	seelog.Warnf("Essential container %s of task %s is missing after restart, stopping the task", container.Name, task.Arn)
+	container.ApplyingError = api.NewNamedError(&EssentialContainerMissingError{name: container.Name})
+	task.SetDesiredStatus(api.TaskStopped)
+	return false
+}
+
+// handlePausedContainer applies the configured policy to a container of a
+// restored task that Docker reports as paused. The container is either
+// unpaused, so that the task carries on, or the task is stopped with a reason
+// naming the container. The task is stopped as well if the container cannot
+// be unpaused
+func (engine *DockerTaskEngine) handlePausedContainer(task *api.Task, cont *api.DockerContainer) {
+	if engine.cfg.PausedContainerPolicy == config.PausedContainerPolicyUnpause {
+		err := engine.client.UnpauseContainer(cont.DockerID, unpauseContainerTimeout)
+		if err == nil {
+			seelog.Infof("Unpaused container %s of task %s, which was paused in Docker", cont.Container.Name, task.Arn)
+			return
+		}
+		seelog.Warnf("Unable to unpause container %s of task %s: %v", cont.Container.Name, task.Arn, err)
+	}
+	seelog.Warnf("Container %s of task %s is paused in Docker, stopping the task", cont.Container.Name, task.Arn)
+	cont.Container.ApplyingError = api.NewNamedError(&ContainerPausedError{name: cont.Container.Name})
+	task.SetDesiredStatus(api.TaskStopped)
+}
+
+// restartRestoredTask resets a restored task and starts it again, so that the
+// whole task is created and started again. It makes calls to Docker, so it is
+// run outside of synchronizeState
+func (engine *DockerTaskEngine) restartRestoredTask(task *api.Task) {
+	engine.resetRestoredTask(task)
+
+	engine.processTasks.Lock()
+	defer engine.processTasks.Unlock()
+	engine.startTask(task)
+}
+
+// resetRestoredTask removes the remaining containers of a restored task from
+// Docker and resets the task and its containers in place. The task is kept in
+// the state, so that the host ports allocated to it are not claimed by other
+// tasks before it is started again
+func (engine *DockerTaskEngine) resetRestoredTask(task *api.Task) {
+	conts, _ := engine.state.ContainerMapByArn(task.Arn)
+	for _, cont := range conts {
+		if cont.DockerID == "" || isVanished(cont.Container) {
+			continue
+		}
+		if !cont.Container.KnownTerminal() {
+			if cont.Container.Type == api.ContainerCNIPause {
+				if err := engine.cleanupPauseContainerNetwork(task, cont.Container); err != nil {
+					seelog.Warnf("Unable to clean up the network namespace of task %s before restarting the task: %v", task.Arn, err)
+				}
+			}
+			metadata := engine.client.StopContainer(cont.DockerID, stopContainerTimeout)
+			if metadata.Error != nil {
+				seelog.Warnf("Unable to stop container %s of task %s before restarting the task: %v", cont.Container.Name, task.Arn, metadata.Error)
+			}
+		}
+		if err := engine.client.RemoveContainer(cont.DockerID, removeContainerTimeout); err != nil {
+			seelog.Warnf("Unable to remove container %s of task %s before restarting the task: %v", cont.Container.Name, task.Arn, err)
+		}
+		engine.imageManager.RemoveContainerReferenceFromImageState(cont.Container)
+	}
+	// Drop the docker ids of the removed containers from the state
+	engine.state.RemoveTaskContainers(task)
+	task.SetKnownStatus(api.TaskStatusNone)
+	for _, container := range task.Containers {
+		container.ResetForRecreate()
+	}
+	engine.saver.Save()
+}
+
+// isVanished returns true if the container was found to be missing from
+// Docker while the state was synchronized
+func isVanished(container *api.Container) bool {
+	return container.ApplyingError != nil && container.ApplyingError.Name == ContainerVanishedError{}.ErrorName()
+}
+
 // CheckTaskState inspects the state of all containers within a task and writes
 // their state to the managed task's container channel.
 func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
@@ -294,7 +625,12 @@ func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
 		if !ok {
 			continue
 		}
+		describeStart := ttime.Now()
 		status, metadata := engine.client.DescribeContainer(dockerContainer.DockerID)
+		engine.steadyStatePoll.observe(ttime.Since(describeStart))
+		if container.IsRunning() && !status.IsRunning() {
+			engine.emitDriftEvent(task, container, dockerContainer.DockerID, status, metadata.Error)
+		}
 		engine.processTasks.RLock()
 		managedTask, ok := engine.managedTasks[task.Arn]
 		engine.processTasks.RUnlock()
@@ -304,6 +640,7 @@ func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
 				container: container,
 				event: DockerContainerChangeEvent{
 					Status:                  status,
+					DetectedByPoll:          true,
 					DockerContainerMetadata: metadata,
 				},
 			}
@@ -311,6 +648,23 @@ func (engine *DockerTaskEngine) CheckTaskState(task *api.Task) {
 	}
 }
 
+// emitDriftEvent writes a ContainerDriftEvent to the container change event
+// stream for a container whose docker status no longer matches its known status
+func (engine *DockerTaskEngine) emitDriftEvent(task *api.Task, container *api.Container, dockerID string, status api.ContainerStatus, err engineError) {
+	event := ContainerDriftEvent{
+		TaskArn:       task.Arn,
+		ContainerName: container.Name,
+		DockerID:      dockerID,
+		KnownStatus:   container.GetKnownStatus(),
+		DockerStatus:  status,
+		Error:         err,
+	}
+	seelog.Warn(event.String())
+	if writeErr := engine.containerChangeEventStream.WriteToEventStream(event); writeErr != nil {
+		seelog.Warnf("Failed to write container drift event to event stream, err %v", writeErr)
+	}
+}
+
 // sweepTask deletes all the containers associated with a task
 func (engine *DockerTaskEngine) sweepTask(task *api.Task) {
 	for _, cont := range task.Containers {
@@ -339,6 +693,19 @@ func (engine *DockerTaskEngine) emitTaskEvent(task *api.Task, reason string) {
 		log.Debug("Already sent task event; no need to re-send", "task", task.Arn, "event", taskKnownStatus.String())
 		return
 	}
+	if taskKnownStatus == api.TaskRunning && task.GetDesiredStatus().Terminal() {
+		// The task was requested to stop before it started; it is being
+		// stopped, and reporting it as running would be misleading
+		seelog.Infof("Task %s started after it was requested to stop; not reporting it as running", task.Arn)
+		return
+	}
+	switch taskKnownStatus {
+	case api.TaskRunning:
+		task.RecordLaunchMilestone(api.TaskLaunchRunning, ttime.Now())
+		task.RecordEvent(api.TaskEvent{Type: api.TaskEventSteadyState, Time: ttime.Now()})
+	case api.TaskStopped:
+		task.RecordEvent(api.TaskEvent{Type: api.TaskEventStopped, Time: ttime.Now()})
+	}
 	event := api.TaskStateChange{
 		TaskARN: task.Arn,
 		Status:  taskKnownStatus,
@@ -391,12 +758,30 @@ func (engine *DockerTaskEngine) emitContainerEvent(task *api.Task, cont *api.Con
 	if reason == "" && cont.ApplyingError != nil {
 		reason = cont.ApplyingError.Error()
 	}
+	if contKnownStatus == api.ContainerStopped && engine.cfg.ContainerExitReasonsEnabled {
+		if exitHistory := cont.GetExitHistory(); len(exitHistory) > 0 {
+			lastExit := exitHistory[len(exitHistory)-1]
+			if reason == "" {
+				reason = lastExit.Reason()
+			}
+			// Stops that no docker event reported are called out, to help
+			// debug missed docker events
+			if lastExit.DetectedByPoll {
+				if reason != "" {
+					reason += "; "
+				}
+				reason += containerStopDetectedByPollReason
+			}
+		}
+	}
 	event := api.ContainerStateChange{
 		TaskArn:       task.Arn,
 		ContainerName: cont.Name,
 		Status:        contKnownStatus.BackendStatus(cont.GetSteadyStateStatus()),
 		ExitCode:      cont.GetKnownExitCode(),
 		PortBindings:  cont.KnownPortBindings,
+		StartAttempts: cont.GetStartAttempts(),
+		Health:        cont.GetHealthStatus(),
 		Reason:        reason,
 		Container:     cont,
 	}
@@ -405,6 +790,27 @@ func (engine *DockerTaskEngine) emitContainerEvent(task *api.Task, cont *api.Con
 	log.Debug("Container change event passed on", "event", event)
 }
 
+// emitContainerHealthEvent passes a change of the health of a container up
+// through the containerEvents channel. The status of the container is the
+// one already reported, so the event is not deduplicated against it
+func (engine *DockerTaskEngine) emitContainerHealthEvent(task *api.Task, cont *api.Container) {
+	if cont.IsInternal() {
+		return
+	}
+	contKnownStatus := cont.GetKnownStatus()
+	event := api.ContainerStateChange{
+		TaskArn:       task.Arn,
+		ContainerName: cont.Name,
+		Status:        contKnownStatus.BackendStatus(cont.GetSteadyStateStatus()),
+		PortBindings:  cont.KnownPortBindings,
+		StartAttempts: cont.GetStartAttempts(),
+		Health:        cont.GetHealthStatus(),
+		Container:     cont,
+	}
+	log.Debug("Container health event", "event", event)
+	engine.stateChangeEvents <- event
+}
+
 // openEventstream opens, but does not consume, the docker event stream
 func (engine *DockerTaskEngine) openEventstream(ctx context.Context) error {
 	events, err := engine.client.ContainerEvents(ctx)
@@ -418,6 +824,9 @@ func (engine *DockerTaskEngine) openEventstream(ctx context.Context) error {
 // handleDockerEvents must be called after openEventstream; it processes each
 // event that it reads from the docker eventstream
 func (engine *DockerTaskEngine) handleDockerEvents(ctx context.Context) {
+	if engine.cfg.StartupEventReconcileEnabled {
+		engine.reconcileEventBacklog(ctx)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -431,6 +840,42 @@ func (engine *DockerTaskEngine) handleDockerEvents(ctx context.Context) {
 	}
 }
 
+// reconcileEventBacklog drains the Docker events that piled up while the
+// engine was starting. Rather than applying each of them as a live transition,
+// which makes containers flap through every state they went through, the
+// state of each affected task is checked once after the backlog is drained
+func (engine *DockerTaskEngine) reconcileEventBacklog(ctx context.Context) {
+	tasks := make(map[string]*api.Task)
+	quietPeriod := time.NewTimer(eventBacklogQuietPeriod)
+	defer quietPeriod.Stop()
+
+drain:
+	for drained := 0; drained < eventBacklogMaxEvents; drained++ {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-engine.events:
+			task, ok := engine.state.TaskByID(event.DockerID)
+			if ok {
+				tasks[task.Arn] = task
+			} else {
+				engine.handleDockerEvent(event)
+			}
+			if !quietPeriod.Stop() {
+				<-quietPeriod.C
+			}
+			quietPeriod.Reset(eventBacklogQuietPeriod)
+		case <-quietPeriod.C:
+			break drain
+		}
+	}
+
+	seelog.Infof("Reconciling %d tasks with Docker events received on startup", len(tasks))
+	for _, task := range tasks {
+		engine.CheckTaskState(task)
+	}
+}
+
 // handleDockerEvent is the entrypoint for task modifications originating with
 // events occurring through Docker, outside the task engine itself.
 // handleDockerEvent is responsible for taking an event that correlates to a
@@ -439,6 +884,50 @@ func (engine *DockerTaskEngine) handleDockerEvents(ctx context.Context) {
 func (engine *DockerTaskEngine) handleDockerEvent(event DockerContainerChangeEvent) bool {
 	log.Debug("Handling a docker event", "event", event)
 
+	if engine.unknownContainers != nil && engine.holdUnknownContainerEvent(event) {
+		return false
+	}
+	return engine.sendDockerEvent(event)
+}
+
+// holdUnknownContainerEvent holds the event of a container that is not
+// managed by the engine while the container is inspected for adoption. The
+// inspection runs outside of the event loop, and is skipped for containers
+// that recently could not be adopted, whose events are dropped. It returns
+// true if the event was held or dropped
+func (engine *DockerTaskEngine) holdUnknownContainerEvent(event DockerContainerChangeEvent) bool {
+	if engine.unknownContainers.hold(event) {
+		return true
+	}
+	_, taskFound := engine.state.TaskByID(event.DockerID)
+	_, containerFound := engine.state.ContainerByID(event.DockerID)
+	if taskFound && containerFound {
+		return false
+	}
+	if engine.unknownContainers.add(event, ttime.Now()) {
+		go engine.adoptUnknownContainer(event.DockerID)
+	}
+	return true
+}
+
+// adoptUnknownContainer inspects a container that is not managed by the
+// engine and, if it is adopted, sends the events held in the meantime to its
+// task, in the order in which they were received
+func (engine *DockerTaskEngine) adoptUnknownContainer(dockerID string) {
+	if !engine.adoptContainer(dockerID) {
+		engine.unknownContainers.ignore(dockerID, ttime.Now())
+		return
+	}
+	for events := engine.unknownContainers.take(dockerID); len(events) > 0; events = engine.unknownContainers.take(dockerID) {
+		for _, event := range events {
+			engine.sendDockerEvent(event)
+		}
+	}
+}
+
+// sendDockerEvent sends the event to the task manager of the task of its
+// container. It returns false if the container is not managed by the engine
+func (engine *DockerTaskEngine) sendDockerEvent(event DockerContainerChangeEvent) bool {
 	task, taskFound := engine.state.TaskByID(event.DockerID)
 	cont, containerFound := engine.state.ContainerByID(event.DockerID)
 	if !taskFound || !containerFound {
@@ -460,6 +949,67 @@ func (engine *DockerTaskEngine) handleDockerEvent(event DockerContainerChangeEve
 	return true
 }
 
+// adoptContainer adds a container that is not tracked by the engine to its
+// task, if the labels of the container show that it was created by this
+// Agent for a container of a task in the engine state that is not already
+// backed by another Docker container. It returns true if the container was
+// adopted
+func (engine *DockerTaskEngine) adoptContainer(dockerID string) bool {
+	dockerContainer, err := engine.client.InspectContainer(dockerID, inspectContainerTimeout)
+	if err != nil {
+		seelog.Warnf("Unable to inspect unknown container %s: %v", dockerID, err)
+		return false
+	}
+	if dockerContainer.Config == nil {
+		return false
+	}
+	labels := dockerContainer.Config.Labels
+	taskArn := labels[labelPrefix+"task-arn"]
+	containerName := labels[labelPrefix+"container-name"]
+	if taskArn == "" || containerName == "" {
+		seelog.Debugf("Unknown container %s was not created by the Agent, not adopting it", dockerID)
+		return false
+	}
+	if labels[labelPrefix+"cluster"] != engine.cfg.Cluster {
+		seelog.Infof("Unknown container %s belongs to cluster %s, not adopting it", dockerID, labels[labelPrefix+"cluster"])
+		return false
+	}
+
+	task, ok := engine.state.TaskByArn(taskArn)
+	if !ok {
+		seelog.Infof("Unknown container %s belongs to task %s which is not managed, not adopting it", dockerID, taskArn)
+		return false
+	}
+	var container *api.Container
+	for _, taskContainer := range task.Containers {
+		if taskContainer.Name == containerName {
+			container = taskContainer
+			break
+		}
+	}
+	if container == nil {
+		seelog.Warnf("Unknown container %s references container %s which is not part of task %s, not adopting it", dockerID, containerName, taskArn)
+		return false
+	}
+	dockerName := strings.TrimPrefix(dockerContainer.Name, "/")
+	if containerMap, ok := engine.state.ContainerMapByArn(taskArn); ok {
+		if existing, ok := containerMap[containerName]; ok {
+			if existing.DockerID != "" && existing.DockerID != dockerID {
+				seelog.Warnf("Container %s of task %s is already backed by container %s, not adopting container %s", containerName, taskArn, existing.DockerID, dockerID)
+				return false
+			}
+			if existing.DockerName != "" {
+				dockerName = existing.DockerName
+			}
+		}
+	}
+
+	seelog.Infof("Adopting unknown container %s as container %s of task %s", dockerID, containerName, taskArn)
+	engine.state.AddContainer(&api.DockerContainer{DockerID: dockerID, DockerName: dockerName, Container: container}, task)
+	engine.saver.Save()
+	return true
+}
+
 // StateChangeEvents returns channels to read task and container state changes. These
 // changes should be read as soon as possible as them not being read will block
 // processing the task referenced by the event.
@@ -469,7 +1019,7 @@ func (engine *DockerTaskEngine) StateChangeEvents() chan statechange.Event {
 
 // AddTask starts tracking a task
 func (engine *DockerTaskEngine) AddTask(task *api.Task) error {
-	task.PostUnmarshalTask(engine.cfg, engine.credentialsManager)
+	postUnmarshalErr := task.PostUnmarshalTask(engine.cfg, engine.credentialsManager)
 
 	engine.processTasks.Lock()
 	defer engine.processTasks.Unlock()
@@ -478,17 +1028,28 @@ func (engine *DockerTaskEngine) AddTask(task *api.Task) error {
 	if !exists {
 		// This will update the container desired status
 		task.UpdateDesiredStatus()
+		task.RecordLaunchMilestone(api.TaskLaunchReceived, ttime.Now())
+		task.RecordEvent(api.TaskEvent{Type: api.TaskEventAdded, Time: ttime.Now()})
 
-		engine.state.AddTask(task)
-		if dependencygraph.ValidDependencies(task) {
+		if task.GetDesiredStatus().Terminal() {
+			engine.state.AddTask(task)
+			engine.stopUnknownTask(task)
 			engine.startTask(task)
-		} else {
-			seelog.Errorf("Unable to progress task with circular dependencies, task: %s", task.String())
-			task.SetKnownStatus(api.TaskStopped)
-			task.SetDesiredStatus(api.TaskStopped)
-			err := TaskDependencyError{task.Arn}
-			engine.emitTaskEvent(task, err.Error())
+			return nil
+		}
+		if err := engine.validateNewTask(task, postUnmarshalErr); err != nil {
+			// Rejected tasks are never added to the state, so that
+			// nothing is left behind for them
+			engine.rejectTask(task, err)
+			return nil
+		}
+		engine.state.AddTask(task)
+		if engine.paused {
+			seelog.Infof("Holding task until the task engine is resumed, task: %s", task.String())
+			engine.heldTasks[task.Arn] = task
+			return nil
 		}
+		engine.startTask(task)
 		return nil
 	}
 
@@ -498,6 +1059,125 @@ func (engine *DockerTaskEngine) AddTask(task *api.Task) error {
 	return nil
 }
 
+// validateNewTask returns the reason a task received for the first time
+// cannot be started, if any. The host ports of the task are only allocated
+// once all the other checks passed, so that rejected tasks never hold them
+func (engine *DockerTaskEngine) validateNewTask(task *api.Task, postUnmarshalErr error) error {
+	if len(task.Containers) == 0 {
+		return NoContainersError{task.Arn}
+	}
+	if engine.draining {
+		return AgentDrainingError{task.Arn}
+	}
+	if postUnmarshalErr != nil {
+		return TaskDefinitionError{taskArn: task.Arn, fromError: postUnmarshalErr}
+	}
+	if !dependencygraph.ValidDependencies(task) {
+		return TaskDependencyError{task.Arn}
+	}
+	if err := engine.checkCPUCapacity(task); err != nil {
+		return err
+	}
+	if err := engine.checkIPCModeSupport(task); err != nil {
+		return err
+	}
+	if err := engine.checkENILimit(task); err != nil {
+		return err
+	}
+	if err := engine.checkENICapacity(task); err != nil {
+		return err
+	}
+	if !engine.cfg.HostPortConflictCheckDisabled {
+		if err := engine.state.AllocateHostPorts(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectTask stops a task that cannot be started and reports it as stopped,
+// with the reason it was rejected
+func (engine *DockerTaskEngine) rejectTask(task *api.Task, err error) {
+	seelog.Errorf("Unable to start task, task: %s: %v", task.String(), err)
+	task.SetKnownStatus(api.TaskStopped)
+	task.SetDesiredStatus(api.TaskStopped)
+	engine.emitTaskEvent(task, err.Error())
+}
+
+// checkIPCModeSupport returns an UnsupportedIPCModeError if the containers of
+// the task share one IPC namespace and the docker daemon is too old to make
+// the IPC namespace of a container shareable
+func (engine *DockerTaskEngine) checkIPCModeSupport(task *api.Task) error {
+	if task.IPCMode != api.IPCModeTask {
+		return nil
+	}
+	for _, version := range engine.client.KnownVersions() {
+		if version == ipcModeTaskMinimumVersion {
+			return nil
+		}
+	}
+	return UnsupportedIPCModeError{
+		taskArn:         task.Arn,
+		ipcMode:         task.IPCMode,
+		requiredVersion: ipcModeTaskMinimumVersion,
+	}
+}
+
+// checkENILimit returns an ENILimitExceededError if the task uses the awsvpc
+// network mode and the attachment of its ENI is refused, as the maximum number
+// of tracked ENI attachments is reached
+func (engine *DockerTaskEngine) checkENILimit(task *api.Task) error {
+	eni := task.GetTaskENI()
+	if eni == nil || !dockerstate.ENILimitReached(engine.state, eni.MacAddress, engine.cfg.MaxTrackedENIs) {
+		return nil
+	}
+	return ENILimitExceededError{taskArn: task.Arn, maxENIs: engine.cfg.MaxTrackedENIs}
+}
+
+// checkENICapacity returns a NoENICapacityError if the task uses the awsvpc
+// network mode, failing fast is enabled, and the ENIs of other tasks already
+// use all of the ENI slots of the instance type
+func (engine *DockerTaskEngine) checkENICapacity(task *api.Task) error {
+	slots := engine.cfg.TaskENISlots
+	if !engine.cfg.ENICapacityFailFastEnabled || slots <= 0 || task.GetTaskENI() == nil {
+		return nil
+	}
+	inUse := 0
+	for _, existingTask := range engine.state.AllTasks() {
+		if existingTask.Arn == task.Arn || existingTask.GetTaskENI() == nil {
+			continue
+		}
+		if existingTask.GetKnownStatus().Terminal() {
+			continue
+		}
+		inUse++
+	}
+	if inUse < slots {
+		return nil
+	}
+	return NoENICapacityError{taskArn: task.Arn, slots: slots}
+}
+
+// stopUnknownTask handles a stop request for a task that the engine does not
+// know about, such as a task that has already been cleaned up. There is
+// nothing left to stop, so the task is marked as already stopped. Its task
+// manager then only emits the STOPPED state change, if enabled, so that the
+// backend converges, and cleans the task up. Otherwise the state change is
+// marked as sent
+func (engine *DockerTaskEngine) stopUnknownTask(task *api.Task) {
+	seelog.Infof("Received stop for unknown task %s, treating it as already stopped", task.Arn)
+	for _, container := range task.Containers {
+		container.SetKnownStatus(api.ContainerStopped)
+		if !engine.cfg.UnknownTaskStopEventsEnabled {
+			container.SetSentStatus(api.ContainerStopped)
+		}
+	}
+	task.SetKnownStatus(api.TaskStopped)
+	if !engine.cfg.UnknownTaskStopEventsEnabled {
+		task.SetSentStatus(api.TaskStopped)
+	}
+}
+
 // ListTasks returns the tasks currently managed by the DockerTaskEngine
 func (engine *DockerTaskEngine) ListTasks() ([]*api.Task, error) {
 	return engine.state.AllTasks(), nil
@@ -512,6 +1192,7 @@ func (engine *DockerTaskEngine) pullContainer(task *api.Task, container *api.Con
 	switch container.Type {
 	case api.ContainerCNIPause:
 		// ContainerCNIPause image are managed at startup
+		engine.waitForPauseImage(task)
 		return DockerContainerMetadata{}
 	case api.ContainerEmptyHostVolume:
 		// ContainerEmptyHostVolume image is either local (must be imported) or remote (must be pulled)
@@ -519,6 +1200,19 @@ func (engine *DockerTaskEngine) pullContainer(task *api.Task, container *api.Con
 			return engine.client.ImportLocalEmptyVolumeImage()
 		}
 	}
+	// The platform is verified before the image is pulled, as the variant of
+	// the image that is pulled depends on it
+	if err := engine.verifyContainerPlatform(task, container); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+	metadata := engine.pullContainerImage(task, container)
+	if engine.cfg.ImagePullDiskFullCleanupEnabled && isDiskFullError(metadata.Error) {
+		return engine.retryDiskFullPull(task, container, metadata)
+	}
+	return metadata
+}
+
+func (engine *DockerTaskEngine) pullContainerImage(task *api.Task, container *api.Container) DockerContainerMetadata {
 	if engine.enableConcurrentPull {
 		seelog.Infof("Pulling container %v concurrently. Task: %v", container, task)
 		return engine.concurrentPull(task, container)
@@ -528,9 +1222,38 @@ func (engine *DockerTaskEngine) pullContainer(task *api.Task, container *api.Con
 	}
 }
 
+// retryDiskFullPull removes unused images to free space on the disk and
+// retries once the pull of an image that failed because the disk is full.
+// The pull fails with a CannotPullContainerDiskFullError if the disk is
+// still full
+func (engine *DockerTaskEngine) retryDiskFullPull(task *api.Task, container *api.Container, metadata DockerContainerMetadata) DockerContainerMetadata {
+	if engine.cfg.ImageCleanupDisabled {
+		seelog.Warnf("Pull of image %s failed because the disk is full, image cleanup is disabled. Task: %v", container.Image, task)
+	} else {
+		seelog.Warnf("Pull of image %s failed because the disk is full, removing unused images before retrying. Task: %v", container.Image, task)
+		engine.imageManager.RemoveUnusedImages()
+		metadata = engine.pullContainerImage(task, container)
+		if !isDiskFullError(metadata.Error) {
+			return metadata
+		}
+	}
+	return DockerContainerMetadata{Error: CannotPullContainerDiskFullError{metadata.Error}}
+}
+
 func (engine *DockerTaskEngine) concurrentPull(task *api.Task, container *api.Container) DockerContainerMetadata {
 	seelog.Debugf("Attempting to obtain ImagePullDeleteLock to pull image - %s. Task: %v", container.Image, task)
+	// Record the wait so that introspection can report tasks that are
+	// blocked behind other pulls or image cleanup
+	container.SetPullLockWaitStart(ttime.Now())
+	// Wait for a pull slot before taking the lock, so that waiting pulls
+	// don't hold off image cleanup
+	if engine.pullSemaphore != nil {
+		seelog.Debugf("Waiting to pull image %s with priority %d, task: %s", container.Image, task.Priority, task.Arn)
+		engine.pullSemaphore.acquire(task.Priority)
+		defer engine.pullSemaphore.release()
+	}
 	ImagePullDeleteLock.RLock()
+	container.SetPullLockWaitStart(time.Time{})
 	seelog.Debugf("Acquired ImagePullDeleteLock, start pulling image - %s. Task: %v", container.Image, task)
 	defer seelog.Debugf("Released ImagePullDeleteLock after pulling image - %s. Task: %v", container.Image, task)
 	defer ImagePullDeleteLock.RUnlock()
@@ -544,7 +1267,9 @@ func (engine *DockerTaskEngine) concurrentPull(task *api.Task, container *api.Co
 
 func (engine *DockerTaskEngine) serialPull(task *api.Task, container *api.Container) DockerContainerMetadata {
 	seelog.Debugf("Attempting to obtain ImagePullDeleteLock to pull image - %s. Task: %v", container.Image, task)
+	container.SetPullLockWaitStart(ttime.Now())
 	ImagePullDeleteLock.Lock()
+	container.SetPullLockWaitStart(time.Time{})
 	seelog.Debugf("Acquired ImagePullDeleteLock, start pulling image - %s. Task: %v", container.Image, task)
 	defer seelog.Debugf("Released ImagePullDeleteLock after pulling image - %s. Task: %v", container.Image, task)
 	defer ImagePullDeleteLock.Unlock()
@@ -565,7 +1290,25 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *api.Task,
 		return DockerContainerMetadata{Error: TaskStoppedBeforePullBeginError{task.Arn}}
 	}
 
-	metadata := engine.client.PullImage(container.Image, container.RegistryAuthentication)
+	// Hold a reference to the image until the container is created, so that it
+	// doesn't get removed by the image cleanup once ImagePullDeleteLock is
+	// released
+	engine.pendingCreateImages.acquire(container)
+
+	var metadata DockerContainerMetadata
+	if reason := engine.imagePullSkipReason(container); reason != "" {
+		seelog.Infof("Skipping pull of image %s for container %s, %s. Task: %v", container.Image, container.Name, reason, task)
+		container.SetImagePullSkipReason(reason)
+		container.SetImagePullSource(api.ImagePullSourceCacheHit)
+	} else {
+		metadata = engine.client.PullImage(container.Image, container.RegistryAuthentication, container.Platform)
+		if metadata.Error == nil {
+			container.SetImagePullSource(metadata.ImagePullSource)
+		} else {
+			// There is no pulled image to keep around
+			engine.pendingCreateImages.release(container)
+		}
+	}
 
 	// Don't add internal images(created by ecs-agent) into imagemanger state
 	if container.IsInternal() {
@@ -582,7 +1325,32 @@ func (engine *DockerTaskEngine) pullAndUpdateContainerReference(task *api.Task,
 	return metadata
 }
 
+// imagePullSkipReason returns why the image of the container should not be
+// pulled under the configured image pull behavior. It returns an empty string
+// if the image should be pulled
+func (engine *DockerTaskEngine) imagePullSkipReason(container *api.Container) string {
+	if container.IsInternal() {
+		return ""
+	}
+	switch engine.cfg.ImagePullBehavior {
+	case config.ImagePullBehaviorOnce:
+		if engine.imageManager.GetImageStateFromImageName(container.Image) != nil {
+			return fmt.Sprintf("image was already pulled by the agent, image pull behavior is %s", config.ImagePullBehaviorOnce)
+		}
+	case config.ImagePullBehaviorPreferCached:
+		if _, err := engine.client.InspectImage(container.Image); err == nil {
+			return fmt.Sprintf("image is cached on the instance, image pull behavior is %s", config.ImagePullBehaviorPreferCached)
+		}
+	}
+	return ""
+}
+
 func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
+	if engine.createSemaphore != nil {
+		seelog.Debugf("Waiting to create container %s with priority %d, task: %s", container.Name, task.Priority, task.Arn)
+		engine.createSemaphore.acquire(task.Priority)
+		defer engine.createSemaphore.release()
+	}
 	log.Info("Creating container", "task", task, "container", container)
 	client := engine.client
 	if container.DockerConfig.Version != nil {
@@ -603,6 +1371,14 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 		}
 	}
 
+	// The reference to the image taken when it was pulled is no longer needed
+	// once the container is created
+	defer engine.pendingCreateImages.release(container)
+
+	if err := engine.resolveHostVolumes(task, container); err != nil {
+		return DockerContainerMetadata{Error: HostVolumeError{err}}
+	}
+
 	// Resolve HostConfig
 	// we have to do this in create, not start, because docker no longer handles
 	// merging create config with start hostconfig the same; e.g. memory limits
@@ -612,11 +1388,45 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 		return DockerContainerMetadata{Error: api.NamedError(hcerr)}
 	}
 
+	engine.applyDefaultDNS(task, container, hostConfig)
+
+	if engine.taskCgroupEnabled(task) {
+		hostConfig.CgroupParent = taskCgroupPath(task.Arn)
+	}
+
+	if err := engine.resolveNetwork(task, container, hostConfig.NetworkMode); err != nil {
+		return DockerContainerMetadata{Error: NetworkError{err}}
+	}
+
+	if err := engine.resolveLogSecretOptions(task, container, hostConfig); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+
+	if err := engine.createAWSLogsGroup(container, hostConfig); err != nil {
+		return DockerContainerMetadata{Error: AWSLogsGroupError{err}}
+	}
+
 	config, err := task.DockerConfig(container)
 	if err != nil {
 		return DockerContainerMetadata{Error: api.NamedError(err)}
 	}
 
+	if err := engine.applyCommandManifest(container, config); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+
+	if err := engine.validateContainerMemory(container, config); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+
+	if err := engine.validateWorkingDir(task, container, config.WorkingDir); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+
+	if err := engine.checkReservedLabels(task, container, config.Labels); err != nil {
+		return DockerContainerMetadata{Error: err}
+	}
+
 	// Augment labels with some metadata from the agent. Explicitly do this last
 	// such that it will always override duplicates in the provided raw config
 	// data.
@@ -625,6 +1435,10 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 	config.Labels[labelPrefix+"task-definition-family"] = task.Family
 	config.Labels[labelPrefix+"task-definition-version"] = task.Version
 	config.Labels[labelPrefix+"cluster"] = engine.cfg.Cluster
+	if engine.instanceTagLabeler != nil {
+		engine.instanceTagLabeler.addLabels(config.Labels, ttime.Now())
+	}
+	engine.mountTaskMetadataFile(task, container, config, hostConfig)
 
 	if dockerContainerName == "" {
 		name := ""
@@ -647,6 +1461,7 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 		engine.saver.ForceSave()
 	}
 
+	container.SetEffectiveConfig(config, hostConfig)
 	metadata := client.CreateContainer(config, hostConfig, dockerContainerName, createContainerTimeout)
 	if metadata.DockerID != "" {
 		engine.state.AddContainer(&api.DockerContainer{DockerID: metadata.DockerID, DockerName: dockerContainerName, Container: container}, task)
@@ -655,6 +1470,225 @@ func (engine *DockerTaskEngine) createContainer(task *api.Task, container *api.C
 	return metadata
 }
 
+// verifyContainerPlatform checks the platform requested for the container
+// against the platform of the host. On a mismatch, an error is returned if the
+// platform mismatch policy is set to fail; otherwise a warning is logged and
+// the requested variant of the image is pulled
+func (engine *DockerTaskEngine) verifyContainerPlatform(task *api.Task, container *api.Container) engineError {
+	if container.Platform == "" {
+		return nil
+	}
+	hostPlatform := runtime.GOOS + "/" + runtime.GOARCH
+	// Compare the os and architecture; the variant is not known for the host
+	platform := strings.SplitN(container.Platform, "/", 3)
+	if len(platform) >= 2 && platform[0] == runtime.GOOS && platform[1] == runtime.GOARCH {
+		return nil
+	}
+	if engine.cfg.PlatformMismatchPolicy == config.PlatformMismatchPolicyFail {
+		return PlatformMismatchError{platform: container.Platform, hostPlatform: hostPlatform}
+	}
+	seelog.Warnf("Platform %s requested for container %s does not match host platform %s, task: %s",
+		container.Platform, container.Name, hostPlatform, task.Arn)
+	return nil
+}
+
+// validateWorkingDir checks the working directory of the container against
+// its image, according to the working directory validation policy. Relative
+// working directories are refused by Docker. Images cannot be listed through
+// the Docker API, so an absolute working directory is considered likely to
+// exist if it is the working directory of the image, or one of its parents,
+// or is at or under a volume of the image or a mount point of the container.
+// Otherwise, an error is returned if the policy is set to fail, and a warning
+// is logged if it is set to warn
+func (engine *DockerTaskEngine) validateWorkingDir(task *api.Task, container *api.Container, workingDir string) engineError {
+	policy := engine.cfg.WorkingDirValidationPolicy
+	if policy == "" || workingDir == "" {
+		return nil
+	}
+	if !path.IsAbs(workingDir) {
+		return applyWorkingDirPolicy(policy, task, container, WorkingDirError{
+			workingDir: workingDir,
+			reason:     "it is not an absolute path",
+		})
+	}
+	dockerImage, err := engine.client.InspectImage(container.Image)
+	if err != nil || dockerImage.Config == nil {
+		seelog.Warnf("Unable to inspect image %s to validate the working directory of container %s, task: %s: %v",
+			container.Image, container.Name, task.Arn, err)
+		return nil
+	}
+
+	if pathWithin(dockerImage.Config.WorkingDir, workingDir) {
+		return nil
+	}
+	for volume := range dockerImage.Config.Volumes {
+		if pathWithin(workingDir, volume) {
+			return nil
+		}
+	}
+	for _, mountPoint := range container.MountPoints {
+		if pathWithin(workingDir, mountPoint.ContainerPath) {
+			return nil
+		}
+	}
+
+	return applyWorkingDirPolicy(policy, task, container, WorkingDirError{
+		workingDir: workingDir,
+		reason: fmt.Sprintf("it is unlikely to exist in image %s, as it is neither the working directory of the image, nor a volume or mount point",
+			container.Image),
+	})
+}
+
+// applyWorkingDirPolicy returns the error if the working directory validation
+// policy is set to fail, and logs it as a warning otherwise
+func applyWorkingDirPolicy(policy string, task *api.Task, container *api.Container, err WorkingDirError) engineError {
+	if policy == config.WorkingDirValidationPolicyFail {
+		return err
+	}
+	seelog.Warnf("Invalid working directory of container %s, task: %s: %v", container.Name, task.Arn, err)
+	return nil
+}
+
+// checkReservedLabels applies the reserved label conflict policy to the labels
+// of the container that use the prefix of the labels set by the agent. They
+// are either dropped with a warning, or cause an error to be returned. The
+// labels are left alone if the policy is unset
+func (engine *DockerTaskEngine) checkReservedLabels(task *api.Task, container *api.Container, labels map[string]string) engineError {
+	policy := engine.cfg.ReservedLabelConflictPolicy
+	if policy == "" {
+		return nil
+	}
+	var reserved []string
+	for key := range labels {
+		if strings.HasPrefix(key, labelPrefix) {
+			reserved = append(reserved, key)
+		}
+	}
+	if len(reserved) == 0 {
+		return nil
+	}
+	sort.Strings(reserved)
+
+	if policy == config.ReservedLabelConflictPolicyFail {
+		return ReservedLabelError{container: container.Name, labels: reserved}
+	}
+	seelog.Warnf("Dropping labels %s of container %s that use the reserved prefix %s, task: %s",
+		strings.Join(reserved, ", "), container.Name, labelPrefix, task.Arn)
+	for _, key := range reserved {
+		delete(labels, key)
+	}
+	return nil
+}
+
+// pathWithin returns true if dir is parent, or is under it
+func pathWithin(dir string, parent string) bool {
+	if dir == "" || parent == "" {
+		return false
+	}
+	dir = path.Clean(dir)
+	parent = path.Clean(parent)
+	return dir == parent || parent == "/" || strings.HasPrefix(dir, parent+"/")
+}
+
+// resolveHostVolumes applies the configured missing volume policy to the host
+// volumes referenced by the container. Depending on the policy, source paths
+// that do not exist are either created or reported as an error. Source paths
+// are looked up under the configured mount of the host root filesystem, as
+// they refer to the host rather than to the Agent container
+func (engine *DockerTaskEngine) resolveHostVolumes(task *api.Task, container *api.Container) error {
+	policy := engine.cfg.MissingVolumePolicy
+	if policy == "" {
+		return nil
+	}
+	for _, mountPoint := range container.MountPoints {
+		hostVolume, ok := task.HostVolumeByName(mountPoint.SourceVolume)
+		if !ok {
+			continue
+		}
+		fsVolume, ok := hostVolume.(*api.FSHostVolume)
+		if !ok || fsVolume.SourcePath() == "" {
+			continue
+		}
+		sourcePath := fsVolume.SourcePath()
+		localPath := filepath.Join(engine.cfg.HostRootDir, sourcePath)
+		_, err := os.Stat(localPath)
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "unable to resolve source path %s of volume %s", sourcePath, mountPoint.SourceVolume)
+		}
+		if policy == config.MissingVolumePolicyFail {
+			return errors.Errorf("source path %s of volume %s does not exist on the host", sourcePath, mountPoint.SourceVolume)
+		}
+		seelog.Infof("Creating missing source path %s of volume %s for container %s, task %s",
+			sourcePath, mountPoint.SourceVolume, container.Name, task.Arn)
+		if err := os.MkdirAll(localPath, engine.cfg.MissingVolumeDirMode); err != nil {
+			return errors.Wrapf(err, "unable to create source path %s of volume %s", sourcePath, mountPoint.SourceVolume)
+		}
+	}
+	return nil
+}
+
+// isUserDefinedNetworkMode returns true if the network mode refers to a docker
+// network by name, rather than to one of the network modes built into docker
+func isUserDefinedNetworkMode(networkMode string) bool {
+	switch networkMode {
+	case "", "default", bridgeNetworkMode, "host", "none", "nat":
+		return false
+	}
+	return !strings.HasPrefix(networkMode, "container:")
+}
+
+// resolveNetwork applies the configured missing network policy to the
+// user-defined docker network referenced by the network mode of the
+// container. Depending on the policy, a network that does not exist is either
+// created or reported as an error
+func (engine *DockerTaskEngine) resolveNetwork(task *api.Task, container *api.Container, networkMode string) error {
+	policy := engine.cfg.MissingNetworkPolicy
+	if policy == "" || container.IsInternal() || !isUserDefinedNetworkMode(networkMode) {
+		return nil
+	}
+	_, err := engine.client.InspectNetwork(networkMode, inspectNetworkTimeout)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*docker.NoSuchNetwork); !ok {
+		return errors.Wrapf(err, "unable to inspect network %s", networkMode)
+	}
+	if policy == config.MissingNetworkPolicyFail {
+		return errors.Errorf("network %s referenced by the network mode of container %s does not exist", networkMode, container.Name)
+	}
+	seelog.Infof("Creating missing network %s for container %s, task %s", networkMode, container.Name, task.Arn)
+	if err := engine.client.CreateNetwork(networkMode, createNetworkTimeout); err != nil {
+		// Another container referencing the network may have created it in
+		// the meantime
+		if _, inspectErr := engine.client.InspectNetwork(networkMode, inspectNetworkTimeout); inspectErr == nil {
+			return nil
+		}
+		return errors.Wrapf(err, "unable to create network %s", networkMode)
+	}
+	return nil
+}
+
+// applyDefaultDNS sets the DNS servers and search domains configured for the
+// agent on containers of bridge mode tasks. DNS settings specified in the
+// container's own host config take precedence over the defaults
+func (engine *DockerTaskEngine) applyDefaultDNS(task *api.Task, container *api.Container, hostConfig *docker.HostConfig) {
+	if container.IsInternal() || task.GetTaskENI() != nil {
+		return
+	}
+	if hostConfig.NetworkMode != "" && hostConfig.NetworkMode != bridgeNetworkMode {
+		return
+	}
+	if len(hostConfig.DNS) == 0 && len(engine.cfg.DefaultDNSServers) != 0 {
+		hostConfig.DNS = engine.cfg.DefaultDNSServers
+	}
+	if len(hostConfig.DNSSearch) == 0 && len(engine.cfg.DefaultDNSSearch) != 0 {
+		hostConfig.DNSSearch = engine.cfg.DefaultDNSSearch
+	}
+}
+
 func (engine *DockerTaskEngine) startContainer(task *api.Task, container *api.Container) DockerContainerMetadata {
 	log.Info("Starting container", "task", task, "container", container)
 	client := engine.client
@@ -675,7 +1709,25 @@ func (engine *DockerTaskEngine) startContainer(task *api.Task, container *api.Co
 			Error: CannotStartContainerError{errors.Errorf("Container not recorded as created")},
 		}
 	}
-	return client.StartContainer(dockerContainer.DockerID, startContainerTimeout)
+
+	// Transient failures to start the container are retried a few times
+	// before the failure is reported. The number of attempts is recorded so
+	// that flaky starts are visible
+	backoff := utils.NewSimpleBackoff(startContainerRetryMinDelay, startContainerRetryMaxDelay,
+		startContainerRetryJitterMultiplier, startContainerRetryDelayMultiplier)
+	var metadata DockerContainerMetadata
+	for attempt := 1; attempt <= startContainerMaxAttempts; attempt++ {
+		metadata = client.StartContainer(dockerContainer.DockerID, startContainerTimeout)
+		container.SetStartAttempts(attempt)
+		startErr, ok := metadata.Error.(CannotStartContainerError)
+		if !ok || !startErr.IsRetriableError() || attempt == startContainerMaxAttempts {
+			break
+		}
+		seelog.Warnf("Error starting container %s (attempt %d of %d), will retry; task: %s, err: %v",
+			container.Name, attempt, startContainerMaxAttempts, task.Arn, startErr)
+		engine.time().Sleep(backoff.Duration())
+	}
+	return metadata
 }
 
 func (engine *DockerTaskEngine) provisionContainerResources(task *api.Task, container *api.Container) DockerContainerMetadata {
@@ -686,7 +1738,13 @@ func (engine *DockerTaskEngine) provisionContainerResources(task *api.Task, cont
 			Error: ContainerNetworkingError{errors.Wrap(err, "container resource provisioning: unable to build cni configuration")},
 		}
 	}
-	// Invoke the libcni to config the network namespace for the container
+	// Invoke the libcni to config the network namespace for the container.
+	// The namespace of a task is set up by its own task manager, which keeps
+	// the operations of each task ordered
+	if engine.cniSetupSemaphore != nil {
+		engine.cniSetupSemaphore.acquire(task.Priority)
+		defer engine.cniSetupSemaphore.release()
+	}
 	err = engine.cniClient.SetupNS(cniConfig)
 	if err != nil {
 		seelog.Errorf("Set up pause container namespace failed, err: %v, task: %s", err, task.String())
@@ -695,6 +1753,7 @@ func (engine *DockerTaskEngine) provisionContainerResources(task *api.Task, cont
 			Error:    ContainerNetworkingError{errors.Wrap(err, "container resource provisioning: failed to setup network namespace")},
 		}
 	}
+	task.SetPauseContainerPID(cniConfig.ContainerPID)
 
 	return DockerContainerMetadata{
 		DockerID: cniConfig.ContainerID,
@@ -787,7 +1846,54 @@ func (engine *DockerTaskEngine) stopContainer(task *api.Task, container *api.Con
 		seelog.Infof("Cleaned pause container network namespace, task: %s", task.String())
 	}
 
-	return engine.client.StopContainer(dockerContainer.DockerID, stopContainerTimeout)
+	attempts := container.IncrementStopAttempts()
+	var metadata DockerContainerMetadata
+	if engine.cfg.TaskStopTimeout > 0 {
+		metadata = engine.client.StopContainerWithGracePeriod(dockerContainer.DockerID,
+			engine.stopGracePeriod(task, container), stopContainerTimeout)
+	} else if container.StopTimeout > 0 {
+		metadata = engine.client.StopContainerWithGracePeriod(dockerContainer.DockerID,
+			engine.containerStopTimeout(container), stopContainerTimeout)
+	} else {
+		metadata = engine.client.StopContainer(dockerContainer.DockerID, stopContainerTimeout)
+	}
+	if metadata.Error == nil && metadata.ExitCode != nil && *metadata.ExitCode == killedExitCode {
+		seelog.Warnf("Container %s of task %s did not exit on the stop signal after %d graceful stop attempt(s) and was killed",
+			container.Name, task.Arn, attempts)
+		container.SetKilledOnStop()
+	}
+	return metadata
+}
+
+// containerStopTimeout returns how long the container is given to exit
+// before it is killed. The stop timeout of the container, if any, is used
+// over the stop timeout of the Agent, up to the configured maximum
+func (engine *DockerTaskEngine) containerStopTimeout(container *api.Container) time.Duration {
+	if container.StopTimeout <= 0 {
+		return engine.cfg.DockerStopTimeout
+	}
+	if engine.cfg.MaxContainerStopTimeout > 0 && container.StopTimeout > engine.cfg.MaxContainerStopTimeout {
+		seelog.Warnf("Stop timeout %v of container %s exceeds the maximum, using %v",
+			container.StopTimeout, container.Name, engine.cfg.MaxContainerStopTimeout)
+		return engine.cfg.MaxContainerStopTimeout
+	}
+	return container.StopTimeout
+}
+
+// stopGracePeriod returns how long the container is given to exit before it
+// is killed, so that all the containers of the task are stopped within the
+// stop budget of the task. A container gets the stop timeout or whatever is
+// left of the budget, whichever is shorter; once the budget is spent, the
+// remaining containers are killed right away.
+func (engine *DockerTaskEngine) stopGracePeriod(task *api.Task, container *api.Container) time.Duration {
+	gracePeriod := task.RemainingStopBudget(engine.cfg.TaskStopTimeout, engine.time().Now())
+	if stopTimeout := engine.containerStopTimeout(container); gracePeriod > stopTimeout {
+		return stopTimeout
+	}
+	if gracePeriod < time.Second {
+		seelog.Warnf("Stop budget of task %s is spent, killing container %s", task.Arn, container.Name)
+	}
+	return gracePeriod
 }
 
 func (engine *DockerTaskEngine) removeContainer(task *api.Task, container *api.Container) error {
@@ -810,6 +1916,16 @@ func (engine *DockerTaskEngine) removeContainer(task *api.Task, container *api.C
 // referenced task, and if needed applies it. It should not be called anywhere
 // but from 'AddTask' and is protected by the processTasks lock there.
 func (engine *DockerTaskEngine) updateTask(task *api.Task, update *api.Task) {
+	if heldTask, ok := engine.heldTasks[task.Arn]; ok {
+		// Held tasks are not managed yet, the update is applied once they
+		// are started
+		if update.GetDesiredStatus() > heldTask.GetDesiredStatus() {
+			log.Debug("Updating the desired status of a held task", "task", task.Arn, "status", update.GetDesiredStatus())
+			heldTask.SetDesiredStatus(update.GetDesiredStatus())
+			heldTask.UpdateDesiredStatus()
+		}
+		return
+	}
 	managedTask, ok := engine.managedTasks[task.Arn]
 	if !ok {
 		log.Crit("ACS message for a task we thought we managed, but don't!  Aborting.", "arn", task.Arn)
@@ -863,11 +1979,42 @@ func (engine *DockerTaskEngine) applyContainerState(task *api.Task, container *a
 		clog.Info("Error transitioning container", "state", nextState.String(), "error", metadata.Error)
 	} else {
 		clog.Debug("Transitioned container", "state", nextState.String())
+		engine.recordContainerLaunchMilestone(task, nextState)
+		engine.recordContainerEvent(task, container, nextState)
 		engine.saver.Save()
 	}
 	return metadata
 }
 
+// recordContainerLaunchMilestone records the launch milestone of the task
+// that is reached by a container transitioning to the given state
+func (engine *DockerTaskEngine) recordContainerLaunchMilestone(task *api.Task, state api.ContainerStatus) {
+	switch state {
+	case api.ContainerPulled:
+		task.RecordLaunchMilestone(api.TaskLaunchPulled, ttime.Now())
+	case api.ContainerCreated:
+		task.RecordLaunchMilestone(api.TaskLaunchCreated, ttime.Now())
+	}
+}
+
+// containerEvents maps the container states whose transitions are recorded
+// in the timeline of the task to the event they are recorded as
+var containerEvents = map[api.ContainerStatus]api.TaskEventType{
+	api.ContainerPulled:  api.TaskEventPulled,
+	api.ContainerCreated: api.TaskEventCreated,
+	api.ContainerRunning: api.TaskEventStarted,
+}
+
+// recordContainerEvent records the transition of a container to the given
+// state in the timeline of the task. Internal containers are not recorded
+func (engine *DockerTaskEngine) recordContainerEvent(task *api.Task, container *api.Container, state api.ContainerStatus) {
+	eventType, ok := containerEvents[state]
+	if !ok || container.IsInternal() {
+		return
+	}
+	task.RecordEvent(api.TaskEvent{Type: eventType, ContainerName: container.Name, Time: ttime.Now()})
+}
+
 // transitionFunctionMap provides the logic for the simple state machine of the
 // DockerTaskEngine. Each desired state maps to a function that can be called
 // to try and move the task to that desired state.
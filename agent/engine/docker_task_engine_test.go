@@ -1,4 +1,6 @@
+//go:build !integration
 // +build !integration
+
 // Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
 //
 // Licensed under the Apache License, Version 2.0 (the "License"). You may
@@ -17,7 +19,11 @@ package engine
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,6 +35,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	"github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/ecscni/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerclient"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/image"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
@@ -39,6 +46,7 @@ import (
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"golang.org/x/net/context"
 )
@@ -99,7 +107,7 @@ func TestBatchContainerHappyPath(t *testing.T) {
 	var createdContainerName string
 	for _, container := range sleepTask.Containers {
 		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-		client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{})
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
 		imageManager.EXPECT().RecordContainerReference(container).Return(nil)
 		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
 		dockerConfig, err := sleepTask.DockerConfig(container)
@@ -148,10 +156,10 @@ func TestBatchContainerHappyPath(t *testing.T) {
 	cleanup := make(chan time.Time, 1)
 	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
 	gomock.InOrder(
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Do(func(d time.Duration) {
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Do(func(d time.Duration) {
 			steadyStateCheckWait.Done()
 		}).Return(steadyStateVerify),
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Return(steadyStateVerify).AnyTimes(),
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).AnyTimes(),
 	)
 
 	ctx, cancel := context.WithCancel(context.TODO())
@@ -241,6 +249,20 @@ func TestBatchContainerHappyPath(t *testing.T) {
 		}
 		time.Sleep(5 * time.Millisecond)
 	}
+
+	var timeline []api.TaskEventType
+	for _, event := range sleepTask.GetTimeline() {
+		timeline = append(timeline, event.Type)
+	}
+	assert.Equal(t, []api.TaskEventType{
+		api.TaskEventAdded,
+		api.TaskEventPulled,
+		api.TaskEventCreated,
+		api.TaskEventStarted,
+		api.TaskEventSteadyState,
+		api.TaskEventStopped,
+		api.TaskEventCleanedUp,
+	}, timeline, "Unexpected task timeline")
 }
 
 // TestTaskWithSteadyStateResourcesProvisioned tests container and task transitions
@@ -288,7 +310,7 @@ func TestTaskWithSteadyStateResourcesProvisioned(t *testing.T) {
 	// parallel. The dependency graph enforcement comes into effect for CREATED transitions.
 	// Hence, do not enforce the order of invocation of these calls
 	imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-	client.EXPECT().PullImage(sleepContainer.Image, nil).Return(DockerContainerMetadata{})
+	client.EXPECT().PullImage(sleepContainer.Image, nil, "").Return(DockerContainerMetadata{})
 	imageManager.EXPECT().RecordContainerReference(sleepContainer).Return(nil)
 	imageManager.EXPECT().GetImageStateFromImageName(sleepContainer.Image).Return(nil)
 
@@ -364,10 +386,10 @@ func TestTaskWithSteadyStateResourcesProvisioned(t *testing.T) {
 
 	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
 	gomock.InOrder(
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Do(func(d time.Duration) {
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Do(func(d time.Duration) {
 			steadyStateCheckWait.Done()
 		}).Return(steadyStateVerify),
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Return(steadyStateVerify).AnyTimes(),
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).AnyTimes(),
 	)
 	ctx, cancel := context.WithCancel(context.TODO())
 	err := taskEngine.Init(ctx)
@@ -423,6 +445,131 @@ func TestTaskWithSteadyStateResourcesProvisioned(t *testing.T) {
 	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Task is not in STOPPED state")
 }
 
+// TestTaskWithHealthyContainerDependency tests that a container that depends
+// on another container being healthy is only created once the health check of
+// that container reports it healthy
+func TestTaskWithHealthyContainerDependency(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	// sleep5 contains a single 'sleep' container, with DesiredStatus == RUNNING
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepTask.Containers[0].TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{
+		{
+			ContainerName:   "db",
+			SatisfiedStatus: api.ContainerRunning,
+			SatisfiedHealth: api.ContainerHealthy,
+			DependentStatus: api.ContainerCreated,
+		}}
+	sleepContainer := sleepTask.Containers[0]
+
+	// Add a second container, whose health check has to pass before the
+	// sleep container is created
+	dbContainer := &api.Container{
+		Name:                "db",
+		Image:               "db",
+		CPU:                 10,
+		Memory:              10,
+		Essential:           true,
+		DesiredStatusUnsafe: api.ContainerRunning,
+		HealthCheck:         &api.HealthCheck{Command: []string{"CMD", "true"}},
+	}
+	sleepTask.Containers = append(sleepTask.Containers, dbContainer)
+
+	eventStream := make(chan DockerContainerChangeEvent)
+	// createStartEventsReported is used to force the test to wait until the container created and started
+	// events are processed
+	createStartEventsReported := sync.WaitGroup{}
+
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+
+	// We cannot rely on the order of pulls between images as they can still be downloaded in
+	// parallel. The dependency graph enforcement comes into effect for CREATED transitions.
+	// Hence, do not enforce the order of invocation of these calls
+	imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+	for _, container := range sleepTask.Containers {
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
+		imageManager.EXPECT().RecordContainerReference(container).Return(nil)
+		imageManager.EXPECT().GetImageStateFromImageName(container.Image).Return(nil)
+	}
+
+	gomock.InOrder(
+		// Ensure that the db container is created first
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, hostConfig *docker.HostConfig, containerName string, z time.Duration) {
+				assert.True(t, strings.Contains(containerName, dbContainer.Name))
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerCreated)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID + ":" + dbContainer.Name}),
+		// Ensure that the db container is started after it's created
+		client.EXPECT().StartContainer(containerID+":"+dbContainer.Name, startContainerTimeout).Do(
+			func(id string, timeout time.Duration) {
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerRunning)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID + ":" + dbContainer.Name}),
+		// Once the db container is healthy, sleep container will be created
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, hostConfig *docker.HostConfig, containerName string, z time.Duration) {
+				assert.True(t, strings.Contains(containerName, sleepContainer.Name))
+				assert.Equal(t, api.ContainerHealthy, dbContainer.GetHealthStatus(),
+					"sleep container should only be created once db is healthy")
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerCreated)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID + ":" + sleepContainer.Name}),
+		// Next, the sleep container is started
+		client.EXPECT().StartContainer(containerID+":"+sleepContainer.Name, startContainerTimeout).Do(
+			func(id string, timeout time.Duration) {
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerRunning)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID + ":" + sleepContainer.Name}),
+	)
+
+	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
+	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	stateChangeEvents := taskEngine.StateChangeEvents()
+	taskEngine.AddTask(sleepTask)
+
+	event := <-stateChangeEvents
+	assert.Equal(t, "db", event.(api.ContainerStateChange).ContainerName)
+	assert.Equal(t, api.ContainerRunning, event.(api.ContainerStateChange).Status, "Expected db to be RUNNING")
+
+	// The health check of the db container reports it healthy once it runs
+	eventStream <- DockerContainerChangeEvent{
+		Status:                  api.ContainerRunning,
+		Health:                  api.ContainerHealthy,
+		DockerContainerMetadata: DockerContainerMetadata{DockerID: containerID + ":" + dbContainer.Name},
+	}
+	event = <-stateChangeEvents
+	assert.Equal(t, "db", event.(api.ContainerStateChange).ContainerName)
+	assert.Equal(t, api.ContainerHealthy, event.(api.ContainerStateChange).Health, "Expected db to be HEALTHY")
+	event = <-stateChangeEvents
+	assert.Equal(t, sleepContainer.Name, event.(api.ContainerStateChange).ContainerName)
+	assert.Equal(t, api.ContainerRunning, event.(api.ContainerStateChange).Status, "Expected sleep to be RUNNING")
+	event = <-stateChangeEvents
+	assert.Equal(t, api.TaskRunning, event.(api.TaskStateChange).Status, "Expected task to be RUNNING")
+
+	// Wait for container create and start events to be processed
+	createStartEventsReported.Wait()
+}
+
 // TestRemoveEvents tests if the task engine can handle task events while the task is being
 // cleaned up. This test ensures that there's no regression in the task engine and ensures
 // there's no deadlock as seen in #313
@@ -441,7 +588,7 @@ func TestRemoveEvents(t *testing.T) {
 	var createdContainerName string
 	for _, container := range sleepTask.Containers {
 		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-		client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{})
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
 		imageManager.EXPECT().RecordContainerReference(container).Return(nil)
 		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
 		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
@@ -471,10 +618,10 @@ func TestRemoveEvents(t *testing.T) {
 	cleanup := make(chan time.Time, 1)
 	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
 	gomock.InOrder(
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Do(func(d time.Duration) {
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Do(func(d time.Duration) {
 			steadyStateCheckWait.Done()
 		}).Return(steadyStateVerify),
-		mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Return(steadyStateVerify).AnyTimes(),
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).AnyTimes(),
 	)
 
 	ctx, cancel := context.WithCancel(context.TODO())
@@ -562,6 +709,170 @@ func TestRemoveEvents(t *testing.T) {
 	}
 }
 
+func TestDuplicateStoppedEventsEmitAndCleanupOnce(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	eventStream := make(chan DockerContainerChangeEvent)
+
+	createStartEventsReported := sync.WaitGroup{}
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+	for _, container := range sleepTask.Containers {
+		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
+		imageManager.EXPECT().RecordContainerReference(container).Return(nil)
+		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, y interface{}, containerName string, z time.Duration) {
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerCreated)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+		client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
+			func(id string, timeout time.Duration) {
+				createStartEventsReported.Add(1)
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerRunning)
+					createStartEventsReported.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+	}
+
+	steadyStateCheckWait := sync.WaitGroup{}
+	steadyStateVerify := make(chan time.Time, 1)
+	cleanup := make(chan time.Time, 1)
+	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
+	gomock.InOrder(
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Do(func(d time.Duration) {
+			steadyStateCheckWait.Done()
+		}).Return(steadyStateVerify),
+		mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).AnyTimes(),
+	)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	stateChangeEvents := taskEngine.StateChangeEvents()
+	steadyStateCheckWait.Add(1)
+	taskEngine.AddTask(sleepTask)
+
+	event := <-stateChangeEvents
+	assert.Equal(t, api.ContainerRunning, event.(api.ContainerStateChange).Status, "Expected container to be RUNNING")
+	event = <-stateChangeEvents
+	assert.Equal(t, api.TaskRunning, event.(api.TaskStateChange).Status, "Expected task to be RUNNING")
+
+	createStartEventsReported.Wait()
+	steadyStateCheckWait.Wait()
+	mockTime.EXPECT().After(gomock.Any()).Return(cleanup).AnyTimes()
+	client.EXPECT().DescribeContainer(gomock.Any()).AnyTimes()
+
+	exitCode := 0
+	eventStream <- DockerContainerChangeEvent{
+		Status: api.ContainerStopped,
+		DockerContainerMetadata: DockerContainerMetadata{
+			DockerID: containerID,
+			ExitCode: &exitCode,
+		},
+	}
+
+	event = <-stateChangeEvents
+	assert.Equal(t, api.ContainerStopped, event.(api.ContainerStateChange).Status, "Expected container to be STOPPED")
+	event = <-stateChangeEvents
+	assert.Equal(t, api.TaskStopped, event.(api.TaskStateChange).Status, "Expected task to be STOPPED")
+
+	// Docker then reports the container as stopped over and over again while
+	// the task waits for cleanup. The container is still removed exactly once
+	client.EXPECT().RemoveContainer(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	imageManager.EXPECT().RemoveContainerReferenceFromImageState(gomock.Any()).Times(1)
+	for i := 0; i < 10; i++ {
+		eventStream <- createDockerEvent(api.ContainerStopped)
+	}
+	select {
+	case event := <-stateChangeEvents:
+		t.Fatalf("Expected no state change for duplicate stopped events, got %s", event)
+	default:
+	}
+
+	sleepTask.SetSentStatus(api.TaskStopped)
+	cleanup <- time.Now()
+
+	for {
+		tasks, _ := taskEngine.(*DockerTaskEngine).ListTasks()
+		if len(tasks) == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case event := <-stateChangeEvents:
+		t.Fatalf("Should be out of events, got %s", event)
+	default:
+	}
+}
+
+func TestContainerHealthChangeEmitted(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepTask.Containers[0].HealthCheck = &api.HealthCheck{Command: []string{"CMD", "true"}}
+	eventStream := make(chan DockerContainerChangeEvent)
+
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+	for _, container := range sleepTask.Containers {
+		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
+		imageManager.EXPECT().RecordContainerReference(container).Return(nil)
+		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, y interface{}, containerName string, z time.Duration) {
+				assert.Equal(t, []string{"CMD", "true"}, config.Healthcheck.Test)
+				go func() { eventStream <- createDockerEvent(api.ContainerCreated) }()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+		client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
+			func(id string, timeout time.Duration) {
+				go func() { eventStream <- createDockerEvent(api.ContainerRunning) }()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+	}
+	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
+	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	stateChangeEvents := taskEngine.StateChangeEvents()
+	taskEngine.AddTask(sleepTask)
+
+	event := <-stateChangeEvents
+	assert.Equal(t, api.ContainerRunning, event.(api.ContainerStateChange).Status, "Expected container to be RUNNING")
+	event = <-stateChangeEvents
+	assert.Equal(t, api.TaskRunning, event.(api.TaskStateChange).Status, "Expected task to be RUNNING")
+
+	for _, health := range []api.ContainerHealthStatus{api.ContainerHealthy, api.ContainerUnhealthy} {
+		eventStream <- DockerContainerChangeEvent{
+			Status:                  api.ContainerRunning,
+			Health:                  health,
+			DockerContainerMetadata: DockerContainerMetadata{DockerID: containerID},
+		}
+		event = <-stateChangeEvents
+		containerEvent, ok := event.(api.ContainerStateChange)
+		require.True(t, ok, "Expected a container state change, got %s", event)
+		assert.Equal(t, api.ContainerRunning, containerEvent.Status)
+		assert.Equal(t, health, containerEvent.Health)
+		assert.Equal(t, health, sleepTask.Containers[0].GetHealthStatus())
+	}
+}
+
 func TestStartTimeoutThenStart(t *testing.T) {
 	ctrl, client, testTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
 	defer ctrl.Finish()
@@ -575,7 +886,7 @@ func TestStartTimeoutThenStart(t *testing.T) {
 	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
 	for _, container := range sleepTask.Containers {
 		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-		client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{})
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
 
 		imageManager.EXPECT().RecordContainerReference(container)
 		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
@@ -640,54 +951,140 @@ func TestStartTimeoutThenStart(t *testing.T) {
 	}
 }
 
-func TestSteadyStatePoll(t *testing.T) {
+// TestStartAfterStopDesired tests that a container whose start completes after
+// its task was requested to stop is stopped right away, without a RUNNING
+// state change being emitted for the container or the task
+func TestStartAfterStopDesired(t *testing.T) {
 	ctrl, client, testTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
 	defer ctrl.Finish()
 
-	wait := &sync.WaitGroup{}
 	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer := sleepTask.Containers[0]
 
 	eventStream := make(chan DockerContainerChangeEvent)
+	testTime.EXPECT().After(gomock.Any()).AnyTimes()
 
 	client.EXPECT().Version()
 	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
-	// set up expectations for each container in the task calling create + start
-	for _, container := range sleepTask.Containers {
-		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-		client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{})
-		imageManager.EXPECT().RecordContainerReference(container)
-		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
-		dockerConfig, err := sleepTask.DockerConfig(container)
-		assert.Nil(t, err)
-
-		// Container config should get updated with this during CreateContainer
-		dockerConfig.Labels["com.amazonaws.ecs.task-arn"] = sleepTask.Arn
-		dockerConfig.Labels["com.amazonaws.ecs.container-name"] = container.Name
-		dockerConfig.Labels["com.amazonaws.ecs.task-definition-family"] = sleepTask.Family
-		dockerConfig.Labels["com.amazonaws.ecs.task-definition-version"] = sleepTask.Version
-		dockerConfig.Labels["com.amazonaws.ecs.cluster"] = ""
-
-		wait.Add(1)
-		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any(), gomock.Any()).Do(
-			func(x, y, z, timeout interface{}) {
-				go func() {
-					eventStream <- createDockerEvent(api.ContainerCreated)
-					wait.Done()
-				}()
-			}).Return(DockerContainerMetadata{DockerID: containerID})
+	imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+	client.EXPECT().PullImage(sleepContainer.Image, nil, "").Return(DockerContainerMetadata{})
+	imageManager.EXPECT().RecordContainerReference(sleepContainer)
+	imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+		DockerContainerMetadata{DockerID: containerID})
 
-		wait.Add(1)
+	stopSent := make(chan struct{})
+	gomock.InOrder(
+		// The stop comes in while the start is in flight
 		client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
 			func(id string, timeout time.Duration) {
+				stoppedTask := testdata.LoadTask("sleep5")
+				stoppedTask.SetDesiredStatus(api.TaskStopped)
 				go func() {
-					eventStream <- createDockerEvent(api.ContainerRunning)
-					wait.Done()
+					taskEngine.AddTask(stoppedTask)
+					close(stopSent)
 				}()
-			}).Return(DockerContainerMetadata{DockerID: containerID})
-	}
+				<-stopSent
+				for !sleepContainer.GetDesiredStatus().Terminal() {
+					time.Sleep(time.Millisecond)
+				}
+			}).Return(DockerContainerMetadata{DockerID: containerID}),
+		client.EXPECT().StopContainer(containerID, gomock.Any()).Return(DockerContainerMetadata{DockerID: containerID}),
+	)
 
-	steadyStateVerify := make(chan time.Time, 10) // channel to trigger a "steady state verify" action
-	testTime.EXPECT().After(steadyStateTaskVerifyInterval).Return(steadyStateVerify).AnyTimes()
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	stateChangeEvents := taskEngine.StateChangeEvents()
+	taskEngine.AddTask(sleepTask)
+
+	event := <-stateChangeEvents
+	containerEvent, ok := event.(api.ContainerStateChange)
+	require.True(t, ok, "Expected a container state change, got %s", event)
+	assert.Equal(t, api.ContainerStopped, containerEvent.Status, "Expected no RUNNING event for the container")
+
+	event = <-stateChangeEvents
+	taskEvent, ok := event.(api.TaskStateChange)
+	require.True(t, ok, "Expected a task state change, got %s", event)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected no RUNNING event for the task")
+
+	select {
+	case event := <-stateChangeEvents:
+		t.Fatalf("Should be out of events, got %s", event)
+	default:
+	}
+}
+
+func TestSteadyStatePoll(t *testing.T) {
+	verifyIntervalConfig := config.DefaultConfig()
+	verifyIntervalConfig.SteadyStateVerifyInterval = 30 * time.Second
+
+	testCases := []struct {
+		name             string
+		cfg              config.Config
+		expectedInterval time.Duration
+	}{
+		{"default interval", defaultConfig, config.DefaultSteadyStateVerifyInterval},
+		{"configured interval", verifyIntervalConfig, 30 * time.Second},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testSteadyStatePoll(t, tc.cfg, tc.expectedInterval)
+		})
+	}
+}
+
+func testSteadyStatePoll(t *testing.T, cfg config.Config, expectedInterval time.Duration) {
+	cfg.ContainerExitReasonsEnabled = true
+	ctrl, client, testTime, taskEngine, _, imageManager := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	wait := &sync.WaitGroup{}
+	sleepTask := testdata.LoadTask("sleep5")
+
+	eventStream := make(chan DockerContainerChangeEvent)
+
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+	// set up expectations for each container in the task calling create + start
+	for _, container := range sleepTask.Containers {
+		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
+		imageManager.EXPECT().RecordContainerReference(container)
+		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
+		dockerConfig, err := sleepTask.DockerConfig(container)
+		assert.Nil(t, err)
+
+		// Container config should get updated with this during CreateContainer
+		dockerConfig.Labels["com.amazonaws.ecs.task-arn"] = sleepTask.Arn
+		dockerConfig.Labels["com.amazonaws.ecs.container-name"] = container.Name
+		dockerConfig.Labels["com.amazonaws.ecs.task-definition-family"] = sleepTask.Family
+		dockerConfig.Labels["com.amazonaws.ecs.task-definition-version"] = sleepTask.Version
+		dockerConfig.Labels["com.amazonaws.ecs.cluster"] = ""
+
+		wait.Add(1)
+		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(x, y, z, timeout interface{}) {
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerCreated)
+					wait.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+
+		wait.Add(1)
+		client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
+			func(id string, timeout time.Duration) {
+				go func() {
+					eventStream <- createDockerEvent(api.ContainerRunning)
+					wait.Done()
+				}()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+	}
+
+	steadyStateVerify := make(chan time.Time, 10) // channel to trigger a "steady state verify" action
+	testTime.EXPECT().After(expectedInterval).Return(steadyStateVerify).AnyTimes()
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	err := taskEngine.Init(ctx) // start the task engine
@@ -738,6 +1135,16 @@ func TestSteadyStatePoll(t *testing.T) {
 	client.EXPECT().RemoveContainer(dockerContainer.DockerName, removeContainerTimeout).Return(nil)
 	imageManager.EXPECT().RemoveContainerReferenceFromImageState(gomock.Any()).Return(nil)
 
+	driftEvents := make(chan ContainerDriftEvent, 10)
+	taskEngine.(*DockerTaskEngine).containerChangeEventStream.Subscribe("drift", func(events ...interface{}) error {
+		for _, event := range events {
+			if driftEvent, ok := event.(ContainerDriftEvent); ok {
+				driftEvents <- driftEvent
+			}
+		}
+		return nil
+	})
+
 	// trigger steady state verification
 	for i := 0; i < 10; i++ {
 		steadyStateVerify <- time.Now()
@@ -745,6 +1152,15 @@ func TestSteadyStatePoll(t *testing.T) {
 
 	event = <-stateChangeEvents
 	assert.Equal(t, event.(api.ContainerStateChange).Status, api.ContainerStopped, "Expected container to be STOPPED")
+	assert.Equal(t, containerStopDetectedByPollReason, event.(api.ContainerStateChange).Reason,
+		"Expected the reason to say that the stop was detected by polling")
+
+	driftEvent := <-driftEvents
+	assert.Equal(t, sleepTask.Arn, driftEvent.TaskArn)
+	assert.Equal(t, sleepTask.Containers[0].Name, driftEvent.ContainerName)
+	assert.Equal(t, containerID, driftEvent.DockerID)
+	assert.Equal(t, api.ContainerRunning, driftEvent.KnownStatus)
+	assert.Equal(t, api.ContainerStopped, driftEvent.DockerStatus)
 
 	event = <-stateChangeEvents
 	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Expected task to be STOPPED")
@@ -797,7 +1213,7 @@ func TestStopWithPendingStops(t *testing.T) {
 
 	pullDone := make(chan bool)
 	pullInvoked := make(chan bool)
-	client.EXPECT().PullImage(gomock.Any(), nil).Do(func(x, y interface{}) {
+	client.EXPECT().PullImage(gomock.Any(), nil, "").Do(func(x, y, z interface{}) {
 		pullInvoked <- true
 		<-pullDone
 	})
@@ -851,158 +1267,1415 @@ func TestCreateContainerForceSave(t *testing.T) {
 	}
 }
 
-func TestCreateContainerMergesLabels(t *testing.T) {
-	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
-	defer ctrl.Finish()
-
-	testTask := &api.Task{
-		Arn:     "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
-		Family:  "myFamily",
-		Version: "1",
-		Containers: []*api.Container{
-			{
-				Name: "c1",
-				DockerConfig: api.DockerConfig{
-					Config: aws.String(`{"Labels":{"key":"value"}}`),
-				},
-			},
+// TestCreateContainerReleasesImageReference tests that the reference to the
+// image of a container taken when it was pulled is held while the container is
+// being created and released once the create completes, whether it succeeded
+// or not
+func TestCreateContainerReleasesImageReference(t *testing.T) {
+	testCases := []struct {
+		name     string
+		metadata DockerContainerMetadata
+	}{
+		{
+			name:     "CreateSucceeded",
+			metadata: DockerContainerMetadata{DockerID: containerID},
+		},
+		{
+			name:     "CreateFailed",
+			metadata: DockerContainerMetadata{Error: CannotCreateContainerError{errors.New("error")}},
 		},
 	}
-	expectedConfig, err := testTask.DockerConfig(testTask.Containers[0])
-	if err != nil {
-		t.Fatal(err)
-	}
-	expectedConfig.Labels = map[string]string{
-		"com.amazonaws.ecs.task-arn":                "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
-		"com.amazonaws.ecs.container-name":          "c1",
-		"com.amazonaws.ecs.task-definition-family":  "myFamily",
-		"com.amazonaws.ecs.task-definition-version": "1",
-		"com.amazonaws.ecs.cluster":                 "",
-		"key": "value",
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+			defer ctrl.Finish()
+
+			sleepTask := testdata.LoadTask("sleep5")
+			sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+			imageNames := []string{sleepContainer.Image}
+			pendingCreateImages := taskEngine.(*DockerTaskEngine).pendingCreateImages
+			pendingCreateImages.acquire(sleepContainer)
+
+			client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+					assert.True(t, pendingCreateImages.isReferenced(imageNames),
+						"Expected image to be referenced while the container is being created")
+				}).Return(tc.metadata)
+
+			taskEngine.(*DockerTaskEngine).createContainer(sleepTask, sleepContainer)
+			assert.False(t, pendingCreateImages.isReferenced(imageNames),
+				"Expected image reference to be released after create")
+		})
 	}
-	client.EXPECT().CreateContainer(expectedConfig, gomock.Any(), gomock.Any(), gomock.Any())
-	taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
 }
 
-// TestTaskTransitionWhenStopContainerTimesout tests that task transitions to stopped
-// only when terminal events are recieved from docker event stream when
-// StopContainer times out
-func TestTaskTransitionWhenStopContainerTimesout(t *testing.T) {
-	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+// TestCreateContainerHonorsTaskPriority tests that when container creation is
+// constrained, the containers of higher priority tasks are created first
+func TestCreateContainerHonorsTaskPriority(t *testing.T) {
+	cfg := defaultConfig
+	cfg.ContainerCreateConcurrency = 1
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &cfg)
 	defer ctrl.Finish()
+	taskEngine := privateTaskEngine.(*DockerTaskEngine)
+
+	lowPriorityTask := testdata.LoadTask("sleep5")
+	lowPriorityTask.Arn = "lowPriorityTask"
+	highPriorityTask := testdata.LoadTask("sleep5")
+	highPriorityTask.Arn = "highPriorityTask"
+	highPriorityTask.Priority = 10
+
+	var createdTasks []string
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			createdTasks = append(createdTasks, config.Labels[labelPrefix+"task-arn"])
+		}).Times(2)
+
+	// Hold the only create slot until both tasks are waiting for it
+	taskEngine.createSemaphore.acquire(0)
+	var wg sync.WaitGroup
+	for i, task := range []*api.Task{lowPriorityTask, highPriorityTask} {
+		taskEngine.state.AddTask(task)
+		wg.Add(1)
+		go func(task *api.Task) {
+			defer wg.Done()
+			taskEngine.createContainer(task, task.Containers[0])
+		}(task)
+		for taskEngine.createSemaphore.numWaiters() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	taskEngine.createSemaphore.release()
+	wg.Wait()
 
-	sleepTask := testdata.LoadTask("sleep5")
+	assert.Equal(t, []string{highPriorityTask.Arn, lowPriorityTask.Arn}, createdTasks)
+}
 
-	eventStream := make(chan DockerContainerChangeEvent)
+// TestProvisionContainerResourcesConcurrency tests that the network namespaces
+// of different tasks are set up concurrently, up to the configured limit
+func TestProvisionContainerResourcesConcurrency(t *testing.T) {
+	cfg := defaultConfig
+	cfg.ENISetupConcurrency = 2
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	taskEngine := privateTaskEngine.(*DockerTaskEngine)
+	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
+	taskEngine.cniClient = cniClient
+
+	client.EXPECT().InspectContainer(gomock.Any(), gomock.Any()).Return(
+		&docker.Container{ID: "pauseContainerID", State: docker.State{Pid: 123}}, nil).Times(3)
+
+	var inFlightLock sync.Mutex
+	inFlight := 0
+	setupStarted := make(chan struct{}, 3)
+	finishSetup := make(chan struct{})
+	cniClient.EXPECT().SetupNS(gomock.Any()).Do(func(cfg interface{}) {
+		inFlightLock.Lock()
+		inFlight++
+		assert.True(t, inFlight <= 2, "no more than 2 namespaces should be set up at the same time")
+		inFlightLock.Unlock()
+		setupStarted <- struct{}{}
+		<-finishSetup
+		inFlightLock.Lock()
+		inFlight--
+		inFlightLock.Unlock()
+	}).Return(nil).Times(3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		task := testdata.LoadTask("sleep5")
+		task.Arn = fmt.Sprintf("task%d", i)
+		task.SetTaskENI(&api.ENI{
+			ID: "id",
+			IPV4Addresses: []*api.ENIIPV4Address{
+				{Primary: true, Address: "ipv4"},
+			},
+		})
+		pauseContainer := &api.Container{Name: api.PauseContainerName}
+		task.Containers = append(task.Containers, pauseContainer)
+		taskEngine.state.AddContainer(&api.DockerContainer{
+			DockerID:   "pauseContainerID",
+			DockerName: fmt.Sprintf("pause%d", i),
+			Container:  pauseContainer,
+		}, task)
+
+		wg.Add(1)
+		go func(task *api.Task, container *api.Container) {
+			defer wg.Done()
+			metadata := taskEngine.provisionContainerResources(task, container)
+			assert.NoError(t, metadata.Error)
+		}(task, pauseContainer)
+	}
 
-	client.EXPECT().Version()
-	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
-	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
-	containerStopTimeoutError := DockerContainerMetadata{
-		Error: &DockerTimeoutError{
-			transition: "stop",
-			duration:   stopContainerTimeout,
-		},
+	// Two setups should run at the same time while the third one waits
+	<-setupStarted
+	<-setupStarted
+	for taskEngine.cniSetupSemaphore.numWaiters() != 1 {
+		time.Sleep(time.Millisecond)
 	}
-	dockerEventSent := make(chan int)
-	for _, container := range sleepTask.Containers {
-		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-		client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{})
-		imageManager.EXPECT().RecordContainerReference(container)
-		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
-		dockerConfig, err := sleepTask.DockerConfig(container)
-		if err != nil {
-			t.Fatal(err)
-		}
-		// Container config should get updated with this during CreateContainer
-		dockerConfig.Labels["com.amazonaws.ecs.task-arn"] = sleepTask.Arn
-		dockerConfig.Labels["com.amazonaws.ecs.container-name"] = container.Name
-		dockerConfig.Labels["com.amazonaws.ecs.task-definition-family"] = sleepTask.Family
-		dockerConfig.Labels["com.amazonaws.ecs.task-definition-version"] = sleepTask.Version
-		dockerConfig.Labels["com.amazonaws.ecs.cluster"] = ""
+	close(finishSetup)
+	wg.Wait()
+	assert.Len(t, setupStarted, 1)
+}
 
-		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any(), gomock.Any()).Do(
-			func(x, y, z, timeout interface{}) {
-				go func() { eventStream <- createDockerEvent(api.ContainerCreated) }()
-			}).Return(DockerContainerMetadata{DockerID: containerID})
+// TestProvisionContainerResourcesRecordsPauseContainerPID tests that the pid
+// of the pause container is recorded once the network namespace of the task
+// is set up
+func TestProvisionContainerResourcesRecordsPauseContainerPID(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine := privateTaskEngine.(*DockerTaskEngine)
+	cniClient := mock_ecscni.NewMockCNIClient(ctrl)
+	taskEngine.cniClient = cniClient
 
-		gomock.InOrder(
-			client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
-				func(id string, timeout time.Duration) {
-					go func() {
-						eventStream <- createDockerEvent(api.ContainerRunning)
-					}()
-				}).Return(DockerContainerMetadata{DockerID: containerID}),
+	task := testdata.LoadTask("sleep5")
+	task.SetTaskENI(&api.ENI{
+		ID: "id",
+		IPV4Addresses: []*api.ENIIPV4Address{
+			{Primary: true, Address: "ipv4"},
+		},
+	})
+	pauseContainer := &api.Container{Name: api.PauseContainerName}
+	task.Containers = append(task.Containers, pauseContainer)
+	taskEngine.state.AddContainer(&api.DockerContainer{
+		DockerID:   "pauseContainerID",
+		DockerName: "pause",
+		Container:  pauseContainer,
+	}, task)
 
-			// StopContainer times out
-			client.EXPECT().StopContainer(containerID, gomock.Any()).Return(containerStopTimeoutError),
-			// Since task is not in steady state, progressContainers causes
-			// another invocation of StopContainer. Return a timeout error
-			// for that as well.
-			client.EXPECT().StopContainer(containerID, gomock.Any()).Do(
-				func(id string, timeout time.Duration) {
-					go func() {
-						dockerEventSent <- 1
-						// Emit 'ContainerStopped' event to the container event stream
-						// This should cause the container and the task to transition
-						// to 'STOPPED'
-						eventStream <- createDockerEvent(api.ContainerStopped)
-					}()
-				}).Return(containerStopTimeoutError).MinTimes(1),
-		)
-	}
+	client.EXPECT().InspectContainer("pause", gomock.Any()).Return(
+		&docker.Container{ID: "pauseContainerID", State: docker.State{Pid: 123}}, nil)
+	cniClient.EXPECT().SetupNS(gomock.Any()).Return(nil)
 
-	ctx, cancel := context.WithCancel(context.TODO())
-	err := taskEngine.Init(ctx)
-	assert.NoError(t, err)
-	defer cancel()
-	stateChangeEvents := taskEngine.StateChangeEvents()
+	metadata := taskEngine.provisionContainerResources(task, pauseContainer)
+	require.NoError(t, metadata.Error)
+	assert.Equal(t, "123", task.GetPauseContainerPID())
+}
 
-	go taskEngine.AddTask(sleepTask)
-	// wait for task running
-	event := <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, api.ContainerRunning, "Expected container to be RUNNING")
+// TestCreateContainerWorkingDirValidation tests that the working directory of
+// a container is checked against its image according to the configured policy
+func TestCreateContainerWorkingDirValidation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		workingDir    string
+		policy        string
+		expectInspect bool
+		expectCreate  bool
+		expectedError string
+	}{
+		{
+			name:         "NotValidated",
+			workingDir:   "/missing",
+			expectCreate: true,
+		},
+		{
+			name:          "ImageWorkingDir",
+			workingDir:    "/app",
+			policy:        config.WorkingDirValidationPolicyFail,
+			expectInspect: true,
+			expectCreate:  true,
+		},
+		{
+			name:          "ImageVolume",
+			workingDir:    "/data/output",
+			policy:        config.WorkingDirValidationPolicyFail,
+			expectInspect: true,
+			expectCreate:  true,
+		},
+		{
+			name:          "MissingWarn",
+			workingDir:    "/missing",
+			policy:        config.WorkingDirValidationPolicyWarn,
+			expectInspect: true,
+			expectCreate:  true,
+		},
+		{
+			name:          "MissingFail",
+			workingDir:    "/missing",
+			policy:        config.WorkingDirValidationPolicyFail,
+			expectInspect: true,
+			expectedError: "WorkingDirError",
+		},
+		{
+			name:         "RelativeWarn",
+			workingDir:   "app",
+			policy:       config.WorkingDirValidationPolicyWarn,
+			expectCreate: true,
+		},
+		{
+			name:          "RelativeFail",
+			workingDir:    "app",
+			policy:        config.WorkingDirValidationPolicyFail,
+			expectedError: "WorkingDirError",
+		},
+	}
 
-	event = <-stateChangeEvents
-	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskRunning, "Expected task to be RUNNING")
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.WorkingDirValidationPolicy = tc.policy
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
 
-	// Set the task desired status to be stopped and StopContainer will be called
-	updateSleepTask := testdata.LoadTask("sleep5")
-	updateSleepTask.SetDesiredStatus(api.TaskStopped)
-	go taskEngine.AddTask(updateSleepTask)
+			sleepTask := testdata.LoadTask("sleep5")
+			sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+			sleepContainer.DockerConfig.Config = aws.String(`{"WorkingDir":"` + tc.workingDir + `"}`)
 
-	// StopContainer timeout error shouldn't cause cantainer/task status change
-	// until receive stop event from docker event stream
-	select {
-	case <-stateChangeEvents:
-		t.Error("Should not get task events")
-	case <-dockerEventSent:
-		t.Logf("Send docker stop event")
-		go func() {
-			for {
-				<-dockerEventSent
+			if tc.expectInspect {
+				client.EXPECT().InspectImage(sleepContainer.Image).Return(&docker.Image{
+					Config: &docker.Config{
+						WorkingDir: "/app",
+						Volumes:    map[string]struct{}{"/data": {}},
+					},
+				}, nil)
 			}
-		}()
+			if tc.expectCreate {
+				client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
+					DockerContainerMetadata{DockerID: containerID})
+			}
+			metadata := taskEngine.(*DockerTaskEngine).createContainer(sleepTask, sleepContainer)
+			if tc.expectedError == "" {
+				assert.NoError(t, metadata.Error)
+			} else {
+				require.Error(t, metadata.Error)
+				assert.Equal(t, tc.expectedError, metadata.Error.ErrorName())
+				assert.Contains(t, metadata.Error.Error(), tc.workingDir)
+			}
+		})
 	}
+}
 
-	// StopContainer was called again and received stop event from docker event stream
-	// Expect it to go to stopped
-	event = <-stateChangeEvents
-	assert.Equal(t, event.(api.ContainerStateChange).Status, api.ContainerStopped, "Expected container to timeout on start and stop")
-
-	event = <-stateChangeEvents
-	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Expected task to be STOPPED")
+// TestPullContainerPlatformMismatch tests that a container requesting a
+// platform other than the host's is handled according to the configured policy
+// before its image is pulled, and that the platform is passed to the pull
+func TestPullContainerPlatformMismatch(t *testing.T) {
+	testCases := []struct {
+		name          string
+		platform      string
+		policy        string
+		expectPull    bool
+		expectedError string
+	}{
+		{
+			name:       "MatchingPlatform",
+			platform:   runtime.GOOS + "/" + runtime.GOARCH,
+			policy:     config.PlatformMismatchPolicyFail,
+			expectPull: true,
+		},
+		{
+			name:       "MismatchWarn",
+			platform:   runtime.GOOS + "/unknownarch",
+			policy:     config.PlatformMismatchPolicyWarn,
+			expectPull: true,
+		},
+		{
+			name:          "MismatchFail",
+			platform:      runtime.GOOS + "/unknownarch",
+			policy:        config.PlatformMismatchPolicyFail,
+			expectedError: "PlatformMismatchError",
+		},
+	}
 
-	select {
-	case <-stateChangeEvents:
-		t.Error("Should be out of events")
-	default:
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.PlatformMismatchPolicy = tc.policy
+			ctrl, client, _, taskEngine, _, imageManager := mocks(t, &cfg)
+			defer ctrl.Finish()
+			saver := mock_statemanager.NewMockStateManager(ctrl)
+			taskEngine.SetSaver(saver)
+
+			sleepTask := testdata.LoadTask("sleep5")
+			sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+			sleepContainer.Platform = tc.platform
+
+			if tc.expectPull {
+				client.EXPECT().PullImage(sleepContainer.Image, nil, tc.platform).Return(DockerContainerMetadata{})
+				imageManager.EXPECT().RecordContainerReference(sleepContainer)
+				imageManager.EXPECT().GetImageStateFromImageName(sleepContainer.Image).Return(&image.ImageState{
+					Image: &image.Image{ImageID: "id"},
+				})
+				saver.EXPECT().Save()
+			}
+			metadata := taskEngine.(*DockerTaskEngine).pullContainer(sleepTask, sleepContainer)
+			if tc.expectedError == "" {
+				assert.NoError(t, metadata.Error)
+			} else {
+				require.Error(t, metadata.Error)
+				assert.Equal(t, tc.expectedError, metadata.Error.ErrorName())
+			}
+		})
 	}
 }
 
-// TestTaskTransitionWhenStopContainerReturnsUnretriableError tests if the task transitions
-// to stopped without retrying stopping the container in the task when the initial
-// stop container call returns an unretriable error from docker, specifically the
+func TestStopContainersWithinTaskStopBudget(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DockerStopTimeout = 20 * time.Second
+	cfg.TaskStopTimeout = 30 * time.Second
+	ctrl, client, mockTime, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	testTask := &api.Task{
+		Arn: "myTaskArn",
+		Containers: []*api.Container{
+			{Name: "c1"},
+			{Name: "c2"},
+			{Name: "c3"},
+		},
+	}
+	state := taskEngine.(*DockerTaskEngine).State()
+	state.AddTask(testTask)
+	for _, container := range testTask.Containers {
+		state.AddContainer(&api.DockerContainer{
+			DockerID:   "id-" + container.Name,
+			DockerName: "name-" + container.Name,
+			Container:  container,
+		}, testTask)
+	}
+
+	// The containers are stopped one after the other, the first one takes
+	// its whole stop timeout to exit
+	stopStarted := time.Now()
+	stopTimes := []time.Time{
+		stopStarted,
+		stopStarted.Add(cfg.DockerStopTimeout),
+		stopStarted.Add(cfg.TaskStopTimeout),
+	}
+	expectedGracePeriods := []time.Duration{
+		cfg.DockerStopTimeout,
+		cfg.TaskStopTimeout - cfg.DockerStopTimeout,
+		0, // the straggler is killed right away
+	}
+	var calls []*gomock.Call
+	for i, container := range testTask.Containers {
+		calls = append(calls,
+			mockTime.EXPECT().Now().Return(stopTimes[i]),
+			client.EXPECT().StopContainerWithGracePeriod("id-"+container.Name, expectedGracePeriods[i], stopContainerTimeout).
+				Return(DockerContainerMetadata{}))
+	}
+	gomock.InOrder(calls...)
+
+	for i, container := range testTask.Containers {
+		gracePeriod := expectedGracePeriods[i]
+		metadata := taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+		assert.NoError(t, metadata.Error)
+		assert.False(t, stopTimes[i].Add(gracePeriod).After(stopStarted.Add(cfg.TaskStopTimeout)),
+			"container %s should be stopped within the task stop budget", container.Name)
+	}
+}
+
+func TestContainerEventExitReason(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reasonsEnabled bool
+		exitCode       int
+		reason         string
+		expectedReason string
+	}{
+		{name: "SIGKILL", reasonsEnabled: true, exitCode: 137, expectedReason: "Container killed (SIGKILL)"},
+		{name: "SIGTERM", reasonsEnabled: true, exitCode: 143, expectedReason: "Container terminated (SIGTERM)"},
+		{name: "ExitedNormally", reasonsEnabled: true, exitCode: 0, expectedReason: ""},
+		{name: "ExplicitReasonKept", reasonsEnabled: true, exitCode: 137, reason: "stopped by user", expectedReason: "stopped by user"},
+		{name: "Disabled", exitCode: 137, expectedReason: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.ContainerExitReasonsEnabled = tc.reasonsEnabled
+			ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+
+			container := &api.Container{
+				Name:              "c1",
+				KnownStatusUnsafe: api.ContainerStopped,
+			}
+			exitCode := tc.exitCode
+			container.SetKnownExitCode(&exitCode)
+			container.RecordExit(api.ContainerExit{ExitCode: &exitCode})
+			task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{container}}
+
+			go taskEngine.(*DockerTaskEngine).emitContainerEvent(task, container, tc.reason)
+			event := <-taskEngine.StateChangeEvents()
+			containerEvent, ok := event.(api.ContainerStateChange)
+			require.True(t, ok, "expected a container state change")
+			assert.Equal(t, api.ContainerStopped, containerEvent.Status)
+			assert.Equal(t, tc.exitCode, *containerEvent.ExitCode)
+			assert.Equal(t, tc.expectedReason, containerEvent.Reason)
+		})
+	}
+}
+
+func TestHandleDockerEventForLabeledUnknownContainer(t *testing.T) {
+	testCases := []struct {
+		name            string
+		policy          string
+		containerLabels map[string]string
+		expectedAdopted bool
+	}{
+		{
+			name:   "Ignored",
+			policy: config.UnknownContainerEventPolicyIgnore,
+		},
+		{
+			name:   "Adopted",
+			policy: config.UnknownContainerEventPolicyAdopt,
+			containerLabels: map[string]string{
+				labelPrefix + "task-arn":       "myTaskArn",
+				labelPrefix + "container-name": "c1",
+				labelPrefix + "cluster":        "myCluster",
+			},
+			expectedAdopted: true,
+		},
+		{
+			name:   "OtherClusterNotAdopted",
+			policy: config.UnknownContainerEventPolicyAdopt,
+			containerLabels: map[string]string{
+				labelPrefix + "task-arn":       "myTaskArn",
+				labelPrefix + "container-name": "c1",
+				labelPrefix + "cluster":        "otherCluster",
+			},
+		},
+		{
+			name:            "UnlabeledNotAdopted",
+			policy:          config.UnknownContainerEventPolicyAdopt,
+			containerLabels: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Cluster = "myCluster"
+			cfg.UnknownContainerEventPolicy = tc.policy
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+			dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+			container := &api.Container{Name: "c1"}
+			task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{container}}
+			dockerTaskEngine.state.AddTask(task)
+			dockerMessages := make(chan dockerContainerChange, 1)
+			dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task, dockerMessages: dockerMessages}
+
+			if tc.policy == config.UnknownContainerEventPolicyAdopt {
+				client.EXPECT().InspectContainer("orphan", gomock.Any()).Return(&docker.Container{
+					ID:     "orphan",
+					Name:   "/ecs-orphan",
+					Config: &docker.Config{Labels: tc.containerLabels},
+				}, nil)
+			}
+
+			handled := dockerTaskEngine.handleDockerEvent(DockerContainerChangeEvent{
+				Status:                  api.ContainerRunning,
+				DockerContainerMetadata: DockerContainerMetadata{DockerID: "orphan"},
+			})
+			assert.False(t, handled, "the events of unknown containers are sent once the container is adopted")
+			if tc.policy == config.UnknownContainerEventPolicyAdopt {
+				waitForUnknownContainerInspection(t, dockerTaskEngine, "orphan")
+			}
+
+			adopted, ok := dockerTaskEngine.state.ContainerByID("orphan")
+			assert.Equal(t, tc.expectedAdopted, ok)
+			if tc.expectedAdopted {
+				assert.Equal(t, container, adopted.Container)
+				assert.Equal(t, "ecs-orphan", adopted.DockerName)
+				change := <-dockerMessages
+				assert.Equal(t, container, change.container)
+				assert.Equal(t, api.ContainerRunning, change.event.Status)
+			}
+		})
+	}
+}
+
+func TestHandleDockerEventDoesNotAdoptReplacedContainer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cluster = "myCluster"
+	cfg.UnknownContainerEventPolicy = config.UnknownContainerEventPolicyAdopt
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	container := &api.Container{Name: "c1"}
+	task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{container}}
+	dockerTaskEngine.state.AddContainer(&api.DockerContainer{DockerID: "current", DockerName: "ecs-current", Container: container}, task)
+
+	client.EXPECT().InspectContainer("orphan", gomock.Any()).Return(&docker.Container{
+		ID:   "orphan",
+		Name: "/ecs-orphan",
+		Config: &docker.Config{Labels: map[string]string{
+			labelPrefix + "task-arn":       "myTaskArn",
+			labelPrefix + "container-name": "c1",
+			labelPrefix + "cluster":        "myCluster",
+		}},
+	}, nil)
+
+	handled := dockerTaskEngine.handleDockerEvent(DockerContainerChangeEvent{
+		Status:                  api.ContainerRunning,
+		DockerContainerMetadata: DockerContainerMetadata{DockerID: "orphan"},
+	})
+	assert.False(t, handled)
+	waitForUnknownContainerInspection(t, dockerTaskEngine, "orphan")
+	_, ok := dockerTaskEngine.state.ContainerByID("orphan")
+	assert.False(t, ok, "a container already backed by another Docker container should not be adopted")
+
+	// The container is not inspected again on its next events
+	dockerTaskEngine.handleDockerEvent(DockerContainerChangeEvent{
+		Status:                  api.ContainerStopped,
+		DockerContainerMetadata: DockerContainerMetadata{DockerID: "orphan"},
+	})
+	waitForUnknownContainerInspection(t, dockerTaskEngine, "orphan")
+}
+
+// TestHandleDockerEventHoldsEventsWhileAdopting tests that unknown containers
+// are inspected outside of the event loop, and that the events received while
+// a container is inspected are sent to its task in order once it is adopted
+func TestHandleDockerEventHoldsEventsWhileAdopting(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cluster = "myCluster"
+	cfg.UnknownContainerEventPolicy = config.UnknownContainerEventPolicyAdopt
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	container := &api.Container{Name: "c1"}
+	task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{container}}
+	dockerTaskEngine.state.AddTask(task)
+	dockerMessages := make(chan dockerContainerChange, 2)
+	dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task, dockerMessages: dockerMessages}
+
+	inspected := make(chan struct{})
+	client.EXPECT().InspectContainer("orphan", gomock.Any()).Do(func(interface{}, interface{}) {
+		<-inspected
+	}).Return(&docker.Container{
+		ID:   "orphan",
+		Name: "/ecs-orphan",
+		Config: &docker.Config{Labels: map[string]string{
+			labelPrefix + "task-arn":       "myTaskArn",
+			labelPrefix + "container-name": "c1",
+			labelPrefix + "cluster":        "myCluster",
+		}},
+	}, nil)
+
+	// Neither event waits for the inspection, which only happens once
+	for _, status := range []api.ContainerStatus{api.ContainerRunning, api.ContainerStopped} {
+		handled := dockerTaskEngine.handleDockerEvent(DockerContainerChangeEvent{
+			Status:                  status,
+			DockerContainerMetadata: DockerContainerMetadata{DockerID: "orphan"},
+		})
+		assert.False(t, handled)
+	}
+	close(inspected)
+	waitForUnknownContainerInspection(t, dockerTaskEngine, "orphan")
+
+	change := <-dockerMessages
+	assert.Equal(t, api.ContainerRunning, change.event.Status)
+	change = <-dockerMessages
+	assert.Equal(t, api.ContainerStopped, change.event.Status)
+}
+
+// waitForUnknownContainerInspection waits until the engine is done inspecting
+// the unknown container for adoption
+func waitForUnknownContainerInspection(t *testing.T, engine *DockerTaskEngine, dockerID string) {
+	for deadline := time.Now().Add(time.Second); ; {
+		engine.unknownContainers.lock.Lock()
+		_, inspecting := engine.unknownContainers.pending[dockerID]
+		engine.unknownContainers.lock.Unlock()
+		if !inspecting {
+			return
+		}
+		require.True(t, time.Now().Before(deadline), "Timed out waiting for container %s to be inspected", dockerID)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestReconcileEventBacklog tests that a burst of Docker events received on
+// startup results in a single check of the affected task, rather than every
+// event being applied as a transition
+func TestReconcileEventBacklog(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.StartupEventReconcileEnabled = true
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	container := &api.Container{Name: "c1", KnownStatusUnsafe: api.ContainerRunning}
+	task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{container}}
+	dockerTaskEngine.state.AddContainer(&api.DockerContainer{DockerID: "c1id", DockerName: "c1", Container: container}, task)
+	dockerMessages := make(chan dockerContainerChange, 10)
+	dockerTaskEngine.managedTasks[task.Arn] = &managedTask{Task: task, dockerMessages: dockerMessages}
+
+	events := make(chan DockerContainerChangeEvent, 10)
+	for _, status := range []api.ContainerStatus{api.ContainerStopped, api.ContainerRunning, api.ContainerStopped, api.ContainerRunning} {
+		events <- DockerContainerChangeEvent{
+			Status:                  status,
+			DockerContainerMetadata: DockerContainerMetadata{DockerID: "c1id"},
+		}
+	}
+	dockerTaskEngine.events = events
+
+	client.EXPECT().DescribeContainer("c1id").Return(api.ContainerRunning, DockerContainerMetadata{DockerID: "c1id"})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	dockerTaskEngine.reconcileEventBacklog(ctx)
+
+	assert.Empty(t, events, "the event backlog should be drained")
+	require.Len(t, dockerMessages, 1, "the task should be reconciled once")
+	change := <-dockerMessages
+	assert.Equal(t, container, change.container)
+	assert.Equal(t, api.ContainerRunning, change.event.Status)
+}
+
+// TestSynchronizeStateRestoresUnsavedMetadata tests that the health and address of the
+// containers of a restored task, which aren't saved, are restored from docker
+func TestSynchronizeStateRestoresUnsavedMetadata(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	container := &api.Container{
+		Name:                "db",
+		DesiredStatusUnsafe: api.ContainerRunning,
+		KnownStatusUnsafe:   api.ContainerRunning,
+		HealthCheck:         &api.HealthCheck{Command: []string{"CMD", "true"}},
+	}
+	task := &api.Task{
+		Arn:                 "myTaskArn",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Containers:          []*api.Container{container},
+	}
+	dockerTaskEngine.state.AddTask(task)
+	dockerTaskEngine.state.AddContainer(&api.DockerContainer{DockerID: "dbid", DockerName: "db", Container: container}, task)
+
+	client.EXPECT().DescribeContainer("dbid").Return(api.ContainerRunning,
+		DockerContainerMetadata{DockerID: "dbid", HealthStatus: api.ContainerHealthy, IPv4Address: "172.17.0.2"})
+	imageManager.EXPECT().RecordContainerReference(container).Return(nil)
+	mockTime.EXPECT().Now().Return(time.Now()).AnyTimes()
+	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+
+	dockerTaskEngine.synchronizeState()
+	assert.Equal(t, api.ContainerHealthy, container.GetHealthStatus())
+	assert.Equal(t, "172.17.0.2", container.GetIPv4Address())
+}
+
+// TestHandleMissingEssentialContainer tests that a restored task whose
+// essential container is missing from Docker is either stopped with a reason
+// naming the container or reset to be started again, depending on the policy
+func TestHandleMissingEssentialContainer(t *testing.T) {
+	for _, policy := range []string{config.MissingEssentialContainerPolicyStop, config.MissingEssentialContainerPolicyRestart} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.MissingEssentialContainerPolicy = policy
+			ctrl, client, _, taskEngine, _, imageManager := mocks(t, &cfg)
+			defer ctrl.Finish()
+			dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+			essential := &api.Container{
+				Name:                "essential",
+				Essential:           true,
+				DesiredStatusUnsafe: api.ContainerRunning,
+				KnownStatusUnsafe:   api.ContainerStopped,
+				ApplyingError:       api.NewNamedError(&ContainerVanishedError{}),
+			}
+			sidecar := &api.Container{
+				Name:                "sidecar",
+				DesiredStatusUnsafe: api.ContainerRunning,
+				KnownStatusUnsafe:   api.ContainerRunning,
+				Ports:               []api.PortBinding{{ContainerPort: 80, HostPort: 8080}},
+			}
+			task := &api.Task{
+				Arn:                 "myTaskArn",
+				DesiredStatusUnsafe: api.TaskRunning,
+				KnownStatusUnsafe:   api.TaskRunning,
+				Containers:          []*api.Container{essential, sidecar},
+			}
+			dockerTaskEngine.state.AddTask(task)
+			dockerTaskEngine.state.AddContainer(&api.DockerContainer{DockerID: "essentialid", DockerName: "essential", Container: essential}, task)
+			dockerTaskEngine.state.AddContainer(&api.DockerContainer{DockerID: "sidecarid", DockerName: "sidecar", Container: sidecar}, task)
+
+			if policy == config.MissingEssentialContainerPolicyRestart {
+				client.EXPECT().StopContainer("sidecarid", stopContainerTimeout).Return(DockerContainerMetadata{DockerID: "sidecarid"})
+				client.EXPECT().RemoveContainer("sidecarid", removeContainerTimeout).Return(nil)
+				imageManager.EXPECT().RemoveContainerReferenceFromImageState(sidecar).Return(nil)
+			}
+
+			restart := dockerTaskEngine.handleMissingEssentialContainer(task, essential)
+
+			if policy == config.MissingEssentialContainerPolicyStop {
+				assert.False(t, restart)
+				assert.Equal(t, api.TaskStopped, task.GetDesiredStatus())
+				require.NotNil(t, essential.ApplyingError)
+				assert.Equal(t, "EssentialContainerMissingError", essential.ApplyingError.Name)
+				assert.Contains(t, essential.ApplyingError.Error(), "essential")
+				_, ok := dockerTaskEngine.state.ContainerByID("sidecarid")
+				assert.True(t, ok, "the remaining containers should be stopped by the task")
+				return
+			}
+			require.True(t, restart)
+			require.NoError(t, dockerTaskEngine.state.AllocateHostPorts(task))
+			dockerTaskEngine.resetRestoredTask(task)
+			assert.Equal(t, api.TaskRunning, task.GetDesiredStatus())
+			assert.Equal(t, api.TaskStatusNone, task.GetKnownStatus())
+			for _, container := range task.Containers {
+				assert.Equal(t, api.ContainerStatusNone, container.GetKnownStatus())
+				assert.Nil(t, container.ApplyingError)
+			}
+			_, ok := dockerTaskEngine.state.ContainerByID("sidecarid")
+			assert.False(t, ok, "the removed containers should no longer be tracked")
+			_, ok = dockerTaskEngine.state.TaskByArn(task.Arn)
+			assert.True(t, ok, "the task should still be tracked")
+			otherTask := &api.Task{
+				Arn:        "otherTaskArn",
+				Containers: []*api.Container{{Name: "c", Ports: []api.PortBinding{{ContainerPort: 80, HostPort: 8080}}}},
+			}
+			assert.Error(t, dockerTaskEngine.state.AllocateHostPorts(otherTask), "the host ports of the task should remain allocated")
+		})
+	}
+}
+
+// TestHandlePausedContainer tests that a container of a restored task that
+// Docker reports as paused is unpaused or that the task is stopped with a
+// reason naming the container, depending on the policy
+func TestHandlePausedContainer(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      string
+		unpauseErr  error
+		expectStop bool
+	}{
+		{"unpause", config.PausedContainerPolicyUnpause, nil, false},
+		{"unpause failure", config.PausedContainerPolicyUnpause, errors.New("unpause failed"), true},
+		{"stop", config.PausedContainerPolicyStop, nil, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.PausedContainerPolicy = tc.policy
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+			dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+			container := &api.Container{
+				Name:                "paused",
+				DesiredStatusUnsafe: api.ContainerRunning,
+				KnownStatusUnsafe:   api.ContainerRunning,
+			}
+			task := &api.Task{
+				Arn:                 "myTaskArn",
+				DesiredStatusUnsafe: api.TaskRunning,
+				KnownStatusUnsafe:   api.TaskRunning,
+				Containers:          []*api.Container{container},
+			}
+			if tc.policy == config.PausedContainerPolicyUnpause {
+				client.EXPECT().UnpauseContainer("pausedid", unpauseContainerTimeout).Return(tc.unpauseErr)
+			}
+
+			dockerTaskEngine.handlePausedContainer(task, &api.DockerContainer{DockerID: "pausedid", Container: container})
+
+			if !tc.expectStop {
+				assert.Equal(t, api.TaskRunning, task.GetDesiredStatus())
+				assert.Nil(t, container.ApplyingError)
+				return
+			}
+			assert.Equal(t, api.TaskStopped, task.GetDesiredStatus())
+			require.NotNil(t, container.ApplyingError)
+			assert.Equal(t, "ContainerPausedError", container.ApplyingError.Name)
+			assert.Contains(t, container.ApplyingError.Error(), "paused")
+		})
+	}
+}
+
+// TestInitPrefetchesImages tests that the configured images are pulled when
+// the engine is initialized, and that failing to pull one of them does not
+// fail the initialization
+func TestInitPrefetchesImages(t *testing.T) {
+	for _, dockerVersion := range []string{"1.10.3", "1.12.6"} {
+		t.Run(dockerVersion, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.PrefetchImages = []string{"busybox:latest", "unavailable:latest"}
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+
+			client.EXPECT().Version().Return(dockerVersion, nil)
+			client.EXPECT().ContainerEvents(gomock.Any()).Return(make(chan DockerContainerChangeEvent), nil)
+			client.EXPECT().PullImage("busybox:latest", nil, "").Return(DockerContainerMetadata{})
+			client.EXPECT().PullImage("unavailable:latest", nil, "").Return(DockerContainerMetadata{
+				Error: CannotPullContainerError{errors.New("not found")},
+			})
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+			err := taskEngine.Init(ctx)
+			assert.NoError(t, err)
+			assert.True(t, taskEngine.(*DockerTaskEngine).initialized)
+		})
+	}
+}
+
+func TestStopUnknownTask(t *testing.T) {
+	testCases := []struct {
+		name        string
+		emitEnabled bool
+	}{
+		{name: "Acknowledged"},
+		{name: "StoppedEventEmitted", emitEnabled: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.UnknownTaskStopEventsEnabled = tc.emitEnabled
+			ctrl, _, mockTime, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+			// The task waits to be cleaned up once it is stopped
+			mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+
+			stopTask := testdata.LoadTask("sleep5")
+			stopTask.SetDesiredStatus(api.TaskStopped)
+
+			// No docker calls are expected, there is nothing to stop. The
+			// state change is emitted by the task manager, so adding the task
+			// does not wait for it to be read
+			require.NoError(t, taskEngine.AddTask(stopTask))
+			_, ok := taskEngine.(*DockerTaskEngine).State().TaskByArn(stopTask.Arn)
+			assert.True(t, ok, "unknown task should be added to the state to be cleaned up")
+			assert.Equal(t, api.TaskStopped, stopTask.GetKnownStatus())
+
+			if !tc.emitEnabled {
+				select {
+				case event := <-taskEngine.StateChangeEvents():
+					t.Fatalf("Unexpected state change: %v", event)
+				case <-time.After(100 * time.Millisecond):
+				}
+				return
+			}
+			for event := range taskEngine.StateChangeEvents() {
+				taskEvent, ok := event.(api.TaskStateChange)
+				if !ok {
+					continue
+				}
+				assert.Equal(t, stopTask.Arn, taskEvent.TaskARN)
+				assert.Equal(t, api.TaskStopped, taskEvent.Status)
+				break
+			}
+		})
+	}
+}
+
+func TestCreateContainerMergesLabels(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := &api.Task{
+		Arn:     "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				DockerConfig: api.DockerConfig{
+					Config: aws.String(`{"Labels":{"key":"value"}}`),
+				},
+			},
+		},
+	}
+	expectedConfig, err := testTask.DockerConfig(testTask.Containers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedConfig.Labels = map[string]string{
+		"com.amazonaws.ecs.task-arn":                "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+		"com.amazonaws.ecs.container-name":          "c1",
+		"com.amazonaws.ecs.task-definition-family":  "myFamily",
+		"com.amazonaws.ecs.task-definition-version": "1",
+		"com.amazonaws.ecs.cluster":                 "",
+		"key": "value",
+	}
+	client.EXPECT().CreateContainer(expectedConfig, gomock.Any(), gomock.Any(), gomock.Any())
+	taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+
+	effectiveConfig, _, ok := testTask.Containers[0].GetEffectiveConfig()
+	require.True(t, ok, "effective config should be recorded on create")
+	assert.Equal(t, expectedConfig.Labels, effectiveConfig.Labels)
+}
+
+func TestCreateContainerReservedLabelConflict(t *testing.T) {
+	testCases := []struct {
+		name           string
+		policy         string
+		expectedError  string
+		expectedLabels map[string]string
+	}{
+		{
+			name:   "Unset",
+			policy: "",
+			expectedLabels: map[string]string{
+				"com.amazonaws.ecs.task-arn": "myTaskArn",
+				"com.amazonaws.ecs.custom":   "user",
+				"key":                        "value",
+			},
+		},
+		{
+			name:   "Drop",
+			policy: config.ReservedLabelConflictPolicyDrop,
+			expectedLabels: map[string]string{
+				"com.amazonaws.ecs.task-arn": "myTaskArn",
+				"key":                        "value",
+			},
+		},
+		{
+			name:          "Fail",
+			policy:        config.ReservedLabelConflictPolicyFail,
+			expectedError: "ReservedLabelError",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.ReservedLabelConflictPolicy = tc.policy
+			ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+			defer ctrl.Finish()
+
+			testTask := &api.Task{
+				Arn:     "myTaskArn",
+				Family:  "myFamily",
+				Version: "1",
+				Containers: []*api.Container{
+					{
+						Name: "c1",
+						DockerConfig: api.DockerConfig{
+							Config: aws.String(`{"Labels":{"key":"value","com.amazonaws.ecs.task-arn":"spoofed","com.amazonaws.ecs.custom":"user"}}`),
+						},
+					},
+				},
+			}
+
+			if tc.expectedError != "" {
+				client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+				require.Error(t, metadata.Error)
+				assert.Equal(t, tc.expectedError, metadata.Error.ErrorName())
+				assert.Contains(t, metadata.Error.Error(), "com.amazonaws.ecs.custom, com.amazonaws.ecs.task-arn")
+				return
+			}
+
+			client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+					for key, value := range tc.expectedLabels {
+						assert.Equal(t, value, config.Labels[key], "label %s", key)
+					}
+					if _, ok := tc.expectedLabels["com.amazonaws.ecs.custom"]; !ok {
+						assert.NotContains(t, config.Labels, "com.amazonaws.ecs.custom")
+					}
+				})
+			metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+			assert.NoError(t, metadata.Error)
+		})
+	}
+}
+
+// TestCreateContainerAppliesDefaultDNS tests that the DNS servers and search
+// domains configured for the agent are applied to bridge mode containers
+func TestCreateContainerAppliesDefaultDNS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DefaultDNSServers = []string{"169.254.169.253"}
+	cfg.DefaultDNSSearch = []string{"example.com"}
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Equal(t, []string{"169.254.169.253"}, hostConfig.DNS)
+			assert.Equal(t, []string{"example.com"}, hostConfig.DNSSearch)
+		})
+	taskEngine.(*DockerTaskEngine).createContainer(sleepTask, sleepContainer)
+}
+
+// TestCreateContainerContainerDNSOverridesDefaultDNS tests that DNS settings
+// specified for the container take precedence over the agent defaults
+func TestCreateContainerContainerDNSOverridesDefaultDNS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DefaultDNSServers = []string{"169.254.169.253"}
+	cfg.DefaultDNSSearch = []string{"example.com"}
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+	sleepContainer.DockerConfig.HostConfig = aws.String(`{"Dns":["10.0.0.2"]}`)
+
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Equal(t, []string{"10.0.0.2"}, hostConfig.DNS)
+			assert.Equal(t, []string{"example.com"}, hostConfig.DNSSearch)
+		})
+	taskEngine.(*DockerTaskEngine).createContainer(sleepTask, sleepContainer)
+}
+
+// TestCreateContainerHostNetworkIgnoresDefaultDNS tests that the default DNS
+// settings are not applied to containers that don't use the bridge network
+func TestCreateContainerHostNetworkIgnoresDefaultDNS(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DefaultDNSServers = []string{"169.254.169.253"}
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+	sleepContainer, _ := sleepTask.ContainerByName("sleep5")
+	sleepContainer.DockerConfig.HostConfig = aws.String(`{"NetworkMode":"host"}`)
+
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Empty(t, hostConfig.DNS)
+		})
+	taskEngine.(*DockerTaskEngine).createContainer(sleepTask, sleepContainer)
+}
+
+// TestCreateContainerPIDMode tests that containers are created in the PID
+// namespace of the PID mode of their task
+func TestCreateContainerPIDMode(t *testing.T) {
+	testCases := []struct {
+		pidMode         string
+		expectedPIDMode string
+	}{
+		{pidMode: "", expectedPIDMode: ""},
+		{pidMode: api.PIDModeHost, expectedPIDMode: "host"},
+		{pidMode: api.PIDModeTask, expectedPIDMode: "container:sourceDockerID"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("pid mode %q", tc.pidMode), func(t *testing.T) {
+			ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &defaultConfig)
+			defer ctrl.Finish()
+			taskEngine := privateTaskEngine.(*DockerTaskEngine)
+
+			testTask := &api.Task{
+				Arn:     "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+				Family:  "myFamily",
+				Version: "1",
+				PIDMode: tc.pidMode,
+				Containers: []*api.Container{
+					{Name: "source"},
+					{Name: "sidecar"},
+				},
+			}
+			taskEngine.State().AddTask(testTask)
+			taskEngine.State().AddContainer(&api.DockerContainer{
+				DockerID:   "sourceDockerID",
+				DockerName: "sourceDockerName",
+				Container:  testTask.Containers[0],
+			}, testTask)
+
+			client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+					assert.Equal(t, tc.expectedPIDMode, hostConfig.PidMode)
+				})
+			metadata := taskEngine.createContainer(testTask, testTask.Containers[1])
+			assert.NoError(t, metadata.Error)
+		})
+	}
+}
+
+func taskWithHostVolume(sourcePath string) *api.Task {
+	return &api.Task{
+		Arn:     "arn:aws:ecs:us-east-1:012345678910:task/c09f0188-7f87-4b0f-bfc3-16296622b6fe",
+		Family:  "myFamily",
+		Version: "1",
+		Volumes: []api.TaskVolume{
+			{
+				Name:   "data",
+				Volume: &api.FSHostVolume{FSSourcePath: sourcePath},
+			},
+		},
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				MountPoints: []api.MountPoint{
+					{
+						SourceVolume:  "data",
+						ContainerPath: "/data",
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestCreateContainerMissingVolumePolicyCreate tests that missing host volume
+// source paths are created under the mount of the host root filesystem with the
+// configured permissions
+func TestCreateContainerMissingVolumePolicyCreate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "missing-volume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	cfg := config.DefaultConfig()
+	cfg.MissingVolumePolicy = config.MissingVolumePolicyCreate
+	cfg.MissingVolumeDirMode = 0700
+	// The temporary directory stands in for the mount of the host root
+	cfg.HostRootDir = tempDir
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	sourcePath := filepath.Join(string(filepath.Separator), "a", "b")
+	testTask := taskWithHostVolume(sourcePath)
+
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Contains(t, hostConfig.Binds, sourcePath+":/data", "the host path should be passed to docker")
+		})
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+	assert.NoError(t, metadata.Error)
+
+	info, err := os.Stat(filepath.Join(tempDir, "a", "b"))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+// TestCreateContainerMissingVolumePolicyFail tests that containers referencing
+// missing host volume source paths fail to be created with a clear reason
+func TestCreateContainerMissingVolumePolicyFail(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "missing-volume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	cfg := config.DefaultConfig()
+	cfg.MissingVolumePolicy = config.MissingVolumePolicyFail
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	sourcePath := filepath.Join(tempDir, "missing")
+	testTask := taskWithHostVolume(sourcePath)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+	assert.Error(t, metadata.Error)
+	assert.Equal(t, "HostVolumeError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), sourcePath)
+
+	_, err = os.Stat(sourcePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func taskWithNetworkMode(networkMode string) *api.Task {
+	return &api.Task{
+		Arn:     "arn:aws:ecs:us-west-2:123456789012:task/network",
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				DockerConfig: api.DockerConfig{
+					HostConfig: aws.String(`{"NetworkMode":"` + networkMode + `"}`),
+				},
+			},
+		},
+	}
+}
+
+// TestCreateContainerMissingNetworkPolicyFail tests that containers
+// referencing missing user-defined networks fail to be created with a clear
+// reason
+func TestCreateContainerMissingNetworkPolicyFail(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MissingNetworkPolicy = config.MissingNetworkPolicyFail
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	testTask := taskWithNetworkMode("mynet")
+	client.EXPECT().InspectNetwork("mynet", inspectNetworkTimeout).Return(nil, &docker.NoSuchNetwork{ID: "mynet"})
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "NetworkError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), "network mynet")
+	assert.Contains(t, metadata.Error.Error(), "does not exist")
+}
+
+// TestCreateContainerMissingNetworkPolicyCreate tests that missing
+// user-defined networks are created before the container
+func TestCreateContainerMissingNetworkPolicyCreate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MissingNetworkPolicy = config.MissingNetworkPolicyCreate
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	testTask := taskWithNetworkMode("mynet")
+	gomock.InOrder(
+		client.EXPECT().InspectNetwork("mynet", inspectNetworkTimeout).Return(nil, &docker.NoSuchNetwork{ID: "mynet"}),
+		client.EXPECT().CreateNetwork("mynet", createNetworkTimeout).Return(nil),
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+				assert.Equal(t, "mynet", hostConfig.NetworkMode)
+			}),
+	)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+	assert.NoError(t, metadata.Error)
+}
+
+// TestCreateContainerMissingNetworkPolicyCreatedConcurrently tests that the
+// creation of a missing network succeeds if another container created it in
+// the meantime
+func TestCreateContainerMissingNetworkPolicyCreatedConcurrently(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MissingNetworkPolicy = config.MissingNetworkPolicyCreate
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	testTask := taskWithNetworkMode("mynet")
+	gomock.InOrder(
+		client.EXPECT().InspectNetwork("mynet", inspectNetworkTimeout).Return(nil, &docker.NoSuchNetwork{ID: "mynet"}),
+		client.EXPECT().CreateNetwork("mynet", createNetworkTimeout).Return(errors.New("network with name mynet already exists")),
+		client.EXPECT().InspectNetwork("mynet", inspectNetworkTimeout).Return(&docker.Network{Name: "mynet"}, nil),
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
+	)
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+	assert.NoError(t, metadata.Error)
+}
+
+// TestCreateContainerMissingNetworkPolicyBuiltInModes tests that network
+// modes built into docker are not looked up as networks
+func TestCreateContainerMissingNetworkPolicyBuiltInModes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MissingNetworkPolicy = config.MissingNetworkPolicyFail
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	for _, networkMode := range []string{"bridge", "host", "none", "container:other"} {
+		testTask := taskWithNetworkMode(networkMode)
+		client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+		metadata := taskEngine.(*DockerTaskEngine).createContainer(testTask, testTask.Containers[0])
+		assert.NoError(t, metadata.Error, "network mode %s", networkMode)
+	}
+}
+
+// TestTaskTransitionWhenStopContainerTimesout tests that task transitions to stopped
+// only when terminal events are recieved from docker event stream when
+// StopContainer times out
+func TestTaskTransitionWhenStopContainerTimesout(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	sleepTask := testdata.LoadTask("sleep5")
+
+	eventStream := make(chan DockerContainerChangeEvent)
+
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any()).Return(eventStream, nil)
+	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+	containerStopTimeoutError := DockerContainerMetadata{
+		Error: &DockerTimeoutError{
+			transition: "stop",
+			duration:   stopContainerTimeout,
+		},
+	}
+	dockerEventSent := make(chan int)
+	for _, container := range sleepTask.Containers {
+		imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
+		client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{})
+		imageManager.EXPECT().RecordContainerReference(container)
+		imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
+		dockerConfig, err := sleepTask.DockerConfig(container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Container config should get updated with this during CreateContainer
+		dockerConfig.Labels["com.amazonaws.ecs.task-arn"] = sleepTask.Arn
+		dockerConfig.Labels["com.amazonaws.ecs.container-name"] = container.Name
+		dockerConfig.Labels["com.amazonaws.ecs.task-definition-family"] = sleepTask.Family
+		dockerConfig.Labels["com.amazonaws.ecs.task-definition-version"] = sleepTask.Version
+		dockerConfig.Labels["com.amazonaws.ecs.cluster"] = ""
+
+		client.EXPECT().CreateContainer(dockerConfig, gomock.Any(), gomock.Any(), gomock.Any()).Do(
+			func(x, y, z, timeout interface{}) {
+				go func() { eventStream <- createDockerEvent(api.ContainerCreated) }()
+			}).Return(DockerContainerMetadata{DockerID: containerID})
+
+		gomock.InOrder(
+			client.EXPECT().StartContainer(containerID, startContainerTimeout).Do(
+				func(id string, timeout time.Duration) {
+					go func() {
+						eventStream <- createDockerEvent(api.ContainerRunning)
+					}()
+				}).Return(DockerContainerMetadata{DockerID: containerID}),
+
+			// StopContainer times out
+			client.EXPECT().StopContainer(containerID, gomock.Any()).Return(containerStopTimeoutError),
+			// Since task is not in steady state, progressContainers causes
+			// another invocation of StopContainer. Return a timeout error
+			// for that as well.
+			client.EXPECT().StopContainer(containerID, gomock.Any()).Do(
+				func(id string, timeout time.Duration) {
+					go func() {
+						dockerEventSent <- 1
+						// Emit 'ContainerStopped' event to the container event stream
+						// This should cause the container and the task to transition
+						// to 'STOPPED'
+						eventStream <- createDockerEvent(api.ContainerStopped)
+					}()
+				}).Return(containerStopTimeoutError).MinTimes(1),
+		)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+	stateChangeEvents := taskEngine.StateChangeEvents()
+
+	go taskEngine.AddTask(sleepTask)
+	// wait for task running
+	event := <-stateChangeEvents
+	assert.Equal(t, event.(api.ContainerStateChange).Status, api.ContainerRunning, "Expected container to be RUNNING")
+
+	event = <-stateChangeEvents
+	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskRunning, "Expected task to be RUNNING")
+
+	// Set the task desired status to be stopped and StopContainer will be called
+	updateSleepTask := testdata.LoadTask("sleep5")
+	updateSleepTask.SetDesiredStatus(api.TaskStopped)
+	go taskEngine.AddTask(updateSleepTask)
+
+	// StopContainer timeout error shouldn't cause cantainer/task status change
+	// until receive stop event from docker event stream
+	select {
+	case <-stateChangeEvents:
+		t.Error("Should not get task events")
+	case <-dockerEventSent:
+		t.Logf("Send docker stop event")
+		go func() {
+			for {
+				<-dockerEventSent
+			}
+		}()
+	}
+
+	// StopContainer was called again and received stop event from docker event stream
+	// Expect it to go to stopped
+	event = <-stateChangeEvents
+	assert.Equal(t, event.(api.ContainerStateChange).Status, api.ContainerStopped, "Expected container to timeout on start and stop")
+
+	event = <-stateChangeEvents
+	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Expected task to be STOPPED")
+
+	select {
+	case <-stateChangeEvents:
+		t.Error("Should be out of events")
+	default:
+	}
+}
+
+// TestTaskTransitionWhenStopContainerReturnsUnretriableError tests if the task transitions
+// to stopped without retrying stopping the container in the task when the initial
+// stop container call returns an unretriable error from docker, specifically the
 // ContainerNotRunning error
 func TestTaskTransitionWhenStopContainerReturnsUnretriableError(t *testing.T) {
 	ctrl, client, mockTime, taskEngine, _, imageManager := mocks(t, &defaultConfig)
@@ -1017,7 +2690,7 @@ func TestTaskTransitionWhenStopContainerReturnsUnretriableError(t *testing.T) {
 	for _, container := range sleepTask.Containers {
 		gomock.InOrder(
 			imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes(),
-			client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{}),
+			client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{}),
 			imageManager.EXPECT().RecordContainerReference(container),
 			imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil),
 			// Simulate successful create container
@@ -1112,7 +2785,7 @@ func TestTaskTransitionWhenStopContainerReturnsTransientErrorBeforeSucceeding(t
 	for _, container := range sleepTask.Containers {
 		gomock.InOrder(
 			imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes(),
-			client.EXPECT().PullImage(container.Image, nil).Return(DockerContainerMetadata{}),
+			client.EXPECT().PullImage(container.Image, nil, "").Return(DockerContainerMetadata{}),
 			imageManager.EXPECT().RecordContainerReference(container),
 			imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil),
 			// Simulate successful create container
@@ -1185,7 +2858,7 @@ func TestGetTaskByArn(t *testing.T) {
 	imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
 	imageManager.EXPECT().RecordContainerReference(gomock.Any()).AnyTimes()
 	imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).AnyTimes()
-	client.EXPECT().PullImage(gomock.Any(), gomock.Any()).AnyTimes() // TODO change to MaxTimes(1)
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes() // TODO change to MaxTimes(1)
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	err := taskEngine.Init(ctx)
@@ -1221,6 +2894,88 @@ func TestEngineEnableConcurrentPull(t *testing.T) {
 		"Task engine should be able to perform concurrent pulling for docker version >= 1.11.1")
 }
 
+// TestConcurrentPullHonorsImagePullConcurrency tests that no more than the
+// configured number of images are pulled at the same time
+func TestConcurrentPullHonorsImagePullConcurrency(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ImagePullConcurrency = 2
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &cfg)
+	defer ctrl.Finish()
+	taskEngine := privateTaskEngine.(*DockerTaskEngine)
+	taskEngine.enableConcurrentPull = true
+
+	task := &api.Task{Arn: "myTaskArn"}
+	for i := 0; i < 4; i++ {
+		task.Containers = append(task.Containers, &api.Container{
+			Name:  fmt.Sprintf("c%d", i),
+			Image: fmt.Sprintf("image%d", i),
+		})
+	}
+
+	var inFlightLock sync.Mutex
+	inFlight := 0
+	maxInFlight := 0
+	client.EXPECT().PullImage(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(image interface{}, auth interface{}, platform interface{}) {
+		inFlightLock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		inFlightLock.Unlock()
+		// Keep the pull in flight long enough for the others to pile up
+		time.Sleep(20 * time.Millisecond)
+		inFlightLock.Lock()
+		inFlight--
+		inFlightLock.Unlock()
+	}).Return(DockerContainerMetadata{}).Times(len(task.Containers))
+	imageManager.EXPECT().RecordContainerReference(gomock.Any()).Return(nil).AnyTimes()
+	imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil).AnyTimes()
+
+	var wg sync.WaitGroup
+	for _, container := range task.Containers {
+		wg.Add(1)
+		go func(container *api.Container) {
+			defer wg.Done()
+			metadata := taskEngine.pullContainer(task, container)
+			assert.NoError(t, metadata.Error)
+		}(container)
+	}
+	wg.Wait()
+	assert.True(t, maxInFlight <= 2, "no more than 2 images should be pulled at the same time, pulled %d", maxInFlight)
+}
+
+// TestPrefetchImageHonorsImagePullConcurrency tests that prefetched images,
+// which include the pause image, wait for a pull slot like the pulls of tasks
+func TestPrefetchImageHonorsImagePullConcurrency(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.ImagePullConcurrency = 1
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	taskEngine := privateTaskEngine.(*DockerTaskEngine)
+	taskEngine.enableConcurrentPull = true
+
+	// Hold the only pull slot
+	taskEngine.pullSemaphore.acquire(0)
+	prefetched := make(chan error)
+	go func() {
+		prefetched <- taskEngine.prefetchImage("busybox:latest")
+	}()
+
+	for deadline := time.Now().Add(time.Second); taskEngine.pullSemaphore.numWaiters() == 0; {
+		require.True(t, time.Now().Before(deadline), "prefetch should wait for a pull slot")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	client.EXPECT().PullImage("busybox:latest", nil, "").Return(DockerContainerMetadata{})
+	taskEngine.pullSemaphore.release()
+	select {
+	case err := <-prefetched:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the image to be prefetched")
+	}
+}
+
 func TestEngineDisableConcurrentPull(t *testing.T) {
 	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
 	defer ctrl.Finish()
@@ -1285,7 +3040,7 @@ func TestPauseContainerHappyPath(t *testing.T) {
 
 	// For the other container
 	imageManager.EXPECT().AddAllImageStates(gomock.Any()).AnyTimes()
-	dockerClient.EXPECT().PullImage(gomock.Any(), nil).Return(DockerContainerMetadata{})
+	dockerClient.EXPECT().PullImage(gomock.Any(), nil, "").Return(DockerContainerMetadata{})
 	imageManager.EXPECT().RecordContainerReference(gomock.Any()).Return(nil)
 	imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(nil)
 	dockerClient.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(DockerContainerMetadata{DockerID: containerID})
@@ -1297,7 +3052,7 @@ func TestPauseContainerHappyPath(t *testing.T) {
 	cleanup := make(chan time.Time)
 	mockTime.EXPECT().Now().Do(func() time.Time { return time.Now() }).AnyTimes()
 	// Expect steady state check once
-	mockTime.EXPECT().After(steadyStateTaskVerifyInterval).Return(steadyStateVerify).MinTimes(1)
+	mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).MinTimes(1)
 	dockerClient.EXPECT().DescribeContainer(containerID).AnyTimes()
 	dockerClient.EXPECT().DescribeContainer(pauseContainerID).AnyTimes()
 
@@ -1463,19 +3218,340 @@ func TestStopPauseContainerCleanupCalled(t *testing.T) {
 		dockerClient.EXPECT().StopContainer(containerID, stopContainerTimeout).Return(DockerContainerMetadata{}),
 	)
 
-	taskEngine.(*DockerTaskEngine).stopContainer(testTask, pauseContainer)
+	taskEngine.(*DockerTaskEngine).stopContainer(testTask, pauseContainer)
+}
+
+// TestStopContainerWithContainerStopTimeout tests that the stop timeout of a
+// container reaches docker, up to the configured maximum
+func TestStopContainerWithContainerStopTimeout(t *testing.T) {
+	testCases := []struct {
+		name                string
+		stopTimeout         time.Duration
+		expectedGracePeriod time.Duration
+	}{
+		{"container stop timeout", 2 * time.Minute, 2 * time.Minute},
+		{"clamped to maximum", time.Hour, config.DefaultMaxContainerStopTimeout},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, dockerClient, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+			defer ctrl.Finish()
+
+			testTask := testdata.LoadTask("sleep5")
+			container := testTask.Containers[0]
+			container.StopTimeout = tc.stopTimeout
+			taskEngine.(*DockerTaskEngine).State().AddTask(testTask)
+			taskEngine.(*DockerTaskEngine).State().AddContainer(&api.DockerContainer{
+				DockerID:   containerID,
+				DockerName: dockerContainerName,
+				Container:  container,
+			}, testTask)
+
+			dockerClient.EXPECT().StopContainerWithGracePeriod(containerID, tc.expectedGracePeriod, stopContainerTimeout).Return(DockerContainerMetadata{})
+			taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+		})
+	}
+}
+
+// TestStopContainerWithoutContainerStopTimeout tests that containers without
+// a stop timeout are stopped with the stop timeout of the Agent
+func TestStopContainerWithoutContainerStopTimeout(t *testing.T) {
+	ctrl, dockerClient, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := testdata.LoadTask("sleep5")
+	container := testTask.Containers[0]
+	taskEngine.(*DockerTaskEngine).State().AddTask(testTask)
+	taskEngine.(*DockerTaskEngine).State().AddContainer(&api.DockerContainer{
+		DockerID:   containerID,
+		DockerName: dockerContainerName,
+		Container:  container,
+	}, testTask)
+
+	dockerClient.EXPECT().StopContainer(containerID, stopContainerTimeout).Return(DockerContainerMetadata{})
+	taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+}
+
+// TestStopContainerRecordsStopEscalation tests that the graceful stop attempts
+// of a container that had to be killed are recorded
+func TestStopContainerRecordsStopEscalation(t *testing.T) {
+	ctrl, dockerClient, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := testdata.LoadTask("sleep5")
+	container := testTask.Containers[0]
+	taskEngine.(*DockerTaskEngine).State().AddTask(testTask)
+	taskEngine.(*DockerTaskEngine).State().AddContainer(&api.DockerContainer{
+		DockerID:   containerID,
+		DockerName: dockerContainerName,
+		Container:  container,
+	}, testTask)
+
+	exitCode := killedExitCode
+	gomock.InOrder(
+		dockerClient.EXPECT().StopContainer(containerID, stopContainerTimeout).Return(
+			DockerContainerMetadata{Error: &DockerTimeoutError{stopContainerTimeout, "stopped"}}),
+		dockerClient.EXPECT().StopContainer(containerID, stopContainerTimeout).Return(
+			DockerContainerMetadata{ExitCode: &exitCode}),
+	)
+
+	taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+	assert.Equal(t, 1, container.GetStopAttempts())
+	assert.False(t, container.GetKilledOnStop(), "a stop that timed out did not kill the container")
+
+	taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+	assert.Equal(t, 2, container.GetStopAttempts())
+	assert.True(t, container.GetKilledOnStop())
+}
+
+// TestStopContainerGracefulExitNotKilled tests that containers that exit on
+// the stop signal are not recorded as killed
+func TestStopContainerGracefulExitNotKilled(t *testing.T) {
+	ctrl, dockerClient, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	testTask := testdata.LoadTask("sleep5")
+	container := testTask.Containers[0]
+	taskEngine.(*DockerTaskEngine).State().AddTask(testTask)
+	taskEngine.(*DockerTaskEngine).State().AddContainer(&api.DockerContainer{
+		DockerID:   containerID,
+		DockerName: dockerContainerName,
+		Container:  container,
+	}, testTask)
+
+	exitCode := 143
+	dockerClient.EXPECT().StopContainer(containerID, stopContainerTimeout).Return(DockerContainerMetadata{ExitCode: &exitCode})
+
+	taskEngine.(*DockerTaskEngine).stopContainer(testTask, container)
+	assert.Equal(t, 1, container.GetStopAttempts())
+	assert.False(t, container.GetKilledOnStop())
+}
+
+// TestTaskWithCircularDependency tests the task with containers of which the
+// dependencies can't be resolved
+func TestTaskWithCircularDependency(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	task := testdata.LoadTask("circular_dependency")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Expected task to move to stopped directly")
+	_, ok := taskEngine.(*DockerTaskEngine).state.TaskByArn(task.Arn)
+	assert.False(t, ok, "Rejected task should not be added to the agent state")
+
+	_, ok = taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+// TestTaskWithConflictingHostPorts tests that a task requesting a static host
+// port that is allocated to another task is stopped
+func TestTaskWithConflictingHostPorts(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	runningTask := testdata.LoadTask("sleep5")
+	runningTask.Arn = "running"
+	runningTask.Containers[0].Ports = []api.PortBinding{{ContainerPort: 80, HostPort: 8080}}
+	runningTask.SetKnownStatus(api.TaskRunning)
+
+	task := testdata.LoadTask("sleep5")
+	task.Containers[0].Ports = []api.PortBinding{{ContainerPort: 8000, HostPort: 8080}}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	// The running task is added after the engine is initialized, so that the
+	// engine does not start managing it
+	state := taskEngine.(*DockerTaskEngine).state
+	state.AddTask(runningTask)
+	require.NoError(t, state.AllocateHostPorts(runningTask))
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "RESOURCE_CONFLICT")
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+// TestTaskWithoutENICapacity tests that an awsvpc task is stopped when all of
+// the ENI slots of the instance are in use by other tasks
+func TestTaskWithoutENICapacity(t *testing.T) {
+	cfg := defaultConfig
+	cfg.TaskENISlots = 1
+	cfg.ENICapacityFailFastEnabled = true
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	runningTask := testdata.LoadTask("sleep5")
+	runningTask.Arn = "running"
+	runningTask.SetTaskENI(&api.ENI{ID: "eni-running", MacAddress: "mac-running"})
+	runningTask.SetKnownStatus(api.TaskRunning)
+
+	task := testdata.LoadTask("sleep5")
+	task.SetTaskENI(&api.ENI{ID: "eni-new", MacAddress: "mac-new"})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	// The running task is added after the engine is initialized, so that the
+	// engine does not start managing it
+	taskEngine.(*DockerTaskEngine).state.AddTask(runningTask)
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "no ENI capacity")
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+// TestTaskWithRefusedENIAttachment tests that a task whose ENI attachment is
+// refused, as the maximum number of tracked ENIs is reached, is stopped with a
+// reason
+func TestTaskWithRefusedENIAttachment(t *testing.T) {
+	cfg := defaultConfig
+	cfg.MaxTrackedENIs = 1
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	task := testdata.LoadTask("sleep5")
+	task.SetTaskENI(&api.ENI{ID: "eni-new", MacAddress: "mac-new"})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	taskEngine.(*DockerTaskEngine).state.AddENIAttachment(&api.ENIAttachment{MACAddress: "mac-running"})
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "ENI attachment refused")
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+func TestTaskWithoutContainers(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	task := &api.Task{
+		Arn:                 "myTaskArn",
+		DesiredStatusUnsafe: api.TaskRunning,
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Equal(t, NoContainersError{task.Arn}.Error(), taskEvent.Reason)
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+// TestTaskWithInvalidIPCMode tests that a task that uses the IPC namespace of
+// the host with the awsvpc network mode is stopped with a clear reason
+func TestTaskWithInvalidIPCMode(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	task := &api.Task{
+		Arn:                 "myTaskArn",
+		DesiredStatusUnsafe: api.TaskRunning,
+		IPCMode:             api.IPCModeHost,
+		ENI:                 &api.ENI{ID: "eniID"},
+		Containers:          []*api.Container{{Name: "c1", Essential: true}},
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "Invalid task definition")
+	assert.Contains(t, taskEvent.Reason, "awsvpc")
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
 }
 
-// TestTaskWithCircularDependency tests the task with containers of which the
-// dependencies can't be resolved
-func TestTaskWithCircularDependency(t *testing.T) {
+// TestTaskWithUnsupportedIPCMode tests that a task whose containers share
+// one IPC namespace is stopped with a clear reason when docker is too old to
+// make the IPC namespace of a container shareable, without being left in the
+// state
+func TestTaskWithUnsupportedIPCMode(t *testing.T) {
 	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
 	defer ctrl.Finish()
 
 	client.EXPECT().Version().Return("1.12.6", nil)
 	client.EXPECT().ContainerEvents(gomock.Any())
+	client.EXPECT().KnownVersions().Return([]dockerclient.DockerVersion{dockerclient.Version_1_24, dockerclient.Version_1_29})
 
-	task := testdata.LoadTask("circular_dependency")
+	task := &api.Task{
+		Arn:                 "myTaskArn",
+		DesiredStatusUnsafe: api.TaskRunning,
+		IPCMode:             api.IPCModeTask,
+		Containers:          []*api.Container{{Name: "c1", Essential: true}},
+	}
 
 	ctx, cancel := context.WithCancel(context.TODO())
 	err := taskEngine.Init(ctx)
@@ -1486,12 +3562,62 @@ func TestTaskWithCircularDependency(t *testing.T) {
 	go taskEngine.AddTask(task)
 
 	event := <-events
-	assert.Equal(t, event.(api.TaskStateChange).Status, api.TaskStopped, "Expected task to move to stopped directly")
-	_, ok := taskEngine.(*DockerTaskEngine).state.TaskByArn(task.Arn)
-	assert.True(t, ok, "Task state should be added to the agent state")
-
-	_, ok = taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Equal(t, UnsupportedIPCModeError{
+		taskArn:         task.Arn,
+		ipcMode:         api.IPCModeTask,
+		requiredVersion: dockerclient.Version_1_30,
+	}.Error(), taskEvent.Reason)
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
 	assert.False(t, ok, "Task should not be added to task manager for processing")
+	_, ok = taskEngine.(*DockerTaskEngine).state.TaskByArn(task.Arn)
+	assert.False(t, ok, "Rejected task should not be added to the agent state")
+}
+
+func TestCheckIPCModeSupport(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	assert.NoError(t, engine.checkIPCModeSupport(&api.Task{IPCMode: api.IPCModeHost}))
+	assert.NoError(t, engine.checkIPCModeSupport(&api.Task{IPCMode: api.IPCModeNone}))
+
+	client.EXPECT().KnownVersions().Return([]dockerclient.DockerVersion{dockerclient.Version_1_29, dockerclient.Version_1_30})
+	assert.NoError(t, engine.checkIPCModeSupport(&api.Task{IPCMode: api.IPCModeTask}))
+}
+
+func TestCheckENICapacity(t *testing.T) {
+	cfg := defaultConfig
+	cfg.TaskENISlots = 2
+	cfg.ENICapacityFailFastEnabled = true
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	stoppedTask := &api.Task{Arn: "stopped"}
+	stoppedTask.SetTaskENI(&api.ENI{ID: "eni-stopped"})
+	stoppedTask.SetKnownStatus(api.TaskStopped)
+	engine.state.AddTask(stoppedTask)
+	runningTask := &api.Task{Arn: "running"}
+	runningTask.SetTaskENI(&api.ENI{ID: "eni-running"})
+	runningTask.SetKnownStatus(api.TaskRunning)
+	engine.state.AddTask(runningTask)
+
+	task := &api.Task{Arn: "new"}
+	task.SetTaskENI(&api.ENI{ID: "eni-new"})
+	assert.NoError(t, engine.checkENICapacity(task), "stopped tasks should not use ENI slots")
+	assert.NoError(t, engine.checkENICapacity(&api.Task{Arn: "bridge"}), "tasks without ENIs should not need ENI slots")
+
+	otherTask := &api.Task{Arn: "other"}
+	otherTask.SetTaskENI(&api.ENI{ID: "eni-other"})
+	otherTask.SetKnownStatus(api.TaskCreated)
+	engine.state.AddTask(otherTask)
+	assert.Error(t, engine.checkENICapacity(task))
+
+	engine.cfg.ENICapacityFailFastEnabled = false
+	assert.NoError(t, engine.checkENICapacity(task))
 }
 
 // TestCreateContainerOnAgentRestart tests when agent restarts it should use the
@@ -1554,11 +3680,490 @@ func TestPullNormalImage(t *testing.T) {
 		Image: &image.Image{ImageID: "id"},
 	}
 
-	client.EXPECT().PullImage(imageName, nil)
+	client.EXPECT().PullImage(imageName, nil, "")
 	imageManager.EXPECT().RecordContainerReference(container)
 	imageManager.EXPECT().GetImageStateFromImageName(imageName).Return(imageState)
 	saver.EXPECT().Save()
 
 	metadata := taskEngine.pullContainer(task, container)
 	assert.Equal(t, DockerContainerMetadata{}, metadata, "expected empty metadata")
+	assert.True(t, taskEngine.pendingCreateImages.isReferenced([]string{imageName}),
+		"Expected image to be referenced until the container is created")
+}
+
+func TestPullNormalImageFailureReleasesImageReference(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	imageName := "image"
+	container := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: imageName,
+	}
+	task := &api.Task{
+		Containers: []*api.Container{container},
+	}
+
+	client.EXPECT().PullImage(imageName, nil, "").Return(DockerContainerMetadata{
+		Error: CannotPullContainerError{errors.New("error")},
+	})
+	imageManager.EXPECT().RecordContainerReference(container)
+	imageManager.EXPECT().GetImageStateFromImageName(imageName)
+	saver.EXPECT().Save()
+
+	metadata := taskEngine.pullContainer(task, container)
+	assert.Error(t, metadata.Error)
+	assert.False(t, taskEngine.pendingCreateImages.isReferenced([]string{imageName}),
+		"Expected image reference to be released after the pull failed")
+}
+
+func TestImageReferencesSharedWithImageManager(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := NewMockDockerClient(ctrl)
+	state := dockerstate.NewTaskEngineState()
+
+	imageManager := NewImageManager(&defaultConfig, client, state)
+	taskEngine := NewDockerTaskEngine(&defaultConfig, client, nil, nil, imageManager, state)
+	assert.True(t, taskEngine.pendingCreateImages == imageManager.(*dockerImageManager).pendingCreateImages,
+		"Expected the task engine to use the image references of the image manager")
+}
+
+func TestPullContainerRecordsPullLockWait(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	imageName := "image"
+	container := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: imageName,
+	}
+	task := &api.Task{
+		Containers: []*api.Container{container},
+	}
+
+	client.EXPECT().PullImage(imageName, nil, "")
+	imageManager.EXPECT().RecordContainerReference(container)
+	imageManager.EXPECT().GetImageStateFromImageName(imageName).Return(&image.ImageState{
+		Image: &image.Image{ImageID: "id"},
+	})
+	saver.EXPECT().Save()
+
+	// Hold the lock the way image cleanup does, so that the pull blocks
+	ImagePullDeleteLock.Lock()
+	pulled := make(chan DockerContainerMetadata)
+	go func() {
+		pulled <- taskEngine.pullContainer(task, container)
+	}()
+
+	for i := 0; container.GetPullLockWaitStart().IsZero(); i++ {
+		if i == 1000 {
+			ImagePullDeleteLock.Unlock()
+			t.Fatal("Timed out waiting for the pull to wait on the pull lock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ImagePullDeleteLock.Unlock()
+
+	metadata := <-pulled
+	assert.Equal(t, DockerContainerMetadata{}, metadata, "expected empty metadata")
+	assert.True(t, container.GetPullLockWaitStart().IsZero(), "pull lock wait should be cleared once the lock is acquired")
+}
+
+func TestPullImageSkippedWhenPulledBeforeWithOncePullBehavior(t *testing.T) {
+	ctrl, _, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{
+		ImagePullBehavior: config.ImagePullBehaviorOnce,
+	})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	imageName := "image"
+	container := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: imageName,
+	}
+	task := &api.Task{
+		Containers: []*api.Container{container},
+	}
+	imageState := &image.ImageState{
+		Image: &image.Image{ImageID: "id"},
+	}
+
+	imageManager.EXPECT().GetImageStateFromImageName(imageName).Return(imageState).Times(2)
+	imageManager.EXPECT().RecordContainerReference(container)
+	saver.EXPECT().Save()
+
+	metadata := taskEngine.pullContainer(task, container)
+	assert.Equal(t, DockerContainerMetadata{}, metadata, "expected empty metadata")
+	assert.Contains(t, container.GetImagePullSkipReason(), config.ImagePullBehaviorOnce)
+}
+
+func TestPullImageWithPreferCachedPullBehavior(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{
+		ImagePullBehavior: config.ImagePullBehaviorPreferCached,
+	})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	cachedContainer := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: "cached",
+	}
+	missingContainer := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: "missing",
+	}
+	task := &api.Task{
+		Containers: []*api.Container{cachedContainer, missingContainer},
+	}
+	imageState := &image.ImageState{
+		Image: &image.Image{ImageID: "id"},
+	}
+
+	client.EXPECT().InspectImage("cached").Return(&docker.Image{ID: "id"}, nil)
+	client.EXPECT().InspectImage("missing").Return(nil, errors.New("no such image"))
+	client.EXPECT().PullImage("missing", nil, "").Return(DockerContainerMetadata{ImagePullSource: api.ImagePullSourceRegistry})
+	imageManager.EXPECT().RecordContainerReference(gomock.Any()).Times(2)
+	imageManager.EXPECT().GetImageStateFromImageName(gomock.Any()).Return(imageState).Times(2)
+	saver.EXPECT().Save().Times(2)
+
+	metadata := taskEngine.pullContainer(task, cachedContainer)
+	assert.Equal(t, DockerContainerMetadata{}, metadata, "expected empty metadata")
+	assert.Contains(t, cachedContainer.GetImagePullSkipReason(), config.ImagePullBehaviorPreferCached)
+	assert.Equal(t, api.ImagePullSourceCacheHit, cachedContainer.GetImagePullSource())
+
+	metadata = taskEngine.pullContainer(task, missingContainer)
+	assert.NoError(t, metadata.Error)
+	assert.Empty(t, missingContainer.GetImagePullSkipReason(), "images that are not cached should be pulled")
+	assert.Equal(t, api.ImagePullSourceRegistry, missingContainer.GetImagePullSource())
+}
+
+func TestPullImageRetriedAfterDiskFullCleanup(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{
+		ImagePullDiskFullCleanupEnabled: true,
+	})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	container := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: "image",
+	}
+	task := &api.Task{
+		Containers: []*api.Container{container},
+	}
+	imageState := &image.ImageState{
+		Image: &image.Image{ImageID: "id"},
+	}
+	diskFullErr := CannotPullContainerError{errors.New("write /var/lib/docker/tmp/layer: no space left on device")}
+
+	imageManager.EXPECT().RecordContainerReference(container).Times(2)
+	imageManager.EXPECT().GetImageStateFromImageName("image").Return(imageState).Times(2)
+	saver.EXPECT().Save().Times(2)
+	gomock.InOrder(
+		client.EXPECT().PullImage("image", nil, "").Return(DockerContainerMetadata{Error: diskFullErr}),
+		imageManager.EXPECT().RemoveUnusedImages(),
+		client.EXPECT().PullImage("image", nil, "").Return(DockerContainerMetadata{}),
+	)
+
+	metadata := taskEngine.pullContainer(task, container)
+	assert.NoError(t, metadata.Error, "pull should succeed once unused images are removed")
+}
+
+func TestPullImageFailsWhenDiskStillFullAfterCleanup(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, imageManager := mocks(t, &config.Config{
+		ImagePullDiskFullCleanupEnabled: true,
+	})
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+	saver := mock_statemanager.NewMockStateManager(ctrl)
+	taskEngine.SetSaver(saver)
+
+	container := &api.Container{
+		Type:  api.ContainerNormal,
+		Image: "image",
+	}
+	task := &api.Task{
+		Containers: []*api.Container{container},
+	}
+	imageState := &image.ImageState{
+		Image: &image.Image{ImageID: "id"},
+	}
+	diskFullErr := CannotPullContainerError{errors.New("no space left on device")}
+
+	imageManager.EXPECT().RecordContainerReference(container).Times(2)
+	imageManager.EXPECT().GetImageStateFromImageName("image").Return(imageState).Times(2)
+	saver.EXPECT().Save().Times(2)
+	imageManager.EXPECT().RemoveUnusedImages()
+	client.EXPECT().PullImage("image", nil, "").Return(DockerContainerMetadata{Error: diskFullErr}).Times(2)
+
+	metadata := taskEngine.pullContainer(task, container)
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "CannotPullContainerDiskFullError", metadata.Error.ErrorName())
+}
+
+func startContainerRetryTask() *api.Task {
+	container := &api.Container{Name: "c1", Image: "image"}
+	return &api.Task{
+		Arn:        "myTaskArn",
+		Containers: []*api.Container{container},
+	}
+}
+
+func TestStartContainerRetriesTransientFailures(t *testing.T) {
+	ctrl, client, mockTime, privateTaskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	task := startContainerRetryTask()
+	container := task.Containers[0]
+	taskEngine.State().AddTask(task)
+	taskEngine.State().AddContainer(&api.DockerContainer{DockerID: containerID, DockerName: "dockerName", Container: container}, task)
+
+	transientErr := CannotStartContainerError{&docker.Error{Status: 503}}
+	gomock.InOrder(
+		client.EXPECT().StartContainer(containerID, startContainerTimeout).Return(
+			DockerContainerMetadata{Error: transientErr}).Times(2),
+		client.EXPECT().StartContainer(containerID, startContainerTimeout).Return(
+			DockerContainerMetadata{DockerID: containerID}),
+	)
+	mockTime.EXPECT().Sleep(gomock.Any()).Times(2)
+
+	metadata := taskEngine.startContainer(task, container)
+	assert.NoError(t, metadata.Error)
+	assert.Equal(t, 3, container.GetStartAttempts())
+
+	container.SetKnownStatus(api.ContainerRunning)
+	go taskEngine.emitContainerEvent(task, container, "")
+	event := <-taskEngine.StateChangeEvents()
+	containerEvent, ok := event.(api.ContainerStateChange)
+	require.True(t, ok)
+	assert.Equal(t, 3, containerEvent.StartAttempts)
+}
+
+func TestStartContainerGivesUpAfterMaxAttempts(t *testing.T) {
+	ctrl, client, mockTime, privateTaskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	task := startContainerRetryTask()
+	container := task.Containers[0]
+	taskEngine.State().AddTask(task)
+	taskEngine.State().AddContainer(&api.DockerContainer{DockerID: containerID, DockerName: "dockerName", Container: container}, task)
+
+	client.EXPECT().StartContainer(containerID, startContainerTimeout).Return(
+		DockerContainerMetadata{Error: CannotStartContainerError{docker.ErrConnectionRefused}}).Times(startContainerMaxAttempts)
+	mockTime.EXPECT().Sleep(gomock.Any()).Times(startContainerMaxAttempts - 1)
+
+	metadata := taskEngine.startContainer(task, container)
+	assert.Error(t, metadata.Error)
+	assert.Equal(t, startContainerMaxAttempts, container.GetStartAttempts())
+}
+
+func TestStartContainerDoesNotRetryNonRetriableFailures(t *testing.T) {
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	task := startContainerRetryTask()
+	container := task.Containers[0]
+	taskEngine.State().AddTask(task)
+	taskEngine.State().AddContainer(&api.DockerContainer{DockerID: containerID, DockerName: "dockerName", Container: container}, task)
+
+	client.EXPECT().StartContainer(containerID, startContainerTimeout).Return(
+		DockerContainerMetadata{Error: CannotStartContainerError{&docker.NoSuchContainer{ID: containerID}}})
+
+	metadata := taskEngine.startContainer(task, container)
+	assert.Error(t, metadata.Error)
+	assert.Equal(t, 1, container.GetStartAttempts())
+}
+
+func TestDrainStopsTasksAndRejectsNewTasks(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	managedTasks := make(map[string]*managedTask)
+	for _, arn := range []string{"task1", "task2", "stoppingTask"} {
+		task := &api.Task{Arn: arn, DesiredStatusUnsafe: api.TaskRunning}
+		mTask := &managedTask{Task: task, acsMessages: make(chan acsTransition, 1)}
+		dockerTaskEngine.managedTasks[arn] = mTask
+		managedTasks[arn] = mTask
+	}
+	managedTasks["stoppingTask"].SetDesiredStatus(api.TaskStopped)
+
+	taskEngine.Drain()
+
+	for _, arn := range []string{"task1", "task2"} {
+		select {
+		case transition := <-managedTasks[arn].acsMessages:
+			assert.Equal(t, api.TaskStopped, transition.desiredStatus, "Wrong desired status for %s", arn)
+		default:
+			t.Errorf("Expected %s to be stopped", arn)
+		}
+	}
+	assert.Len(t, managedTasks["stoppingTask"].acsMessages, 0, "Stopping task should not be stopped again")
+
+	newTask := &api.Task{
+		Arn:                 "newTask",
+		DesiredStatusUnsafe: api.TaskRunning,
+		Containers:          []*api.Container{{Name: "c1", Essential: true}},
+	}
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(newTask)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "draining")
+
+	_, ok := dockerTaskEngine.managedTasks[newTask.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
+
+func TestDrainDoesNotHoldLockWhileStoppingTasks(t *testing.T) {
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	task := &api.Task{Arn: "task1", DesiredStatusUnsafe: api.TaskRunning}
+	mTask := &managedTask{Task: task, acsMessages: make(chan acsTransition)}
+	dockerTaskEngine.managedTasks[task.Arn] = mTask
+
+	drained := make(chan struct{})
+	go func() {
+		taskEngine.Drain()
+		close(drained)
+	}()
+
+	// The task manager is busy and needs the lock, once the engine is
+	// draining, before it receives
+	locked := make(chan struct{})
+	go func() {
+		for draining := false; !draining; {
+			dockerTaskEngine.processTasks.RLock()
+			draining = dockerTaskEngine.draining
+			dockerTaskEngine.processTasks.RUnlock()
+		}
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the lock to be available while draining")
+	}
+
+	transition := <-mTask.acsMessages
+	assert.Equal(t, api.TaskStopped, transition.desiredStatus)
+	<-drained
+}
+
+func TestPauseHoldsNewTasksUntilResumed(t *testing.T) {
+	ctrl, _, mockTime, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+	// The task waits to be cleaned up once it is stopped
+	mockTime.EXPECT().After(gomock.Any()).AnyTimes()
+
+	taskEngine.Pause()
+	assert.True(t, taskEngine.Paused())
+
+	task := testdata.LoadTask("sleep5")
+	require.NoError(t, taskEngine.AddTask(task))
+	_, ok := dockerTaskEngine.State().TaskByArn(task.Arn)
+	assert.True(t, ok, "Held task should be added to the state")
+	_, ok = dockerTaskEngine.managedTasks[task.Arn]
+	assert.False(t, ok, "Held task should not be started")
+
+	// Updates of the held task are applied without starting it
+	update := testdata.LoadTask("sleep5")
+	update.SetDesiredStatus(api.TaskStopped)
+	require.NoError(t, taskEngine.AddTask(update))
+	assert.Equal(t, api.TaskStopped, task.GetDesiredStatus())
+	_, ok = dockerTaskEngine.managedTasks[task.Arn]
+	assert.False(t, ok, "Held task should not be started")
+
+	// The held task is started on resume. No docker calls are expected, it
+	// is stopped before any of its containers is created
+	taskEngine.Resume()
+	assert.False(t, taskEngine.Paused())
+	assert.Empty(t, dockerTaskEngine.heldTasks)
+	dockerTaskEngine.processTasks.RLock()
+	_, ok = dockerTaskEngine.managedTasks[task.Arn]
+	dockerTaskEngine.processTasks.RUnlock()
+	assert.True(t, ok, "Held task should be started once the engine is resumed")
+	for event := range taskEngine.StateChangeEvents() {
+		if taskEvent, ok := event.(api.TaskStateChange); ok {
+			assert.Equal(t, api.TaskStopped, taskEvent.Status)
+			break
+		}
+	}
+}
+
+func TestSteadyStatePollContinuesWhilePaused(t *testing.T) {
+	ctrl, client, mockTime, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	dockerTaskEngine := taskEngine.(*DockerTaskEngine)
+
+	client.EXPECT().Version()
+	client.EXPECT().ContainerEvents(gomock.Any())
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	require.NoError(t, taskEngine.Init(ctx))
+
+	// A task that is running when the engine is paused
+	task := testdata.LoadTask("sleep5")
+	task.SetKnownStatus(api.TaskRunning)
+	task.SetSentStatus(api.TaskRunning)
+	container := task.Containers[0]
+	container.SetKnownStatus(api.ContainerRunning)
+	container.SetSentStatus(api.ContainerRunning)
+	dockerTaskEngine.State().AddTask(task)
+	dockerTaskEngine.State().AddContainer(&api.DockerContainer{DockerID: containerID, DockerName: "dockerName", Container: container}, task)
+
+	steadyStateVerify := make(chan time.Time, 1)
+	mockTime.EXPECT().After(config.DefaultSteadyStateVerifyInterval).Return(steadyStateVerify).AnyTimes()
+	polled := make(chan struct{})
+	client.EXPECT().DescribeContainer(containerID).Do(func(string) {
+		close(polled)
+	}).Return(api.ContainerRunning, DockerContainerMetadata{DockerID: containerID})
+
+	dockerTaskEngine.processTasks.Lock()
+	dockerTaskEngine.startTask(task)
+	dockerTaskEngine.processTasks.Unlock()
+	taskEngine.Pause()
+
+	// New tasks are held, and not pulled
+	newTask := testdata.LoadTask("sleep5")
+	newTask.Arn = "newTask"
+	require.NoError(t, taskEngine.AddTask(newTask))
+	assert.Contains(t, dockerTaskEngine.heldTasks, newTask.Arn)
+
+	// The steady state of the running task is still verified
+	steadyStateVerify <- time.Now()
+	select {
+	case <-polled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the steady state of the task to be verified while paused")
+	}
 }
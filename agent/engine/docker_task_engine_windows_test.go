@@ -42,7 +42,7 @@ func TestPullEmptyVolumeImage(t *testing.T) {
 	}
 
 	assert.False(t, emptyvolume.LocalImage, "Windows empty volume image is not local")
-	client.EXPECT().PullImage(imageName, nil)
+	client.EXPECT().PullImage(imageName, nil, "")
 
 	metadata := taskEngine.pullContainer(task, container)
 	assert.Equal(t, DockerContainerMetadata{}, metadata, "expected empty metadata")
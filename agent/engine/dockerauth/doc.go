@@ -23,6 +23,13 @@ keys to values indicated below.
 
 These keys may be set by either setting the environment variables
 "ECS_ENGINE_AUTH_TYPE" and "ECS_ENGINE_AUTH_DATA" or by setting the keys "EngineAuthData" and "EngineAuthType" in the JSON configuration file located at the configured "ECS_AGENT_CONFIG_FILE_PATH" (see http://godoc.org/github.com/aws/amazon-ecs-agent/agent/config)
+The auth data may also be read from a file, such as a mounted secret, named by
+the environment variable "ECS_ENGINE_AUTH_DATA_FILE".
+
+The auth data used for an image is that of the longest key that is a prefix of
+its repository, e.g. "my.registry.example.com/team" is used over
+"my.registry.example.com" for "my.registry.example.com/team/image". Images of
+registries without auth data are pulled anonymously.
 
 Auth Types
 
@@ -63,7 +63,7 @@ func (authProvider *dockerAuthProvider) GetAuthconfig(image string) (docker.Auth
 	authConfigKey := indexName
 
 	// Take a direct match of the index hostname as a sane default
-	if _, found := authDataMap[authConfigKey]; found && len(authConfigKey) > len(longestKey) {
+	if _, found := authDataMap[authConfigKey]; found {
 		longestKey = authConfigKey
 	}
 
@@ -72,13 +72,12 @@ func (authProvider *dockerAuthProvider) GetAuthconfig(image string) (docker.Auth
 		hostname := nameParts[0]
 
 		// Only ever take a new key if the hostname matches in normal cases
-		if authConfigKey == hostname {
-			if longestKey == "" {
-				longestKey = registry
-			} else if len(registry) > len(longestKey) && strings.HasPrefix(image, registry) {
-				// If we have a longer match, that indicates a username / namespace appended
-				longestKey = registry
-			}
+		if authConfigKey != hostname || registry == authConfigKey {
+			continue
+		}
+		// A longer match indicates a username / namespace appended
+		if isRepositoryPrefix(repository, registry) && len(registry) > len(longestKey) {
+			longestKey = registry
 		}
 	}
 	if longestKey != "" {
@@ -87,6 +86,14 @@ func (authProvider *dockerAuthProvider) GetAuthconfig(image string) (docker.Auth
 	return docker.AuthConfiguration{}, nil
 }
 
+// isRepositoryPrefix returns whether the registry key is a prefix of the
+// repository made of whole path components, e.g. 'registry.tld/user' is a
+// prefix of 'registry.tld/user/image' but not of 'registry.tld/username/image'
+func isRepositoryPrefix(repository, registry string) bool {
+	registry = strings.TrimSuffix(registry, "/")
+	return repository == registry || strings.HasPrefix(repository, registry+"/")
+}
+
 // Normalize all auth types into a uniform 'dockerAuths' type.
 // On error, any appropriate information will be logged and an empty dockerAuths will be returned
 func parseAuthData(authType string, authData json.RawMessage) dockerAuths {
@@ -88,8 +88,9 @@ func TestAuthAppliesToOnlyRegistry(t *testing.T) {
 	}`)
 
 	var expectedPairs = []authTestPair{
-		// '/user2' should apply here because of matching hostname
-		{"example.tld/foo", "user", "swordfish"},
+		{"example.tld/user2/foo", "user", "swordfish"},
+		// '/user2' is not a prefix of the repository, so pull anonymously
+		{"example.tld/foo", "", ""},
 		{"registry.tld", "", ""},
 		{"nginx", "", ""},
 	}
@@ -104,6 +105,37 @@ func TestAuthAppliesToOnlyRegistry(t *testing.T) {
 	}
 }
 
+func TestAuthLongestRepositoryPrefix(t *testing.T) {
+	authData := []byte(`{
+		"registry.tld/team":{"username":"team","password":"a"},
+		"registry.tld/team/app":{"username":"app","password":"b"},
+		"registry.tld/teams":{"username":"teams","password":"c"},
+		"registry.tld":{"username":"registry","password":"d"}
+	}`)
+
+	var expectedPairs = []authTestPair{
+		{"registry.tld/team/app", "app", "b"},
+		{"registry.tld/team/app:latest", "app", "b"},
+		{"registry.tld/team/app/worker", "app", "b"},
+		{"registry.tld/team/application", "team", "a"},
+		{"registry.tld/teams/image", "teams", "c"},
+		{"registry.tld/teamsters/image", "registry", "d"},
+		{"other.tld/team/app", "", ""},
+		{"nginx", "", ""},
+	}
+
+	provider := NewDockerAuthProvider("docker", authData)
+	// Repeat lookups as map iteration order varies between them
+	for i := 0; i < 10; i++ {
+		for ndx, pair := range expectedPairs {
+			authConfig, _ := provider.GetAuthconfig(pair.Image)
+			if authConfig.Username != pair.ExpectedUser || authConfig.Password != pair.ExpectedPass {
+				t.Errorf("Expectation failure: #%v. Got %v, wanted %v", ndx, authConfig, pair)
+			}
+		}
+	}
+}
+
 func TestAuthErrors(t *testing.T) {
 	badPairs := []struct {
 		t string
@@ -16,6 +16,7 @@ package dockerauth
 import (
 	"encoding/base64"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
@@ -33,6 +34,21 @@ type ecrAuthProvider struct {
 
 const proxyEndpointScheme = "https://"
 
+// ecrImagePattern matches the repositories of ECR registries, e.g.
+// 123456789012.dkr.ecr.us-west-2.amazonaws.com/image, capturing the id of the
+// registry and its region
+var ecrImagePattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/`)
+
+// ECRAuthDataForImage returns the auth data to pull the image from ECR with
+// the credentials of the instance, if the image is in an ECR registry
+func ECRAuthDataForImage(image string) (*api.ECRAuthData, bool) {
+	matches := ecrImagePattern.FindStringSubmatch(image)
+	if matches == nil {
+		return nil, false
+	}
+	return &api.ECRAuthData{RegistryID: matches[1], Region: matches[2]}, true
+}
+
 // NewECRAuthProvider returns a DockerAuthProvider that can handle retrieve
 // credentials for pulling from Amazon EC2 Container Registry
 func NewECRAuthProvider(authData *api.ECRAuthData, clientFactory ecr.ECRFactory) DockerAuthProvider {
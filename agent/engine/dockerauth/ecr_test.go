@@ -245,3 +245,34 @@ func TestGetAuthConfigNoAuthData(t *testing.T) {
 		t.Fatalf("Expected Authconfig to be empty, but was %v", authconfig)
 	}
 }
+
+func TestECRAuthDataForImage(t *testing.T) {
+	testCases := []struct {
+		image      string
+		registryID string
+		region     string
+		isECR      bool
+	}{
+		{"123456789012.dkr.ecr.us-west-2.amazonaws.com/image:tag", "123456789012", "us-west-2", true},
+		{"123456789012.dkr.ecr-fips.us-east-1.amazonaws.com/team/image", "123456789012", "us-east-1", true},
+		{"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/image", "123456789012", "cn-north-1", true},
+		{"123456789012.dkr.ecr.us-west-2.amazonaws.com.example.com/image", "", "", false},
+		{"12345.dkr.ecr.us-west-2.amazonaws.com/image", "", "", false},
+		{"registry.tld/image", "", "", false},
+		{"nginx", "", "", false},
+	}
+
+	for _, testCase := range testCases {
+		authData, ok := ECRAuthDataForImage(testCase.image)
+		if ok != testCase.isECR {
+			t.Errorf("Expected ECR image to be %t for %s", testCase.isECR, testCase.image)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if authData.RegistryID != testCase.registryID || authData.Region != testCase.region {
+			t.Errorf("Wrong auth data for %s: %+v", testCase.image, authData)
+		}
+	}
+}
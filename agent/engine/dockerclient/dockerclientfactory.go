@@ -14,11 +14,16 @@
 package dockerclient
 
 import (
+	"context"
 	"errors"
+	"net"
+	"net/http"
+	"net/url"
 
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface"
 	log "github.com/cihub/seelog"
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/hashicorp/go-cleanhttp"
 )
 
 // Factory provides a collection of docker remote clients that include a
@@ -49,6 +54,11 @@ type Factory interface {
 	FindKnownAPIVersions() []DockerVersion
 }
 
+// unixSocketHTTPEndpoint is the endpoint of pooled clients for unix socket
+// endpoints. Its host is never resolved, as the connections of such clients are
+// all dialed to the socket
+const unixSocketHTTPEndpoint = "http://docker.sock"
+
 type factory struct {
 	endpoint string
 	clients  map[DockerVersion]dockeriface.Client
@@ -62,9 +72,17 @@ var newVersionedClient = func(endpoint, version string) (dockeriface.Client, err
 
 // NewFactory initializes a client factory using a specified endpoint.
 func NewFactory(endpoint string) Factory {
+	return NewFactoryWithPoolSize(endpoint, 0)
+}
+
+// NewFactoryWithPoolSize initializes a client factory using a specified
+// endpoint. If poolSize is greater than zero, the clients keep up to poolSize
+// idle connections to the Docker daemon open for reuse instead of opening a
+// new connection for every request.
+func NewFactoryWithPoolSize(endpoint string, poolSize int) Factory {
 	return &factory{
 		endpoint: endpoint,
-		clients:  findDockerVersions(endpoint),
+		clients:  findDockerVersions(endpoint, poolSize),
 	}
 }
 
@@ -109,10 +127,16 @@ func (f *factory) getClient(version DockerVersion) (dockeriface.Client, error) {
 
 // findDockerVersions loops over all known API versions and finds which ones
 // are supported by the docker daemon on the host
-func findDockerVersions(endpoint string) map[DockerVersion]dockeriface.Client {
+func findDockerVersions(endpoint string, poolSize int) map[DockerVersion]dockeriface.Client {
 	clients := make(map[DockerVersion]dockeriface.Client)
 	for _, version := range getKnownAPIVersions() {
-		client, err := newVersionedClient(endpoint, string(version))
+		var client dockeriface.Client
+		var err error
+		if poolSize > 0 {
+			client, err = newPooledVersionedClient(endpoint, string(version), poolSize)
+		} else {
+			client, err = newVersionedClient(endpoint, string(version))
+		}
 		if err != nil {
 			log.Infof("Error while creating client: %v", err)
 			continue
@@ -126,3 +150,64 @@ func findDockerVersions(endpoint string) map[DockerVersion]dockeriface.Client {
 	}
 	return clients
 }
+
+// newPooledVersionedClient returns a client whose transport keeps up to
+// poolSize idle connections open to the Docker daemon. go-dockerclient sends
+// the requests to unix socket endpoints over a transport of its own that
+// can't be replaced, so clients for such endpoints are created for an http
+// endpoint instead and dial all their connections to the socket
+func newPooledVersionedClient(endpoint, version string, poolSize int) (dockeriface.Client, error) {
+	socketPath := ""
+	endpointURL, err := url.Parse(endpoint)
+	if err == nil && endpointURL.Scheme == "unix" {
+		socketPath = endpointURL.Path
+		endpoint = unixSocketHTTPEndpoint
+	}
+	client, err := newVersionedClient(endpoint, version)
+	if err != nil {
+		return nil, err
+	}
+	dockerClient, ok := client.(*docker.Client)
+	if !ok {
+		return client, nil
+	}
+	dockerClient.HTTPClient = newPooledHTTPClient(poolSize, socketPath)
+	if socketPath != "" {
+		// Streams that are hijacked from requests, e.g. to attach to
+		// containers, are dialed by the client itself
+		dockerClient.Dialer = &socketDialer{path: socketPath}
+	}
+	return dockerClient, nil
+}
+
+// newPooledHTTPClient returns an http client whose transport keeps up to
+// poolSize idle connections open to the Docker daemon. The connections are
+// dialed to the unix socket at socketPath if it isn't empty
+func newPooledHTTPClient(poolSize int, socketPath string) *http.Client {
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.MaxIdleConns = poolSize
+	transport.MaxIdleConnsPerHost = poolSize
+	if socketPath != "" {
+		transport.DialContext = (&socketDialer{path: socketPath}).DialContext
+	}
+	return &http.Client{
+		Transport: transport,
+	}
+}
+
+// socketDialer dials connections to a unix socket, whatever the address they
+// are dialed for
+type socketDialer struct {
+	path string
+}
+
+// Dial dials a connection to the socket
+func (dialer *socketDialer) Dial(network, address string) (net.Conn, error) {
+	return dialer.DialContext(context.Background(), network, address)
+}
+
+// DialContext dials a connection to the socket using the context
+func (dialer *socketDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var netDialer net.Dialer
+	return netDialer.DialContext(ctx, "unix", dialer.path)
+}
@@ -15,10 +15,12 @@
 package dockerclient
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface/mocks"
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
@@ -46,6 +48,27 @@ func TestGetDefaultClientSuccess(t *testing.T) {
 	assert.Equal(t, expectedClient, actualClient)
 }
 
+func TestNewFactoryWithPoolSize(t *testing.T) {
+	poolSize := 16
+	newVersionedClient = func(endpoint, version string) (dockeriface.Client, error) {
+		// Nothing is expected to listen on this endpoint; the ping failing
+		// is only logged
+		return docker.NewVersionedClient("tcp://127.0.0.1:1", version)
+	}
+
+	factory := NewFactoryWithPoolSize(expectedEndpoint, poolSize)
+	client, err := factory.GetDefaultClient()
+	assert.NoError(t, err)
+
+	dockerClient, ok := client.(*docker.Client)
+	assert.True(t, ok, "expected a go-dockerclient client")
+	transport, ok := dockerClient.HTTPClient.Transport.(*http.Transport)
+	assert.True(t, ok, "expected an http transport")
+	assert.False(t, transport.DisableKeepAlives)
+	assert.Equal(t, poolSize, transport.MaxIdleConns)
+	assert.Equal(t, poolSize, transport.MaxIdleConnsPerHost)
+}
+
 func TestFindSupportedAPIVersions(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -15,12 +15,21 @@
 package dockerclient
 
 import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockeriface/mocks"
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetClientCached(t *testing.T) {
@@ -42,3 +51,44 @@ func TestGetClientCached(t *testing.T) {
 
 	assert.Equal(t, client, clientAgain)
 }
+
+// TestNewFactoryWithPoolSizeUnixSocket tests that clients for unix socket
+// endpoints reuse their connections to the Docker daemon
+func TestNewFactoryWithPoolSizeUnixSocket(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "docker-socket")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	socketPath := filepath.Join(tempDir, "docker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	var connections int32
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/version") {
+				w.Write([]byte(`{"ApiVersion":"1.30"}`))
+				return
+			}
+			w.Write([]byte("OK"))
+		}),
+		ConnState: func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&connections, 1)
+			}
+		},
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	newVersionedClient = func(endpoint, version string) (dockeriface.Client, error) {
+		return docker.NewVersionedClient(endpoint, version)
+	}
+	factory := NewFactoryWithPoolSize("unix://"+socketPath, 4)
+	client, err := factory.GetDefaultClient()
+	require.NoError(t, err)
+	atomic.StoreInt32(&connections, 0)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, client.Ping())
+	}
+	assert.True(t, atomic.LoadInt32(&connections) <= 1, "expected pings to share a connection")
+}
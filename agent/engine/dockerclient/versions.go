@@ -29,6 +29,7 @@ const (
 	Version_1_27 DockerVersion = "1.27"
 	Version_1_28 DockerVersion = "1.28"
 	Version_1_29 DockerVersion = "1.29"
+	Version_1_30 DockerVersion = "1.30"
 )
 
 // getKnownAPIVersions returns all of the API versions that we know about.
@@ -48,5 +49,6 @@ func getKnownAPIVersions() []DockerVersion {
 		Version_1_27,
 		Version_1_28,
 		Version_1_29,
+		Version_1_30,
 	}
 }
@@ -39,7 +39,10 @@ type Client interface {
 	StopContainer(id string, timeout uint) error
 	StopContainerWithContext(id string, timeout uint, ctx context.Context) error
 	Stats(opts docker.StatsOptions) error
+	UnpauseContainer(id string) error
 	Version() (*docker.Env, error)
 	RemoveImage(imageName string) error
 	LoadImage(opts docker.LoadImageOptions) error
+	NetworkInfo(id string) (*docker.Network, error)
+	CreateNetwork(opts docker.CreateNetworkOptions) (*docker.Network, error)
 }
@@ -64,6 +64,17 @@ func (_mr *_MockClientRecorder) CreateContainer(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateContainer", arg0)
 }
 
+func (_m *MockClient) CreateNetwork(_param0 go_dockerclient.CreateNetworkOptions) (*go_dockerclient.Network, error) {
+	ret := _m.ctrl.Call(_m, "CreateNetwork", _param0)
+	ret0, _ := ret[0].(*go_dockerclient.Network)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) CreateNetwork(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateNetwork", arg0)
+}
+
 func (_m *MockClient) ImportImage(_param0 go_dockerclient.ImportImageOptions) error {
 	ret := _m.ctrl.Call(_m, "ImportImage", _param0)
 	ret0, _ := ret[0].(error)
@@ -128,6 +139,17 @@ func (_mr *_MockClientRecorder) LoadImage(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "LoadImage", arg0)
 }
 
+func (_m *MockClient) NetworkInfo(_param0 string) (*go_dockerclient.Network, error) {
+	ret := _m.ctrl.Call(_m, "NetworkInfo", _param0)
+	ret0, _ := ret[0].(*go_dockerclient.Network)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockClientRecorder) NetworkInfo(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "NetworkInfo", arg0)
+}
+
 func (_m *MockClient) Ping() error {
 	ret := _m.ctrl.Call(_m, "Ping")
 	ret0, _ := ret[0].(error)
@@ -228,6 +250,16 @@ func (_mr *_MockClientRecorder) StopContainerWithContext(arg0, arg1, arg2 interf
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainerWithContext", arg0, arg1, arg2)
 }
 
+func (_m *MockClient) UnpauseContainer(_param0 string) error {
+	ret := _m.ctrl.Call(_m, "UnpauseContainer", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockClientRecorder) UnpauseContainer(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UnpauseContainer", arg0)
+}
+
 func (_m *MockClient) Version() (*go_dockerclient.Env, error) {
 	ret := _m.ctrl.Call(_m, "Version")
 	ret0, _ := ret[0].(*go_dockerclient.Env)
@@ -47,6 +47,9 @@ type TaskEngineState interface {
 	TaskByArn(arn string) (*api.Task, bool)
 	// AddTask adds a task to the state to be stored
 	AddTask(task *api.Task)
+	// AllocateHostPorts allocates the static host ports requested by a task,
+	// failing if one of them is allocated to another task
+	AllocateHostPorts(task *api.Task) error
 	// AddContainer adds a container to the state to be stored for a given task
 	AddContainer(container *api.DockerContainer, task *api.Task)
 	// AddImageState adds an image.ImageState to be stored
@@ -57,8 +60,13 @@ type TaskEngineState interface {
 	RemoveENIAttachment(mac string)
 	// ENIByMac returns the specific ENIAttachment of the given mac address
 	ENIByMac(mac string) (*api.ENIAttachment, bool)
+	// AllENIAttachments returns all the eni attachments being tracked
+	AllENIAttachments() []*api.ENIAttachment
 	// RemoveTask removes a task from the state
 	RemoveTask(task *api.Task)
+	// RemoveTaskContainers removes the containers of a task from the state,
+	// keeping the task and the host ports allocated to it
+	RemoveTaskContainers(task *api.Task)
 	// Reset resets all the fileds in the state
 	Reset()
 	// RemoveImageState removes an image.ImageState
@@ -89,6 +97,7 @@ type DockerTaskEngineState struct {
 	idToContainer  map[string]*api.DockerContainer            // DockerId -> api.DockerContainer
 	eniAttachments map[string]*api.ENIAttachment              // ENIMac -> api.ENIAttachment
 	imageStates    map[string]*image.ImageState
+	hostPorts      map[string]string // port/protocol -> taskarn
 }
 
 // NewTaskEngineState returns a new TaskEngineState
@@ -112,6 +121,7 @@ func (state *DockerTaskEngineState) initializeDockerTaskEngineState() {
 	state.idToContainer = make(map[string]*api.DockerContainer)
 	state.imageStates = make(map[string]*image.ImageState)
 	state.eniAttachments = make(map[string]*api.ENIAttachment)
+	state.hostPorts = make(map[string]string)
 }
 
 // Reset resets all the states
@@ -367,6 +377,7 @@ func (state *DockerTaskEngineState) RemoveTask(task *api.Task) {
 		return
 	}
 	delete(state.tasks, task.Arn)
+	state.releaseHostPortsUnsafe(task)
 	containerMap, ok := state.taskToID[task.Arn]
 	if !ok {
 		return
@@ -379,6 +390,27 @@ func (state *DockerTaskEngineState) RemoveTask(task *api.Task) {
 	}
 }
 
+// RemoveTaskContainers removes the containers of a task from this state, so
+// that the task can be started again with new containers. The task and the
+// host ports allocated to it are kept
+func (state *DockerTaskEngineState) RemoveTaskContainers(task *api.Task) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	containerMap, ok := state.taskToID[task.Arn]
+	if !ok {
+		return
+	}
+	delete(state.taskToID, task.Arn)
+
+	for _, dockerContainer := range containerMap {
+		delete(state.idToTask, dockerContainer.DockerID)
+		delete(state.idToContainer, dockerContainer.DockerID)
+		delete(state.idToTask, dockerContainer.DockerName)
+		delete(state.idToContainer, dockerContainer.DockerName)
+	}
+}
+
 // RemoveImageState removes an image.ImageState
 func (state *DockerTaskEngineState) RemoveImageState(imageState *image.ImageState) {
 	if imageState == nil {
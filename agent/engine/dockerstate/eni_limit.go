@@ -0,0 +1,31 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+// ENILimitReached returns true if the state tracks maxENIs or more attachments
+// of enis other than the one with the mac address, so that the attachment of
+// that eni is refused. The number of attachments is not limited if maxENIs is
+// not positive
+func ENILimitReached(state TaskEngineState, mac string, maxENIs int) bool {
+	if maxENIs <= 0 {
+		return false
+	}
+	tracked := 0
+	for _, eni := range state.AllENIAttachments() {
+		if eni.MACAddress != mac {
+			tracked++
+		}
+	}
+	return tracked >= maxENIs
+}
@@ -0,0 +1,32 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestENILimitReached(t *testing.T) {
+	state := NewTaskEngineState()
+	state.AddENIAttachment(&api.ENIAttachment{MACAddress: "mac1"})
+	state.AddENIAttachment(&api.ENIAttachment{MACAddress: "mac2"})
+
+	assert.False(t, ENILimitReached(state, "mac3", 0), "the number of enis is not limited")
+	assert.True(t, ENILimitReached(state, "mac3", 2))
+	assert.False(t, ENILimitReached(state, "mac2", 2), "the attachment of the eni itself should not count")
+	assert.False(t, ENILimitReached(state, "mac3", 3))
+}
@@ -0,0 +1,100 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// HostPortConflictError is the error for a task requesting a static host port
+// that is allocated to another task
+type HostPortConflictError struct {
+	taskArn            string
+	hostPort           string
+	conflictingTaskArn string
+}
+
+func (err HostPortConflictError) Error() string {
+	return fmt.Sprintf("RESOURCE_CONFLICT: host port %s requested by task %s is already allocated to task %s",
+		err.hostPort, err.taskArn, err.conflictingTaskArn)
+}
+
+// ErrorName is the name of the error
+func (err HostPortConflictError) ErrorName() string {
+	return "HostPortConflictError"
+}
+
+// AllocateHostPorts allocates the static host ports requested by the
+// containers of the task. It returns a HostPortConflictError without
+// allocating any port if one of them is allocated to another task that has
+// not stopped
+func (state *DockerTaskEngineState) AllocateHostPorts(task *api.Task) error {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	return state.allocateHostPortsUnsafe(task)
+}
+
+func (state *DockerTaskEngineState) allocateHostPortsUnsafe(task *api.Task) error {
+	hostPorts := taskHostPorts(task)
+	for _, hostPort := range hostPorts {
+		taskArn, ok := state.hostPorts[hostPort]
+		if !ok || taskArn == task.Arn {
+			continue
+		}
+		owner, ok := state.tasks[taskArn]
+		if ok && !owner.GetKnownStatus().Terminal() {
+			return HostPortConflictError{
+				taskArn:            task.Arn,
+				hostPort:           hostPort,
+				conflictingTaskArn: taskArn,
+			}
+		}
+	}
+	for _, hostPort := range hostPorts {
+		state.hostPorts[hostPort] = task.Arn
+	}
+	return nil
+}
+
+// releaseHostPortsUnsafe releases the host ports allocated to the task
+func (state *DockerTaskEngineState) releaseHostPortsUnsafe(task *api.Task) {
+	for hostPort, taskArn := range state.hostPorts {
+		if taskArn == task.Arn {
+			delete(state.hostPorts, hostPort)
+		}
+	}
+}
+
+// taskHostPorts returns the static host ports, in the 'port/protocol' format,
+// requested by the containers of the task. Tasks with their own network
+// interface don't bind ports on the host
+func taskHostPorts(task *api.Task) []string {
+	if task.GetTaskENI() != nil {
+		return nil
+	}
+	var hostPorts []string
+	for _, container := range task.Containers {
+		for _, portBinding := range container.Ports {
+			if portBinding.HostPort == 0 {
+				continue
+			}
+			hostPorts = append(hostPorts, strconv.Itoa(int(portBinding.HostPort))+"/"+portBinding.Protocol.String())
+		}
+	}
+	return hostPorts
+}
@@ -0,0 +1,126 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package dockerstate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hostPortTask(arn string, hostPorts ...uint16) *api.Task {
+	container := &api.Container{Name: "c"}
+	for _, hostPort := range hostPorts {
+		container.Ports = append(container.Ports, api.PortBinding{
+			ContainerPort: 80,
+			HostPort:      hostPort,
+			Protocol:      api.TransportProtocolTCP,
+		})
+	}
+	return &api.Task{
+		Arn:        arn,
+		Containers: []*api.Container{container},
+	}
+}
+
+func TestAllocateHostPortsConflict(t *testing.T) {
+	state := newDockerTaskEngineState()
+	task1 := hostPortTask("t1", 8080, 0)
+	task2 := hostPortTask("t2", 9090, 8080)
+	task3 := hostPortTask("t3", 9090)
+	state.AddTask(task1)
+	state.AddTask(task2)
+	state.AddTask(task3)
+
+	require.NoError(t, state.AllocateHostPorts(task1))
+	err := state.AllocateHostPorts(task2)
+	require.Error(t, err)
+	assert.IsType(t, HostPortConflictError{}, err)
+	assert.Contains(t, err.Error(), "RESOURCE_CONFLICT")
+	assert.Contains(t, err.Error(), "8080/tcp")
+	assert.NoError(t, state.AllocateHostPorts(task3), "ports of a rejected task should not be allocated")
+	assert.NoError(t, state.AllocateHostPorts(task1), "allocating ports again for the same task should succeed")
+}
+
+func TestAllocateHostPortsReleased(t *testing.T) {
+	state := newDockerTaskEngineState()
+	task1 := hostPortTask("t1", 8080)
+	task2 := hostPortTask("t2", 8080)
+	task3 := hostPortTask("t3", 8080)
+	state.AddTask(task1)
+	state.AddTask(task2)
+	state.AddTask(task3)
+
+	require.NoError(t, state.AllocateHostPorts(task1))
+	task1.SetKnownStatus(api.TaskStopped)
+	require.NoError(t, state.AllocateHostPorts(task2), "ports of stopped tasks should be reused")
+
+	state.RemoveTask(task2)
+	assert.NoError(t, state.AllocateHostPorts(task3), "ports of removed tasks should be released")
+}
+
+func TestAllocateHostPortsIgnoresTasksWithENI(t *testing.T) {
+	state := newDockerTaskEngineState()
+	task1 := hostPortTask("t1", 8080)
+	task2 := hostPortTask("t2", 8080)
+	task2.SetTaskENI(&api.ENI{ID: "eni"})
+	state.AddTask(task1)
+	state.AddTask(task2)
+
+	require.NoError(t, state.AllocateHostPorts(task1))
+	assert.NoError(t, state.AllocateHostPorts(task2))
+}
+
+func TestAllocateHostPortsRestored(t *testing.T) {
+	state := newDockerTaskEngineState()
+	running := hostPortTask("running", 8080)
+	running.SetKnownStatus(api.TaskRunning)
+	stopped := hostPortTask("stopped", 9090)
+	state.AddTask(running)
+	state.AddTask(stopped)
+	require.NoError(t, state.AllocateHostPorts(running))
+	require.NoError(t, state.AllocateHostPorts(stopped))
+	stopped.SetKnownStatus(api.TaskStopped)
+
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	restored := newDockerTaskEngineState()
+	require.NoError(t, json.Unmarshal(data, restored))
+
+	err = restored.AllocateHostPorts(hostPortTask("new", 8080))
+	assert.Error(t, err, "ports of running tasks should be restored")
+	assert.NoError(t, restored.AllocateHostPorts(hostPortTask("other", 9090)))
+}
+
+func TestRemoveTaskContainersKeepsHostPorts(t *testing.T) {
+	state := newDockerTaskEngineState()
+	task1 := hostPortTask("t1", 8080)
+	task2 := hostPortTask("t2", 8080)
+	state.AddTask(task1)
+	state.AddTask(task2)
+	state.AddContainer(&api.DockerContainer{DockerID: "did", DockerName: "dname", Container: task1.Containers[0]}, task1)
+	require.NoError(t, state.AllocateHostPorts(task1))
+
+	state.RemoveTaskContainers(task1)
+	_, ok := state.ContainerByID("did")
+	assert.False(t, ok, "the containers of the task should be removed")
+	_, ok = state.ContainerMapByArn(task1.Arn)
+	assert.False(t, ok, "the containers of the task should be removed")
+	_, ok = state.TaskByArn(task1.Arn)
+	assert.True(t, ok, "the task should be kept")
+	assert.Error(t, state.AllocateHostPorts(task2), "the host ports of the task should remain allocated")
+}
@@ -60,6 +60,15 @@ func (state *DockerTaskEngineState) UnmarshalJSON(data []byte) error {
 	for _, task := range saved.Tasks {
 		clean.AddTask(task)
 	}
+	// restore the host ports allocated to tasks that have not stopped
+	for _, task := range saved.Tasks {
+		if task.GetKnownStatus().Terminal() {
+			continue
+		}
+		if err := clean.AllocateHostPorts(task); err != nil {
+			log.Warn("Unable to restore host ports of task", "arn", task.Arn, "err", err)
+		}
+	}
 	// add image states
 	for _, imageState := range saved.ImageStates {
 		clean.AddImageState(imageState)
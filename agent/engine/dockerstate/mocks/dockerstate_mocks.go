@@ -75,6 +75,16 @@ func (_mr *_MockTaskEngineStateRecorder) AddTask(arg0 interface{}) *gomock.Call
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AddTask", arg0)
 }
 
+func (_m *MockTaskEngineState) AllENIAttachments() []*api.ENIAttachment {
+	ret := _m.ctrl.Call(_m, "AllENIAttachments")
+	ret0, _ := ret[0].([]*api.ENIAttachment)
+	return ret0
+}
+
+func (_mr *_MockTaskEngineStateRecorder) AllENIAttachments() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "AllENIAttachments")
+}
+
 func (_m *MockTaskEngineState) AllImageStates() []*image.ImageState {
 	ret := _m.ctrl.Call(_m, "AllImageStates")
 	ret0, _ := ret[0].([]*image.ImageState)
@@ -95,6 +105,16 @@ func (_mr *_MockTaskEngineStateRecorder) AllTasks() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "AllTasks")
 }
 
+func (_m *MockTaskEngineState) AllocateHostPorts(_param0 *api.Task) error {
+	ret := _m.ctrl.Call(_m, "AllocateHostPorts", _param0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockTaskEngineStateRecorder) AllocateHostPorts(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "AllocateHostPorts", arg0)
+}
+
 func (_m *MockTaskEngineState) ContainerByID(_param0 string) (*api.DockerContainer, bool) {
 	ret := _m.ctrl.Call(_m, "ContainerByID", _param0)
 	ret0, _ := ret[0].(*api.DockerContainer)
@@ -173,6 +193,14 @@ func (_mr *_MockTaskEngineStateRecorder) RemoveTask(arg0 interface{}) *gomock.Ca
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveTask", arg0)
 }
 
+func (_m *MockTaskEngineState) RemoveTaskContainers(_param0 *api.Task) {
+	_m.ctrl.Call(_m, "RemoveTaskContainers", _param0)
+}
+
+func (_mr *_MockTaskEngineStateRecorder) RemoveTaskContainers(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveTaskContainers", arg0)
+}
+
 func (_m *MockTaskEngineState) Reset() {
 	_m.ctrl.Call(_m, "Reset")
 }
@@ -70,6 +70,14 @@ func (_mr *_MockTaskEngineRecorder) Disable() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "Disable")
 }
 
+func (_m *MockTaskEngine) Drain() {
+	_m.ctrl.Call(_m, "Drain")
+}
+
+func (_mr *_MockTaskEngineRecorder) Drain() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Drain")
+}
+
 func (_m *MockTaskEngine) GetTaskByArn(_param0 string) (*api.Task, bool) {
 	ret := _m.ctrl.Call(_m, "GetTaskByArn", _param0)
 	ret0, _ := ret[0].(*api.Task)
@@ -121,6 +129,32 @@ func (_mr *_MockTaskEngineRecorder) MustInit(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "MustInit", arg0)
 }
 
+func (_m *MockTaskEngine) Pause() {
+	_m.ctrl.Call(_m, "Pause")
+}
+
+func (_mr *_MockTaskEngineRecorder) Pause() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Pause")
+}
+
+func (_m *MockTaskEngine) Paused() bool {
+	ret := _m.ctrl.Call(_m, "Paused")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+func (_mr *_MockTaskEngineRecorder) Paused() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Paused")
+}
+
+func (_m *MockTaskEngine) Resume() {
+	_m.ctrl.Call(_m, "Resume")
+}
+
+func (_mr *_MockTaskEngineRecorder) Resume() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Resume")
+}
+
 func (_m *MockTaskEngine) SetSaver(_param0 statemanager.Saver) {
 	_m.ctrl.Call(_m, "SetSaver", _param0)
 }
@@ -202,6 +236,16 @@ func (_mr *_MockDockerClientRecorder) CreateContainer(arg0, arg1, arg2, arg3 int
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateContainer", arg0, arg1, arg2, arg3)
 }
 
+func (_m *MockDockerClient) CreateNetwork(_param0 string, _param1 time.Duration) error {
+	ret := _m.ctrl.Call(_m, "CreateNetwork", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) CreateNetwork(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "CreateNetwork", arg0, arg1)
+}
+
 func (_m *MockDockerClient) DescribeContainer(_param0 string) (api.ContainerStatus, DockerContainerMetadata) {
 	ret := _m.ctrl.Call(_m, "DescribeContainer", _param0)
 	ret0, _ := ret[0].(api.ContainerStatus)
@@ -245,6 +289,17 @@ func (_mr *_MockDockerClientRecorder) InspectImage(arg0 interface{}) *gomock.Cal
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "InspectImage", arg0)
 }
 
+func (_m *MockDockerClient) InspectNetwork(_param0 string, _param1 time.Duration) (*go_dockerclient.Network, error) {
+	ret := _m.ctrl.Call(_m, "InspectNetwork", _param0, _param1)
+	ret0, _ := ret[0].(*go_dockerclient.Network)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDockerClientRecorder) InspectNetwork(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "InspectNetwork", arg0, arg1)
+}
+
 func (_m *MockDockerClient) KnownVersions() []dockerclient.DockerVersion {
 	ret := _m.ctrl.Call(_m, "KnownVersions")
 	ret0, _ := ret[0].([]dockerclient.DockerVersion)
@@ -275,14 +330,14 @@ func (_mr *_MockDockerClientRecorder) LoadImage(arg0, arg1 interface{}) *gomock.
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "LoadImage", arg0, arg1)
 }
 
-func (_m *MockDockerClient) PullImage(_param0 string, _param1 *api.RegistryAuthenticationData) DockerContainerMetadata {
-	ret := _m.ctrl.Call(_m, "PullImage", _param0, _param1)
+func (_m *MockDockerClient) PullImage(_param0 string, _param1 *api.RegistryAuthenticationData, _param2 string) DockerContainerMetadata {
+	ret := _m.ctrl.Call(_m, "PullImage", _param0, _param1, _param2)
 	ret0, _ := ret[0].(DockerContainerMetadata)
 	return ret0
 }
 
-func (_mr *_MockDockerClientRecorder) PullImage(arg0, arg1 interface{}) *gomock.Call {
-	return _mr.mock.ctrl.RecordCall(_mr.mock, "PullImage", arg0, arg1)
+func (_mr *_MockDockerClientRecorder) PullImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "PullImage", arg0, arg1, arg2)
 }
 
 func (_m *MockDockerClient) RemoveContainer(_param0 string, _param1 time.Duration) error {
@@ -336,6 +391,16 @@ func (_mr *_MockDockerClientRecorder) StopContainer(arg0, arg1 interface{}) *gom
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainer", arg0, arg1)
 }
 
+func (_m *MockDockerClient) StopContainerWithGracePeriod(_param0 string, _param1 time.Duration, _param2 time.Duration) DockerContainerMetadata {
+	ret := _m.ctrl.Call(_m, "StopContainerWithGracePeriod", _param0, _param1, _param2)
+	ret0, _ := ret[0].(DockerContainerMetadata)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) StopContainerWithGracePeriod(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "StopContainerWithGracePeriod", arg0, arg1, arg2)
+}
+
 func (_m *MockDockerClient) SupportedVersions() []dockerclient.DockerVersion {
 	ret := _m.ctrl.Call(_m, "SupportedVersions")
 	ret0, _ := ret[0].([]dockerclient.DockerVersion)
@@ -346,6 +411,16 @@ func (_mr *_MockDockerClientRecorder) SupportedVersions() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "SupportedVersions")
 }
 
+func (_m *MockDockerClient) UnpauseContainer(_param0 string, _param1 time.Duration) error {
+	ret := _m.ctrl.Call(_m, "UnpauseContainer", _param0, _param1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (_mr *_MockDockerClientRecorder) UnpauseContainer(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "UnpauseContainer", arg0, arg1)
+}
+
 func (_m *MockDockerClient) Version() (string, error) {
 	ret := _m.ctrl.Call(_m, "Version")
 	ret0, _ := ret[0].(string)
@@ -367,6 +442,17 @@ func (_mr *_MockDockerClientRecorder) WithVersion(arg0 interface{}) *gomock.Call
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "WithVersion", arg0)
 }
 
+func (_m *MockDockerClient) WritableLayerSize(_param0 string, _param1 time.Duration) (int64, error) {
+	ret := _m.ctrl.Call(_m, "WritableLayerSize", _param0, _param1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDockerClientRecorder) WritableLayerSize(arg0, arg1 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "WritableLayerSize", arg0, arg1)
+}
+
 // Mock of ImageManager interface
 type MockImageManager struct {
 	ctrl     *gomock.Controller
@@ -426,6 +512,14 @@ func (_mr *_MockImageManagerRecorder) RemoveContainerReferenceFromImageState(arg
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveContainerReferenceFromImageState", arg0)
 }
 
+func (_m *MockImageManager) RemoveUnusedImages() {
+	_m.ctrl.Call(_m, "RemoveUnusedImages")
+}
+
+func (_mr *_MockImageManagerRecorder) RemoveUnusedImages() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "RemoveUnusedImages")
+}
+
 func (_m *MockImageManager) SetSaver(_param0 statemanager.Saver) {
 	_m.ctrl.Call(_m, "SetSaver", _param0)
 }
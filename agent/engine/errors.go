@@ -14,6 +14,11 @@
 package engine
 
 import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
@@ -67,6 +72,45 @@ func (err ContainerVanishedError) Error() string { return "No container matching
 // ErrorName returns the name of the error
 func (err ContainerVanishedError) ErrorName() string { return "ContainerVanishedError" }
 
+// EssentialContainerMissingError is a type for describing an essential
+// container of a restored task that no longer exists in Docker
+type EssentialContainerMissingError struct {
+	name string
+}
+
+func (err EssentialContainerMissingError) Error() string {
+	return "Essential container " + err.name + " was removed from Docker while the agent was down"
+}
+
+// ErrorName returns the name of the error
+func (err EssentialContainerMissingError) ErrorName() string { return "EssentialContainerMissingError" }
+
+// ContainerPausedError is a type for describing a container of a restored task
+// that Docker reports as paused
+type ContainerPausedError struct {
+	name string
+}
+
+func (err ContainerPausedError) Error() string {
+	return "Container " + err.name + " was found paused in Docker"
+}
+
+// ErrorName returns the name of the error
+func (err ContainerPausedError) ErrorName() string { return "ContainerPausedError" }
+
+// HealthyDependencyTimeoutError is a type for describing a container that
+// timed out waiting for the containers it depends on to become healthy
+type HealthyDependencyTimeoutError struct {
+	name string
+}
+
+func (err HealthyDependencyTimeoutError) Error() string {
+	return "Container " + err.name + " timed out waiting for its dependencies to become healthy"
+}
+
+// ErrorName returns the name of the error
+func (err HealthyDependencyTimeoutError) ErrorName() string { return "HealthyDependencyTimeoutError" }
+
 // OutOfMemoryError is a type for errors caused by running out of memory
 type OutOfMemoryError struct{}
 
@@ -132,6 +176,106 @@ func (err TaskDependencyError) ErrorName() string {
 	return "TaskDependencyError"
 }
 
+// NoContainersError is the error for a task that is stopped because it has
+// no containers to run
+type NoContainersError struct {
+	taskArn string
+}
+
+func (err NoContainersError) Error() string {
+	return "Task has no containers, taskArn: " + err.taskArn
+}
+
+// ErrorName is the name of the error
+func (err NoContainersError) ErrorName() string {
+	return "NoContainersError"
+}
+
+// TaskDefinitionError is the error for a task that is stopped because it
+// can't be run as defined, e.g. because it combines incompatible settings
+type TaskDefinitionError struct {
+	taskArn   string
+	fromError error
+}
+
+func (err TaskDefinitionError) Error() string {
+	return "Invalid task definition: " + err.fromError.Error() + ", taskArn: " + err.taskArn
+}
+
+// ErrorName is the name of the error
+func (err TaskDefinitionError) ErrorName() string {
+	return "TaskDefinitionError"
+}
+
+// AgentDrainingError is the error for a task that is stopped because it was
+// received while the Agent is draining the instance before it exits
+type AgentDrainingError struct {
+	taskArn string
+}
+
+func (err AgentDrainingError) Error() string {
+	return "Agent is draining the instance and does not accept new tasks, taskArn: " + err.taskArn
+}
+
+// ErrorName is the name of the error
+func (err AgentDrainingError) ErrorName() string {
+	return "AgentDrainingError"
+}
+
+// NoENICapacityError is the error for a task using the awsvpc network mode
+// that is stopped because all of the ENI slots of the instance are in use
+type NoENICapacityError struct {
+	taskArn string
+	slots   int
+}
+
+func (err NoENICapacityError) Error() string {
+	return fmt.Sprintf("no ENI capacity: all %d ENI slots of the instance are in use by other tasks, taskArn: %s",
+		err.slots, err.taskArn)
+}
+
+// ErrorName is the name of the error
+func (err NoENICapacityError) ErrorName() string {
+	return "NoENICapacityError"
+}
+
+// ENILimitExceededError is the error for a task using the awsvpc network mode
+// that is stopped because the attachment of its ENI is refused, as the
+// maximum number of tracked ENI attachments is reached
+type ENILimitExceededError struct {
+	taskArn string
+	maxENIs int
+}
+
+func (err ENILimitExceededError) Error() string {
+	return fmt.Sprintf("ENI attachment refused: the maximum number of tracked ENIs (%d) is reached, taskArn: %s",
+		err.maxENIs, err.taskArn)
+}
+
+// ErrorName is the name of the error
+func (err ENILimitExceededError) ErrorName() string {
+	return "ENILimitExceededError"
+}
+
+// UnsupportedIPCModeError is the error for a task that is stopped because
+// its IPC mode requires a newer version of the docker API than the docker
+// daemon supports
+type UnsupportedIPCModeError struct {
+	taskArn         string
+	ipcMode         string
+	requiredVersion dockerclient.DockerVersion
+}
+
+func (err UnsupportedIPCModeError) Error() string {
+	return fmt.Sprintf("ipc mode %q requires docker API version %s, taskArn: %s",
+		err.ipcMode, err.requiredVersion, err.taskArn)
+}
+
+// ErrorName is the name of the error
+func (err UnsupportedIPCModeError) ErrorName() string {
+	return "UnsupportedIPCModeError"
+}
+
 // TaskStoppedBeforePullBeginError is a type for task errors involving pull
 type TaskStoppedBeforePullBeginError struct {
 	taskArn string
@@ -190,6 +334,61 @@ func (err CannotPullContainerError) ErrorName() string {
 	return "CannotPullContainerError"
 }
 
+// CannotPullContainerAuthError indicates that a container image could not be
+// pulled because its registry requires authentication, and no credentials
+// are configured for it
+type CannotPullContainerAuthError struct {
+	fromError error
+}
+
+func (err CannotPullContainerAuthError) Error() string {
+	return "authentication required but no credentials configured: " + err.fromError.Error()
+}
+
+func (err CannotPullContainerAuthError) ErrorName() string {
+	return "CannotPullContainerAuthError"
+}
+
+// Retry returns false, as retrying the pull without credentials is bound to
+// fail again
+func (err CannotPullContainerAuthError) Retry() bool {
+	return false
+}
+
+// isAuthRequiredError returns true if the pull of an image failed because the
+// registry requires authentication
+func isAuthRequiredError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if dockerErr, ok := err.(*docker.Error); ok && dockerErr.Status == http.StatusUnauthorized {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unauthorized") ||
+		strings.Contains(msg, "authentication required") ||
+		strings.Contains(msg, "may require 'docker login'")
+}
+
+// CannotPullContainerDiskFullError indicates that a container image could
+// not be pulled because the disk is full, even after removing unused images
+type CannotPullContainerDiskFullError struct {
+	fromError error
+}
+
+func (err CannotPullContainerDiskFullError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err CannotPullContainerDiskFullError) ErrorName() string {
+	return "CannotPullContainerDiskFullError"
+}
+
+// isDiskFullError returns true if the error was caused by the disk being full
+func isDiskFullError(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "no space left on device")
+}
+
 // CannotPullECRContainerError indicates any error when trying to pull
 // a container image from ECR
 type CannotPullECRContainerError struct {
@@ -239,6 +438,172 @@ func (err CannotCreateContainerError) ErrorName() string {
 	return "CannotCreateContainerError"
 }
 
+// HostVolumeError indicates that the source path of a host volume referenced
+// by a container could not be resolved
+type HostVolumeError struct {
+	fromError error
+}
+
+func (err HostVolumeError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err HostVolumeError) ErrorName() string {
+	return "HostVolumeError"
+}
+
+// NetworkError indicates that the user-defined docker network referenced by
+// the network mode of a container could not be resolved
+type NetworkError struct {
+	fromError error
+}
+
+func (err NetworkError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err NetworkError) ErrorName() string {
+	return "NetworkError"
+}
+
+// LogSecretOptionError indicates that the value of a secret log driver option
+// of a container could not be resolved
+type LogSecretOptionError struct {
+	fromError error
+}
+
+func (err LogSecretOptionError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err LogSecretOptionError) ErrorName() string {
+	return "LogSecretOptionError"
+}
+
+// TaskCgroupError indicates that the parent cgroup that enforces the limits
+// of a task could not be created
+type TaskCgroupError struct {
+	fromError error
+}
+
+func (err TaskCgroupError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err TaskCgroupError) ErrorName() string {
+	return "TaskCgroupError"
+}
+
+// AWSLogsGroupError indicates that the log group of a container that uses the
+// awslogs log driver could not be created
+type AWSLogsGroupError struct {
+	fromError error
+}
+
+func (err AWSLogsGroupError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err AWSLogsGroupError) ErrorName() string {
+	return "AWSLogsGroupError"
+}
+
+// PlatformMismatchError indicates that the platform requested for a container
+// does not match the platform of the host
+type PlatformMismatchError struct {
+	platform     string
+	hostPlatform string
+}
+
+func (err PlatformMismatchError) Error() string {
+	return fmt.Sprintf("requested platform %s does not match host platform %s", err.platform, err.hostPlatform)
+}
+
+func (err PlatformMismatchError) ErrorName() string {
+	return "PlatformMismatchError"
+}
+
+// WorkingDirError indicates that the working directory of a container is
+// unlikely to exist in its image
+type WorkingDirError struct {
+	workingDir string
+	reason     string
+}
+
+func (err WorkingDirError) Error() string {
+	return fmt.Sprintf("invalid working directory %s: %s", err.workingDir, err.reason)
+}
+
+func (err WorkingDirError) ErrorName() string {
+	return "WorkingDirError"
+}
+
+// MemoryLimitError indicates that the memory limit of a container exceeds
+// the memory of the instance
+type MemoryLimitError struct {
+	container      string
+	memory         int64
+	instanceMemory int64
+}
+
+func (err MemoryLimitError) Error() string {
+	return fmt.Sprintf("memory limit of container %s (%d MiB) exceeds the memory of the instance (%d MiB)",
+		err.container, err.memory/1024/1024, err.instanceMemory/1024/1024)
+}
+
+func (err MemoryLimitError) ErrorName() string {
+	return "MemoryLimitError"
+}
+
+// CPUCapacityError indicates that a task requests more CPU than the instance
+// has
+type CPUCapacityError struct {
+	taskArn     string
+	cpu         int64
+	instanceCPU int64
+}
+
+func (err CPUCapacityError) Error() string {
+	return fmt.Sprintf("RESOURCE_CONFLICT: task requests %d CPU units, which exceeds the %d CPU units of the instance, taskArn: %s",
+		err.cpu, err.instanceCPU, err.taskArn)
+}
+
+func (err CPUCapacityError) ErrorName() string {
+	return "CPUCapacityError"
+}
+
+// ReservedLabelError indicates that a container has labels that use the
+// prefix reserved for the labels set by the agent
+type ReservedLabelError struct {
+	container string
+	labels    []string
+}
+
+func (err ReservedLabelError) Error() string {
+	return fmt.Sprintf("labels %s of container %s use the reserved prefix %s",
+		strings.Join(err.labels, ", "), err.container, labelPrefix)
+}
+
+func (err ReservedLabelError) ErrorName() string {
+	return "ReservedLabelError"
+}
+
+// CommandManifestError indicates that the command manifest of a container
+// could not be applied
+type CommandManifestError struct {
+	container string
+	manifest  string
+	err       error
+}
+
+func (err CommandManifestError) Error() string {
+	return fmt.Sprintf("unable to apply command manifest %s of container %s: %v", err.manifest, err.container, err.err)
+}
+
+func (err CommandManifestError) ErrorName() string {
+	return "CommandManifestError"
+}
+
 // CannotStartContainerError indicates any error when trying to start a container
 type CannotStartContainerError struct {
 	fromError error
@@ -252,6 +617,50 @@ func (err CannotStartContainerError) ErrorName() string {
 	return "CannotStartContainerError"
 }
 
+// IsRetriableError returns a boolean indicating whether the call that
+// generated the error can be retried. Only errors known to be transient are,
+// such as the Docker daemon being unavailable or busy, or the connection to it
+// failing. Other errors, including most internal errors of the daemon such as
+// an invalid entrypoint, fail the same way when retried
+func (err CannotStartContainerError) IsRetriableError() bool {
+	switch fromError := err.fromError.(type) {
+	case *docker.Error:
+		switch fromError.Status {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		case http.StatusInternalServerError:
+			return isTransientStartError(fromError)
+		}
+		return false
+	case net.Error:
+		return fromError.Timeout() || fromError.Temporary()
+	}
+	return err.fromError == docker.ErrConnectionRefused ||
+		err.fromError == io.EOF ||
+		err.fromError == io.ErrUnexpectedEOF
+}
+
+// transientStartErrorMessages are parts of the messages of internal errors of
+// the Docker daemon that are known to be transient when starting a container
+var transientStartErrorMessages = []string{
+	"device or resource busy",
+	"resource temporarily unavailable",
+	"connection reset by peer",
+	"i/o timeout",
+}
+
+// isTransientStartError returns true if the internal error of the Docker
+// daemon is known to be transient
+func isTransientStartError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, transientMsg := range transientStartErrorMessages {
+		if strings.Contains(msg, transientMsg) {
+			return true
+		}
+	}
+	return false
+}
+
 // CannotInspectContainerError indicates any error when trying to inspect a container
 type CannotInspectContainerError struct {
 	fromError error
@@ -278,6 +687,19 @@ func (err CannotRemoveContainerError) ErrorName() string {
 	return "CannotRemoveContainerError"
 }
 
+// CannotUnpauseContainerError indicates any error when trying to unpause a container
+type CannotUnpauseContainerError struct {
+	fromError error
+}
+
+func (err CannotUnpauseContainerError) Error() string {
+	return err.fromError.Error()
+}
+
+func (err CannotUnpauseContainerError) ErrorName() string {
+	return "CannotUnpauseContainerError"
+}
+
 // CannotDescribeContainerError indicates any error when trying to describe a container
 type CannotDescribeContainerError struct {
 	fromError error
@@ -16,6 +16,8 @@ package engine
 
 import (
 	"errors"
+	"io"
+	"net"
 	"testing"
 
 	docker "github.com/fsouza/go-dockerclient"
@@ -36,3 +38,35 @@ func TestRetriableErrorReturnsTrue(t *testing.T) {
 	err := CannotStopContainerError{errors.New("error")}
 	assert.True(t, err.IsRetriableError(), "Non unretriable error treated as unretriable docker error")
 }
+
+func TestCannotStartContainerErrorIsRetriable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retriable bool
+	}{
+		{"NoSuchContainer", &docker.NoSuchContainer{}, false},
+		{"ContainerAlreadyRunning", &docker.ContainerAlreadyRunning{}, false},
+		{"ClientError", &docker.Error{Status: 400}, false},
+		{"ServerError", &docker.Error{Status: 500, Message: "invalid entrypoint"}, false},
+		{"ServerErrorDeviceBusy", &docker.Error{Status: 500, Message: "Device or resource busy"}, true},
+		{"ServiceUnavailable", &docker.Error{Status: 503}, true},
+		{"ConnectionRefused", docker.ErrConnectionRefused, true},
+		{"UnexpectedEOF", io.ErrUnexpectedEOF, true},
+		{"NetTimeout", &net.OpError{Op: "read", Err: timeoutError{}}, true},
+		{"OtherError", errors.New("error"), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CannotStartContainerError{tc.err}
+			assert.Equal(t, tc.retriable, err.IsRetriableError())
+		})
+	}
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return false }
@@ -85,6 +85,15 @@ func (imageState *ImageState) RemoveImageName(containerImageName string) {
 	}
 }
 
+// GetImageNames returns the names the image is known by
+func (imageState *ImageState) GetImageNames() []string {
+	imageState.updateLock.RLock()
+	defer imageState.updateLock.RUnlock()
+	names := make([]string, len(imageState.Image.Names))
+	copy(names, imageState.Image.Names)
+	return names
+}
+
 func (imageState *ImageState) HasImageName(containerImageName string) bool {
 	for _, imageName := range imageState.Image.Names {
 		if imageName == containerImageName {
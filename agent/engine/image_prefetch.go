@@ -0,0 +1,74 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cihub/seelog"
+)
+
+// prefetchImages pulls the images configured to be prefetched. Images are
+// pulled concurrently if the Docker daemon supports concurrent pulls. Failures
+// are logged, they do not prevent the engine from starting
+func (engine *DockerTaskEngine) prefetchImages() {
+	if len(engine.cfg.PrefetchImages) == 0 {
+		return
+	}
+	seelog.Infof("Prefetching %d images", len(engine.cfg.PrefetchImages))
+
+	if !engine.enableConcurrentPull {
+		for _, image := range engine.cfg.PrefetchImages {
+			engine.prefetchImage(image)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, image := range engine.cfg.PrefetchImages {
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			engine.prefetchImage(image)
+		}(image)
+	}
+	wg.Wait()
+}
+
+// prefetchImage pulls an image under the image pull lock, once a pull slot is
+// free. Failures are logged and returned
+func (engine *DockerTaskEngine) prefetchImage(image string) error {
+	// Wait for a pull slot before taking the lock, like the pulls of tasks
+	if engine.pullSemaphore != nil {
+		engine.pullSemaphore.acquire(0)
+		defer engine.pullSemaphore.release()
+	}
+	if engine.enableConcurrentPull {
+		ImagePullDeleteLock.RLock()
+		defer ImagePullDeleteLock.RUnlock()
+	} else {
+		ImagePullDeleteLock.Lock()
+		defer ImagePullDeleteLock.Unlock()
+	}
+
+	pullStart := time.Now()
+	metadata := engine.client.PullImage(image, nil, "")
+	if metadata.Error != nil {
+		seelog.Warnf("Failed to prefetch image %s: %v", image, metadata.Error)
+		return metadata.Error
+	}
+	seelog.Infof("Prefetched image %s in %s", image, time.Since(pullStart).String())
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// imageReferences tracks the images referenced by containers. The image
+// manager owns the references of containers that are pulled but not created
+// yet to their images, which the task engine takes while holding
+// ImagePullDeleteLock to pull the image and releases once the container is
+// created, or won't be, so that the image cleanup doesn't remove an image in
+// between. A nil imageReferences references no images
+type imageReferences struct {
+	lock       sync.RWMutex
+	references map[*api.Container]string
+}
+
+// pendingCreateImagesOf returns the references of the containers being
+// created to their images owned by the image manager. Image managers that
+// don't clean images up have no use for them, and get references of their own
+func pendingCreateImagesOf(imageManager ImageManager) *imageReferences {
+	if manager, ok := imageManager.(*dockerImageManager); ok {
+		return manager.pendingCreateImages
+	}
+	return newImageReferences()
+}
+
+func newImageReferences() *imageReferences {
+	return &imageReferences{
+		references: make(map[*api.Container]string),
+	}
+}
+
+// acquire adds a reference of the container to its image
+func (refs *imageReferences) acquire(container *api.Container) {
+	refs.lock.Lock()
+	defer refs.lock.Unlock()
+	refs.references[container] = container.Image
+}
+
+// release removes the reference of the container to its image, if it holds one
+func (refs *imageReferences) release(container *api.Container) {
+	if refs == nil {
+		return
+	}
+	refs.lock.Lock()
+	defer refs.lock.Unlock()
+	delete(refs.references, container)
+}
+
+// isReferenced returns true if any of the image names is referenced
+func (refs *imageReferences) isReferenced(imageNames []string) bool {
+	if refs == nil {
+		return false
+	}
+	refs.lock.RLock()
+	defer refs.lock.RUnlock()
+	for _, referenced := range refs.references {
+		for _, imageName := range imageNames {
+			if referenced == imageName {
+				return true
+			}
+		}
+	}
+	return false
+}
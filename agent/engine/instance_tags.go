@@ -0,0 +1,86 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ec2"
+	"github.com/cihub/seelog"
+)
+
+// instanceTagsTTL is how long instance tags are cached before they are
+// looked up again
+const instanceTagsTTL = 5 * time.Minute
+
+// instanceTagLabeler adds selected instance tags as labels of containers
+type instanceTagLabeler struct {
+	ec2MetadataClient ec2.EC2MetadataClient
+	keys              []string
+
+	lock      sync.Mutex
+	tags      map[string]string
+	fetchedAt time.Time
+}
+
+// newInstanceTagLabeler returns a labeler adding the tags with the given keys
+func newInstanceTagLabeler(ec2MetadataClient ec2.EC2MetadataClient, keys []string) *instanceTagLabeler {
+	return &instanceTagLabeler{
+		ec2MetadataClient: ec2MetadataClient,
+		keys:              keys,
+	}
+}
+
+// addLabels adds the selected instance tags to labels. Tags never override
+// labels that are already set, and tags in the namespace of the labels
+// reserved by the Agent are ignored
+func (labeler *instanceTagLabeler) addLabels(labels map[string]string, now time.Time) {
+	tags := labeler.getTags(now)
+	for _, key := range labeler.keys {
+		value, ok := tags[key]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(key, labelPrefix) {
+			seelog.Warnf("Not adding instance tag %s as a container label, the label is reserved", key)
+			continue
+		}
+		if _, ok := labels[key]; ok {
+			continue
+		}
+		labels[key] = value
+	}
+}
+
+// getTags returns the cached instance tags, looking them up again if they are
+// older than instanceTagsTTL. The previous tags are kept if the lookup fails,
+// and the lookup is not retried until they expire again
+func (labeler *instanceTagLabeler) getTags(now time.Time) map[string]string {
+	labeler.lock.Lock()
+	defer labeler.lock.Unlock()
+
+	if !labeler.fetchedAt.IsZero() && now.Sub(labeler.fetchedAt) < instanceTagsTTL {
+		return labeler.tags
+	}
+	labeler.fetchedAt = now
+	tags, err := labeler.ec2MetadataClient.InstanceTags()
+	if err != nil {
+		seelog.Warnf("Unable to get instance tags for container labels: %v", err)
+		return labeler.tags
+	}
+	labeler.tags = tags
+	return tags
+}
@@ -0,0 +1,80 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/ec2/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceTagLabelerAddsSelectedTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	ec2MetadataClient.EXPECT().InstanceTags().Return(map[string]string{
+		"CostCenter":            "1234",
+		"Team":                  "web",
+		"Owner":                 "someone",
+		labelPrefix + "cluster": "tag-cluster",
+	}, nil)
+
+	labeler := newInstanceTagLabeler(ec2MetadataClient, []string{"CostCenter", "Team", "Missing", labelPrefix + "cluster"})
+	labels := map[string]string{
+		labelPrefix + "cluster": "default",
+		"Team":                  "from-task",
+	}
+	labeler.addLabels(labels, time.Now())
+
+	assert.Equal(t, map[string]string{
+		labelPrefix + "cluster": "default",
+		"Team":                  "from-task",
+		"CostCenter":            "1234",
+	}, labels, "only selected tags should be added without overriding labels")
+}
+
+func TestInstanceTagLabelerCachesTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ec2MetadataClient := mock_ec2.NewMockEC2MetadataClient(ctrl)
+	labeler := newInstanceTagLabeler(ec2MetadataClient, []string{"CostCenter"})
+	now := time.Now()
+
+	gomock.InOrder(
+		ec2MetadataClient.EXPECT().InstanceTags().Return(map[string]string{"CostCenter": "1234"}, nil),
+		ec2MetadataClient.EXPECT().InstanceTags().Return(nil, errors.New("unavailable")),
+	)
+
+	labels := make(map[string]string)
+	labeler.addLabels(labels, now)
+	assert.Equal(t, "1234", labels["CostCenter"])
+
+	labels = make(map[string]string)
+	labeler.addLabels(labels, now.Add(instanceTagsTTL/2))
+	assert.Equal(t, "1234", labels["CostCenter"], "tags should be cached")
+
+	labels = make(map[string]string)
+	labeler.addLabels(labels, now.Add(instanceTagsTTL))
+	assert.Equal(t, "1234", labels["CostCenter"], "cached tags should be kept when the lookup fails")
+
+	labels = make(map[string]string)
+	labeler.addLabels(labels, now.Add(instanceTagsTTL+time.Second))
+	assert.Equal(t, "1234", labels["CostCenter"], "failed lookups should not be retried until the tags expire")
+}
@@ -30,6 +30,16 @@ type TaskEngine interface {
 	// (e.g. right before exiting down the process). It will irreversably stop
 	// this task engine from processing new tasks
 	Disable()
+	// Drain stops the engine from accepting new tasks, which are stopped as
+	// soon as they are added, and moves all of the tasks it manages to STOPPED
+	Drain()
+	// Pause stops the engine from starting new tasks until it is resumed. The
+	// tasks it manages already keep running and being verified
+	Pause()
+	// Resume starts the tasks held while the engine was paused
+	Resume()
+	// Paused returns true if the engine is paused
+	Paused() bool
 
 	// StateChangeEvents will provide information about tasks that have been previously
 	// executed. Specifically, it will provide information when they reach
@@ -0,0 +1,53 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/cihub/seelog"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// resolveLogSecretOptions resolves the secret log driver options of the
+// container with the credentials of the task role, and sets them on the log
+// config the container is created with. Only the names of the options are
+// logged, never their values
+func (engine *DockerTaskEngine) resolveLogSecretOptions(task *api.Task, container *api.Container, hostConfig *docker.HostConfig) engineError {
+	if len(container.LogSecretOptions) == 0 {
+		return nil
+	}
+	credentialsID := task.GetCredentialsID()
+	if credentialsID == "" {
+		return LogSecretOptionError{fmt.Errorf("secret log driver options of container %s require a task role", container.Name)}
+	}
+	taskCredentials, ok := engine.credentialsManager.GetTaskCredentials(credentialsID)
+	if !ok {
+		return LogSecretOptionError{fmt.Errorf("credentials of the task role not found to resolve the secret log driver options of container %s", container.Name)}
+	}
+
+	if hostConfig.LogConfig.Config == nil {
+		hostConfig.LogConfig.Config = make(map[string]string)
+	}
+	for _, option := range container.LogSecretOptions {
+		value, err := engine.secretsResolver.Resolve(option.ValueFrom, engine.cfg.AWSRegion, taskCredentials.IAMRoleCredentials)
+		if err != nil {
+			return LogSecretOptionError{fmt.Errorf("unable to resolve log driver option %s of container %s: %v", option.Name, container.Name, err)}
+		}
+		seelog.Debugf("Resolved secret log driver option %s of container %s, task: %s", option.Name, container.Name, task.Arn)
+		hostConfig.LogConfig.Config[option.Name] = value
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/secrets/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logSecretsTask() *api.Task {
+	task := &api.Task{
+		Arn:     "myTaskArn",
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				DockerConfig: api.DockerConfig{
+					HostConfig: aws.String(`{"LogConfig":{"Type":"splunk","Config":{"splunk-url":"https://splunk.example.com"}}}`),
+				},
+				LogSecretOptions: []api.LogSecretOption{
+					{Name: "splunk-token", ValueFrom: "arn:aws:ssm:us-west-2:123456789012:parameter/splunk-token"},
+				},
+			},
+		},
+	}
+	task.SetCredentialsID(credentialsID)
+	return task
+}
+
+func TestCreateContainerResolvesLogSecretOptions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	ctrl, client, _, taskEngine, credentialsManager, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	resolver := mock_secrets.NewMockResolver(ctrl)
+	taskEngine.(*DockerTaskEngine).secretsResolver = resolver
+
+	task := logSecretsTask()
+	roleCredentials := credentials.TaskIAMRoleCredentials{
+		IAMRoleCredentials: credentials.IAMRoleCredentials{CredentialsID: credentialsID, AccessKeyID: "akid"},
+	}
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(roleCredentials, true)
+	resolver.EXPECT().Resolve("arn:aws:ssm:us-west-2:123456789012:parameter/splunk-token", "us-west-2",
+		roleCredentials.IAMRoleCredentials).Return("secret-token", nil)
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Equal(t, map[string]string{
+				"splunk-url":   "https://splunk.example.com",
+				"splunk-token": "secret-token",
+			}, hostConfig.LogConfig.Config)
+		})
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	assert.NoError(t, metadata.Error)
+
+	_, effectiveHostConfig, ok := task.Containers[0].GetEffectiveConfig()
+	require.True(t, ok)
+	assert.NotEqual(t, "secret-token", effectiveHostConfig.LogConfig.Config["splunk-token"],
+		"secret values should not be recorded")
+}
+
+func TestCreateContainerLogSecretOptionResolutionFailure(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.AWSRegion = "us-west-2"
+	ctrl, _, _, taskEngine, credentialsManager, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	resolver := mock_secrets.NewMockResolver(ctrl)
+	taskEngine.(*DockerTaskEngine).secretsResolver = resolver
+
+	task := logSecretsTask()
+	credentialsManager.EXPECT().GetTaskCredentials(credentialsID).Return(credentials.TaskIAMRoleCredentials{}, true)
+	resolver.EXPECT().Resolve(gomock.Any(), gomock.Any(), gomock.Any()).Return("", errors.New("AccessDeniedException"))
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "LogSecretOptionError", metadata.Error.ErrorName())
+	assert.Contains(t, metadata.Error.Error(), "splunk-token")
+}
+
+func TestCreateContainerLogSecretOptionsWithoutTaskRole(t *testing.T) {
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+
+	task := logSecretsTask()
+	task.SetCredentialsID("")
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.Error(t, metadata.Error)
+	assert.Equal(t, "LogSecretOptionError", metadata.Error.ErrorName())
+}
@@ -0,0 +1,80 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+)
+
+// pauseImagePull tracks the pull of a custom pause image, which starts when
+// the engine is initialized. Pause containers are not created until it
+// completes
+type pauseImagePull struct {
+	image string
+	// done is closed once the pull completes, successfully or not
+	done chan struct{}
+	// err is the error of the pull. It must only be read once done is closed
+	err error
+}
+
+// newPauseImagePull returns the pull of the pause image if it is enabled and
+// the image is not loaded from the tarball, or nil otherwise
+func newPauseImagePull(cfg *config.Config) *pauseImagePull {
+	if !cfg.PauseImagePrefetchEnabled {
+		return nil
+	}
+	if cfg.PauseContainerImageName == config.DefaultPauseContainerImageName &&
+		cfg.PauseContainerTag == config.DefaultPauseContainerTag {
+		return nil
+	}
+	return &pauseImagePull{
+		image: cfg.PauseContainerImageName + ":" + cfg.PauseContainerTag,
+		done:  make(chan struct{}),
+	}
+}
+
+// pullPauseImage pulls the pause image, if the engine tracks its pull. It
+// runs in the background so that tasks are accepted while the image is pulled
+func (engine *DockerTaskEngine) pullPauseImage() {
+	if engine.pauseImage == nil {
+		return
+	}
+	engine.pauseImage.err = engine.prefetchImage(engine.pauseImage.image)
+	close(engine.pauseImage.done)
+}
+
+// waitForPauseImage blocks until the pause image is pulled, if it is still
+// being pulled. The task reports that it is waiting for the pause image in
+// the meantime, since none of its containers can be created before the pause
+// container
+func (engine *DockerTaskEngine) waitForPauseImage(task *api.Task) {
+	if engine.pauseImage == nil {
+		return
+	}
+	select {
+	case <-engine.pauseImage.done:
+	default:
+		seelog.Infof("Task [%s]: waiting for pause image %s to be pulled", task.String(), engine.pauseImage.image)
+		task.SetBlockedReason(api.TaskBlockedOnPauseImage)
+		<-engine.pauseImage.done
+		task.SetBlockedReason("")
+	}
+	if engine.pauseImage.err != nil {
+		// The image may still be present on the instance, in which case the
+		// pause container can be created anyway
+		seelog.Warnf("Task [%s]: pause image %s could not be pulled: %v", task.String(), engine.pauseImage.image, engine.pauseImage.err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pauseImageConfig() config.Config {
+	cfg := config.DefaultConfig()
+	cfg.PauseImagePrefetchEnabled = true
+	cfg.PauseContainerImageName = "custom-pause"
+	cfg.PauseContainerTag = "1.0"
+	return cfg
+}
+
+func pauseImageTask() (*api.Task, *api.Container) {
+	pause := &api.Container{Name: api.PauseContainerName, Type: api.ContainerCNIPause}
+	task := &api.Task{Arn: "myTaskArn", Containers: []*api.Container{pause}}
+	return task, pause
+}
+
+func TestNewPauseImagePull(t *testing.T) {
+	cfg := pauseImageConfig()
+	pull := newPauseImagePull(&cfg)
+	require.NotNil(t, pull)
+	assert.Equal(t, "custom-pause:1.0", pull.image)
+
+	cfg.PauseImagePrefetchEnabled = false
+	assert.Nil(t, newPauseImagePull(&cfg))
+
+	cfg = pauseImageConfig()
+	cfg.PauseContainerImageName = config.DefaultPauseContainerImageName
+	cfg.PauseContainerTag = config.DefaultPauseContainerTag
+	assert.Nil(t, newPauseImagePull(&cfg), "the default pause image is loaded from the tarball")
+}
+
+func TestInitPullsPauseImage(t *testing.T) {
+	cfg := pauseImageConfig()
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+	client.EXPECT().PullImage("custom-pause:1.0", nil, "").Return(DockerContainerMetadata{})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	require.NoError(t, taskEngine.Init(ctx))
+
+	select {
+	case <-taskEngine.(*DockerTaskEngine).pauseImage.done:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the pause image to be pulled")
+	}
+	assert.NoError(t, taskEngine.(*DockerTaskEngine).pauseImage.err)
+}
+
+func TestPullPauseContainerWaitsForPauseImage(t *testing.T) {
+	cfg := pauseImageConfig()
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	task, pause := pauseImageTask()
+	pulled := make(chan DockerContainerMetadata)
+	go func() {
+		pulled <- engine.pullContainer(task, pause)
+	}()
+
+	for deadline := time.Now().Add(time.Second); task.GetBlockedReason() == ""; {
+		require.True(t, time.Now().Before(deadline), "task should report that it waits for the pause image")
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, api.TaskBlockedOnPauseImage, task.GetBlockedReason())
+	select {
+	case <-pulled:
+		t.Fatal("pause container should wait for the pause image to be pulled")
+	default:
+	}
+
+	client.EXPECT().PullImage("custom-pause:1.0", nil, "").Return(DockerContainerMetadata{})
+	engine.pullPauseImage()
+
+	select {
+	case metadata := <-pulled:
+		assert.NoError(t, metadata.Error)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for the pause container to be pulled")
+	}
+	assert.Empty(t, task.GetBlockedReason())
+}
+
+func TestPullPauseContainerPrefetchedPauseImage(t *testing.T) {
+	cfg := pauseImageConfig()
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	client.EXPECT().PullImage("custom-pause:1.0", nil, "").Return(DockerContainerMetadata{})
+	engine.pullPauseImage()
+
+	task, pause := pauseImageTask()
+	pulled := make(chan DockerContainerMetadata)
+	go func() {
+		pulled <- engine.pullContainer(task, pause)
+	}()
+	select {
+	case metadata := <-pulled:
+		assert.NoError(t, metadata.Error)
+	case <-time.After(time.Second):
+		t.Fatal("pause container should not wait for a prefetched pause image")
+	}
+	assert.Empty(t, task.GetBlockedReason())
+}
@@ -0,0 +1,89 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sort"
+	"sync"
+)
+
+// prioritySemaphore is a counting semaphore that hands out free slots to
+// waiters in the order of their priority, highest priority first. Waiters with
+// the same priority are served in the order in which they arrived.
+type prioritySemaphore struct {
+	lock      sync.Mutex
+	available int
+	sequence  uint64
+	waiters   []*semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	priority int
+	sequence uint64
+	ready    chan struct{}
+}
+
+// newPrioritySemaphore returns a semaphore with the given number of slots
+func newPrioritySemaphore(size int) *prioritySemaphore {
+	return &prioritySemaphore{
+		available: size,
+	}
+}
+
+// acquire blocks until a slot is handed out to the caller
+func (sem *prioritySemaphore) acquire(priority int) {
+	sem.lock.Lock()
+	if sem.available > 0 && len(sem.waiters) == 0 {
+		sem.available--
+		sem.lock.Unlock()
+		return
+	}
+	waiter := &semaphoreWaiter{
+		priority: priority,
+		sequence: sem.sequence,
+		ready:    make(chan struct{}),
+	}
+	sem.sequence++
+	// Keep the waiters sorted by priority, and by arrival within a priority
+	index := sort.Search(len(sem.waiters), func(i int) bool {
+		return sem.waiters[i].priority < priority
+	})
+	sem.waiters = append(sem.waiters, nil)
+	copy(sem.waiters[index+1:], sem.waiters[index:])
+	sem.waiters[index] = waiter
+	sem.lock.Unlock()
+
+	<-waiter.ready
+}
+
+// release returns a slot, handing it to the highest priority waiter if there
+// is one
+func (sem *prioritySemaphore) release() {
+	sem.lock.Lock()
+	defer sem.lock.Unlock()
+	if len(sem.waiters) == 0 {
+		sem.available++
+		return
+	}
+	waiter := sem.waiters[0]
+	sem.waiters = sem.waiters[1:]
+	close(waiter.ready)
+}
+
+// numWaiters returns the number of callers blocked in acquire
+func (sem *prioritySemaphore) numWaiters() int {
+	sem.lock.Lock()
+	defer sem.lock.Unlock()
+	return len(sem.waiters)
+}
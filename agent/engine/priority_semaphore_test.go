@@ -0,0 +1,61 @@
+// +build !integration
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrioritySemaphoreAcquireWithoutContention(t *testing.T) {
+	sem := newPrioritySemaphore(2)
+	sem.acquire(0)
+	sem.acquire(0)
+	assert.Equal(t, 0, sem.numWaiters())
+	sem.release()
+	sem.release()
+	assert.Equal(t, 2, sem.available)
+}
+
+func TestPrioritySemaphoreServesHighestPriorityFirst(t *testing.T) {
+	sem := newPrioritySemaphore(1)
+	sem.acquire(0)
+
+	var order []int
+	var orderLock sync.Mutex
+	var wg sync.WaitGroup
+	for i, priority := range []int{1, 5, 1, 3} {
+		wg.Add(1)
+		go func(priority int) {
+			defer wg.Done()
+			sem.acquire(priority)
+			orderLock.Lock()
+			order = append(order, priority)
+			orderLock.Unlock()
+			sem.release()
+		}(priority)
+		// Wait for the waiter to be queued so that arrival order is deterministic
+		for sem.numWaiters() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	sem.release()
+	wg.Wait()
+	assert.Equal(t, []int{5, 3, 1, 1}, order)
+}
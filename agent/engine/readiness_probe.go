@@ -0,0 +1,148 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/cihub/seelog"
+)
+
+// localhost is the address probed for containers that are only reachable
+// through the ports they publish on the host, or that use the host network
+const localhost = "127.0.0.1"
+
+// startReadinessProbes starts the readiness probes of the running containers
+// of the task whose readiness is not known yet, e.g. after the state of the
+// task was restored
+func (mtask *managedTask) startReadinessProbes() {
+	for _, container := range mtask.Containers {
+		if container.GetKnownStatus() == api.ContainerRunning &&
+			container.GetReadinessStatus() == api.ContainerReadinessUnknown {
+			mtask.startReadinessProbe(container)
+		}
+	}
+}
+
+// startReadinessProbe starts probing the readiness of the container, if it
+// has a readiness probe that is not running already
+func (mtask *managedTask) startReadinessProbe(container *api.Container) {
+	if container.ReadinessProbe == nil || mtask.readinessProbes[container.Name] {
+		return
+	}
+	mtask.readinessProbes[container.Name] = true
+	go mtask.runReadinessProbe(container)
+}
+
+// runReadinessProbe probes the container until it is ready, its probe failed
+// more times than it is retried, or it is no longer running. The task manager
+// is notified when the readiness of the container is determined
+func (mtask *managedTask) runReadinessProbe(container *api.Container) {
+	probe := container.ReadinessProbe
+	if probe.Type != api.ReadinessProbeTCP && probe.Type != api.ReadinessProbeHTTP {
+		seelog.Warnf("Unsupported readiness probe type %s of container %s of task %s, the container will not be ready",
+			probe.Type, container.Name, mtask.Arn)
+		mtask.setReadiness(container, api.ContainerNotReady)
+		return
+	}
+
+	failures := 0
+	for {
+		if container.DesiredTerminal() || container.GetKnownStatus() != api.ContainerRunning {
+			seelog.Debugf("Stopped probing the readiness of container %s of task %s, which is no longer running",
+				container.Name, mtask.Arn)
+			return
+		}
+		err := checkReadiness(probe, mtask.readinessProbeAddress(container))
+		if err == nil {
+			seelog.Infof("Container %s of task %s is ready", container.Name, mtask.Arn)
+			mtask.setReadiness(container, api.ContainerReady)
+			return
+		}
+		failures++
+		seelog.Debugf("Readiness probe of container %s of task %s failed: %v", container.Name, mtask.Arn, err)
+		if failures >= probe.RetryCount() {
+			seelog.Warnf("Readiness probe of container %s of task %s failed %d times, the container will not be ready: %v",
+				container.Name, mtask.Arn, failures, err)
+			mtask.setReadiness(container, api.ContainerNotReady)
+			return
+		}
+		mtask.time().Sleep(probe.IntervalDuration())
+	}
+}
+
+// setReadiness records the readiness of the container and wakes up the task
+// manager, which may be waiting on it to transition other containers
+func (mtask *managedTask) setReadiness(container *api.Container, readiness api.ContainerReadinessStatus) {
+	container.SetReadinessStatus(readiness)
+	select {
+	case mtask.readinessChanges <- struct{}{}:
+	default:
+		// The task manager was already notified of a change it did not
+		// handle yet
+	}
+}
+
+// readinessProbeAddress returns the address the readiness probe of the
+// container connects to
+func (mtask *managedTask) readinessProbeAddress(container *api.Container) string {
+	port := container.ReadinessProbe.Port
+	if ipv4Address := container.GetIPv4Address(); ipv4Address != "" {
+		return net.JoinHostPort(ipv4Address, strconv.Itoa(int(port)))
+	}
+	if eni := mtask.GetTaskENI(); eni != nil && len(eni.IPV4Addresses) > 0 {
+		return net.JoinHostPort(eni.IPV4Addresses[0].Address, strconv.Itoa(int(port)))
+	}
+	for _, binding := range container.KnownPortBindings {
+		if binding.ContainerPort == port && binding.Protocol == api.TransportProtocolTCP {
+			return net.JoinHostPort(localhost, strconv.Itoa(int(binding.HostPort)))
+		}
+	}
+	return net.JoinHostPort(localhost, strconv.Itoa(int(port)))
+}
+
+// checkReadiness makes a single check of the readiness probe against the
+// address, returning an error if it fails
+func checkReadiness(probe *api.ReadinessProbe, address string) error {
+	timeout := probe.TimeoutDuration()
+	switch probe.Type {
+	case api.ReadinessProbeTCP:
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case api.ReadinessProbeHTTP:
+		path := probe.Path
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get("http://" + address + path)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported readiness probe type %s", probe.Type)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dependencygraph"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readinessProbeTask(probe *api.ReadinessProbe) (*managedTask, *api.Container, *api.Container) {
+	db := &api.Container{
+		Name:                "db",
+		DesiredStatusUnsafe: api.ContainerRunning,
+		KnownStatusUnsafe:   api.ContainerRunning,
+		ReadinessProbe:      probe,
+	}
+	app := &api.Container{
+		Name:                "app",
+		DesiredStatusUnsafe: api.ContainerRunning,
+		KnownStatusUnsafe:   api.ContainerPulled,
+	}
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedReady:  true,
+		DependentStatus: api.ContainerCreated,
+	}}
+	mtask := &managedTask{
+		Task: &api.Task{
+			Arn:                 "myTaskArn",
+			Containers:          []*api.Container{db, app},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		readinessChanges: make(chan struct{}, 1),
+		readinessProbes:  make(map[string]bool),
+	}
+	return mtask, db, app
+}
+
+func waitForReadinessChange(t *testing.T, mtask *managedTask) {
+	select {
+	case <-mtask.readinessChanges:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the readiness of the container to be determined")
+	}
+}
+
+func TestReadinessProbeGatesDependent(t *testing.T) {
+	// Find a free port, which nothing listens on until the container is ready
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	mtask, db, app := readinessProbeTask(&api.ReadinessProbe{
+		Type:     api.ReadinessProbeTCP,
+		Port:     uint16(port),
+		Interval: 1,
+		Retries:  10,
+	})
+	mtask.startReadinessProbe(db)
+	// The probe is only started once
+	mtask.startReadinessProbe(db)
+
+	assert.Equal(t, api.ContainerReadinessUnknown, db.GetReadinessStatus())
+	assert.False(t, dependencygraph.DependenciesAreResolved(app, mtask.Containers), "app shouldn't be created before db is ready")
+	assert.True(t, mtask.waitingForReadyDependencies())
+
+	listener, err = net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	require.NoError(t, err)
+	defer listener.Close()
+
+	waitForReadinessChange(t, mtask)
+	assert.Equal(t, api.ContainerReady, db.GetReadinessStatus())
+	assert.True(t, dependencygraph.DependenciesAreResolved(app, mtask.Containers), "app should be created once db is ready")
+	assert.False(t, mtask.waitingForReadyDependencies())
+}
+
+func TestReadinessProbeTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Never respond within the timeout of the probe
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	mtask, db, app := readinessProbeTask(&api.ReadinessProbe{
+		Type:    api.ReadinessProbeHTTP,
+		Port:    uint16(port),
+		Path:    "ready",
+		Timeout: 1,
+		Retries: 1,
+	})
+	start := time.Now()
+	mtask.startReadinessProbe(db)
+
+	waitForReadinessChange(t, mtask)
+	assert.True(t, time.Since(start) >= time.Second, "the probe should wait for its timeout")
+	assert.Equal(t, api.ContainerNotReady, db.GetReadinessStatus())
+	assert.False(t, dependencygraph.DependenciesAreResolved(app, mtask.Containers))
+	// The task is stopped since app can never be created
+	assert.False(t, mtask.waitingForReadyDependencies())
+}
+
+func TestReadinessProbeDefaultRetries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockTime := mock_ttime.NewMockTime(ctrl)
+
+	// Nothing listens on a port that was just released, so every check fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	mtask, db, _ := readinessProbeTask(&api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: uint16(port)})
+	mtask._time = mockTime
+	// The probe waits on the clock of the task between its 3 checks
+	mockTime.EXPECT().Sleep(5 * time.Second).Times(2)
+
+	mtask.runReadinessProbe(db)
+	assert.Equal(t, api.ContainerNotReady, db.GetReadinessStatus())
+}
+
+func TestReadinessProbeStopsWhenContainerStops(t *testing.T) {
+	mtask, db, _ := readinessProbeTask(&api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: 1})
+	db.SetDesiredStatus(api.ContainerStopped)
+
+	mtask.runReadinessProbe(db)
+	assert.Equal(t, api.ContainerReadinessUnknown, db.GetReadinessStatus())
+}
+
+func TestReadinessProbeUnsupportedType(t *testing.T) {
+	mtask, db, _ := readinessProbeTask(&api.ReadinessProbe{Type: "udp", Port: 53})
+
+	mtask.runReadinessProbe(db)
+	assert.Equal(t, api.ContainerNotReady, db.GetReadinessStatus())
+}
+
+func TestCheckReadinessHTTPStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	address := server.Listener.Addr().String()
+
+	assert.NoError(t, checkReadiness(&api.ReadinessProbe{Type: api.ReadinessProbeHTTP, Path: "/ready"}, address))
+	assert.Error(t, checkReadiness(&api.ReadinessProbe{Type: api.ReadinessProbeHTTP, Path: "/starting"}, address))
+}
+
+func TestReadinessProbeAddress(t *testing.T) {
+	mtask, db, _ := readinessProbeTask(&api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: 80})
+	assert.Equal(t, "127.0.0.1:80", mtask.readinessProbeAddress(db))
+
+	db.KnownPortBindings = []api.PortBinding{{ContainerPort: 80, HostPort: 32768, Protocol: api.TransportProtocolTCP}}
+	assert.Equal(t, "127.0.0.1:32768", mtask.readinessProbeAddress(db))
+
+	db.SetIPv4Address("172.17.0.2")
+	assert.Equal(t, "172.17.0.2:80", mtask.readinessProbeAddress(db))
+}
@@ -0,0 +1,105 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/cihub/seelog"
+)
+
+// steadyStatePollInterval adapts the interval at which tasks in steady state
+// are checked with Docker to the latency of Docker responses, within bounds.
+type steadyStatePollInterval struct {
+	min              time.Duration
+	max              time.Duration
+	latencyThreshold time.Duration
+
+	lock     sync.Mutex
+	interval time.Duration
+}
+
+// newSteadyStatePollInterval returns an interval starting at initial, clamped
+// between min and max, that is doubled up to max when Docker responds slower
+// than latencyThreshold and halved down to min when it responds faster
+func newSteadyStatePollInterval(initial, min, max, latencyThreshold time.Duration) *steadyStatePollInterval {
+	poll := &steadyStatePollInterval{
+		min:              min,
+		max:              max,
+		latencyThreshold: latencyThreshold,
+	}
+	poll.interval = poll.clamp(initial)
+	return poll
+}
+
+// get returns the current interval
+func (poll *steadyStatePollInterval) get() time.Duration {
+	poll.lock.Lock()
+	defer poll.lock.Unlock()
+
+	return poll.interval
+}
+
+// observe adapts the interval to the latency of a Docker response. It does
+// nothing if the interval is not adapted
+func (poll *steadyStatePollInterval) observe(latency time.Duration) {
+	if poll == nil {
+		return
+	}
+	poll.lock.Lock()
+	defer poll.lock.Unlock()
+
+	previous := poll.interval
+	if latency > poll.latencyThreshold {
+		poll.interval = poll.clamp(poll.interval * 2)
+	} else {
+		poll.interval = poll.clamp(poll.interval / 2)
+	}
+	if poll.interval != previous {
+		seelog.Debugf("Adapted steady state poll interval from %v to %v after Docker responded in %v", previous, poll.interval, latency)
+	}
+}
+
+// clamp returns the interval bounded by min and max
+func (poll *steadyStatePollInterval) clamp(interval time.Duration) time.Duration {
+	if interval < poll.min {
+		return poll.min
+	}
+	if interval > poll.max {
+		return poll.max
+	}
+	return interval
+}
+
+// steadyStateVerifyInterval returns the interval after which tasks in steady
+// state are checked with Docker. The adaptive interval is used if enabled,
+// otherwise the configured interval
+func (engine *DockerTaskEngine) steadyStateVerifyInterval() time.Duration {
+	if engine.steadyStatePoll != nil {
+		return engine.steadyStatePoll.get()
+	}
+	return engine.fixedSteadyStateVerifyInterval()
+}
+
+// fixedSteadyStateVerifyInterval returns the configured interval after which
+// tasks in steady state are checked with Docker, or the default interval if
+// it is unset
+func (engine *DockerTaskEngine) fixedSteadyStateVerifyInterval() time.Duration {
+	if engine.cfg.SteadyStateVerifyInterval > 0 {
+		return engine.cfg.SteadyStateVerifyInterval
+	}
+	return config.DefaultSteadyStateVerifyInterval
+}
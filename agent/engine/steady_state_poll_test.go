@@ -0,0 +1,113 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSteadyStatePollIntervalAdapts(t *testing.T) {
+	poll := newSteadyStatePollInterval(time.Minute, time.Minute, 8*time.Minute, 2*time.Second)
+	assert.Equal(t, time.Minute, poll.get())
+
+	poll.observe(5 * time.Second)
+	assert.Equal(t, 2*time.Minute, poll.get(), "interval should back off when docker is slow")
+	poll.observe(5 * time.Second)
+	poll.observe(5 * time.Second)
+	poll.observe(5 * time.Second)
+	assert.Equal(t, 8*time.Minute, poll.get(), "interval should not exceed the maximum")
+
+	poll.observe(100 * time.Millisecond)
+	assert.Equal(t, 4*time.Minute, poll.get(), "interval should speed up when docker is fast")
+	poll.observe(100 * time.Millisecond)
+	poll.observe(100 * time.Millisecond)
+	assert.Equal(t, time.Minute, poll.get(), "interval should not go below the minimum")
+}
+
+func TestSteadyStatePollIntervalStartsAtConfiguredInterval(t *testing.T) {
+	poll := newSteadyStatePollInterval(4*time.Minute, 30*time.Second, 8*time.Minute, 2*time.Second)
+	assert.Equal(t, 4*time.Minute, poll.get(), "interval should start at the configured interval")
+
+	poll.observe(100 * time.Millisecond)
+	poll.observe(100 * time.Millisecond)
+	poll.observe(100 * time.Millisecond)
+	assert.Equal(t, 30*time.Second, poll.get(), "interval should speed up down to the minimum")
+}
+
+func TestSteadyStatePollIntervalClampsInitialInterval(t *testing.T) {
+	poll := newSteadyStatePollInterval(time.Second, 30*time.Second, 8*time.Minute, 2*time.Second)
+	assert.Equal(t, 30*time.Second, poll.get())
+
+	poll = newSteadyStatePollInterval(time.Hour, 30*time.Second, 8*time.Minute, 2*time.Second)
+	assert.Equal(t, 8*time.Minute, poll.get())
+}
+
+func TestSteadyStatePollIntervalFixed(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SteadyStateVerifyInterval = 30 * time.Second
+	ctrl, _, _, privateTaskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	taskEngine.steadyStatePoll.observe(time.Minute)
+	assert.Equal(t, 30*time.Second, taskEngine.steadyStateVerifyInterval())
+}
+
+func TestCheckTaskStateAdaptsSteadyStatePollInterval(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.SteadyStateVerifyInterval = time.Minute
+	cfg.SteadyStatePollMinInterval = 30 * time.Second
+	cfg.SteadyStatePollMaxInterval = 8 * time.Minute
+	cfg.SteadyStatePollLatencyThreshold = 2 * time.Second
+	ctrl, client, _, privateTaskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	taskEngine, _ := privateTaskEngine.(*DockerTaskEngine)
+
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	ttime.SetTime(mockTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	task := testdata.LoadTask("sleep5")
+	container := task.Containers[0]
+	taskEngine.state.AddTask(task)
+	taskEngine.state.AddContainer(&api.DockerContainer{DockerID: containerID, Container: container}, task)
+
+	now := time.Now()
+	gomock.InOrder(
+		// Docker takes 5 seconds to respond
+		mockTime.EXPECT().Now().Return(now),
+		client.EXPECT().DescribeContainer(containerID).Return(api.ContainerRunning, DockerContainerMetadata{}),
+		mockTime.EXPECT().Now().Return(now.Add(5*time.Second)),
+		// Docker takes 10 milliseconds to respond
+		mockTime.EXPECT().Now().Return(now),
+		client.EXPECT().DescribeContainer(containerID).Return(api.ContainerRunning, DockerContainerMetadata{}),
+		mockTime.EXPECT().Now().Return(now.Add(10*time.Millisecond)),
+	)
+
+	assert.Equal(t, time.Minute, taskEngine.steadyStatePoll.get())
+	taskEngine.CheckTaskState(task)
+	assert.Equal(t, 2*time.Minute, taskEngine.steadyStatePoll.get(), "interval should back off after a slow response")
+	taskEngine.CheckTaskState(task)
+	assert.Equal(t, time.Minute, taskEngine.steadyStatePoll.get(), "interval should speed up after a fast response")
+	assert.Equal(t, 30*time.Second, taskEngine.steadyStatePoll.min)
+}
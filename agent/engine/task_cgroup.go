@@ -0,0 +1,94 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"path"
+	"strings"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/cgroup"
+	"github.com/cihub/seelog"
+)
+
+const (
+	// taskCgroupRoot is the cgroup the parent cgroups of tasks are created in
+	taskCgroupRoot = "/ecs"
+	bytesPerMiB    = 1024 * 1024
+)
+
+// taskCgroupPath returns the path of the parent cgroup of a task, which is
+// named after the id at the end of its arn
+func taskCgroupPath(taskARN string) string {
+	return path.Join(taskCgroupRoot, taskARN[strings.LastIndex(taskARN, "/")+1:])
+}
+
+// taskCgroupEnabled returns true if the containers of the task are created in
+// a parent cgroup that enforces the task-level limits of the task
+func (engine *DockerTaskEngine) taskCgroupEnabled(task *api.Task) bool {
+	return engine.cgroupControl != nil && (task.CPU > 0 || task.Memory > 0)
+}
+
+// setupTaskCgroup creates the parent cgroup of the task with its task-level
+// limits
+func (engine *DockerTaskEngine) setupTaskCgroup(task *api.Task) error {
+	if !engine.taskCgroupEnabled(task) {
+		return nil
+	}
+	spec := cgroup.Spec{
+		Path:        taskCgroupPath(task.Arn),
+		CPUPeriod:   cgroup.DefaultCPUPeriod,
+		CPUQuota:    int64(task.CPU * cgroup.DefaultCPUPeriod),
+		MemoryLimit: task.Memory * bytesPerMiB,
+	}
+	seelog.Infof("Task [%s]: creating cgroup %s with cpu quota %d and memory limit %d",
+		task.String(), spec.Path, spec.CPUQuota, spec.MemoryLimit)
+	return engine.cgroupControl.Create(spec)
+}
+
+// cleanupTaskCgroup removes the parent cgroup of the task
+func (engine *DockerTaskEngine) cleanupTaskCgroup(task *api.Task) error {
+	if !engine.taskCgroupEnabled(task) {
+		return nil
+	}
+	seelog.Infof("Task [%s]: removing cgroup %s", task.String(), taskCgroupPath(task.Arn))
+	return engine.cgroupControl.Remove(taskCgroupPath(task.Arn))
+}
+
+// provisionTaskResources creates the resources the containers of the task
+// need before any of them is created. The task is stopped if they cannot be
+// created
+func (mtask *managedTask) provisionTaskResources() {
+	if mtask.GetDesiredStatus().Terminal() || mtask.GetKnownStatus().Terminal() {
+		return
+	}
+	err := mtask.engine.setupTaskCgroup(mtask.Task)
+	if err == nil {
+		return
+	}
+	seelog.Errorf("Task [%s]: unable to create the cgroup of the task, stopping it: %v", mtask.Task.String(), err)
+	for _, container := range mtask.Containers {
+		if container.ApplyingError == nil {
+			container.ApplyingError = api.NewNamedError(TaskCgroupError{err})
+		}
+	}
+	mtask.handleDesiredStatusChange(api.TaskStopped, 0)
+}
+
+// cleanupTaskResources removes the resources created for the containers of
+// the task once it stopped
+func (mtask *managedTask) cleanupTaskResources() {
+	if err := mtask.engine.cleanupTaskCgroup(mtask.Task); err != nil {
+		seelog.Warnf("Task [%s]: unable to remove the cgroup of the task: %v", mtask.Task.String(), err)
+	}
+}
@@ -0,0 +1,114 @@
+// +build linux,!integration
+
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+package engine
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/cgroup"
+	"github.com/aws/amazon-ecs-agent/agent/cgroup/mocks"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cgroupTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/a1b2c3d4"
+
+func taskWithLimits() *api.Task {
+	return &api.Task{
+		Arn:    cgroupTaskARN,
+		CPU:    0.5,
+		Memory: 512,
+		Containers: []*api.Container{
+			{Name: "c1", Image: "myImage"},
+			{Name: "c2", Image: "myImage"},
+		},
+		DesiredStatusUnsafe: api.TaskRunning,
+	}
+}
+
+func TestTaskCgroupPassedToContainers(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	control := mock_cgroup.NewMockControl(ctrl)
+	engine := taskEngine.(*DockerTaskEngine)
+	engine.cgroupControl = control
+
+	task := taskWithLimits()
+	control.EXPECT().Create(cgroup.Spec{
+		Path:        "/ecs/a1b2c3d4",
+		CPUPeriod:   100000,
+		CPUQuota:    50000,
+		MemoryLimit: 512 * 1024 * 1024,
+	}).Return(nil)
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Equal(t, "/ecs/a1b2c3d4", hostConfig.CgroupParent)
+		}).Times(len(task.Containers))
+
+	mtask := &managedTask{Task: task, engine: engine}
+	mtask.provisionTaskResources()
+	for _, container := range task.Containers {
+		metadata := engine.createContainer(task, container)
+		assert.NoError(t, metadata.Error)
+	}
+
+	control.EXPECT().Remove("/ecs/a1b2c3d4").Return(nil)
+	mtask.cleanupTaskResources()
+}
+
+func TestTaskCgroupNotCreatedWithoutLimits(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+	engine.cgroupControl = mock_cgroup.NewMockControl(ctrl)
+
+	task := taskWithLimits()
+	task.CPU = 0
+	task.Memory = 0
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Empty(t, hostConfig.CgroupParent)
+		})
+
+	mtask := &managedTask{Task: task, engine: engine}
+	mtask.provisionTaskResources()
+	metadata := engine.createContainer(task, task.Containers[0])
+	assert.NoError(t, metadata.Error)
+	mtask.cleanupTaskResources()
+}
+
+func TestTaskCgroupCreationFailureStopsTask(t *testing.T) {
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	control := mock_cgroup.NewMockControl(ctrl)
+	engine := taskEngine.(*DockerTaskEngine)
+	engine.cgroupControl = control
+
+	task := taskWithLimits()
+	control.EXPECT().Create(gomock.Any()).Return(errors.New("permission denied"))
+
+	mtask := &managedTask{Task: task, engine: engine}
+	mtask.provisionTaskResources()
+	assert.Equal(t, api.TaskStopped, task.GetDesiredStatus())
+	for _, container := range task.Containers {
+		require.NotNil(t, container.ApplyingError)
+		assert.Equal(t, "TaskCgroupError", container.ApplyingError.ErrorName())
+	}
+}
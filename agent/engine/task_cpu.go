@@ -0,0 +1,39 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"runtime"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
+
+// instanceCPUUnits returns the CPU of the instance in CPU units, the same as
+// is advertised when the container instance is registered
+func instanceCPUUnits() int64 {
+	return int64(runtime.NumCPU() * api.CPUUnitsPerVCPU)
+}
+
+// checkCPUCapacity returns a CPUCapacityError if the task requests more CPU
+// than the instance has, unless the check is disabled
+func (engine *DockerTaskEngine) checkCPUCapacity(task *api.Task) error {
+	if engine.cfg.TaskCPUCapacityCheckDisabled || engine.instanceCPU <= 0 {
+		return nil
+	}
+	cpu := task.ReservedCPU()
+	if cpu <= engine.instanceCPU {
+		return nil
+	}
+	return CPUCapacityError{taskArn: task.Arn, cpu: cpu, instanceCPU: engine.instanceCPU}
+}
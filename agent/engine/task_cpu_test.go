@@ -0,0 +1,77 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestCheckCPUCapacity(t *testing.T) {
+	cfg := defaultConfig
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+	engine.instanceCPU = 2048
+
+	task := &api.Task{Arn: "myTaskArn", CPU: 2}
+	assert.NoError(t, engine.checkCPUCapacity(task))
+
+	task.CPU = 4
+	err := engine.checkCPUCapacity(task)
+	assert.Error(t, err)
+	assert.Equal(t, "CPUCapacityError", err.(CPUCapacityError).ErrorName())
+
+	cfg.TaskCPUCapacityCheckDisabled = true
+	assert.NoError(t, engine.checkCPUCapacity(task), "Check should be skipped when disabled")
+}
+
+// TestTaskOverCPUCapacity tests that a task requesting more CPU than the
+// instance has is stopped with a RESOURCE_CONFLICT reason
+func TestTaskOverCPUCapacity(t *testing.T) {
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &defaultConfig)
+	defer ctrl.Finish()
+	taskEngine.(*DockerTaskEngine).instanceCPU = 2048
+
+	client.EXPECT().Version().Return("1.12.6", nil)
+	client.EXPECT().ContainerEvents(gomock.Any())
+
+	task := &api.Task{
+		Arn:                 "myTaskArn",
+		DesiredStatusUnsafe: api.TaskRunning,
+		CPU:                 4,
+		Containers:          []*api.Container{{Name: "c1", Essential: true}},
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := taskEngine.Init(ctx)
+	assert.NoError(t, err)
+	defer cancel()
+
+	events := taskEngine.StateChangeEvents()
+	go taskEngine.AddTask(task)
+
+	event := <-events
+	taskEvent := event.(api.TaskStateChange)
+	assert.Equal(t, api.TaskStopped, taskEvent.Status, "Expected task to move to stopped directly")
+	assert.Contains(t, taskEvent.Reason, "RESOURCE_CONFLICT")
+	assert.Contains(t, taskEvent.Reason, "4096 CPU units")
+
+	_, ok := taskEngine.(*DockerTaskEngine).managedTasks[task.Arn]
+	assert.False(t, ok, "Task should not be added to task manager for processing")
+}
@@ -25,10 +25,12 @@ import (
 )
 
 const (
-	steadyStateTaskVerifyInterval         = 10 * time.Minute
 	stoppedSentWaitInterval               = 30 * time.Second
 	maxStoppedWaitTimes                   = 72 * time.Hour / stoppedSentWaitInterval
 	taskUnableToTransitionToStoppedReason = "TaskStateError: Agent could not progress task's state to stopped"
+	// healthyDependencyTimeout is how long containers wait for the containers
+	// they depend on to report the health they require before they fail
+	healthyDependencyTimeout = 10 * time.Minute
 )
 
 type acsTaskUpdate struct {
@@ -72,6 +74,12 @@ type managedTask struct {
 
 	acsMessages    chan acsTransition
 	dockerMessages chan dockerContainerChange
+	// readinessChanges is written to when the readiness probe of a container
+	// determines its readiness
+	readinessChanges chan struct{}
+	// readinessProbes holds the names of the containers whose readiness
+	// probe was started
+	readinessProbes map[string]bool
 
 	// unexpectedStart is a once that controls stopping a container that
 	// unexpectedly started one time.
@@ -82,6 +90,11 @@ type managedTask struct {
 	// thing managing the container.
 	unexpectedStart sync.Once
 
+	// healthyDependencyWaitStart is the time containers of the task started
+	// waiting for the containers they depend on to report the health they
+	// require. It is the zero time when no container is waiting
+	healthyDependencyWaitStart time.Time
+
 	_time     ttime.Time
 	_timeOnce sync.Once
 }
@@ -91,10 +104,12 @@ type managedTask struct {
 // already held.
 func (engine *DockerTaskEngine) newManagedTask(task *api.Task) *managedTask {
 	t := &managedTask{
-		Task:           task,
-		acsMessages:    make(chan acsTransition),
-		dockerMessages: make(chan dockerContainerChange),
-		engine:         engine,
+		Task:             task,
+		acsMessages:      make(chan acsTransition),
+		dockerMessages:   make(chan dockerContainerChange),
+		readinessChanges: make(chan struct{}, 1),
+		readinessProbes:  make(map[string]bool),
+		engine:           engine,
 	}
 	engine.managedTasks[task.Arn] = t
 	return t
@@ -112,9 +127,12 @@ func (mtask *managedTask) overseeTask() {
 	mtask.UpdateStatus()
 	// If this was a 'state restore', send all unsent statuses
 	mtask.emitCurrentStatus()
+	mtask.startReadinessProbes()
 
 	// Wait for host resources required by this task to become available
 	mtask.waitForHostResources()
+	mtask.RecordLaunchMilestone(api.TaskLaunchStarted, ttime.Now())
+	mtask.provisionTaskResources()
 
 	// Main infinite loop. This is where we receive messages and dispatch work.
 	for {
@@ -146,13 +164,13 @@ func (mtask *managedTask) overseeTask() {
 	// We only break out of the above if this task is known to be stopped. Do
 	// onetime cleanup here, including removing the task after a timeout
 	llog.Debug("Task has reached stopped. We're just waiting and removing containers now")
-	mtask.cleanupCredentials()
 	if mtask.StopSequenceNumber != 0 {
 		llog.Debug("Marking done for this sequence", "seqnum", mtask.StopSequenceNumber)
 		mtask.engine.taskStopGroup.Done(mtask.StopSequenceNumber)
 	}
 	// TODO: make this idempotent on agent restart
 	go mtask.releaseIPInIPAM()
+	mtask.cleanupTaskResources()
 	mtask.cleanupTask(mtask.engine.cfg.TaskCleanupWaitDuration)
 }
 
@@ -196,7 +214,7 @@ func (mtask *managedTask) waitSteady() {
 	llog.Debug("Task at steady state", "state", mtask.GetKnownStatus().String())
 
 	maxWait := make(chan bool, 1)
-	timer := mtask.time().After(steadyStateTaskVerifyInterval)
+	timer := mtask.time().After(mtask.engine.steadyStateVerifyInterval())
 	go func() {
 		<-timer
 		maxWait <- true
@@ -209,12 +227,21 @@ func (mtask *managedTask) waitSteady() {
 	}
 }
 
-// cleanupCredentials removes credentials for a stopped task
+// cleanupCredentials removes credentials for a task that is being cleaned up,
+// unless they are referenced by another task in the state. It should be called
+// with the processTasks lock held, so that tasks can't be added concurrently
 func (mtask *managedTask) cleanupCredentials() {
 	taskCredentialsID := mtask.GetCredentialsID()
-	if taskCredentialsID != "" {
-		mtask.engine.credentialsManager.RemoveCredentials(taskCredentialsID)
+	if taskCredentialsID == "" {
+		return
+	}
+	for _, task := range mtask.engine.state.AllTasks() {
+		if task != mtask.Task && task.GetCredentialsID() == taskCredentialsID {
+			seelog.Infof("Not removing credentials of task %s, they are referenced by task %s", mtask.Arn, task.Arn)
+			return
+		}
 	}
+	mtask.engine.credentialsManager.RemoveCredentials(taskCredentialsID)
 }
 
 // waitEvent waits for any event to occur. If an event occurs, the appropriate
@@ -231,6 +258,9 @@ func (mtask *managedTask) waitEvent(stopWaiting <-chan bool) bool {
 		log.Debug("Got container event for task", "task", mtask.Task)
 		mtask.handleContainerChange(dockerChange)
 		return false
+	case <-mtask.readinessChanges:
+		log.Debug("Got readiness change for task", "task", mtask.Task)
+		return false
 	case b := <-stopWaiting:
 		log.Debug("No longer waiting", "task", mtask.Task)
 		return b
@@ -281,15 +311,46 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 	event := containerChange.event
 	llog.Debug("Handling container change", "change", containerChange)
 
+	// Docker may report the same container as stopped more than once, e.g. for
+	// both the 'die' and the 'stop' events. Once a container is known to be
+	// stopped, further stopped events are no-ops that never emit state changes
+	// or drive the task towards cleanup again
+	containerKnownStatus := container.GetKnownStatus()
+	if event.Status == api.ContainerStopped && containerKnownStatus == api.ContainerStopped {
+		seelog.Debugf("Ignoring duplicate stopped event for container %s of task %s", container.Name, mtask.Task.Arn)
+		return
+	}
+
+	if event.Health != api.ContainerHealthUnknown {
+		mtask.handleContainerHealthChange(container, event.Health)
+		return
+	}
+
 	// If this is a backwards transition stopped->running, the first time set it
 	// to be known running so it will be stopped. Subsequently ignore these backward transitions
-	containerKnownStatus := container.GetKnownStatus()
 	mtask.handleStoppedToRunningContainerTransition(event.Status, container)
 	if event.Status <= containerKnownStatus {
 		seelog.Infof("Redundant container state change for task %s: %s to %s, but already %s", mtask.Task, container, event.Status, containerKnownStatus)
 		return
 	}
 
+	if event.Status == api.ContainerStopped && containerKnownStatus == api.ContainerRunning &&
+		container.DockerRestartsOnExit(event.ExitCode) {
+		mtask.handleDockerRestart(container, event)
+		return
+	}
+
+	// If the container started after it was requested to stop, e.g. because
+	// the start was already in flight when the stop came in, record it as
+	// running so that it is stopped right away, but do not report it. The
+	// backend never sees a RUNNING state for a container it asked to stop
+	if event.Status.IsRunning() && event.Error == nil && container.GetDesiredStatus().Terminal() {
+		seelog.Infof("Container %s of task %s started after it was requested to stop; stopping it without reporting it as running",
+			container.Name, mtask.Task.Arn)
+		container.SetKnownStatus(event.Status)
+		return
+	}
+
 	// Update the container to be known
 	currentKnownStatus := containerKnownStatus
 	container.SetKnownStatus(event.Status)
@@ -310,19 +371,49 @@ func (mtask *managedTask) handleContainerChange(containerChange dockerContainerC
 	if event.ExitCode != nil && event.ExitCode != container.GetKnownExitCode() {
 		container.SetKnownExitCode(event.ExitCode)
 	}
+	if event.Status == api.ContainerStopped {
+		mtask.recordContainerExit(container, event)
+	}
 	if event.PortBindings != nil {
 		container.KnownPortBindings = event.PortBindings
 	}
+	if event.Status == api.ContainerRunning && !event.StartedAt.IsZero() {
+		container.SetStartedAt(event.StartedAt)
+	}
+	if event.Status == api.ContainerRunning && event.IPv4Address != "" {
+		container.SetIPv4Address(event.IPv4Address)
+	}
+	if event.Status == api.ContainerRunning {
+		mtask.startReadinessProbe(container)
+	}
 	if event.Volumes != nil {
 		mtask.UpdateMountPoints(container, event.Volumes)
 	}
 
-	mtask.engine.emitContainerEvent(mtask.Task, container, "")
+	mtask.engine.emitContainerEvent(mtask.Task, container, event.Reason)
 	if mtask.UpdateStatus() {
 		llog.Debug("Container change also resulted in task change")
 		// If knownStatus changed, let it be known
 		mtask.engine.emitTaskEvent(mtask.Task, "")
 	}
+	mtask.engine.refreshTaskMetadataFile(mtask.Task)
+}
+
+// handleContainerHealthChange records a change of the health of a running
+// container and reports it. Health reported for containers that are not
+// known to be running is ignored
+func (mtask *managedTask) handleContainerHealthChange(container *api.Container, health api.ContainerHealthStatus) {
+	if container.GetKnownStatus() != api.ContainerRunning {
+		seelog.Debugf("Ignoring health %s of container %s of task %s, which is %s",
+			health, container.Name, mtask.Task.Arn, container.GetKnownStatus().String())
+		return
+	}
+	if container.GetHealthStatus() == health {
+		return
+	}
+	seelog.Infof("Container %s of task %s is now %s", container.Name, mtask.Task.Arn, health)
+	container.SetHealthStatus(health)
+	mtask.engine.emitContainerHealthEvent(mtask.Task, container)
 }
 
 // releaseIPInIPAM releases the ip used by the task for awsvpc
@@ -399,6 +490,19 @@ func (mtask *managedTask) handleEventError(containerChange dockerContainerChange
 		seelog.Warnf("'docker stop' returned %s: %s", event.Error.ErrorName(), event.Error.Error())
 		container.SetKnownStatus(api.ContainerStopped)
 		container.SetDesiredStatus(api.ContainerStopped)
+	} else if _, ok := event.Error.(CannotPullContainerDiskFullError); ok && event.Status == api.ContainerPulled {
+		// Running the container is bound to fail when there's no space left
+		// to pull its image
+		seelog.Warnf("Error while pulling container %v for task %v, disk is full; stopping container: %v", container, mtask, event.Error)
+		container.SetKnownStatus(currentKnownStatus)
+		container.SetDesiredStatus(api.ContainerStopped)
+		return false
+	} else if _, ok := event.Error.(PlatformMismatchError); ok && event.Status == api.ContainerPulled {
+		// The image is not pulled for a platform the host does not run
+		seelog.Warnf("Platform of container %v for task %v does not match the host; stopping container: %v", container, mtask, event.Error)
+		container.SetKnownStatus(currentKnownStatus)
+		container.SetDesiredStatus(api.ContainerStopped)
+		return false
 	} else if event.Status == api.ContainerPulled {
 		// Another special case; a failure to pull might not be fatal if e.g. the image already exists.
 		seelog.Errorf("Error while pulling container %v for task %v, will try to run anyway: %v", container, mtask, event.Error)
@@ -442,10 +546,32 @@ func (mtask *managedTask) progressContainers() {
 		})
 
 	if !anyCanTransition {
+		if mtask.waitingForVolumeCompletion() {
+			// Containers are waiting for the containers populating their
+			// volumes to exit, which is reported by a container event
+			seelog.Debugf("Task [%s]: waiting for volume containers to complete", mtask.Task.String())
+			mtask.waitEvent(nil)
+			return
+		}
+		if mtask.waitingForHealthyDependencies() {
+			// Containers are waiting for the containers they depend on to
+			// report the required health, which is reported by a health event
+			seelog.Debugf("Task [%s]: waiting for dependencies to become healthy", mtask.Task.String())
+			mtask.waitForHealthyDependencies()
+			return
+		}
+		if mtask.waitingForReadyDependencies() {
+			// Containers are waiting for the containers they depend on to be
+			// found ready by their readiness probes
+			seelog.Debugf("Task [%s]: waiting for dependencies to become ready", mtask.Task.String())
+			mtask.waitEvent(nil)
+			return
+		}
 		mtask.onContainersUnableToTransitionState()
 		return
 	}
 
+	mtask.healthyDependencyWaitStart = time.Time{}
 	// We've kicked off one or more transitions, wait for them to
 	// complete, but keep reading events as we do.. in fact, we have to for
 	// transitions to complete
@@ -475,6 +601,9 @@ func (mtask *managedTask) startContainerTransitions(transitionFunc containerTran
 		anyCanTransition = true
 
 		if !shouldCallTransitionFunc {
+			// The container is stopped without being created, so the
+			// reference to its image taken when it was pulled is not needed
+			mtask.engine.pendingCreateImages.release(cont)
 			mtask.handleContainerChange(dockerContainerChange{cont, DockerContainerChangeEvent{Status: nextState}})
 			continue
 		}
@@ -531,6 +660,77 @@ func (mtask *managedTask) containerNextState(container *api.Container) (api.Cont
 	return nextState, true, true
 }
 
+// waitingForVolumeCompletion returns true if any container of the task is
+// waiting for a container populating its volumes to exit
+func (mtask *managedTask) waitingForVolumeCompletion() bool {
+	for _, cont := range mtask.Containers {
+		if dependencygraph.WaitingForVolumeCompletion(cont, mtask.Containers) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitingForHealthyDependencies returns true if any container of the task is
+// waiting for a container it depends on to report the health it requires
+func (mtask *managedTask) waitingForHealthyDependencies() bool {
+	for _, cont := range mtask.Containers {
+		if dependencygraph.WaitingForHealthyDependency(cont, mtask.Containers) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForHealthyDependencies waits for an event while containers of the task
+// wait for the containers they depend on to report the health they require.
+// The task is stopped once they have waited for longer than
+// healthyDependencyTimeout
+func (mtask *managedTask) waitForHealthyDependencies() {
+	now := mtask.time().Now()
+	if mtask.healthyDependencyWaitStart.IsZero() {
+		mtask.healthyDependencyWaitStart = now
+	}
+	remaining := healthyDependencyTimeout - now.Sub(mtask.healthyDependencyWaitStart)
+	if remaining <= 0 {
+		mtask.failHealthyDependencies()
+		return
+	}
+
+	maxWait := make(chan bool, 1)
+	timer := mtask.time().After(remaining)
+	go func() {
+		<-timer
+		maxWait <- true
+	}()
+	mtask.waitEvent(maxWait)
+}
+
+// failHealthyDependencies stops the task, recording on each container still
+// waiting for the health of a container it depends on that the wait timed out
+func (mtask *managedTask) failHealthyDependencies() {
+	for _, cont := range mtask.Containers {
+		if dependencygraph.WaitingForHealthyDependency(cont, mtask.Containers) {
+			seelog.Warnf("Task [%s]: container %s timed out waiting for its dependencies to become healthy",
+				mtask.Task.String(), cont.Name)
+			cont.ApplyingError = api.NewNamedError(&HealthyDependencyTimeoutError{name: cont.Name})
+		}
+	}
+	mtask.healthyDependencyWaitStart = time.Time{}
+	mtask.handleDesiredStatusChange(api.TaskStopped, 0)
+}
+
+// waitingForReadyDependencies returns true if any container of the task is
+// waiting for a container it depends on to be found ready
+func (mtask *managedTask) waitingForReadyDependencies() bool {
+	for _, cont := range mtask.Containers {
+		if dependencygraph.WaitingForReadyDependency(cont, mtask.Containers) {
+			return true
+		}
+	}
+	return false
+}
+
 func (mtask *managedTask) onContainersUnableToTransitionState() {
 	log.Crit("Task in a bad state; it's not steadystate but no containers want to transition", "task", mtask.Task)
 	if mtask.GetDesiredStatus().Terminal() {
@@ -573,6 +773,40 @@ func (mtask *managedTask) waitForContainerTransitions(transitions map[string]api
 	}
 }
 
+// recordContainerExit adds the exit described by a stopped event to the exit
+// history of the container
+func (mtask *managedTask) recordContainerExit(container *api.Container, event DockerContainerChangeEvent) api.ContainerExit {
+	_, oomKilled := event.Error.(OutOfMemoryError)
+	exit := api.ContainerExit{
+		ExitCode:       event.ExitCode,
+		OOMKilled:      oomKilled,
+		DetectedByPoll: event.DetectedByPoll,
+		Time:           ttime.Now(),
+	}
+	container.RecordExit(exit)
+	if oomKilled {
+		seelog.Warnf("Container %s of task %s was killed for exceeding its memory limit", container.Name, mtask.Arn)
+	}
+	return exit
+}
+
+// handleDockerRestart reconciles the exit of a container that docker restarts
+// according to its restart policy. The exit and the restart are recorded, but
+// the container is kept running so that the task isn't stopped. Containers
+// that were unhealthy when they exited are recorded as restarted for failing
+// their health check, unless they ran out of memory
+func (mtask *managedTask) handleDockerRestart(container *api.Container, event DockerContainerChangeEvent) {
+	exit := mtask.recordContainerExit(container, event)
+	restart := api.NewContainerExitRestart(exit, exit.Time)
+	if !exit.OOMKilled && container.GetHealthStatus() == api.ContainerUnhealthy {
+		seelog.Warnf("Container %s of task %s exited after failing its health check", container.Name, mtask.Arn)
+		restart = api.NewContainerHealthCheckRestart(exit.Time)
+	}
+	container.RecordRestart(restart)
+	seelog.Infof("Container %s of task %s exited with code %d and is being restarted by docker (restart %d)",
+		container.Name, mtask.Arn, *exit.ExitCode, container.GetRestartCount())
+}
+
 func (mtask *managedTask) time() ttime.Time {
 	mtask._timeOnce.Do(func() {
 		if mtask._time == nil {
@@ -616,8 +850,14 @@ func (mtask *managedTask) cleanupTask(taskStoppedDuration time.Duration) {
 	// discard events while the task is being removed from engine state
 	go mtask.discardEventsUntil(handleCleanupDone)
 	mtask.engine.sweepTask(mtask.Task)
+	mtask.engine.removeTaskMetadataFile(mtask.Task)
+	mtask.RecordEvent(api.TaskEvent{Type: api.TaskEventCleanedUp, Time: ttime.Now()})
+	// The task is about to be forgotten, log its timeline for post-mortem
+	// debugging
+	seelog.Infof("Task [%s]: timeline: %v", mtask.Task.String(), mtask.GetTimeline())
 	// Now remove ourselves from the global state and cleanup channels
 	mtask.engine.processTasks.Lock()
+	mtask.cleanupCredentials()
 	mtask.engine.state.RemoveTask(mtask.Task)
 	eni := mtask.Task.GetTaskENI()
 	if eni == nil {
@@ -22,6 +22,9 @@ import (
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	"github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/engine/testdata"
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
@@ -31,6 +34,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/golang/mock/gomock"
 	"golang.org/x/net/context"
@@ -552,6 +556,7 @@ func TestStartContainerTransitionsInvokesHandleContainerChange(t *testing.T) {
 			DesiredStatusUnsafe: api.TaskRunning,
 		},
 		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
 			containerChangeEventStream: containerChangeEventStream,
 			stateChangeEvents:          stateChangeEvents,
 		},
@@ -587,6 +592,501 @@ func TestStartContainerTransitionsInvokesHandleContainerChange(t *testing.T) {
 	eventsGenerated.Wait()
 }
 
+func TestStartContainerTransitionsReleasesImageOfContainerNotCreated(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTRELEASEIMAGE", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		Image:               "image",
+		KnownStatusUnsafe:   api.ContainerPulled,
+		DesiredStatusUnsafe: api.ContainerStopped,
+	}
+	pendingCreateImages := newImageReferences()
+	pendingCreateImages.acquire(container)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			DesiredStatusUnsafe: api.TaskStopped,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          make(chan statechange.Event, 10),
+			pendingCreateImages:        pendingCreateImages,
+		},
+	}
+
+	task.startContainerTransitions(func(cont *api.Container, nextStatus api.ContainerStatus) {
+		t.Error("Invalid code path. The transition function should not be invoked when transitioning container from PULLED -> STOPPED")
+	})
+	assert.Equal(t, api.ContainerStopped, container.GetKnownStatus())
+	assert.False(t, pendingCreateImages.isReferenced([]string{"image"}),
+		"Expected image reference to be released once the container won't be created")
+}
+
+func TestHandleContainerChangeRecordsExitHistory(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTEXITHISTORY", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          make(chan statechange.Event, 10),
+		},
+	}
+
+	exitCode := 1
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerStopped,
+			DockerContainerMetadata: DockerContainerMetadata{
+				ExitCode: &exitCode,
+			},
+		},
+	})
+
+	// Simulate the container being restarted and killed for its memory usage
+	container.SetKnownStatus(api.ContainerRunning)
+	oomExitCode := 137
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerStopped,
+			DockerContainerMetadata: DockerContainerMetadata{
+				ExitCode: &oomExitCode,
+				Error:    OutOfMemoryError{},
+			},
+		},
+	})
+
+	history := container.GetExitHistory()
+	assert.Len(t, history, 2)
+	assert.Equal(t, exitCode, *history[0].ExitCode)
+	assert.False(t, history[0].OOMKilled)
+	assert.Equal(t, oomExitCode, *history[1].ExitCode)
+	assert.True(t, history[1].OOMKilled)
+}
+
+// TestHandleContainerChangeEmitsExitReason tests that the reason docker
+// reports for the exit of a container is emitted in its state change
+func TestHandleContainerChangeEmitsExitReason(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTEXITREASON", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+		SentStatusUnsafe:    api.ContainerRunning,
+	}
+	// An error applying an earlier transition should not hide the reason of
+	// the exit
+	container.ApplyingError = api.NewNamedError(errors.New("earlier error"))
+	stateChangeEvents := make(chan statechange.Event, 10)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          stateChangeEvents,
+		},
+	}
+
+	exitCode := 137
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerStopped,
+			DockerContainerMetadata: DockerContainerMetadata{
+				ExitCode: &exitCode,
+				Error:    OutOfMemoryError{},
+				Reason:   "OutOfMemoryError: Container killed due to memory usage",
+			},
+		},
+	})
+
+	require.NotEmpty(t, stateChangeEvents)
+	event, ok := (<-stateChangeEvents).(api.ContainerStateChange)
+	require.True(t, ok)
+	assert.Equal(t, api.ContainerStopped, event.Status)
+	assert.Equal(t, exitCode, *event.ExitCode)
+	assert.Equal(t, "OutOfMemoryError: Container killed due to memory usage", event.Reason)
+}
+
+// TestHandleContainerChangeStartAfterStopDesired tests that a container that
+// starts after it was requested to stop is recorded as running, so that it is
+// stopped, but that no RUNNING state change is emitted for it
+func TestHandleContainerChangeStartAfterStopDesired(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTSTARTAFTERSTOP", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerCreated,
+		DesiredStatusUnsafe: api.ContainerStopped,
+	}
+	stateChangeEvents := make(chan statechange.Event, 10)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskCreated,
+			DesiredStatusUnsafe: api.TaskStopped,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          stateChangeEvents,
+		},
+	}
+
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerRunning,
+		},
+	})
+
+	assert.Equal(t, api.ContainerRunning, container.GetKnownStatus())
+	assert.Equal(t, api.TaskCreated, task.GetKnownStatus())
+	assert.Empty(t, stateChangeEvents, "no state change should be emitted for the start")
+
+	// The container is stopped on the next transition
+	_, transitions := task.startContainerTransitions(func(cont *api.Container, nextStatus api.ContainerStatus) {})
+	assert.Equal(t, api.ContainerStopped, transitions[container.Name])
+}
+
+func TestHandleContainerChangeStopReasonDistinguishesPollFromEvent(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reasonsEnabled bool
+		detectedByPoll bool
+		expectedReason string
+	}{
+		{name: "DockerEvent", reasonsEnabled: true, detectedByPoll: false, expectedReason: ""},
+		{name: "SteadyStatePoll", reasonsEnabled: true, detectedByPoll: true, expectedReason: containerStopDetectedByPollReason},
+		{name: "SteadyStatePollReasonsDisabled", detectedByPoll: true, expectedReason: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.ContainerExitReasonsEnabled = tc.reasonsEnabled
+			containerChangeEventStream := eventstream.NewEventStream("TESTSTOPREASON"+tc.name, context.Background())
+			containerChangeEventStream.StartListening()
+
+			container := &api.Container{
+				Name:                "container1",
+				KnownStatusUnsafe:   api.ContainerRunning,
+				DesiredStatusUnsafe: api.ContainerRunning,
+			}
+			stateChangeEvents := make(chan statechange.Event, 10)
+			task := &managedTask{
+				Task: &api.Task{
+					Arn:                 "task1",
+					Containers:          []*api.Container{container},
+					KnownStatusUnsafe:   api.TaskRunning,
+					DesiredStatusUnsafe: api.TaskRunning,
+				},
+				engine: &DockerTaskEngine{
+					cfg:                        &cfg,
+					containerChangeEventStream: containerChangeEventStream,
+					stateChangeEvents:          stateChangeEvents,
+				},
+			}
+
+			exitCode := 0
+			task.handleContainerChange(dockerContainerChange{
+				container: container,
+				event: DockerContainerChangeEvent{
+					Status:                  api.ContainerStopped,
+					DetectedByPoll:          tc.detectedByPoll,
+					DockerContainerMetadata: DockerContainerMetadata{ExitCode: &exitCode},
+				},
+			})
+
+			require.NotEmpty(t, stateChangeEvents)
+			event, ok := (<-stateChangeEvents).(api.ContainerStateChange)
+			require.True(t, ok, "expected a container state change")
+			assert.Equal(t, api.ContainerStopped, event.Status)
+			assert.Equal(t, tc.expectedReason, event.Reason)
+		})
+	}
+}
+
+func TestHandleContainerChangeIgnoresDuplicateStoppedEvents(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTDUPLICATESTOPPED", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	stateChangeEvents := make(chan statechange.Event, 50)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskRunning,
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          stateChangeEvents,
+		},
+	}
+
+	exitCode := 0
+	for i := 0; i < 20; i++ {
+		task.handleContainerChange(dockerContainerChange{
+			container: container,
+			event: DockerContainerChangeEvent{
+				Status:                  api.ContainerStopped,
+				DockerContainerMetadata: DockerContainerMetadata{ExitCode: &exitCode},
+			},
+		})
+	}
+
+	require.Len(t, stateChangeEvents, 2, "expected exactly one container and one task state change")
+	containerEvent, ok := (<-stateChangeEvents).(api.ContainerStateChange)
+	require.True(t, ok, "expected a container state change")
+	assert.Equal(t, api.ContainerStopped, containerEvent.Status)
+	taskEvent, ok := (<-stateChangeEvents).(api.TaskStateChange)
+	require.True(t, ok, "expected a task state change")
+	assert.Equal(t, api.TaskStopped, taskEvent.Status)
+	assert.Len(t, container.GetExitHistory(), 1, "the exit of the container should be recorded once")
+}
+
+func TestHandleContainerHealthChange(t *testing.T) {
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	stateChangeEvents := make(chan statechange.Event, 10)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskRunning,
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:               &defaultConfig,
+			stateChangeEvents: stateChangeEvents,
+		},
+	}
+	healthChange := func(health api.ContainerHealthStatus) dockerContainerChange {
+		return dockerContainerChange{
+			container: container,
+			event:     DockerContainerChangeEvent{Status: api.ContainerRunning, Health: health},
+		}
+	}
+
+	task.handleContainerChange(healthChange(api.ContainerHealthy))
+	task.handleContainerChange(healthChange(api.ContainerHealthy))
+	require.Len(t, stateChangeEvents, 1, "unchanged health should not be reported again")
+	event := (<-stateChangeEvents).(api.ContainerStateChange)
+	assert.Equal(t, api.ContainerHealthy, event.Health)
+
+	container.SetKnownStatus(api.ContainerStopped)
+	task.handleContainerChange(healthChange(api.ContainerUnhealthy))
+	assert.Empty(t, stateChangeEvents, "health of stopped containers should be ignored")
+	assert.Equal(t, api.ContainerHealthy, container.GetHealthStatus())
+}
+
+func TestHandleContainerChangeRecordsDockerStartTime(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTSTARTEDAT", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerCreated,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskCreated,
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          make(chan statechange.Event, 10),
+		},
+	}
+
+	// The start time reported by docker predates the event being handled
+	startedAt := time.Now().Add(-time.Minute).UTC()
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status:                  api.ContainerRunning,
+			DockerContainerMetadata: DockerContainerMetadata{StartedAt: startedAt},
+		},
+	})
+
+	assert.Equal(t, api.ContainerRunning, container.GetKnownStatus())
+	assert.Equal(t, startedAt, container.GetStartedAt())
+}
+
+func TestHandleContainerChangeDockerRestart(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTDOCKERRESTART", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+		Essential:           true,
+		RestartPolicy:       &api.RestartPolicy{Name: api.RestartPolicyOnFailure, MaximumRetryCount: 1},
+	}
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskRunning,
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          make(chan statechange.Event, 10),
+		},
+	}
+
+	exitCode := 1
+	stoppedEvent := dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerStopped,
+			DockerContainerMetadata: DockerContainerMetadata{
+				ExitCode: &exitCode,
+			},
+		},
+	}
+	task.handleContainerChange(stoppedEvent)
+	// The start of the container restarted by docker is redundant
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event:     DockerContainerChangeEvent{Status: api.ContainerRunning},
+	})
+
+	assert.Equal(t, api.ContainerRunning, container.GetKnownStatus())
+	assert.Equal(t, api.TaskRunning, task.GetKnownStatus(), "docker restart should not stop the task")
+	assert.Equal(t, 1, container.GetRestartCount())
+	restart, ok := container.GetLastRestart()
+	require.True(t, ok)
+	assert.Equal(t, api.ContainerRestartReasonExitCode, restart.Reason)
+	assert.Len(t, container.GetExitHistory(), 1)
+	assert.Len(t, task.engine.stateChangeEvents, 0, "no state change should be emitted for docker restarts")
+
+	// Once the maximum retry count is reached, docker no longer restarts
+	// the container and the exit stops it
+	task.handleContainerChange(stoppedEvent)
+	assert.Equal(t, api.ContainerStopped, container.GetKnownStatus())
+	assert.Equal(t, 1, container.GetRestartCount())
+}
+
+func TestHandleContainerChangeDockerRestartUnhealthy(t *testing.T) {
+	containerChangeEventStream := eventstream.NewEventStream("TESTDOCKERRESTARTUNHEALTHY", context.Background())
+	containerChangeEventStream.StartListening()
+
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+		Essential:           true,
+		RestartPolicy:       &api.RestartPolicy{Name: api.RestartPolicyOnFailure},
+	}
+	container.SetHealthStatus(api.ContainerUnhealthy)
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			KnownStatusUnsafe:   api.TaskRunning,
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg:                        &defaultConfig,
+			containerChangeEventStream: containerChangeEventStream,
+			stateChangeEvents:          make(chan statechange.Event, 10),
+		},
+	}
+
+	exitCode := 1
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerStopped,
+			DockerContainerMetadata: DockerContainerMetadata{
+				ExitCode: &exitCode,
+			},
+		},
+	})
+
+	assert.Equal(t, api.ContainerRunning, container.GetKnownStatus())
+	assert.Equal(t, 1, container.GetRestartCount())
+	restart, ok := container.GetLastRestart()
+	require.True(t, ok)
+	assert.Equal(t, api.ContainerRestartReasonHealthCheck, restart.Reason)
+	assert.Len(t, container.GetExitHistory(), 1)
+}
+
+func TestHandleContainerChangeDiskFullPullErrorStopsContainer(t *testing.T) {
+	container := &api.Container{
+		Name:                "container1",
+		KnownStatusUnsafe:   api.ContainerStatusNone,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	task := &managedTask{
+		Task: &api.Task{
+			Arn:                 "task1",
+			Containers:          []*api.Container{container},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		engine: &DockerTaskEngine{
+			cfg: &defaultConfig,
+		},
+	}
+
+	task.handleContainerChange(dockerContainerChange{
+		container: container,
+		event: DockerContainerChangeEvent{
+			Status: api.ContainerPulled,
+			DockerContainerMetadata: DockerContainerMetadata{
+				Error: CannotPullContainerDiskFullError{errors.New("no space left on device")},
+			},
+		},
+	})
+
+	assert.Equal(t, api.ContainerStatusNone, container.GetKnownStatus())
+	assert.Equal(t, api.ContainerStopped, container.GetDesiredStatus())
+	assert.Equal(t, "CannotPullContainerDiskFullError", container.ApplyingError.Name)
+}
+
 func TestWaitForContainerTransitionsForNonTerminalTask(t *testing.T) {
 	acsMessages := make(chan acsTransition)
 	dockerMessages := make(chan dockerContainerChange)
@@ -710,6 +1210,43 @@ func TestOnContainersUnableToTransitionStateForDesiredRunningTask(t *testing.T)
 
 // TODO: Test progressContainers workflow
 
+// TestProgressContainersWaitsForVolumeCompletion verifies that a task whose
+// containers are waiting for a volume container to complete keeps waiting for
+// events instead of being stopped
+func TestProgressContainersWaitsForVolumeCompletion(t *testing.T) {
+	populate := &api.Container{
+		Name:                "populate",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	app := &api.Container{
+		Name:                "app",
+		KnownStatusUnsafe:   api.ContainerCreated,
+		DesiredStatusUnsafe: api.ContainerRunning,
+		VolumesFrom:         []api.VolumeFrom{{SourceContainer: "populate", WaitForCompletion: true}},
+	}
+	acsMessages := make(chan acsTransition)
+	task := &managedTask{
+		acsMessages:    acsMessages,
+		dockerMessages: make(chan dockerContainerChange),
+		Task: &api.Task{
+			Containers:          []*api.Container{populate, app},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+	}
+
+	progressed := make(chan struct{})
+	go func() {
+		task.progressContainers()
+		close(progressed)
+	}()
+	acsMessages <- acsTransition{desiredStatus: api.TaskRunning}
+	<-progressed
+
+	assert.Equal(t, api.TaskRunning, task.GetDesiredStatus(), "the task should not be stopped")
+	assert.Equal(t, api.ContainerRunning, app.GetDesiredStatus())
+}
+
 func TestHandleStoppedToSteadyStateTransition(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockStateManager := mock_statemanager.NewMockStateManager(ctrl)
@@ -1013,3 +1550,147 @@ func TestCleanupTaskENIs(t *testing.T) {
 	mockState.EXPECT().RemoveENIAttachment(mac)
 	mTask.cleanupTask(taskStoppedDuration)
 }
+
+func TestCleanupTaskRemovesCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	mockClient := NewMockDockerClient(ctrl)
+	mockImageManager := NewMockImageManager(ctrl)
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	defer ctrl.Finish()
+
+	state := dockerstate.NewTaskEngineState()
+	taskEngine := &DockerTaskEngine{
+		saver:              statemanager.NewNoopStateManager(),
+		state:              state,
+		client:             mockClient,
+		imageManager:       mockImageManager,
+		credentialsManager: credentialsManager,
+	}
+	mTask := &managedTask{
+		Task:           testdata.LoadTask("sleep5"),
+		_time:          mockTime,
+		engine:         taskEngine,
+		acsMessages:    make(chan acsTransition),
+		dockerMessages: make(chan dockerContainerChange),
+	}
+	mTask.SetCredentialsID(credentialsID)
+	mTask.SetKnownStatus(api.TaskStopped)
+	mTask.SetSentStatus(api.TaskStopped)
+	state.AddTask(mTask.Task)
+
+	now := mTask.GetKnownStatusTime()
+	mockTime.EXPECT().Now().Return(now).AnyTimes()
+	cleanupTimeTrigger := make(chan time.Time)
+	mockTime.EXPECT().After(gomock.Any()).Return(cleanupTimeTrigger)
+	go func() {
+		cleanupTimeTrigger <- now
+	}()
+
+	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(gomock.Any()).Return(nil)
+	credentialsManager.EXPECT().RemoveCredentials(credentialsID)
+	mTask.cleanupTask(time.Minute)
+
+	_, ok := state.TaskByArn(mTask.Arn)
+	assert.False(t, ok, "task should be removed from the state")
+}
+
+func TestCleanupTaskKeepsCredentialsOfReAddedTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+	mockClient := NewMockDockerClient(ctrl)
+	mockImageManager := NewMockImageManager(ctrl)
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	defer ctrl.Finish()
+
+	state := dockerstate.NewTaskEngineState()
+	taskEngine := &DockerTaskEngine{
+		saver:              statemanager.NewNoopStateManager(),
+		state:              state,
+		client:             mockClient,
+		imageManager:       mockImageManager,
+		credentialsManager: credentialsManager,
+	}
+	mTask := &managedTask{
+		Task:           testdata.LoadTask("sleep5"),
+		_time:          mockTime,
+		engine:         taskEngine,
+		acsMessages:    make(chan acsTransition),
+		dockerMessages: make(chan dockerContainerChange),
+	}
+	mTask.SetCredentialsID(credentialsID)
+	mTask.SetKnownStatus(api.TaskStopped)
+	mTask.SetSentStatus(api.TaskStopped)
+	state.AddTask(mTask.Task)
+
+	now := mTask.GetKnownStatusTime()
+	mockTime.EXPECT().Now().Return(now).AnyTimes()
+	cleanupTimeTrigger := make(chan time.Time)
+	mockTime.EXPECT().After(gomock.Any()).Return(cleanupTimeTrigger)
+
+	reAddedTask := testdata.LoadTask("sleep5")
+	reAddedTask.Arn = "reAddedTask"
+	reAddedTask.SetCredentialsID(credentialsID)
+	go func() {
+		// The task is re-added with the same credentials while the stopped
+		// task is waiting to be cleaned up
+		taskEngine.processTasks.Lock()
+		state.AddTask(reAddedTask)
+		taskEngine.processTasks.Unlock()
+		cleanupTimeTrigger <- now
+	}()
+
+	mockImageManager.EXPECT().RemoveContainerReferenceFromImageState(gomock.Any()).Return(nil)
+	credentialsManager.EXPECT().RemoveCredentials(gomock.Any()).Times(0)
+	mTask.cleanupTask(time.Minute)
+
+	_, ok := state.TaskByArn(reAddedTask.Arn)
+	assert.True(t, ok, "re-added task should be kept in the state")
+}
+
+// TestWaitForHealthyDependenciesTimeout tests that a task whose containers
+// have waited for too long on the health of the containers they depend on is
+// stopped, with the reason recorded on the waiting container
+func TestWaitForHealthyDependenciesTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockTime := mock_ttime.NewMockTime(ctrl)
+
+	db := &api.Container{
+		Name:                "db",
+		DesiredStatusUnsafe: api.ContainerRunning,
+		KnownStatusUnsafe:   api.ContainerRunning,
+		HealthCheck:         &api.HealthCheck{Command: []string{"CMD", "true"}},
+	}
+	db.SetHealthStatus(api.ContainerUnhealthy)
+	app := &api.Container{
+		Name:                "app",
+		DesiredStatusUnsafe: api.ContainerRunning,
+		KnownStatusUnsafe:   api.ContainerPulled,
+	}
+	app.TransitionDependencySet.ContainerDependencies = []api.ContainerDependency{{
+		ContainerName:   "db",
+		SatisfiedStatus: api.ContainerRunning,
+		SatisfiedHealth: api.ContainerHealthy,
+		DependentStatus: api.ContainerCreated,
+	}}
+	mtask := &managedTask{
+		Task: &api.Task{
+			Arn:                 "myTaskArn",
+			Containers:          []*api.Container{db, app},
+			DesiredStatusUnsafe: api.TaskRunning,
+		},
+		_time: mockTime,
+	}
+
+	now := time.Now()
+	mtask.healthyDependencyWaitStart = now.Add(-healthyDependencyTimeout)
+	mockTime.EXPECT().Now().Return(now)
+
+	mtask.waitForHealthyDependencies()
+	assert.Equal(t, api.TaskStopped, mtask.GetDesiredStatus())
+	require.NotNil(t, app.ApplyingError)
+	assert.Equal(t, "HealthyDependencyTimeoutError", app.ApplyingError.ErrorName())
+	assert.Nil(t, db.ApplyingError)
+	assert.True(t, mtask.healthyDependencyWaitStart.IsZero())
+}
@@ -0,0 +1,212 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/cihub/seelog"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/pkg/errors"
+)
+
+const (
+	// taskMetadataFileName is the name of the file with the metadata of the
+	// task, in the metadata directory of the task
+	taskMetadataFileName = "task-metadata.json"
+	// taskMetadataFileEnvVar is the environment variable that tells
+	// containers the path of the metadata file
+	taskMetadataFileEnvVar = "ECS_TASK_METADATA_FILE"
+	// metadataDir is the directory, in DataDir, that contains the metadata
+	// directories of tasks
+	metadataDir = "metadata"
+)
+
+// taskMetadataContents is the content of the metadata file of a task
+type taskMetadataContents struct {
+	Cluster       string
+	TaskARN       string
+	Family        string
+	Revision      string
+	DesiredStatus string
+	KnownStatus   string
+	Containers    []containerMetadataContents
+}
+
+// containerMetadataContents is the metadata of a container in the metadata
+// file of its task
+type containerMetadataContents struct {
+	Name          string
+	DockerID      string `json:"DockerId,omitempty"`
+	DockerName    string `json:",omitempty"`
+	Image         string
+	DesiredStatus string
+	KnownStatus   string
+	Ports         []api.PortBinding `json:",omitempty"`
+}
+
+// taskMetadataFileWriter writes the metadata of tasks into files that are
+// mounted into their containers. Files are rewritten, atomically, whenever
+// their contents change
+type taskMetadataFileWriter struct {
+	// dataDir is the directory the Agent writes metadata files to
+	dataDir string
+	// dataDirOnHost is the path of dataDir on the host, which is what the
+	// containers of tasks mount
+	dataDirOnHost string
+
+	lock sync.Mutex
+	// written holds the contents last written for each task arn, so that
+	// files are only rewritten when a relevant field changed
+	written map[string][]byte
+}
+
+func newTaskMetadataFileWriter(dataDir, dataDirOnHost string) *taskMetadataFileWriter {
+	return &taskMetadataFileWriter{
+		dataDir:       filepath.Join(dataDir, metadataDir),
+		dataDirOnHost: filepath.Join(dataDirOnHost, "data", metadataDir),
+		written:       make(map[string][]byte),
+	}
+}
+
+// taskMetadataDirName returns the name of the metadata directory of a task,
+// which is the id at the end of its arn
+func taskMetadataDirName(taskARN string) string {
+	return taskARN[strings.LastIndex(taskARN, "/")+1:]
+}
+
+// mountTaskMetadataFile writes the metadata file of the task and mounts its
+// directory into the container. Failures are logged and the container is
+// created without the file
+func (engine *DockerTaskEngine) mountTaskMetadataFile(task *api.Task, container *api.Container, config *docker.Config, hostConfig *docker.HostConfig) {
+	if engine.taskMetadataFile == nil {
+		return
+	}
+	if err := engine.taskMetadataFile.write(engine.taskMetadata(task)); err != nil {
+		seelog.Warnf("Unable to write the metadata file of task %s for container %s: %v", task.Arn, container.Name, err)
+		return
+	}
+	dirName := taskMetadataDirName(task.Arn)
+	hostConfig.Binds = append(hostConfig.Binds,
+		filepath.Join(engine.taskMetadataFile.dataDirOnHost, dirName)+":"+containerMetadataDir+readOnlyBindSuffix)
+	config.Env = append(config.Env, taskMetadataFileEnvVar+"="+containerMetadataDir+containerPathSeparator+taskMetadataFileName)
+}
+
+// refreshTaskMetadataFile rewrites the metadata file of the task if any of
+// its contents changed
+func (engine *DockerTaskEngine) refreshTaskMetadataFile(task *api.Task) {
+	if engine.taskMetadataFile == nil {
+		return
+	}
+	if err := engine.taskMetadataFile.write(engine.taskMetadata(task)); err != nil {
+		seelog.Warnf("Unable to update the metadata file of task %s: %v", task.Arn, err)
+	}
+}
+
+// removeTaskMetadataFile removes the metadata directory of the task once its
+// containers are removed
+func (engine *DockerTaskEngine) removeTaskMetadataFile(task *api.Task) {
+	if engine.taskMetadataFile == nil {
+		return
+	}
+	if err := engine.taskMetadataFile.remove(task.Arn); err != nil {
+		seelog.Warnf("Unable to remove the metadata file of task %s: %v", task.Arn, err)
+	}
+}
+
+// taskMetadata returns the current metadata of the task
+func (engine *DockerTaskEngine) taskMetadata(task *api.Task) *taskMetadataContents {
+	metadata := &taskMetadataContents{
+		Cluster:       engine.cfg.Cluster,
+		TaskARN:       task.Arn,
+		Family:        task.Family,
+		Revision:      task.Version,
+		DesiredStatus: task.GetDesiredStatus().String(),
+		KnownStatus:   task.GetKnownStatus().String(),
+	}
+	containerMap, _ := engine.state.ContainerMapByArn(task.Arn)
+	for _, container := range task.Containers {
+		if container.IsInternal() {
+			continue
+		}
+		containerMetadata := containerMetadataContents{
+			Name:          container.Name,
+			Image:         container.Image,
+			DesiredStatus: container.GetDesiredStatus().String(),
+			KnownStatus:   container.GetKnownStatus().String(),
+			Ports:         container.KnownPortBindings,
+		}
+		if dockerContainer, ok := containerMap[container.Name]; ok {
+			containerMetadata.DockerID = dockerContainer.DockerID
+			containerMetadata.DockerName = dockerContainer.DockerName
+		}
+		metadata.Containers = append(metadata.Containers, containerMetadata)
+	}
+	return metadata
+}
+
+// write writes the metadata file of a task, unless its contents are the same
+// as the ones last written
+func (writer *taskMetadataFileWriter) write(metadata *taskMetadataContents) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal task metadata")
+	}
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	if bytes.Equal(writer.written[metadata.TaskARN], data) {
+		return nil
+	}
+
+	dir := filepath.Join(writer.dataDir, taskMetadataDirName(metadata.TaskARN))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "unable to create metadata directory")
+	}
+	// Write to a temporary file that is renamed over the metadata file, so
+	// that containers never read a partially written file
+	tmpFile, err := ioutil.TempFile(dir, "tmp_"+taskMetadataFileName)
+	if err != nil {
+		return errors.Wrap(err, "unable to create temporary metadata file")
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	tmpFile.Close()
+	if err != nil {
+		return errors.Wrap(err, "unable to write temporary metadata file")
+	}
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return errors.Wrap(err, "unable to set the mode of the metadata file")
+	}
+	if err := os.Rename(tmpFile.Name(), filepath.Join(dir, taskMetadataFileName)); err != nil {
+		return errors.Wrap(err, "unable to move temporary metadata file")
+	}
+	writer.written[metadata.TaskARN] = data
+	return nil
+}
+
+// remove removes the metadata directory of a task
+func (writer *taskMetadataFileWriter) remove(taskARN string) error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	delete(writer.written, taskARN)
+	return os.RemoveAll(filepath.Join(writer.dataDir, taskMetadataDirName(taskARN)))
+}
@@ -0,0 +1,124 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/config"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metadataTaskARN = "arn:aws:ecs:us-west-2:123456789012:task/a1b2c3d4"
+
+func readTaskMetadataFile(t *testing.T, dataDir string) taskMetadataContents {
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "metadata", "a1b2c3d4", "task-metadata.json"))
+	require.NoError(t, err)
+	var contents taskMetadataContents
+	require.NoError(t, json.Unmarshal(data, &contents))
+	return contents
+}
+
+func TestCreateContainerMountsTaskMetadataFile(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "ecs_task_metadata_file_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	cfg := config.DefaultConfig()
+	cfg.Cluster = "myCluster"
+	cfg.DataDir = dataDir
+	cfg.DataDirOnHost = "/var/lib/ecs"
+	cfg.TaskMetadataFileEnabled = true
+	ctrl, client, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+
+	task := &api.Task{
+		Arn:     metadataTaskARN,
+		Family:  "myFamily",
+		Version: "1",
+		Containers: []*api.Container{
+			{Name: "c1", Image: "myImage"},
+		},
+	}
+	task.SetDesiredStatus(api.TaskRunning)
+	taskEngine.(*DockerTaskEngine).State().AddTask(task)
+
+	client.EXPECT().CreateContainer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+		func(config *docker.Config, hostConfig *docker.HostConfig, name string, timeout time.Duration) {
+			assert.Contains(t, hostConfig.Binds,
+				filepath.Join("/var/lib/ecs", "data", "metadata", "a1b2c3d4")+":"+containerMetadataDir+readOnlyBindSuffix)
+			assert.Contains(t, config.Env,
+				"ECS_TASK_METADATA_FILE="+containerMetadataDir+containerPathSeparator+"task-metadata.json")
+		}).Return(DockerContainerMetadata{DockerID: "dockerID"})
+
+	metadata := taskEngine.(*DockerTaskEngine).createContainer(task, task.Containers[0])
+	require.NoError(t, metadata.Error)
+
+	contents := readTaskMetadataFile(t, dataDir)
+	assert.Equal(t, "myCluster", contents.Cluster)
+	assert.Equal(t, metadataTaskARN, contents.TaskARN)
+	assert.Equal(t, "myFamily", contents.Family)
+	assert.Equal(t, "1", contents.Revision)
+	assert.Equal(t, "RUNNING", contents.DesiredStatus)
+	require.Len(t, contents.Containers, 1)
+	assert.Equal(t, "c1", contents.Containers[0].Name)
+	assert.Equal(t, "myImage", contents.Containers[0].Image)
+	assert.Empty(t, contents.Containers[0].Ports)
+}
+
+func TestRefreshTaskMetadataFile(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "ecs_task_metadata_file_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+
+	cfg := config.DefaultConfig()
+	cfg.DataDir = dataDir
+	cfg.TaskMetadataFileEnabled = true
+	ctrl, _, _, taskEngine, _, _ := mocks(t, &cfg)
+	defer ctrl.Finish()
+	engine := taskEngine.(*DockerTaskEngine)
+
+	container := &api.Container{Name: "c1", Image: "myImage"}
+	task := &api.Task{Arn: metadataTaskARN, Containers: []*api.Container{container}}
+	engine.State().AddTask(task)
+	engine.State().AddContainer(&api.DockerContainer{DockerID: "dockerID", DockerName: "dockerName", Container: container}, task)
+
+	engine.refreshTaskMetadataFile(task)
+	contents := readTaskMetadataFile(t, dataDir)
+	require.Len(t, contents.Containers, 1)
+	assert.Equal(t, "dockerID", contents.Containers[0].DockerID)
+	assert.Equal(t, "NONE", contents.Containers[0].KnownStatus)
+
+	container.SetKnownStatus(api.ContainerRunning)
+	container.KnownPortBindings = []api.PortBinding{{ContainerPort: 80, HostPort: 32768, Protocol: api.TransportProtocolTCP}}
+	engine.refreshTaskMetadataFile(task)
+
+	contents = readTaskMetadataFile(t, dataDir)
+	require.Len(t, contents.Containers, 1)
+	assert.Equal(t, "RUNNING", contents.Containers[0].KnownStatus)
+	assert.Equal(t, container.KnownPortBindings, contents.Containers[0].Ports)
+
+	engine.removeTaskMetadataFile(task)
+	_, err = os.Stat(filepath.Join(dataDir, "metadata", "a1b2c3d4"))
+	assert.True(t, os.IsNotExist(err))
+}
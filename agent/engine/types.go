@@ -13,8 +13,12 @@
 
 package engine
 
-import "fmt"
-import "github.com/aws/amazon-ecs-agent/agent/api"
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+)
 
 // ContainerNotFound is a type for a missing container
 type ContainerNotFound struct {
@@ -29,6 +33,12 @@ func (cnferror ContainerNotFound) Error() string {
 // DockerContainerChangeEvent is a type for container change events
 type DockerContainerChangeEvent struct {
 	Status api.ContainerStatus
+	// DetectedByPoll is set for changes found by the steady state poll of
+	// the task rather than reported by a docker event
+	DetectedByPoll bool
+	// Health is set for changes of the health of the container reported by
+	// its docker health check. The status of the container is unchanged
+	Health api.ContainerHealthStatus
 
 	DockerContainerMetadata
 }
@@ -40,6 +50,42 @@ type DockerContainerMetadata struct {
 	PortBindings []api.PortBinding
 	Error        engineError
 	Volumes      map[string]string
+	// StartedAt is the time docker reports the container was started at
+	StartedAt time.Time
+	// IPv4Address is the address docker assigned to the container on the
+	// network it is attached to, if any
+	IPv4Address string
+	// Reason describes, from the state docker reports, why the container
+	// exited or failed to start, e.g. because it was killed for using too
+	// much memory. It is empty otherwise
+	Reason string
+	// Paused is set if docker reports the container as paused. Docker
+	// reports paused containers as running as well
+	Paused bool
+	// HealthStatus is the health docker reports for the container, if it
+	// has a health check that has passed or failed
+	HealthStatus api.ContainerHealthStatus
+	// ImagePullSource classifies, from the layers docker reported while
+	// pulling, where the pulled image came from
+	ImagePullSource api.ImagePullSource
+}
+
+// ContainerDriftEvent is a type for events emitted when the steady-state check
+// finds a container whose status, as reported by Docker, has drifted from the
+// status known to the engine. It is written to the container change event
+// stream before the corrective transition is applied
+type ContainerDriftEvent struct {
+	TaskArn       string
+	ContainerName string
+	DockerID      string
+	KnownStatus   api.ContainerStatus
+	DockerStatus  api.ContainerStatus
+	Error         engineError
+}
+
+func (event ContainerDriftEvent) String() string {
+	return fmt.Sprintf("Container drift detected, task: %s, container: %s, id: %s, known: %s, docker: %s",
+		event.TaskArn, event.ContainerName, event.DockerID, event.KnownStatus.String(), event.DockerStatus.String())
 }
 
 // ListContainersResponse encapsulates the response from the docker client for the
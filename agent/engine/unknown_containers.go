@@ -0,0 +1,109 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// unknownContainerIgnoreDuration is how long the events of an unknown
+// container that could not be adopted are ignored, before the container is
+// inspected again
+const unknownContainerIgnoreDuration = 10 * time.Minute
+
+// unknownContainers tracks the Docker containers that are not managed by the
+// engine while they are considered for adoption. Containers are inspected
+// outside of the event loop: the events of a container being inspected are
+// held until the inspection is done, and the containers that could not be
+// adopted are ignored for a while instead of being inspected on every event
+type unknownContainers struct {
+	lock sync.Mutex
+	// pending holds the events of the containers being inspected, in the
+	// order in which they were received
+	pending map[string][]DockerContainerChangeEvent
+	// ignored holds the time at which each container that could not be
+	// adopted was inspected
+	ignored map[string]time.Time
+}
+
+func newUnknownContainers() *unknownContainers {
+	return &unknownContainers{
+		pending: make(map[string][]DockerContainerChangeEvent),
+		ignored: make(map[string]time.Time),
+	}
+}
+
+// hold holds the event if its container is being inspected. It returns true
+// if the event was held
+func (uc *unknownContainers) hold(event DockerContainerChangeEvent) bool {
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+
+	events, ok := uc.pending[event.DockerID]
+	if !ok {
+		return false
+	}
+	uc.pending[event.DockerID] = append(events, event)
+	return true
+}
+
+// add records the event of an unknown container. It returns true if the
+// container needs to be inspected, that is if it is neither being inspected
+// nor ignored
+func (uc *unknownContainers) add(event DockerContainerChangeEvent, now time.Time) bool {
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+
+	if inspected, ok := uc.ignored[event.DockerID]; ok {
+		if now.Sub(inspected) < unknownContainerIgnoreDuration {
+			return false
+		}
+		delete(uc.ignored, event.DockerID)
+	}
+	events, inspecting := uc.pending[event.DockerID]
+	uc.pending[event.DockerID] = append(events, event)
+	return !inspecting
+}
+
+// take returns the events held for the container since the last call. Once
+// no event is left, the container is no longer considered as being inspected
+func (uc *unknownContainers) take(dockerID string) []DockerContainerChangeEvent {
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+
+	events := uc.pending[dockerID]
+	if len(events) == 0 {
+		delete(uc.pending, dockerID)
+		return nil
+	}
+	uc.pending[dockerID] = nil
+	return events
+}
+
+// ignore drops the events held for a container that could not be adopted,
+// and ignores the container from now on. Containers ignored for longer than
+// unknownContainerIgnoreDuration are forgotten
+func (uc *unknownContainers) ignore(dockerID string, now time.Time) {
+	uc.lock.Lock()
+	defer uc.lock.Unlock()
+
+	delete(uc.pending, dockerID)
+	for id, inspected := range uc.ignored {
+		if now.Sub(inspected) >= unknownContainerIgnoreDuration {
+			delete(uc.ignored, id)
+		}
+	}
+	uc.ignored[dockerID] = now
+}
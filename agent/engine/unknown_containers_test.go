@@ -0,0 +1,56 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unknownContainerEvent(dockerID string) DockerContainerChangeEvent {
+	return DockerContainerChangeEvent{DockerContainerMetadata: DockerContainerMetadata{DockerID: dockerID}}
+}
+
+func TestUnknownContainersHoldEventsWhileInspecting(t *testing.T) {
+	uc := newUnknownContainers()
+	now := time.Now()
+
+	assert.False(t, uc.hold(unknownContainerEvent("c1")), "events of containers not being inspected are not held")
+	assert.True(t, uc.add(unknownContainerEvent("c1"), now), "the container should be inspected on its first event")
+	assert.False(t, uc.add(unknownContainerEvent("c1"), now), "the container is already being inspected")
+	assert.True(t, uc.hold(unknownContainerEvent("c1")))
+
+	assert.Len(t, uc.take("c1"), 3)
+	assert.True(t, uc.hold(unknownContainerEvent("c1")), "the container is inspected until no event is left")
+	assert.Len(t, uc.take("c1"), 1)
+	assert.Empty(t, uc.take("c1"))
+	assert.False(t, uc.hold(unknownContainerEvent("c1")))
+}
+
+func TestUnknownContainersIgnore(t *testing.T) {
+	uc := newUnknownContainers()
+	now := time.Now()
+
+	assert.True(t, uc.add(unknownContainerEvent("c1"), now))
+	uc.ignore("c1", now)
+	assert.False(t, uc.hold(unknownContainerEvent("c1")), "events of ignored containers are not held")
+	assert.False(t, uc.add(unknownContainerEvent("c1"), now.Add(time.Minute)), "ignored containers are not inspected again")
+
+	later := now.Add(unknownContainerIgnoreDuration)
+	uc.ignore("c2", later)
+	assert.NotContains(t, uc.ignored, "c1", "containers ignored for long enough are forgotten")
+	assert.True(t, uc.add(unknownContainerEvent("c1"), later), "the container is inspected again once it is no longer ignored")
+}
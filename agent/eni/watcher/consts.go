@@ -21,7 +21,11 @@ const (
 	udevPCISubsystem              = "pci"
 	udevEventAction               = "ACTION"
 	udevAddEvent                  = "add"
+	udevRemoveEvent               = "remove"
 	udevDevPath                   = "DEVPATH"
 	udevInterface                 = "INTERFACE"
 	defaultReconciliationInterval = time.Second * 30
+	// defaultPendingENIRetryInterval is how often enis reported by udev
+	// before their attachment was known are re-evaluated
+	defaultPendingENIRetryInterval = time.Second
 )
@@ -17,6 +17,7 @@ package watcher
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	log "github.com/cihub/seelog"
@@ -30,6 +31,7 @@ import (
 	eniUtils "github.com/aws/amazon-ecs-agent/agent/eni/networkutils"
 	"github.com/aws/amazon-ecs-agent/agent/eni/udevwrapper"
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 )
 
 const (
@@ -46,21 +48,51 @@ const (
 // to the instance. It also has supporting elements to
 // maintain consistency and update intervals
 type UdevWatcher struct {
-	ctx                  context.Context
-	cancel               context.CancelFunc
-	updateIntervalTicker *time.Ticker
-	netlinkClient        netlinkwrapper.NetLink
-	udevMonitor          udevwrapper.Udev
-	events               chan *udev.UEvent
-	agentState           dockerstate.TaskEngineState
-	eniChangeEvent       chan<- statechange.Event
-	primaryMAC           string
-}
-
-// New is used to return an instance of the UdevWatcher struct
+	ctx            context.Context
+	cancel         context.CancelFunc
+	netlinkClient  netlinkwrapper.NetLink
+	udevMonitor    udevwrapper.Udev
+	events         chan *udev.UEvent
+	agentState     dockerstate.TaskEngineState
+	eniChangeEvent chan<- statechange.Event
+	primaryMAC     string
+	// reconciliationInterval is how often the enis on the instance are
+	// reconciled with their attachments, to send the attachments of enis
+	// whose udev events were missed
+	reconciliationInterval time.Duration
+	time                   ttime.Time
+	// pendingENIs maps the mac addresses of ENIs reported by udev before their
+	// attachment was known to the time they were reported. It is only
+	// accessed from the udev event handler
+	pendingENIs map[string]time.Time
+	// pendingENITimeout is how long a pending ENI is re-evaluated for before
+	// it is dropped. Pending ENIs are dropped right away if it is not positive
+	pendingENITimeout       time.Duration
+	pendingENIRetryInterval time.Duration
+	// maxENIs is the maximum number of enis held by the watcher until their
+	// attachment is known. The number of enis is not limited if it is not
+	// positive
+	maxENIs int
+	// interfaceMACs maps the names of the network interfaces seen by the
+	// watcher to their mac addresses, as udev remove events are only
+	// reported by name once the interface is gone
+	interfaceMACs     map[string]string
+	interfaceMACsLock sync.Mutex
+}
+
+// New is used to return an instance of the UdevWatcher struct. Udev events
+// for ENIs whose attachment is not yet known are held for pendingENITimeout
+// in case the attachment is received late. At most maxENIs ENIs are held,
+// unless it is not positive. The ENIs on the instance are reconciled every
+// reconciliationInterval
 func New(ctx context.Context, primaryMAC string, udevwrap udevwrapper.Udev,
-	state dockerstate.TaskEngineState, stateChangeEvents chan<- statechange.Event) *UdevWatcher {
-	return newWatcher(ctx, primaryMAC, netlinkwrapper.New(), udevwrap, state, stateChangeEvents)
+	state dockerstate.TaskEngineState, stateChangeEvents chan<- statechange.Event,
+	pendingENITimeout time.Duration, maxENIs int, reconciliationInterval time.Duration) *UdevWatcher {
+	watcher := newWatcher(ctx, primaryMAC, netlinkwrapper.New(), udevwrap, state, stateChangeEvents)
+	watcher.pendingENITimeout = pendingENITimeout
+	watcher.maxENIs = maxENIs
+	watcher.reconciliationInterval = reconciliationInterval
+	return watcher
 }
 
 // newWatcher is used to nest the return of the UdevWatcher struct
@@ -81,11 +113,18 @@ func newWatcher(ctx context.Context,
 		agentState:     state,
 		eniChangeEvent: stateChangeEvents,
 		primaryMAC:     primaryMAC,
+		pendingENIs:    make(map[string]time.Time),
+		interfaceMACs:  make(map[string]string),
+
+		reconciliationInterval:  defaultReconciliationInterval,
+		time:                    &ttime.DefaultTime{},
+		pendingENIRetryInterval: defaultPendingENIRetryInterval,
 	}
 }
 
 // Init initializes a new ENI Watcher
 func (udevWatcher *UdevWatcher) Init() error {
+	udevWatcher.removeExpiredENIAttachments()
 	return udevWatcher.reconcileOnce()
 }
 
@@ -93,7 +132,7 @@ func (udevWatcher *UdevWatcher) Init() error {
 func (udevWatcher *UdevWatcher) Start() {
 	// Udev Event Handler
 	go udevWatcher.eventHandler()
-	udevWatcher.performPeriodicReconciliation(defaultReconciliationInterval)
+	udevWatcher.reconcile()
 }
 
 // Stop is used to invoke the cancellation routine
@@ -101,18 +140,18 @@ func (udevWatcher *UdevWatcher) Stop() {
 	udevWatcher.cancel()
 }
 
-// performPeriodicReconciliation is used to periodically invoke the
-// reconciliation process based on a ticker
-func (udevWatcher *UdevWatcher) performPeriodicReconciliation(updateInterval time.Duration) {
-	udevWatcher.updateIntervalTicker = time.NewTicker(updateInterval)
+// reconcile periodically reconciles the state of ENIs attached to the
+// instance, so that attachments whose udev events were missed are sent. It
+// returns when the context of the watcher is cancelled
+func (udevWatcher *UdevWatcher) reconcile() {
 	for {
 		select {
-		case <-udevWatcher.updateIntervalTicker.C:
+		case <-udevWatcher.time.After(udevWatcher.reconciliationInterval):
+			udevWatcher.removeExpiredENIAttachments()
 			if err := udevWatcher.reconcileOnce(); err != nil {
 				log.Warnf("Udev watcher reconciliation failed: %v", err)
 			}
 		case <-udevWatcher.ctx.Done():
-			udevWatcher.updateIntervalTicker.Stop()
 			return
 		}
 	}
@@ -138,16 +177,22 @@ func (udevWatcher *UdevWatcher) reconcileOnce() error {
 	// the race here. The state would be corrected during the next reconciliation loop.
 
 	// Add new interfaces next
-	for mac, _ := range currentState {
+	for mac, name := range currentState {
+		udevWatcher.recordInterfaceMAC(name, mac)
 		udevWatcher.sendENIStateChange(mac)
 	}
 	return nil
 }
 
-// sendENIStateChange handles the eni event from udev or reconcile phase
-func (udevWatcher *UdevWatcher) sendENIStateChange(mac string) {
+// sendENIStateChange handles the eni event from udev or reconcile phase. It
+// returns false if the eni is not managed by ecs
+func (udevWatcher *UdevWatcher) sendENIStateChange(mac string) bool {
 	eniAttachment, ok := udevWatcher.shouldSendENIStateChange(mac)
 	if ok {
+		eniAttachment.SetAttachedAt(time.Now())
+		if lag, ok := eniAttachment.GetReconciliationLag(); ok {
+			log.Infof("Udev watcher: device of eni %s confirmed %v after its attachment was received", mac, lag)
+		}
 		go func(eni *api.ENIAttachment) {
 			eni.Status = api.ENIAttached
 			log.Infof("Emitting ENI change event for: %v", eni)
@@ -157,6 +202,93 @@ func (udevWatcher *UdevWatcher) sendENIStateChange(mac string) {
 			}
 		}(eniAttachment)
 	}
+	return eniAttachment != nil
+}
+
+// addPendingENI holds on to an eni reported by udev before its attachment is
+// known, so that it can be re-evaluated once the attachment is received
+func (udevWatcher *UdevWatcher) addPendingENI(mac string) {
+	if mac == "" || udevWatcher.pendingENITimeout <= 0 {
+		return
+	}
+	if _, ok := udevWatcher.pendingENIs[mac]; ok {
+		return
+	}
+	if udevWatcher.maxENIs > 0 && len(udevWatcher.pendingENIs) >= udevWatcher.maxENIs {
+		log.Warnf("Udev watcher: not holding event for eni %s, the maximum number of pending enis (%d) is reached",
+			mac, udevWatcher.maxENIs)
+		return
+	}
+	log.Debugf("Udev watcher: holding event for eni %s until its attachment is known", mac)
+	udevWatcher.pendingENIs[mac] = time.Now()
+}
+
+// retryPendingENIs re-evaluates the pending enis, sending the state changes of
+// those whose attachment is now known and dropping those that have been
+// pending for longer than the timeout
+func (udevWatcher *UdevWatcher) retryPendingENIs() {
+	for mac, reported := range udevWatcher.pendingENIs {
+		if udevWatcher.sendENIStateChange(mac) {
+			delete(udevWatcher.pendingENIs, mac)
+			continue
+		}
+		if time.Since(reported) >= udevWatcher.pendingENITimeout {
+			log.Infof("Udev watcher: no attachment received for eni %s within %v, dropping its event",
+				mac, udevWatcher.pendingENITimeout)
+			delete(udevWatcher.pendingENIs, mac)
+		}
+	}
+}
+
+// removeExpiredENIAttachments stops tracking the attachments of enis whose
+// devices did not appear before the attachments expired. Their ack timers do
+// the same, but are not restarted for attachments restored from saved state
+func (udevWatcher *UdevWatcher) removeExpiredENIAttachments() {
+	for _, eni := range udevWatcher.agentState.AllENIAttachments() {
+		if eni.IsSent() || !eni.HasExpired() {
+			continue
+		}
+		log.Warnf("Udev watcher: device of eni %s did not appear before its attachment expired, removing %s",
+			eni.MACAddress, eni.String())
+		udevWatcher.agentState.RemoveENIAttachment(eni.MACAddress)
+	}
+}
+
+// recordInterfaceMAC records the mac address of a network interface, so that
+// it can be looked up when the interface is removed
+func (udevWatcher *UdevWatcher) recordInterfaceMAC(name, mac string) {
+	udevWatcher.interfaceMACsLock.Lock()
+	defer udevWatcher.interfaceMACsLock.Unlock()
+	udevWatcher.interfaceMACs[name] = mac
+}
+
+// removeInterfaceMAC stops tracking a network interface and returns its mac
+// address. It returns false if the interface was not seen by the watcher
+func (udevWatcher *UdevWatcher) removeInterfaceMAC(name string) (string, bool) {
+	udevWatcher.interfaceMACsLock.Lock()
+	defer udevWatcher.interfaceMACsLock.Unlock()
+	mac, ok := udevWatcher.interfaceMACs[name]
+	delete(udevWatcher.interfaceMACs, name)
+	return mac, ok
+}
+
+// handleENIRemoved stops tracking the attachment of an eni whose device was
+// removed from the instance, so that it does not linger in the state. Devices
+// that are not enis managed by ecs are ignored
+func (udevWatcher *UdevWatcher) handleENIRemoved(netInterface string) {
+	mac, ok := udevWatcher.removeInterfaceMAC(netInterface)
+	if !ok {
+		log.Debugf("Udev watcher event-handler: ignoring removal of unknown interface: %s", netInterface)
+		return
+	}
+	delete(udevWatcher.pendingENIs, mac)
+	eniAttachment, ok := udevWatcher.agentState.ENIByMac(mac)
+	if !ok {
+		log.Debugf("Udev watcher event-handler: ignoring removal of interface %s, eni not managed by ecs: %s", netInterface, mac)
+		return
+	}
+	log.Infof("Udev watcher event-handler: device of eni %s was removed, removing %s", mac, eniAttachment.String())
+	udevWatcher.agentState.RemoveENIAttachment(mac)
 }
 
 // shouldSendENIStateChange checks whether this eni is managed by ecs
@@ -178,6 +310,11 @@ func (udevWatcher *UdevWatcher) shouldSendENIStateChange(macAddress string) (*ap
 		return eni, false
 	}
 
+	if eni.HasExpired() {
+		log.Warnf("ENI state manager: eni attachment has expired: %s", macAddress)
+		return eni, false
+	}
+
 	return eni, true
 }
 
@@ -206,13 +343,22 @@ func (udevWatcher *UdevWatcher) buildState(links []netlink.Link) map[string]stri
 func (udevWatcher *UdevWatcher) eventHandler() {
 	// The shutdown channel will be used to terminate the watch for udev events
 	shutdown := udevWatcher.udevMonitor.Monitor(udevWatcher.events)
+	retryTicker := time.NewTicker(udevWatcher.pendingENIRetryInterval)
+	defer retryTicker.Stop()
 	for {
 		select {
+		case <-retryTicker.C:
+			udevWatcher.retryPendingENIs()
 		case event := <-udevWatcher.events:
 			subsystem, ok := event.Env[udevSubsystem]
 			if !ok || subsystem != udevNetSubsystem {
 				continue
 			}
+			if event.Env[udevEventAction] == udevRemoveEvent {
+				log.Debugf("Udev watcher event-handler: remove interface: %s", event.Env[udevInterface])
+				udevWatcher.handleENIRemoved(event.Env[udevInterface])
+				continue
+			}
 			if event.Env[udevEventAction] != udevAddEvent {
 				continue
 			}
@@ -227,7 +373,10 @@ func (udevWatcher *UdevWatcher) eventHandler() {
 				log.Warnf("Udev watcher event-handler: error obtaining MACAddress for interface %s", netInterface)
 				continue
 			}
-			udevWatcher.sendENIStateChange(macAddress)
+			udevWatcher.recordInterfaceMAC(netInterface, macAddress)
+			if !udevWatcher.sendENIStateChange(macAddress) {
+				udevWatcher.addPendingENI(macAddress)
+			}
 		case <-udevWatcher.ctx.Done():
 			log.Info("Stopping udev event handler")
 			// Send the shutdown signal and close the connection
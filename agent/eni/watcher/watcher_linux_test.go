@@ -22,6 +22,7 @@ import (
 	"net"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/deniswernert/udev"
 	"github.com/golang/mock/gomock"
@@ -36,6 +37,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/eni/netlinkwrapper/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/eni/udevwrapper/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
 )
 
 const (
@@ -204,6 +206,171 @@ func TestReconcileENIs(t *testing.T) {
 	}
 }
 
+// TestReconcileENIsRestoredAttachStatusSent checks that the attached status of
+// an eni that was sent before the agent restarted is not sent again
+func TestReconcileENIsRestoredAttachStatusSent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	parsedMAC, err := net.ParseMAC(randomMAC)
+	require.NoError(t, err)
+
+	savedState := dockerstate.NewTaskEngineState()
+	savedState.AddENIAttachment(&api.ENIAttachment{
+		MACAddress:       randomMAC,
+		AttachStatusSent: true,
+	})
+	data, err := savedState.MarshalJSON()
+	require.NoError(t, err)
+	taskEngineState := dockerstate.NewTaskEngineState()
+	require.NoError(t, taskEngineState.UnmarshalJSON(data))
+	eniAttachment, ok := taskEngineState.ENIByMac(randomMAC)
+	require.True(t, ok)
+	require.True(t, eniAttachment.IsSent())
+
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	mockNetlink.EXPECT().LinkList().Return([]netlink.Link{
+		&netlink.Device{
+			LinkAttrs: netlink.LinkAttrs{
+				HardwareAddr: parsedMAC,
+				Name:         randomDevice,
+			},
+		},
+	}, nil)
+	eventChannel := make(chan statechange.Event)
+
+	watcher := newWatcher(context.Background(), primaryMAC, mockNetlink, nil, taskEngineState, eventChannel)
+	require.NoError(t, watcher.reconcileOnce())
+
+	select {
+	case event := <-eventChannel:
+		t.Errorf("Unexpected state change event for an eni whose attached status was sent: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestReconcileENIsPeriodically checks that the enis on the instance are
+// reconciled at every interval, and that the attachment of an eni is only sent
+// by the first reconciliation that finds it
+func TestReconcileENIsPeriodically(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	parsedMAC, err := net.ParseMAC(randomMAC)
+	require.NoError(t, err)
+
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	mockTime := mock_ttime.NewMockTime(mockCtrl)
+	taskEngineState := dockerstate.NewTaskEngineState()
+	eventChannel := make(chan statechange.Event)
+
+	eniAttachment := &api.ENIAttachment{
+		MACAddress:       randomMAC,
+		AttachStatusSent: false,
+	}
+	taskEngineState.AddENIAttachment(eniAttachment)
+
+	reconciled := make(chan struct{})
+	mockNetlink.EXPECT().LinkList().Do(func() {
+		reconciled <- struct{}{}
+	}).Return([]netlink.Link{
+		&netlink.Device{
+			LinkAttrs: netlink.LinkAttrs{
+				HardwareAddr: parsedMAC,
+				Name:         randomDevice,
+			},
+		},
+	}, nil).Times(2)
+	firstTick := make(chan time.Time, 1)
+	secondTick := make(chan time.Time, 1)
+	gomock.InOrder(
+		mockTime.EXPECT().After(time.Minute).Return(firstTick),
+		mockTime.EXPECT().After(time.Minute).Return(secondTick),
+		mockTime.EXPECT().After(time.Minute).Return(make(chan time.Time)).AnyTimes(),
+	)
+
+	watcher := newWatcher(context.Background(), primaryMAC, mockNetlink, nil, taskEngineState, eventChannel)
+	watcher.reconciliationInterval = time.Minute
+	watcher.time = mockTime
+	stopped := make(chan struct{})
+	go func() {
+		watcher.reconcile()
+		close(stopped)
+	}()
+
+	firstTick <- time.Now()
+	<-reconciled
+	select {
+	case event := <-eventChannel:
+		assert.Equal(t, randomMAC, event.(api.TaskStateChange).Attachment.MACAddress)
+	case <-time.After(time.Second):
+		t.Fatal("Expected a state change event from the first reconciliation")
+	}
+	// The attached status is marked as sent once the event is submitted
+	eniAttachment.SetSentStatus()
+
+	secondTick <- time.Now()
+	<-reconciled
+	select {
+	case event := <-eventChannel:
+		t.Errorf("Unexpected state change event from the second reconciliation: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	watcher.Stop()
+	<-stopped
+}
+
+// TestReconcileENIsExpiredAttachment checks that the attachment of an eni
+// whose device did not appear before it expired is removed, and that the
+// device appearing afterwards is not reported
+func TestReconcileENIsExpiredAttachment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	parsedMAC, err := net.ParseMAC(randomMAC)
+	require.NoError(t, err)
+
+	taskEngineState := dockerstate.NewTaskEngineState()
+	taskEngineState.AddENIAttachment(&api.ENIAttachment{
+		MACAddress: randomMAC,
+		ExpiresAt:  time.Now().Add(-time.Second),
+	})
+	taskEngineState.AddENIAttachment(&api.ENIAttachment{
+		MACAddress:       primaryMAC,
+		AttachStatusSent: true,
+		ExpiresAt:        time.Now().Add(-time.Second),
+	})
+	eventChannel := make(chan statechange.Event)
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	watcher := newWatcher(context.Background(), primaryMAC, mockNetlink, nil, taskEngineState, eventChannel)
+
+	_, ok := watcher.shouldSendENIStateChange(randomMAC)
+	assert.False(t, ok, "expired attachments should not be reported")
+
+	// The device appears after the attachment expired
+	mockNetlink.EXPECT().LinkList().Return([]netlink.Link{
+		&netlink.Device{
+			LinkAttrs: netlink.LinkAttrs{
+				HardwareAddr: parsedMAC,
+				Name:         randomDevice,
+			},
+		},
+	}, nil)
+	require.NoError(t, watcher.Init())
+
+	_, ok = taskEngineState.ENIByMac(randomMAC)
+	assert.False(t, ok, "the expired attachment should no longer be tracked")
+	_, ok = taskEngineState.ENIByMac(primaryMAC)
+	assert.True(t, ok, "attachments whose status was sent should be kept")
+
+	select {
+	case event := <-eventChannel:
+		t.Errorf("Unexpected state change event for an expired eni attachment: %v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // TestReconcileENIsWithNetlinkErr tests reconciliation with netlink error
 func TestReconcileENIsWithNetlinkErr(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
@@ -321,6 +488,228 @@ func TestUdevAddEvent(t *testing.T) {
 	waitForClose.Wait()
 }
 
+// TestUdevRemoveEvent checks that the attachment of an eni is removed from the
+// state when udev reports that its device was removed
+func TestUdevRemoveEvent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	mockUdev := mock_udevwrapper.NewMockUdev(mockCtrl)
+	parsedMAC, _ := net.ParseMAC(randomMAC)
+	taskEngineState := dockerstate.NewTaskEngineState()
+	eventChannel := make(chan statechange.Event)
+
+	taskEngineState.AddENIAttachment(&api.ENIAttachment{MACAddress: randomMAC})
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	watcher := newWatcher(ctx, primaryMAC, mockNetlink, mockUdev, taskEngineState, eventChannel)
+
+	mockUdev.EXPECT().Monitor(watcher.events).Return(make(chan bool, 1))
+	mockUdev.EXPECT().Close().Return(nil).AnyTimes()
+	mockNetlink.EXPECT().LinkByName(randomDevice).Return(
+		&netlink.Device{
+			LinkAttrs: netlink.LinkAttrs{
+				HardwareAddr: parsedMAC,
+				Name:         randomDevice,
+			},
+		}, nil)
+
+	go watcher.eventHandler()
+	addEvent := getUdevEventDummy(udevAddEvent, udevNetSubsystem, randomDevPath)
+	watcher.events <- &addEvent
+	eniChangeEvent := <-eventChannel
+	assert.Equal(t, randomMAC, eniChangeEvent.(api.TaskStateChange).Attachment.MACAddress)
+
+	removeEvent := getUdevEventDummy(udevRemoveEvent, udevNetSubsystem, randomDevPath)
+	watcher.events <- &removeEvent
+	for i := 0; i < 100; i++ {
+		if _, ok := taskEngineState.ENIByMac(randomMAC); !ok {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, ok := taskEngineState.ENIByMac(randomMAC)
+	assert.False(t, ok, "attachment of the removed eni should be removed")
+}
+
+// TestUdevRemoveEventUnknownInterface checks that udev events for the removal
+// of interfaces the watcher has not seen are ignored
+func TestUdevRemoveEventUnknownInterface(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	taskEngineState := dockerstate.NewTaskEngineState()
+	taskEngineState.AddENIAttachment(&api.ENIAttachment{MACAddress: randomMAC})
+	watcher := newWatcher(context.TODO(), primaryMAC, nil, nil, taskEngineState, nil)
+	watcher.recordInterfaceMAC("eth2", "00:0a:95:9d:68:17")
+
+	watcher.handleENIRemoved(randomDevice)
+	_, ok := taskEngineState.ENIByMac(randomMAC)
+	assert.True(t, ok, "attachments should not be removed for unknown interfaces")
+
+	watcher.handleENIRemoved("eth2")
+	_, ok = taskEngineState.ENIByMac(randomMAC)
+	assert.True(t, ok, "attachments should not be removed for enis not managed by ecs")
+	assert.Empty(t, watcher.interfaceMACs)
+}
+
+// TestUdevAddEventRecordsReconciliationLag checks that the time at which the
+// device of an eni is confirmed is recorded, so that the lag is measured from
+// the time its attachment was received
+func TestUdevAddEventRecordsReconciliationLag(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ctx := context.TODO()
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	mockUdev := mock_udevwrapper.NewMockUdev(mockCtrl)
+	parsedMAC, _ := net.ParseMAC(randomMAC)
+	mockStateManager := mock_dockerstate.NewMockTaskEngineState(mockCtrl)
+	eventChannel := make(chan statechange.Event)
+
+	watcher := newWatcher(ctx, primaryMAC, mockNetlink, mockUdev, mockStateManager, eventChannel)
+
+	receivedAt := time.Now().Add(-time.Second)
+	eniAttachment := &api.ENIAttachment{
+		MACAddress: randomMAC,
+		ReceivedAt: receivedAt,
+	}
+	shutdown := make(chan bool)
+	gomock.InOrder(
+		mockUdev.EXPECT().Monitor(watcher.events).Return(shutdown),
+		mockNetlink.EXPECT().LinkByName(randomDevice).Return(
+			&netlink.Device{
+				LinkAttrs: netlink.LinkAttrs{
+					HardwareAddr: parsedMAC,
+					Name:         randomDevice,
+				},
+			}, nil),
+		mockStateManager.EXPECT().ENIByMac(randomMAC).Return(eniAttachment, true),
+	)
+
+	go watcher.eventHandler()
+	beforeEvent := time.Now()
+	event := getUdevEventDummy(udevAddEvent, udevNetSubsystem, randomDevPath)
+	watcher.events <- &event
+	<-eventChannel
+
+	lag, ok := eniAttachment.GetReconciliationLag()
+	require.True(t, ok, "reconciliation lag should be known once the device is confirmed")
+	assert.False(t, eniAttachment.AttachedAt.Before(beforeEvent))
+	assert.Equal(t, eniAttachment.AttachedAt.Sub(receivedAt), lag)
+	assert.True(t, lag >= time.Second)
+
+	var waitForClose sync.WaitGroup
+	waitForClose.Add(2)
+	mockUdev.EXPECT().Close().Do(func() {
+		waitForClose.Done()
+	}).Return(nil)
+	go func() {
+		<-shutdown
+		waitForClose.Done()
+	}()
+
+	go watcher.Stop()
+	waitForClose.Wait()
+}
+
+// TestUdevAddEventBeforeAttachment checks that a udev add event for an eni
+// whose attachment is not known yet is held until the attachment is received
+func TestUdevAddEventBeforeAttachment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ctx := context.TODO()
+	mockNetlink := mock_netlinkwrapper.NewMockNetLink(mockCtrl)
+	mockUdev := mock_udevwrapper.NewMockUdev(mockCtrl)
+	parsedMAC, _ := net.ParseMAC(randomMAC)
+	mockStateManager := mock_dockerstate.NewMockTaskEngineState(mockCtrl)
+	eventChannel := make(chan statechange.Event)
+
+	// Create Watcher
+	watcher := newWatcher(ctx, primaryMAC, mockNetlink, mockUdev, mockStateManager, eventChannel)
+	watcher.pendingENITimeout = time.Minute
+	watcher.pendingENIRetryInterval = 10 * time.Millisecond
+
+	shutdown := make(chan bool)
+	eniUnknown := make(chan struct{})
+	eniAttachment := &api.ENIAttachment{TaskARN: "task", MACAddress: randomMAC}
+	gomock.InOrder(
+		mockUdev.EXPECT().Monitor(watcher.events).Return(shutdown),
+		mockNetlink.EXPECT().LinkByName(randomDevice).Return(
+			&netlink.Device{
+				LinkAttrs: netlink.LinkAttrs{
+					HardwareAddr: parsedMAC,
+					Name:         randomDevice,
+				},
+			}, nil),
+		// The attachment has not been received when the udev event arrives
+		mockStateManager.EXPECT().ENIByMac(randomMAC).Do(func(string) {
+			close(eniUnknown)
+		}).Return(nil, false),
+		// The attachment is received afterwards
+		mockStateManager.EXPECT().ENIByMac(randomMAC).Return(eniAttachment, true),
+	)
+
+	// Spin off event handler
+	go watcher.eventHandler()
+	// Send event to channel
+	event := getUdevEventDummy(udevAddEvent, udevNetSubsystem, randomDevPath)
+	watcher.events <- &event
+	<-eniUnknown
+
+	eniChangeEvent := <-eventChannel
+	taskStateChange, ok := eniChangeEvent.(api.TaskStateChange)
+	require.True(t, ok)
+	assert.Equal(t, "task", taskStateChange.TaskARN)
+	assert.Equal(t, api.ENIAttached, taskStateChange.Attachment.Status)
+
+	var waitForClose sync.WaitGroup
+	waitForClose.Add(2)
+	mockUdev.EXPECT().Close().Do(func() {
+		waitForClose.Done()
+	}).Return(nil)
+	go func() {
+		<-shutdown
+		waitForClose.Done()
+	}()
+
+	go watcher.Stop()
+	waitForClose.Wait()
+	assert.Empty(t, watcher.pendingENIs)
+}
+
+// TestRetryPendingENIsExpires checks that enis whose attachment is not
+// received in time are dropped
+func TestRetryPendingENIsExpires(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockStateManager := mock_dockerstate.NewMockTaskEngineState(mockCtrl)
+	watcher := newWatcher(context.TODO(), primaryMAC, nil, nil, mockStateManager, nil)
+	watcher.pendingENITimeout = time.Minute
+
+	mockStateManager.EXPECT().ENIByMac(randomMAC).Return(nil, false).Times(2)
+
+	watcher.addPendingENI(randomMAC)
+	watcher.retryPendingENIs()
+	assert.Contains(t, watcher.pendingENIs, randomMAC, "eni should still be pending")
+
+	watcher.pendingENIs[randomMAC] = time.Now().Add(-2 * time.Minute)
+	watcher.retryPendingENIs()
+	assert.Empty(t, watcher.pendingENIs, "eni should have been dropped")
+}
+
+// TestAddPendingENIDisabled checks that enis are not held when the pending
+// eni timeout is not set
+func TestAddPendingENIDisabled(t *testing.T) {
+	watcher := newWatcher(context.TODO(), primaryMAC, nil, nil, nil, nil)
+	watcher.addPendingENI(randomMAC)
+	assert.Empty(t, watcher.pendingENIs)
+}
+
 // TestUdevSubsystemFilter checks the subsystem filter in the event handler
 func TestUdevSubsystemFilter(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
@@ -462,6 +851,19 @@ func TestSendENIStateChange(t *testing.T) {
 	assert.Equal(t, api.ENIAttached, taskStateChange.Attachment.Status)
 }
 
+// TestAddPendingENIBeyondMaxENIs checks that no more enis than the maximum
+// number of tracked enis are held until their attachment is known
+func TestAddPendingENIBeyondMaxENIs(t *testing.T) {
+	watcher := newWatcher(context.TODO(), primaryMAC, nil, nil, nil, nil)
+	watcher.pendingENITimeout = time.Minute
+	watcher.maxENIs = 1
+
+	watcher.addPendingENI(randomMAC)
+	watcher.addPendingENI(primaryMAC)
+	assert.Len(t, watcher.pendingENIs, 1)
+	assert.Contains(t, watcher.pendingENIs, randomMAC)
+}
+
 func TestShouldSendENIStateChange(t *testing.T) {
 	testCases := []struct {
 		eniAttachment     *api.ENIAttachment
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/statechange"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -86,6 +87,79 @@ func TestSendsEventsOneEventRetries(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSubmissionFailureRecorded(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	stateManager := statemanager.NewNoopStateManager()
+
+	handler := NewTaskHandler(stateManager)
+	taskarn := "taskarn"
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	retriable := utils.NewRetriableError(utils.NewRetriable(true), errors.New("ThrottlingException"))
+	submitted := make(chan struct{})
+
+	gomock.InOrder(
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(retriable).Do(func(interface{}) { wg.Done() }),
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(nil).Do(func(interface{}) {
+			// The failure is recorded before the submission is retried
+			stats := handler.GetSubmissionStats()
+			assert.Equal(t, int64(1), stats.Failures)
+			assert.Equal(t, int64(1), stats.ConsecutiveFailures)
+			assert.Contains(t, stats.LastError, "ThrottlingException")
+			assert.False(t, stats.LastErrorTime.IsZero())
+			assert.True(t, stats.LastErrorRetriable)
+			wg.Done()
+			close(submitted)
+		}),
+	)
+
+	handler.AddStateChangeEvent(taskEvent(taskarn), client)
+	wg.Wait()
+	<-submitted
+
+	// The consecutive failures are reset by the successful submission
+	for i := 0; i < 100 && handler.GetSubmissionStats().ConsecutiveFailures != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int64(0), handler.GetSubmissionStats().ConsecutiveFailures)
+	assert.Equal(t, int64(1), handler.GetSubmissionStats().Failures)
+}
+
+func TestInstanceNotFoundSubmissionFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	stateManager := statemanager.NewNoopStateManager()
+
+	handler := NewTaskHandler(stateManager)
+	reregistrations := make(chan struct{}, 2)
+	submitted := make(chan struct{})
+	handler.SetInstanceNotFoundHandler(func() error {
+		reregistrations <- struct{}{}
+		// The handler isn't invoked again for failures while it runs
+		<-submitted
+		return nil
+	})
+
+	notFound := awserr.New("ClientException", "The referenced container instance was not found.", nil)
+	gomock.InOrder(
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(notFound),
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(notFound),
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Return(nil).Do(func(interface{}) {
+			close(submitted)
+		}),
+	)
+
+	handler.AddStateChangeEvent(taskEvent("taskarn"), client)
+	<-submitted
+	<-reregistrations
+	assert.Len(t, reregistrations, 0, "re-registration should be attempted once")
+}
+
 func TestSendsEventsConcurrentLimit(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -244,6 +318,107 @@ func TestSendsEventsDedupe(t *testing.T) {
 	wg.Wait()
 }
 
+func TestSendsEventsCoalescedWithinBatchWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	stateManager := statemanager.NewNoopStateManager()
+
+	handler := NewTaskHandler(stateManager)
+	handler.SetBatchWindow(100 * time.Millisecond)
+	taskarn := "taskarn"
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	// The container changes within the window are submitted with the task
+	// change, keeping only the latest change of each container
+	client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
+		assert.Equal(t, api.TaskRunning, change.Status)
+		assert.Equal(t, 1, len(change.Containers))
+		assert.Equal(t, api.ContainerStopped, change.Containers[0].Status)
+		wg.Done()
+	}).Times(1)
+
+	submitStart := time.Now()
+	handler.AddStateChangeEvent(containerEvent(taskarn), client)
+	handler.AddStateChangeEvent(taskEvent(taskarn), client)
+	handler.AddStateChangeEvent(containerEventStopped(taskarn), client)
+
+	wg.Wait()
+	assert.True(t, time.Since(submitStart) >= 100*time.Millisecond, "the change should be held for the window")
+}
+
+func TestSendsEveryTaskStatusWithinBatchWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	stateManager := statemanager.NewNoopStateManager()
+
+	handler := NewTaskHandler(stateManager)
+	handler.SetBatchWindow(100 * time.Millisecond)
+	taskarn := "taskarn"
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// The task goes from RUNNING to STOPPED within the window; both changes
+	// are submitted, in order
+	gomock.InOrder(
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
+			assert.Equal(t, api.TaskRunning, change.Status)
+			assert.Equal(t, 1, len(change.Containers))
+			assert.Equal(t, api.ContainerRunning, change.Containers[0].Status)
+			wg.Done()
+		}),
+		client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
+			assert.Equal(t, api.TaskStopped, change.Status)
+			assert.Equal(t, 1, len(change.Containers))
+			assert.Equal(t, api.ContainerStopped, change.Containers[0].Status)
+			wg.Done()
+		}),
+	)
+
+	handler.AddStateChangeEvent(containerEvent(taskarn), client)
+	handler.AddStateChangeEvent(taskEvent(taskarn), client)
+	stoppedEvent := taskEventStopped(taskarn).(api.TaskStateChange)
+	stoppedEvent.Containers = []api.ContainerStateChange{containerEventStopped(taskarn).(api.ContainerStateChange)}
+	handler.AddStateChangeEvent(stoppedEvent, client)
+
+	wg.Wait()
+}
+
+func TestSendsEventsBatchWindowPerTask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+	stateManager := statemanager.NewNoopStateManager()
+
+	handler := NewTaskHandler(stateManager)
+	handler.SetBatchWindow(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Changes of different tasks are not coalesced
+	submitted := make(chan string, 2)
+	client.EXPECT().SubmitTaskStateChange(gomock.Any()).Do(func(change api.TaskStateChange) {
+		submitted <- change.TaskARN
+		wg.Done()
+	}).Times(2)
+
+	handler.AddStateChangeEvent(taskEvent("taskarnA"), client)
+	handler.AddStateChangeEvent(taskEvent("taskarnB"), client)
+
+	wg.Wait()
+	close(submitted)
+	arns := make(map[string]bool)
+	for arn := range submitted {
+		arns[arn] = true
+	}
+	assert.Equal(t, map[string]bool{"taskarnA": true, "taskarnB": true}, arns)
+}
+
 // TestCleanupTaskEventAfterSubmit tests the map of task event is removed after
 // calling submittaskstatechange
 func TestCleanupTaskEventAfterSubmit(t *testing.T) {
@@ -277,6 +452,24 @@ func TestCleanupTaskEventAfterSubmit(t *testing.T) {
 	assert.Len(t, handler.tasksToEvents, 0)
 }
 
+func TestHealthChangeOfSubmittedContainerNotBatched(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := mock_api.NewMockECSClient(ctrl)
+
+	handler := NewTaskHandler(statemanager.NewNoopStateManager())
+	container := &api.Container{SentStatusUnsafe: api.ContainerRunning}
+	healthEvent := api.ContainerStateChange{
+		TaskArn:   "taskarn",
+		Status:    api.ContainerRunning,
+		Health:    api.ContainerHealthy,
+		Container: container,
+	}
+
+	assert.NoError(t, handler.AddStateChangeEvent(healthEvent, client))
+	assert.Empty(t, handler.tasksToContainerStates["taskarn"])
+}
+
 func TestShouldBeSent(t *testing.T) {
 	sendableEvent := newSendableContainerEvent(api.ContainerStateChange{
 		Status: api.ContainerStopped,
@@ -49,15 +49,51 @@ type TaskHandler struct {
 	// tasksToContainerStates is used to collect container events
 	// between task transitions
 	tasksToContainerStates map[string][]api.ContainerStateChange
+	// tasksToPendingEvents holds the task events that are coalesced within
+	// the batch window before they are submitted
+	tasksToPendingEvents map[string]*api.TaskStateChange
 
 	//  taskHandlerLock is used to safely access the following maps:
 	// * taskToEvents
 	// * tasksToContainerStates
+	// * tasksToPendingEvents
 	taskHandlerLock sync.RWMutex
 
+	// batchWindow is the window over which task events are coalesced before
+	// they are submitted. Task events are submitted right away if it is 0
+	batchWindow time.Duration
+
 	// stateSaver is a statemanager which may be used to save any
 	// changes to a task or container's SentStatus
 	stateSaver statemanager.Saver
+
+	// submissionStats tracks the failures to submit state changes
+	submissionStats     SubmissionStats
+	submissionStatsLock sync.RWMutex
+
+	// instanceNotFoundHandler is invoked when a submission fails because the
+	// container instance is no longer registered
+	instanceNotFoundHandler func() error
+	// handlingInstanceNotFound is set while instanceNotFoundHandler runs, so
+	// that failures of other submissions don't invoke it again
+	handlingInstanceNotFound bool
+	instanceNotFoundLock     sync.Mutex
+}
+
+// SubmissionStats describes the failures to submit state changes to the
+// backend, so that submissions that are stuck retrying are visible
+type SubmissionStats struct {
+	// Failures is the number of failed submissions since the agent started
+	Failures int64
+	// ConsecutiveFailures is the number of failed submissions since the last
+	// successful one
+	ConsecutiveFailures int64
+	// LastError is the error of the last failed submission
+	LastError string
+	// LastErrorTime is the time of the last failed submission
+	LastErrorTime time.Time
+	// LastErrorRetriable is set if the last failed submission is retried
+	LastErrorRetriable bool
 }
 
 // NewTaskHandler returns a pointer to TaskHandler
@@ -66,10 +102,31 @@ func NewTaskHandler(stateManager statemanager.Saver) *TaskHandler {
 		tasksToEvents:          make(map[string]*eventList),
 		submitSemaphore:        utils.NewSemaphore(concurrentEventCalls),
 		tasksToContainerStates: make(map[string][]api.ContainerStateChange),
+		tasksToPendingEvents:   make(map[string]*api.TaskStateChange),
 		stateSaver:             stateManager,
 	}
 }
 
+// SetBatchWindow sets the window over which the container events of a task are
+// coalesced into its pending task event before it is submitted. A task event
+// is held for the window, unless the task moves on to another status, in
+// which case it is submitted right away so that no task status is lost
+func (handler *TaskHandler) SetBatchWindow(window time.Duration) {
+	handler.taskHandlerLock.Lock()
+	defer handler.taskHandlerLock.Unlock()
+	handler.batchWindow = window
+}
+
+// SetInstanceNotFoundHandler sets the function that is invoked when a
+// submission fails because the container instance is no longer registered,
+// e.g. to register it again. If the function returns an error, it is invoked
+// again on the next such failure
+func (handler *TaskHandler) SetInstanceNotFoundHandler(instanceNotFoundHandler func() error) {
+	handler.instanceNotFoundLock.Lock()
+	defer handler.instanceNotFoundLock.Unlock()
+	handler.instanceNotFoundHandler = instanceNotFoundHandler
+}
+
 // AddStateChangeEvent queues up a state change for sending using the given client.
 func (handler *TaskHandler) AddStateChangeEvent(change statechange.Event, client api.ECSClient) error {
 	switch change.GetEventType() {
@@ -79,6 +136,9 @@ func (handler *TaskHandler) AddStateChangeEvent(change statechange.Event, client
 			return errors.New("eventhandler: unable to get task event from state change event")
 		}
 		handler.flushBatch(&event)
+		if handler.coalesceTaskEvent(event, client) {
+			return nil
+		}
 		handler.addEvent(newSendableTaskEvent(event), client)
 		return nil
 
@@ -87,6 +147,13 @@ func (handler *TaskHandler) AddStateChangeEvent(change statechange.Event, client
 		if !ok {
 			return errors.New("eventhandler: unable to get container event from state change event")
 		}
+		if event.Health != api.ContainerHealthUnknown && event.Container != nil &&
+			event.Container.GetSentStatus() >= event.Status {
+			// The backend only tracks the status of containers, so a change of
+			// the health of a container whose status was submitted is not
+			seelog.Debugf("TaskHandler, not batching health change of container: %s", event.String())
+			return nil
+		}
 		handler.batchContainerEvent(event)
 		return nil
 
@@ -95,11 +162,17 @@ func (handler *TaskHandler) AddStateChangeEvent(change statechange.Event, client
 	}
 }
 
-// batchContainerEvent collects container state change events for a given task arn
+// batchContainerEvent collects container state change events for a given task
+// arn. They are coalesced into the pending task event of the task, if any
 func (handler *TaskHandler) batchContainerEvent(event api.ContainerStateChange) {
 	handler.taskHandlerLock.Lock()
 	defer handler.taskHandlerLock.Unlock()
 
+	if pending, ok := handler.tasksToPendingEvents[event.TaskArn]; ok {
+		seelog.Infof("TaskHandler, coalescing container event: %s", event.String())
+		mergeContainerStateChanges(pending, []api.ContainerStateChange{event})
+		return
+	}
 	seelog.Infof("TaskHandler, batching container event: %s", event.String())
 	handler.tasksToContainerStates[event.TaskArn] = append(handler.tasksToContainerStates[event.TaskArn], event)
 }
@@ -114,11 +187,137 @@ func (handler *TaskHandler) flushBatch(event *api.TaskStateChange) {
 	delete(handler.tasksToContainerStates, event.TaskARN)
 }
 
+// coalesceTaskEvent holds the task event for the batch window. If the task
+// has a pending event with the same status, only the container changes of the
+// event are merged into it. If the status differs, the pending event is
+// submitted and the event takes its place until the window elapses. It
+// returns false if the event should be submitted right away instead, either
+// because batching is disabled or because it is an attachment event
+func (handler *TaskHandler) coalesceTaskEvent(event api.TaskStateChange, client api.ECSClient) bool {
+	handler.taskHandlerLock.Lock()
+	defer handler.taskHandlerLock.Unlock()
+
+	if handler.batchWindow <= 0 || event.Status == api.TaskStatusNone {
+		return false
+	}
+	pending, ok := handler.tasksToPendingEvents[event.TaskARN]
+	if !ok {
+		handler.tasksToPendingEvents[event.TaskARN] = &event
+		time.AfterFunc(handler.batchWindow, func() {
+			handler.submitPendingTaskEvent(event.TaskARN, client)
+		})
+		return true
+	}
+
+	if event.Status == pending.Status {
+		seelog.Infof("TaskHandler, coalescing task event: %s", event.String())
+		mergeContainerStateChanges(pending, event.Containers)
+		return true
+	}
+	// The pending event is queued before the later one, which is submitted
+	// once the window of the pending one elapses
+	handler.addEventUnsafe(newSendableTaskEvent(*pending), client)
+	handler.tasksToPendingEvents[event.TaskARN] = &event
+	return true
+}
+
+// mergeContainerStateChanges merges container changes into a pending task
+// event, so that only the latest change of each container is kept
+func mergeContainerStateChanges(pending *api.TaskStateChange, containerChanges []api.ContainerStateChange) {
+	for _, containerChange := range containerChanges {
+		merged := false
+		for i, pendingChange := range pending.Containers {
+			if pendingChange.ContainerName == containerChange.ContainerName {
+				pending.Containers[i] = containerChange
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			pending.Containers = append(pending.Containers, containerChange)
+		}
+	}
+}
+
+// submitPendingTaskEvent queues the pending event of a task for submission
+// once its batch window has elapsed
+func (handler *TaskHandler) submitPendingTaskEvent(taskARN string, client api.ECSClient) {
+	handler.taskHandlerLock.Lock()
+	pending, ok := handler.tasksToPendingEvents[taskARN]
+	delete(handler.tasksToPendingEvents, taskARN)
+	handler.taskHandlerLock.Unlock()
+
+	if ok {
+		handler.addEvent(newSendableTaskEvent(*pending), client)
+	}
+}
+
+// GetSubmissionStats returns the failures to submit state changes
+func (handler *TaskHandler) GetSubmissionStats() SubmissionStats {
+	handler.submissionStatsLock.RLock()
+	defer handler.submissionStatsLock.RUnlock()
+	return handler.submissionStats
+}
+
+// recordSubmissionFailure records a failed submission of a state change
+func (handler *TaskHandler) recordSubmissionFailure(err error) {
+	handler.submissionStatsLock.Lock()
+	defer handler.submissionStatsLock.Unlock()
+	handler.submissionStats.Failures++
+	handler.submissionStats.ConsecutiveFailures++
+	handler.submissionStats.LastError = err.Error()
+	handler.submissionStats.LastErrorTime = time.Now()
+	handler.submissionStats.LastErrorRetriable = true
+	if retriableErr, ok := err.(utils.Retriable); ok {
+		handler.submissionStats.LastErrorRetriable = retriableErr.Retry()
+	}
+	if api.IsInstanceNotFoundError(err) {
+		handler.handleInstanceNotFound(err)
+	}
+}
+
+// handleInstanceNotFound invokes the instance not found handler, unless there
+// is none or it is already running
+func (handler *TaskHandler) handleInstanceNotFound(err error) {
+	handler.instanceNotFoundLock.Lock()
+	defer handler.instanceNotFoundLock.Unlock()
+	if handler.instanceNotFoundHandler == nil || handler.handlingInstanceNotFound {
+		return
+	}
+	seelog.Warnf("TaskHandler, container instance is no longer registered: %v", err)
+	handler.handlingInstanceNotFound = true
+	instanceNotFoundHandler := handler.instanceNotFoundHandler
+	// The handler is invoked asynchronously since submissions hold the locks
+	// of their event lists while they are retried
+	go func() {
+		handlerErr := instanceNotFoundHandler()
+		if handlerErr != nil {
+			seelog.Errorf("TaskHandler, unable to handle the container instance no longer being registered: %v", handlerErr)
+		}
+		handler.instanceNotFoundLock.Lock()
+		defer handler.instanceNotFoundLock.Unlock()
+		handler.handlingInstanceNotFound = false
+	}()
+}
+
+// recordSubmissionSuccess records a successful submission of a state change
+func (handler *TaskHandler) recordSubmissionSuccess() {
+	handler.submissionStatsLock.Lock()
+	defer handler.submissionStatsLock.Unlock()
+	handler.submissionStats.ConsecutiveFailures = 0
+}
+
 // addEvent prepares a given event to be sent by adding it to the handler's appropriate
 // eventList and remove the entry in tasksToEvents map
 func (handler *TaskHandler) addEvent(change *sendableEvent, client api.ECSClient) {
 	handler.taskHandlerLock.Lock()
 	defer handler.taskHandlerLock.Unlock()
+	handler.addEventUnsafe(change, client)
+}
+
+// addEventUnsafe queues up a change for sending. It must be called with
+// taskHandlerLock held
+func (handler *TaskHandler) addEventUnsafe(change *sendableEvent, client api.ECSClient) {
 	seelog.Infof("TaskHandler, Adding event: %s", change.String())
 
 	taskEvents := handler.getTaskEventList(change)
@@ -197,10 +396,12 @@ func (handler *TaskHandler) SubmitTaskEvents(taskEvents *eventList, client api.E
 						event.containerChange.Container.SetSentStatus(event.containerChange.Status)
 					}
 					handler.stateSaver.Save()
+					handler.recordSubmissionSuccess()
 					seelog.Debugf("TaskHandler, Submitted container state change: %s", event.String())
 					backoff.Reset()
 					taskEvents.events.Remove(eventToSubmit)
 				} else {
+					handler.recordSubmissionFailure(err)
 					seelog.Errorf("TaskHandler, Unretriable error submitting container state change [%s]: %v",
 						event.String(), err)
 				}
@@ -214,10 +415,12 @@ func (handler *TaskHandler) SubmitTaskEvents(taskEvents *eventList, client api.E
 						event.taskChange.Task.SetSentStatus(event.taskChange.Status)
 					}
 					handler.stateSaver.Save()
+					handler.recordSubmissionSuccess()
 					seelog.Debugf("TaskHandler, Submitted task state change: %s", event.String())
 					backoff.Reset()
 					taskEvents.events.Remove(eventToSubmit)
 				} else {
+					handler.recordSubmissionFailure(err)
 					seelog.Errorf("TaskHandler, Unretriable error submitting task state change[%s]: %v",
 						event.String(), err)
 				}
@@ -232,10 +435,12 @@ func (handler *TaskHandler) SubmitTaskEvents(taskEvents *eventList, client api.E
 						event.taskChange.Attachment.StopAckTimer()
 					}
 					handler.stateSaver.Save()
+					handler.recordSubmissionSuccess()
 					seelog.Debugf("TaskHandler, Submitted task attachment state change: %s", event.String())
 					backoff.Reset()
 					taskEvents.events.Remove(eventToSubmit)
 				} else {
+					handler.recordSubmissionFailure(err)
 					seelog.Errorf("TaskHandler, Unretriable error submitting task attachment state change [%s]: %v",
 						event.String(), err)
 				}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/handlers"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit"
 	"github.com/aws/amazon-ecs-agent/agent/logger/audit/request"
@@ -68,8 +69,9 @@ type errorMessage struct {
 	httpErrorCode int
 }
 
-// ServeHTTP serves IAM Role Credentials for Tasks being managed by the agent.
-func ServeHTTP(credentialsManager credentials.Manager, containerInstanceArn string, cfg *config.Config) {
+// ServeHTTP serves IAM Role Credentials for Tasks being managed by the agent,
+// along with the metadata of the task of the calling container.
+func ServeHTTP(credentialsManager credentials.Manager, containerInstanceArn string, cfg *config.Config, taskEngine engine.TaskEngine) {
 	// Create and initialize the audit log
 	// TODO Use seelog's programmatic configuration instead of xml.
 	logger, err := log.LoggerFromConfigAsString(audit.AuditLoggerConfig(cfg))
@@ -81,7 +83,7 @@ func ServeHTTP(credentialsManager credentials.Manager, containerInstanceArn stri
 
 	auditLogger := audit.NewAuditLog(containerInstanceArn, cfg, logger)
 
-	server := setupServer(credentialsManager, auditLogger)
+	server := setupServer(credentialsManager, auditLogger, taskEngine.(*engine.DockerTaskEngine))
 
 	for {
 		utils.RetryWithBackoff(utils.NewSimpleBackoff(time.Second, time.Minute, 0.2, 2), func() error {
@@ -95,11 +97,13 @@ func ServeHTTP(credentialsManager credentials.Manager, containerInstanceArn stri
 	}
 }
 
-// setupServer starts the HTTP server for serving IAM Role Credentials for Tasks.
-func setupServer(credentialsManager credentials.Manager, auditLogger audit.AuditLogger) *http.Server {
+// setupServer starts the HTTP server for serving IAM Role Credentials and the
+// metadata of Tasks.
+func setupServer(credentialsManager credentials.Manager, auditLogger audit.AuditLogger, taskEngine handlers.DockerStateResolver) *http.Server {
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc(credentials.V1CredentialsPath, credentialsV1V2RequestHandler(credentialsManager, auditLogger, getV1CredentialsID, apiVersion1))
 	serverMux.HandleFunc(credentials.V2CredentialsPath+"/", credentialsV1V2RequestHandler(credentialsManager, auditLogger, getV2CredentialsID, apiVersion2))
+	serverMux.HandleFunc(handlers.TaskMetadataPath, handlers.TaskMetadataRequestHandlerMaker(taskEngine))
 
 	// Log all requests and then pass through to serverMux
 	loggingServeMux := http.NewServeMux()
@@ -25,6 +25,9 @@ import (
 
 	"github.com/aws/amazon-ecs-agent/agent/credentials"
 	mock_credentials "github.com/aws/amazon-ecs-agent/agent/credentials/mocks"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers"
+	mock_handlers "github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
 	mock_audit "github.com/aws/amazon-ecs-agent/agent/logger/audit/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -42,6 +45,25 @@ func TestInvalidPath(t *testing.T) {
 	testErrorResponsesFromServer(t, "/", nil)
 }
 
+// TestTaskMetadataServed tests that the task metadata API is served along
+// with the credentials API
+func TestTaskMetadataServed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	credentialsManager := mock_credentials.NewMockManager(ctrl)
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	stateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+	stateResolver.EXPECT().State().Return(dockerstate.NewTaskEngineState())
+	server := setupServer(credentialsManager, auditLog, stateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", handlers.TaskMetadataPath, nil)
+	req.RemoteAddr = "172.17.0.2:45678"
+	server.Handler.ServeHTTP(recorder, req)
+	// No task has the address of the request
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
 // TestCredentialsV1RequestWithNoArguments tests if HTTP status code 400 is returned when
 // query parameters are not specified for the credentials endpoint.
 func TestCredentialsV1RequestWithNoArguments(t *testing.T) {
@@ -168,13 +190,50 @@ func TestCredentialsV2RequestWhenCredentialsFound(t *testing.T) {
 	assert.Equal(t, secretAccessKey, credentials.SecretAccessKey, "Incorrect credentials received: secret access key")
 }
 
+// TestCredentialsV2RequestAfterRestore tests if credentials restored from the
+// saved state of the agent are served by the credentials endpoint
+func TestCredentialsV2RequestAfterRestore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	savedManager := credentials.NewManager()
+	err := savedManager.SetTaskCredentials(credentials.TaskIAMRoleCredentials{
+		ARN: "arn",
+		IAMRoleCredentials: credentials.IAMRoleCredentials{
+			CredentialsID:   credentialsID,
+			RoleArn:         roleArn,
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+		},
+	})
+	assert.NoError(t, err)
+	data, err := json.Marshal(savedManager)
+	assert.NoError(t, err)
+	credentialsManager := credentials.NewManager()
+	assert.NoError(t, json.Unmarshal(data, credentialsManager))
+
+	auditLog := mock_audit.NewMockAuditLogger(ctrl)
+	auditLog.EXPECT().Log(gomock.Any(), gomock.Any(), gomock.Any())
+	server := setupServer(credentialsManager, auditLog, nil)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", credentials.V2CredentialsPath+"/"+credentialsID, nil)
+	server.Handler.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	creds, err := parseResponseBody(recorder.Body)
+	assert.NoError(t, err, "Error retrieving credentials")
+	assert.Equal(t, roleArn, creds.RoleArn, "Incorrect credentials received: role ARN")
+	assert.Equal(t, accessKeyID, creds.AccessKeyID, "Incorrect credentials received: access key ID")
+	assert.Equal(t, secretAccessKey, creds.SecretAccessKey, "Incorrect credentials received: secret access key")
+}
+
 func testErrorResponsesFromServer(t *testing.T, path string, expectedErrorMessage *errorMessage) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	credentialsManager := mock_credentials.NewMockManager(ctrl)
 	auditLog := mock_audit.NewMockAuditLogger(ctrl)
-	server := setupServer(credentialsManager, auditLog)
+	server := setupServer(credentialsManager, auditLog, nil)
 
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", path, nil)
@@ -207,7 +266,7 @@ func getResponseForCredentialsRequest(t *testing.T, expectedStatus int,
 	defer ctrl.Finish()
 	credentialsManager := mock_credentials.NewMockManager(ctrl)
 	auditLog := mock_audit.NewMockAuditLogger(ctrl)
-	server := setupServer(credentialsManager, auditLog)
+	server := setupServer(credentialsManager, auditLog, nil)
 	recorder := httptest.NewRecorder()
 
 	creds, ok := getCredentials()
@@ -0,0 +1,118 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/cihub/seelog"
+)
+
+// dockerHealthCheckInterval is the interval at which the Docker daemon is
+// checked for the health endpoint
+const dockerHealthCheckInterval = 30 * time.Second
+
+// DockerVersioner gets the version of the Docker daemon, which is used to
+// check that the daemon is reachable
+type DockerVersioner interface {
+	Version() (string, error)
+}
+
+// dockerHealthChecker periodically checks that the Docker daemon responds
+// and caches the result, so that health requests never wait on the daemon
+type dockerHealthChecker struct {
+	versioner DockerVersioner
+	interval  time.Duration
+
+	lock        sync.RWMutex
+	checked     bool
+	healthy     bool
+	version     string
+	lastError   string
+	lastChecked time.Time
+}
+
+func newDockerHealthChecker(versioner DockerVersioner, interval time.Duration) *dockerHealthChecker {
+	return &dockerHealthChecker{
+		versioner: versioner,
+		interval:  interval,
+	}
+}
+
+// start checks the Docker daemon at every interval. It never returns
+func (checker *dockerHealthChecker) start() {
+	ticker := time.NewTicker(checker.interval)
+	defer ticker.Stop()
+	for {
+		checker.check()
+		<-ticker.C
+	}
+}
+
+// check gets the version of the Docker daemon and caches the result
+func (checker *dockerHealthChecker) check() {
+	version, err := checker.versioner.Version()
+	if err != nil {
+		seelog.Warnf("Docker daemon health check failed: %v", err)
+	}
+
+	checker.lock.Lock()
+	defer checker.lock.Unlock()
+
+	checker.checked = true
+	checker.healthy = err == nil
+	checker.version = version
+	checker.lastError = ""
+	if err != nil {
+		checker.lastError = err.Error()
+	}
+	checker.lastChecked = ttime.Now()
+}
+
+// status returns the result of the last check. The daemon is not considered
+// healthy until it has been checked
+func (checker *dockerHealthChecker) status() DockerHealthResponse {
+	checker.lock.RLock()
+	defer checker.lock.RUnlock()
+
+	response := DockerHealthResponse{
+		Healthy: checker.healthy,
+		Version: checker.version,
+		Error:   checker.lastError,
+	}
+	if checker.checked {
+		lastChecked := checker.lastChecked
+		response.LastChecked = &lastChecked
+	}
+	return response
+}
+
+func healthV1RequestHandlerMaker(checker *dockerHealthChecker) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dockerHealth := checker.status()
+		response := &HealthResponse{
+			Healthy: dockerHealth.Healthy,
+			Docker:  dockerHealth,
+		}
+		responseJSON, _ := json.Marshal(response)
+		if !response.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(responseJSON)
+	}
+}
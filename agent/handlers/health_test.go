@@ -0,0 +1,90 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func performHealthRequest(t *testing.T, checker *dockerHealthChecker) (int, HealthResponse) {
+	requestHandler := healthV1RequestHandlerMaker(checker)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/health", nil)
+	requestHandler(recorder, req)
+
+	var healthResponse HealthResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &healthResponse)
+	require.NoError(t, err)
+	return recorder.Code, healthResponse
+}
+
+func TestHealthDockerReachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	versioner := mock_handlers.NewMockDockerVersioner(ctrl)
+	versioner.EXPECT().Version().Return("17.03.1-ce", nil)
+	checker := newDockerHealthChecker(versioner, dockerHealthCheckInterval)
+	checker.check()
+
+	code, response := performHealthRequest(t, checker)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.Healthy)
+	assert.True(t, response.Docker.Healthy)
+	assert.Equal(t, "17.03.1-ce", response.Docker.Version)
+	assert.Empty(t, response.Docker.Error)
+	assert.NotNil(t, response.Docker.LastChecked)
+}
+
+func TestHealthDockerUnreachable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	versioner := mock_handlers.NewMockDockerVersioner(ctrl)
+	gomock.InOrder(
+		versioner.EXPECT().Version().Return("17.03.1-ce", nil),
+		versioner.EXPECT().Version().Return("", errors.New("cannot connect to the Docker daemon")),
+	)
+	checker := newDockerHealthChecker(versioner, dockerHealthCheckInterval)
+	checker.check()
+	checker.check()
+
+	code, response := performHealthRequest(t, checker)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, response.Healthy)
+	assert.False(t, response.Docker.Healthy)
+	assert.Equal(t, "cannot connect to the Docker daemon", response.Docker.Error)
+}
+
+func TestHealthDockerNotChecked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The health request should not call the daemon itself
+	checker := newDockerHealthChecker(mock_handlers.NewMockDockerVersioner(ctrl), dockerHealthCheckInterval)
+
+	code, response := performHealthRequest(t, checker)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, response.Docker.Healthy)
+	assert.Nil(t, response.Docker.LastChecked)
+}
@@ -12,7 +12,7 @@
 // permissions and limitations under the License.
 
 // Automatically generated by MockGen. DO NOT EDIT!
-// Source: github.com/aws/amazon-ecs-agent/agent/handlers (interfaces: DockerStateResolver)
+// Source: github.com/aws/amazon-ecs-agent/agent/handlers (interfaces: DockerStateResolver,DockerVersioner,PauseReporter)
 
 package mock_handlers
 
@@ -51,3 +51,66 @@ func (_m *MockDockerStateResolver) State() dockerstate.TaskEngineState {
 func (_mr *_MockDockerStateResolverRecorder) State() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "State")
 }
+
+// Mock of DockerVersioner interface
+type MockDockerVersioner struct {
+	ctrl     *gomock.Controller
+	recorder *_MockDockerVersionerRecorder
+}
+
+// Recorder for MockDockerVersioner (not exported)
+type _MockDockerVersionerRecorder struct {
+	mock *MockDockerVersioner
+}
+
+func NewMockDockerVersioner(ctrl *gomock.Controller) *MockDockerVersioner {
+	mock := &MockDockerVersioner{ctrl: ctrl}
+	mock.recorder = &_MockDockerVersionerRecorder{mock}
+	return mock
+}
+
+func (_m *MockDockerVersioner) EXPECT() *_MockDockerVersionerRecorder {
+	return _m.recorder
+}
+
+func (_m *MockDockerVersioner) Version() (string, error) {
+	ret := _m.ctrl.Call(_m, "Version")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockDockerVersionerRecorder) Version() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Version")
+}
+
+// Mock of PauseReporter interface
+type MockPauseReporter struct {
+	ctrl     *gomock.Controller
+	recorder *_MockPauseReporterRecorder
+}
+
+// Recorder for MockPauseReporter (not exported)
+type _MockPauseReporterRecorder struct {
+	mock *MockPauseReporter
+}
+
+func NewMockPauseReporter(ctrl *gomock.Controller) *MockPauseReporter {
+	mock := &MockPauseReporter{ctrl: ctrl}
+	mock.recorder = &_MockPauseReporterRecorder{mock}
+	return mock
+}
+
+func (_m *MockPauseReporter) EXPECT() *_MockPauseReporterRecorder {
+	return _m.recorder
+}
+
+func (_m *MockPauseReporter) Paused() bool {
+	ret := _m.ctrl.Call(_m, "Paused")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+func (_mr *_MockPauseReporterRecorder) Paused() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "Paused")
+}
@@ -0,0 +1,140 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+)
+
+// TaskMetadataPath is the path of the task metadata API, which is served
+// alongside the credentials API so that containers can reach it
+const TaskMetadataPath = "/v1/metadata"
+
+// TaskMetadataResponse is the metadata of the task of the container that
+// called the task metadata API
+type TaskMetadataResponse struct {
+	TaskARN       string
+	Family        string
+	Revision      string
+	DesiredStatus string
+	KnownStatus   string
+	// ContainerName is the name of the calling container. It is not set for
+	// tasks in the awsvpc network mode, as their containers share the address
+	// of the ENI of the task
+	ContainerName string `json:",omitempty"`
+	Containers    []ContainerMetadataResponse
+	ENI           *ENIResponse `json:",omitempty"`
+}
+
+// ContainerMetadataResponse is the metadata of a container in the response
+// of the task metadata API
+type ContainerMetadataResponse struct {
+	Name          string
+	DockerId      string `json:",omitempty"`
+	DockerName    string `json:",omitempty"`
+	Image         string
+	DesiredStatus string
+	KnownStatus   string
+}
+
+// TaskMetadataRequestHandlerMaker creates the handler of the task metadata
+// API. The task is looked up from the address the request comes from: the
+// address of the ENI of the task in the awsvpc network mode, or the address
+// docker assigned to the calling container otherwise
+func TaskMetadataRequestHandlerMaker(taskEngine DockerStateResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			log.Info("Unable to parse the address of a task metadata request", "addr", r.RemoteAddr, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		state := taskEngine.State()
+		task, container, found := taskByAddress(state, host)
+		if !found {
+			log.Info("No task found for a task metadata request", "addr", r.RemoteAddr)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		containerMap, _ := state.ContainerMapByArn(task.Arn)
+		responseJSON, _ := json.Marshal(newTaskMetadataResponse(task, container, containerMap))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(responseJSON)
+	}
+}
+
+// taskByAddress returns the task, and the container in bridge and other
+// docker network modes, that the address belongs to. Only running containers
+// are matched, as docker reuses the addresses of stopped containers
+func taskByAddress(state dockerstate.TaskEngineState, address string) (*api.Task, *api.Container, bool) {
+	for _, task := range state.AllTasks() {
+		eni := task.GetTaskENI()
+		if eni == nil {
+			continue
+		}
+		for _, ipv4 := range eni.IPV4Addresses {
+			if ipv4.Address == address {
+				return task, nil, true
+			}
+		}
+	}
+	for _, dockerID := range state.GetAllContainerIDs() {
+		dockerContainer, ok := state.ContainerByID(dockerID)
+		if !ok || dockerContainer.Container.GetKnownStatus() != api.ContainerRunning ||
+			dockerContainer.Container.GetIPv4Address() != address {
+			continue
+		}
+		if task, ok := state.TaskByID(dockerID); ok {
+			return task, dockerContainer.Container, true
+		}
+	}
+	return nil, nil, false
+}
+
+func newTaskMetadataResponse(task *api.Task, caller *api.Container, containerMap map[string]*api.DockerContainer) *TaskMetadataResponse {
+	resp := &TaskMetadataResponse{
+		TaskARN:       task.Arn,
+		Family:        task.Family,
+		Revision:      task.Version,
+		DesiredStatus: task.GetDesiredStatus().String(),
+		KnownStatus:   task.GetKnownStatus().String(),
+		Containers:    []ContainerMetadataResponse{},
+		ENI:           newENIResponse(task.GetTaskENI()),
+	}
+	if caller != nil {
+		resp.ContainerName = caller.Name
+	}
+	for _, container := range task.Containers {
+		if container.IsInternal() {
+			continue
+		}
+		containerResp := ContainerMetadataResponse{
+			Name:          container.Name,
+			Image:         container.Image,
+			DesiredStatus: container.GetDesiredStatus().String(),
+			KnownStatus:   container.GetKnownStatus().String(),
+		}
+		if dockerContainer, ok := containerMap[container.Name]; ok {
+			containerResp.DockerId = dockerContainer.DockerID
+			containerResp.DockerName = dockerContainer.DockerName
+		}
+		resp.Containers = append(resp.Containers, containerResp)
+	}
+	return resp
+}
@@ -0,0 +1,175 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTaskMetadata(t *testing.T, state dockerstate.TaskEngineState, remoteAddr string) *httptest.ResponseRecorder {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+	mockStateResolver.EXPECT().State().Return(state).AnyTimes()
+	requestHandler := TaskMetadataRequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", TaskMetadataPath, nil)
+	req.RemoteAddr = remoteAddr
+	requestHandler(recorder, req)
+	return recorder
+}
+
+func TestTaskMetadataBridgeMode(t *testing.T) {
+	web := &api.Container{
+		Name:                "web",
+		Image:               "nginx",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	web.SetIPv4Address("172.17.0.2")
+	db := &api.Container{
+		Name:                "db",
+		Image:               "mysql",
+		KnownStatusUnsafe:   api.ContainerRunning,
+		DesiredStatusUnsafe: api.ContainerRunning,
+	}
+	db.SetIPv4Address("172.17.0.3")
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{web, db},
+	}
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	recorder := getTaskMetadata(t, state, "172.17.0.3:45678")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var metadata TaskMetadataResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &metadata))
+	assert.Equal(t, TaskMetadataResponse{
+		TaskARN:       "task1",
+		Family:        "test",
+		Revision:      "1",
+		DesiredStatus: "RUNNING",
+		KnownStatus:   "RUNNING",
+		ContainerName: "db",
+		Containers: []ContainerMetadataResponse{
+			{
+				Name:          "web",
+				DockerId:      "dockerid-task1-web",
+				DockerName:    "dockername-task1-web",
+				Image:         "nginx",
+				DesiredStatus: "RUNNING",
+				KnownStatus:   "RUNNING",
+			},
+			{
+				Name:          "db",
+				DockerId:      "dockerid-task1-db",
+				DockerName:    "dockername-task1-db",
+				Image:         "mysql",
+				DesiredStatus: "RUNNING",
+				KnownStatus:   "RUNNING",
+			},
+		},
+	}, metadata)
+}
+
+func TestTaskMetadataAWSVPCMode(t *testing.T) {
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers: []*api.Container{
+			{
+				Name:                "web",
+				Image:               "nginx",
+				KnownStatusUnsafe:   api.ContainerRunning,
+				DesiredStatusUnsafe: api.ContainerRunning,
+			},
+		},
+	}
+	testTask.SetTaskENI(&api.ENI{
+		ID:            "eni-1",
+		MacAddress:    "06:aa:bb:cc:dd:ee",
+		IPV4Addresses: []*api.ENIIPV4Address{{Primary: true, Address: "10.0.0.5"}},
+	})
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	recorder := getTaskMetadata(t, state, "10.0.0.5:45678")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var metadata TaskMetadataResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &metadata))
+	assert.Equal(t, "task1", metadata.TaskARN)
+	assert.Empty(t, metadata.ContainerName, "containers of awsvpc tasks share the address of the ENI")
+	require.Len(t, metadata.Containers, 1)
+	assert.Equal(t, "web", metadata.Containers[0].Name)
+	require.NotNil(t, metadata.ENI)
+	assert.Equal(t, "eni-1", metadata.ENI.ID)
+	assert.Equal(t, []string{"10.0.0.5"}, metadata.ENI.IPv4Addresses)
+}
+
+func TestTaskMetadataUnknownAddress(t *testing.T) {
+	container := &api.Container{Name: "web"}
+	container.SetIPv4Address("172.17.0.2")
+	testTask := &api.Task{
+		Arn:        "task1",
+		Containers: []*api.Container{container},
+	}
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	recorder := getTaskMetadata(t, state, "172.17.0.9:45678")
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = getTaskMetadata(t, state, "not-an-address")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestTaskMetadataStoppedContainer(t *testing.T) {
+	container := &api.Container{
+		Name:              "web",
+		KnownStatusUnsafe: api.ContainerStopped,
+	}
+	container.SetIPv4Address("172.17.0.2")
+	testTask := &api.Task{
+		Arn:        "task1",
+		Containers: []*api.Container{container},
+	}
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	recorder := getTaskMetadata(t, state, "172.17.0.2:45678")
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "the address of a stopped container may be reused")
+}
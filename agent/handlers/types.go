@@ -13,12 +13,50 @@
 
 package handlers
 
-import "github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+import (
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
+	docker "github.com/fsouza/go-dockerclient"
+)
 
 type MetadataResponse struct {
-	Cluster              string
-	ContainerInstanceArn *string
-	Version              string
+	Cluster                 string
+	ContainerInstanceArn    *string
+	Version                 string
+	UnavailableCapabilities []UnavailableCapability `json:",omitempty"`
+}
+
+// UnavailableCapability describes a capability that was requested in the
+// config, but could not be advertised by the agent
+type UnavailableCapability struct {
+	Name   string
+	Reason string
+}
+
+// HealthResponse is the health of the agent, made of the results of its
+// sub-checks
+type HealthResponse struct {
+	Healthy bool
+	Docker  DockerHealthResponse
+}
+
+// DockerHealthResponse is the result of the last check of the Docker daemon
+type DockerHealthResponse struct {
+	Healthy     bool
+	Version     string     `json:",omitempty"`
+	Error       string     `json:",omitempty"`
+	LastChecked *time.Time `json:",omitempty"`
+}
+
+// AgentResponse is the uptime of the agent, the last time its state was
+// saved and whether its task engine is paused
+type AgentResponse struct {
+	StartedAt time.Time
+	Uptime    string
+	LastSaved *time.Time `json:",omitempty"`
+	Paused    bool
 }
 
 type TaskResponse struct {
@@ -28,18 +66,164 @@ type TaskResponse struct {
 	Family        string
 	Version       string
 	Containers    []ContainerResponse
+	ENI           *ENIResponse `json:",omitempty"`
+	// NetworkNamespacePath is the path, on the host, of the network
+	// namespace of awsvpc tasks, once it is set up
+	NetworkNamespacePath string `json:",omitempty"`
+	// WaitingOnPullLock lists the containers of the task whose images are
+	// waiting on the image pull lock to be pulled
+	WaitingOnPullLock []PullLockWaitResponse `json:",omitempty"`
+	// BlockedReason describes what keeps the containers of the task from
+	// progressing, such as the pause image not being pulled yet
+	BlockedReason string `json:",omitempty"`
+	// Timeline lists the significant events in the lifecycle of the task,
+	// from the oldest to the most recent
+	Timeline []TaskEventResponse `json:",omitempty"`
+}
+
+// TaskEventResponse is an event in the timeline of a task
+type TaskEventResponse struct {
+	Event         string
+	ContainerName string `json:",omitempty"`
+	Time          time.Time
+}
+
+// PullLockWaitResponse is a container waiting on the image pull lock, which
+// is held by other pulls or by image cleanup
+type PullLockWaitResponse struct {
+	ContainerName string
+	Image         string
+	Since         time.Time
+}
+
+// ENIResponse is the ENI attached to a task in the awsvpc network mode
+type ENIResponse struct {
+	ID            string
+	MacAddress    string
+	IPv4Addresses []string
+	IPv6Addresses []string `json:",omitempty"`
 }
 
 type TasksResponse struct {
 	Tasks []*TaskResponse
 }
 
-type ContainerResponse struct {
+// TaskCountsResponse is the number of tasks managed by the agent in each
+// status. Stopped tasks are counted until they are cleaned up
+type TaskCountsResponse struct {
+	Pending               int
+	Running               int
+	StoppedPendingCleanup int
+}
+
+// ResourcesResponse is the CPU and memory of the instance registered with
+// ECS, the part of it reserved by the tasks that are not stopped, and the part
+// still available for new tasks
+type ResourcesResponse struct {
+	Registered ResourceAmountsResponse
+	Reserved   ResourceAmountsResponse
+	Available  ResourceAmountsResponse
+}
+
+// ResourceAmountsResponse is an amount of CPU, in CPU units, and memory, in
+// MiB
+type ResourceAmountsResponse struct {
+	CPU    int64
+	Memory int64
+}
+
+// PendingENIAttachmentsResponse is the list of tasks that are waiting for
+// their ENI to be attached to the instance
+type PendingENIAttachmentsResponse struct {
+	Tasks []PendingENIAttachmentResponse
+}
+
+// PendingENIAttachmentResponse is a task waiting for its ENI to be attached,
+// along with the expected MAC address of the ENI and how long it has waited
+type PendingENIAttachmentResponse struct {
+	TaskArn       string
+	AttachmentArn string
+	MACAddress    string
+	ReceivedAt    *time.Time `json:",omitempty"`
+	Waiting       string     `json:",omitempty"`
+	ExpiresAt     time.Time
+}
+
+// ContainerConfigResponse is the docker config and host config the agent
+// computed for a container when creating it, with secrets redacted
+type ContainerConfigResponse struct {
 	DockerId   string
-	DockerName string
 	Name       string
+	Config     *docker.Config
+	HostConfig *docker.HostConfig
+}
+
+type ContainerResponse struct {
+	DockerId            string
+	DockerName          string
+	Name                string
+	ExposedPorts        []ExposedPortResponse     `json:",omitempty"`
+	ExitHistory         []ContainerExitResponse   `json:",omitempty"`
+	RestartCount        int                       `json:",omitempty"`
+	LastRestart         *ContainerRestartResponse `json:",omitempty"`
+	ImagePullSkipReason string                    `json:",omitempty"`
+	NetworkMode         string                    `json:",omitempty"`
+	StartAttempts       int                       `json:",omitempty"`
+	ImageSize           int64                     `json:",omitempty"`
+	StartedAt           *time.Time                `json:",omitempty"`
+	StopEscalation      *StopEscalationResponse   `json:",omitempty"`
+	Readiness           string                    `json:",omitempty"`
+	Essential           bool
+	// CausedTaskStop is true for the essential container whose exit caused
+	// its task to stop
+	CausedTaskStop bool `json:",omitempty"`
+}
+
+// StopEscalationResponse is the number of attempts made to gracefully stop a
+// container, and whether it had to be killed because it did not exit on the
+// stop signal
+type StopEscalationResponse struct {
+	GracefulStopAttempts int
+	Killed               bool
+}
+
+type ExposedPortResponse struct {
+	ContainerPort uint16
+	Protocol      string
+}
+
+type ContainerExitResponse struct {
+	ExitCode  *int `json:",omitempty"`
+	OOMKilled bool
+	Time      time.Time
+}
+
+type ContainerRestartResponse struct {
+	Reason   string
+	ExitCode *int `json:",omitempty"`
+	Time     time.Time
 }
 
 type DockerStateResolver interface {
 	State() dockerstate.TaskEngineState
 }
+
+// PauseReporter reports whether the task engine is paused, in which case new
+// tasks are held until it is resumed
+type PauseReporter interface {
+	Paused() bool
+}
+
+// SubmissionStatsReporter reports the failures to submit state changes to the
+// backend
+type SubmissionStatsReporter interface {
+	GetSubmissionStats() eventhandler.SubmissionStats
+}
+
+type StateChangeSubmissionsResponse struct {
+	Failures            int64
+	ConsecutiveFailures int64
+	LastError           string     `json:",omitempty"`
+	LastErrorTime       *time.Time `json:",omitempty"`
+	LastErrorRetriable  bool       `json:",omitempty"`
+}
@@ -16,16 +16,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
+	"github.com/aws/amazon-ecs-agent/agent/api/ecsclient"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/logger"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/version"
 )
@@ -36,6 +39,9 @@ const (
 	dockerIdQueryField = "dockerid"
 	taskArnQueryField  = "taskarn"
 	dockerShortIdLen   = 12
+	// netNSPathFormat is the format of the path of the network namespace of
+	// a process on the host
+	netNSPathFormat = "/proc/%s/ns/net"
 )
 
 type rootResponse struct {
@@ -50,11 +56,12 @@ func ValueFromRequest(r *http.Request, field string) (string, bool) {
 	return values.Get(field), exists
 }
 
-func metadataV1RequestHandlerMaker(containerInstanceArn *string, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+func metadataV1RequestHandlerMaker(containerInstanceArn *string, cfg *config.Config, unavailableCapabilities []UnavailableCapability) func(http.ResponseWriter, *http.Request) {
 	resp := &MetadataResponse{
-		Cluster:              cfg.Cluster,
-		ContainerInstanceArn: containerInstanceArn,
-		Version:              version.String(),
+		Cluster:                 cfg.Cluster,
+		ContainerInstanceArn:    containerInstanceArn,
+		Version:                 version.String(),
+		UnavailableCapabilities: unavailableCapabilities,
 	}
 	responseJSON, _ := json.Marshal(resp)
 
@@ -63,13 +70,105 @@ func metadataV1RequestHandlerMaker(containerInstanceArn *string, cfg *config.Con
 	}
 }
 
-func newTaskResponse(task *api.Task, containerMap map[string]*api.DockerContainer) *TaskResponse {
+func newContainerExitResponses(history []api.ContainerExit) []ContainerExitResponse {
+	if len(history) == 0 {
+		return nil
+	}
+	exits := make([]ContainerExitResponse, 0, len(history))
+	for _, exit := range history {
+		exits = append(exits, ContainerExitResponse{
+			ExitCode:  exit.ExitCode,
+			OOMKilled: exit.OOMKilled,
+			Time:      exit.Time,
+		})
+	}
+	return exits
+}
+
+func newContainerRestartResponse(container *api.Container) *ContainerRestartResponse {
+	restart, ok := container.GetLastRestart()
+	if !ok {
+		return nil
+	}
+	return &ContainerRestartResponse{
+		Reason:   string(restart.Reason),
+		ExitCode: restart.ExitCode,
+		Time:     restart.Time,
+	}
+}
+
+func newExposedPortResponses(exposedPorts []api.ExposedPort) []ExposedPortResponse {
+	if len(exposedPorts) == 0 {
+		return nil
+	}
+	ports := make([]ExposedPortResponse, 0, len(exposedPorts))
+	for _, exposedPort := range exposedPorts {
+		ports = append(ports, ExposedPortResponse{
+			ContainerPort: exposedPort.ContainerPort,
+			Protocol:      exposedPort.Protocol.String(),
+		})
+	}
+	return ports
+}
+
+// newStartedAtResponse returns the time docker reports the container was
+// started at, or nil if it was not started
+func newStartedAtResponse(container *api.Container) *time.Time {
+	startedAt := container.GetStartedAt()
+	if startedAt.IsZero() {
+		return nil
+	}
+	return &startedAt
+}
+
+func newStopEscalationResponse(container *api.Container) *StopEscalationResponse {
+	attempts := container.GetStopAttempts()
+	if attempts == 0 {
+		return nil
+	}
+	return &StopEscalationResponse{
+		GracefulStopAttempts: attempts,
+		Killed:               container.GetKilledOnStop(),
+	}
+}
+
+// newImageSizes returns the on-disk sizes of the images recorded by the image
+// manager, by image name
+func newImageSizes(state dockerstate.TaskEngineState) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, imageState := range state.AllImageStates() {
+		for _, name := range imageState.GetImageNames() {
+			sizes[name] = imageState.Image.Size
+		}
+	}
+	return sizes
+}
+
+func newTaskResponse(task *api.Task, containerMap map[string]*api.DockerContainer, imageSizes map[string]int64) *TaskResponse {
 	containers := []ContainerResponse{}
+	stoppedByContainer := task.GetStoppedByContainer()
 	for containerName, container := range containerMap {
 		if container.Container.IsInternal() {
 			continue
 		}
-		containers = append(containers, ContainerResponse{container.DockerID, container.DockerName, containerName})
+		containers = append(containers, ContainerResponse{
+			DockerId:            container.DockerID,
+			DockerName:          container.DockerName,
+			Name:                containerName,
+			ExposedPorts:        newExposedPortResponses(container.Container.ExposedPorts),
+			ExitHistory:         newContainerExitResponses(container.Container.GetExitHistory()),
+			RestartCount:        container.Container.GetRestartCount(),
+			LastRestart:         newContainerRestartResponse(container.Container),
+			ImagePullSkipReason: container.Container.GetImagePullSkipReason(),
+			NetworkMode:         task.ContainerNetworkMode(container.Container),
+			StartAttempts:       container.Container.GetStartAttempts(),
+			ImageSize:           imageSizes[container.Container.Image],
+			StartedAt:           newStartedAtResponse(container.Container),
+			StopEscalation:      newStopEscalationResponse(container.Container),
+			Readiness:           string(container.Container.GetReadinessStatus()),
+			Essential:           container.Container.Essential,
+			CausedTaskStop:      containerName == stoppedByContainer,
+		})
 	}
 
 	knownStatus := task.GetKnownStatus()
@@ -82,33 +181,123 @@ func newTaskResponse(task *api.Task, containerMap map[string]*api.DockerContaine
 	}
 
 	return &TaskResponse{
-		Arn:           task.Arn,
-		DesiredStatus: desiredStatus,
-		KnownStatus:   knownBackendStatus,
-		Family:        task.Family,
-		Version:       task.Version,
-		Containers:    containers,
+		Arn:                  task.Arn,
+		DesiredStatus:        desiredStatus,
+		KnownStatus:          knownBackendStatus,
+		Family:               task.Family,
+		Version:              task.Version,
+		Containers:           containers,
+		ENI:                  newENIResponse(task.GetTaskENI()),
+		WaitingOnPullLock:    newPullLockWaitResponses(task),
+		NetworkNamespacePath: newNetworkNamespacePath(task),
+		BlockedReason:        task.GetBlockedReason(),
+		Timeline:             newTaskEventResponses(task.GetTimeline()),
+	}
+}
+
+func newTaskEventResponses(timeline []api.TaskEvent) []TaskEventResponse {
+	if len(timeline) == 0 {
+		return nil
+	}
+	events := make([]TaskEventResponse, 0, len(timeline))
+	for _, event := range timeline {
+		events = append(events, TaskEventResponse{
+			Event:         string(event.Type),
+			ContainerName: event.ContainerName,
+			Time:          event.Time,
+		})
 	}
+	return events
+}
+
+// newNetworkNamespacePath returns the path of the network namespace of the
+// task, derived from the pid of its pause container. It is only reported by
+// introspection, which is only reachable from the instance
+func newNetworkNamespacePath(task *api.Task) string {
+	if task.GetTaskENI() == nil {
+		return ""
+	}
+	pid := task.GetPauseContainerPID()
+	if pid == "" {
+		return ""
+	}
+	return fmt.Sprintf(netNSPathFormat, pid)
+}
+
+// newPullLockWaitResponses returns the containers of the task that are waiting
+// on the image pull lock. Such containers are not created yet, so they are
+// read from the task rather than from its container map
+func newPullLockWaitResponses(task *api.Task) []PullLockWaitResponse {
+	var waits []PullLockWaitResponse
+	for _, container := range task.Containers {
+		if container.IsInternal() {
+			continue
+		}
+		waitStart := container.GetPullLockWaitStart()
+		if waitStart.IsZero() {
+			continue
+		}
+		waits = append(waits, PullLockWaitResponse{
+			ContainerName: container.Name,
+			Image:         container.Image,
+			Since:         waitStart,
+		})
+	}
+	return waits
+}
+
+func newENIResponse(eni *api.ENI) *ENIResponse {
+	if eni == nil {
+		return nil
+	}
+	resp := &ENIResponse{
+		ID:         eni.ID,
+		MacAddress: eni.MacAddress,
+	}
+	for _, ipv4 := range eni.IPV4Addresses {
+		resp.IPv4Addresses = append(resp.IPv4Addresses, ipv4.Address)
+	}
+	for _, ipv6 := range eni.IPV6Addresses {
+		resp.IPv6Addresses = append(resp.IPv6Addresses, ipv6.Address)
+	}
+	return resp
 }
 
 func newTasksResponse(state dockerstate.TaskEngineState) *TasksResponse {
 	allTasks := state.AllTasks()
 	taskResponses := make([]*TaskResponse, len(allTasks))
+	imageSizes := newImageSizes(state)
 	for ndx, task := range allTasks {
 		containerMap, _ := state.ContainerMapByArn(task.Arn)
-		taskResponses[ndx] = newTaskResponse(task, containerMap)
+		taskResponses[ndx] = newTaskResponse(task, containerMap, imageSizes)
 	}
 
 	return &TasksResponse{Tasks: taskResponses}
 }
 
+func newTaskCountsResponse(state dockerstate.TaskEngineState) *TaskCountsResponse {
+	counts := &TaskCountsResponse{}
+	for _, task := range state.AllTasks() {
+		knownStatus := task.GetKnownStatus()
+		switch knownStatus.BackendStatus() {
+		case "RUNNING":
+			counts.Running++
+		case "STOPPED":
+			counts.StoppedPendingCleanup++
+		default:
+			counts.Pending++
+		}
+	}
+	return counts
+}
+
 // Creates JSON response and sets the http status code for the task queried.
 func createTaskJSONResponse(task *api.Task, found bool, resourceId string, state dockerstate.TaskEngineState) ([]byte, int) {
 	var responseJSON []byte
 	status := http.StatusOK
 	if found {
 		containerMap, _ := state.ContainerMapByArn(task.Arn)
-		responseJSON, _ = json.Marshal(newTaskResponse(task, containerMap))
+		responseJSON, _ = json.Marshal(newTaskResponse(task, containerMap, newImageSizes(state)))
 	} else {
 		log.Warn("Could not find requested resource: " + resourceId)
 		responseJSON, _ = json.Marshal(&TaskResponse{})
@@ -169,6 +358,176 @@ func tasksV1RequestHandlerMaker(taskEngine DockerStateResolver) func(http.Respon
 	}
 }
 
+// Creates response for the 'v1/tasks/counts' API. Counts the tasks in the
+// state of the task engine by their known status.
+func taskCountsV1RequestHandlerMaker(taskEngine DockerStateResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, _ := json.Marshal(newTaskCountsResponse(taskEngine.State()))
+		w.Write(responseJSON)
+	}
+}
+
+// registeredResources returns the CPU, in CPU units, and the memory, in MiB,
+// the instance registers with
+var registeredResources = func(cfg *config.Config) (int64, int64) {
+	cpu, mem := ecsclient.GetCPUAndMemory()
+	return cpu, mem - int64(cfg.ReservedMemory)
+}
+
+// newResourcesResponse adds up the CPU and memory reserved by the tasks in the
+// state of the task engine that are not stopped yet
+func newResourcesResponse(state dockerstate.TaskEngineState, registeredCPU, registeredMemory int64) *ResourcesResponse {
+	resp := &ResourcesResponse{
+		Registered: ResourceAmountsResponse{CPU: registeredCPU, Memory: registeredMemory},
+	}
+	for _, task := range state.AllTasks() {
+		if task.GetKnownStatus().Terminal() {
+			continue
+		}
+		resp.Reserved.CPU += task.ReservedCPU()
+		resp.Reserved.Memory += task.ReservedMemory()
+	}
+	resp.Available = ResourceAmountsResponse{
+		CPU:    registeredCPU - resp.Reserved.CPU,
+		Memory: registeredMemory - resp.Reserved.Memory,
+	}
+	return resp
+}
+
+// Creates response for the 'v1/resources' API. Reports the CPU and memory
+// registered for the instance and how much of it is reserved by tasks.
+func resourcesV1RequestHandlerMaker(taskEngine DockerStateResolver, cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	registeredCPU, registeredMemory := registeredResources(cfg)
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, _ := json.Marshal(newResourcesResponse(taskEngine.State(), registeredCPU, registeredMemory))
+		w.Write(responseJSON)
+	}
+}
+
+func newPendingENIAttachmentsResponse(state dockerstate.TaskEngineState) *PendingENIAttachmentsResponse {
+	resp := &PendingENIAttachmentsResponse{Tasks: []PendingENIAttachmentResponse{}}
+	for _, eniAttachment := range state.AllENIAttachments() {
+		if eniAttachment.IsSent() {
+			continue
+		}
+		pending := PendingENIAttachmentResponse{
+			TaskArn:       eniAttachment.TaskARN,
+			AttachmentArn: eniAttachment.AttachmentARN,
+			MACAddress:    eniAttachment.MACAddress,
+			ExpiresAt:     eniAttachment.ExpiresAt,
+		}
+		// Attachments restored from older versions of the state do not
+		// record when they were received
+		if receivedAt := eniAttachment.ReceivedAt; !receivedAt.IsZero() {
+			pending.ReceivedAt = &receivedAt
+			pending.Waiting = time.Since(receivedAt).String()
+		}
+		resp.Tasks = append(resp.Tasks, pending)
+	}
+	return resp
+}
+
+// Creates response for the 'v1/tasks/pending-eni' API. Lists the tasks whose
+// ENI attachment has not been reported as attached yet.
+func pendingENIAttachmentsV1RequestHandlerMaker(taskEngine DockerStateResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON, _ := json.Marshal(newPendingENIAttachmentsResponse(taskEngine.State()))
+		w.Write(responseJSON)
+	}
+}
+
+// Creates response for the 'v1/containers/config' API. Returns the effective
+// docker config and host config of the container specified by 'dockerid',
+// with the values of environment variables and log driver options redacted.
+func containerConfigV1RequestHandlerMaker(taskEngine DockerStateResolver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var responseJSON []byte
+		dockerId, dockerIdExists := ValueFromRequest(r, dockerIdQueryField)
+		if !dockerIdExists {
+			log.Info("Request doesn't contain ", dockerIdQueryField)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(responseJSON)
+			return
+		}
+		dockerContainer, found := taskEngine.State().ContainerByID(dockerId)
+		if !found {
+			log.Warn("Could not find requested container: " + dockerId)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(responseJSON)
+			return
+		}
+		config, hostConfig, ok := dockerContainer.Container.GetEffectiveConfig()
+		if !ok {
+			log.Info("Effective config of requested container is not known: " + dockerId)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(responseJSON)
+			return
+		}
+		responseJSON, _ = json.Marshal(&ContainerConfigResponse{
+			DockerId:   dockerContainer.DockerID,
+			Name:       dockerContainer.Container.Name,
+			Config:     config,
+			HostConfig: hostConfig,
+		})
+		w.Write(responseJSON)
+	}
+}
+
+// Creates response for the 'v1/agent' API. Reports when the agent started,
+// its uptime, when its state was last saved and whether its task engine is
+// paused.
+func agentV1RequestHandlerMaker(startTime time.Time, stateManager statemanager.StateManager, pauseReporter PauseReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := &AgentResponse{
+			StartedAt: startTime,
+			Uptime:    time.Since(startTime).String(),
+			Paused:    pauseReporter.Paused(),
+		}
+		if lastSaved := stateManager.LastSaved(); !lastSaved.IsZero() {
+			resp.LastSaved = &lastSaved
+		}
+		responseJSON, _ := json.Marshal(resp)
+		w.Write(responseJSON)
+	}
+}
+
+// Creates response for the 'v1/config' API. Reports the configuration the
+// agent is running with, with sensitive values masked. The configuration is
+// snapshotted when the handler is made, at startup, so that serving it never
+// reads the configuration while the agent runs.
+func configV1RequestHandlerMaker(cfg *config.Config) func(http.ResponseWriter, *http.Request) {
+	responseJSON, err := json.Marshal(cfg.Redacted())
+	if err != nil {
+		log.Error("Unable to marshal the agent config", "err", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(responseJSON)
+	}
+}
+
+// Creates response for the 'v1/statechanges' API. Reports the
+// failures to submit state changes to the backend.
+func stateChangeSubmissionsV1RequestHandlerMaker(reporter SubmissionStatsReporter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := reporter.GetSubmissionStats()
+		resp := &StateChangeSubmissionsResponse{
+			Failures:            stats.Failures,
+			ConsecutiveFailures: stats.ConsecutiveFailures,
+			LastError:           stats.LastError,
+			LastErrorRetriable:  stats.LastErrorRetriable,
+		}
+		if !stats.LastErrorTime.IsZero() {
+			resp.LastErrorTime = &stats.LastErrorTime
+		}
+		responseJSON, _ := json.Marshal(resp)
+		w.Write(responseJSON)
+	}
+}
+
 var licenseProvider = utils.NewLicenseProvider()
 
 func licenseHandler(w http.ResponseWriter, h *http.Request) {
@@ -180,11 +539,19 @@ func licenseHandler(w http.ResponseWriter, h *http.Request) {
 	}
 }
 
-func setupServer(containerInstanceArn *string, taskEngine DockerStateResolver, cfg *config.Config) *http.Server {
+func setupServer(containerInstanceArn *string, taskEngine DockerStateResolver, pauseReporter PauseReporter, healthChecker *dockerHealthChecker, stateManager statemanager.StateManager, submissionStats SubmissionStatsReporter, startTime time.Time, cfg *config.Config, unavailableCapabilities []UnavailableCapability) *http.Server {
 	serverFunctions := map[string]func(w http.ResponseWriter, r *http.Request){
-		"/v1/metadata": metadataV1RequestHandlerMaker(containerInstanceArn, cfg),
-		"/v1/tasks":    tasksV1RequestHandlerMaker(taskEngine),
-		"/license":     licenseHandler,
+		"/v1/metadata":          metadataV1RequestHandlerMaker(containerInstanceArn, cfg, unavailableCapabilities),
+		"/v1/tasks":             tasksV1RequestHandlerMaker(taskEngine),
+		"/v1/tasks/counts":      taskCountsV1RequestHandlerMaker(taskEngine),
+		"/v1/tasks/pending-eni": pendingENIAttachmentsV1RequestHandlerMaker(taskEngine),
+		"/v1/resources":         resourcesV1RequestHandlerMaker(taskEngine, cfg),
+		"/v1/health":            healthV1RequestHandlerMaker(healthChecker),
+		"/v1/containers/config": containerConfigV1RequestHandlerMaker(taskEngine),
+		"/v1/agent":             agentV1RequestHandlerMaker(startTime, stateManager, pauseReporter),
+		"/v1/config":            configV1RequestHandlerMaker(cfg),
+		"/v1/statechanges":      stateChangeSubmissionsV1RequestHandlerMaker(submissionStats),
+		"/license":              licenseHandler,
 	}
 
 	paths := make([]string, 0, len(serverFunctions))
@@ -220,13 +587,19 @@ func setupServer(containerInstanceArn *string, taskEngine DockerStateResolver, c
 }
 
 // ServeHttp serves information about this agent / containerInstance and tasks
-// running on it.
-func ServeHttp(containerInstanceArn *string, taskEngine engine.TaskEngine, cfg *config.Config) {
+// running on it. The capabilities that were requested in the config but could
+// not be advertised at registration are reported as part of the metadata.
+// The start time of the agent and the last save of the state manager are
+// reported as part of the agent information.
+func ServeHttp(containerInstanceArn *string, taskEngine engine.TaskEngine, stateManager statemanager.StateManager, submissionStats SubmissionStatsReporter, startTime time.Time, cfg *config.Config, unavailableCapabilities []UnavailableCapability) {
 	// Is this the right level to type assert, assuming we'd abstract multiple taskengines here?
 	// Revisit if we ever add another type..
 	dockerTaskEngine := taskEngine.(*engine.DockerTaskEngine)
 
-	server := setupServer(containerInstanceArn, dockerTaskEngine, cfg)
+	healthChecker := newDockerHealthChecker(dockerTaskEngine, dockerHealthCheckInterval)
+	go healthChecker.start()
+
+	server := setupServer(containerInstanceArn, dockerTaskEngine, dockerTaskEngine, healthChecker, stateManager, submissionStats, startTime, cfg, unavailableCapabilities)
 	for {
 		once := sync.Once{}
 		utils.RetryWithBackoff(utils.NewSimpleBackoff(time.Second, time.Minute, 0.2, 2), func() error {
@@ -16,18 +16,26 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	"github.com/aws/amazon-ecs-agent/agent/config"
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
+	"github.com/aws/amazon-ecs-agent/agent/engine/image"
+	"github.com/aws/amazon-ecs-agent/agent/eventhandler"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/mocks"
 	"github.com/aws/amazon-ecs-agent/agent/handlers/mocks/http"
+	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/aws/amazon-ecs-agent/agent/utils"
 	"github.com/aws/amazon-ecs-agent/agent/utils/mocks"
+	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -37,7 +45,7 @@ const testContainerInstanceArn = "test_container_instance_arn"
 const testClusterArn = "test_cluster_arn"
 
 func TestMetadataHandler(t *testing.T) {
-	metadataHandler := metadataV1RequestHandlerMaker(utils.Strptr(testContainerInstanceArn), &config.Config{Cluster: testClusterArn})
+	metadataHandler := metadataV1RequestHandlerMaker(utils.Strptr(testContainerInstanceArn), &config.Config{Cluster: testClusterArn}, nil)
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", "http://localhost:"+strconv.Itoa(config.AgentIntrospectionPort), nil)
@@ -54,6 +62,26 @@ func TestMetadataHandler(t *testing.T) {
 	}
 }
 
+func TestMetadataHandlerUnavailableCapabilities(t *testing.T) {
+	unavailableCapabilities := []UnavailableCapability{
+		{
+			Name:   "ecs.capability.task-eni",
+			Reason: "cni plugins not found",
+		},
+	}
+	metadataHandler := metadataV1RequestHandlerMaker(utils.Strptr(testContainerInstanceArn),
+		&config.Config{Cluster: testClusterArn}, unavailableCapabilities)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://localhost:"+strconv.Itoa(config.AgentIntrospectionPort), nil)
+	metadataHandler(w, req)
+
+	var resp MetadataResponse
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+	assert.Equal(t, unavailableCapabilities, resp.UnavailableCapabilities)
+}
+
 func TestListMultipleTasks(t *testing.T) {
 	recorder := performMockRequest(t, "/v1/tasks")
 
@@ -184,6 +212,804 @@ func TestBackendMismatchMapping(t *testing.T) {
 	}
 }
 
+func TestGetTaskContainerExitHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	container := &api.Container{
+		Name: "c1",
+	}
+	exitCode := 1
+	container.RecordExit(api.ContainerExit{ExitCode: &exitCode})
+	oomExitCode := 137
+	container.RecordExit(api.ContainerExit{ExitCode: &oomExitCode, OOMKilled: true})
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{container},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	exitHistory := taskResponse.Containers[0].ExitHistory
+	require.Len(t, exitHistory, 2)
+	assert.Equal(t, exitCode, *exitHistory[0].ExitCode)
+	assert.False(t, exitHistory[0].OOMKilled)
+	assert.Equal(t, oomExitCode, *exitHistory[1].ExitCode)
+	assert.True(t, exitHistory[1].OOMKilled)
+}
+
+func TestGetTaskContainerStartAttempts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	container := &api.Container{
+		Name: "c1",
+	}
+	container.SetStartAttempts(3)
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{container},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	assert.Equal(t, 3, taskResponse.Containers[0].StartAttempts)
+}
+
+func TestGetTaskContainerStartedAt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	startedAt := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	startedContainer := &api.Container{Name: "started"}
+	startedContainer.SetStartedAt(startedAt)
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{startedContainer},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	require.NotNil(t, taskResponse.Containers[0].StartedAt)
+	assert.True(t, startedAt.Equal(*taskResponse.Containers[0].StartedAt))
+}
+
+func TestGetTaskWaitingOnPullLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	waitStart := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	waitingContainer := &api.Container{Name: "waiting", Image: "busybox:latest"}
+	waitingContainer.SetPullLockWaitStart(waitStart)
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskStatusNone,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{waitingContainer, {Name: "pulled"}},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	state.AddTask(testTask)
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	assert.Empty(t, taskResponse.Containers)
+	require.Len(t, taskResponse.WaitingOnPullLock, 1)
+	assert.Equal(t, "waiting", taskResponse.WaitingOnPullLock[0].ContainerName)
+	assert.Equal(t, "busybox:latest", taskResponse.WaitingOnPullLock[0].Image)
+	assert.True(t, waitStart.Equal(taskResponse.WaitingOnPullLock[0].Since))
+
+	waitingContainer.SetPullLockWaitStart(time.Time{})
+	mockStateResolver.EXPECT().State().Return(state)
+	recorder = httptest.NewRecorder()
+	requestHandler(recorder, req)
+	assert.NotContains(t, recorder.Body.String(), "WaitingOnPullLock")
+}
+
+func TestGetTaskBlockedReason(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskStatusNone,
+		Family:              "test",
+		Version:             "1",
+	}
+	testTask.SetBlockedReason(api.TaskBlockedOnPauseImage)
+
+	state := dockerstate.NewTaskEngineState()
+	state.AddTask(testTask)
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	assert.Equal(t, "waiting for pause image", taskResponse.BlockedReason)
+
+	testTask.SetBlockedReason("")
+	mockStateResolver.EXPECT().State().Return(state)
+	recorder = httptest.NewRecorder()
+	requestHandler(recorder, req)
+	assert.NotContains(t, recorder.Body.String(), "BlockedReason")
+}
+
+func TestGetTaskTimeline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	added := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+	}
+	testTask.RecordEvent(api.TaskEvent{Type: api.TaskEventAdded, Time: added})
+	testTask.RecordEvent(api.TaskEvent{Type: api.TaskEventStarted, ContainerName: "c1", Time: added.Add(time.Second)})
+	testTask.RecordEvent(api.TaskEvent{Type: api.TaskEventSteadyState, Time: added.Add(2 * time.Second)})
+
+	state := dockerstate.NewTaskEngineState()
+	state.AddTask(testTask)
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Timeline, 3)
+	assert.Equal(t, "Added", taskResponse.Timeline[0].Event)
+	assert.True(t, added.Equal(taskResponse.Timeline[0].Time))
+	assert.Equal(t, "Started", taskResponse.Timeline[1].Event)
+	assert.Equal(t, "c1", taskResponse.Timeline[1].ContainerName)
+	assert.Equal(t, "SteadyState", taskResponse.Timeline[2].Event)
+}
+
+func TestGetTaskContainerStopEscalation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	killedContainer := &api.Container{Name: "killed"}
+	killedContainer.IncrementStopAttempts()
+	killedContainer.IncrementStopAttempts()
+	killedContainer.SetKilledOnStop()
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskStopped,
+		KnownStatusUnsafe:   api.TaskStopped,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{killedContainer},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	require.NotNil(t, taskResponse.Containers[0].StopEscalation)
+	assert.Equal(t, 2, taskResponse.Containers[0].StopEscalation.GracefulStopAttempts)
+	assert.True(t, taskResponse.Containers[0].StopEscalation.Killed)
+}
+
+func TestGetTaskContainerReadiness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	readyContainer := &api.Container{
+		Name:           "ready",
+		ReadinessProbe: &api.ReadinessProbe{Type: api.ReadinessProbeTCP, Port: 80},
+	}
+	readyContainer.SetReadinessStatus(api.ContainerReady)
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{readyContainer},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	assert.Equal(t, "READY", taskResponse.Containers[0].Readiness)
+}
+
+func TestGetTaskContainerEssential(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	essentialContainer := &api.Container{
+		Name:              "essential",
+		Essential:         true,
+		KnownStatusUnsafe: api.ContainerStopped,
+	}
+	sidecarContainer := &api.Container{
+		Name:              "sidecar",
+		KnownStatusUnsafe: api.ContainerRunning,
+	}
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{essentialContainer, sidecarContainer},
+	}
+	// The exit of the essential container stops the task
+	testTask.UpdateDesiredStatus()
+	require.Equal(t, api.TaskStopped, testTask.GetDesiredStatus())
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 2)
+	containers := make(map[string]ContainerResponse)
+	for _, container := range taskResponse.Containers {
+		containers[container.Name] = container
+	}
+	assert.True(t, containers["essential"].Essential)
+	assert.True(t, containers["essential"].CausedTaskStop)
+	assert.False(t, containers["sidecar"].Essential)
+	assert.False(t, containers["sidecar"].CausedTaskStop)
+}
+
+func TestGetTaskNetworkNamespacePath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	awsvpcTask := &api.Task{
+		Arn:                 "awsvpcTask",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{{Name: "c1"}},
+	}
+	awsvpcTask.SetTaskENI(&api.ENI{ID: "eniID"})
+	awsvpcTask.SetPauseContainerPID("1234")
+	bridgeTask := &api.Task{
+		Arn:                 "bridgeTask",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{{Name: "c1"}},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{awsvpcTask, bridgeTask})
+
+	mockStateResolver.EXPECT().State().Return(state).Times(2)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	for arn, expectedPath := range map[string]string{
+		"awsvpcTask": "/proc/1234/ns/net",
+		"bridgeTask": "",
+	} {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/tasks?taskarn="+arn, nil)
+		requestHandler(recorder, req)
+
+		var taskResponse TaskResponse
+		err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+		require.NoError(t, err)
+		assert.Equal(t, expectedPath, taskResponse.NetworkNamespacePath, arn)
+	}
+}
+
+func TestGetTaskContainerImageSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	container := &api.Container{
+		Name:  "c1",
+		Image: "busybox:latest",
+	}
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{container},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+	state.AddImageState(&image.ImageState{
+		Image: &image.Image{
+			ImageID: "sha256:busybox",
+			Names:   []string{"busybox:latest"},
+			Size:    1234567,
+		},
+	})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	assert.Equal(t, int64(1234567), taskResponse.Containers[0].ImageSize)
+}
+
+func TestGetTaskContainerLastRestart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	exitCode := 1
+	exitContainer := &api.Container{Name: "exit"}
+	exitContainer.RecordRestart(api.NewContainerExitRestart(api.ContainerExit{ExitCode: &exitCode}, time.Now()))
+	oomExitCode := 137
+	oomContainer := &api.Container{Name: "oom"}
+	oomContainer.RecordRestart(api.NewContainerExitRestart(api.ContainerExit{ExitCode: &oomExitCode, OOMKilled: true}, time.Now()))
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers:          []*api.Container{exitContainer, oomContainer, {Name: "never-restarted"}},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 3)
+	restarts := make(map[string]*ContainerRestartResponse)
+	for _, container := range taskResponse.Containers {
+		restarts[container.Name] = container.LastRestart
+	}
+	require.NotNil(t, restarts["exit"])
+	assert.Equal(t, "ExitCode", restarts["exit"].Reason)
+	assert.Equal(t, exitCode, *restarts["exit"].ExitCode)
+	require.NotNil(t, restarts["oom"])
+	assert.Equal(t, "OutOfMemory", restarts["oom"].Reason)
+	assert.Equal(t, oomExitCode, *restarts["oom"].ExitCode)
+	assert.Nil(t, restarts["never-restarted"])
+}
+
+func TestGetTaskContainerExposedPorts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	testTask := &api.Task{
+		Arn:                 "task1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Family:              "test",
+		Version:             "1",
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				ExposedPorts: []api.ExposedPort{
+					{ContainerPort: 8080, Protocol: api.TransportProtocolTCP},
+					{ContainerPort: 53, Protocol: api.TransportProtocolUDP},
+				},
+			},
+		},
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{testTask})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks?taskarn=task1", nil)
+	requestHandler(recorder, req)
+
+	var taskResponse TaskResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &taskResponse)
+	require.NoError(t, err)
+	require.Len(t, taskResponse.Containers, 1)
+	assert.Equal(t, []ExposedPortResponse{
+		{ContainerPort: 8080, Protocol: "tcp"},
+		{ContainerPort: 53, Protocol: "udp"},
+	}, taskResponse.Containers[0].ExposedPorts)
+}
+
+func TestTaskResponseNetworkMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	bridgeTask := &api.Task{
+		Arn:                 "bridge",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Containers: []*api.Container{
+			{
+				Name: "c1",
+				DockerConfig: api.DockerConfig{
+					HostConfig: utils.Strptr(`{"NetworkMode":"bridge"}`),
+				},
+			},
+		},
+	}
+	awsvpcTask := &api.Task{
+		Arn:                 "awsvpc",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+		Containers:          []*api.Container{{Name: "c1"}},
+	}
+	awsvpcTask.SetTaskENI(&api.ENI{
+		ID:            "eni-12345",
+		MacAddress:    "00:0a:95:9d:68:16",
+		IPV4Addresses: []*api.ENIIPV4Address{{Primary: true, Address: "10.0.0.2"}},
+		IPV6Addresses: []*api.ENIIPV6Address{{Address: "2001:db8::2"}},
+	})
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, []*api.Task{bridgeTask, awsvpcTask})
+
+	mockStateResolver.EXPECT().State().Return(state).Times(2)
+	requestHandler := tasksV1RequestHandlerMaker(mockStateResolver)
+	getTaskResponse := func(taskArn string) TaskResponse {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/tasks?taskarn="+taskArn, nil)
+		requestHandler(recorder, req)
+		var taskResponse TaskResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &taskResponse))
+		require.Len(t, taskResponse.Containers, 1)
+		return taskResponse
+	}
+
+	taskResponse := getTaskResponse("bridge")
+	assert.Equal(t, "bridge", taskResponse.Containers[0].NetworkMode)
+	assert.Nil(t, taskResponse.ENI, "bridge tasks have no eni")
+
+	taskResponse = getTaskResponse("awsvpc")
+	assert.Equal(t, "awsvpc", taskResponse.Containers[0].NetworkMode)
+	assert.Equal(t, &ENIResponse{
+		ID:            "eni-12345",
+		MacAddress:    "00:0a:95:9d:68:16",
+		IPv4Addresses: []string{"10.0.0.2"},
+		IPv6Addresses: []string{"2001:db8::2"},
+	}, taskResponse.ENI)
+}
+
+func TestTaskCounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	newTask := func(arn string, desiredStatus, knownStatus api.TaskStatus) *api.Task {
+		return &api.Task{
+			Arn:                 arn,
+			DesiredStatusUnsafe: desiredStatus,
+			KnownStatusUnsafe:   knownStatus,
+			Family:              "test",
+			Version:             "1",
+			Containers:          []*api.Container{{Name: "c1"}},
+		}
+	}
+	tasks := []*api.Task{
+		newTask("none", api.TaskRunning, api.TaskStatusNone),
+		newTask("pulled", api.TaskRunning, api.TaskPulled),
+		newTask("created", api.TaskRunning, api.TaskCreated),
+		newTask("running1", api.TaskRunning, api.TaskRunning),
+		newTask("running2", api.TaskRunning, api.TaskRunning),
+		newTask("stopping", api.TaskStopped, api.TaskRunning),
+		newTask("stopped", api.TaskStopped, api.TaskStopped),
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, tasks)
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := taskCountsV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks/counts", nil)
+	requestHandler(recorder, req)
+
+	var countsResponse TaskCountsResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &countsResponse)
+	require.NoError(t, err)
+	assert.Equal(t, TaskCountsResponse{
+		Pending:               3,
+		Running:               3,
+		StoppedPendingCleanup: 1,
+	}, countsResponse)
+}
+
+func TestResources(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	defer func(resources func(*config.Config) (int64, int64)) { registeredResources = resources }(registeredResources)
+	registeredResources = func(cfg *config.Config) (int64, int64) {
+		return 4096, 8192 - int64(cfg.ReservedMemory)
+	}
+
+	newTask := func(arn string, knownStatus api.TaskStatus, containers ...*api.Container) *api.Task {
+		return &api.Task{
+			Arn:                 arn,
+			DesiredStatusUnsafe: api.TaskRunning,
+			KnownStatusUnsafe:   knownStatus,
+			Family:              "test",
+			Version:             "1",
+			Containers:          containers,
+		}
+	}
+	taskLevel := newTask("taskLevel", api.TaskRunning, &api.Container{Name: "c1", CPU: 128, Memory: 128})
+	taskLevel.CPU = 1
+	taskLevel.Memory = 2048
+	tasks := []*api.Task{
+		newTask("pending", api.TaskPulled, &api.Container{Name: "c1", CPU: 256, Memory: 512}),
+		newTask("running", api.TaskRunning,
+			&api.Container{Name: "c1", CPU: 512, Memory: 1, MemoryUnit: api.MemoryUnitGiB},
+			&api.Container{Name: "c2", CPU: 256, Memory: 256}),
+		taskLevel,
+		newTask("stopped", api.TaskStopped, &api.Container{Name: "c1", CPU: 1024, Memory: 1024}),
+	}
+
+	state := dockerstate.NewTaskEngineState()
+	stateSetupHelper(state, tasks)
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := resourcesV1RequestHandlerMaker(mockStateResolver, &config.Config{ReservedMemory: 192})
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/resources", nil)
+	requestHandler(recorder, req)
+
+	var resourcesResponse ResourcesResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &resourcesResponse)
+	require.NoError(t, err)
+	assert.Equal(t, ResourcesResponse{
+		Registered: ResourceAmountsResponse{CPU: 4096, Memory: 8000},
+		Reserved:   ResourceAmountsResponse{CPU: 2048, Memory: 3840},
+		Available:  ResourceAmountsResponse{CPU: 2048, Memory: 4160},
+	}, resourcesResponse)
+}
+
+func TestPendingENIAttachments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	state := dockerstate.NewTaskEngineState()
+	receivedAt := time.Now().Add(-time.Minute)
+	state.AddENIAttachment(&api.ENIAttachment{
+		TaskARN:       "waiting",
+		AttachmentARN: "attachment1",
+		MACAddress:    "00:0a:95:9d:68:16",
+		ReceivedAt:    receivedAt,
+		ExpiresAt:     receivedAt.Add(5 * time.Minute),
+	})
+	state.AddENIAttachment(&api.ENIAttachment{
+		TaskARN:          "attached",
+		AttachmentARN:    "attachment2",
+		MACAddress:       "00:0a:95:9d:68:17",
+		AttachStatusSent: true,
+		Status:           api.ENIAttached,
+	})
+
+	mockStateResolver.EXPECT().State().Return(state)
+	requestHandler := pendingENIAttachmentsV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/tasks/pending-eni", nil)
+	requestHandler(recorder, req)
+
+	var pendingResponse PendingENIAttachmentsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &pendingResponse))
+	require.Len(t, pendingResponse.Tasks, 1)
+	pending := pendingResponse.Tasks[0]
+	assert.Equal(t, "waiting", pending.TaskArn)
+	assert.Equal(t, "attachment1", pending.AttachmentArn)
+	assert.Equal(t, "00:0a:95:9d:68:16", pending.MACAddress)
+	require.NotNil(t, pending.ReceivedAt)
+	assert.True(t, receivedAt.Equal(*pending.ReceivedAt))
+	waiting, err := time.ParseDuration(pending.Waiting)
+	require.NoError(t, err)
+	assert.True(t, waiting >= time.Minute, "wait should be measured from when the attachment was received")
+}
+
+func TestContainerConfigHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStateResolver := mock_handlers.NewMockDockerStateResolver(ctrl)
+
+	container := &api.Container{Name: "c1"}
+	container.SetEffectiveConfig(&docker.Config{
+		Image:  "busybox",
+		Env:    []string{"DB_PASSWORD=hunter2", "EMPTY="},
+		Labels: map[string]string{"key": "value"},
+	}, &docker.HostConfig{
+		NetworkMode: "bridge",
+		LogConfig: docker.LogConfig{
+			Type:   "splunk",
+			Config: map[string]string{"splunk-token": "secret"},
+		},
+	})
+	state := dockerstate.NewTaskEngineState()
+	task := &api.Task{Arn: "t1", Containers: []*api.Container{container}}
+	state.AddTask(task)
+	state.AddContainer(&api.DockerContainer{DockerID: "dockerid1", DockerName: "dockername1", Container: container}, task)
+
+	mockStateResolver.EXPECT().State().Return(state).Times(2)
+	requestHandler := containerConfigV1RequestHandlerMaker(mockStateResolver)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/containers/config?dockerid=dockerid1", nil)
+	requestHandler(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var configResponse ContainerConfigResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &configResponse))
+	assert.Equal(t, "dockerid1", configResponse.DockerId)
+	assert.Equal(t, "c1", configResponse.Name)
+	assert.Equal(t, "busybox", configResponse.Config.Image)
+	assert.Equal(t, map[string]string{"key": "value"}, configResponse.Config.Labels)
+	assert.Equal(t, []string{"DB_PASSWORD=[redacted]", "EMPTY=[redacted]"}, configResponse.Config.Env)
+	assert.Equal(t, "bridge", configResponse.HostConfig.NetworkMode)
+	assert.Equal(t, map[string]string{"splunk-token": "[redacted]"}, configResponse.HostConfig.LogConfig.Config)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/containers/config?dockerid=unknown", nil)
+	requestHandler(recorder, req)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1/containers/config", nil)
+	requestHandler(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
 func TestLicenseHandler(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -317,6 +1143,150 @@ func stateSetupHelper(state dockerstate.TaskEngineState, tasks []*api.Task) {
 	}
 }
 
+func TestAgentHandler(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "TestAgentHandler")
+	require.NoError(t, err)
+	defer os.RemoveAll(dataDir)
+	stateManager, err := statemanager.NewStateManager(&config.Config{DataDir: dataDir})
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	pauseReporter := mock_handlers.NewMockPauseReporter(ctrl)
+	pauseReporter.EXPECT().Paused().Return(false).Times(2)
+
+	startTime := time.Now().Add(-time.Minute)
+	agentHandler := agentV1RequestHandlerMaker(startTime, stateManager, pauseReporter)
+	getAgentResponse := func() AgentResponse {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/agent", nil)
+		agentHandler(recorder, req)
+		var agentResponse AgentResponse
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &agentResponse))
+		return agentResponse
+	}
+
+	agentResponse := getAgentResponse()
+	assert.True(t, startTime.Equal(agentResponse.StartedAt))
+	uptime, err := time.ParseDuration(agentResponse.Uptime)
+	require.NoError(t, err)
+	assert.True(t, uptime >= time.Minute, "uptime should be measured from the start time")
+	assert.Nil(t, agentResponse.LastSaved, "state has not been saved yet")
+
+	beforeSave := time.Now()
+	require.NoError(t, stateManager.ForceSave())
+	agentResponse = getAgentResponse()
+	require.NotNil(t, agentResponse.LastSaved, "state save should be reported")
+	assert.False(t, agentResponse.LastSaved.Before(beforeSave))
+	assert.False(t, agentResponse.Paused)
+}
+
+func TestAgentHandlerReportsPause(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	pauseReporter := mock_handlers.NewMockPauseReporter(ctrl)
+	pauseReporter.EXPECT().Paused().Return(true)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/agent", nil)
+	agentV1RequestHandlerMaker(time.Now(), statemanager.NewNoopStateManager(), pauseReporter)(recorder, req)
+
+	var agentResponse AgentResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &agentResponse))
+	assert.True(t, agentResponse.Paused, "the pause of the task engine should be reported")
+}
+
+func TestConfigHandler(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cluster = "myCluster"
+	cfg.AWSRegion = "us-west-2"
+	cfg.ReservedMemory = 128
+	cfg.TaskCleanupWaitDuration = 5 * time.Minute
+	cfg.InstanceAttributes = map[string]string{"stack": "prod"}
+	cfg.EngineAuthType = "dockercfg"
+	cfg.EngineAuthData = config.NewSensitiveRawMessage([]byte(`{"https://index.docker.io/v1/":{"auth":"secret"}}`))
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/config", nil)
+	configV1RequestHandlerMaker(&cfg)(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "secret", "sensitive values should be masked")
+
+	var configResponse config.Config
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &configResponse))
+	assert.Equal(t, cfg.Cluster, configResponse.Cluster)
+	assert.Equal(t, cfg.AWSRegion, configResponse.AWSRegion)
+	assert.Equal(t, cfg.ReservedMemory, configResponse.ReservedMemory)
+	assert.Equal(t, cfg.TaskCleanupWaitDuration, configResponse.TaskCleanupWaitDuration)
+	assert.Equal(t, cfg.InstanceAttributes, configResponse.InstanceAttributes)
+	assert.Equal(t, cfg.EngineAuthType, configResponse.EngineAuthType)
+	require.NotNil(t, configResponse.EngineAuthData)
+	assert.Equal(t, `"[redacted]"`, string(configResponse.EngineAuthData.Contents()))
+}
+
+func TestConfigHandlerServesStartupSnapshot(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Cluster = "myCluster"
+	handler := configV1RequestHandlerMaker(&cfg)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		cfg.Cluster = "otherCluster"
+	}()
+	go func() {
+		defer wg.Done()
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/v1/config", nil)
+		handler(recorder, req)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}()
+	wg.Wait()
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/config", nil)
+	handler(recorder, req)
+	var configResponse config.Config
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &configResponse))
+	assert.Equal(t, "myCluster", configResponse.Cluster, "the config at startup should be served")
+}
+
+type fakeSubmissionStatsReporter struct {
+	stats eventhandler.SubmissionStats
+}
+
+func (reporter fakeSubmissionStatsReporter) GetSubmissionStats() eventhandler.SubmissionStats {
+	return reporter.stats
+}
+
+func TestStateChangeSubmissionsHandler(t *testing.T) {
+	lastErrorTime := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+	reporter := fakeSubmissionStatsReporter{
+		stats: eventhandler.SubmissionStats{
+			Failures:            5,
+			ConsecutiveFailures: 2,
+			LastError:           "ThrottlingException: Rate exceeded",
+			LastErrorTime:       lastErrorTime,
+			LastErrorRetriable:  true,
+		},
+	}
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/statechanges", nil)
+	stateChangeSubmissionsV1RequestHandlerMaker(reporter)(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response StateChangeSubmissionsResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, int64(5), response.Failures)
+	assert.Equal(t, int64(2), response.ConsecutiveFailures)
+	assert.Equal(t, "ThrottlingException: Rate exceeded", response.LastError)
+	require.NotNil(t, response.LastErrorTime)
+	assert.True(t, lastErrorTime.Equal(*response.LastErrorTime))
+	assert.True(t, response.LastErrorRetriable)
+}
+
 func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -327,7 +1297,11 @@ func performMockRequest(t *testing.T, path string) *httptest.ResponseRecorder {
 	stateSetupHelper(state, testTasks)
 
 	mockStateResolver.EXPECT().State().Return(state)
-	requestHandler := setupServer(utils.Strptr(testContainerInstanceArn), mockStateResolver, &config.Config{Cluster: testClusterArn})
+	healthChecker := newDockerHealthChecker(mock_handlers.NewMockDockerVersioner(ctrl), dockerHealthCheckInterval)
+	requestHandler := setupServer(utils.Strptr(testContainerInstanceArn), mockStateResolver,
+		mock_handlers.NewMockPauseReporter(ctrl), healthChecker,
+		statemanager.NewNoopStateManager(), eventhandler.NewTaskHandler(statemanager.NewNoopStateManager()),
+		time.Now(), &config.Config{Cluster: testClusterArn}, nil)
 
 	recorder := httptest.NewRecorder()
 	req, _ := http.NewRequest("GET", path, nil)
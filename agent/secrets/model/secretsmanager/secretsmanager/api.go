@@ -0,0 +1,209 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secretsmanager
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+const opGetSecretValue = "GetSecretValue"
+
+// GetSecretValueRequest generates a "aws/request.Request" representing the
+// client's request for the GetSecretValue operation. The "output" return
+// value can be used to capture response data after the request's "Send" method
+// is called.
+//
+// See GetSecretValue for usage and error information.
+//
+// Creating a request object using this method should be used when you want to inject
+// custom logic into the request's lifecycle using a custom handler, or if you want to
+// access properties on the request object before or after sending the request. If
+// you just want the service response, call the GetSecretValue method directly
+// instead.
+//
+// Note: You must call the "Send" method on the returned request object in order
+// to execute the request.
+//
+//	// Example sending a request using the GetSecretValueRequest method.
+//	req, resp := client.GetSecretValueRequest(params)
+//
+//	err := req.Send()
+//	if err == nil { // resp is now filled
+//	    fmt.Println(resp)
+//	}
+func (c *SecretsManager) GetSecretValueRequest(input *GetSecretValueInput) (req *request.Request, output *GetSecretValueOutput) {
+	op := &request.Operation{
+		Name:       opGetSecretValue,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	if input == nil {
+		input = &GetSecretValueInput{}
+	}
+
+	output = &GetSecretValueOutput{}
+	req = c.newRequest(op, input, output)
+	return
+}
+
+// GetSecretValue API operation for AWS Secrets Manager.
+//
+// Returns awserr.Error for service API and SDK errors. Use runtime type assertions
+// with awserr.Error's Code and Message methods to get detailed information about
+// the error.
+//
+// See the AWS API reference guide for AWS Secrets Manager's
+// API operation GetSecretValue for usage and error information.
+//
+// Returned Error Codes:
+//
+//   - ErrCodeResourceNotFoundException "ResourceNotFoundException"
+//
+//   - ErrCodeInvalidParameterException "InvalidParameterException"
+//
+//   - ErrCodeInvalidRequestException "InvalidRequestException"
+//
+//   - ErrCodeDecryptionFailure "DecryptionFailure"
+//
+//   - ErrCodeInternalServiceError "InternalServiceError"
+func (c *SecretsManager) GetSecretValue(input *GetSecretValueInput) (*GetSecretValueOutput, error) {
+	req, out := c.GetSecretValueRequest(input)
+	return out, req.Send()
+}
+
+// GetSecretValueWithContext is the same as GetSecretValue with the addition of
+// the ability to pass a context and additional request options.
+//
+// See GetSecretValue for details on how to use this API operation.
+//
+// The context must be non-nil and will be used for request cancellation. If
+// the context is nil a panic will occur. In the future the SDK may create
+// sub-contexts for http.Requests. See https://golang.org/pkg/context/
+// for more information on using Contexts.
+func (c *SecretsManager) GetSecretValueWithContext(ctx aws.Context, input *GetSecretValueInput, opts ...request.Option) (*GetSecretValueOutput, error) {
+	req, out := c.GetSecretValueRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+type GetSecretValueInput struct {
+	_ struct{} `type:"structure"`
+
+	// SecretId is a required field
+	SecretId *string `min:"1" type:"string" required:"true"`
+
+	VersionId *string `min:"32" type:"string"`
+
+	VersionStage *string `min:"1" type:"string"`
+}
+
+// String returns the string representation
+func (s GetSecretValueInput) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s GetSecretValueInput) GoString() string {
+	return s.String()
+}
+
+// Validate inspects the fields of the type to determine if they are valid.
+func (s *GetSecretValueInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetSecretValueInput"}
+	if s.SecretId == nil {
+		invalidParams.Add(request.NewErrParamRequired("SecretId"))
+	}
+	if s.SecretId != nil && len(*s.SecretId) < 1 {
+		invalidParams.Add(request.NewErrParamMinLen("SecretId", 1))
+	}
+	if s.VersionId != nil && len(*s.VersionId) < 32 {
+		invalidParams.Add(request.NewErrParamMinLen("VersionId", 32))
+	}
+	if s.VersionStage != nil && len(*s.VersionStage) < 1 {
+		invalidParams.Add(request.NewErrParamMinLen("VersionStage", 1))
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetSecretId sets the SecretId field's value.
+func (s *GetSecretValueInput) SetSecretId(v string) *GetSecretValueInput {
+	s.SecretId = &v
+	return s
+}
+
+// SetVersionId sets the VersionId field's value.
+func (s *GetSecretValueInput) SetVersionId(v string) *GetSecretValueInput {
+	s.VersionId = &v
+	return s
+}
+
+// SetVersionStage sets the VersionStage field's value.
+func (s *GetSecretValueInput) SetVersionStage(v string) *GetSecretValueInput {
+	s.VersionStage = &v
+	return s
+}
+
+type GetSecretValueOutput struct {
+	_ struct{} `type:"structure"`
+
+	ARN *string `min:"20" type:"string"`
+
+	Name *string `min:"1" type:"string"`
+
+	SecretString *string `type:"string"`
+
+	VersionId *string `min:"32" type:"string"`
+}
+
+// String returns the string representation
+func (s GetSecretValueOutput) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s GetSecretValueOutput) GoString() string {
+	return s.String()
+}
+
+// SetARN sets the ARN field's value.
+func (s *GetSecretValueOutput) SetARN(v string) *GetSecretValueOutput {
+	s.ARN = &v
+	return s
+}
+
+// SetName sets the Name field's value.
+func (s *GetSecretValueOutput) SetName(v string) *GetSecretValueOutput {
+	s.Name = &v
+	return s
+}
+
+// SetSecretString sets the SecretString field's value.
+func (s *GetSecretValueOutput) SetSecretString(v string) *GetSecretValueOutput {
+	s.SecretString = &v
+	return s
+}
+
+// SetVersionId sets the VersionId field's value.
+func (s *GetSecretValueOutput) SetVersionId(v string) *GetSecretValueOutput {
+	s.VersionId = &v
+	return s
+}
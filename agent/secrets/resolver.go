@@ -0,0 +1,166 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+// Package secrets resolves the values of secrets stored in SSM Parameter Store
+// and Secrets Manager
+package secrets
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/httpclient"
+	secretsmanagerapi "github.com/aws/amazon-ecs-agent/agent/secrets/model/secretsmanager/secretsmanager"
+	ssmapi "github.com/aws/amazon-ecs-agent/agent/secrets/model/ssm/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+	awscredentials "github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	roundtripTimeout = 5 * time.Second
+
+	arnPrefix               = "arn:"
+	secretsManagerARNPrefix = "secretsmanager"
+	ssmARNPrefix            = "ssm"
+	ssmParameterResource    = "parameter"
+)
+
+// Resolver resolves the values of secrets with the credentials of a task role
+type Resolver interface {
+	// Resolve returns the value of the secret referenced by valueFrom, which
+	// is either the ARN of a Secrets Manager secret or the name or ARN of an
+	// SSM parameter. Parameters referenced by name are looked up in the given
+	// region
+	Resolve(valueFrom, region string, creds credentials.IAMRoleCredentials) (string, error)
+}
+
+// SSMSDK is an interface that specifies the subset of the AWS Go SDK's SSM
+// client that the Agent uses. This interface is meant to allow injecting a
+// mock for testing.
+type SSMSDK interface {
+	GetParameter(*ssmapi.GetParameterInput) (*ssmapi.GetParameterOutput, error)
+}
+
+// SecretsManagerSDK is an interface that specifies the subset of the AWS Go
+// SDK's Secrets Manager client that the Agent uses. This interface is meant to
+// allow injecting a mock for testing.
+type SecretsManagerSDK interface {
+	GetSecretValue(*secretsmanagerapi.GetSecretValueInput) (*secretsmanagerapi.GetSecretValueOutput, error)
+}
+
+type resolver struct {
+	httpClient *http.Client
+
+	newSSMClient            func(cfg *aws.Config) SSMSDK
+	newSecretsManagerClient func(cfg *aws.Config) SecretsManagerSDK
+}
+
+// NewResolver returns a Resolver that calls SSM and Secrets Manager with the
+// credentials it is given
+func NewResolver(acceptInsecureCert bool) Resolver {
+	return &resolver{
+		httpClient: httpclient.New(roundtripTimeout, acceptInsecureCert),
+		newSSMClient: func(cfg *aws.Config) SSMSDK {
+			return ssmapi.New(session.New(cfg))
+		},
+		newSecretsManagerClient: func(cfg *aws.Config) SecretsManagerSDK {
+			return secretsmanagerapi.New(session.New(cfg))
+		},
+	}
+}
+
+// Resolve returns the value of the secret referenced by valueFrom
+func (r *resolver) Resolve(valueFrom, region string, creds credentials.IAMRoleCredentials) (string, error) {
+	ref, err := parseSecretReference(valueFrom, region)
+	if err != nil {
+		return "", err
+	}
+	cfg := aws.NewConfig().
+		WithRegion(ref.region).
+		WithHTTPClient(r.httpClient).
+		WithCredentials(awscredentials.NewStaticCredentials(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken))
+
+	if ref.secretsManager {
+		output, err := r.newSecretsManagerClient(cfg).GetSecretValue(&secretsmanagerapi.GetSecretValueInput{
+			SecretId: aws.String(ref.name),
+		})
+		if err != nil {
+			return "", fmt.Errorf("secrets: unable to get secret %s: %v", valueFrom, err)
+		}
+		if output.SecretString == nil {
+			return "", fmt.Errorf("secrets: secret %s has no string value", valueFrom)
+		}
+		return aws.StringValue(output.SecretString), nil
+	}
+
+	output, err := r.newSSMClient(cfg).GetParameter(&ssmapi.GetParameterInput{
+		Name:           aws.String(ref.name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("secrets: unable to get parameter %s: %v", valueFrom, err)
+	}
+	if output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("secrets: parameter %s has no value", valueFrom)
+	}
+	return aws.StringValue(output.Parameter.Value), nil
+}
+
+// secretReference is a parsed reference to a secret
+type secretReference struct {
+	// secretsManager is true if the secret is stored in Secrets Manager, and
+	// false if it is an SSM parameter
+	secretsManager bool
+	region         string
+	// name is the secret id or parameter name the service is called with
+	name string
+}
+
+// parseSecretReference parses the ARN of a Secrets Manager secret or SSM
+// parameter. Anything that is not an ARN is treated as the name of an SSM
+// parameter in the default region
+func parseSecretReference(valueFrom, defaultRegion string) (secretReference, error) {
+	if !strings.HasPrefix(valueFrom, arnPrefix) {
+		if valueFrom == "" {
+			return secretReference{}, fmt.Errorf("secrets: empty secret reference")
+		}
+		return secretReference{region: defaultRegion, name: valueFrom}, nil
+	}
+
+	// arn:partition:service:region:account-id:resource
+	fields := strings.SplitN(valueFrom, ":", 6)
+	if len(fields) != 6 || fields[3] == "" || fields[5] == "" {
+		return secretReference{}, fmt.Errorf("secrets: invalid secret arn %s", valueFrom)
+	}
+	service, region, resource := fields[2], fields[3], fields[5]
+	switch service {
+	case secretsManagerARNPrefix:
+		return secretReference{secretsManager: true, region: region, name: valueFrom}, nil
+	case ssmARNPrefix:
+		if !strings.HasPrefix(resource, ssmParameterResource+"/") {
+			return secretReference{}, fmt.Errorf("secrets: arn %s does not reference a parameter", valueFrom)
+		}
+		// Parameters in a hierarchy keep their leading slash
+		name := strings.TrimPrefix(resource, ssmParameterResource)
+		if strings.Count(name, "/") == 1 {
+			name = strings.TrimPrefix(name, "/")
+		}
+		return secretReference{region: region, name: name}, nil
+	default:
+		return secretReference{}, fmt.Errorf("secrets: unsupported service %s in secret arn %s", service, valueFrom)
+	}
+}
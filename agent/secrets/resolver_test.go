@@ -0,0 +1,129 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/credentials"
+	"github.com/aws/amazon-ecs-agent/agent/secrets/mocks"
+	secretsmanagerapi "github.com/aws/amazon-ecs-agent/agent/secrets/model/secretsmanager/secretsmanager"
+	ssmapi "github.com/aws/amazon-ecs-agent/agent/secrets/model/ssm/ssm"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecretReference(t *testing.T) {
+	testCases := []struct {
+		valueFrom string
+		expected  secretReference
+		err       bool
+	}{
+		{
+			valueFrom: "splunk-token",
+			expected:  secretReference{region: "us-west-2", name: "splunk-token"},
+		},
+		{
+			valueFrom: "arn:aws:ssm:eu-west-1:123456789012:parameter/splunk-token",
+			expected:  secretReference{region: "eu-west-1", name: "splunk-token"},
+		},
+		{
+			valueFrom: "arn:aws:ssm:eu-west-1:123456789012:parameter/logging/splunk-token",
+			expected:  secretReference{region: "eu-west-1", name: "/logging/splunk-token"},
+		},
+		{
+			valueFrom: "arn:aws:secretsmanager:eu-west-1:123456789012:secret:splunk-token-AbCdEf",
+			expected: secretReference{
+				secretsManager: true,
+				region:         "eu-west-1",
+				name:           "arn:aws:secretsmanager:eu-west-1:123456789012:secret:splunk-token-AbCdEf",
+			},
+		},
+		{valueFrom: "", err: true},
+		{valueFrom: "arn:aws:ssm:eu-west-1:123456789012:document/splunk-token", err: true},
+		{valueFrom: "arn:aws:s3:::bucket/splunk-token", err: true},
+		{valueFrom: "arn:aws:kms:eu-west-1:123456789012:key/splunk-token", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.valueFrom, func(t *testing.T) {
+			ref, err := parseSecretReference(tc.valueFrom, "us-west-2")
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ref)
+		})
+	}
+}
+
+func newTestResolver(ctrl *gomock.Controller) (*resolver, *mock_secrets.MockSSMSDK, *mock_secrets.MockSecretsManagerSDK) {
+	ssmClient := mock_secrets.NewMockSSMSDK(ctrl)
+	secretsManagerClient := mock_secrets.NewMockSecretsManagerSDK(ctrl)
+	return &resolver{
+		newSSMClient: func(cfg *aws.Config) SSMSDK {
+			return ssmClient
+		},
+		newSecretsManagerClient: func(cfg *aws.Config) SecretsManagerSDK {
+			return secretsManagerClient
+		},
+	}, ssmClient, secretsManagerClient
+}
+
+func TestResolveSSMParameter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, ssmClient, _ := newTestResolver(ctrl)
+
+	ssmClient.EXPECT().GetParameter(&ssmapi.GetParameterInput{
+		Name:           aws.String("splunk-token"),
+		WithDecryption: aws.Bool(true),
+	}).Return(&ssmapi.GetParameterOutput{
+		Parameter: &ssmapi.Parameter{Value: aws.String("token")},
+	}, nil)
+
+	value, err := r.Resolve("splunk-token", "us-west-2", credentials.IAMRoleCredentials{})
+	require.NoError(t, err)
+	assert.Equal(t, "token", value)
+}
+
+func TestResolveSecretsManagerSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, _, secretsManagerClient := newTestResolver(ctrl)
+
+	secretARN := "arn:aws:secretsmanager:eu-west-1:123456789012:secret:splunk-token-AbCdEf"
+	secretsManagerClient.EXPECT().GetSecretValue(&secretsmanagerapi.GetSecretValueInput{
+		SecretId: aws.String(secretARN),
+	}).Return(&secretsmanagerapi.GetSecretValueOutput{SecretString: aws.String("token")}, nil)
+
+	value, err := r.Resolve(secretARN, "us-west-2", credentials.IAMRoleCredentials{})
+	require.NoError(t, err)
+	assert.Equal(t, "token", value)
+}
+
+func TestResolveError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r, ssmClient, _ := newTestResolver(ctrl)
+
+	ssmClient.EXPECT().GetParameter(gomock.Any()).Return(nil, errors.New("AccessDeniedException"))
+
+	_, err := r.Resolve("splunk-token", "us-west-2", credentials.IAMRoleCredentials{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+// +build !windows
+
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package sighandlers
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aws/amazon-ecs-agent/agent/engine"
+	"github.com/cihub/seelog"
+)
+
+// StartPauseHandler pauses the task engine when SIGUSR2 is received, and
+// resumes it when SIGUSR2 is received again. New tasks are held while the
+// engine is paused, the tasks started before keep running
+func StartPauseHandler(taskEngine engine.TaskEngine) {
+	signalChannel := make(chan os.Signal, 1)
+	signal.Notify(signalChannel, syscall.SIGUSR2)
+	go func() {
+		for range signalChannel {
+			togglePause(taskEngine)
+		}
+	}()
+}
+
+// togglePause resumes the task engine if it is paused, and pauses it otherwise
+func togglePause(taskEngine engine.TaskEngine) {
+	if taskEngine.Paused() {
+		seelog.Info("Resuming the task engine")
+		taskEngine.Resume()
+		return
+	}
+	seelog.Info("Pausing the task engine")
+	taskEngine.Pause()
+}
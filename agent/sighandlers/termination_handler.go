@@ -13,9 +13,11 @@
 
 // sighandlers handle signals and behave appropriately.
 // SIGTERM:
-//   Flush state to disk and exit
+//   Drain the instance, if enabled, then flush state to disk and exit
 // SIGUSR1:
 //   Print a dump of goroutines to the logger and DON'T exit
+// SIGUSR2:
+//   Pause the task engine, or resume it if it is paused, and DON'T exit
 package sighandlers
 
 import (
@@ -34,20 +36,29 @@ import (
 
 var log = logger.ForModule("TerminationHandler")
 
-func StartTerminationHandler(saver statemanager.Saver, taskEngine engine.TaskEngine) {
+// StartTerminationHandler waits for a termination signal, then saves state and
+// exits. If drain is not nil, it is called first and the exit code it returns
+// is used when state is saved successfully
+func StartTerminationHandler(saver statemanager.Saver, taskEngine engine.TaskEngine, drain func() int) {
 	signalChannel := make(chan os.Signal, 2)
 	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
 
 	sig := <-signalChannel
 	log.Debug("Received termination signal", "signal", sig.String())
 
+	exitCode := exitcodes.ExitSuccess
+	if drain != nil {
+		log.Info("Draining the instance before shutting down")
+		exitCode = drain()
+	}
+
 	err := FinalSave(saver, taskEngine)
 	if err != nil {
 		log.Crit("Error saving state before final shutdown", "err", err)
 		// Terminal because it's a sigterm; the user doesn't want it to restart
 		os.Exit(exitcodes.ExitTerminal)
 	}
-	os.Exit(exitcodes.ExitSuccess)
+	os.Exit(exitCode)
 }
 
 const engineDisableTimeout = 5 * time.Second
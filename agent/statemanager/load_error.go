@@ -0,0 +1,58 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package statemanager
+
+import "fmt"
+
+// LoadFailureReason describes why the saved state could not be loaded
+type LoadFailureReason string
+
+const (
+	// LoadFailureUnreadableFile indicates that the state file exists but
+	// could not be read
+	LoadFailureUnreadableFile LoadFailureReason = "UnreadableFile"
+	// LoadFailureCorruptFile indicates that the state file, or the data saved
+	// for one of its fields, is not valid json for the expected format
+	LoadFailureCorruptFile LoadFailureReason = "CorruptFile"
+	// LoadFailureVersionMismatch indicates that the state file was written
+	// with a newer data version than this agent understands
+	LoadFailureVersionMismatch LoadFailureReason = "VersionMismatch"
+)
+
+// LoadError is returned by Load when saved state exists but cannot be
+// restored. It carries a structured reason so that callers can report why
+// the restore failed
+type LoadError struct {
+	Reason LoadFailureReason
+	// Field is the saved field that failed to load, if the failure is
+	// specific to one
+	Field string
+	// Version is the data version found in the state file, if it was read
+	Version int
+	Err     error
+}
+
+func (err *LoadError) Error() string {
+	msg := fmt.Sprintf("unable to load saved state: %s", err.Reason)
+	if err.Field != "" {
+		msg += fmt.Sprintf(" (field: %s)", err.Field)
+	}
+	if err.Reason == LoadFailureVersionMismatch {
+		msg += fmt.Sprintf(" (version: %d, supported: %d)", err.Version, ECSDataVersion)
+	}
+	if err.Err != nil {
+		msg += ": " + err.Err.Error()
+	}
+	return msg
+}
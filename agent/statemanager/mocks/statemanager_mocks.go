@@ -17,6 +17,8 @@
 package mock_statemanager
 
 import (
+	time "time"
+
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -51,6 +53,16 @@ func (_mr *_MockStateManagerRecorder) ForceSave() *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ForceSave")
 }
 
+func (_m *MockStateManager) LastSaved() time.Time {
+	ret := _m.ctrl.Call(_m, "LastSaved")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+func (_mr *_MockStateManagerRecorder) LastSaved() *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "LastSaved")
+}
+
 func (_m *MockStateManager) Load() error {
 	ret := _m.ctrl.Call(_m, "Load")
 	ret0, _ := ret[0].(error)
@@ -13,6 +13,8 @@
 
 package statemanager
 
+import "time"
+
 // NoopStateManager is a state manager that succeeds for all reads/writes without
 // even trying; it allows disabling of state serialization by being a drop-in
 // replacement so no other code need be concerned with it.
@@ -37,3 +39,8 @@ func (nsm *NoopStateManager) ForceSave() error {
 func (nsm *NoopStateManager) Load() error {
 	return nil
 }
+
+// LastSaved returns the zero time, as the state is never saved
+func (nsm *NoopStateManager) LastSaved() time.Time {
+	return time.Time{}
+}
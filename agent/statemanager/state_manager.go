@@ -106,6 +106,9 @@ type platformDependencies interface{}
 type StateManager interface {
 	Saver
 	Load() error
+	// LastSaved returns the time the state was last saved successfully. It
+	// returns the zero time if the state was not saved yet
+	LastSaved() time.Time
 }
 
 type basicStateManager struct {
@@ -119,6 +122,9 @@ type basicStateManager struct {
 
 	savingLock sync.Mutex // guards marshal, write, move (on Linux), and load (on Windows)
 
+	lastSavedLock sync.RWMutex // guards lastSaved
+	lastSaved     time.Time    // the last time a save succeeded
+
 	platformDependencies platformDependencies // platform-specific dependencies
 }
 
@@ -212,7 +218,22 @@ func (manager *basicStateManager) ForceSave() error {
 		log.Error("Error saving state; could not marshal data; this is odd", "err", err)
 		return err
 	}
-	return manager.writeFile(data)
+	err = manager.writeFile(data)
+	if err != nil {
+		return err
+	}
+	manager.lastSavedLock.Lock()
+	manager.lastSaved = time.Now()
+	manager.lastSavedLock.Unlock()
+	return nil
+}
+
+// LastSaved returns the time the state was last saved successfully
+func (manager *basicStateManager) LastSaved() time.Time {
+	manager.lastSavedLock.RLock()
+	defer manager.lastSavedLock.RUnlock()
+
+	return manager.lastSaved
 }
 
 // Load reads state off the disk from the well-known filepath and loads it into
@@ -223,7 +244,7 @@ func (manager *basicStateManager) Load() error {
 	data, err := manager.readFile()
 	if err != nil {
 		log.Error("Error reading existing state file", "err", err)
-		return err
+		return &LoadError{Reason: LoadFailureUnreadableFile, Err: err}
 	}
 	if data == nil {
 		return nil
@@ -244,7 +265,7 @@ func (manager *basicStateManager) Load() error {
 	err = json.Unmarshal(data, &intermediate)
 	if err != nil {
 		log.Debug("Could not unmarshal into intermediate")
-		return err
+		return &LoadError{Reason: LoadFailureCorruptFile, Err: err}
 	}
 
 	for key, rawJSON := range intermediate.Data {
@@ -256,7 +277,7 @@ func (manager *basicStateManager) Load() error {
 		err = json.Unmarshal(rawJSON, actualPointer)
 		if err != nil {
 			log.Debug("Could not unmarshal into actual")
-			return err
+			return &LoadError{Reason: LoadFailureCorruptFile, Field: key, Err: err}
 		}
 	}
 
@@ -270,11 +291,15 @@ func (manager *basicStateManager) dryRun(data []byte) error {
 	err := json.Unmarshal(data, &tmps)
 	if err != nil {
 		log.Crit("Could not unmarshal existing state; corrupted data?", "err", err, "data", data)
-		return err
+		return &LoadError{Reason: LoadFailureCorruptFile, Err: err}
 	}
 	if tmps.Version > ECSDataVersion {
 		strversion := strconv.Itoa(tmps.Version)
-		return errors.New("Unsupported data format: Version " + strversion + " not " + strconv.Itoa(ECSDataVersion))
+		return &LoadError{
+			Reason:  LoadFailureVersionMismatch,
+			Version: tmps.Version,
+			Err:     errors.New("Unsupported data format: Version " + strversion + " not " + strconv.Itoa(ECSDataVersion)),
+		}
 	}
 	return nil
 }
@@ -14,6 +14,8 @@
 package statemanager_test
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -24,6 +26,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/engine/dockerstate"
 	"github.com/aws/amazon-ecs-agent/agent/statemanager"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStateManagerNonexistantDirectory(t *testing.T) {
@@ -70,3 +73,51 @@ func TestLoadsV1DataCorrectly(t *testing.T) {
 	expected, _ := time.Parse(time.RFC3339, "2015-04-28T17:29:48.129140193Z")
 	assert.Equal(t, deadTask.GetKnownStatusTime(), expected)
 }
+
+func loadStateFile(t *testing.T, data string) error {
+	tmpDir, err := ioutil.TempDir("", "ecs_statemanager_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "ecs_agent_data.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+	cleanup, err := setupWindowsTest(path)
+	require.NoError(t, err, "Failed to set up test")
+	defer cleanup()
+
+	var containerInstanceArn string
+	stateManager, err := statemanager.NewStateManager(&config.Config{DataDir: tmpDir},
+		statemanager.AddSaveable("ContainerInstanceArn", &containerInstanceArn))
+	require.NoError(t, err)
+	return stateManager.Load()
+}
+
+func TestLoadCorruptFileReason(t *testing.T) {
+	err := loadStateFile(t, `{"Version":6,"Data":{`)
+	require.Error(t, err)
+	loadErr, ok := err.(*statemanager.LoadError)
+	require.True(t, ok, "Expected a LoadError")
+	assert.Equal(t, statemanager.LoadFailureCorruptFile, loadErr.Reason)
+}
+
+func TestLoadCorruptFieldReason(t *testing.T) {
+	err := loadStateFile(t, `{"Version":6,"Data":{"ContainerInstanceArn":42}}`)
+	require.Error(t, err)
+	loadErr, ok := err.(*statemanager.LoadError)
+	require.True(t, ok, "Expected a LoadError")
+	assert.Equal(t, statemanager.LoadFailureCorruptFile, loadErr.Reason)
+	assert.Equal(t, "ContainerInstanceArn", loadErr.Field)
+}
+
+func TestLoadVersionMismatchReason(t *testing.T) {
+	err := loadStateFile(t, `{"Version":99,"Data":{}}`)
+	require.Error(t, err)
+	loadErr, ok := err.(*statemanager.LoadError)
+	require.True(t, ok, "Expected a LoadError")
+	assert.Equal(t, statemanager.LoadFailureVersionMismatch, loadErr.Reason)
+	assert.Equal(t, 99, loadErr.Version)
+}
+
+func TestLoadMissingVersion(t *testing.T) {
+	err := loadStateFile(t, `{"Data":{}}`)
+	assert.NoError(t, err, "State without a version should be loaded")
+}
@@ -118,6 +118,10 @@ func (resolver *IntegContainerMetadataResolver) ResolveContainer(containerID str
 	return container, nil
 }
 
+func (resolver *IntegContainerMetadataResolver) ResolveENIAttachment(mac string) (*api.ENIAttachment, error) {
+	return nil, fmt.Errorf("unmapped eni")
+}
+
 func validateContainerMetrics(containerMetrics []*ecstcs.ContainerMetric, expected int) error {
 	if len(containerMetrics) != expected {
 		return fmt.Errorf("Mismatch in number of ContainerStatsSet elements. Expected: %d, Got: %d", expected, len(containerMetrics))
@@ -229,3 +233,16 @@ func (engine *MockTaskEngine) Capabilities() []*ecs.Attribute {
 
 func (engine *MockTaskEngine) Disable() {
 }
+
+func (engine *MockTaskEngine) Drain() {
+}
+
+func (engine *MockTaskEngine) Pause() {
+}
+
+func (engine *MockTaskEngine) Resume() {
+}
+
+func (engine *MockTaskEngine) Paused() bool {
+	return false
+}
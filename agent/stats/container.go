@@ -19,6 +19,9 @@ import (
 
 	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/stats/resolver"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/cihub/seelog"
 	"golang.org/x/net/context"
 )
@@ -32,16 +35,17 @@ const (
 	ContainerStatsBufferLength = 120
 )
 
-func newStatsContainer(dockerID string, client ecsengine.DockerClient, resolver resolver.ContainerMetadataResolver) *StatsContainer {
+func newStatsContainer(dockerID string, client ecsengine.DockerClient, resolver resolver.ContainerMetadataResolver, filesystemUsageInterval time.Duration) *StatsContainer {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &StatsContainer{
 		containerMetadata: &ContainerMetadata{
 			DockerID: dockerID,
 		},
-		ctx:      ctx,
-		cancel:   cancel,
-		client:   client,
-		resolver: resolver,
+		ctx:                     ctx,
+		cancel:                  cancel,
+		client:                  client,
+		resolver:                resolver,
+		filesystemUsageInterval: filesystemUsageInterval,
 	}
 }
 
@@ -50,6 +54,11 @@ func (container *StatsContainer) StartStatsCollection() {
 	container.statsQueue = NewQueue(ContainerStatsBufferLength)
 	container.statsQueue.Reset()
 	go container.collect()
+
+	if container.filesystemUsageInterval > 0 {
+		container.filesystemUsageQueue = newFilesystemUsageQueue(FilesystemUsageBufferLength)
+		go container.collectFilesystemUsage()
+	}
 }
 
 func (container *StatsContainer) StopStatsCollection() {
@@ -94,6 +103,72 @@ func (container *StatsContainer) collect() {
 	}
 }
 
+// collectFilesystemUsage samples the writable layer size of the container
+// until stats collection is stopped. Docker has to walk the container's
+// filesystem to compute the size, so it is sampled far less often than the
+// stats stream reports cpu and memory usage.
+func (container *StatsContainer) collectFilesystemUsage() {
+	dockerID := container.containerMetadata.DockerID
+	for {
+		select {
+		case <-container.ctx.Done():
+			seelog.Debugf("Stopping filesystem usage collection for container %s", dockerID)
+			return
+		case <-container.time().After(container.filesystemUsageInterval):
+			size, err := container.client.WritableLayerSize(dockerID, ecsengine.WritableLayerSizeTimeout)
+			if err != nil {
+				seelog.Debugf("Error querying filesystem usage for container %s: %v", dockerID, err)
+				continue
+			}
+			container.filesystemUsageQueue.add(size)
+		}
+	}
+}
+
+// getFilesystemUsageStatsSet gets the stats set for the writable layer size
+// samples taken since the last call. It returns nil if there are none
+func (container *StatsContainer) getFilesystemUsageStatsSet() *ecstcs.CWStatsSet {
+	if container.filesystemUsageQueue == nil {
+		return nil
+	}
+	statsSet, err := container.filesystemUsageQueue.getCWStatsSet()
+	if err != nil {
+		return nil
+	}
+	return statsSet
+}
+
+func (container *StatsContainer) setCPUThrottlingData(throttling cpuThrottlingData) {
+	container.throttlingLock.Lock()
+	defer container.throttlingLock.Unlock()
+	container.throttling = throttling
+}
+
+// getCPUThrottlingStats gets the most recent CPU throttling counters of the
+// container. Counters are cumulative over the lifetime of the container. It
+// returns nil if the container has no CPU quota, and so is never throttled
+func (container *StatsContainer) getCPUThrottlingStats() *ecstcs.CPUThrottlingStats {
+	container.throttlingLock.RLock()
+	defer container.throttlingLock.RUnlock()
+	if container.throttling.periods == 0 {
+		return nil
+	}
+	return &ecstcs.CPUThrottlingStats{
+		Periods:          aws.Int64(int64(container.throttling.periods)),
+		ThrottledPeriods: aws.Int64(int64(container.throttling.throttledPeriods)),
+		ThrottledTime:    aws.Int64(int64(container.throttling.throttledTime)),
+	}
+}
+
+func (container *StatsContainer) time() ttime.Time {
+	container._timeOnce.Do(func() {
+		if container._time == nil {
+			container._time = &ttime.DefaultTime{}
+		}
+	})
+	return container._time
+}
+
 func (container *StatsContainer) processStatsStream() error {
 	dockerID := container.containerMetadata.DockerID
 	seelog.Debugf("Collecting stats for container %s", dockerID)
@@ -108,6 +183,7 @@ func (container *StatsContainer) processStatsStream() error {
 		stat, err := dockerStatsToContainerStats(rawStat)
 		if err == nil {
 			container.statsQueue.Add(stat)
+			container.setCPUThrottlingData(dockerStatsToCPUThrottlingData(rawStat))
 		} else {
 			seelog.Warnf("Error converting stats for container %s: %v", dockerID, err)
 		}
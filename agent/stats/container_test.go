@@ -24,8 +24,10 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
 	mock_resolver "github.com/aws/amazon-ecs-agent/agent/stats/resolver/mock"
+	mock_ttime "github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 )
 
@@ -122,6 +124,92 @@ func TestContainerStatsCollection(t *testing.T) {
 	}
 }
 
+func TestContainerFilesystemUsageCollection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := ecsengine.NewMockDockerClient(ctrl)
+	mockTime := mock_ttime.NewMockTime(ctrl)
+
+	dockerID := "container1"
+	interval := 5 * time.Minute
+	container := newStatsContainer(dockerID, mockDockerClient, nil, interval)
+	container._time = mockTime
+	container.filesystemUsageQueue = newFilesystemUsageQueue(FilesystemUsageBufferLength)
+
+	// Every sample waits for the configured interval rather than the cadence
+	// of the stats stream
+	tick := make(chan time.Time)
+	mockTime.EXPECT().After(interval).Return(tick).AnyTimes()
+	sizes := []int64{100, 300}
+	for _, size := range sizes {
+		mockDockerClient.EXPECT().WritableLayerSize(dockerID, ecsengine.WritableLayerSizeTimeout).Return(size, nil)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		container.collectFilesystemUsage()
+		close(done)
+	}()
+	assert.Nil(t, container.getFilesystemUsageStatsSet(), "no samples before the interval elapses")
+	for range sizes {
+		tick <- time.Now()
+	}
+	container.StopStatsCollection()
+	<-done
+
+	statsSet := container.getFilesystemUsageStatsSet()
+	if assert.NotNil(t, statsSet) {
+		assert.Equal(t, int64(2), *statsSet.SampleCount)
+		assert.Equal(t, float64(100), *statsSet.Min)
+		assert.Equal(t, float64(300), *statsSet.Max)
+		assert.Equal(t, float64(400), *statsSet.Sum)
+	}
+	assert.Nil(t, container.getFilesystemUsageStatsSet(), "samples should only be reported once")
+}
+
+func TestContainerCPUThrottlingStatsCollection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockDockerClient := ecsengine.NewMockDockerClient(ctrl)
+
+	dockerID := "container1"
+	container := newStatsContainer(dockerID, mockDockerClient, nil, 0)
+	container.statsQueue = NewQueue(ContainerStatsBufferLength)
+	assert.Nil(t, container.getCPUThrottlingStats(), "no throttling stats before any are collected")
+
+	statChan := make(chan *docker.Stats, 2)
+	for i, throttled := range []uint64{2, 5} {
+		jsonStat := fmt.Sprintf(`
+			{
+				"cpu_stats":{
+					"cpu_usage":{
+						"percpu_usage":[%d],
+						"total_usage":%d
+					},
+					"throttling_data":{
+						"periods":%d,
+						"throttled_periods":%d,
+						"throttled_time":%d
+					}
+				}
+			}`, statsData[i].cpuTime, statsData[i].cpuTime, 10*(i+1), throttled, throttled*100000000)
+		dockerStat := &docker.Stats{}
+		json.Unmarshal([]byte(jsonStat), dockerStat)
+		dockerStat.Read = statsData[i].timestamp
+		statChan <- dockerStat
+	}
+	close(statChan)
+	mockDockerClient.EXPECT().Stats(dockerID, gomock.Any()).Return(statChan, nil)
+
+	assert.NoError(t, container.processStatsStream())
+	throttlingStats := container.getCPUThrottlingStats()
+	if assert.NotNil(t, throttlingStats) {
+		assert.Equal(t, int64(20), *throttlingStats.Periods)
+		assert.Equal(t, int64(5), *throttlingStats.ThrottledPeriods)
+		assert.Equal(t, int64(500000000), *throttlingStats.ThrottledTime)
+	}
+}
+
 func TestContainerStatsCollectionReconnection(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -64,6 +64,12 @@ type DockerStatsEngine struct {
 	tasksToContainers map[string]map[string]*StatsContainer
 	// tasksToDefinitions maps task arns to task definiton name and family metadata objects.
 	tasksToDefinitions map[string]*taskDefinition
+	// stoppingTasks maps the arns of the tasks whose containers have all been
+	// removed to the tasks, until their time to stop is reported
+	stoppingTasks map[string]*stoppingTask
+	// filesystemUsageInterval is the interval at which the writable layer
+	// size of containers is sampled, 0 if it is not sampled
+	filesystemUsageInterval time.Duration
 }
 
 var EmptyMetricsError = errors.New("No task metrics to report")
@@ -94,15 +100,35 @@ func (resolver *DockerContainerMetadataResolver) ResolveContainer(dockerID strin
 	return container, nil
 }
 
+// ResolveENIAttachment resolves the eni attachment, given the mac address of
+// the eni.
+func (resolver *DockerContainerMetadataResolver) ResolveENIAttachment(mac string) (*api.ENIAttachment, error) {
+	if resolver.dockerTaskEngine == nil {
+		return nil, fmt.Errorf("Docker task engine uninitialized")
+	}
+	eniAttachment, found := resolver.dockerTaskEngine.State().ENIByMac(mac)
+	if !found {
+		return nil, fmt.Errorf("Could not map mac address to eni attachment: %s", mac)
+	}
+
+	return eniAttachment, nil
+}
+
 // NewDockerStatsEngine creates a new instance of the DockerStatsEngine object.
 // MustInit() must be called to initialize the fields of the new event listener.
 func NewDockerStatsEngine(cfg *config.Config, client ecsengine.DockerClient, containerChangeEventStream *eventstream.EventStream) *DockerStatsEngine {
+	var filesystemUsageInterval time.Duration
+	if cfg.FilesystemMetricsEnabled {
+		filesystemUsageInterval = cfg.FilesystemMetricsInterval
+	}
 	return &DockerStatsEngine{
 		client:                     client,
 		resolver:                   nil,
 		tasksToContainers:          make(map[string]map[string]*StatsContainer),
 		tasksToDefinitions:         make(map[string]*taskDefinition),
+		stoppingTasks:              make(map[string]*stoppingTask),
 		containerChangeEventStream: containerChangeEventStream,
+		filesystemUsageInterval:    filesystemUsageInterval,
 	}
 }
 
@@ -185,7 +211,8 @@ func (engine *DockerStatsEngine) addExistingContainers() error {
 // GetInstanceMetrics gets all task metrics and instance metadata from stats engine.
 func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
 	var taskMetrics []*ecstcs.TaskMetric
-	idle := engine.isIdle()
+	stopMetrics := engine.getTaskStopMetrics()
+	idle := engine.isIdle() && len(stopMetrics) == 0
 	metricsMetadata := &ecstcs.MetricsMetadata{
 		Cluster:           aws.String(engine.cluster),
 		ContainerInstance: aws.String(engine.containerInstanceArn),
@@ -201,7 +228,7 @@ func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata,
 	}
 
 	for taskArn := range engine.tasksToContainers {
-		containerMetrics, err := engine.getContainerMetricsForTask(taskArn)
+		containerMetrics, aggregateMetric, err := engine.getContainerMetricsForTask(taskArn)
 		if err != nil {
 			seelog.Debugf("Error getting container metrics for task: %s, err: %v", taskArn, err)
 			continue
@@ -224,9 +251,15 @@ func (engine *DockerStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata,
 			TaskDefinitionFamily:  &taskDef.family,
 			TaskDefinitionVersion: &taskDef.version,
 			ContainerMetrics:      containerMetrics,
+			AggregateMetric:       aggregateMetric,
+			LaunchLatency:         engine.getLaunchLatencyForTask(taskArn),
+			EniReconciliationLag:  engine.getENIReconciliationLagForTask(taskArn),
+			ImagePulls:            engine.getImagePullsForTask(taskArn),
+			Tags:                  taskDef.metricTags(),
 		}
 		taskMetrics = append(taskMetrics, taskMetric)
 	}
+	taskMetrics = append(taskMetrics, stopMetrics...)
 
 	if len(taskMetrics) == 0 {
 		// Not idle. Expect taskMetrics to be there.
@@ -250,6 +283,11 @@ func (engine *DockerStatsEngine) isIdle() bool {
 // event that it reads from the docker event stream.
 func (engine *DockerStatsEngine) handleDockerEvents(events ...interface{}) error {
 	for _, event := range events {
+		if _, ok := event.(ecsengine.ContainerDriftEvent); ok {
+			// Drift events are informational; the corrective transition
+			// arrives as a regular container change event
+			continue
+		}
 		dockerContainerChangeEvent, ok := event.(ecsengine.DockerContainerChangeEvent)
 		if !ok {
 			return fmt.Errorf("Unexpected event received, expected docker container change event")
@@ -308,9 +346,9 @@ func (engine *DockerStatsEngine) addContainer(dockerID string) {
 	}
 
 	seelog.Debugf("Adding container to stats watch list, id: %s, task: %s", dockerID, task.Arn)
-	container := newStatsContainer(dockerID, engine.client, engine.resolver)
+	container := newStatsContainer(dockerID, engine.client, engine.resolver, engine.filesystemUsageInterval)
 	engine.tasksToContainers[task.Arn][dockerID] = container
-	engine.tasksToDefinitions[task.Arn] = &taskDefinition{family: task.Family, version: task.Version}
+	engine.tasksToDefinitions[task.Arn] = &taskDefinition{family: task.Family, version: task.Version, tags: task.Tags}
 	container.StartStatsCollection()
 }
 
@@ -359,17 +397,19 @@ func newDockerContainerMetadataResolver(taskEngine ecsengine.TaskEngine) (*Docke
 	return resolver, nil
 }
 
-// getContainerMetricsForTask gets all container metrics for a task arn.
-func (engine *DockerStatsEngine) getContainerMetricsForTask(taskArn string) ([]*ecstcs.ContainerMetric, error) {
+// getContainerMetricsForTask gets all container metrics for a task arn, and
+// the aggregate metric of the task.
+func (engine *DockerStatsEngine) getContainerMetricsForTask(taskArn string) ([]*ecstcs.ContainerMetric, *ecstcs.ContainerMetric, error) {
 	engine.containersLock.Lock()
 	defer engine.containersLock.Unlock()
 
 	containerMap, taskExists := engine.tasksToContainers[taskArn]
 	if !taskExists {
-		return nil, fmt.Errorf("Task not found")
+		return nil, nil, fmt.Errorf("Task not found")
 	}
 
 	var containerMetrics []*ecstcs.ContainerMetric
+	var containersUsageStats [][]UsageStats
 	for _, container := range containerMap {
 		dockerID := container.containerMetadata.DockerID
 		// Check if the container is terminal. If it is, make sure that it is
@@ -414,13 +454,154 @@ func (engine *DockerStatsEngine) getContainerMetricsForTask(taskArn string) ([]*
 		}
 
 		containerMetrics = append(containerMetrics, &ecstcs.ContainerMetric{
-			CpuStatsSet:    cpuStatsSet,
-			MemoryStatsSet: memoryStatsSet,
+			CpuStatsSet:             cpuStatsSet,
+			CpuThrottlingStats:      container.getCPUThrottlingStats(),
+			MemoryStatsSet:          memoryStatsSet,
+			FilesystemUsageStatsSet: container.getFilesystemUsageStatsSet(),
 		})
+		if usageStats, err := container.statsQueue.GetRawUsageStats(ContainerStatsBufferLength); err == nil {
+			containersUsageStats = append(containersUsageStats, usageStats)
+		}
+	}
+
+	return containerMetrics, aggregateUsageStats(containersUsageStats), nil
+}
 
+// metricTags returns the tags of the task in the format of the metrics
+func (taskDef *taskDefinition) metricTags() []*ecstcs.Tag {
+	if len(taskDef.tags) == 0 {
+		return nil
 	}
+	tags := make([]*ecstcs.Tag, 0, len(taskDef.tags))
+	for _, tag := range taskDef.tags {
+		tags = append(tags, &ecstcs.Tag{
+			Key:   aws.String(tag.Key),
+			Value: aws.String(tag.Value),
+		})
+	}
+	return tags
+}
+
+// getLaunchLatencyForTask gets the launch phase durations, in milliseconds,
+// of a task arn. It returns nil if the launch latency of the task is unknown
+// or has already been reported, as it is reported once per task.
+func (engine *DockerStatsEngine) getLaunchLatencyForTask(taskArn string) *ecstcs.TaskLaunchLatency {
+	engine.containersLock.RLock()
+	defer engine.containersLock.RUnlock()
 
-	return containerMetrics, nil
+	for dockerID := range engine.tasksToContainers[taskArn] {
+		task, err := engine.resolver.ResolveTask(dockerID)
+		if err != nil {
+			continue
+		}
+		latency, ok := task.TakeLaunchLatency()
+		if !ok {
+			return nil
+		}
+		return &ecstcs.TaskLaunchLatency{
+			QueueWait: aws.Float64(durationToMillis(latency.QueueWait)),
+			Pull:      aws.Float64(durationToMillis(latency.Pull)),
+			Create:    aws.Float64(durationToMillis(latency.Create)),
+			Start:     aws.Float64(durationToMillis(latency.Start)),
+			Total:     aws.Float64(durationToMillis(latency.Total())),
+		}
+	}
+	return nil
+}
+
+// getImagePullsForTask counts the image pulls of the containers of a task arn
+// that have not been reported yet, by where the images came from, so that each
+// pull is reported once. It returns nil if there are no such pulls
+func (engine *DockerStatsEngine) getImagePullsForTask(taskArn string) *ecstcs.TaskImagePulls {
+	engine.containersLock.RLock()
+	defer engine.containersLock.RUnlock()
+
+	for dockerID := range engine.tasksToContainers[taskArn] {
+		task, err := engine.resolver.ResolveTask(dockerID)
+		if err != nil {
+			continue
+		}
+		counts := make(map[api.ImagePullSource]int64)
+		for _, container := range task.Containers {
+			if source := container.TakeImagePullSource(); source != "" {
+				counts[source]++
+			}
+		}
+		if len(counts) == 0 {
+			return nil
+		}
+		return &ecstcs.TaskImagePulls{
+			CacheHit: aws.Int64(counts[api.ImagePullSourceCacheHit]),
+			Partial:  aws.Int64(counts[api.ImagePullSourcePartial]),
+			Registry: aws.Int64(counts[api.ImagePullSourceRegistry]),
+		}
+	}
+	return nil
+}
+
+// getENIReconciliationLagForTask gets the time, in milliseconds, between the
+// attachment of the eni of the task being received and its device being
+// confirmed on the instance. It returns nil if the task has no eni or the
+// device has not been confirmed
+func (engine *DockerStatsEngine) getENIReconciliationLagForTask(taskArn string) *float64 {
+	engine.containersLock.RLock()
+	defer engine.containersLock.RUnlock()
+
+	for dockerID := range engine.tasksToContainers[taskArn] {
+		task, err := engine.resolver.ResolveTask(dockerID)
+		if err != nil {
+			continue
+		}
+		eni := task.GetTaskENI()
+		if eni == nil {
+			return nil
+		}
+		eniAttachment, err := engine.resolver.ResolveENIAttachment(eni.MacAddress)
+		if err != nil {
+			return nil
+		}
+		lag, ok := eniAttachment.GetReconciliationLag()
+		if !ok {
+			return nil
+		}
+		return aws.Float64(durationToMillis(lag))
+	}
+	return nil
+}
+
+// getTaskStopMetrics gets the metrics carrying the time to stop, in
+// milliseconds, of the tasks that stopped since the last report. Tasks are
+// reported once they have stopped; the ones whose stop was not observed from
+// start to finish are dropped without being reported
+func (engine *DockerStatsEngine) getTaskStopMetrics() []*ecstcs.TaskMetric {
+	engine.containersLock.Lock()
+	defer engine.containersLock.Unlock()
+
+	var taskMetrics []*ecstcs.TaskMetric
+	for taskArn, stopping := range engine.stoppingTasks {
+		if stopping.task.GetKnownStatus() != api.TaskStopped {
+			continue
+		}
+		delete(engine.stoppingTasks, taskArn)
+		latency, ok := stopping.task.GetStopLatency()
+		if !ok {
+			continue
+		}
+		metricTaskArn := taskArn
+		taskMetrics = append(taskMetrics, &ecstcs.TaskMetric{
+			TaskArn:               &metricTaskArn,
+			TaskDefinitionFamily:  aws.String(stopping.definition.family),
+			TaskDefinitionVersion: aws.String(stopping.definition.version),
+			ContainerMetrics:      []*ecstcs.ContainerMetric{},
+			StopLatency:           aws.Float64(durationToMillis(latency)),
+			Tags:                  stopping.definition.metricTags(),
+		})
+	}
+	return taskMetrics
+}
+
+func durationToMillis(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
 }
 
 func (engine *DockerStatsEngine) doRemoveContainer(container *StatsContainer, taskArn string) {
@@ -430,6 +611,12 @@ func (engine *DockerStatsEngine) doRemoveContainer(container *StatsContainer, ta
 	seelog.Debugf("Deleted container from tasks, id: %s", dockerID)
 
 	if len(engine.tasksToContainers[taskArn]) == 0 {
+		// Hold on to the task until it stops to report its time to stop
+		if taskDef, ok := engine.tasksToDefinitions[taskArn]; ok {
+			if task, err := engine.resolver.ResolveTask(dockerID); err == nil {
+				engine.stoppingTasks[taskArn] = &stoppingTask{task: task, definition: taskDef}
+			}
+		}
 		// No containers in task, delete task arn from map.
 		delete(engine.tasksToContainers, taskArn)
 		// No need to verify if the key exists in tasksToDefinitions.
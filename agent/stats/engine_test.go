@@ -17,12 +17,18 @@ package stats
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/amazon-ecs-agent/agent/api"
 	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
 	mock_resolver "github.com/aws/amazon-ecs-agent/agent/stats/resolver/mock"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime/mocks"
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestStatsEngineAddRemoveContainers(t *testing.T) {
@@ -83,7 +89,7 @@ func TestStatsEngineAddRemoveContainers(t *testing.T) {
 	}
 
 	// Ensure task shows up in metrics.
-	containerMetrics, err := engine.getContainerMetricsForTask("t1")
+	containerMetrics, _, err := engine.getContainerMetricsForTask("t1")
 	if err != nil {
 		t.Errorf("Error getting container metrics: %v", err)
 	}
@@ -113,7 +119,7 @@ func TestStatsEngineAddRemoveContainers(t *testing.T) {
 	}
 
 	// Ensure that only valid task shows up in metrics.
-	_, err = engine.getContainerMetricsForTask("t2")
+	_, _, err = engine.getContainerMetricsForTask("t2")
 	if err == nil {
 		t.Error("Expected non-empty error for non existent task")
 	}
@@ -214,6 +220,343 @@ func TestStatsEngineMetadataInStatsSets(t *testing.T) {
 	}
 }
 
+func TestStatsEngineTaskTagsInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	t1 := &api.Task{Arn: "t1", Family: "f1", Tags: []api.TaskTag{{Key: "team", Value: "web"}}}
+	t2 := &api.Task{Arn: "t2", Family: "f2"}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveTask("c2").AnyTimes().Return(t2, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineTaskTagsInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+	engine.addContainer("c2")
+	containerStats := []*ContainerStats{
+		{22400432, 1839104, parseNanoTime("2015-02-12T21:22:05.131117533Z")},
+		{116499979, 3649536, parseNanoTime("2015-02-12T21:22:05.232291187Z")},
+	}
+	for _, taskArn := range []string{"t1", "t2"} {
+		for _, statsContainer := range engine.tasksToContainers[taskArn] {
+			for i := 0; i < 2; i++ {
+				statsContainer.statsQueue.Add(containerStats[i])
+			}
+		}
+	}
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 2)
+
+	tags := make(map[string][]*ecstcs.Tag)
+	for _, taskMetric := range taskMetrics {
+		tags[*taskMetric.TaskArn] = taskMetric.Tags
+	}
+	require.Len(t, tags["t1"], 1)
+	assert.Equal(t, "team", *tags["t1"][0].Key)
+	assert.Equal(t, "web", *tags["t1"][0].Value)
+	assert.Empty(t, tags["t2"], "untagged tasks should have no tags")
+}
+
+func TestStatsEngineCPUThrottlingStatsInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	t1 := &api.Task{Arn: "t1", Family: "f1"}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveTask("c2").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineCPUThrottlingStatsInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+	engine.addContainer("c2")
+	for _, statsContainer := range engine.tasksToContainers["t1"] {
+		for _, fakeContainerStats := range createFakeContainerStats() {
+			statsContainer.statsQueue.Add(fakeContainerStats)
+		}
+	}
+	// Only c1 has a CPU quota
+	engine.tasksToContainers["t1"]["c1"].setCPUThrottlingData(cpuThrottlingData{
+		periods:          100,
+		throttledPeriods: 7,
+		throttledTime:    350000000,
+	})
+
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	require.Len(t, taskMetrics[0].ContainerMetrics, 2)
+
+	var throttlingStats []*ecstcs.CPUThrottlingStats
+	for _, containerMetric := range taskMetrics[0].ContainerMetrics {
+		if containerMetric.CpuThrottlingStats != nil {
+			throttlingStats = append(throttlingStats, containerMetric.CpuThrottlingStats)
+		}
+	}
+	require.Len(t, throttlingStats, 1, "only containers with a CPU quota should report throttling stats")
+	assert.Equal(t, int64(100), *throttlingStats[0].Periods)
+	assert.Equal(t, int64(7), *throttlingStats[0].ThrottledPeriods)
+	assert.Equal(t, int64(350000000), *throttlingStats[0].ThrottledTime)
+}
+
+func TestStatsEngineTaskAggregateInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	t1 := &api.Task{Arn: "t1", Family: "f1"}
+	for _, dockerID := range []string{"c1", "c2", "c3"} {
+		resolver.EXPECT().ResolveTask(dockerID).AnyTimes().Return(t1, nil)
+	}
+	resolver.EXPECT().ResolveContainer("c1").AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{KnownStatusUnsafe: api.ContainerRunning},
+	}, nil)
+	resolver.EXPECT().ResolveContainer("c2").AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{KnownStatusUnsafe: api.ContainerRunning},
+	}, nil)
+	// c3 stopped within the lifetime of the task
+	resolver.EXPECT().ResolveContainer("c3").AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{KnownStatusUnsafe: api.ContainerStopped},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineTaskAggregateInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+	engine.addContainer("c2")
+	engine.addContainer("c3")
+	for _, statsContainer := range engine.tasksToContainers["t1"] {
+		for _, fakeContainerStats := range createFakeContainerStats() {
+			statsContainer.statsQueue.Add(fakeContainerStats)
+		}
+	}
+
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	require.Len(t, taskMetrics[0].ContainerMetrics, 2, "stopped containers should not report metrics")
+	aggregate := taskMetrics[0].AggregateMetric
+	require.NotNil(t, aggregate)
+
+	// Both samples of the containers are taken within the same second, so
+	// the usage of the task is the sum of the last sample of each container.
+	// The first sample of a container has no CPU usage
+	var cpuSum float64
+	for _, containerMetric := range taskMetrics[0].ContainerMetrics {
+		cpuSum += *containerMetric.CpuStatsSet.Sum
+	}
+	memorySum := float64(2 * (3649536 / BytesInMiB))
+	assert.Equal(t, statsSet(cpuSum, cpuSum, cpuSum, 1), aggregate.CpuStatsSet)
+	assert.Equal(t, statsSet(memorySum, memorySum, memorySum, 1), aggregate.MemoryStatsSet)
+}
+
+func TestStatsEngineENIReconciliationLagInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	t1 := &api.Task{Arn: "t1", Family: "f1"}
+	t1.SetTaskENI(&api.ENI{ID: "eni-1", MacAddress: "mac1"})
+	receivedAt := time.Now()
+	eniAttachment := &api.ENIAttachment{
+		TaskARN:    "t1",
+		MACAddress: "mac1",
+		ReceivedAt: receivedAt,
+	}
+	eniAttachment.SetAttachedAt(receivedAt.Add(1500 * time.Millisecond))
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{},
+	}, nil)
+	resolver.EXPECT().ResolveENIAttachment("mac1").AnyTimes().Return(eniAttachment, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineENIReconciliationLagInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+	for _, fakeContainerStats := range createFakeContainerStats() {
+		engine.tasksToContainers["t1"]["c1"].statsQueue.Add(fakeContainerStats)
+	}
+
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	require.NotNil(t, taskMetrics[0].EniReconciliationLag)
+	assert.Equal(t, float64(1500), *taskMetrics[0].EniReconciliationLag)
+}
+
+func TestStatsEngineLaunchLatencyReportedOnce(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	t1 := &api.Task{Arn: "t1", Family: "f1"}
+	receivedAt := time.Now()
+	t1.RecordLaunchMilestone(api.TaskLaunchReceived, receivedAt)
+	t1.RecordLaunchMilestone(api.TaskLaunchRunning, receivedAt.Add(2*time.Second))
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineLaunchLatencyReportedOnce"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+
+	for i := 0; i < 2; i++ {
+		for _, fakeContainerStats := range createFakeContainerStats() {
+			engine.tasksToContainers["t1"]["c1"].statsQueue.Add(fakeContainerStats)
+		}
+		_, taskMetrics, err := engine.GetInstanceMetrics()
+		require.NoError(t, err)
+		require.Len(t, taskMetrics, 1)
+		if i == 0 {
+			require.NotNil(t, taskMetrics[0].LaunchLatency)
+			assert.Equal(t, float64(2000), *taskMetrics[0].LaunchLatency.Total)
+		} else {
+			assert.Nil(t, taskMetrics[0].LaunchLatency, "launch latency should be reported once")
+		}
+	}
+}
+
+func TestStatsEngineImagePullsInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	cached := &api.Container{Name: "cached"}
+	cached.SetImagePullSource(api.ImagePullSourceCacheHit)
+	pulled := &api.Container{Name: "pulled"}
+	pulled.SetImagePullSource(api.ImagePullSourceRegistry)
+	t1 := &api.Task{Arn: "t1", Family: "f1", Containers: []*api.Container{cached, pulled, {Name: "pending"}}}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	resolver.EXPECT().ResolveContainer(gomock.Any()).AnyTimes().Return(&api.DockerContainer{
+		Container: &api.Container{},
+	}, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineImagePullsInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+	for _, fakeContainerStats := range createFakeContainerStats() {
+		engine.tasksToContainers["t1"]["c1"].statsQueue.Add(fakeContainerStats)
+	}
+
+	_, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	require.NotNil(t, taskMetrics[0].ImagePulls)
+	assert.Equal(t, int64(1), *taskMetrics[0].ImagePulls.CacheHit)
+	assert.Equal(t, int64(0), *taskMetrics[0].ImagePulls.Partial)
+	assert.Equal(t, int64(1), *taskMetrics[0].ImagePulls.Registry)
+
+	// Each pull is reported once
+	for _, fakeContainerStats := range createFakeContainerStats() {
+		engine.tasksToContainers["t1"]["c1"].statsQueue.Add(fakeContainerStats)
+	}
+	_, taskMetrics, err = engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	require.Len(t, taskMetrics, 1)
+	assert.Nil(t, taskMetrics[0].ImagePulls)
+}
+
+func TestStatsEngineTaskStopLatencyInMetrics(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	resolver := mock_resolver.NewMockContainerMetadataResolver(mockCtrl)
+	mockDockerClient := ecsengine.NewMockDockerClient(mockCtrl)
+	mockTime := mock_ttime.NewMockTime(mockCtrl)
+	ttime.SetTime(mockTime)
+	defer ttime.SetTime(&ttime.DefaultTime{})
+
+	t1 := &api.Task{
+		Arn:                 "t1",
+		Family:              "f1",
+		Version:             "1",
+		DesiredStatusUnsafe: api.TaskRunning,
+		KnownStatusUnsafe:   api.TaskRunning,
+	}
+	resolver.EXPECT().ResolveTask("c1").AnyTimes().Return(t1, nil)
+	mockDockerClient.EXPECT().Stats(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	stopRequested := time.Now()
+	gomock.InOrder(
+		mockTime.EXPECT().Now().Return(stopRequested),
+		mockTime.EXPECT().Now().Return(stopRequested.Add(3*time.Second)),
+	)
+
+	engine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineTaskStopLatencyInMetrics"))
+	engine.resolver = resolver
+	engine.cluster = defaultCluster
+	engine.containerInstanceArn = defaultContainerInstance
+	engine.client = mockDockerClient
+	engine.addContainer("c1")
+
+	t1.SetDesiredStatus(api.TaskStopped)
+	engine.removeContainer("c1")
+	metadata, taskMetrics, err := engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	assert.True(t, *metadata.Idle)
+	assert.Empty(t, taskMetrics, "time to stop should only be reported once the task stopped")
+
+	t1.SetKnownStatus(api.TaskStopped)
+	metadata, taskMetrics, err = engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	assert.False(t, *metadata.Idle)
+	require.Len(t, taskMetrics, 1)
+	assert.Equal(t, "t1", *taskMetrics[0].TaskArn)
+	assert.Equal(t, "f1", *taskMetrics[0].TaskDefinitionFamily)
+	assert.Equal(t, float64(3000), *taskMetrics[0].StopLatency)
+	assert.Empty(t, taskMetrics[0].ContainerMetrics)
+
+	metadata, _, err = engine.GetInstanceMetrics()
+	require.NoError(t, err)
+	assert.True(t, *metadata.Idle, "time to stop should only be reported once")
+}
+
+func TestStatsEngineFilesystemUsageInterval(t *testing.T) {
+	fsCfg := cfg
+	fsCfg.FilesystemMetricsInterval = 10 * time.Minute
+	engine := NewDockerStatsEngine(&fsCfg, nil, eventStream("TestStatsEngineFilesystemUsageDisabled"))
+	if engine.filesystemUsageInterval != 0 {
+		t.Errorf("Expected filesystem usage sampling to be disabled, got interval: %v", engine.filesystemUsageInterval)
+	}
+
+	fsCfg.FilesystemMetricsEnabled = true
+	engine = NewDockerStatsEngine(&fsCfg, nil, eventStream("TestStatsEngineFilesystemUsageEnabled"))
+	if engine.filesystemUsageInterval != 10*time.Minute {
+		t.Errorf("Expected filesystem usage sampling interval of 10m, got: %v", engine.filesystemUsageInterval)
+	}
+}
+
 func TestStatsEngineInvalidTaskEngine(t *testing.T) {
 	statsEngine := NewDockerStatsEngine(&cfg, nil, eventStream("TestStatsEngineInvalidTaskEngine"))
 	taskEngine := &MockTaskEngine{}
@@ -0,0 +1,80 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+// FilesystemUsageBufferLength is the number of writable layer size samples
+// stored in memory for a container. Samples are taken every few minutes, so
+// this covers far longer than the interval between metrics publications.
+const FilesystemUsageBufferLength = 10
+
+// filesystemUsageQueue holds the writable layer sizes, in bytes, sampled for a
+// container since the last time they were reported
+type filesystemUsageQueue struct {
+	samples []float64
+	maxSize int
+	lock    sync.Mutex
+}
+
+func newFilesystemUsageQueue(maxSize int) *filesystemUsageQueue {
+	return &filesystemUsageQueue{maxSize: maxSize}
+}
+
+// add appends a sample to the queue, dropping the oldest sample if the queue
+// is full
+func (queue *filesystemUsageQueue) add(sizeInBytes int64) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	if len(queue.samples) == queue.maxSize {
+		queue.samples = queue.samples[1:]
+	}
+	queue.samples = append(queue.samples, float64(sizeInBytes))
+}
+
+// getCWStatsSet gets the stats set for the samples in the queue and empties it
+// so that each sample is only reported once
+func (queue *filesystemUsageQueue) getCWStatsSet() (*ecstcs.CWStatsSet, error) {
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+
+	if len(queue.samples) == 0 {
+		return nil, fmt.Errorf("No filesystem usage samples in the queue")
+	}
+
+	min := math.MaxFloat64
+	max := -math.MaxFloat64
+	var sum float64
+	for _, sample := range queue.samples {
+		min = math.Min(min, sample)
+		max = math.Max(max, sample)
+		sum += sample
+	}
+	sampleCount := int64(len(queue.samples))
+	queue.samples = nil
+
+	return &ecstcs.CWStatsSet{
+		Max:         &max,
+		Min:         &min,
+		SampleCount: &sampleCount,
+		Sum:         &sum,
+	}, nil
+}
@@ -63,3 +63,14 @@ func (_m *MockContainerMetadataResolver) ResolveTask(_param0 string) (*api.Task,
 func (_mr *_MockContainerMetadataResolverRecorder) ResolveTask(arg0 interface{}) *gomock.Call {
 	return _mr.mock.ctrl.RecordCall(_mr.mock, "ResolveTask", arg0)
 }
+
+func (_m *MockContainerMetadataResolver) ResolveENIAttachment(_param0 string) (*api.ENIAttachment, error) {
+	ret := _m.ctrl.Call(_m, "ResolveENIAttachment", _param0)
+	ret0, _ := ret[0].(*api.ENIAttachment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+func (_mr *_MockContainerMetadataResolverRecorder) ResolveENIAttachment(arg0 interface{}) *gomock.Call {
+	return _mr.mock.ctrl.RecordCall(_mr.mock, "ResolveENIAttachment", arg0)
+}
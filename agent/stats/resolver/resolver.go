@@ -21,4 +21,5 @@ import "github.com/aws/amazon-ecs-agent/agent/api"
 type ContainerMetadataResolver interface {
 	ResolveTask(string) (*api.Task, error)
 	ResolveContainer(string) (*api.DockerContainer, error)
+	ResolveENIAttachment(string) (*api.ENIAttachment, error)
 }
@@ -0,0 +1,86 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"math"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+)
+
+// aggregateUsageStats returns the CPU and memory usage of a task from the
+// samples of the containers it has metrics for. Containers that stopped, or
+// that started too recently to have enough samples, are not part of the
+// metrics and so are not counted either
+func aggregateUsageStats(containersUsageStats [][]UsageStats) *ecstcs.ContainerMetric {
+	return &ecstcs.ContainerMetric{
+		CpuStatsSet:    aggregateStatsSet(containersUsageStats, getCPUUsagePerc),
+		MemoryStatsSet: aggregateStatsSet(containersUsageStats, getMemoryUsagePerc),
+	}
+}
+
+// aggregateStatsSet returns the stats set of the usage of a task. Docker
+// samples the stats of each container about once a second, so the samples of
+// the containers are aligned on the second of their timestamp: the usage of
+// the task at a second is the sum of the samples of its containers at that
+// second. Seconds that some of the containers have no sample for are skipped,
+// as the usage of the task is not known then. The minimum, maximum, sum and
+// sample count are those of the usage of the task at each of the seconds
+func aggregateStatsSet(containersUsageStats [][]UsageStats, f getUsageFunc) *ecstcs.CWStatsSet {
+	if len(containersUsageStats) == 0 {
+		return nil
+	}
+	taskUsage := make(map[int64]float64)
+	sampledContainers := make(map[int64]int)
+	for _, usageStats := range containersUsageStats {
+		sampled := make(map[int64]bool)
+		for i := range usageStats {
+			usage := f(&usageStats[i])
+			if math.IsNaN(usage) || math.IsInf(usage, 0) {
+				continue
+			}
+			// Keep a single sample of the container per second
+			second := usageStats[i].Timestamp.Unix()
+			if sampled[second] {
+				continue
+			}
+			sampled[second] = true
+			taskUsage[second] += usage
+			sampledContainers[second]++
+		}
+	}
+
+	min := math.MaxFloat64
+	max := -math.MaxFloat64
+	var sum float64
+	var sampleCount int64
+	for second, usage := range taskUsage {
+		if sampledContainers[second] != len(containersUsageStats) {
+			continue
+		}
+		min = math.Min(min, usage)
+		max = math.Max(max, usage)
+		sum += usage
+		sampleCount++
+	}
+	if sampleCount == 0 {
+		return nil
+	}
+	return &ecstcs.CWStatsSet{
+		Min:         &min,
+		Max:         &max,
+		Sum:         &sum,
+		SampleCount: &sampleCount,
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func statsSet(min, max, sum float64, sampleCount int64) *ecstcs.CWStatsSet {
+	return &ecstcs.CWStatsSet{
+		Min:         aws.Float64(min),
+		Max:         aws.Float64(max),
+		Sum:         aws.Float64(sum),
+		SampleCount: aws.Int64(sampleCount),
+	}
+}
+
+func usageStat(timestamp time.Time, cpu float32, memory uint32) UsageStats {
+	return UsageStats{Timestamp: timestamp, CPUUsagePerc: cpu, MemoryUsageInMegs: memory}
+}
+
+func TestAggregateUsageStats(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	aggregate := aggregateUsageStats([][]UsageStats{
+		{
+			usageStat(start.Add(100*time.Millisecond), float32(math.NaN()), 100),
+			usageStat(start.Add(1100*time.Millisecond), 10, 110),
+			usageStat(start.Add(2100*time.Millisecond), 30, 120),
+			usageStat(start.Add(3100*time.Millisecond), 20, 130),
+		},
+		// A container that started within the window has fewer samples,
+		// which are taken at other times within each second
+		{
+			usageStat(start.Add(2900*time.Millisecond), 5, 50),
+			usageStat(start.Add(3900*time.Millisecond), 15, 60),
+		},
+	})
+
+	// The usage of the task is only known at the seconds both containers
+	// have a sample for: 35% and 35% of CPU, 170MiB and 190MiB of memory
+	assert.Equal(t, statsSet(35, 35, 70, 2), aggregate.CpuStatsSet)
+	assert.Equal(t, statsSet(170, 190, 360, 2), aggregate.MemoryStatsSet)
+}
+
+func TestAggregateUsageStatsMinMax(t *testing.T) {
+	start := time.Unix(1500000000, 0)
+	aggregate := aggregateUsageStats([][]UsageStats{
+		{usageStat(start, 10, 100), usageStat(start.Add(time.Second), 50, 100)},
+		{usageStat(start, 40, 100), usageStat(start.Add(time.Second), 0, 100)},
+	})
+
+	// The containers peak at different times, so the peak of the task is
+	// lower than the sum of the peaks of the containers
+	assert.Equal(t, statsSet(50, 50, 100, 2), aggregate.CpuStatsSet)
+	assert.Equal(t, statsSet(200, 200, 400, 2), aggregate.MemoryStatsSet)
+}
+
+func TestAggregateUsageStatsWithoutStats(t *testing.T) {
+	aggregate := aggregateUsageStats(nil)
+	require.NotNil(t, aggregate)
+	assert.Nil(t, aggregate.CpuStatsSet)
+	assert.Nil(t, aggregate.MemoryStatsSet)
+
+	start := time.Unix(1500000000, 0)
+	aggregate = aggregateUsageStats([][]UsageStats{
+		{usageStat(start, 10, 100)},
+		{usageStat(start.Add(time.Second), 10, 100)},
+	})
+	assert.Nil(t, aggregate.CpuStatsSet, "no second has a sample of every container")
+	assert.Nil(t, aggregate.MemoryStatsSet)
+}
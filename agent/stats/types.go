@@ -14,10 +14,13 @@
 package stats
 
 import (
+	"sync"
 	"time"
 
+	"github.com/aws/amazon-ecs-agent/agent/api"
 	ecsengine "github.com/aws/amazon-ecs-agent/agent/engine"
 	"github.com/aws/amazon-ecs-agent/agent/stats/resolver"
+	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"golang.org/x/net/context"
 )
 
@@ -28,6 +31,19 @@ type ContainerStats struct {
 	timestamp   time.Time
 }
 
+// cpuThrottlingData holds the cumulative CPU throttling counters of the cgroup
+// of a container, as read from its cpu.stat file
+type cpuThrottlingData struct {
+	// periods is the number of enforcement periods of the CPU quota elapsed
+	periods uint64
+	// throttledPeriods is the number of periods in which the container was
+	// throttled (nr_throttled)
+	throttledPeriods uint64
+	// throttledTime is the total time, in nanoseconds, for which the
+	// container was throttled (throttled_time)
+	throttledTime uint64
+}
+
 // UsageStats abstracts the format in which the queue stores data.
 type UsageStats struct {
 	CPUUsagePerc      float32   `json:"cpuUsagePerc"`
@@ -49,10 +65,30 @@ type StatsContainer struct {
 	client            ecsengine.DockerClient
 	statsQueue        *Queue
 	resolver          resolver.ContainerMetadataResolver
+	// filesystemUsageInterval is the interval at which the writable layer
+	// size of the container is sampled. Sampling is disabled when it is 0
+	filesystemUsageInterval time.Duration
+	filesystemUsageQueue    *filesystemUsageQueue
+	// throttling holds the most recent CPU throttling counters reported for
+	// the container
+	throttling     cpuThrottlingData
+	throttlingLock sync.RWMutex
+
+	_time     ttime.Time
+	_timeOnce sync.Once
 }
 
-// taskDefinition encapsulates family and version strings for a task definition
+// taskDefinition encapsulates family and version strings for a task definition,
+// along with the tags of the task
 type taskDefinition struct {
 	family  string
 	version string
+	tags    []api.TaskTag
+}
+
+// stoppingTask is a task whose containers are no longer watched, kept until
+// it stops so that its time to stop can be reported
+type stoppingTask struct {
+	task       *api.Task
+	definition *taskDefinition
 }
@@ -52,6 +52,16 @@ func dockerStatsToContainerStats(dockerStats *docker.Stats) (*ContainerStats, er
 	}, nil
 }
 
+// dockerStatsToCPUThrottlingData returns the CPU throttling counters of the
+// container's cgroup from docker stats
+func dockerStatsToCPUThrottlingData(dockerStats *docker.Stats) cpuThrottlingData {
+	return cpuThrottlingData{
+		periods:          dockerStats.CPUStats.ThrottlingData.Periods,
+		throttledPeriods: dockerStats.CPUStats.ThrottlingData.ThrottledPeriods,
+		throttledTime:    dockerStats.CPUStats.ThrottlingData.ThrottledTime,
+	}
+}
+
 // parseNanoTime returns the time object from a string formatted with RFC3339Nano layout.
 func parseNanoTime(value string) time.Time {
 	ts, _ := time.Parse(time.RFC3339Nano, value)
@@ -40,22 +40,27 @@ type clientServer struct {
 	publishTicker          *time.Ticker
 	endPublish             chan struct{}
 	publishMetricsInterval time.Duration
+	metricsBuffer          *MetricsBuffer
 	wsclient.ClientServerImpl
 }
 
 // New returns a client/server to bidirectionally communicate with the backend.
 // The returned struct should have both 'Connect' and 'Serve' called upon it
-// before being used.
+// before being used. Metrics that cannot be published are held in the
+// metrics buffer, if one is given, and are published ahead of newer metrics
+// once the connection is available again.
 func New(url string,
 	cfg *config.Config,
 	credentialProvider *credentials.Credentials,
 	statsEngine stats.Engine,
+	metricsBuffer *MetricsBuffer,
 	publishMetricsInterval time.Duration,
 	rwTimeout time.Duration) wsclient.ClientServer {
 	cs := &clientServer{
 		statsEngine:            statsEngine,
 		publishTicker:          nil,
 		publishMetricsInterval: publishMetricsInterval,
+		metricsBuffer:          metricsBuffer,
 	}
 	cs.URL = url
 	cs.AgentConfig = cfg
@@ -162,26 +167,33 @@ func (cs *clientServer) publishMetrics() {
 
 // publishMetricsOnce is invoked by the ticker to periodically publish metrics to backend.
 func (cs *clientServer) publishMetricsOnce() error {
+	// Metrics buffered while the connection was unavailable are sent first,
+	// so that the backend receives them in order
+	requests := cs.metricsBuffer.Drain()
+
 	// Get the list of objects to send to backend.
-	requests, err := cs.metricsToPublishMetricRequests()
-	if err != nil {
-		return err
-	}
+	metricsRequests, err := cs.metricsToPublishMetricRequests()
+	requests = append(requests, metricsRequests...)
 
 	// Make the publish metrics request to the backend.
-	for _, request := range requests {
-		err = cs.MakeRequest(request)
-		if err != nil {
-			return err
+	for i, request := range requests {
+		requestErr := cs.MakeRequest(request)
+		if requestErr != nil {
+			cs.metricsBuffer.Add(requests[i:]...)
+			return requestErr
 		}
 	}
-	return nil
+	return err
 }
 
 // metricsToPublishMetricRequests gets task metrics and converts them to a list of PublishMetricRequest
 // objects.
 func (cs *clientServer) metricsToPublishMetricRequests() ([]*ecstcs.PublishMetricsRequest, error) {
-	metadata, taskMetrics, err := cs.statsEngine.GetInstanceMetrics()
+	return metricsToPublishMetricRequests(cs.statsEngine)
+}
+
+func metricsToPublishMetricRequests(statsEngine stats.Engine) ([]*ecstcs.PublishMetricsRequest, error) {
+	metadata, taskMetrics, err := statsEngine.GetInstanceMetrics()
 	if err != nil {
 		return nil, err
 	}
@@ -209,7 +209,7 @@ func testCS(conn *mock_wsclient.MockWebsocketConn) wsclient.ClientServer {
 		AWSRegion:          "us-east-1",
 		AcceptInsecureCert: true,
 	}
-	cs := New("localhost:443", cfg, testCreds, &mockStatsEngine{},
+	cs := New("localhost:443", cfg, testCreds, &mockStatsEngine{}, nil,
 		testPublishMetricsInterval, rwTimeout).(*clientServer)
 	cs.SetConnection(conn)
 	return cs
@@ -0,0 +1,93 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcsclient
+
+import (
+	"sync"
+
+	"github.com/aws/amazon-ecs-agent/agent/stats"
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/cihub/seelog"
+)
+
+// MetricsBuffer holds a bounded number of publish metrics requests that could
+// not be sent to the backend. When the buffer is full, the oldest requests are
+// dropped first. A nil MetricsBuffer holds nothing.
+type MetricsBuffer struct {
+	size     int
+	requests []*ecstcs.PublishMetricsRequest
+	lock     sync.Mutex
+}
+
+// NewMetricsBuffer returns a MetricsBuffer holding up to size requests
+func NewMetricsBuffer(size int) *MetricsBuffer {
+	return &MetricsBuffer{size: size}
+}
+
+// Add appends requests to the buffer, dropping the oldest requests if the
+// buffer is full
+func (buffer *MetricsBuffer) Add(requests ...*ecstcs.PublishMetricsRequest) {
+	if buffer == nil || buffer.size <= 0 {
+		return
+	}
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.requests = append(buffer.requests, requests...)
+	if dropped := len(buffer.requests) - buffer.size; dropped > 0 {
+		seelog.Warnf("Telemetry metrics buffer is full, dropping %d oldest metrics requests", dropped)
+		buffer.requests = buffer.requests[dropped:]
+	}
+}
+
+// Collect gets the current metrics from the stats engine and adds them to the
+// buffer. It is used to retain metrics while there is no connection to the
+// backend to publish them on.
+func (buffer *MetricsBuffer) Collect(statsEngine stats.Engine) error {
+	if buffer == nil || buffer.size <= 0 {
+		// Getting the metrics resets them in the stats engine, leave them
+		// to be published with the next request instead
+		return nil
+	}
+	requests, err := metricsToPublishMetricRequests(statsEngine)
+	if err != nil {
+		return err
+	}
+	buffer.Add(requests...)
+	return nil
+}
+
+// Drain removes and returns all requests in the buffer, oldest first
+func (buffer *MetricsBuffer) Drain() []*ecstcs.PublishMetricsRequest {
+	if buffer == nil {
+		return nil
+	}
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	requests := buffer.requests
+	buffer.requests = nil
+	return requests
+}
+
+// Len returns the number of requests in the buffer
+func (buffer *MetricsBuffer) Len() int {
+	if buffer == nil {
+		return 0
+	}
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	return len(buffer.requests)
+}
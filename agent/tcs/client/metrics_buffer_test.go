@@ -0,0 +1,107 @@
+// Copyright 2014-2017 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+
+package tcsclient
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
+	"github.com/aws/amazon-ecs-agent/agent/wsclient/mock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testPublishMetricsRequest(messageId string) *ecstcs.PublishMetricsRequest {
+	return &ecstcs.PublishMetricsRequest{
+		Metadata: &ecstcs.MetricsMetadata{MessageId: aws.String(messageId)},
+	}
+}
+
+func TestMetricsBufferDropsOldest(t *testing.T) {
+	buffer := NewMetricsBuffer(2)
+	buffer.Add(testPublishMetricsRequest("1"), testPublishMetricsRequest("2"))
+	buffer.Add(testPublishMetricsRequest("3"))
+
+	requests := buffer.Drain()
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "2", *requests[0].Metadata.MessageId)
+	assert.Equal(t, "3", *requests[1].Metadata.MessageId)
+	assert.Zero(t, buffer.Len())
+}
+
+func TestMetricsBufferDisabled(t *testing.T) {
+	buffer := NewMetricsBuffer(0)
+	buffer.Add(testPublishMetricsRequest("1"))
+	assert.Zero(t, buffer.Len())
+	assert.NoError(t, buffer.Collect(&mockStatsEngine{}), "metrics should not be gathered")
+
+	var nilBuffer *MetricsBuffer
+	nilBuffer.Add(testPublishMetricsRequest("1"))
+	assert.Empty(t, nilBuffer.Drain())
+}
+
+func TestMetricsBufferCollect(t *testing.T) {
+	buffer := NewMetricsBuffer(10)
+	assert.NoError(t, buffer.Collect(&idleStatsEngine{}))
+	assert.Equal(t, 1, buffer.Len())
+	assert.Error(t, buffer.Collect(&emptyStatsEngine{}))
+	assert.Equal(t, 1, buffer.Len())
+}
+
+func TestPublishMetricsOnceFlushesBuffer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock_wsclient.NewMockWebsocketConn(ctrl)
+	cs := testCS(conn).(*clientServer)
+	cs.statsEngine = &idleStatsEngine{}
+	cs.metricsBuffer = NewMetricsBuffer(10)
+	cs.metricsBuffer.Add(testPublishMetricsRequest("buffered"))
+
+	var written []string
+	conn.EXPECT().SetWriteDeadline(gomock.Any()).Return(nil).AnyTimes()
+	conn.EXPECT().WriteMessage(gomock.Any(), gomock.Any()).Do(func(messageType int, data []byte) {
+		written = append(written, string(data))
+	}).Return(nil).Times(2)
+
+	err := cs.publishMetricsOnce()
+	assert.NoError(t, err)
+	assert.Len(t, written, 2)
+	assert.Contains(t, written[0], `"messageId":"buffered"`, "buffered metrics should be published first")
+	assert.Contains(t, written[1], `"messageId":"`+testMessageId+`"`)
+	assert.Zero(t, cs.metricsBuffer.Len())
+}
+
+func TestPublishMetricsOnceBuffersUnsentMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn := mock_wsclient.NewMockWebsocketConn(ctrl)
+	cs := testCS(conn).(*clientServer)
+	cs.statsEngine = &idleStatsEngine{}
+	cs.metricsBuffer = NewMetricsBuffer(10)
+	cs.metricsBuffer.Add(testPublishMetricsRequest("buffered"))
+
+	conn.EXPECT().SetWriteDeadline(gomock.Any()).Return(nil).AnyTimes()
+	conn.EXPECT().WriteMessage(gomock.Any(), gomock.Any()).Return(errors.New("connection reset"))
+
+	err := cs.publishMetricsOnce()
+	assert.Error(t, err)
+	requests := cs.metricsBuffer.Drain()
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "buffered", *requests[0].Metadata.MessageId)
+	assert.Equal(t, testMessageId, *requests[1].Metadata.MessageId)
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/cihub/seelog"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -74,28 +75,53 @@ func StartMetricsSession(params TelemetrySessionParams) {
 // using the passed in arguments.
 // The engine is expected to initialized and gathering container metrics by
 // the time the websocket client starts using it.
+// The session is re-established with a jittered backoff when it is lost,
+// unless reconnecting is disabled in the config. Metrics gathered while
+// disconnected are buffered and published once the session is back.
+// It stops reconnecting once the context of the params is cancelled.
 func StartSession(params TelemetrySessionParams, statsEngine stats.Engine) error {
+	ctx := params.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	backoff := utils.NewSimpleBackoff(time.Second, 1*time.Minute, 0.2, 2)
+	metricsBuffer := tcsclient.NewMetricsBuffer(params.Cfg.TelemetryBufferSize)
 	for {
-		tcsError := startTelemetrySession(params, statsEngine)
+		tcsError := startTelemetrySession(params, statsEngine, metricsBuffer)
+		if params.Cfg.TelemetryReconnectDisabled {
+			seelog.Infof("TCS connection closed and reconnecting is disabled: %v", tcsError)
+			return tcsError
+		}
+		// Hold on to the metrics gathered so far, they would otherwise be
+		// lost if the stats engine discards them before the session is back
+		if err := metricsBuffer.Collect(statsEngine); err != nil && err != stats.EmptyMetricsError {
+			seelog.Warnf("Error buffering metrics while disconnected from tcs: %v", err)
+		}
+		var reconnectDelay time.Duration
 		if tcsError == nil || tcsError == io.EOF {
 			seelog.Info("TCS Websocket connection closed for a valid reason")
 			backoff.Reset()
 		} else {
 			seelog.Infof("Error from tcs; backing off: %v", tcsError)
-			params.time().Sleep(backoff.Duration())
+			reconnectDelay = backoff.Duration()
+		}
+		select {
+		case <-ctx.Done():
+			seelog.Info("TCS session context cancelled, not reconnecting")
+			return ctx.Err()
+		case <-params.time().After(reconnectDelay):
 		}
 	}
 }
 
-func startTelemetrySession(params TelemetrySessionParams, statsEngine stats.Engine) error {
+func startTelemetrySession(params TelemetrySessionParams, statsEngine stats.Engine, metricsBuffer *tcsclient.MetricsBuffer) error {
 	tcsEndpoint, err := params.ECSClient.DiscoverTelemetryEndpoint(params.ContainerInstanceArn)
 	if err != nil {
 		seelog.Errorf("Unable to discover poll endpoint: %v", err)
 		return err
 	}
 	url := formatURL(tcsEndpoint, params.Cfg.Cluster, params.ContainerInstanceArn)
-	return startSession(url, params.Cfg, params.CredentialProvider, statsEngine,
+	return startSession(url, params.Cfg, params.CredentialProvider, statsEngine, metricsBuffer,
 		defaultHeartbeatTimeout, defaultHeartbeatJitter, defaultPublishMetricsInterval,
 		params.DeregisterInstanceEventStream)
 }
@@ -104,10 +130,11 @@ func startSession(url string,
 	cfg *config.Config,
 	credentialProvider *credentials.Credentials,
 	statsEngine stats.Engine,
+	metricsBuffer *tcsclient.MetricsBuffer,
 	heartbeatTimeout, heartbeatJitter,
 	publishMetricsInterval time.Duration,
 	deregisterInstanceEventStream *eventstream.EventStream) error {
-	client := tcsclient.New(url, cfg, credentialProvider, statsEngine,
+	client := tcsclient.New(url, cfg, credentialProvider, statsEngine, metricsBuffer,
 		publishMetricsInterval, wsRWTimeout)
 	defer client.Close()
 
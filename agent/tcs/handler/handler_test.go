@@ -18,6 +18,7 @@ import (
 	"io"
 	"math/rand"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -30,10 +31,12 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/tcs/model/ecstcs"
 	"github.com/aws/amazon-ecs-agent/agent/wsclient"
 	wsmock "github.com/aws/amazon-ecs-agent/agent/wsclient/mock/utils"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/golang/mock/gomock"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 )
 
@@ -58,6 +61,23 @@ func (engine *mockStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []
 	return req.Metadata, req.TaskMetrics, nil
 }
 
+// sequenceStatsEngine numbers the message id of the metrics it returns, so
+// that tests can tell which call the published metrics came from
+type sequenceStatsEngine struct {
+	lock     sync.Mutex
+	sequence int
+}
+
+func (engine *sequenceStatsEngine) GetInstanceMetrics() (*ecstcs.MetricsMetadata, []*ecstcs.TaskMetric, error) {
+	engine.lock.Lock()
+	defer engine.lock.Unlock()
+
+	engine.sequence++
+	req := createPublishMetricsRequest()
+	req.Metadata.MessageId = aws.String(strconv.Itoa(engine.sequence))
+	return req.Metadata, req.TaskMetrics, nil
+}
+
 func TestFormatURL(t *testing.T) {
 	endpoint := "http://127.0.0.0.1/"
 	wsurl := formatURL(endpoint, testClusterArn, testInstanceArn)
@@ -106,7 +126,7 @@ func TestStartSession(t *testing.T) {
 
 	deregisterInstanceEventStream := eventstream.NewEventStream("Deregister_Instance", context.Background())
 	// Start a session with the test server.
-	go startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, defaultHeartbeatTimeout, defaultHeartbeatJitter, testPublishMetricsInterval, deregisterInstanceEventStream)
+	go startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, nil, defaultHeartbeatTimeout, defaultHeartbeatJitter, testPublishMetricsInterval, deregisterInstanceEventStream)
 
 	// startSession internally starts publishing metrics from the mockStatsEngine object.
 	time.Sleep(testPublishMetricsInterval)
@@ -168,7 +188,7 @@ func TestSessionConnectionClosedByRemote(t *testing.T) {
 	defer cancel()
 
 	// Start a session with the test server.
-	err = startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, defaultHeartbeatTimeout, defaultHeartbeatJitter, testPublishMetricsInterval, deregisterInstanceEventStream)
+	err = startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, nil, defaultHeartbeatTimeout, defaultHeartbeatJitter, testPublishMetricsInterval, deregisterInstanceEventStream)
 
 	if err == nil {
 		t.Error("Expected io.EOF on closed connection")
@@ -203,7 +223,7 @@ func TestConnectionInactiveTimeout(t *testing.T) {
 	deregisterInstanceEventStream.StartListening()
 	defer cancel()
 	// Start a session with the test server.
-	err = startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, 50*time.Millisecond, 100*time.Millisecond, testPublishMetricsInterval, deregisterInstanceEventStream)
+	err = startSession(server.URL, testCfg, credentials.AnonymousCredentials, &mockStatsEngine{}, nil, 50*time.Millisecond, 100*time.Millisecond, testPublishMetricsInterval, deregisterInstanceEventStream)
 	// if we are not blocked here, then the test pass as it will reconnect in StartSession
 	assert.Error(t, err, "Close the connection should cause the tcs client return error")
 
@@ -212,6 +232,99 @@ func TestConnectionInactiveTimeout(t *testing.T) {
 	closeSocket(closeWS)
 }
 
+func TestStartSessionReconnectsAndFlushesBufferedMetrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The connection to the first server is closed by the remote once the
+	// first metrics are published on it
+	closeFirstWS := make(chan []byte)
+	firstServer, firstServerChan, firstRequestChan, firstServerErr, err := wsmock.GetMockServer(closeFirstWS)
+	require.NoError(t, err)
+	firstServer.StartTLS()
+	defer firstServer.Close()
+	go drainMockServer(nil, firstServerErr)
+
+	closeSecondWS := make(chan []byte)
+	secondServer, _, secondRequestChan, secondServerErr, err := wsmock.GetMockServer(closeSecondWS)
+	require.NoError(t, err)
+	secondServer.StartTLS()
+	defer secondServer.Close()
+	go drainMockServer(nil, secondServerErr)
+
+	mockEcs := mock_api.NewMockECSClient(ctrl)
+	gomock.InOrder(
+		mockEcs.EXPECT().DiscoverTelemetryEndpoint(testInstanceArn).Do(func(string) {
+			go func() {
+				<-firstRequestChan
+				closeSocket(closeFirstWS)
+				close(firstServerChan)
+			}()
+		}).Return(firstServer.URL, nil),
+		mockEcs.EXPECT().DiscoverTelemetryEndpoint(testInstanceArn).Return(secondServer.URL, nil),
+		// Block any further reconnects until the test is done
+		mockEcs.EXPECT().DiscoverTelemetryEndpoint(testInstanceArn).Do(func(string) {
+			<-ctx.Done()
+		}).Return("", errors.New("test is done")).AnyTimes(),
+	)
+
+	cfg := *testCfg
+	cfg.TelemetryBufferSize = 10
+	sessionDone := make(chan error)
+	go func() {
+		sessionDone <- StartSession(TelemetrySessionParams{
+			Ctx:                           ctx,
+			ContainerInstanceArn:          testInstanceArn,
+			CredentialProvider:            credentials.AnonymousCredentials,
+			Cfg:                           &cfg,
+			DeregisterInstanceEventStream: eventstream.NewEventStream("Deregister_Instance", context.Background()),
+			ECSClient:                     mockEcs,
+		}, &sequenceStatsEngine{})
+	}()
+
+	// Metrics 2 are buffered while disconnected and are published on
+	// reconnect, ahead of metrics 3
+	assert.Equal(t, "2", getMessageIdFromRequest(t, <-secondRequestChan))
+	assert.Equal(t, "3", getMessageIdFromRequest(t, <-secondRequestChan))
+	closeSocket(closeSecondWS)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-sessionDone, "StartSession should return once its context is cancelled")
+}
+
+func TestStartSessionReconnectDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	closeWS := make(chan []byte)
+	server, serverChan, requestChan, serverErr, err := wsmock.GetMockServer(closeWS)
+	require.NoError(t, err)
+	server.StartTLS()
+	defer server.Close()
+	go drainMockServer(requestChan, serverErr)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		closeSocket(closeWS)
+		close(serverChan)
+	}()
+
+	mockEcs := mock_api.NewMockECSClient(ctrl)
+	mockEcs.EXPECT().DiscoverTelemetryEndpoint(testInstanceArn).Return(server.URL, nil)
+
+	cfg := *testCfg
+	cfg.TelemetryReconnectDisabled = true
+	err = StartSession(TelemetrySessionParams{
+		ContainerInstanceArn:          testInstanceArn,
+		CredentialProvider:            credentials.AnonymousCredentials,
+		Cfg:                           &cfg,
+		DeregisterInstanceEventStream: eventstream.NewEventStream("Deregister_Instance", context.Background()),
+		ECSClient:                     mockEcs,
+	}, &mockStatsEngine{})
+	assert.Equal(t, io.EOF, err, "StartSession should return once the connection is closed")
+}
+
 func TestDiscoverEndpointAndStartSession(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -219,7 +332,7 @@ func TestDiscoverEndpointAndStartSession(t *testing.T) {
 	mockEcs := mock_api.NewMockECSClient(ctrl)
 	mockEcs.EXPECT().DiscoverTelemetryEndpoint(gomock.Any()).Return("", errors.New("error"))
 
-	err := startTelemetrySession(TelemetrySessionParams{ECSClient: mockEcs}, nil)
+	err := startTelemetrySession(TelemetrySessionParams{ECSClient: mockEcs}, nil, nil)
 	if err == nil {
 		t.Error("Expected error from startTelemetrySession when DiscoverTelemetryEndpoint returns error")
 	}
@@ -234,6 +347,28 @@ func getPayloadFromRequest(request string) (string, error) {
 	return "", errors.New("Could not get payload")
 }
 
+// getMessageIdFromRequest decodes the publish metrics request sent to the mock
+// server and returns its message id
+func getMessageIdFromRequest(t *testing.T, request string) string {
+	payload, err := getPayloadFromRequest(request)
+	require.NoError(t, err)
+	message, responseType, err := wsclient.DecodeData([]byte(payload), tcsclient.NewTCSDecoder())
+	require.NoError(t, err)
+	require.Equal(t, "PublishMetricsRequest", responseType)
+	return aws.StringValue(message.(*ecstcs.PublishMetricsRequest).Metadata.MessageId)
+}
+
+// drainMockServer discards the requests and errors of a mock server that a
+// test is not interested in
+func drainMockServer(requestChan <-chan string, serverErr <-chan error) {
+	for {
+		select {
+		case <-requestChan:
+		case <-serverErr:
+		}
+	}
+}
+
 // closeSocket tells the server to send a close frame. This lets us test
 // what happens if the connection is closed by the remote server.
 func closeSocket(ws chan<- []byte) {
@@ -23,9 +23,12 @@ import (
 	"github.com/aws/amazon-ecs-agent/agent/eventstream"
 	"github.com/aws/amazon-ecs-agent/agent/utils/ttime"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"golang.org/x/net/context"
 )
 
 type TelemetrySessionParams struct {
+	// Ctx stops the session from reconnecting once it is cancelled
+	Ctx                           context.Context
 	ContainerInstanceArn          string
 	CredentialProvider            *credentials.Credentials
 	Cfg                           *config.Config
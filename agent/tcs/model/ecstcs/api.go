@@ -51,6 +51,26 @@ func (s BadRequestException) GoString() string {
 	return s.String()
 }
 
+type CPUThrottlingStats struct {
+	_ struct{} `type:"structure"`
+
+	Periods *int64 `locationName:"periods" type:"integer"`
+
+	ThrottledPeriods *int64 `locationName:"throttledPeriods" type:"integer"`
+
+	ThrottledTime *int64 `locationName:"throttledTime" type:"integer"`
+}
+
+// String returns the string representation
+func (s CPUThrottlingStats) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s CPUThrottlingStats) GoString() string {
+	return s.String()
+}
+
 type CWStatsSet struct {
 	_ struct{} `type:"structure"`
 
@@ -78,6 +98,10 @@ type ContainerMetric struct {
 
 	CpuStatsSet *CWStatsSet `locationName:"cpuStatsSet" type:"structure"`
 
+	CpuThrottlingStats *CPUThrottlingStats `locationName:"cpuThrottlingStats" type:"structure"`
+
+	FilesystemUsageStatsSet *CWStatsSet `locationName:"filesystemUsageStatsSet" type:"structure"`
+
 	MemoryStatsSet *CWStatsSet `locationName:"memoryStatsSet" type:"structure"`
 }
 
@@ -247,11 +271,85 @@ func (s StopTelemetrySessionMessage) GoString() string {
 	return s.String()
 }
 
+type Tag struct {
+	_ struct{} `type:"structure"`
+
+	Key *string `locationName:"key" type:"string"`
+
+	Value *string `locationName:"value" type:"string"`
+}
+
+// String returns the string representation
+func (s Tag) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s Tag) GoString() string {
+	return s.String()
+}
+
+type TaskImagePulls struct {
+	_ struct{} `type:"structure"`
+
+	CacheHit *int64 `locationName:"cacheHit" type:"integer"`
+
+	Partial *int64 `locationName:"partial" type:"integer"`
+
+	Registry *int64 `locationName:"registry" type:"integer"`
+}
+
+// String returns the string representation
+func (s TaskImagePulls) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s TaskImagePulls) GoString() string {
+	return s.String()
+}
+
+type TaskLaunchLatency struct {
+	_ struct{} `type:"structure"`
+
+	Create *float64 `locationName:"create" type:"double"`
+
+	Pull *float64 `locationName:"pull" type:"double"`
+
+	QueueWait *float64 `locationName:"queueWait" type:"double"`
+
+	Start *float64 `locationName:"start" type:"double"`
+
+	Total *float64 `locationName:"total" type:"double"`
+}
+
+// String returns the string representation
+func (s TaskLaunchLatency) String() string {
+	return awsutil.Prettify(s)
+}
+
+// GoString returns the string representation
+func (s TaskLaunchLatency) GoString() string {
+	return s.String()
+}
+
 type TaskMetric struct {
 	_ struct{} `type:"structure"`
 
+	AggregateMetric *ContainerMetric `locationName:"aggregateMetric" type:"structure"`
+
 	ContainerMetrics []*ContainerMetric `locationName:"containerMetrics" type:"list"`
 
+	EniReconciliationLag *float64 `locationName:"eniReconciliationLag" type:"double"`
+
+	ImagePulls *TaskImagePulls `locationName:"imagePulls" type:"structure"`
+
+	LaunchLatency *TaskLaunchLatency `locationName:"launchLatency" type:"structure"`
+
+	StopLatency *float64 `locationName:"stopLatency" type:"double"`
+
+	Tags []*Tag `locationName:"tags" type:"list"`
+
 	TaskArn *string `locationName:"taskArn" type:"string"`
 
 	TaskDefinitionFamily *string `locationName:"taskDefinitionFamily" type:"string"`